@@ -0,0 +1,720 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+)
+
+// DefaultJWTSecret is the development signing key; it is rejected in production
+const DefaultJWTSecret = "secured_secret_key"
+
+// Environments the service can run in
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// Config holds every setting needed to start the service
+type Config struct {
+	Environment string            `json:"environment"`
+	Server      ServerConfig      `json:"server"`
+	Storage     StorageConfig     `json:"storage"`
+	JWT         JWTConfig         `json:"jwt"`
+	Reminders   RemindersConfig   `json:"reminders"`
+	Webhooks    WebhooksConfig    `json:"webhooks"`
+	Stream      StreamConfig      `json:"stream"`
+	Attachments AttachmentsConfig `json:"attachments"`
+	Permissions PermissionsConfig `json:"permissions"`
+	OIDC        OIDCConfig        `json:"oidc"`
+	// Workflow defines the task statuses and the transitions between them; when it is
+	// not set, domain.DefaultWorkflow is used
+	Workflow *domain.Workflow `json:"workflow,omitempty"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Address         string   `json:"address"`
+	RequestTimeout  Duration `json:"request_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+}
+
+// StorageConfig selects and configures the storage backend
+type StorageConfig struct {
+	Backend  string `json:"backend"`
+	MongoURI string `json:"mongo_uri"`
+	Database string `json:"database"`
+}
+
+// JWTConfig configures how access and refresh tokens are issued
+type JWTConfig struct {
+	Secret        string   `json:"secret"`
+	Issuer        string   `json:"issuer"`
+	Expiry        Duration `json:"expiry"`
+	RefreshExpiry Duration `json:"refresh_expiry"`
+}
+
+// RemindersConfig configures the scheduler that reminds owners of tasks coming due.
+// A reminder is sent for each lead time before a task's due date; a zero lead time
+// reminds once the task is overdue.
+type RemindersConfig struct {
+	Enabled   bool       `json:"enabled"`
+	Interval  Duration   `json:"interval"`
+	LeadTimes []Duration `json:"lead_times"`
+	CatchUp   Duration   `json:"catch_up"`
+	Notifier  string     `json:"notifier"`
+	SMTP      SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig configures the SMTP notifier
+type SMTPConfig struct {
+	Host            string `json:"host"`
+	Port            int    `json:"port"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	From            string `json:"from"`
+	RecipientDomain string `json:"recipient_domain"`
+}
+
+// WebhooksConfig configures the worker that delivers webhooks. A failed delivery is
+// retried after Backoff, doubling after each failure up to MaxBackoff, until it has
+// been attempted MaxAttempts times.
+type WebhooksConfig struct {
+	Interval    Duration `json:"interval"`
+	MaxAttempts int      `json:"max_attempts"`
+	Backoff     Duration `json:"backoff"`
+	MaxBackoff  Duration `json:"max_backoff"`
+	Timeout     Duration `json:"timeout"`
+}
+
+// StreamConfig configures the live task stream. The last ReplaySize events are kept
+// for clients that reconnect; zero turns replay off.
+type StreamConfig struct {
+	ReplaySize int `json:"replay_size"`
+}
+
+// AttachmentsConfig configures where attachment contents are kept and how large a
+// single attachment may be, in bytes
+type AttachmentsConfig struct {
+	Directory string `json:"directory"`
+	MaxSize   int64  `json:"max_size"`
+}
+
+// PermissionsConfig configures how long the permissions resolved from a user's roles
+// are cached. Changes are seen straight away by the instance that made them; other
+// instances see them once CacheTTL has passed. Zero turns caching off.
+type PermissionsConfig struct {
+	CacheTTL Duration `json:"cache_ttl"`
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider. It is
+// turned on by setting Issuer. UsernameClaim names the ID token claim new users are
+// named after; RoleClaim names the claim their roles are read from, each value going
+// through RoleMapping when one is set. Without RoleClaim, roles are managed in this
+// service as for any other user.
+type OIDCConfig struct {
+	Issuer        string            `json:"issuer"`
+	ClientID      string            `json:"client_id"`
+	ClientSecret  string            `json:"client_secret"`
+	RedirectURL   string            `json:"redirect_url"`
+	Scopes        []string          `json:"scopes"`
+	UsernameClaim string            `json:"username_claim"`
+	RoleClaim     string            `json:"role_claim"`
+	RoleMapping   map[string]string `json:"role_mapping"`
+	Timeout       Duration          `json:"timeout"`
+}
+
+// maxWebhookTimeout keeps a webhook request well within the lease that stops other
+// workers from sending the same delivery
+const maxWebhookTimeout = time.Minute
+
+// Duration is a time.Duration written as a string such as "15m" in config files
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("duration must be a string such as \"30s\"")
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() Config {
+	return Config{
+		Environment: Development,
+		Server: ServerConfig{
+			Address:         ":8080",
+			RequestTimeout:  Duration(10 * time.Second),
+			ShutdownTimeout: Duration(10 * time.Second),
+		},
+		Storage: StorageConfig{
+			Backend:  "mongo",
+			MongoURI: "mongodb://localhost:27017",
+			Database: "task_manager",
+		},
+		JWT: JWTConfig{
+			Secret:        DefaultJWTSecret,
+			Issuer:        "task-manager",
+			Expiry:        Duration(15 * time.Minute),
+			RefreshExpiry: Duration(7 * 24 * time.Hour),
+		},
+		Reminders: RemindersConfig{
+			Enabled:   true,
+			Interval:  Duration(time.Minute),
+			LeadTimes: []Duration{Duration(24 * time.Hour), Duration(time.Hour), 0},
+			CatchUp:   Duration(24 * time.Hour),
+			Notifier:  "log",
+			SMTP:      SMTPConfig{Port: 587},
+		},
+		Webhooks: WebhooksConfig{
+			Interval:    Duration(5 * time.Second),
+			MaxAttempts: 8,
+			Backoff:     Duration(30 * time.Second),
+			MaxBackoff:  Duration(time.Hour),
+			Timeout:     Duration(10 * time.Second),
+		},
+		Stream: StreamConfig{
+			ReplaySize: 256,
+		},
+		Attachments: AttachmentsConfig{
+			Directory: "attachments",
+			MaxSize:   10 << 20,
+		},
+		Permissions: PermissionsConfig{
+			CacheTTL: Duration(30 * time.Second),
+		},
+		OIDC: OIDCConfig{
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+			Timeout:       Duration(10 * time.Second),
+		},
+	}
+}
+
+// setting maps one value onto a flag and an environment variable
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	apply func(cfg *Config, value string) error
+}
+
+var settings = []setting{
+	{"env", "APP_ENV", "environment to run in (development or production)", func(cfg *Config, value string) error {
+		cfg.Environment = value
+		return nil
+	}},
+	{"addr", "SERVER_ADDRESS", "address the HTTP server listens on", func(cfg *Config, value string) error {
+		cfg.Server.Address = value
+		return nil
+	}},
+	{"request-timeout", "REQUEST_TIMEOUT", "deadline for each request, 0 to disable", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Server.RequestTimeout, value)
+	}},
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long to wait for requests and background jobs on shutdown", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Server.ShutdownTimeout, value)
+	}},
+	{"storage", "STORAGE_BACKEND", "storage backend (mongo or memory)", func(cfg *Config, value string) error {
+		cfg.Storage.Backend = value
+		return nil
+	}},
+	{"mongo-uri", "MONGODB_URI", "MongoDB connection string", func(cfg *Config, value string) error {
+		cfg.Storage.MongoURI = value
+		return nil
+	}},
+	{"mongo-database", "MONGODB_DATABASE", "MongoDB database name", func(cfg *Config, value string) error {
+		cfg.Storage.Database = value
+		return nil
+	}},
+	{"jwt-secret", "JWT_SECRET", "key used to sign access tokens", func(cfg *Config, value string) error {
+		cfg.JWT.Secret = value
+		return nil
+	}},
+	{"jwt-issuer", "JWT_ISSUER", "issuer claim of access tokens", func(cfg *Config, value string) error {
+		cfg.JWT.Issuer = value
+		return nil
+	}},
+	{"jwt-expiry", "JWT_EXPIRY", "lifetime of access tokens", func(cfg *Config, value string) error {
+		return setDuration(&cfg.JWT.Expiry, value)
+	}},
+	{"jwt-refresh-expiry", "JWT_REFRESH_EXPIRY", "lifetime of refresh tokens", func(cfg *Config, value string) error {
+		return setDuration(&cfg.JWT.RefreshExpiry, value)
+	}},
+	{"reminders", "REMINDERS_ENABLED", "whether to send due-date reminders (true or false)", func(cfg *Config, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		cfg.Reminders.Enabled = enabled
+		return err
+	}},
+	{"reminder-interval", "REMINDER_INTERVAL", "how often to look for due reminders", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Reminders.Interval, value)
+	}},
+	{"reminder-lead-times", "REMINDER_LEAD_TIMES", "comma separated times before the due date to remind at, 0s for overdue", func(cfg *Config, value string) error {
+		leadTimes := []Duration{}
+		for _, part := range strings.Split(value, ",") {
+			var lead Duration
+			if err := setDuration(&lead, strings.TrimSpace(part)); err != nil {
+				return err
+			}
+			leadTimes = append(leadTimes, lead)
+		}
+		cfg.Reminders.LeadTimes = leadTimes
+		return nil
+	}},
+	{"reminder-catch-up", "REMINDER_CATCH_UP", "how long overdue tasks are still reminded, e.g. after downtime", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Reminders.CatchUp, value)
+	}},
+	{"notifier", "REMINDER_NOTIFIER", "how reminders are sent (log or smtp)", func(cfg *Config, value string) error {
+		cfg.Reminders.Notifier = value
+		return nil
+	}},
+	{"smtp-host", "SMTP_HOST", "SMTP server host", func(cfg *Config, value string) error {
+		cfg.Reminders.SMTP.Host = value
+		return nil
+	}},
+	{"smtp-port", "SMTP_PORT", "SMTP server port", func(cfg *Config, value string) error {
+		port, err := strconv.Atoi(value)
+		cfg.Reminders.SMTP.Port = port
+		return err
+	}},
+	{"smtp-username", "SMTP_USERNAME", "SMTP username, empty to send without authenticating", func(cfg *Config, value string) error {
+		cfg.Reminders.SMTP.Username = value
+		return nil
+	}},
+	{"smtp-password", "SMTP_PASSWORD", "SMTP password", func(cfg *Config, value string) error {
+		cfg.Reminders.SMTP.Password = value
+		return nil
+	}},
+	{"smtp-from", "SMTP_FROM", "sender address of reminder emails", func(cfg *Config, value string) error {
+		cfg.Reminders.SMTP.From = value
+		return nil
+	}},
+	{"smtp-recipient-domain", "SMTP_RECIPIENT_DOMAIN", "domain appended to usernames that are not email addresses", func(cfg *Config, value string) error {
+		cfg.Reminders.SMTP.RecipientDomain = value
+		return nil
+	}},
+	{"webhook-interval", "WEBHOOK_INTERVAL", "how often to look for webhook deliveries that are due", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Webhooks.Interval, value)
+	}},
+	{"webhook-max-attempts", "WEBHOOK_MAX_ATTEMPTS", "how many times a webhook delivery is attempted before it fails", func(cfg *Config, value string) error {
+		attempts, err := strconv.Atoi(value)
+		cfg.Webhooks.MaxAttempts = attempts
+		return err
+	}},
+	{"webhook-backoff", "WEBHOOK_BACKOFF", "delay before the first retry of a webhook delivery; it doubles after each failure", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Webhooks.Backoff, value)
+	}},
+	{"webhook-max-backoff", "WEBHOOK_MAX_BACKOFF", "longest delay between retries of a webhook delivery", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Webhooks.MaxBackoff, value)
+	}},
+	{"webhook-timeout", "WEBHOOK_TIMEOUT", "deadline for each webhook request", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Webhooks.Timeout, value)
+	}},
+	{"stream-replay-size", "STREAM_REPLAY_SIZE", "how many task events are kept for stream clients that reconnect", func(cfg *Config, value string) error {
+		size, err := strconv.Atoi(value)
+		cfg.Stream.ReplaySize = size
+		return err
+	}},
+	{"attachment-dir", "ATTACHMENT_DIR", "directory attachment contents are stored in", func(cfg *Config, value string) error {
+		cfg.Attachments.Directory = value
+		return nil
+	}},
+	{"attachment-max-size", "ATTACHMENT_MAX_SIZE", "largest attachment accepted, in bytes", func(cfg *Config, value string) error {
+		size, err := strconv.ParseInt(value, 10, 64)
+		cfg.Attachments.MaxSize = size
+		return err
+	}},
+	{"permission-cache-ttl", "PERMISSION_CACHE_TTL", "how long the permissions of a user's roles are cached, 0 to disable", func(cfg *Config, value string) error {
+		return setDuration(&cfg.Permissions.CacheTTL, value)
+	}},
+	{"oidc-issuer", "OIDC_ISSUER", "issuer URL of the OpenID Connect provider, empty to turn single sign-on off", func(cfg *Config, value string) error {
+		cfg.OIDC.Issuer = value
+		return nil
+	}},
+	{"oidc-client-id", "OIDC_CLIENT_ID", "client ID registered with the OpenID Connect provider", func(cfg *Config, value string) error {
+		cfg.OIDC.ClientID = value
+		return nil
+	}},
+	{"oidc-client-secret", "OIDC_CLIENT_SECRET", "client secret registered with the OpenID Connect provider, empty for a public client", func(cfg *Config, value string) error {
+		cfg.OIDC.ClientSecret = value
+		return nil
+	}},
+	{"oidc-redirect-url", "OIDC_REDIRECT_URL", "URL of /auth/oidc/callback the provider sends users back to", func(cfg *Config, value string) error {
+		cfg.OIDC.RedirectURL = value
+		return nil
+	}},
+	{"oidc-scopes", "OIDC_SCOPES", "comma separated scopes requested from the OpenID Connect provider", func(cfg *Config, value string) error {
+		scopes := []string{}
+		for _, part := range strings.Split(value, ",") {
+			if scope := strings.TrimSpace(part); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		cfg.OIDC.Scopes = scopes
+		return nil
+	}},
+	{"oidc-username-claim", "OIDC_USERNAME_CLAIM", "ID token claim new single sign-on users are named after", func(cfg *Config, value string) error {
+		cfg.OIDC.UsernameClaim = value
+		return nil
+	}},
+	{"oidc-role-claim", "OIDC_ROLE_CLAIM", "ID token claim roles are read from, empty to manage roles here", func(cfg *Config, value string) error {
+		cfg.OIDC.RoleClaim = value
+		return nil
+	}},
+	{"oidc-role-mapping", "OIDC_ROLE_MAPPING", "comma separated claim=role pairs; only mapped claim values give roles", func(cfg *Config, value string) error {
+		mapping := map[string]string{}
+		for _, part := range strings.Split(value, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			claim, role, ok := strings.Cut(part, "=")
+			if !ok {
+				return fmt.Errorf("role mapping %q must be written as claim=role", part)
+			}
+			mapping[strings.TrimSpace(claim)] = strings.TrimSpace(role)
+		}
+		cfg.OIDC.RoleMapping = mapping
+		return nil
+	}},
+	{"oidc-timeout", "OIDC_TIMEOUT", "deadline for each request to the OpenID Connect provider", func(cfg *Config, value string) error {
+		return setDuration(&cfg.OIDC.Timeout, value)
+	}},
+	{"workflow-file", "WORKFLOW_FILE", "JSON file defining the task workflow", func(cfg *Config, value string) error {
+		workflow, err := loadWorkflow(value)
+		cfg.Workflow = workflow
+		return err
+	}},
+}
+
+func setDuration(target *Duration, value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*target = Duration(parsed)
+	return nil
+}
+
+// Load builds the configuration from defaults, a JSON config file, environment
+// variables and command line flags; later sources override earlier ones. The file
+// is named by the -config flag or the CONFIG_FILE environment variable.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("task-manager", flag.ContinueOnError)
+	configFile := fs.String("config", getenv("CONFIG_FILE"), "path to a JSON config file")
+	for _, s := range settings {
+		fs.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := s.apply(&cfg, value); err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.apply(&cfg, f.Value.String()); err != nil {
+					flagErr = fmt.Errorf("invalid -%s: %w", s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadWorkflow reads a workflow definition from its own JSON file
+func loadWorkflow(path string) (*domain.Workflow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	workflow := &domain.Workflow{}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(workflow); err != nil {
+		return nil, fmt.Errorf("parsing workflow file %s: %w", path, err)
+	}
+
+	return workflow, nil
+}
+
+// TaskWorkflow returns the configured workflow, or the default one
+func (c *Config) TaskWorkflow() domain.Workflow {
+	if c.Workflow == nil {
+		return domain.DefaultWorkflow()
+	}
+
+	return *c.Workflow
+}
+
+// Validate checks that the configuration is complete and safe to run with
+func (c *Config) Validate() error {
+	if c.Environment != Development && c.Environment != Production {
+		return fmt.Errorf("environment must be either %s or %s", Development, Production)
+	}
+
+	if c.Server.Address == "" {
+		return errors.New("server address is required")
+	}
+
+	if c.Server.RequestTimeout < 0 {
+		return errors.New("request timeout must not be negative")
+	}
+
+	if c.Server.ShutdownTimeout <= 0 {
+		return errors.New("shutdown timeout must be positive")
+	}
+
+	switch c.Storage.Backend {
+	case "mongo":
+		if c.Storage.MongoURI == "" || c.Storage.Database == "" {
+			return errors.New("mongo storage requires a URI and a database name")
+		}
+	case "memory":
+	default:
+		return errors.New("storage backend must be either mongo or memory")
+	}
+
+	if c.JWT.Secret == "" {
+		return errors.New("JWT secret is required")
+	}
+
+	if c.JWT.Expiry <= 0 {
+		return errors.New("JWT expiry must be positive")
+	}
+
+	if c.JWT.RefreshExpiry <= c.JWT.Expiry {
+		return errors.New("refresh token expiry must be longer than the JWT expiry")
+	}
+
+	if err := c.Reminders.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Webhooks.Validate(); err != nil {
+		return err
+	}
+
+	if c.Stream.ReplaySize < 0 {
+		return errors.New("stream replay size must not be negative")
+	}
+
+	if c.Attachments.Directory == "" {
+		return errors.New("attachment directory is required")
+	}
+
+	if c.Attachments.MaxSize <= 0 {
+		return errors.New("attachment max size must be positive")
+	}
+
+	if c.Permissions.CacheTTL < 0 {
+		return errors.New("permission cache TTL must not be negative")
+	}
+
+	if err := c.OIDC.Validate(); err != nil {
+		return err
+	}
+
+	if c.Workflow != nil {
+		if err := c.Workflow.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Environment == Production {
+		if c.JWT.Secret == DefaultJWTSecret {
+			return errors.New("the default JWT secret cannot be used in production")
+		}
+
+		if len(c.JWT.Secret) < 32 {
+			return errors.New("JWT secret must be at least 32 characters in production")
+		}
+
+		if c.OIDC.Enabled() && !strings.HasPrefix(c.OIDC.Issuer, "https://") {
+			return errors.New("the OIDC issuer must use https in production")
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the reminder settings; they are only checked when reminders are enabled
+func (c *RemindersConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Interval <= 0 {
+		return errors.New("reminder interval must be positive")
+	}
+
+	if len(c.LeadTimes) == 0 {
+		return errors.New("reminders need at least one lead time")
+	}
+
+	for _, lead := range c.LeadTimes {
+		if lead < 0 {
+			return errors.New("reminder lead times must not be negative")
+		}
+	}
+
+	if c.CatchUp < 0 {
+		return errors.New("reminder catch-up must not be negative")
+	}
+
+	switch c.Notifier {
+	case "log":
+	case "smtp":
+		if c.SMTP.Host == "" || c.SMTP.From == "" {
+			return errors.New("the smtp notifier requires a host and a sender address")
+		}
+
+		if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+			return errors.New("SMTP port must be between 1 and 65535")
+		}
+	default:
+		return errors.New("reminder notifier must be either log or smtp")
+	}
+
+	return nil
+}
+
+// Validate checks the webhook delivery settings
+func (c *WebhooksConfig) Validate() error {
+	if c.Interval <= 0 {
+		return errors.New("webhook interval must be positive")
+	}
+
+	if c.MaxAttempts < 1 {
+		return errors.New("webhook max attempts must be at least 1")
+	}
+
+	if c.Backoff <= 0 {
+		return errors.New("webhook backoff must be positive")
+	}
+
+	if c.MaxBackoff < c.Backoff {
+		return errors.New("webhook max backoff must not be shorter than the backoff")
+	}
+
+	if c.Timeout <= 0 || c.Timeout > Duration(maxWebhookTimeout) {
+		return fmt.Errorf("webhook timeout must be positive and at most %s", maxWebhookTimeout)
+	}
+
+	return nil
+}
+
+// Enabled reports whether single sign-on is configured
+func (c *OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// Validate checks the single sign-on settings; they are only checked when it is enabled
+func (c *OIDCConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if issuer, err := url.Parse(c.Issuer); err != nil || !issuer.IsAbs() || issuer.Host == "" || issuer.RawQuery != "" || issuer.Fragment != "" {
+		return errors.New("the OIDC issuer must be an absolute URL without a query")
+	}
+
+	if c.ClientID == "" || c.RedirectURL == "" {
+		return errors.New("OIDC requires a client ID and a redirect URL")
+	}
+
+	if redirect, err := url.Parse(c.RedirectURL); err != nil || !redirect.IsAbs() || redirect.Host == "" {
+		return errors.New("the OIDC redirect URL must be an absolute URL")
+	}
+
+	if !slices.Contains(c.Scopes, "openid") {
+		return errors.New("the OIDC scopes must include openid")
+	}
+
+	if c.UsernameClaim == "" {
+		return errors.New("the OIDC username claim is required")
+	}
+
+	if len(c.RoleMapping) > 0 && c.RoleClaim == "" {
+		return errors.New("an OIDC role mapping requires a role claim")
+	}
+
+	for claim, role := range c.RoleMapping {
+		if claim == "" {
+			return errors.New("OIDC role mapping has an empty claim value")
+		}
+
+		if _, err := domain.ValidateUserRoles([]string{role}); err != nil {
+			return fmt.Errorf("OIDC role mapping for %q: %v", claim, err)
+		}
+	}
+
+	if c.Timeout <= 0 {
+		return errors.New("OIDC timeout must be positive")
+	}
+
+	return nil
+}
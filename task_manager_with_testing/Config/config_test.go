@@ -0,0 +1,492 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Equal(t, ":8080", cfg.Server.Address)
+	assert.Equal(t, "task_manager", cfg.Storage.Database)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"server": {"address": ":9000", "request_timeout": "5s"},
+		"storage": {"backend": "memory"},
+		"jwt": {"expiry": "1h"}
+	}`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-addr", ":9200"},
+		env(map[string]string{"SERVER_ADDRESS": ":9100", "JWT_EXPIRY": "30m", "JWT_REFRESH_EXPIRY": "720h"}),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9200", cfg.Server.Address, "flags override env and file")
+	assert.Equal(t, Duration(30*time.Minute), cfg.JWT.Expiry, "env overrides file")
+	assert.Equal(t, Duration(720*time.Hour), cfg.JWT.RefreshExpiry)
+	assert.Equal(t, Duration(5*time.Second), cfg.Server.RequestTimeout, "file overrides defaults")
+	assert.Equal(t, "memory", cfg.Storage.Backend)
+	assert.Equal(t, "mongodb://localhost:27017", cfg.Storage.MongoURI, "unset values keep their defaults")
+}
+
+func TestLoad_Reminders(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"reminders": {"notifier": "smtp", "smtp": {"host": "mail.example.com", "from": "tasks@example.com"}}
+	}`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-smtp-port", "2525"},
+		env(map[string]string{"REMINDER_LEAD_TIMES": "48h, 30m,0s", "REMINDERS_ENABLED": "true"}),
+	)
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Reminders.Enabled)
+	assert.Equal(t, []Duration{Duration(48 * time.Hour), Duration(30 * time.Minute), 0}, cfg.Reminders.LeadTimes)
+	assert.Equal(t, SMTPConfig{Host: "mail.example.com", Port: 2525, From: "tasks@example.com"}, cfg.Reminders.SMTP)
+
+	_, err = Load(nil, env(map[string]string{"REMINDERS_ENABLED": "sometimes"}))
+	assert.ErrorContains(t, err, "invalid REMINDERS_ENABLED")
+}
+
+func TestLoad_Webhooks(t *testing.T) {
+	path := writeConfigFile(t, `{"webhooks": {"max_attempts": 3, "backoff": "10s"}}`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-webhook-timeout", "5s"},
+		env(map[string]string{"WEBHOOK_MAX_BACKOFF": "10m"}),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, WebhooksConfig{
+		Interval:    Duration(5 * time.Second),
+		MaxAttempts: 3,
+		Backoff:     Duration(10 * time.Second),
+		MaxBackoff:  Duration(10 * time.Minute),
+		Timeout:     Duration(5 * time.Second),
+	}, cfg.Webhooks)
+
+	_, err = Load(nil, env(map[string]string{"WEBHOOK_MAX_ATTEMPTS": "many"}))
+	assert.ErrorContains(t, err, "invalid WEBHOOK_MAX_ATTEMPTS")
+}
+
+func TestLoad_Stream(t *testing.T) {
+	cfg, err := Load(nil, env(map[string]string{"STREAM_REPLAY_SIZE": "0"}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, StreamConfig{ReplaySize: 0}, cfg.Stream)
+
+	_, err = Load([]string{"-stream-replay-size", "lots"}, env(nil))
+	assert.ErrorContains(t, err, "invalid")
+}
+
+func TestLoad_Attachments(t *testing.T) {
+	cfg, err := Load([]string{"-attachment-max-size", "1048576"}, env(map[string]string{"ATTACHMENT_DIR": "/var/lib/task-manager/attachments"}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, AttachmentsConfig{Directory: "/var/lib/task-manager/attachments", MaxSize: 1 << 20}, cfg.Attachments)
+
+	_, err = Load(nil, env(map[string]string{"ATTACHMENT_MAX_SIZE": "10MB"}))
+	assert.ErrorContains(t, err, "invalid ATTACHMENT_MAX_SIZE")
+}
+
+func TestLoad_Permissions(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, Duration(30*time.Second), cfg.Permissions.CacheTTL)
+
+	cfg, err = Load([]string{"-permission-cache-ttl", "0s"}, env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, Duration(0), cfg.Permissions.CacheTTL)
+}
+
+func TestLoad_OIDC(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	assert.NoError(t, err)
+	assert.False(t, cfg.OIDC.Enabled())
+
+	cfg, err = Load([]string{"-oidc-role-mapping", "tm-admins=admin, tm-users=user"}, env(map[string]string{
+		"OIDC_ISSUER":       "https://login.example.com",
+		"OIDC_CLIENT_ID":    "task-manager",
+		"OIDC_REDIRECT_URL": "https://tasks.example.com/auth/oidc/callback",
+		"OIDC_SCOPES":       "openid, email, groups",
+		"OIDC_ROLE_CLAIM":   "groups",
+	}))
+	assert.NoError(t, err)
+	assert.True(t, cfg.OIDC.Enabled())
+	assert.Equal(t, OIDCConfig{
+		Issuer:        "https://login.example.com",
+		ClientID:      "task-manager",
+		RedirectURL:   "https://tasks.example.com/auth/oidc/callback",
+		Scopes:        []string{"openid", "email", "groups"},
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "groups",
+		RoleMapping:   map[string]string{"tm-admins": "admin", "tm-users": "user"},
+		Timeout:       Duration(10 * time.Second),
+	}, cfg.OIDC)
+
+	_, err = Load([]string{"-oidc-role-mapping", "tm-admins"}, env(nil))
+	assert.ErrorContains(t, err, "must be written as claim=role")
+}
+
+func TestLoad_Workflow(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.Workflow)
+	assert.Equal(t, domain.DefaultWorkflow(), cfg.TaskWorkflow())
+
+	path := writeConfigFile(t, `{"workflow": {"initial": "todo", "states": [{"name": "todo"}, {"name": "done", "category": "completed"}], "transitions": [{"from": "todo", "to": "done", "roles": ["admin"]}]}}`)
+	cfg, err = Load([]string{"-config", path}, env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Workflow{
+		Initial:     "todo",
+		States:      []domain.WorkflowState{{Name: "todo", Category: domain.CategoryOpen}, {Name: "done", Category: domain.CategoryCompleted}},
+		Transitions: []domain.WorkflowTransition{{From: "todo", To: "done", Roles: []string{"admin"}}},
+	}, cfg.TaskWorkflow())
+}
+
+func TestLoad_WorkflowFile(t *testing.T) {
+	path := writeConfigFile(t, `{"initial": "todo", "states": [{"name": "todo"}, {"name": "done", "category": "completed"}], "transitions": [{"from": "*", "to": "todo"}]}`)
+
+	cfg, err := Load(nil, env(map[string]string{"WORKFLOW_FILE": path}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"todo", "done"}, cfg.TaskWorkflow().StateNames())
+
+	_, err = Load(nil, env(map[string]string{"WORKFLOW_FILE": filepath.Join(t.TempDir(), "missing.json")}))
+	assert.ErrorContains(t, err, "invalid WORKFLOW_FILE")
+
+	invalid := writeConfigFile(t, `{"initial": "done", "states": [{"name": "done", "category": "completed"}]}`)
+	_, err = Load([]string{"-workflow-file", invalid}, env(nil))
+	assert.EqualError(t, err, "workflow initial state must be open")
+
+	unknown := writeConfigFile(t, `{"initial": "todo", "states": [{"name": "todo", "colour": "red"}]}`)
+	_, err = Load([]string{"-workflow-file", unknown}, env(nil))
+	assert.ErrorContains(t, err, "unknown field")
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, `{"storage": {"database": "from_file"}}`)
+
+	cfg, err := Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "from_file", cfg.Storage.Database)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		file     string
+		expected string
+	}{
+		{
+			name:     "missing config file",
+			args:     []string{"-config", filepath.Join(os.TempDir(), "does-not-exist.json")},
+			expected: "reading config file",
+		},
+		{
+			name:     "unknown field in config file",
+			file:     `{"server": {"port": 8080}}`,
+			expected: "unknown field",
+		},
+		{
+			name:     "invalid duration in env",
+			env:      map[string]string{"REQUEST_TIMEOUT": "soon"},
+			expected: "invalid REQUEST_TIMEOUT",
+		},
+		{
+			name:     "invalid duration flag",
+			args:     []string{"-jwt-expiry", "forever"},
+			expected: "invalid -jwt-expiry",
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"-port", "8080"},
+			expected: "flag provided but not defined",
+		},
+		{
+			name:     "default secret in production",
+			env:      map[string]string{"APP_ENV": "production"},
+			expected: "the default JWT secret cannot be used in production",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "-config", writeConfigFile(t, tt.file))
+			}
+
+			_, err := Load(args, env(tt.env))
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		expected string
+	}{
+		{
+			name:     "defaults",
+			modify:   func(cfg *Config) {},
+			expected: "",
+		},
+		{
+			name: "production with strong secret",
+			modify: func(cfg *Config) {
+				cfg.Environment = Production
+				cfg.JWT.Secret = "a-production-secret-of-32-chars!"
+			},
+			expected: "",
+		},
+		{
+			name:     "unknown environment",
+			modify:   func(cfg *Config) { cfg.Environment = "staging" },
+			expected: "environment must be either development or production",
+		},
+		{
+			name:     "empty address",
+			modify:   func(cfg *Config) { cfg.Server.Address = "" },
+			expected: "server address is required",
+		},
+		{
+			name:     "negative request timeout",
+			modify:   func(cfg *Config) { cfg.Server.RequestTimeout = Duration(-time.Second) },
+			expected: "request timeout must not be negative",
+		},
+		{
+			name:     "unknown storage backend",
+			modify:   func(cfg *Config) { cfg.Storage.Backend = "postgres" },
+			expected: "storage backend must be either mongo or memory",
+		},
+		{
+			name:     "mongo without URI",
+			modify:   func(cfg *Config) { cfg.Storage.MongoURI = "" },
+			expected: "mongo storage requires a URI and a database name",
+		},
+		{
+			name:     "empty JWT secret",
+			modify:   func(cfg *Config) { cfg.JWT.Secret = "" },
+			expected: "JWT secret is required",
+		},
+		{
+			name:     "zero JWT expiry",
+			modify:   func(cfg *Config) { cfg.JWT.Expiry = 0 },
+			expected: "JWT expiry must be positive",
+		},
+		{
+			name:     "refresh expiry not longer than JWT expiry",
+			modify:   func(cfg *Config) { cfg.JWT.RefreshExpiry = cfg.JWT.Expiry },
+			expected: "refresh token expiry must be longer than the JWT expiry",
+		},
+		{
+			name:     "zero shutdown timeout",
+			modify:   func(cfg *Config) { cfg.Server.ShutdownTimeout = 0 },
+			expected: "shutdown timeout must be positive",
+		},
+		{
+			name: "reminders disabled skip their checks",
+			modify: func(cfg *Config) {
+				cfg.Reminders.Enabled = false
+				cfg.Reminders.Notifier = "pager"
+			},
+			expected: "",
+		},
+		{
+			name:     "zero reminder interval",
+			modify:   func(cfg *Config) { cfg.Reminders.Interval = 0 },
+			expected: "reminder interval must be positive",
+		},
+		{
+			name:     "no reminder lead times",
+			modify:   func(cfg *Config) { cfg.Reminders.LeadTimes = nil },
+			expected: "reminders need at least one lead time",
+		},
+		{
+			name:     "negative reminder lead time",
+			modify:   func(cfg *Config) { cfg.Reminders.LeadTimes = []Duration{Duration(-time.Hour)} },
+			expected: "reminder lead times must not be negative",
+		},
+		{
+			name:     "unknown notifier",
+			modify:   func(cfg *Config) { cfg.Reminders.Notifier = "pager" },
+			expected: "reminder notifier must be either log or smtp",
+		},
+		{
+			name:     "smtp notifier without host",
+			modify:   func(cfg *Config) { cfg.Reminders.Notifier = "smtp" },
+			expected: "the smtp notifier requires a host and a sender address",
+		},
+		{
+			name: "smtp notifier with invalid port",
+			modify: func(cfg *Config) {
+				cfg.Reminders.Notifier = "smtp"
+				cfg.Reminders.SMTP = SMTPConfig{Host: "mail.example.com", Port: 0, From: "tasks@example.com"}
+			},
+			expected: "SMTP port must be between 1 and 65535",
+		},
+		{
+			name:     "zero webhook interval",
+			modify:   func(cfg *Config) { cfg.Webhooks.Interval = 0 },
+			expected: "webhook interval must be positive",
+		},
+		{
+			name:     "no webhook attempts",
+			modify:   func(cfg *Config) { cfg.Webhooks.MaxAttempts = 0 },
+			expected: "webhook max attempts must be at least 1",
+		},
+		{
+			name:     "zero webhook backoff",
+			modify:   func(cfg *Config) { cfg.Webhooks.Backoff = 0 },
+			expected: "webhook backoff must be positive",
+		},
+		{
+			name:     "webhook max backoff below backoff",
+			modify:   func(cfg *Config) { cfg.Webhooks.MaxBackoff = Duration(time.Second) },
+			expected: "webhook max backoff must not be shorter than the backoff",
+		},
+		{
+			name:     "webhook timeout too long",
+			modify:   func(cfg *Config) { cfg.Webhooks.Timeout = Duration(5 * time.Minute) },
+			expected: "webhook timeout must be positive and at most 1m0s",
+		},
+		{
+			name:     "negative stream replay size",
+			modify:   func(cfg *Config) { cfg.Stream.ReplaySize = -1 },
+			expected: "stream replay size must not be negative",
+		},
+		{
+			name:     "missing attachment directory",
+			modify:   func(cfg *Config) { cfg.Attachments.Directory = "" },
+			expected: "attachment directory is required",
+		},
+		{
+			name:     "zero attachment max size",
+			modify:   func(cfg *Config) { cfg.Attachments.MaxSize = 0 },
+			expected: "attachment max size must be positive",
+		},
+		{
+			name:     "negative permission cache TTL",
+			modify:   func(cfg *Config) { cfg.Permissions.CacheTTL = Duration(-time.Second) },
+			expected: "permission cache TTL must not be negative",
+		},
+		{
+			name: "oidc enabled",
+			modify: func(cfg *Config) {
+				cfg.OIDC = validOIDC()
+			},
+			expected: "",
+		},
+		{
+			name: "oidc issuer is not a URL",
+			modify: func(cfg *Config) {
+				cfg.OIDC = validOIDC()
+				cfg.OIDC.Issuer = "login.example.com"
+			},
+			expected: "the OIDC issuer must be an absolute URL without a query",
+		},
+		{
+			name: "oidc without client ID",
+			modify: func(cfg *Config) {
+				cfg.OIDC = validOIDC()
+				cfg.OIDC.ClientID = ""
+			},
+			expected: "OIDC requires a client ID and a redirect URL",
+		},
+		{
+			name: "oidc without openid scope",
+			modify: func(cfg *Config) {
+				cfg.OIDC = validOIDC()
+				cfg.OIDC.Scopes = []string{"email"}
+			},
+			expected: "the OIDC scopes must include openid",
+		},
+		{
+			name: "oidc role mapping without role claim",
+			modify: func(cfg *Config) {
+				cfg.OIDC = validOIDC()
+				cfg.OIDC.RoleClaim = ""
+			},
+			expected: "an OIDC role mapping requires a role claim",
+		},
+		{
+			name: "oidc role mapping to an invalid role",
+			modify: func(cfg *Config) {
+				cfg.OIDC = validOIDC()
+				cfg.OIDC.RoleMapping = map[string]string{"admins": "Admin!"}
+			},
+			expected: `OIDC role mapping for "admins": invalid role name "Admin!"`,
+		},
+		{
+			name: "oidc issuer over http in production",
+			modify: func(cfg *Config) {
+				cfg.Environment = Production
+				cfg.JWT.Secret = "a-production-secret-of-32-chars!"
+				cfg.OIDC = validOIDC()
+				cfg.OIDC.Issuer = "http://login.example.com"
+			},
+			expected: "the OIDC issuer must use https in production",
+		},
+		{
+			name: "short secret in production",
+			modify: func(cfg *Config) {
+				cfg.Environment = Production
+				cfg.JWT.Secret = "short"
+			},
+			expected: "JWT secret must be at least 32 characters in production",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func validOIDC() OIDCConfig {
+	cfg := Default().OIDC
+	cfg.Issuer = "https://login.example.com"
+	cfg.ClientID = "task-manager"
+	cfg.RedirectURL = "https://tasks.example.com/auth/oidc/callback"
+	cfg.RoleClaim = "groups"
+	cfg.RoleMapping = map[string]string{"tm-admins": "admin"}
+	return cfg
+}
@@ -1,14 +1,56 @@
 package controllers
 
 import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	domain "task-manager/Domain"
 	usecases "task-manager/Usecases"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
 )
 
+// streamHeartbeat is how often an idle task stream is written to, so proxies along
+// the way do not close it
+const streamHeartbeat = 15 * time.Second
+
+// streamWriteTimeout drops a WebSocket client that stops reading
+const streamWriteTimeout = 10 * time.Second
+
+// streamResync is sent instead of a backlog when the client may have missed events
+const streamResync = "resync"
+
+// maxImportSize bounds the body of a task import
+const maxImportSize = 10 << 20
+
+// multipartOverhead is how much larger than the file an upload body may be, to make
+// room for the multipart boundaries and headers
+const multipartOverhead = 64 << 10
+
+// oidcStateCookie binds a single sign-on login to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// oidcCookiePath limits the state cookie to the single sign-on routes
+const oidcCookiePath = "/auth/oidc"
+
+// exportContentTypes is the media type of each export format
+var exportContentTypes = map[string]string{
+	domain.FormatCSV:    "text/csv; charset=utf-8",
+	domain.FormatJSON:   "application/json; charset=utf-8",
+	domain.FormatNDJSON: "application/x-ndjson",
+}
+
 // ApiController interface
 type ApiController interface {
 	CreateTask(c *gin.Context)
@@ -16,20 +58,86 @@ type ApiController interface {
 	GetTasks(c *gin.Context)
 	UpdateTask(c *gin.Context)
 	DeleteTask(c *gin.Context)
+	GetTaskHistory(c *gin.Context)
+	SearchTasks(c *gin.Context)
+	StreamTasks(c *gin.Context)
+	BatchTasks(c *gin.Context)
+	ExportTasks(c *gin.Context)
+	ImportTasks(c *gin.Context)
+	GetSubtree(c *gin.Context)
+	GetDependencyGraph(c *gin.Context)
+	GetOccurrences(c *gin.Context)
+	GetNextStates(c *gin.Context)
+	GetComments(c *gin.Context)
+	AddComment(c *gin.Context)
+	UpdateComment(c *gin.Context)
+	DeleteComment(c *gin.Context)
+	GetAttachments(c *gin.Context)
+	UploadAttachment(c *gin.Context)
+	DownloadAttachment(c *gin.Context)
+	DeleteAttachment(c *gin.Context)
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	OIDCLogin(c *gin.Context)
+	OIDCCallback(c *gin.Context)
+	RefreshToken(c *gin.Context)
+	Logout(c *gin.Context)
 	PromoteUser(c *gin.Context)
+	GetAuditEntries(c *gin.Context)
+	VerifyAudit(c *gin.Context)
+	CreateWebhook(c *gin.Context)
+	GetWebhooks(c *gin.Context)
+	DeleteWebhook(c *gin.Context)
+	GetWebhookDeliveries(c *gin.Context)
+	ReplayWebhookDelivery(c *gin.Context)
+	GetCalendar(c *gin.Context)
+	CreateCalendarToken(c *gin.Context)
+	RevokeCalendarToken(c *gin.Context)
+	GetTags(c *gin.Context)
+	UpdateTag(c *gin.Context)
+	RenameTag(c *gin.Context)
+	MergeTags(c *gin.Context)
+	CreateProject(c *gin.Context)
+	GetProjects(c *gin.Context)
+	GetProject(c *gin.Context)
+	UpdateProject(c *gin.Context)
+	DeleteProject(c *gin.Context)
+	SetProjectMember(c *gin.Context)
+	RemoveProjectMember(c *gin.Context)
+	GetPermissions(c *gin.Context)
+	GetRoles(c *gin.Context)
+	GetRole(c *gin.Context)
+	CreateRole(c *gin.Context)
+	UpdateRole(c *gin.Context)
+	DeleteRole(c *gin.Context)
+	GetUserRoles(c *gin.Context)
+	SetUserRoles(c *gin.Context)
+	CreateAPIKey(c *gin.Context)
+	GetAPIKeys(c *gin.Context)
+	RevokeAPIKey(c *gin.Context)
 }
 
 // apiController struct
 type apiController struct {
-	taskUsecase usecases.TaskUsecase
-	userUsecase usecases.UserUsecase
+	taskUsecase       usecases.TaskUsecase
+	userUsecase       usecases.UserUsecase
+	auditUsecase      usecases.AuditUsecase
+	webhookUsecase    usecases.WebhookUsecase
+	taskStream        usecases.TaskStream
+	calendarUsecase   usecases.CalendarUsecase
+	tagUsecase        usecases.TagUsecase
+	commentUsecase    usecases.CommentUsecase
+	attachmentUsecase usecases.AttachmentUsecase
+	projectUsecase    usecases.ProjectUsecase
+	roleUsecase       usecases.RoleUsecase
+	apiKeyUsecase     usecases.APIKeyUsecase
+	// oidcUsecase is nil when single sign-on is not configured
+	oidcUsecase usecases.OIDCUsecase
 }
 
 // NewApiController creates a new api controller
-func NewApiController(taskUsecase usecases.TaskUsecase, userUsecase usecases.UserUsecase) ApiController {
-	return &apiController{taskUsecase, userUsecase}
+func NewApiController(taskUsecase usecases.TaskUsecase, userUsecase usecases.UserUsecase, auditUsecase usecases.AuditUsecase, webhookUsecase usecases.WebhookUsecase, taskStream usecases.TaskStream, calendarUsecase usecases.CalendarUsecase, tagUsecase usecases.TagUsecase, commentUsecase usecases.CommentUsecase, attachmentUsecase usecases.AttachmentUsecase, projectUsecase usecases.ProjectUsecase, roleUsecase usecases.RoleUsecase, apiKeyUsecase usecases.APIKeyUsecase, oidcUsecase usecases.OIDCUsecase) ApiController {
+	return &apiController{taskUsecase, userUsecase, auditUsecase, webhookUsecase, taskStream, calendarUsecase, tagUsecase, commentUsecase, attachmentUsecase, projectUsecase, roleUsecase, apiKeyUsecase, oidcUsecase}
 }
 
 // CreateTask creates a new task
@@ -41,63 +149,86 @@ func (c *apiController) CreateTask(ctx *gin.Context) {
 		return
 	}
 
-	err = c.taskUsecase.CreateTask(task)
+	created, err := c.taskUsecase.CreateTask(ctx.Request.Context(), identity(ctx), task)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "Task created successfully"})
+	ctx.Header("ETag", taskETag(created))
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Task created successfully", "task": created})
 }
 
 // GetTask retrieves a task by ID
 func (c *apiController) GetTask(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	task, err := c.taskUsecase.GetTask(id)
+	task, err := c.taskUsecase.GetTask(ctx.Request.Context(), identity(ctx), id)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
 	}
 
+	ctx.Header("ETag", taskETag(task))
 	ctx.JSON(http.StatusOK, task)
 }
 
-// GetTasks retrieves all tasks
+// GetTasks retrieves a filtered page of tasks
 func (c *apiController) GetTasks(ctx *gin.Context) {
-	tasks, err := c.taskUsecase.GetTasks()
+	query, err := parseTaskQuery(ctx)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.taskUsecase.GetTasks(ctx.Request.Context(), identity(ctx), query)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, tasks)
+	ctx.JSON(http.StatusOK, page)
 }
 
-// UpdateTask updates a task
+// UpdateTask updates a task. The If-Match header must carry the ETag the task was read
+// with, so an update based on an outdated copy fails instead of overwriting newer changes.
 func (c *apiController) UpdateTask(ctx *gin.Context) {
 	id := ctx.Param("id")
 
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch == "" {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return
+	}
+
+	version, err := parseTaskETag(ifMatch)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	task := domain.Task{}
-	err := ctx.BindJSON(&task)
+	err = ctx.BindJSON(&task)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = c.taskUsecase.UpdateTask(id, task)
+	updated, err := c.taskUsecase.UpdateTask(ctx.Request.Context(), identity(ctx), id, version, task)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
 		return
 
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Task updated successfully"})
+	ctx.Header("ETag", taskETag(updated))
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task updated successfully", "task": updated})
 }
 
 // DeleteTask deletes a task
 func (c *apiController) DeleteTask(ctx *gin.Context) {
 	id := ctx.Param("id")
-	err := c.taskUsecase.DeleteTask(id)
+	err := c.taskUsecase.DeleteTask(ctx.Request.Context(), identity(ctx), id)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
 		return
@@ -107,6 +238,375 @@ func (c *apiController) DeleteTask(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
 
+// GetTaskHistory retrieves every recorded version of a task
+func (c *apiController) GetTaskHistory(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	history, err := c.taskUsecase.GetTaskHistory(ctx.Request.Context(), identity(ctx), id)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"versions": history})
+}
+
+// GetSubtree retrieves a task with its subtasks nested under it
+func (c *apiController) GetSubtree(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	tree, err := c.taskUsecase.GetSubtree(ctx.Request.Context(), identity(ctx), id)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tree)
+}
+
+// GetDependencyGraph retrieves a task's dependency graph in topological order
+func (c *apiController) GetDependencyGraph(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	graph, err := c.taskUsecase.GetDependencyGraph(ctx.Request.Context(), identity(ctx), id)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, graph)
+}
+
+// GetOccurrences previews the next count due dates of a recurring task
+func (c *apiController) GetOccurrences(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	limit := 0
+	if count := ctx.Query("count"); count != "" {
+		var err error
+		if limit, err = strconv.Atoi(count); err != nil || limit < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "count must be a positive integer"})
+			return
+		}
+	}
+
+	occurrences, err := c.taskUsecase.GetOccurrences(ctx.Request.Context(), identity(ctx), id, limit)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"occurrences": occurrences})
+}
+
+// GetNextStates lists the statuses the caller may move a task to next
+func (c *apiController) GetNextStates(ctx *gin.Context) {
+	states, err := c.taskUsecase.GetNextStates(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"next_states": states})
+}
+
+// GetComments retrieves the comments on a task as threads, oldest first
+func (c *apiController) GetComments(ctx *gin.Context) {
+	comments, err := c.commentUsecase.GetComments(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// AddComment adds a comment to a task, as a reply if it names a parent_id
+func (c *apiController) AddComment(ctx *gin.Context) {
+	comment := domain.Comment{}
+	err := ctx.BindJSON(&comment)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := c.commentUsecase.AddComment(ctx.Request.Context(), identity(ctx), ctx.Param("id"), comment)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Comment added successfully", "comment": created})
+}
+
+// UpdateComment changes the body of a comment and marks it as edited
+func (c *apiController) UpdateComment(ctx *gin.Context) {
+	comment := domain.Comment{}
+	err := ctx.BindJSON(&comment)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := c.commentUsecase.UpdateComment(ctx.Request.Context(), identity(ctx), ctx.Param("id"), ctx.Param("comment_id"), comment)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully", "comment": updated})
+}
+
+// DeleteComment marks a comment as deleted
+func (c *apiController) DeleteComment(ctx *gin.Context) {
+	err := c.commentUsecase.DeleteComment(ctx.Request.Context(), identity(ctx), ctx.Param("id"), ctx.Param("comment_id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// GetAttachments lists the attachments of a task
+func (c *apiController) GetAttachments(ctx *gin.Context) {
+	attachments, err := c.attachmentUsecase.GetAttachments(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"attachments": attachments})
+}
+
+// UploadAttachment attaches the file in the "file" part of a multipart/form-data body to
+// a task. The body is streamed straight to the blob store rather than parsed into memory.
+func (c *apiController) UploadAttachment(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.attachmentUsecase.MaxSize()+multipartOverhead)
+	form, err := ctx.Request.MultipartReader()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The body must be multipart/form-data"})
+		return
+	}
+
+	var part *multipart.Part
+	for {
+		part, err = form.NextPart()
+		if err == io.EOF {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "The body must have a file part"})
+			return
+		}
+		if err != nil {
+			err = uploadError(err)
+			ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+		part.Close()
+	}
+	defer part.Close()
+
+	created, err := c.attachmentUsecase.UploadAttachment(ctx.Request.Context(), identity(ctx), ctx.Param("id"), part.FileName(), uploadReader{part})
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Attachment uploaded successfully", "attachment": created})
+}
+
+// DownloadAttachment streams the contents of an attachment. Its SHA-256 checksum is the
+// entity tag, so clients that already have the file get 304 Not Modified.
+func (c *apiController) DownloadAttachment(ctx *gin.Context) {
+	attachment, contents, err := c.attachmentUsecase.OpenAttachment(ctx.Request.Context(), identity(ctx), ctx.Param("id"), ctx.Param("attachment_id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer contents.Close()
+
+	etag := strconv.Quote(attachment.SHA256)
+	ctx.Header("ETag", etag)
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, contents, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// DeleteAttachment removes an attachment from a task
+func (c *apiController) DeleteAttachment(ctx *gin.Context) {
+	err := c.attachmentUsecase.DeleteAttachment(ctx.Request.Context(), identity(ctx), ctx.Param("id"), ctx.Param("attachment_id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// SearchTasks finds tasks matching the q search text, most relevant first
+func (c *apiController) SearchTasks(ctx *gin.Context) {
+	query := domain.TaskSearchQuery{Text: ctx.Query("q")}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	results, err := c.taskUsecase.SearchTasks(ctx.Request.Context(), identity(ctx), query)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// StreamTasks pushes task changes to the client as they happen, as Server-Sent
+// Events or over a WebSocket when the request asks for an upgrade
+func (c *apiController) StreamTasks(ctx *gin.Context) {
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+
+	// the caller's projects are looked up once, so membership changes apply from the next connection
+	access, err := c.projectUsecase.TaskAccess(ctx.Request.Context(), identity(ctx))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := c.taskStream.Subscribe(access, lastEventID)
+	defer subscription.Close()
+
+	if strings.EqualFold(ctx.GetHeader("Upgrade"), "websocket") {
+		streamWebSocket(ctx, subscription)
+		return
+	}
+
+	streamServerSentEvents(ctx, subscription)
+}
+
+// BatchTasks applies a list of task operations and reports the outcome of each
+func (c *apiController) BatchTasks(ctx *gin.Context) {
+	// decoded without binding so an invalid task fails its own operation, not the whole batch
+	var request domain.BatchRequest
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch: " + err.Error()})
+		return
+	}
+
+	results, err := c.taskUsecase.BatchTasks(ctx.Request.Context(), identity(ctx), request)
+	if err != nil && results == nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]gin.H, len(results))
+	failed := 0
+	for i, result := range results {
+		item := gin.H{"index": i, "op": result.Op}
+		if result.ID != "" {
+			item["id"] = result.ID
+		}
+
+		if result.Err != nil {
+			failed++
+			item["status"] = getStatusCode(result.Err)
+			item["error"] = result.Err.Error()
+		} else {
+			item["status"] = batchStatus(result.Op)
+			if result.Task != nil {
+				item["task"] = result.Task
+			}
+		}
+		items[i] = item
+	}
+
+	// an all-or-nothing batch that failed answers with the status of its first failure
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error(), "results": items})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"succeeded": len(results) - failed, "failed": failed, "results": items})
+}
+
+// ExportTasks streams every task the caller can see, filtered and sorted like GetTasks,
+// as CSV, JSON or NDJSON
+func (c *apiController) ExportTasks(ctx *gin.Context) {
+	query, err := parseTaskQuery(ctx)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	out := &exportWriter{ctx: ctx, format: ctx.DefaultQuery("format", domain.FormatJSON)}
+	err = c.taskUsecase.ExportTasks(ctx.Request.Context(), identity(ctx), query, out.format, out)
+	if err != nil && !out.started {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		// the response is under way, so the connection is dropped to keep the client
+		// from taking a partial export for a complete one
+		log.Printf("export: failed after the response started: %v", err)
+		if conn, _, err := ctx.Writer.Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+}
+
+// ImportTasks creates tasks from a CSV, JSON or NDJSON body and reports on every record.
+// The format is taken from the Content-Type header unless the query names it.
+func (c *apiController) ImportTasks(ctx *gin.Context) {
+	options := domain.ImportOptions{
+		Format:      ctx.Query("format"),
+		Mapping:     ctx.QueryMap("map"),
+		OnDuplicate: ctx.Query("on_duplicate"),
+	}
+
+	if options.Format == "" {
+		options.Format = importFormat(ctx.ContentType())
+	}
+
+	if dryRun := ctx.Query("dry_run"); dryRun != "" {
+		var err error
+		if options.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize)
+	report, err := c.taskUsecase.ImportTasks(ctx.Request.Context(), identity(ctx), body, options)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("An import must not be larger than %d MB", maxImportSize>>20)})
+		return
+	}
+
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
 // Register registers a new user
 func (c *apiController) Register(ctx *gin.Context) {
 	var registerInfo domain.User
@@ -117,7 +617,7 @@ func (c *apiController) Register(ctx *gin.Context) {
 		return
 	}
 
-	err = c.userUsecase.Register(registerInfo.Username, registerInfo.Password)
+	err = c.userUsecase.Register(ctx.Request.Context(), registerInfo.Username, registerInfo.Password)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
 		return
@@ -136,32 +636,903 @@ func (c *apiController) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := c.userUsecase.Login(loginInfo.Username, loginInfo.Password)
+	tokens, err := c.userUsecase.Login(ctx.Request.Context(), loginInfo.Username, loginInfo.Password)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Logged in successfully", "token": token})
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       "Logged in successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
 }
 
-// PromoteUser promotes a user to admin
-func (c *apiController) PromoteUser(ctx *gin.Context) {
-	var userInfo struct {
-		Username string `json:"username" binding:"required"`
+// OIDCLogin sends the browser to the identity provider to log in with single sign-on
+func (c *apiController) OIDCLogin(ctx *gin.Context) {
+	if c.oidcUsecase == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
 	}
-	err := ctx.BindJSON(&userInfo)
+
+	authURL, state, err := c.oidcUsecase.BeginLogin(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// the callback only completes the login in the browser that holds the state, so a
+	// callback link crafted by someone else cannot log the victim in as them
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, int(domain.OIDCLoginExpiry.Seconds()), oidcCookiePath, "", secure, true)
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes a single sign-on login when the identity provider sends the
+// browser back, answering with tokens as Login does
+func (c *apiController) OIDCCallback(ctx *gin.Context) {
+	if c.oidcUsecase == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+
+	if providerError := ctx.Query("error"); providerError != "" {
+		message := "Single sign-on failed: " + providerError
+		if description := ctx.Query("error_description"); description != "" {
+			message += ": " + description
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": message})
+		return
+	}
+
+	cookieState, _ := ctx.Cookie(oidcStateCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", false, true)
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(ctx.Query("state"))) != 1 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Single sign-on was not started in this browser, please try again"})
+		return
+	}
+
+	tokens, err := c.oidcUsecase.CompleteLogin(ctx.Request.Context(), ctx.Query("state"), ctx.Query("code"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       "Logged in successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token
+func (c *apiController) RefreshToken(ctx *gin.Context) {
+	var refreshInfo struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	err := ctx.BindJSON(&refreshInfo)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = c.userUsecase.PromoteUser(userInfo.Username)
+	tokens, err := c.userUsecase.RefreshToken(ctx.Request.Context(), refreshInfo.RefreshToken)
 	if err != nil {
 		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "User promoted successfully"})
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the current access token and, if given, its refresh token
+func (c *apiController) Logout(ctx *gin.Context) {
+	var logoutInfo struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&logoutInfo); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if identity(ctx).APIKeyID != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "API keys have no session to log out of; revoke the key instead"})
+		return
+	}
+
+	accessToken, ok := ctx.MustGet("access_token").(domain.AccessToken)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := c.userUsecase.Logout(ctx.Request.Context(), accessToken, logoutInfo.RefreshToken)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// PromoteUser promotes a user to admin
+func (c *apiController) PromoteUser(ctx *gin.Context) {
+	var userInfo struct {
+		Username string `json:"username" binding:"required"`
+	}
+	err := ctx.BindJSON(&userInfo)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.userUsecase.PromoteUser(ctx.Request.Context(), identity(ctx), userInfo.Username)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User promoted successfully"})
+}
+
+// GetAuditEntries retrieves a filtered page of the audit log
+func (c *apiController) GetAuditEntries(ctx *gin.Context) {
+	query, err := parseAuditQuery(ctx)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.auditUsecase.GetEntries(ctx.Request.Context(), query)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+// VerifyAudit checks the audit log's hash chain for tampering
+func (c *apiController) VerifyAudit(ctx *gin.Context) {
+	result, err := c.auditUsecase.Verify(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// CreateWebhook registers a webhook; the response is the only one that includes its secret
+func (c *apiController) CreateWebhook(ctx *gin.Context) {
+	webhook := domain.Webhook{}
+	err := ctx.BindJSON(&webhook)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := c.webhookUsecase.CreateWebhook(ctx.Request.Context(), identity(ctx), webhook)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Webhook created successfully", "webhook": created})
+}
+
+// GetWebhooks retrieves every webhook
+func (c *apiController) GetWebhooks(ctx *gin.Context) {
+	webhooks, err := c.webhookUsecase.GetWebhooks(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook deletes a webhook by ID
+func (c *apiController) DeleteWebhook(ctx *gin.Context) {
+	err := c.webhookUsecase.DeleteWebhook(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries retrieves the most recent webhook deliveries, newest first
+func (c *apiController) GetWebhookDeliveries(ctx *gin.Context) {
+	query := domain.DeliveryQuery{
+		WebhookID: ctx.Query("webhook_id"),
+		Status:    ctx.Query("status"),
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	deliveries, err := c.webhookUsecase.GetDeliveries(ctx.Request.Context(), query)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// ReplayWebhookDelivery queues a failed delivery to be sent again
+func (c *apiController) ReplayWebhookDelivery(ctx *gin.Context) {
+	replay, err := c.webhookUsecase.ReplayDelivery(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued for replay", "delivery": replay})
+}
+
+// GetCalendar serves the pending tasks of the token's user as an iCalendar file.
+// Calendar clients cannot send a bearer token, so the feed token comes in the URL.
+func (c *apiController) GetCalendar(ctx *gin.Context) {
+	feed, err := c.calendarUsecase.GetFeed(ctx.Request.Context(), ctx.Query("token"), ctx.Query("component"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	etag := strconv.Quote(feed.Tag)
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "private, no-cache")
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/calendar; charset=utf-8")
+	ctx.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	ctx.Status(http.StatusOK)
+	if err := c.calendarUsecase.WriteFeed(ctx.Writer, feed); err != nil {
+		log.Printf("calendar feed for %s stopped: %v", feed.Username, err)
+	}
+}
+
+// CreateCalendarToken issues a calendar feed token for the caller; the response is the
+// only one that includes it. Any token the caller had before stops working.
+func (c *apiController) CreateCalendarToken(ctx *gin.Context) {
+	token, err := c.calendarUsecase.CreateToken(ctx.Request.Context(), identity(ctx))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"token": token, "url": "/calendar.ics?token=" + token})
+}
+
+// RevokeCalendarToken removes the caller's calendar feed token
+func (c *apiController) RevokeCalendarToken(ctx *gin.Context) {
+	err := c.calendarUsecase.RevokeToken(ctx.Request.Context(), identity(ctx))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Calendar token revoked successfully"})
+}
+
+// GetTags lists the caller's tags with how many of their tasks carry each
+func (c *apiController) GetTags(ctx *gin.Context) {
+	tags, err := c.tagUsecase.GetTags(ctx.Request.Context(), identity(ctx))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// UpdateTag sets the color of one of the caller's tags
+func (c *apiController) UpdateTag(ctx *gin.Context) {
+	tag := domain.Tag{}
+	err := ctx.BindJSON(&tag)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := c.tagUsecase.UpdateTag(ctx.Request.Context(), identity(ctx), ctx.Param("name"), tag)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tag updated successfully", "tag": updated})
+}
+
+// RenameTag renames one of the caller's tags on every task carrying it
+func (c *apiController) RenameTag(ctx *gin.Context) {
+	tag := domain.Tag{}
+	err := ctx.BindJSON(&tag)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	renamed, err := c.tagUsecase.RenameTag(ctx.Request.Context(), identity(ctx), ctx.Param("name"), tag.Name)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tag renamed successfully", "tag": renamed})
+}
+
+// MergeTags replaces some of the caller's tags with another one on every task carrying them
+func (c *apiController) MergeTags(ctx *gin.Context) {
+	merge := domain.TagMerge{}
+	err := ctx.BindJSON(&merge)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	merged, err := c.tagUsecase.MergeTags(ctx.Request.Context(), identity(ctx), merge)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tags merged successfully", "tag": merged})
+}
+
+// CreateProject creates a project owned by the caller
+func (c *apiController) CreateProject(ctx *gin.Context) {
+	project := domain.Project{}
+	err := ctx.BindJSON(&project)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := c.projectUsecase.CreateProject(ctx.Request.Context(), identity(ctx), project)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Project created successfully", "project": created})
+}
+
+// GetProjects lists the projects the caller is a member of
+func (c *apiController) GetProjects(ctx *gin.Context) {
+	projects, err := c.projectUsecase.GetProjects(ctx.Request.Context(), identity(ctx))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"projects": projects})
+}
+
+// GetProject retrieves a project along with its members
+func (c *apiController) GetProject(ctx *gin.Context) {
+	project, err := c.projectUsecase.GetProject(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, project)
+}
+
+// UpdateProject changes the name and description of a project
+func (c *apiController) UpdateProject(ctx *gin.Context) {
+	project := domain.Project{}
+	err := ctx.BindJSON(&project)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := c.projectUsecase.UpdateProject(ctx.Request.Context(), identity(ctx), ctx.Param("id"), project)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Project updated successfully", "project": updated})
+}
+
+// DeleteProject deletes a project that has no tasks
+func (c *apiController) DeleteProject(ctx *gin.Context) {
+	err := c.projectUsecase.DeleteProject(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// SetProjectMember adds a user to a project with the role in the body, or changes their role
+func (c *apiController) SetProjectMember(ctx *gin.Context) {
+	var member struct {
+		Role string `json:"role"`
+	}
+	err := ctx.BindJSON(&member)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := c.projectUsecase.SetMember(ctx.Request.Context(), identity(ctx), ctx.Param("id"), ctx.Param("username"), member.Role)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member updated successfully", "project": project})
+}
+
+// RemoveProjectMember removes a user from a project
+func (c *apiController) RemoveProjectMember(ctx *gin.Context) {
+	project, err := c.projectUsecase.RemoveMember(ctx.Request.Context(), identity(ctx), ctx.Param("id"), ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member removed successfully", "project": project})
+}
+
+// GetPermissions lists every permission a role can grant
+func (c *apiController) GetPermissions(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"permissions": domain.Permissions})
+}
+
+// GetRoles lists the built-in and custom roles
+func (c *apiController) GetRoles(ctx *gin.Context) {
+	roles, err := c.roleUsecase.GetRoles(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GetRole retrieves a role and its permissions
+func (c *apiController) GetRole(ctx *gin.Context) {
+	role, err := c.roleUsecase.GetRole(ctx.Request.Context(), ctx.Param("name"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, role)
+}
+
+// CreateRole creates a custom role
+func (c *apiController) CreateRole(ctx *gin.Context) {
+	role := domain.Role{}
+	err := ctx.BindJSON(&role)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := c.roleUsecase.CreateRole(ctx.Request.Context(), identity(ctx), role)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Role created successfully", "role": created})
+}
+
+// UpdateRole changes the description and permissions of a custom role
+func (c *apiController) UpdateRole(ctx *gin.Context) {
+	role := domain.Role{}
+	err := ctx.BindJSON(&role)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := c.roleUsecase.UpdateRole(ctx.Request.Context(), identity(ctx), ctx.Param("name"), role)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "role": updated})
+}
+
+// DeleteRole deletes a custom role no user holds
+func (c *apiController) DeleteRole(ctx *gin.Context) {
+	err := c.roleUsecase.DeleteRole(ctx.Request.Context(), identity(ctx), ctx.Param("name"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// GetUserRoles retrieves the roles a user holds and the permissions they give
+func (c *apiController) GetUserRoles(ctx *gin.Context) {
+	grants, err := c.roleUsecase.GetUserRoles(ctx.Request.Context(), ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, grants)
+}
+
+// SetUserRoles replaces the roles a user holds with the ones in the body
+func (c *apiController) SetUserRoles(ctx *gin.Context) {
+	var body struct {
+		Roles []string `json:"roles"`
+	}
+	err := ctx.BindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	grants, err := c.roleUsecase.SetUserRoles(ctx.Request.Context(), identity(ctx), ctx.Param("username"), body.Roles)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Roles updated successfully", "roles": grants.Roles, "permissions": grants.Permissions})
+}
+
+// CreateAPIKey creates an API key for the caller; the key is only shown in this response
+func (c *apiController) CreateAPIKey(ctx *gin.Context) {
+	key := domain.APIKey{}
+	err := ctx.BindJSON(&key)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, secret, err := c.apiKeyUsecase.CreateKey(ctx.Request.Context(), identity(ctx), key)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "API key created successfully", "api_key": created, "key": secret})
+}
+
+// GetAPIKeys lists the caller's API keys, without the keys themselves
+func (c *apiController) GetAPIKeys(ctx *gin.Context) {
+	keys, err := c.apiKeyUsecase.GetKeys(ctx.Request.Context(), identity(ctx))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// RevokeAPIKey deletes one of the caller's API keys
+func (c *apiController) RevokeAPIKey(ctx *gin.Context) {
+	err := c.apiKeyUsecase.RevokeKey(ctx.Request.Context(), identity(ctx), ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// identity returns the user set by the Authenticate middleware
+func identity(ctx *gin.Context) domain.Identity {
+	identity, _ := ctx.Get("identity")
+	user, _ := identity.(domain.Identity)
+	return user
+}
+
+// streamServerSentEvents writes the subscription to the response as Server-Sent Events
+// until the client goes away or the subscription ends
+func streamServerSentEvents(ctx *gin.Context, subscription *usecases.TaskSubscription) {
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	if subscription.Resync {
+		writeServerSentEvent(ctx.Writer, "", streamResync, gin.H{"type": streamResync})
+	}
+
+	for _, event := range subscription.Backlog {
+		writeServerSentEvent(ctx.Writer, event.ID, event.Type, event)
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			writeServerSentEvent(ctx.Writer, event.ID, event.Type, event)
+		case <-heartbeat.C:
+			io.WriteString(ctx.Writer, ": heartbeat\n\n")
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// writeServerSentEvent writes one event; an event without an ID leaves the client's
+// Last-Event-ID as it was
+func writeServerSentEvent(w io.Writer, id, name string, data any) {
+	payload, _ := json.Marshal(data)
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+}
+
+// streamWebSocket upgrades the connection and sends the subscription as JSON text
+// messages until the client goes away or the subscription ends
+func streamWebSocket(ctx *gin.Context, subscription *usecases.TaskSubscription) {
+	server := websocket.Server{
+		// the stream is authenticated by the Authorization header rather than a cookie,
+		// so a page on another origin cannot open it on a user's behalf
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+
+			// clients have nothing to say; reading only notices when they go away
+			gone := make(chan struct{})
+			go func() {
+				defer close(gone)
+				var message []byte
+				for websocket.Message.Receive(conn, &message) == nil {
+				}
+			}()
+
+			send := func(codec websocket.Codec, message any) bool {
+				conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				return codec.Send(conn, message) == nil
+			}
+
+			if subscription.Resync && !send(websocket.JSON, gin.H{"type": streamResync}) {
+				return
+			}
+
+			for _, event := range subscription.Backlog {
+				if !send(websocket.JSON, event) {
+					return
+				}
+			}
+
+			heartbeat := time.NewTicker(streamHeartbeat)
+			defer heartbeat.Stop()
+
+			for {
+				select {
+				case <-gone:
+					return
+				case event, ok := <-subscription.Events:
+					if !ok || !send(websocket.JSON, event) {
+						return
+					}
+				case <-heartbeat.C:
+					if !send(pingCodec, nil) {
+						return
+					}
+				}
+			}
+		},
+	}
+
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// pingCodec sends an empty ping frame; clients answer it without involving the application
+var pingCodec = websocket.Codec{Marshal: func(any) ([]byte, byte, error) {
+	return nil, websocket.PingFrame, nil
+}}
+
+// exportWriter sets the headers of an export just before its first write, so an export
+// that fails to start can still answer with an error
+type exportWriter struct {
+	ctx     *gin.Context
+	format  string
+	started bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.ctx.Header("Content-Type", exportContentTypes[w.format])
+		w.ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, w.format))
+		w.ctx.Status(http.StatusOK)
+	}
+
+	return w.ctx.Writer.Write(p)
+}
+
+// importFormat returns the import format of a media type, or "" if there is none
+func importFormat(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return domain.FormatCSV
+	case "application/json":
+		return domain.FormatJSON
+	case "application/x-ndjson":
+		return domain.FormatNDJSON
+	}
+
+	return ""
+}
+
+// batchStatus returns the status a successful operation would have had as a request of its own
+func batchStatus(op string) int {
+	if op == domain.BatchCreate {
+		return http.StatusCreated
+	}
+
+	return http.StatusOK
+}
+
+// uploadReader reports a body that cannot be read as a client error
+type uploadReader struct {
+	r io.Reader
+}
+
+func (r uploadReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = uploadError(err)
+	}
+
+	return n, err
+}
+
+// uploadError turns an error reading an upload body into the error reported for it
+func uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &domain.TooLargeError{Message: "The upload is too large"}
+	}
+
+	return &domain.BadRequestError{Message: "Error reading the upload: " + err.Error()}
+}
+
+// taskETag returns the entity tag identifying the task's current version
+func taskETag(task domain.Task) string {
+	return strconv.Quote(strconv.FormatInt(task.Version, 10))
+}
+
+// etagMatches reports whether an If-None-Match header lists the entity tag. Weak
+// comparison is used, as RFC 9110 asks for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTaskETag reads the task version from an entity tag returned by taskETag
+func parseTaskETag(etag string) (int64, error) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return 0, &domain.BadRequestError{Message: "If-Match must be a single ETag returned for the task"}
+	}
+
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 10, 64)
+	if err != nil || version < 0 {
+		return 0, &domain.BadRequestError{Message: "If-Match must be a single ETag returned for the task"}
+	}
+
+	return version, nil
+}
+
+// parseTaskQuery reads the task list filters, sort order and paging options from the query string
+func parseTaskQuery(ctx *gin.Context) (domain.TaskQuery, error) {
+	query := domain.TaskQuery{
+		Status:      ctx.Query("status"),
+		ProjectID:   ctx.Query("project_id"),
+		TitlePrefix: ctx.Query("title_prefix"),
+		Cursor:      ctx.Query("cursor"),
+	}
+
+	// Every tag parameter has to match; the comma separated tags within one are alternatives
+	for _, tags := range ctx.QueryArray("tag") {
+		query.Tags = append(query.Tags, strings.Split(tags, ","))
+	}
+
+	if sort := ctx.Query("sort"); sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.SortBy = strings.TrimPrefix(sort, "-")
+	}
+
+	var err error
+	if dueAfter := ctx.Query("due_after"); dueAfter != "" {
+		if query.DueAfter, err = time.Parse(time.RFC3339, dueAfter); err != nil {
+			return query, &domain.BadRequestError{Message: "due_after must be an RFC3339 timestamp"}
+		}
+	}
+
+	if dueBefore := ctx.Query("due_before"); dueBefore != "" {
+		if query.DueBefore, err = time.Parse(time.RFC3339, dueBefore); err != nil {
+			return query, &domain.BadRequestError{Message: "due_before must be an RFC3339 timestamp"}
+		}
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			return query, &domain.BadRequestError{Message: "limit must be a positive integer"}
+		}
+	}
+
+	return query, nil
+}
+
+// parseAuditQuery reads the audit log filters and paging options from the query string
+func parseAuditQuery(ctx *gin.Context) (domain.AuditQuery, error) {
+	query := domain.AuditQuery{
+		Actor:      ctx.Query("actor"),
+		Action:     ctx.Query("action"),
+		TargetType: ctx.Query("target_type"),
+		TargetID:   ctx.Query("target_id"),
+		Cursor:     ctx.Query("cursor"),
+	}
+
+	var err error
+	if since := ctx.Query("since"); since != "" {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return query, &domain.BadRequestError{Message: "since must be an RFC3339 timestamp"}
+		}
+	}
+
+	if until := ctx.Query("until"); until != "" {
+		if query.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return query, &domain.BadRequestError{Message: "until must be an RFC3339 timestamp"}
+		}
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			return query, &domain.BadRequestError{Message: "limit must be a positive integer"}
+		}
+	}
+
+	return query, nil
 }
 
 func getStatusCode(err error) int {
@@ -176,6 +1547,16 @@ func getStatusCode(err error) int {
 		return http.StatusUnauthorized
 	case *domain.ForbiddenError:
 		return http.StatusForbidden
+	case *domain.ConflictError:
+		return http.StatusPreconditionFailed
+	case *domain.AlreadyExistsError:
+		return http.StatusConflict
+	case *domain.TimeoutError:
+		return http.StatusGatewayTimeout
+	case *domain.TooLargeError:
+		return http.StatusRequestEntityTooLarge
+	case *domain.NotAppliedError:
+		return http.StatusFailedDependency
 	default:
 		return http.StatusInternalServerError
 	}
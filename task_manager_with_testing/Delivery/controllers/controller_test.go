@@ -1,6 +1,11 @@
 package controllers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -8,85 +13,525 @@ import (
 	"time"
 
 	domain "task-manager/Domain"
+	usecases "task-manager/Usecases"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/websocket"
 )
 
 type MockTaskUsecase struct {
 	mock.Mock
 }
 
-func (m *MockTaskUsecase) CreateTask(task domain.Task) error {
-	args := m.Called(task)
-	return args.Error(0)
+func (m *MockTaskUsecase) CreateTask(ctx context.Context, identity domain.Identity, task domain.Task) (domain.Task, error) {
+	args := m.Called(ctx, identity, task)
+	return args.Get(0).(domain.Task), args.Error(1)
 }
 
-func (m *MockTaskUsecase) GetTask(id string) (domain.Task, error) {
-	args := m.Called(id)
+func (m *MockTaskUsecase) GetTask(ctx context.Context, identity domain.Identity, id string) (domain.Task, error) {
+	args := m.Called(ctx, identity, id)
 	return args.Get(0).(domain.Task), args.Error(1)
 }
 
-func (m *MockTaskUsecase) GetTasks() ([]domain.Task, error) {
-	args := m.Called()
-	return args.Get(0).([]domain.Task), args.Error(1)
+func (m *MockTaskUsecase) GetTasks(ctx context.Context, identity domain.Identity, query domain.TaskQuery) (domain.TaskPage, error) {
+	args := m.Called(ctx, identity, query)
+	return args.Get(0).(domain.TaskPage), args.Error(1)
 }	
 
-func (m *MockTaskUsecase) UpdateTask(id string, task domain.Task) error {
-	args := m.Called(id, task)
+func (m *MockTaskUsecase) UpdateTask(ctx context.Context, identity domain.Identity, id string, version int64, task domain.Task) (domain.Task, error) {
+	args := m.Called(ctx, identity, id, version, task)
+	return args.Get(0).(domain.Task), args.Error(1)
+}
+
+
+func (m *MockTaskUsecase) DeleteTask(ctx context.Context, identity domain.Identity, id string) error {
+	args := m.Called(ctx, identity, id)
 	return args.Error(0)
 }
 
+func (m *MockTaskUsecase) SearchTasks(ctx context.Context, identity domain.Identity, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error) {
+	args := m.Called(ctx, identity, query)
+	return args.Get(0).([]domain.TaskSearchResult), args.Error(1)
+}
+
+func (m *MockTaskUsecase) GetTaskHistory(ctx context.Context, identity domain.Identity, id string) ([]domain.TaskVersion, error) {
+	args := m.Called(ctx, identity, id)
+	return args.Get(0).([]domain.TaskVersion), args.Error(1)
+}
+
+func (m *MockTaskUsecase) GetSubtree(ctx context.Context, identity domain.Identity, id string) (domain.TaskNode, error) {
+	args := m.Called(ctx, identity, id)
+	return args.Get(0).(domain.TaskNode), args.Error(1)
+}
+
+func (m *MockTaskUsecase) GetDependencyGraph(ctx context.Context, identity domain.Identity, id string) (domain.TaskGraph, error) {
+	args := m.Called(ctx, identity, id)
+	return args.Get(0).(domain.TaskGraph), args.Error(1)
+}
+
+func (m *MockTaskUsecase) GetOccurrences(ctx context.Context, identity domain.Identity, id string, limit int) ([]time.Time, error) {
+	args := m.Called(ctx, identity, id, limit)
+	return args.Get(0).([]time.Time), args.Error(1)
+}
+
+func (m *MockTaskUsecase) BatchTasks(ctx context.Context, identity domain.Identity, request domain.BatchRequest) ([]domain.BatchResult, error) {
+	args := m.Called(ctx, identity, request)
+	results, _ := args.Get(0).([]domain.BatchResult)
+	return results, args.Error(1)
+}
 
-func (m *MockTaskUsecase) DeleteTask(id string) error {
-	args := m.Called(id)
+func (m *MockTaskUsecase) ExportTasks(ctx context.Context, identity domain.Identity, query domain.TaskQuery, format string, w io.Writer) error {
+	args := m.Called(ctx, identity, query, format, w)
 	return args.Error(0)
 }
 
+func (m *MockTaskUsecase) ImportTasks(ctx context.Context, identity domain.Identity, r io.Reader, options domain.ImportOptions) (domain.ImportReport, error) {
+	args := m.Called(ctx, identity, r, options)
+	return args.Get(0).(domain.ImportReport), args.Error(1)
+}
+
+func (m *MockTaskUsecase) GetNextStates(ctx context.Context, identity domain.Identity, id string) ([]string, error) {
+	args := m.Called(ctx, identity, id)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 type MockUserUsecase struct {
 	mock.Mock
 }
 
-func (m *MockUserUsecase) Register(username, password string) error {
-	args := m.Called(username, password)
+func (m *MockUserUsecase) Register(ctx context.Context, username, password string) error {
+	args := m.Called(ctx, username, password)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) Login(ctx context.Context, username, password string) (domain.TokenPair, error) {
+	args := m.Called(ctx, username, password)
+	return args.Get(0).(domain.TokenPair), args.Error(1)
+}
+
+func (m *MockUserUsecase) RefreshToken(ctx context.Context, refreshToken string) (domain.TokenPair, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.Get(0).(domain.TokenPair), args.Error(1)
+}
+
+func (m *MockUserUsecase) Logout(ctx context.Context, accessToken domain.AccessToken, refreshToken string) error {
+	args := m.Called(ctx, accessToken, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) PromoteUser(ctx context.Context, identity domain.Identity, username string) error {
+	args := m.Called(ctx, identity, username)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) LoginExternal(ctx context.Context, account domain.ExternalAccount) (domain.TokenPair, error) {
+	args := m.Called(ctx, account)
+	return args.Get(0).(domain.TokenPair), args.Error(1)
+}
+
+type MockAuditUsecase struct {
+	mock.Mock
+}
+
+func (m *MockAuditUsecase) GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(domain.AuditPage), args.Error(1)
+}
+
+func (m *MockAuditUsecase) Verify(ctx context.Context) (domain.AuditVerification, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(domain.AuditVerification), args.Error(1)
+}
+
+type MockWebhookUsecase struct {
+	mock.Mock
+}
+
+func (m *MockWebhookUsecase) Publish(ctx context.Context, event domain.TaskEvent) {
+	m.Called(ctx, event)
+}
+
+func (m *MockWebhookUsecase) CreateWebhook(ctx context.Context, identity domain.Identity, webhook domain.Webhook) (domain.Webhook, error) {
+	args := m.Called(ctx, identity, webhook)
+	return args.Get(0).(domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookUsecase) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookUsecase) DeleteWebhook(ctx context.Context, identity domain.Identity, id string) error {
+	args := m.Called(ctx, identity, id)
 	return args.Error(0)
 }
 
-func (m *MockUserUsecase) Login(username, password string) (string, error) {
-	args := m.Called(username, password)
+func (m *MockWebhookUsecase) GetDeliveries(ctx context.Context, query domain.DeliveryQuery) ([]domain.WebhookDelivery, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]domain.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookUsecase) ReplayDelivery(ctx context.Context, id string) (domain.WebhookDelivery, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookUsecase) DeliverDue(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+type MockCalendarUsecase struct {
+	mock.Mock
+}
+
+func (m *MockCalendarUsecase) CreateToken(ctx context.Context, identity domain.Identity) (string, error) {
+	args := m.Called(ctx, identity)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockUserUsecase) PromoteUser(username string) error {
-	args := m.Called(username)
+func (m *MockCalendarUsecase) RevokeToken(ctx context.Context, identity domain.Identity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockCalendarUsecase) GetFeed(ctx context.Context, token, component string) (domain.CalendarFeed, error) {
+	args := m.Called(ctx, token, component)
+	return args.Get(0).(domain.CalendarFeed), args.Error(1)
+}
+
+func (m *MockCalendarUsecase) WriteFeed(w io.Writer, feed domain.CalendarFeed) error {
+	args := m.Called(w, feed)
+	if write, ok := args.Get(0).(string); ok {
+		io.WriteString(w, write)
+	}
+	return args.Error(1)
+}
+
+type MockTagUsecase struct {
+	mock.Mock
+}
+
+func (m *MockTagUsecase) GetTags(ctx context.Context, identity domain.Identity) ([]domain.Tag, error) {
+	args := m.Called(ctx, identity)
+	return args.Get(0).([]domain.Tag), args.Error(1)
+}
+
+func (m *MockTagUsecase) UpdateTag(ctx context.Context, identity domain.Identity, name string, tag domain.Tag) (domain.Tag, error) {
+	args := m.Called(ctx, identity, name, tag)
+	return args.Get(0).(domain.Tag), args.Error(1)
+}
+
+func (m *MockTagUsecase) RenameTag(ctx context.Context, identity domain.Identity, name, newName string) (domain.Tag, error) {
+	args := m.Called(ctx, identity, name, newName)
+	return args.Get(0).(domain.Tag), args.Error(1)
+}
+
+func (m *MockTagUsecase) MergeTags(ctx context.Context, identity domain.Identity, merge domain.TagMerge) (domain.Tag, error) {
+	args := m.Called(ctx, identity, merge)
+	return args.Get(0).(domain.Tag), args.Error(1)
+}
+
+type MockCommentUsecase struct {
+	mock.Mock
+}
+
+func (m *MockCommentUsecase) AddComment(ctx context.Context, identity domain.Identity, taskID string, comment domain.Comment) (domain.Comment, error) {
+	args := m.Called(ctx, identity, taskID, comment)
+	return args.Get(0).(domain.Comment), args.Error(1)
+}
+
+func (m *MockCommentUsecase) GetComments(ctx context.Context, identity domain.Identity, taskID string) ([]domain.Comment, error) {
+	args := m.Called(ctx, identity, taskID)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockCommentUsecase) UpdateComment(ctx context.Context, identity domain.Identity, taskID, id string, comment domain.Comment) (domain.Comment, error) {
+	args := m.Called(ctx, identity, taskID, id, comment)
+	return args.Get(0).(domain.Comment), args.Error(1)
+}
+
+func (m *MockCommentUsecase) DeleteComment(ctx context.Context, identity domain.Identity, taskID, id string) error {
+	args := m.Called(ctx, identity, taskID, id)
+	return args.Error(0)
+}
+
+// MockAttachmentUsecase reads uploads in full and is called with their contents
+type MockAttachmentUsecase struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentUsecase) Publish(ctx context.Context, event domain.TaskEvent) {
+	m.Called(ctx, event)
+}
+
+func (m *MockAttachmentUsecase) UploadAttachment(ctx context.Context, identity domain.Identity, taskID, filename string, r io.Reader) (domain.Attachment, error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+
+	args := m.Called(ctx, identity, taskID, filename, string(contents))
+	return args.Get(0).(domain.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentUsecase) GetAttachments(ctx context.Context, identity domain.Identity, taskID string) ([]domain.Attachment, error) {
+	args := m.Called(ctx, identity, taskID)
+	return args.Get(0).([]domain.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentUsecase) OpenAttachment(ctx context.Context, identity domain.Identity, taskID, id string) (domain.Attachment, io.ReadCloser, error) {
+	args := m.Called(ctx, identity, taskID, id)
+	contents, _ := args.Get(1).(io.ReadCloser)
+	return args.Get(0).(domain.Attachment), contents, args.Error(2)
+}
+
+func (m *MockAttachmentUsecase) DeleteAttachment(ctx context.Context, identity domain.Identity, taskID, id string) error {
+	args := m.Called(ctx, identity, taskID, id)
+	return args.Error(0)
+}
+
+func (m *MockAttachmentUsecase) MaxSize() int64 {
+	return 1 << 20
+}
+
+type MockProjectUsecase struct {
+	mock.Mock
+}
+
+func (m *MockProjectUsecase) CreateProject(ctx context.Context, identity domain.Identity, project domain.Project) (domain.Project, error) {
+	args := m.Called(ctx, identity, project)
+	return args.Get(0).(domain.Project), args.Error(1)
+}
+
+func (m *MockProjectUsecase) GetProjects(ctx context.Context, identity domain.Identity) ([]domain.Project, error) {
+	args := m.Called(ctx, identity)
+	return args.Get(0).([]domain.Project), args.Error(1)
+}
+
+func (m *MockProjectUsecase) GetProject(ctx context.Context, identity domain.Identity, id string) (domain.Project, error) {
+	args := m.Called(ctx, identity, id)
+	return args.Get(0).(domain.Project), args.Error(1)
+}
+
+func (m *MockProjectUsecase) UpdateProject(ctx context.Context, identity domain.Identity, id string, project domain.Project) (domain.Project, error) {
+	args := m.Called(ctx, identity, id, project)
+	return args.Get(0).(domain.Project), args.Error(1)
+}
+
+func (m *MockProjectUsecase) DeleteProject(ctx context.Context, identity domain.Identity, id string) error {
+	args := m.Called(ctx, identity, id)
+	return args.Error(0)
+}
+
+func (m *MockProjectUsecase) SetMember(ctx context.Context, identity domain.Identity, id, username, role string) (domain.Project, error) {
+	args := m.Called(ctx, identity, id, username, role)
+	return args.Get(0).(domain.Project), args.Error(1)
+}
+
+func (m *MockProjectUsecase) RemoveMember(ctx context.Context, identity domain.Identity, id, username string) (domain.Project, error) {
+	args := m.Called(ctx, identity, id, username)
+	return args.Get(0).(domain.Project), args.Error(1)
+}
+
+func (m *MockProjectUsecase) TaskAccess(ctx context.Context, identity domain.Identity) (domain.TaskAccess, error) {
+	args := m.Called(ctx, identity)
+	return args.Get(0).(domain.TaskAccess), args.Error(1)
+}
+
+func (m *MockProjectUsecase) AssignLegacyTasks(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockRoleUsecase struct {
+	mock.Mock
+}
+
+func (m *MockRoleUsecase) GetRoles(ctx context.Context) ([]domain.Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Role), args.Error(1)
+}
+
+func (m *MockRoleUsecase) GetRole(ctx context.Context, name string) (domain.Role, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(domain.Role), args.Error(1)
+}
+
+func (m *MockRoleUsecase) CreateRole(ctx context.Context, identity domain.Identity, role domain.Role) (domain.Role, error) {
+	args := m.Called(ctx, identity, role)
+	return args.Get(0).(domain.Role), args.Error(1)
+}
+
+func (m *MockRoleUsecase) UpdateRole(ctx context.Context, identity domain.Identity, name string, role domain.Role) (domain.Role, error) {
+	args := m.Called(ctx, identity, name, role)
+	return args.Get(0).(domain.Role), args.Error(1)
+}
+
+func (m *MockRoleUsecase) DeleteRole(ctx context.Context, identity domain.Identity, name string) error {
+	args := m.Called(ctx, identity, name)
+	return args.Error(0)
+}
+
+func (m *MockRoleUsecase) GetUserRoles(ctx context.Context, username string) (domain.Grants, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(domain.Grants), args.Error(1)
+}
+
+func (m *MockRoleUsecase) SetUserRoles(ctx context.Context, identity domain.Identity, username string, roles []string) (domain.Grants, error) {
+	args := m.Called(ctx, identity, username, roles)
+	return args.Get(0).(domain.Grants), args.Error(1)
+}
+
+type MockAPIKeyUsecase struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyUsecase) CreateKey(ctx context.Context, identity domain.Identity, key domain.APIKey) (domain.APIKey, string, error) {
+	args := m.Called(ctx, identity, key)
+	return args.Get(0).(domain.APIKey), args.String(1), args.Error(2)
+}
+
+func (m *MockAPIKeyUsecase) GetKeys(ctx context.Context, identity domain.Identity) ([]domain.APIKey, error) {
+	args := m.Called(ctx, identity)
+	return args.Get(0).([]domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyUsecase) RevokeKey(ctx context.Context, identity domain.Identity, id string) error {
+	args := m.Called(ctx, identity, id)
 	return args.Error(0)
 }
 
+func (m *MockAPIKeyUsecase) AuthenticateAPIKey(ctx context.Context, key string) (domain.Identity, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(domain.Identity), args.Error(1)
+}
+
+type MockOIDCUsecase struct {
+	mock.Mock
+}
+
+func (m *MockOIDCUsecase) BeginLogin(ctx context.Context) (string, string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOIDCUsecase) CompleteLogin(ctx context.Context, state, code string) (domain.TokenPair, error) {
+	args := m.Called(ctx, state, code)
+	return args.Get(0).(domain.TokenPair), args.Error(1)
+}
+
+var testAccessToken = domain.AccessToken{Token: "access", ID: "token-id", Username: "testuser"}
+
+var testIdentity = domain.Identity{UserID: "user-id", Username: "testuser", Roles: []string{"user"}}
+
 type ApiControllerTestSuite struct {
 	suite.Suite
-	taskUsecase *MockTaskUsecase
-	userUsecase *MockUserUsecase
-	controller  ApiController
-	router      *gin.Engine
+	taskUsecase     *MockTaskUsecase
+	userUsecase     *MockUserUsecase
+	auditUsecase    *MockAuditUsecase
+	webhookUsecase  *MockWebhookUsecase
+	calendarUsecase *MockCalendarUsecase
+	tagUsecase      *MockTagUsecase
+	commentUsecase  *MockCommentUsecase
+	attachments     *MockAttachmentUsecase
+	projectUsecase  *MockProjectUsecase
+	roleUsecase     *MockRoleUsecase
+	apiKeyUsecase   *MockAPIKeyUsecase
+	oidcUsecase     *MockOIDCUsecase
+	taskStream      usecases.TaskStream
+	controller      ApiController
+	router          *gin.Engine
 }
 
 func (suite *ApiControllerTestSuite) SetupTest() {
 	suite.taskUsecase = new(MockTaskUsecase)
 	suite.userUsecase = new(MockUserUsecase)
-	suite.controller = NewApiController(suite.taskUsecase, suite.userUsecase)
+	suite.auditUsecase = new(MockAuditUsecase)
+	suite.webhookUsecase = new(MockWebhookUsecase)
+	suite.calendarUsecase = new(MockCalendarUsecase)
+	suite.tagUsecase = new(MockTagUsecase)
+	suite.commentUsecase = new(MockCommentUsecase)
+	suite.attachments = new(MockAttachmentUsecase)
+	suite.projectUsecase = new(MockProjectUsecase)
+	suite.roleUsecase = new(MockRoleUsecase)
+	suite.apiKeyUsecase = new(MockAPIKeyUsecase)
+	suite.oidcUsecase = new(MockOIDCUsecase)
+	suite.taskStream = usecases.NewTaskStream(8)
+	suite.controller = NewApiController(suite.taskUsecase, suite.userUsecase, suite.auditUsecase, suite.webhookUsecase, suite.taskStream, suite.calendarUsecase, suite.tagUsecase, suite.commentUsecase, suite.attachments, suite.projectUsecase, suite.roleUsecase, suite.apiKeyUsecase, suite.oidcUsecase)
 	suite.router = gin.Default()
+	suite.router.Use(func(ctx *gin.Context) {
+		ctx.Set("identity", testIdentity)
+	})
 
 	// Register routes
 	suite.router.POST("/tasks", suite.controller.CreateTask)
+	suite.router.POST("/tasks/batch", suite.controller.BatchTasks)
+	suite.router.POST("/tasks/import", suite.controller.ImportTasks)
+	suite.router.GET("/tasks/export", suite.controller.ExportTasks)
 	suite.router.GET("/tasks/:id", suite.controller.GetTask)
 	suite.router.GET("/tasks", suite.controller.GetTasks)
+	suite.router.GET("/tasks/search", suite.controller.SearchTasks)
+	suite.router.GET("/tasks/stream", suite.controller.StreamTasks)
 	suite.router.PUT("/tasks/:id", suite.controller.UpdateTask)
 	suite.router.DELETE("/tasks/:id", suite.controller.DeleteTask)
+	suite.router.GET("/tasks/:id/history", suite.controller.GetTaskHistory)
+	suite.router.GET("/tasks/:id/subtree", suite.controller.GetSubtree)
+	suite.router.GET("/tasks/:id/dependencies", suite.controller.GetDependencyGraph)
+	suite.router.GET("/tasks/:id/occurrences", suite.controller.GetOccurrences)
+	suite.router.GET("/tasks/:id/transitions", suite.controller.GetNextStates)
+	suite.router.GET("/tasks/:id/comments", suite.controller.GetComments)
+	suite.router.POST("/tasks/:id/comments", suite.controller.AddComment)
+	suite.router.PUT("/tasks/:id/comments/:comment_id", suite.controller.UpdateComment)
+	suite.router.DELETE("/tasks/:id/comments/:comment_id", suite.controller.DeleteComment)
+	suite.router.GET("/tasks/:id/attachments", suite.controller.GetAttachments)
+	suite.router.POST("/tasks/:id/attachments", suite.controller.UploadAttachment)
+	suite.router.GET("/tasks/:id/attachments/:attachment_id", suite.controller.DownloadAttachment)
+	suite.router.DELETE("/tasks/:id/attachments/:attachment_id", suite.controller.DeleteAttachment)
 	suite.router.POST("/register", suite.controller.Register)
 	suite.router.POST("/login", suite.controller.Login)
+	suite.router.GET("/auth/oidc/login", suite.controller.OIDCLogin)
+	suite.router.GET("/auth/oidc/callback", suite.controller.OIDCCallback)
+	suite.router.POST("/token/refresh", suite.controller.RefreshToken)
+	suite.router.POST("/logout", func(ctx *gin.Context) {
+		ctx.Set("access_token", testAccessToken)
+	}, suite.controller.Logout)
 	suite.router.POST("/promote", suite.controller.PromoteUser)
+	suite.router.GET("/audit", suite.controller.GetAuditEntries)
+	suite.router.GET("/audit/verify", suite.controller.VerifyAudit)
+	suite.router.POST("/webhooks", suite.controller.CreateWebhook)
+	suite.router.GET("/webhooks", suite.controller.GetWebhooks)
+	suite.router.DELETE("/webhooks/:id", suite.controller.DeleteWebhook)
+	suite.router.GET("/webhooks/deliveries", suite.controller.GetWebhookDeliveries)
+	suite.router.POST("/webhooks/deliveries/:id/replay", suite.controller.ReplayWebhookDelivery)
+	suite.router.GET("/calendar.ics", suite.controller.GetCalendar)
+	suite.router.POST("/calendar/token", suite.controller.CreateCalendarToken)
+	suite.router.DELETE("/calendar/token", suite.controller.RevokeCalendarToken)
+	suite.router.GET("/tags", suite.controller.GetTags)
+	suite.router.PUT("/tags/:name", suite.controller.UpdateTag)
+	suite.router.POST("/tags/:name/rename", suite.controller.RenameTag)
+	suite.router.POST("/tags/merge", suite.controller.MergeTags)
+	suite.router.GET("/projects", suite.controller.GetProjects)
+	suite.router.POST("/projects", suite.controller.CreateProject)
+	suite.router.GET("/projects/:id", suite.controller.GetProject)
+	suite.router.PUT("/projects/:id", suite.controller.UpdateProject)
+	suite.router.DELETE("/projects/:id", suite.controller.DeleteProject)
+	suite.router.PUT("/projects/:id/members/:username", suite.controller.SetProjectMember)
+	suite.router.DELETE("/projects/:id/members/:username", suite.controller.RemoveProjectMember)
+	suite.router.GET("/permissions", suite.controller.GetPermissions)
+	suite.router.GET("/roles", suite.controller.GetRoles)
+	suite.router.POST("/roles", suite.controller.CreateRole)
+	suite.router.GET("/roles/:name", suite.controller.GetRole)
+	suite.router.PUT("/roles/:name", suite.controller.UpdateRole)
+	suite.router.DELETE("/roles/:name", suite.controller.DeleteRole)
+	suite.router.GET("/users/:username/roles", suite.controller.GetUserRoles)
+	suite.router.PUT("/users/:username/roles", suite.controller.SetUserRoles)
+	suite.router.POST("/api-keys", suite.controller.CreateAPIKey)
+	suite.router.GET("/api-keys", suite.controller.GetAPIKeys)
+	suite.router.DELETE("/api-keys/:id", suite.controller.RevokeAPIKey)
 }
 
 func TestApiControllerTestSuite(t *testing.T) {
@@ -96,7 +541,7 @@ func TestApiControllerTestSuite(t *testing.T) {
 func (suite *ApiControllerTestSuite) TestCreateTask_Success() {
 	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
 	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
-	suite.taskUsecase.On("CreateTask", task).Return(nil)
+	suite.taskUsecase.On("CreateTask", mock.Anything, testIdentity, task).Return(domain.Task{ID: "1", Title: "Test Task", DueDate: dueDate, Status: "pending"}, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
@@ -104,6 +549,7 @@ func (suite *ApiControllerTestSuite) TestCreateTask_Success() {
 
 	assert.Equal(suite.T(), http.StatusCreated, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Task created successfully")
+	assert.Contains(suite.T(), w.Body.String(), `"id":"1"`)
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
@@ -114,13 +560,13 @@ func (suite *ApiControllerTestSuite) TestCreateTask_BadRequest() {
 
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Key: 'Task.Title' Error:Field validation for 'Title' failed on the 'required' tag")
-	suite.taskUsecase.AssertNotCalled(suite.T(), "CreateTask", mock.Anything)
+	suite.taskUsecase.AssertNotCalled(suite.T(), "CreateTask", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (suite *ApiControllerTestSuite) TestCreateTask_Error() {
 	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
 	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
-	suite.taskUsecase.On("CreateTask", task).Return(&domain.InternalServerError{Message: "Internal server error"})
+	suite.taskUsecase.On("CreateTask", mock.Anything, testIdentity, task).Return(domain.Task{}, &domain.InternalServerError{Message: "Internal server error"})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
@@ -132,8 +578,8 @@ func (suite *ApiControllerTestSuite) TestCreateTask_Error() {
 }
 
 func (suite *ApiControllerTestSuite) TestGetTask_Success() {
-	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"}
-	suite.taskUsecase.On("GetTask", "1").Return(task, nil)
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending", Version: 3}
+	suite.taskUsecase.On("GetTask", mock.Anything, testIdentity, "1").Return(task, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/tasks/1", nil)
@@ -141,11 +587,12 @@ func (suite *ApiControllerTestSuite) TestGetTask_Success() {
 
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Test Task")
+	assert.Equal(suite.T(), `"3"`, w.Header().Get("ETag"))
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
 func (suite *ApiControllerTestSuite) TestGetTask_NotFound() {
-	suite.taskUsecase.On("GetTask", "1").Return(domain.Task{}, &domain.NotFoundError{Message: "Task not found"})
+	suite.taskUsecase.On("GetTask", mock.Anything, testIdentity, "1").Return(domain.Task{}, &domain.NotFoundError{Message: "Task not found"})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/tasks/1", nil)
@@ -157,7 +604,7 @@ func (suite *ApiControllerTestSuite) TestGetTask_NotFound() {
 }
 
 func (suite *ApiControllerTestSuite) TestGetTask_Error() {
-	suite.taskUsecase.On("GetTask", "1").Return(domain.Task{}, &domain.InternalServerError{Message: "Internal server error"})
+	suite.taskUsecase.On("GetTask", mock.Anything, testIdentity, "1").Return(domain.Task{}, &domain.InternalServerError{Message: "Internal server error"})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/tasks/1", nil)
@@ -173,7 +620,7 @@ func (suite *ApiControllerTestSuite) TestGetTasks_Success() {
 		{Title: "Test Task 1", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"},
 		{Title: "Test Task 2", DueDate: time.Now().Add(48 * time.Hour), Status: "completed"},
 	}
-	suite.taskUsecase.On("GetTasks").Return(tasks, nil)
+	suite.taskUsecase.On("GetTasks", mock.Anything, testIdentity, domain.TaskQuery{}).Return(domain.TaskPage{Tasks: tasks, NextCursor: "abc"}, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/tasks", nil)
@@ -182,52 +629,62 @@ func (suite *ApiControllerTestSuite) TestGetTasks_Success() {
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Test Task 1")
 	assert.Contains(suite.T(), w.Body.String(), "Test Task 2")
+	assert.Contains(suite.T(), w.Body.String(), `"next_cursor":"abc"`)
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestGetTasks_Error() {
-	suite.taskUsecase.On("GetTasks").Return([]domain.Task{}, &domain.InternalServerError{Message: "Internal server error"})
+func (suite *ApiControllerTestSuite) TestGetTasks_WithQuery() {
+	dueAfter, _ := time.Parse(time.RFC3339, "2024-01-01T00:00:00Z")
+	dueBefore, _ := time.Parse(time.RFC3339, "2024-02-01T00:00:00Z")
+	query := domain.TaskQuery{
+		Status:      "pending",
+		ProjectID:   "p1",
+		DueAfter:    dueAfter,
+		DueBefore:   dueBefore,
+		TitlePrefix: "Sprint",
+		SortBy:      "title",
+		Descending:  true,
+		Cursor:      "abc",
+		Limit:       10,
+	}
+	suite.taskUsecase.On("GetTasks", mock.Anything, testIdentity, query).Return(domain.TaskPage{Tasks: []domain.Task{}}, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/tasks", nil)
+	req, _ := http.NewRequest("GET", "/tasks?status=pending&project_id=p1&due_after=2024-01-01T00:00:00Z&due_before=2024-02-01T00:00:00Z&title_prefix=Sprint&sort=-title&cursor=abc&limit=10", nil)
 	suite.router.ServeHTTP(w, req)
 
-	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Internal server error")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"tasks":[]`)
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestUpdateTask_Success() {
-	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
-	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
-	suite.taskUsecase.On("UpdateTask", "1", task).Return(nil)
+func (suite *ApiControllerTestSuite) TestGetTasks_TagFilter() {
+	query := domain.TaskQuery{Tags: [][]string{{"home", "work"}, {"urgent"}}}
+	suite.taskUsecase.On("GetTasks", mock.Anything, testIdentity, query).Return(domain.TaskPage{Tasks: []domain.Task{}}, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+	req, _ := http.NewRequest("GET", "/tasks?tag=home,work&tag=urgent", nil)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Task updated successfully")
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestUpdateTask_BadRequest() {
+func (suite *ApiControllerTestSuite) TestGetTasks_InvalidQuery() {
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": ""}`))
+	req, _ := http.NewRequest("GET", "/tasks?due_after=tomorrow", nil)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Key: 'Task.Title' Error:Field validation for 'Title' failed on the 'required' tag")
-	suite.taskUsecase.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything)
+	assert.Contains(suite.T(), w.Body.String(), "due_after must be an RFC3339 timestamp")
+	suite.taskUsecase.AssertNotCalled(suite.T(), "GetTasks", mock.Anything, mock.Anything, mock.Anything)
 }
 
-func (suite *ApiControllerTestSuite) TestUpdateTask_Error() {
-	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
-	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
-	suite.taskUsecase.On("UpdateTask", "1", task).Return(&domain.InternalServerError{Message: "Internal server error"})
-	
+func (suite *ApiControllerTestSuite) TestGetTasks_Error() {
+	suite.taskUsecase.On("GetTasks", mock.Anything, testIdentity, domain.TaskQuery{}).Return(domain.TaskPage{}, &domain.InternalServerError{Message: "Internal server error"})
+
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+	req, _ := http.NewRequest("GET", "/tasks", nil)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
@@ -235,91 +692,343 @@ func (suite *ApiControllerTestSuite) TestUpdateTask_Error() {
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestDeleteTask_Success() {
-	suite.taskUsecase.On("DeleteTask", "1").Return(nil)
+func (suite *ApiControllerTestSuite) TestGetTasks_Timeout() {
+	suite.taskUsecase.On("GetTasks", mock.Anything, testIdentity, domain.TaskQuery{}).Return(domain.TaskPage{}, &domain.TimeoutError{Message: "Request timed out"})
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("DELETE", "/tasks/1", nil)
+	req, _ := http.NewRequest("GET", "/tasks", nil)
 	suite.router.ServeHTTP(w, req)
 
-	assert.Equal(suite.T(), http.StatusOK, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Task deleted successfully")
+	assert.Equal(suite.T(), http.StatusGatewayTimeout, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Request timed out")
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestDeleteTask_Error() {
-	suite.taskUsecase.On("DeleteTask", "1").Return(&domain.InternalServerError{Message: "Internal server error"})
+func (suite *ApiControllerTestSuite) TestUpdateTask_Success() {
+	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
+	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
+	updated := task
+	updated.ID = "1"
+	updated.Version = 4
+	suite.taskUsecase.On("UpdateTask", mock.Anything, testIdentity, "1", int64(3), task).Return(updated, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("DELETE", "/tasks/1", nil)
+	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+	req.Header.Set("If-Match", `"3"`)
 	suite.router.ServeHTTP(w, req)
 
-	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Internal server error")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Task updated successfully")
+	assert.Equal(suite.T(), `"4"`, w.Header().Get("ETag"))
 	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestRegister_Success() {
-	suite.userUsecase.On("Register", "testuser", "password").Return(nil)
+func (suite *ApiControllerTestSuite) TestUpdateTask_MissingIfMatch() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusPreconditionRequired, w.Code)
+	suite.taskUsecase.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestUpdateTask_InvalidIfMatch() {
+	for _, ifMatch := range []string{"3", `W/"3"`, `"3", "4"`, `"abc"`, "*"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+		req.Header.Set("If-Match", ifMatch)
+		suite.router.ServeHTTP(w, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, ifMatch)
+	}
+	suite.taskUsecase.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestUpdateTask_PreconditionFailed() {
+	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
+	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
+	suite.taskUsecase.On("UpdateTask", mock.Anything, testIdentity, "1", int64(2), task).Return(domain.Task{}, &domain.ConflictError{Message: "Task has been modified since it was read"})
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+	req.Header.Set("If-Match", `"2"`)
 	suite.router.ServeHTTP(w, req)
 
-	assert.Equal(suite.T(), http.StatusCreated, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "User registered successfully")
-	suite.userUsecase.AssertExpectations(suite.T())
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Task has been modified since it was read")
+	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestRegister_BadRequest() {
+func (suite *ApiControllerTestSuite) TestUpdateTask_BadRequest() {
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": ""}`))
+	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": ""}`))
+	req.Header.Set("If-Match", `"1"`)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Key: 'User.Username' Error:Field validation for 'Username' failed on the 'required' tag")
-	suite.userUsecase.AssertNotCalled(suite.T(), "Register", mock.Anything, mock.Anything)
+	assert.Contains(suite.T(), w.Body.String(), "Key: 'Task.Title' Error:Field validation for 'Title' failed on the 'required' tag")
+	suite.taskUsecase.AssertNotCalled(suite.T(), "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
-func (suite *ApiControllerTestSuite) TestRegister_Error() {
-	suite.userUsecase.On("Register", "testuser", "password").Return(&domain.InternalServerError{Message: "Internal server error"})
-
+func (suite *ApiControllerTestSuite) TestUpdateTask_Error() {
+	dueDate, _ := time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")
+	task := domain.Task{Title: "Test Task", DueDate: dueDate, Status: "pending"}
+	suite.taskUsecase.On("UpdateTask", mock.Anything, testIdentity, "1", int64(1), task).Return(domain.Task{}, &domain.InternalServerError{Message: "Internal server error"})
+	
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	req, _ := http.NewRequest("PUT", "/tasks/1", strings.NewReader(`{"title": "Test Task", "due_date": "2021-01-01T00:00:00Z", "status": "pending"}`))
+	req.Header.Set("If-Match", `"1"`)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Internal server error")
-	suite.userUsecase.AssertExpectations(suite.T())
+	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestLogin_Success() {
-	suite.userUsecase.On("Login", "testuser", "password").Return("token", nil)
-	
+func (suite *ApiControllerTestSuite) TestSearchTasks() {
+	results := []domain.TaskSearchResult{{Task: domain.Task{ID: "1", Title: "Sprint review"}, Score: 1.5, Snippet: "<mark>Sprint</mark> review"}}
+	suite.taskUsecase.On("SearchTasks", mock.Anything, testIdentity, domain.TaskSearchQuery{Text: `"sprint review"`, Limit: 5}).Return(results, nil)
+
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	req, _ := http.NewRequest("GET", `/tasks/search?q=%22sprint+review%22&limit=5`, nil)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "token")
-	suite.userUsecase.AssertExpectations(suite.T())
+	assert.Contains(suite.T(), w.Body.String(), `"score":1.5`)
+	assert.Contains(suite.T(), w.Body.String(), `"snippet":"\u003cmark\u003eSprint\u003c/mark\u003e review"`)
+	suite.taskUsecase.AssertExpectations(suite.T())
 }
 
-func (suite *ApiControllerTestSuite) TestLogin_BadRequest() {
+func (suite *ApiControllerTestSuite) TestSearchTasks_BadRequest() {
+	suite.taskUsecase.On("SearchTasks", mock.Anything, testIdentity, domain.TaskSearchQuery{}).Return([]domain.TaskSearchResult(nil), &domain.BadRequestError{Message: "q is required"})
+
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username": ""}`))
+	req, _ := http.NewRequest("GET", "/tasks/search", nil)
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "Key: 'User.Username' Error:Field validation for 'Username' failed on the 'required' tag")
-	suite.userUsecase.AssertNotCalled(suite.T(), "Login", mock.Anything, mock.Anything)
+	assert.Contains(suite.T(), w.Body.String(), "q is required")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/tasks/search?q=sprint&limit=zero", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "limit must be a positive integer")
 }
 
-func (suite *ApiControllerTestSuite) TestLogin_Error() {
-	suite.userUsecase.On("Login", "testuser", "password").Return("", &domain.InternalServerError{Message: "Internal server error"})
+func (suite *ApiControllerTestSuite) TestGetTaskHistory() {
+	history := []domain.TaskVersion{{TaskID: "1", Version: 1, Title: "Test Task"}, {TaskID: "1", Version: 2, Title: "Renamed Task"}}
+	suite.taskUsecase.On("GetTaskHistory", mock.Anything, testIdentity, "1").Return(history, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	req, _ := http.NewRequest("GET", "/tasks/1/history", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Renamed Task")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetSubtree() {
+	tree := domain.TaskNode{
+		Task:     domain.Task{ID: "1", Title: "Release"},
+		Subtasks: []domain.TaskNode{{Task: domain.Task{ID: "2", ParentID: "1", Title: "Write changelog"}, Subtasks: []domain.TaskNode{}}},
+	}
+	suite.taskUsecase.On("GetSubtree", mock.Anything, testIdentity, "1").Return(tree, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/subtree", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Write changelog")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetSubtree_NotFound() {
+	suite.taskUsecase.On("GetSubtree", mock.Anything, testIdentity, "1").Return(domain.TaskNode{}, &domain.NotFoundError{Message: "Task not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/subtree", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *ApiControllerTestSuite) TestGetDependencyGraph() {
+	graph := domain.TaskGraph{
+		Tasks:        []domain.Task{{ID: "2", Title: "Build"}, {ID: "1", Title: "Release", DependsOn: []string{"2"}}},
+		Dependencies: []domain.TaskDependency{{TaskID: "1", DependsOn: "2"}},
+	}
+	suite.taskUsecase.On("GetDependencyGraph", mock.Anything, testIdentity, "1").Return(graph, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/dependencies", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Build")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetOccurrences() {
+	monday := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+	occurrences := []time.Time{monday, monday.AddDate(0, 0, 7)}
+	suite.taskUsecase.On("GetOccurrences", mock.Anything, testIdentity, "1", 2).Return(occurrences, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/occurrences?count=2", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "2030-01-14T09:00:00Z")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetOccurrences_InvalidCount() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/occurrences?count=none", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "count must be a positive integer")
+}
+
+func (suite *ApiControllerTestSuite) TestGetNextStates() {
+	suite.taskUsecase.On("GetNextStates", mock.Anything, testIdentity, "1").Return([]string{"in_progress", "completed"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/transitions", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"next_states":["in_progress","completed"]}`, w.Body.String())
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetNextStates_NotFound() {
+	suite.taskUsecase.On("GetNextStates", mock.Anything, testIdentity, "1").Return([]string(nil), &domain.NotFoundError{Message: "Task not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/transitions", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteTask_Success() {
+	suite.taskUsecase.On("DeleteTask", mock.Anything, testIdentity, "1").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/tasks/1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Task deleted successfully")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteTask_Error() {
+	suite.taskUsecase.On("DeleteTask", mock.Anything, testIdentity, "1").Return(&domain.InternalServerError{Message: "Internal server error"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/tasks/1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Internal server error")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteTask_Forbidden() {
+	suite.taskUsecase.On("DeleteTask", mock.Anything, testIdentity, "2").Return(&domain.ForbiddenError{Message: "You can only modify your own tasks"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/tasks/2", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "You can only modify your own tasks")
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRegister_Success() {
+	suite.userUsecase.On("Register", mock.Anything, "testuser", "password").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "User registered successfully")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRegister_BadRequest() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": ""}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Key: 'User.Username' Error:Field validation for 'Username' failed on the 'required' tag")
+	suite.userUsecase.AssertNotCalled(suite.T(), "Register", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestRegister_UsernameTaken() {
+	suite.userUsecase.On("Register", mock.Anything, "testuser", "password").Return(&domain.AlreadyExistsError{Message: "username already exists"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "username already exists")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRegister_Error() {
+	suite.userUsecase.On("Register", mock.Anything, "testuser", "password").Return(&domain.InternalServerError{Message: "Internal server error"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/register", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Internal server error")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestLogin_Success() {
+	tokens := domain.TokenPair{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+	suite.userUsecase.On("Login", mock.Anything, "testuser", "password").Return(tokens, nil)
+	
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username": "testuser", "password": "password"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"token":"access"`)
+	assert.Contains(suite.T(), w.Body.String(), `"refresh_token":"refresh"`)
+	assert.Contains(suite.T(), w.Body.String(), `"expires_in":900`)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestLogin_BadRequest() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username": ""}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Key: 'User.Username' Error:Field validation for 'Username' failed on the 'required' tag")
+	suite.userUsecase.AssertNotCalled(suite.T(), "Login", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestLogin_Error() {
+	suite.userUsecase.On("Login", mock.Anything, "testuser", "password").Return(domain.TokenPair{}, &domain.InternalServerError{Message: "Internal server error"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username": "testuser", "password": "password"}`))
 	suite.router.ServeHTTP(w, req)
 
 	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
@@ -327,8 +1036,189 @@ func (suite *ApiControllerTestSuite) TestLogin_Error() {
 	suite.userUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *ApiControllerTestSuite) TestOIDCLogin_Redirects() {
+	suite.oidcUsecase.On("BeginLogin", mock.Anything).Return("https://login.example.com/authorize?state=abc", "abc", nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusFound, w.Code)
+	assert.Equal(suite.T(), "https://login.example.com/authorize?state=abc", w.Header().Get("Location"))
+	assert.Equal(suite.T(), "no-store", w.Header().Get("Cache-Control"))
+	cookie := w.Header().Get("Set-Cookie")
+	assert.Contains(suite.T(), cookie, "oidc_state=abc")
+	assert.Contains(suite.T(), cookie, "Path=/auth/oidc")
+	assert.Contains(suite.T(), cookie, "HttpOnly")
+	assert.Contains(suite.T(), cookie, "SameSite=Lax")
+	suite.oidcUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestOIDCLogin_ProviderUnreachable() {
+	suite.oidcUsecase.On("BeginLogin", mock.Anything).Return("", "", &domain.InternalServerError{Message: "Error contacting the identity provider"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Error contacting the identity provider")
+}
+
+func (suite *ApiControllerTestSuite) TestOIDCCallback_Success() {
+	tokens := domain.TokenPair{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+	suite.oidcUsecase.On("CompleteLogin", mock.Anything, "abc", "the-code").Return(tokens, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/auth/oidc/callback?state=abc&code=the-code", nil)
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "abc"})
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"token":"access"`)
+	assert.Contains(suite.T(), w.Body.String(), `"refresh_token":"refresh"`)
+	assert.Contains(suite.T(), w.Body.String(), `"expires_in":900`)
+	assert.Equal(suite.T(), "no-store", w.Header().Get("Cache-Control"))
+	suite.oidcUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestOIDCCallback_Errors() {
+	suite.oidcUsecase.On("CompleteLogin", mock.Anything, "stale", "the-code").Return(domain.TokenPair{}, &domain.UnauthorizedError{Message: "Single sign-on login has expired, please try again"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/auth/oidc/callback?state=stale&code=the-code", nil)
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "stale"})
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Single sign-on login has expired, please try again")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/auth/oidc/callback?state=abc&error=access_denied&error_description=User+cancelled", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Single sign-on failed: access_denied: User cancelled")
+	suite.oidcUsecase.AssertNumberOfCalls(suite.T(), "CompleteLogin", 1)
+}
+
+func (suite *ApiControllerTestSuite) TestOIDCCallback_StateCookieMismatch() {
+	for _, cookie := range []*http.Cookie{nil, {Name: "oidc_state", Value: "other"}} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/oidc/callback?state=abc&code=the-code", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		suite.router.ServeHTTP(w, req)
+
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), "Single sign-on was not started in this browser, please try again")
+	}
+	suite.oidcUsecase.AssertNotCalled(suite.T(), "CompleteLogin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestOIDC_NotConfigured() {
+	controller := NewApiController(suite.taskUsecase, suite.userUsecase, suite.auditUsecase, suite.webhookUsecase, suite.taskStream, suite.calendarUsecase, suite.tagUsecase, suite.commentUsecase, suite.attachments, suite.projectUsecase, suite.roleUsecase, suite.apiKeyUsecase, nil)
+	router := gin.New()
+	router.GET("/auth/oidc/login", controller.OIDCLogin)
+	router.GET("/auth/oidc/callback", controller.OIDCCallback)
+
+	for _, path := range []string{"/auth/oidc/login", "/auth/oidc/callback?state=abc&code=the-code"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), "Single sign-on is not configured")
+	}
+}
+
+func (suite *ApiControllerTestSuite) TestRefreshToken_Success() {
+	tokens := domain.TokenPair{AccessToken: "new-access", RefreshToken: "new-refresh", ExpiresIn: 900}
+	suite.userUsecase.On("RefreshToken", mock.Anything, "refresh").Return(tokens, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/token/refresh", strings.NewReader(`{"refresh_token": "refresh"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"token":"new-access"`)
+	assert.Contains(suite.T(), w.Body.String(), `"refresh_token":"new-refresh"`)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRefreshToken_BadRequest() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/token/refresh", strings.NewReader(`{}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *ApiControllerTestSuite) TestRefreshToken_Unauthorized() {
+	suite.userUsecase.On("RefreshToken", mock.Anything, "reused").Return(domain.TokenPair{}, &domain.UnauthorizedError{Message: "refresh token reuse detected, please log in again"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/token/refresh", strings.NewReader(`{"refresh_token": "reused"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "refresh token reuse detected")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestLogout_Success() {
+	suite.userUsecase.On("Logout", mock.Anything, testAccessToken, "refresh").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/logout", strings.NewReader(`{"refresh_token": "refresh"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Logged out successfully")
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestLogout_WithoutBody() {
+	suite.userUsecase.On("Logout", mock.Anything, testAccessToken, "").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/logout", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestLogout_WithAPIKey() {
+	suite.router.POST("/logout/key", func(ctx *gin.Context) {
+		withKey := testIdentity
+		withKey.APIKeyID = "key-id"
+		ctx.Set("identity", withKey)
+	}, suite.controller.Logout)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/logout/key", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "revoke the key instead")
+	suite.userUsecase.AssertNotCalled(suite.T(), "Logout", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestLogout_Forbidden() {
+	suite.userUsecase.On("Logout", mock.Anything, testAccessToken, "other").Return(&domain.ForbiddenError{Message: "refresh token belongs to another user"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/logout", strings.NewReader(`{"refresh_token": "other"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	suite.userUsecase.AssertExpectations(suite.T())
+}
+
 func (suite *ApiControllerTestSuite) TestPromoteUser_Success() {
-	suite.userUsecase.On("PromoteUser", "testuser").Return(nil)
+	suite.userUsecase.On("PromoteUser", mock.Anything, testIdentity, "testuser").Return(nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/promote", strings.NewReader(`{"username": "testuser"}`))
@@ -346,11 +1236,11 @@ func (suite *ApiControllerTestSuite) TestPromoteUser_BadRequest() {
 
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Key: 'Username' Error:Field validation for 'Username' failed on the 'required' tag")
-	suite.userUsecase.AssertNotCalled(suite.T(), "PromoteUser", mock.Anything)
+	suite.userUsecase.AssertNotCalled(suite.T(), "PromoteUser", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (suite *ApiControllerTestSuite) TestPromoteUser_Error() {
-	suite.userUsecase.On("PromoteUser", "testuser").Return(&domain.InternalServerError{Message: "Internal server error"})
+	suite.userUsecase.On("PromoteUser", mock.Anything, testIdentity, "testuser").Return(&domain.InternalServerError{Message: "Internal server error"})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/promote", strings.NewReader(`{"username": "testuser"}`))
@@ -360,3 +1250,877 @@ func (suite *ApiControllerTestSuite) TestPromoteUser_Error() {
 	assert.Contains(suite.T(), w.Body.String(), "Internal server error")
 	suite.userUsecase.AssertExpectations(suite.T())
 }
+
+func (suite *ApiControllerTestSuite) TestGetAuditEntries_Success() {
+	since, _ := time.Parse(time.RFC3339, "2024-01-01T00:00:00Z")
+	query := domain.AuditQuery{Actor: "admin", Action: domain.AuditTaskUpdate, TargetType: "task", TargetID: "1", Since: since, Cursor: "10", Limit: 5}
+	page := domain.AuditPage{Entries: []domain.AuditEntry{{Sequence: 9, Actor: "admin", Action: domain.AuditTaskUpdate}}, NextCursor: "9"}
+	suite.auditUsecase.On("GetEntries", mock.Anything, query).Return(page, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/audit?actor=admin&action=task.update&target_type=task&target_id=1&since=2024-01-01T00:00:00Z&cursor=10&limit=5", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"next_cursor":"9"`)
+	suite.auditUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetAuditEntries_InvalidSince() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/audit?since=yesterday", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.auditUsecase.AssertNotCalled(suite.T(), "GetEntries", mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestVerifyAudit() {
+	suite.auditUsecase.On("Verify", mock.Anything).Return(domain.AuditVerification{Valid: false, Entries: 3, BrokenAt: 2, Reason: "previous hash does not match"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/audit/verify", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"broken_at":2`)
+	suite.auditUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestCreateWebhook_Success() {
+	webhook := domain.Webhook{URL: "https://example.com/hook", Events: []string{domain.EventTaskCompleted}}
+	suite.webhookUsecase.On("CreateWebhook", mock.Anything, testIdentity, webhook).Return(domain.Webhook{ID: "1", URL: webhook.URL, Events: webhook.Events, Secret: "secret"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "events": ["task.completed"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"secret":"secret"`)
+	suite.webhookUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestCreateWebhook_MissingURL() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"events": ["task.completed"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.webhookUsecase.AssertNotCalled(suite.T(), "CreateWebhook", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestGetWebhooks() {
+	suite.webhookUsecase.On("GetWebhooks", mock.Anything).Return([]domain.Webhook{{ID: "1", URL: "https://example.com/hook", Events: []string{}}}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/webhooks", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"url":"https://example.com/hook"`)
+	assert.NotContains(suite.T(), w.Body.String(), `"secret"`)
+	suite.webhookUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteWebhook_NotFound() {
+	suite.webhookUsecase.On("DeleteWebhook", mock.Anything, testIdentity, "1").Return(&domain.NotFoundError{Message: "Webhook not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/webhooks/1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	suite.webhookUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetWebhookDeliveries() {
+	query := domain.DeliveryQuery{WebhookID: "1", Status: domain.DeliveryFailed, Limit: 10}
+	suite.webhookUsecase.On("GetDeliveries", mock.Anything, query).Return([]domain.WebhookDelivery{{ID: "2", WebhookID: "1", Status: domain.DeliveryFailed}}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/webhooks/deliveries?webhook_id=1&status=failed&limit=10", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"deliveries":[{"id":"2"`)
+	suite.webhookUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetWebhookDeliveries_InvalidLimit() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/webhooks/deliveries?limit=none", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.webhookUsecase.AssertNotCalled(suite.T(), "GetDeliveries", mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestReplayWebhookDelivery() {
+	suite.webhookUsecase.On("ReplayDelivery", mock.Anything, "2").Return(domain.WebhookDelivery{ID: "3", ReplayOf: "2", Status: domain.DeliveryPending}, nil)
+	suite.webhookUsecase.On("ReplayDelivery", mock.Anything, "3").Return(domain.WebhookDelivery{}, &domain.BadRequestError{Message: "Only failed deliveries can be replayed"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/webhooks/deliveries/2/replay", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"replay_of":"2"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/webhooks/deliveries/3/replay", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Only failed deliveries can be replayed")
+	suite.webhookUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetCalendar() {
+	feed := domain.CalendarFeed{Username: "testuser", Component: domain.CalendarTodos, Tasks: []domain.Task{{ID: "1"}}, Tag: "abc"}
+	suite.calendarUsecase.On("GetFeed", mock.Anything, "secret", "vtodo").Return(feed, nil)
+	suite.calendarUsecase.On("WriteFeed", mock.Anything, feed).Return("BEGIN:VCALENDAR\r\n", nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/calendar.ics?token=secret&component=vtodo", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), `"abc"`, w.Header().Get("ETag"))
+	assert.Equal(suite.T(), "BEGIN:VCALENDAR\r\n", w.Body.String())
+	suite.calendarUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetCalendar_NotModified() {
+	suite.calendarUsecase.On("GetFeed", mock.Anything, "secret", "").Return(domain.CalendarFeed{Tag: "abc"}, nil)
+
+	for _, ifNoneMatch := range []string{`"abc"`, `W/"abc"`, `"old", "abc"`, "*"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/calendar.ics?token=secret", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		suite.router.ServeHTTP(w, req)
+
+		assert.Equal(suite.T(), http.StatusNotModified, w.Code, ifNoneMatch)
+		assert.Empty(suite.T(), w.Body.String())
+	}
+	suite.calendarUsecase.AssertNotCalled(suite.T(), "WriteFeed", mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestGetCalendar_InvalidToken() {
+	suite.calendarUsecase.On("GetFeed", mock.Anything, "wrong", "").Return(domain.CalendarFeed{}, &domain.UnauthorizedError{Message: "Invalid calendar token"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/calendar.ics?token=wrong", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Invalid calendar token")
+}
+
+func (suite *ApiControllerTestSuite) TestCreateCalendarToken() {
+	suite.calendarUsecase.On("CreateToken", mock.Anything, testIdentity).Return("secret", nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/calendar/token", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.JSONEq(suite.T(), `{"token":"secret","url":"/calendar.ics?token=secret"}`, w.Body.String())
+	suite.calendarUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRevokeCalendarToken() {
+	suite.calendarUsecase.On("RevokeToken", mock.Anything, testIdentity).Return(&domain.NotFoundError{Message: "Calendar token not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/calendar/token", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	suite.calendarUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetComments() {
+	comments := []domain.Comment{{ID: "c1", TaskID: "1", Author: "testuser", Body: "First", Replies: []domain.Comment{{ID: "c2", TaskID: "1", ParentID: "c1", Deleted: true}}}}
+	suite.commentUsecase.On("GetComments", mock.Anything, testIdentity, "1").Return(comments, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/comments", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"replies":[{"id":"c2"`)
+	assert.Contains(suite.T(), w.Body.String(), `"deleted":true`)
+	suite.commentUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestAddComment() {
+	suite.commentUsecase.On("AddComment", mock.Anything, testIdentity, "1", domain.Comment{ParentID: "c1", Body: "Agreed"}).Return(domain.Comment{ID: "c2", TaskID: "1", ParentID: "c1", Body: "Agreed"}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/1/comments", strings.NewReader(`{"parent_id":"c1","body":"Agreed"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"id":"c2"`)
+	suite.commentUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestUpdateComment_Forbidden() {
+	suite.commentUsecase.On("UpdateComment", mock.Anything, testIdentity, "1", "c1", domain.Comment{Body: "Edited"}).Return(domain.Comment{}, &domain.ForbiddenError{Message: "You can only modify your own comments"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/tasks/1/comments/c1", strings.NewReader(`{"body":"Edited"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	suite.commentUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteComment() {
+	suite.commentUsecase.On("DeleteComment", mock.Anything, testIdentity, "1", "c1").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/tasks/1/comments/c1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Comment deleted successfully")
+	suite.commentUsecase.AssertExpectations(suite.T())
+}
+
+// multipartBody builds an upload with a text field before the file part
+func multipartBody(fieldName, filename, contents string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	form.WriteField("note", "ignored")
+	part, _ := form.CreateFormFile(fieldName, filename)
+	part.Write([]byte(contents))
+	form.Close()
+	return body, form.FormDataContentType()
+}
+
+func (suite *ApiControllerTestSuite) TestGetAttachments() {
+	suite.attachments.On("GetAttachments", mock.Anything, testIdentity, "1").Return([]domain.Attachment{{ID: "a1", Filename: "notes.txt"}}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/attachments", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"filename":"notes.txt"`)
+	suite.attachments.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestUploadAttachment() {
+	suite.attachments.On("UploadAttachment", mock.Anything, testIdentity, "1", "notes.txt", "plain notes").Return(domain.Attachment{ID: "a1", Filename: "notes.txt", Size: 11}, nil)
+	body, contentType := multipartBody("file", "notes.txt", "plain notes")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/1/attachments", body)
+	req.Header.Set("Content-Type", contentType)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"id":"a1"`)
+	suite.attachments.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestUploadAttachment_BadRequest() {
+	body, contentType := multipartBody("document", "notes.txt", "plain notes")
+
+	for _, test := range []struct{ contentType, body string }{
+		{"application/json", `{"file":"notes.txt"}`},
+		{contentType, body.String()},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/tasks/1/attachments", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		suite.router.ServeHTTP(w, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, test.contentType)
+	}
+	suite.attachments.AssertNotCalled(suite.T(), "UploadAttachment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestUploadAttachment_TooLarge() {
+	body, contentType := multipartBody("file", "big.bin", strings.Repeat("a", 2<<20))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/1/attachments", body)
+	req.Header.Set("Content-Type", contentType)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func (suite *ApiControllerTestSuite) TestDownloadAttachment() {
+	attachment := domain.Attachment{ID: "a1", Filename: "résumé.pdf", ContentType: "application/pdf", Size: 4, SHA256: "abc"}
+	suite.attachments.On("OpenAttachment", mock.Anything, testIdentity, "1", "a1").Return(attachment, io.NopCloser(strings.NewReader("%PDF")), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/attachments/a1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), "4", w.Header().Get("Content-Length"))
+	assert.Equal(suite.T(), `"abc"`, w.Header().Get("ETag"))
+	assert.Equal(suite.T(), "attachment; filename*=utf-8''r%C3%A9sum%C3%A9.pdf", w.Header().Get("Content-Disposition"))
+	assert.Equal(suite.T(), "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(suite.T(), "%PDF", w.Body.String())
+	suite.attachments.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDownloadAttachment_NotModified() {
+	suite.attachments.On("OpenAttachment", mock.Anything, testIdentity, "1", "a1").Return(domain.Attachment{ID: "a1", Size: 4, SHA256: "abc"}, io.NopCloser(strings.NewReader("%PDF")), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/attachments/a1", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+	assert.Empty(suite.T(), w.Body.String())
+}
+
+func (suite *ApiControllerTestSuite) TestDownloadAttachment_NotFound() {
+	suite.attachments.On("OpenAttachment", mock.Anything, testIdentity, "1", "a1").Return(domain.Attachment{}, nil, &domain.NotFoundError{Message: "Attachment not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/1/attachments/a1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteAttachment() {
+	suite.attachments.On("DeleteAttachment", mock.Anything, testIdentity, "1", "a1").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/tasks/1/attachments/a1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Attachment deleted successfully")
+	suite.attachments.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetTags() {
+	suite.tagUsecase.On("GetTags", mock.Anything, testIdentity).Return([]domain.Tag{{OwnerID: testIdentity.UserID, Name: "home", Color: "#abc", Count: 2}}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tags", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"tags":[{"name":"home","color":"#abc","count":2}]}`, w.Body.String())
+	suite.tagUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestUpdateTag() {
+	suite.tagUsecase.On("UpdateTag", mock.Anything, testIdentity, "home", domain.Tag{Color: "#abc"}).Return(domain.Tag{Name: "home", Color: "#abc", Count: 1}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/tags/home", strings.NewReader(`{"color":"#abc"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"tag":{"name":"home","color":"#abc","count":1}`)
+	suite.tagUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRenameTag_Existing() {
+	suite.tagUsecase.On("RenameTag", mock.Anything, testIdentity, "bug", "defect").Return(domain.Tag{}, &domain.BadRequestError{Message: "Tag defect already exists; merge the tags instead"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tags/bug/rename", strings.NewReader(`{"name":"defect"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "merge the tags instead")
+	suite.tagUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestMergeTags() {
+	merge := domain.TagMerge{From: []string{"bug", "defect"}, Into: "issue"}
+	suite.tagUsecase.On("MergeTags", mock.Anything, testIdentity, merge).Return(domain.Tag{Name: "issue", Count: 3}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tags/merge", strings.NewReader(`{"from":["bug","defect"],"into":"issue"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"tag":{"name":"issue","count":3}`)
+	suite.tagUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestMergeTags_InvalidBody() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tags/merge", strings.NewReader(`{"from":"bug"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.tagUsecase.AssertNotCalled(suite.T(), "MergeTags", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestCreateProject() {
+	suite.projectUsecase.On("CreateProject", mock.Anything, testIdentity, domain.Project{Name: "Launch"}).Return(domain.Project{ID: "p1", Name: "Launch", Version: 1}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/projects", strings.NewReader(`{"name":"Launch"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"id":"p1"`)
+	suite.projectUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetProjects() {
+	projects := []domain.Project{{ID: "p1", Name: "Launch", Members: []domain.ProjectMember{{UserID: testIdentity.UserID, Username: "testuser", Role: domain.ProjectOwner}}}}
+	suite.projectUsecase.On("GetProjects", mock.Anything, testIdentity).Return(projects, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/projects", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"members":[{"user_id":"user-id","username":"testuser","role":"owner"}]`)
+	suite.projectUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetProject_NotMember() {
+	suite.projectUsecase.On("GetProject", mock.Anything, testIdentity, "p1").Return(domain.Project{}, &domain.NotFoundError{Message: "Project not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/projects/p1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	suite.projectUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteProject_HasTasks() {
+	suite.projectUsecase.On("DeleteProject", mock.Anything, testIdentity, "p1").Return(&domain.BadRequestError{Message: "Project has tasks; delete or move them first"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/projects/p1", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "delete or move them first")
+	suite.projectUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestSetProjectMember() {
+	project := domain.Project{ID: "p1", Name: "Launch", Members: []domain.ProjectMember{{UserID: "bob-id", Username: "bob", Role: domain.ProjectViewer}}}
+	suite.projectUsecase.On("SetMember", mock.Anything, testIdentity, "p1", "bob", domain.ProjectViewer).Return(project, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/projects/p1/members/bob", strings.NewReader(`{"role":"viewer"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"role":"viewer"`)
+	suite.projectUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRemoveProjectMember_Forbidden() {
+	suite.projectUsecase.On("RemoveMember", mock.Anything, testIdentity, "p1", "bob").Return(domain.Project{}, &domain.ForbiddenError{Message: "Only project owners can change the project"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/projects/p1/members/bob", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	suite.projectUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetPermissions() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/permissions", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"task:create"`)
+	assert.Contains(suite.T(), w.Body.String(), `"user:promote"`)
+}
+
+func (suite *ApiControllerTestSuite) TestCreateRole() {
+	role := domain.Role{Name: "triager", Permissions: []string{domain.PermissionTaskRead, domain.PermissionTaskUpdate}}
+	suite.roleUsecase.On("CreateRole", mock.Anything, testIdentity, role).Return(role, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/roles", strings.NewReader(`{"name":"triager","permissions":["task:read","task:update"]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"name":"triager"`)
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestCreateRole_AlreadyExists() {
+	role := domain.Role{Name: "triager", Permissions: []string{domain.PermissionTaskRead}}
+	suite.roleUsecase.On("CreateRole", mock.Anything, testIdentity, role).Return(domain.Role{}, &domain.AlreadyExistsError{Message: "Role already exists"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/roles", strings.NewReader(`{"name":"triager","permissions":["task:read"]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Role already exists")
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestUpdateRole_BuiltIn() {
+	role := domain.Role{Permissions: []string{domain.PermissionTaskRead}}
+	suite.roleUsecase.On("UpdateRole", mock.Anything, testIdentity, "admin", role).Return(domain.Role{}, &domain.BadRequestError{Message: "Built-in roles cannot be changed"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/roles/admin", strings.NewReader(`{"permissions":["task:read"]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Built-in roles cannot be changed")
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestDeleteRole_NotFound() {
+	suite.roleUsecase.On("DeleteRole", mock.Anything, testIdentity, "triager").Return(&domain.NotFoundError{Message: "Role not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/roles/triager", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestSetUserRoles() {
+	grants := domain.Grants{Roles: []string{"triager", "user"}, Permissions: []string{domain.PermissionTaskRead}}
+	suite.roleUsecase.On("SetUserRoles", mock.Anything, testIdentity, "bob", []string{"user", "triager"}).Return(grants, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/users/bob/roles", strings.NewReader(`{"roles":["user","triager"]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"roles":["triager","user"]`)
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestSetUserRoles_Forbidden() {
+	suite.roleUsecase.On("SetUserRoles", mock.Anything, testIdentity, "bob", []string{"admin"}).Return(domain.Grants{}, &domain.ForbiddenError{Message: "You cannot grant permissions you do not have"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/users/bob/roles", strings.NewReader(`{"roles":["admin"]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	suite.roleUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestCreateAPIKey() {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := domain.APIKey{Name: "CI", Scopes: []string{domain.PermissionTaskRead}, ExpiresAt: &expiresAt}
+	created := domain.APIKey{ID: "key-id", Name: "CI", Hint: "tm_abcdef", KeyHash: "hash", Scopes: key.Scopes, ExpiresAt: &expiresAt}
+	suite.apiKeyUsecase.On("CreateKey", mock.Anything, testIdentity, key).Return(created, "tm_abcdefsecret", nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api-keys", strings.NewReader(`{"name":"CI","scopes":["task:read"],"expires_at":"2030-01-01T00:00:00Z"}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"key":"tm_abcdefsecret"`)
+	assert.Contains(suite.T(), w.Body.String(), `"hint":"tm_abcdef"`)
+	assert.NotContains(suite.T(), w.Body.String(), "hash")
+	suite.apiKeyUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestCreateAPIKey_Forbidden() {
+	key := domain.APIKey{Name: "CI", Scopes: []string{domain.PermissionAuditRead}}
+	suite.apiKeyUsecase.On("CreateKey", mock.Anything, testIdentity, key).Return(domain.APIKey{}, "", &domain.ForbiddenError{Message: "You cannot give an API key permissions you do not have"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api-keys", strings.NewReader(`{"name":"CI","scopes":["audit:read"]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	suite.apiKeyUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestGetAPIKeys() {
+	lastUsed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	keys := []domain.APIKey{{ID: "key-id", Name: "CI", Hint: "tm_abcdef", KeyHash: "hash", Scopes: []string{domain.PermissionTaskRead}, LastUsedAt: &lastUsed}}
+	suite.apiKeyUsecase.On("GetKeys", mock.Anything, testIdentity).Return(keys, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api-keys", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"last_used_at":"2026-01-02T03:04:05Z"`)
+	assert.NotContains(suite.T(), w.Body.String(), "hash")
+	suite.apiKeyUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestRevokeAPIKey_NotFound() {
+	suite.apiKeyUsecase.On("RevokeKey", mock.Anything, testIdentity, "key-id").Return(&domain.NotFoundError{Message: "API key not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api-keys/key-id", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	suite.apiKeyUsecase.AssertExpectations(suite.T())
+}
+
+func streamEvent(id, eventType string) domain.TaskEvent {
+	return domain.TaskEvent{ID: id, Type: eventType, Task: domain.Task{ID: "1", OwnerID: testIdentity.UserID}}
+}
+
+// readServerSentEvent reads the next event from the stream, skipping comments
+func readServerSentEvent(reader *bufio.Reader) (string, error) {
+	var event []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(event) > 0:
+			return strings.Join(event, "\n"), nil
+		case line != "" && !strings.HasPrefix(line, ":"):
+			event = append(event, line)
+		}
+	}
+}
+
+func (suite *ApiControllerTestSuite) TestStreamTasks_ServerSentEvents() {
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+	suite.projectUsecase.On("TaskAccess", mock.Anything, testIdentity).Return(domain.TaskAccess{Identity: testIdentity}, nil)
+
+	suite.taskStream.Publish(context.Background(), streamEvent("e1", domain.EventTaskCreated))
+	suite.taskStream.Publish(context.Background(), streamEvent("e2", domain.EventTaskUpdated))
+
+	req, _ := http.NewRequest("GET", server.URL+"/tasks/stream", nil)
+	req.Header.Set("Last-Event-ID", "e1")
+	resp, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	event, err := readServerSentEvent(reader)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), strings.HasPrefix(event, "id: e2\nevent: task.updated\ndata: {\"id\":\"e2\""), event)
+
+	// events the user may not see are not sent
+	suite.taskStream.Publish(context.Background(), domain.TaskEvent{ID: "e3", Type: domain.EventTaskCreated, Task: domain.Task{ID: "2", OwnerID: "someone-else"}})
+	suite.taskStream.Publish(context.Background(), streamEvent("e4", domain.EventTaskDeleted))
+
+	event, err = readServerSentEvent(reader)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), strings.HasPrefix(event, "id: e4\nevent: task.deleted\n"), event)
+}
+
+func (suite *ApiControllerTestSuite) TestStreamTasks_ServerSentEvents_Resync() {
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+	suite.projectUsecase.On("TaskAccess", mock.Anything, testIdentity).Return(domain.TaskAccess{Identity: testIdentity}, nil)
+
+	resp, err := http.Get(server.URL + "/tasks/stream?last_event_id=gone")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	event, err := readServerSentEvent(bufio.NewReader(resp.Body))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "event: resync\ndata: {\"type\":\"resync\"}", event)
+}
+
+func (suite *ApiControllerTestSuite) TestStreamTasks_ServerSentEvents_EndsWhenStreamCloses() {
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+	suite.projectUsecase.On("TaskAccess", mock.Anything, testIdentity).Return(domain.TaskAccess{Identity: testIdentity}, nil)
+
+	resp, err := http.Get(server.URL + "/tasks/stream")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.taskStream.Close()
+
+	_, err = readServerSentEvent(bufio.NewReader(resp.Body))
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ApiControllerTestSuite) TestStreamTasks_WebSocket() {
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+	suite.projectUsecase.On("TaskAccess", mock.Anything, testIdentity).Return(domain.TaskAccess{Identity: testIdentity}, nil)
+
+	suite.taskStream.Publish(context.Background(), streamEvent("e1", domain.EventTaskCreated))
+	suite.taskStream.Publish(context.Background(), streamEvent("e2", domain.EventTaskUpdated))
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/tasks/stream?last_event_id=e1", "", server.URL)
+	suite.Require().NoError(err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var event domain.TaskEvent
+	suite.Require().NoError(websocket.JSON.Receive(conn, &event))
+	assert.Equal(suite.T(), "e2", event.ID)
+	assert.Equal(suite.T(), domain.EventTaskUpdated, event.Type)
+
+	suite.taskStream.Publish(context.Background(), streamEvent("e3", domain.EventTaskCompleted))
+
+	suite.Require().NoError(websocket.JSON.Receive(conn, &event))
+	assert.Equal(suite.T(), "e3", event.ID)
+	assert.Equal(suite.T(), "1", event.Task.ID)
+}
+
+func (suite *ApiControllerTestSuite) TestBatchTasks() {
+	version := int64(2)
+	request := domain.BatchRequest{Operations: []domain.BatchOperation{
+		{Op: domain.BatchCreate, Task: &domain.Task{Title: "New"}},
+		{Op: domain.BatchUpdate, ID: "1", Version: &version, Task: &domain.Task{Title: "Renamed"}},
+		{Op: domain.BatchDelete, ID: "2"},
+	}}
+	suite.taskUsecase.On("BatchTasks", mock.Anything, testIdentity, request).Return([]domain.BatchResult{
+		{Op: domain.BatchCreate, ID: "3", Task: &domain.Task{ID: "3", Title: "New"}},
+		{Op: domain.BatchUpdate, ID: "1", Err: &domain.ConflictError{Message: "Task has been modified since it was read"}},
+		{Op: domain.BatchDelete, ID: "2"},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/batch", strings.NewReader(`{"operations": [
+		{"op": "create", "task": {"title": "New"}},
+		{"op": "update", "id": "1", "version": 2, "task": {"title": "Renamed"}},
+		{"op": "delete", "id": "2"}
+	]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"succeeded": 2, "failed": 1, "results": [
+		{"index": 0, "op": "create", "id": "3", "status": 201, "task": {"id": "3", "title": "New", "due_date": "0001-01-01T00:00:00Z", "status": "", "version": 0}},
+		{"index": 1, "op": "update", "id": "1", "status": 412, "error": "Task has been modified since it was read"},
+		{"index": 2, "op": "delete", "id": "2", "status": 200}
+	]}`, w.Body.String())
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestBatchTasks_AtomicFailure() {
+	suite.taskUsecase.On("BatchTasks", mock.Anything, testIdentity, mock.Anything).Return([]domain.BatchResult{
+		{Op: domain.BatchDelete, ID: "1", Err: &domain.NotAppliedError{Message: "Not applied because another operation in the batch failed"}},
+		{Op: domain.BatchDelete, ID: "2", Err: &domain.NotFoundError{Message: "Task not found"}},
+	}, &domain.NotFoundError{Message: "Task not found"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/batch", strings.NewReader(`{"atomic": true, "operations": [{"op": "delete", "id": "1"}, {"op": "delete", "id": "2"}]}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"error":"Task not found"`)
+	assert.Contains(suite.T(), w.Body.String(), `{"error":"Not applied because another operation in the batch failed","id":"1","index":0,"op":"delete","status":424}`)
+}
+
+func (suite *ApiControllerTestSuite) TestBatchTasks_InvalidBody() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/batch", strings.NewReader(`{"operations": {}}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.taskUsecase.AssertNotCalled(suite.T(), "BatchTasks", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestBatchTasks_TooLarge() {
+	suite.taskUsecase.On("BatchTasks", mock.Anything, testIdentity, domain.BatchRequest{}).Return(nil, &domain.BadRequestError{Message: "a batch must have between 1 and 500 operations"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/batch", strings.NewReader(`{}`))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "a batch must have between 1 and 500 operations")
+}
+
+func (suite *ApiControllerTestSuite) TestExportTasks() {
+	query := domain.TaskQuery{Status: "pending"}
+	suite.taskUsecase.On("ExportTasks", mock.Anything, testIdentity, query, domain.FormatCSV, mock.Anything).Run(func(args mock.Arguments) {
+		io.WriteString(args.Get(4).(io.Writer), "id,title\n1,Task 1\n")
+	}).Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=csv&status=pending", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), `attachment; filename="tasks.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(suite.T(), "id,title\n1,Task 1\n", w.Body.String())
+}
+
+func (suite *ApiControllerTestSuite) TestExportTasks_Failure() {
+	suite.taskUsecase.On("ExportTasks", mock.Anything, testIdentity, domain.TaskQuery{}, "xml", mock.Anything).Return(&domain.BadRequestError{Message: "format must be csv, json or ndjson"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=xml", nil)
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Equal(suite.T(), "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Empty(suite.T(), w.Header().Get("Content-Disposition"))
+	assert.JSONEq(suite.T(), `{"error": "format must be csv, json or ndjson"}`, w.Body.String())
+}
+
+func (suite *ApiControllerTestSuite) TestImportTasks() {
+	options := domain.ImportOptions{
+		Format:      domain.FormatCSV,
+		Mapping:     map[string]string{"Due": "due_date", "Notes": "-"},
+		DryRun:      true,
+		OnDuplicate: domain.DuplicateSkip,
+	}
+	report := domain.ImportReport{DryRun: true, Created: 1, Rows: []domain.ImportRow{{Row: 2, Title: "Task 1", Action: domain.ImportCreate}}}
+	suite.taskUsecase.On("ImportTasks", mock.Anything, testIdentity, mock.Anything, options).Run(func(args mock.Arguments) {
+		body, _ := io.ReadAll(args.Get(2).(io.Reader))
+		assert.Equal(suite.T(), "title,Due,Notes\nTask 1,2030-01-01,\n", string(body))
+	}).Return(report, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/import?dry_run=true&on_duplicate=skip&map[Due]=due_date&map[Notes]=-", strings.NewReader("title,Due,Notes\nTask 1,2030-01-01,\n"))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"dry_run": true, "created": 1, "updated": 0, "skipped": 0, "failed": 0, "rows": [
+		{"row": 2, "title": "Task 1", "action": "create"}
+	]}`, w.Body.String())
+	suite.taskUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *ApiControllerTestSuite) TestImportTasks_InvalidDryRun() {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/import?format=json&dry_run=maybe", strings.NewReader("[]"))
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.taskUsecase.AssertNotCalled(suite.T(), "ImportTasks", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ApiControllerTestSuite) TestImportTasks_TooLarge() {
+	suite.taskUsecase.On("ImportTasks", mock.Anything, testIdentity, mock.Anything, mock.Anything).Return(domain.ImportReport{}, &http.MaxBytesError{Limit: maxImportSize})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks/import", strings.NewReader("[]"))
+	req.Header.Set("Content-Type", "application/json")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "An import must not be larger than 10 MB")
+}
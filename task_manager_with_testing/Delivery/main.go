@@ -1,6 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	config "task-manager/Config"
 	"task-manager/Delivery/controllers"
 	"task-manager/Delivery/routers"
 	infrastructure "task-manager/Infrastructure"
@@ -9,30 +19,197 @@ import (
 )
 
 func main() {
-	
+
+	// Load configuration
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Initialize services
-	jwtService := infrastructure.NewJWTService()
+	jwtService := infrastructure.NewJWTService(cfg.JWT.Secret, cfg.JWT.Issuer, time.Duration(cfg.JWT.Expiry))
 	passwordService := infrastructure.NewPasswordService()
-	
-	// Initialize database
-	databaseService := infrastructure.NewDatabase()
-	db := databaseService.Connect()
-	
-	// Initialize repositories
-	userRepo := repositories.NewUserRepository(db, "users")
-	taskRepo := repositories.NewTaskRepository(db, "tasks")
+	refreshTokenService := infrastructure.NewRefreshTokenService()
+
+	// Initialize repositories on the configured storage backend
+	var userRepo repositories.UserRepository
+	var taskRepo repositories.TaskRepository
+	var taskHistoryRepo repositories.TaskHistoryRepository
+	var refreshTokenRepo repositories.RefreshTokenRepository
+	var revokedTokenRepo repositories.RevokedTokenRepository
+	var auditRepo repositories.AuditRepository
+	var reminderRepo repositories.ReminderRepository
+	var webhookRepo repositories.WebhookRepository
+	var webhookDeliveryRepo repositories.WebhookDeliveryRepository
+	var calendarTokenRepo repositories.CalendarTokenRepository
+	var tagRepo repositories.TagRepository
+	var commentRepo repositories.CommentRepository
+	var projectRepo repositories.ProjectRepository
+	var roleRepo repositories.RoleRepository
+	var apiKeyRepo repositories.APIKeyRepository
+	var oidcLoginRepo repositories.OIDCLoginRepository
+
+	switch cfg.Storage.Backend {
+	case "memory":
+		log.Println("Using in-memory storage; data will be lost on restart")
+		userRepo = repositories.NewUserMemoryRepository()
+		taskRepo = repositories.NewTaskMemoryRepository()
+		taskHistoryRepo = repositories.NewTaskHistoryMemoryRepository()
+		refreshTokenRepo = repositories.NewRefreshTokenMemoryRepository()
+		revokedTokenRepo = repositories.NewRevokedTokenMemoryRepository()
+		auditRepo = repositories.NewAuditMemoryRepository()
+		reminderRepo = repositories.NewReminderMemoryRepository()
+		webhookRepo = repositories.NewWebhookMemoryRepository()
+		webhookDeliveryRepo = repositories.NewWebhookDeliveryMemoryRepository()
+		calendarTokenRepo = repositories.NewCalendarTokenMemoryRepository()
+		tagRepo = repositories.NewTagMemoryRepository()
+		commentRepo = repositories.NewCommentMemoryRepository()
+		projectRepo = repositories.NewProjectMemoryRepository()
+		roleRepo = repositories.NewRoleMemoryRepository()
+		apiKeyRepo = repositories.NewAPIKeyMemoryRepository()
+		oidcLoginRepo = repositories.NewOIDCLoginMemoryRepository()
+	default:
+		databaseService := infrastructure.NewDatabase(cfg.Storage.MongoURI, cfg.Storage.Database)
+		db, err := databaseService.Connect()
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+
+		userRepo = repositories.NewUserRepository(db, "users")
+		taskRepo = repositories.NewTaskRepository(db, "tasks")
+		taskHistoryRepo = repositories.NewTaskHistoryRepository(db, "task_history")
+		refreshTokenRepo = repositories.NewRefreshTokenRepository(db, "refresh_tokens")
+		revokedTokenRepo = repositories.NewRevokedTokenRepository(db, "revoked_tokens")
+		auditRepo = repositories.NewAuditRepository(db, "audit_log")
+		reminderRepo = repositories.NewReminderRepository(db, "reminders")
+		webhookRepo = repositories.NewWebhookRepository(db, "webhooks")
+		webhookDeliveryRepo = repositories.NewWebhookDeliveryRepository(db, "webhook_deliveries")
+		calendarTokenRepo = repositories.NewCalendarTokenRepository(db, "calendar_tokens")
+		tagRepo = repositories.NewTagRepository(db, "tags")
+		commentRepo = repositories.NewCommentRepository(db, "comments")
+		projectRepo = repositories.NewProjectRepository(db, "projects")
+		roleRepo = repositories.NewRoleRepository(db, "roles")
+		apiKeyRepo = repositories.NewAPIKeyRepository(db, "api_keys")
+		oidcLoginRepo = repositories.NewOIDCLoginRepository(db, "oidc_logins")
+	}
+
 	// Initialize use cases
-	userUsecase := usecases.NewUserUsecase(userRepo, passwordService, jwtService)
-	taskUsecase := usecases.NewTaskUsecase(taskRepo)
+	permissionResolver := usecases.NewPermissionResolver(userRepo, roleRepo, time.Duration(cfg.Permissions.CacheTTL))
+	userUsecase := usecases.NewUserUsecase(userRepo, refreshTokenRepo, revokedTokenRepo, auditRepo, passwordService, jwtService, refreshTokenService, time.Duration(cfg.JWT.RefreshExpiry), permissionResolver)
+	webhookUsecase := usecases.NewWebhookUsecase(webhookRepo, webhookDeliveryRepo, auditRepo, infrastructure.NewWebhookSender(time.Duration(cfg.Webhooks.Timeout)), usecases.WebhookRetryPolicy{
+		MaxAttempts: cfg.Webhooks.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.Webhooks.Backoff),
+		MaxDelay:    time.Duration(cfg.Webhooks.MaxBackoff),
+	})
+	taskStream := usecases.NewTaskStream(cfg.Stream.ReplaySize)
+	blobStore, err := infrastructure.NewLocalBlobStore(cfg.Attachments.Directory)
+	if err != nil {
+		log.Fatalf("Failed to open the attachment store: %v", err)
+	}
+	attachmentUsecase := usecases.NewAttachmentUsecase(taskRepo, projectRepo, blobStore, auditRepo, cfg.Attachments.MaxSize)
+	workflow := cfg.TaskWorkflow()
+	taskEvents := usecases.TaskEventPublishers{webhookUsecase, taskStream, attachmentUsecase}
+	taskUsecase := usecases.NewTaskUsecase(taskRepo, taskHistoryRepo, commentRepo, projectRepo, auditRepo, taskEvents, workflow)
+	auditUsecase := usecases.NewAuditUsecase(auditRepo)
+	calendarUsecase := usecases.NewCalendarUsecase(calendarTokenRepo, userRepo, permissionResolver, taskUsecase, auditRepo, refreshTokenService, workflow)
+	tagUsecase := usecases.NewTagUsecase(tagRepo, taskRepo, taskHistoryRepo, auditRepo, taskEvents)
+	commentUsecase := usecases.NewCommentUsecase(commentRepo, taskUsecase, auditRepo)
+	projectUsecase := usecases.NewProjectUsecase(projectRepo, userRepo, taskRepo, auditRepo)
+	roleUsecase := usecases.NewRoleUsecase(roleRepo, userRepo, permissionResolver, auditRepo)
+	apiKeyUsecase := usecases.NewAPIKeyUsecase(apiKeyRepo, userRepo, permissionResolver, auditRepo, refreshTokenService)
+
+	// Single sign-on is only offered when an identity provider is configured
+	var oidcUsecase usecases.OIDCUsecase
+	if cfg.OIDC.Enabled() {
+		oidcClient := infrastructure.NewOIDCClient(infrastructure.OIDCConfig{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+			Timeout:      time.Duration(cfg.OIDC.Timeout),
+		})
+		oidcUsecase = usecases.NewOIDCUsecase(oidcClient, oidcLoginRepo, userUsecase, refreshTokenService, usecases.OIDCSettings{
+			UsernameClaim: cfg.OIDC.UsernameClaim,
+			RoleClaim:     cfg.OIDC.RoleClaim,
+			RoleMapping:   cfg.OIDC.RoleMapping,
+		})
+	}
+
+	// Move tasks created before projects into their owners' personal projects; tasks
+	// that are not moved keep the rules from before projects, so a failure is not fatal
+	if moved, err := projectUsecase.AssignLegacyTasks(context.Background()); err != nil {
+		log.Printf("Failed to assign tasks created before projects: %v", err)
+	} else if moved > 0 {
+		log.Printf("Moved %d tasks created before projects into personal projects", moved)
+	}
 
 	// Initialize controllers
-	apiController := controllers.NewApiController(taskUsecase, userUsecase)
+	apiController := controllers.NewApiController(taskUsecase, userUsecase, auditUsecase, webhookUsecase, taskStream, calendarUsecase, tagUsecase, commentUsecase, attachmentUsecase, projectUsecase, roleUsecase, apiKeyUsecase, oidcUsecase)
 
 	// Setup router
-	r := routers.SetupRouter(apiController, jwtService)
+	r := routers.SetupRouter(apiController, jwtService, revokedTokenRepo, permissionResolver, apiKeyUsecase, time.Duration(cfg.Server.RequestTimeout))
+
+	// Start sending due-date reminders in the background
+	var reminderScheduler *usecases.ReminderScheduler
+	if cfg.Reminders.Enabled {
+		var notifier infrastructure.Notifier = infrastructure.NewLogNotifier(nil)
+		if cfg.Reminders.Notifier == "smtp" {
+			smtp := cfg.Reminders.SMTP
+			notifier = infrastructure.NewSMTPNotifier(infrastructure.SMTPConfig{
+				Host:            smtp.Host,
+				Port:            smtp.Port,
+				Username:        smtp.Username,
+				Password:        smtp.Password,
+				From:            smtp.From,
+				RecipientDomain: smtp.RecipientDomain,
+			})
+		}
+
+		leadTimes := make([]time.Duration, len(cfg.Reminders.LeadTimes))
+		for i, lead := range cfg.Reminders.LeadTimes {
+			leadTimes[i] = time.Duration(lead)
+		}
+
+		reminderUsecase := usecases.NewReminderUsecase(taskRepo, userRepo, reminderRepo, notifier, leadTimes, time.Duration(cfg.Reminders.CatchUp), workflow)
+		reminderScheduler = usecases.NewReminderScheduler(reminderUsecase, time.Duration(cfg.Reminders.Interval))
+		reminderScheduler.Start()
+	}
+
+	// Start delivering webhooks in the background
+	webhookWorker := usecases.NewWebhookWorker(webhookUsecase, time.Duration(cfg.Webhooks.Interval))
+	webhookWorker.Start()
+
+	// Start the server and stop it cleanly on SIGINT or SIGTERM
+	server := &http.Server{Addr: cfg.Server.Address, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+
+	if reminderScheduler != nil {
+		if err := reminderScheduler.Stop(shutdownCtx); err != nil {
+			log.Printf("Reminder scheduler did not stop in time: %v", err)
+		}
+	}
+
+	if err := webhookWorker.Stop(shutdownCtx); err != nil {
+		log.Printf("Webhook worker did not stop in time: %v", err)
+	}
+
+	// Live task streams never finish on their own, so end them before waiting on requests
+	taskStream.Close()
 
-	// Start the server
-	if r.Run(":8080") != nil {
-		panic("Failed to start server")
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server did not shut down cleanly: %v", err)
 	}
 }
@@ -1,34 +1,115 @@
 package routers
 
 import (
+	"time"
+
 	"task-manager/Delivery/controllers"
+	domain "task-manager/Domain"
 	infrastructure "task-manager/Infrastructure"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(apiController controllers.ApiController, jwtService infrastructure.JWTService) *gin.Engine {
+func SetupRouter(apiController controllers.ApiController, jwtService infrastructure.JWTService, revocationList infrastructure.RevocationList, grantResolver infrastructure.GrantResolver, apiKeys infrastructure.APIKeyAuthenticator, requestTimeout time.Duration) *gin.Engine {
 	r := gin.Default()
+	r.Use(infrastructure.RequestIDMiddleware())
+
+	authMiddleware := infrastructure.NewAuthMiddleware(jwtService, revocationList, grantResolver, apiKeys)
+
+	// Each route needs the permissions it names; access to each task is checked by the
+	// task usecase, and each operation of a batch by its own permission
+	canRead := authMiddleware.Authorize(domain.PermissionTaskRead)
+	canCreate := authMiddleware.Authorize(domain.PermissionTaskCreate)
+	canUpdate := authMiddleware.Authorize(domain.PermissionTaskUpdate)
+	canDelete := authMiddleware.Authorize(domain.PermissionTaskDelete)
+	canComment := authMiddleware.Authorize(domain.PermissionCommentWrite)
+
+	// The live task stream stays open for as long as the client wants it, and an export
+	// or an attachment takes as long as the client needs to send or receive it, so these
+	// are registered before the request timeout is applied to every other route
+	r.GET("/tasks/stream", authMiddleware.Authenticate(), canRead, apiController.StreamTasks)
+	r.GET("/tasks/export", authMiddleware.Authenticate(), canRead, apiController.ExportTasks)
+	r.POST("/tasks/:id/attachments", authMiddleware.Authenticate(), canUpdate, apiController.UploadAttachment)
+	r.GET("/tasks/:id/attachments/:attachment_id", authMiddleware.Authenticate(), canRead, apiController.DownloadAttachment)
+
+	r.Use(infrastructure.TimeoutMiddleware(requestTimeout))
 
 	// Public routes
 	r.POST("/register", apiController.Register)
 	r.POST("/login", apiController.Login)
+	r.POST("/token/refresh", apiController.RefreshToken)
+
+	// Single sign-on sends the browser to the identity provider and back
+	r.GET("/auth/oidc/login", apiController.OIDCLogin)
+	r.GET("/auth/oidc/callback", apiController.OIDCCallback)
+
+	// Calendar clients cannot send a bearer token, so the feed checks the token in its URL
+	r.GET("/calendar.ics", apiController.GetCalendar)
 
 	// Protected routes
-	authMiddleware := infrastructure.NewAuthMiddleware(jwtService)
 	r.Use(authMiddleware.Authenticate())
 
-	// All users routes
-	r.GET("/tasks", apiController.GetTasks)
-	r.GET("/tasks/:id", apiController.GetTask)
+	r.POST("/logout", apiController.Logout)
+	r.GET("/tasks", canRead, apiController.GetTasks)
+	r.GET("/tasks/search", canRead, apiController.SearchTasks)
+	r.GET("/tasks/:id", canRead, apiController.GetTask)
+	r.GET("/tasks/:id/history", canRead, apiController.GetTaskHistory)
+	r.GET("/tasks/:id/subtree", canRead, apiController.GetSubtree)
+	r.GET("/tasks/:id/dependencies", canRead, apiController.GetDependencyGraph)
+	r.GET("/tasks/:id/occurrences", canRead, apiController.GetOccurrences)
+	r.GET("/tasks/:id/transitions", canRead, apiController.GetNextStates)
+	r.GET("/tasks/:id/comments", canRead, apiController.GetComments)
+	r.GET("/tasks/:id/attachments", canRead, apiController.GetAttachments)
+	r.POST("/tasks", canCreate, apiController.CreateTask)
+	r.POST("/tasks/batch", canRead, apiController.BatchTasks)
+	r.POST("/tasks/import", canCreate, apiController.ImportTasks)
+	r.PUT("/tasks/:id", canUpdate, apiController.UpdateTask)
+	r.DELETE("/tasks/:id", canDelete, apiController.DeleteTask)
+	r.POST("/tasks/:id/comments", canComment, apiController.AddComment)
+	r.PUT("/tasks/:id/comments/:comment_id", canComment, apiController.UpdateComment)
+	r.DELETE("/tasks/:id/comments/:comment_id", canComment, apiController.DeleteComment)
+	r.DELETE("/tasks/:id/attachments/:attachment_id", canUpdate, apiController.DeleteAttachment)
+	r.POST("/calendar/token", canRead, apiController.CreateCalendarToken)
+	r.DELETE("/calendar/token", apiController.RevokeCalendarToken)
+	r.POST("/api-keys", apiController.CreateAPIKey)
+	r.GET("/api-keys", apiController.GetAPIKeys)
+	r.DELETE("/api-keys/:id", apiController.RevokeAPIKey)
+	r.GET("/tags", canRead, apiController.GetTags)
+	r.PUT("/tags/:name", canUpdate, apiController.UpdateTag)
+	r.POST("/tags/merge", canUpdate, apiController.MergeTags)
+	r.POST("/tags/:name/rename", canUpdate, apiController.RenameTag)
+
+	// Project routes; membership and project roles are checked by the project usecase
+	r.GET("/projects", apiController.GetProjects)
+	r.POST("/projects", authMiddleware.Authorize(domain.PermissionProjectCreate), apiController.CreateProject)
+	r.GET("/projects/:id", apiController.GetProject)
+	r.PUT("/projects/:id", apiController.UpdateProject)
+	r.DELETE("/projects/:id", apiController.DeleteProject)
+	r.PUT("/projects/:id/members/:username", apiController.SetProjectMember)
+	r.DELETE("/projects/:id/members/:username", apiController.RemoveProjectMember)
 
-	adminAuthoriser := authMiddleware.Authorize("admin")
+	canPromote := authMiddleware.Authorize(domain.PermissionUserPromote)
+	canManageRoles := authMiddleware.Authorize(domain.PermissionRoleManage)
+	canReadAudit := authMiddleware.Authorize(domain.PermissionAuditRead)
+	canManageWebhooks := authMiddleware.Authorize(domain.PermissionWebhookManage)
 
-	// Admin only routes
-	r.POST("/promote", adminAuthoriser, apiController.PromoteUser)
-	r.POST("/tasks", adminAuthoriser, apiController.CreateTask)
-	r.PUT("/tasks/:id", adminAuthoriser, apiController.UpdateTask)
-	r.DELETE("/tasks/:id", adminAuthoriser, apiController.DeleteTask)
+	// User, role and integration routes
+	r.POST("/promote", canPromote, apiController.PromoteUser)
+	r.GET("/users/:username/roles", canPromote, apiController.GetUserRoles)
+	r.PUT("/users/:username/roles", canPromote, apiController.SetUserRoles)
+	r.GET("/permissions", canManageRoles, apiController.GetPermissions)
+	r.GET("/roles", canManageRoles, apiController.GetRoles)
+	r.POST("/roles", canManageRoles, apiController.CreateRole)
+	r.GET("/roles/:name", canManageRoles, apiController.GetRole)
+	r.PUT("/roles/:name", canManageRoles, apiController.UpdateRole)
+	r.DELETE("/roles/:name", canManageRoles, apiController.DeleteRole)
+	r.GET("/audit", canReadAudit, apiController.GetAuditEntries)
+	r.GET("/audit/verify", canReadAudit, apiController.VerifyAudit)
+	r.POST("/webhooks", canManageWebhooks, apiController.CreateWebhook)
+	r.GET("/webhooks", canManageWebhooks, apiController.GetWebhooks)
+	r.DELETE("/webhooks/:id", canManageWebhooks, apiController.DeleteWebhook)
+	r.GET("/webhooks/deliveries", canManageWebhooks, apiController.GetWebhookDeliveries)
+	r.POST("/webhooks/deliveries/:id/replay", canManageWebhooks, apiController.ReplayWebhookDelivery)
 
 	return r
 }
@@ -0,0 +1,180 @@
+package routers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"task-manager/Delivery/controllers"
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// requestTimeout is kept well below how long the slow handlers take
+const requestTimeout = 20 * time.Millisecond
+
+// slowController stands in for the real controller on the routes under test. Each
+// handler takes several times the request timeout and gives up, as the real ones do
+// once their context is done. Uploads are stored in blobs. Other routes are not
+// implemented.
+type slowController struct {
+	controllers.ApiController
+	blobs infrastructure.BlobStore
+}
+
+// GetTask waits for the request deadline, if there is one
+func (c slowController) GetTask(ctx *gin.Context) {
+	select {
+	case <-ctx.Request.Context().Done():
+		ctx.Status(http.StatusGatewayTimeout)
+	case <-time.After(5 * requestTimeout):
+		ctx.Status(http.StatusOK)
+	}
+}
+
+// ExportTasks streams a few lines, pausing before each
+func (c slowController) ExportTasks(ctx *gin.Context) {
+	streamSlowly(ctx, 5)
+}
+
+// UploadAttachment stores the request body the way the attachment usecase does
+func (c slowController) UploadAttachment(ctx *gin.Context) {
+	if err := c.blobs.Put(ctx.Request.Context(), ctx.Param("id")+"/upload", ctx.Request.Body); err != nil {
+		ctx.Status(http.StatusGatewayTimeout)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// DownloadAttachment streams a few lines, pausing before each
+func (c slowController) DownloadAttachment(ctx *gin.Context) {
+	streamSlowly(ctx, 5)
+}
+
+// slowReader hands out its contents a few bytes at a time, pausing before each read
+type slowReader struct {
+	r io.Reader
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	time.Sleep(requestTimeout / 4)
+	return r.r.Read(p[:min(len(p), 4)])
+}
+
+// streamSlowly writes the given number of lines, pausing before each, and stops early
+// once the request is cancelled
+func streamSlowly(ctx *gin.Context, lines int) {
+	ctx.Status(http.StatusOK)
+	for i := 1; i <= lines; i++ {
+		time.Sleep(requestTimeout / 2)
+		if ctx.Request.Context().Err() != nil {
+			return
+		}
+		fmt.Fprintf(ctx.Writer, "line %d\n", i)
+		ctx.Writer.Flush()
+	}
+}
+
+// expectedLines is what streamSlowly writes when it is not cut off
+func expectedLines(lines int) string {
+	var b strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+// allowAll is a revocation list that never revokes a token
+type allowAll struct{}
+
+func (allowAll) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return false, nil
+}
+
+// taskGrants gives every user the permissions to read and update tasks
+type taskGrants struct{}
+
+func (taskGrants) ResolveGrants(ctx context.Context, userID string) (domain.Grants, error) {
+	return domain.Grants{
+		Roles:       []string{domain.UserRole},
+		Permissions: []string{domain.PermissionTaskRead, domain.PermissionTaskUpdate},
+	}, nil
+}
+
+// newTestRouter sets up the routes with a short request timeout and returns the
+// Authorization header of a logged-in user
+func newTestRouter(t *testing.T, controller controllers.ApiController) (*gin.Engine, string) {
+	jwtService := infrastructure.NewJWTService("0123456789abcdef0123456789abcdef", "task-manager", time.Minute)
+	token, err := jwtService.GenerateToken("user-1", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return SetupRouter(controller, jwtService, allowAll{}, taskGrants{}, nil, requestTimeout), "Bearer " + token.Token
+}
+
+func serve(router *gin.Engine, auth string, req *http.Request) *httptest.ResponseRecorder {
+	req.Header.Set("Authorization", auth)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetupRouter_RequestTimeout(t *testing.T) {
+	router, auth := newTestRouter(t, slowController{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/task-1", nil)
+	w := serve(router, auth, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestSetupRouter_SlowExportIsNotCutOff(t *testing.T) {
+	router, auth := newTestRouter(t, slowController{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/export", nil)
+	w := serve(router, auth, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, expectedLines(5), w.Body.String())
+}
+
+func TestSetupRouter_SlowUploadIsNotCutOff(t *testing.T) {
+	blobs, err := infrastructure.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, auth := newTestRouter(t, slowController{blobs: blobs})
+
+	// the body takes several times the request timeout to arrive
+	req, _ := http.NewRequest(http.MethodPost, "/tasks/task-1/attachments", slowReader{strings.NewReader("a slowly uploaded file")})
+	w := serve(router, auth, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	stored, err := blobs.Open(context.Background(), "task-1/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stored.Close()
+	contents, _ := io.ReadAll(stored)
+	assert.Equal(t, "a slowly uploaded file", string(contents))
+}
+
+func TestSetupRouter_SlowDownloadIsNotCutOff(t *testing.T) {
+	router, auth := newTestRouter(t, slowController{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/task-1/attachments/attachment-1", nil)
+	w := serve(router, auth, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, expectedLines(5), w.Body.String())
+}
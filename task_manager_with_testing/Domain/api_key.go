@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognise
+const APIKeyPrefix = "tm_"
+
+// Limits on an API key's name and on how many keys a user can have
+const (
+	MaxAPIKeyNameLength = 100
+	MaxAPIKeysPerUser   = 20
+)
+
+// apiKeyHintLength is how much of a key is kept in the clear so its owner can tell
+// their keys apart
+const apiKeyHintLength = len(APIKeyPrefix) + 6
+
+// APIKey lets a script or service call the API on behalf of the user who created it,
+// limited to the permissions in its scopes. Only its hash is stored, so the key itself
+// is shown once, when it is created.
+type APIKey struct {
+	ID         string     `bson:"_id,omitempty" json:"id"`
+	UserID     string     `bson:"user_id" json:"-"`
+	Name       string     `bson:"name" json:"name"`
+	Hint       string     `bson:"hint" json:"hint"`
+	KeyHash    string     `bson:"key_hash" json:"-"`
+	Scopes     []string   `bson:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// Validate checks the key's name, scopes and expiry, trimming the name and putting the
+// scopes in the order of Permissions
+func (k *APIKey) Validate(now time.Time) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if utf8.RuneCountInString(k.Name) > MaxAPIKeyNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxAPIKeyNameLength)
+	}
+
+	if len(k.Scopes) == 0 {
+		return errors.New("scopes must list at least one permission")
+	}
+
+	for _, scope := range k.Scopes {
+		if !slices.Contains(Permissions, scope) {
+			return fmt.Errorf("unknown permission %q", scope)
+		}
+	}
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+
+	k.Scopes = SortPermissions(k.Scopes)
+	return nil
+}
+
+// APIKeyHint returns the start of the key that is stored in the clear
+func APIKeyHint(key string) string {
+	if len(key) <= apiKeyHintLength {
+		return key
+	}
+
+	return key[:apiKeyHintLength]
+}
+
+// Expired reports whether the key can no longer be used
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
+// Limit narrows the grants of the key's owner to the key's scopes, so a key never has
+// a permission its owner has lost. The key keeps only the roles whose every permission
+// is in its scopes, so a narrow key cannot act in a role, such as taking a workflow
+// transition, that its scopes do not justify.
+func (k APIKey) Limit(grants Grants) Grants {
+	limited := Grants{Roles: []string{}, Permissions: []string{}, RolePermissions: map[string][]string{}}
+	for _, permission := range grants.Permissions {
+		if slices.Contains(k.Scopes, permission) {
+			limited.Permissions = append(limited.Permissions, permission)
+		}
+	}
+
+	for _, role := range grants.Roles {
+		permissions, ok := grants.RolePermissions[role]
+		if ok && !slices.ContainsFunc(permissions, func(permission string) bool { return !slices.Contains(limited.Permissions, permission) }) {
+			limited.Roles = append(limited.Roles, role)
+			limited.RolePermissions[role] = permissions
+		}
+	}
+
+	return limited
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey_Validate(t *testing.T) {
+	now := time.Now()
+	key := APIKey{Name: " CI ", Scopes: []string{PermissionTaskCreate, PermissionTaskRead, PermissionTaskCreate}}
+	assert.NoError(t, key.Validate(now))
+	assert.Equal(t, "CI", key.Name)
+	assert.Equal(t, []string{PermissionTaskRead, PermissionTaskCreate}, key.Scopes)
+
+	past := now.Add(-time.Minute)
+	tests := []struct {
+		name string
+		key  APIKey
+		err  string
+	}{
+		{name: "no name", key: APIKey{Name: " ", Scopes: []string{PermissionTaskRead}}, err: "name is required"},
+		{name: "long name", key: APIKey{Name: strings.Repeat("a", MaxAPIKeyNameLength+1), Scopes: []string{PermissionTaskRead}}, err: "name must be at most 100 characters"},
+		{name: "no scopes", key: APIKey{Name: "CI"}, err: "scopes must list at least one permission"},
+		{name: "unknown scope", key: APIKey{Name: "CI", Scopes: []string{"task:fly"}}, err: `unknown permission "task:fly"`},
+		{name: "expired", key: APIKey{Name: "CI", Scopes: []string{PermissionTaskRead}, ExpiresAt: &past}, err: "expires_at must be in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.key.Validate(now), tt.err)
+		})
+	}
+}
+
+func TestAPIKey_Expired(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	key := APIKey{ExpiresAt: &expiresAt}
+
+	assert.False(t, key.Expired(now))
+	assert.True(t, key.Expired(expiresAt))
+	assert.False(t, APIKey{}.Expired(now))
+}
+
+func TestAPIKey_Limit(t *testing.T) {
+	key := APIKey{Scopes: []string{PermissionTaskRead, PermissionAuditRead}}
+	grants := GrantsOf([]Role{
+		{Name: "reader", Permissions: []string{PermissionTaskRead}},
+		{Name: "writer", Permissions: []string{PermissionTaskRead, PermissionTaskCreate}},
+	})
+
+	limited := key.Limit(grants)
+
+	// the key keeps only the roles its scopes cover in full
+	assert.Equal(t, []string{"reader"}, limited.Roles)
+	assert.Equal(t, []string{PermissionTaskRead}, limited.Permissions)
+}
+
+func TestAPIKeyHint(t *testing.T) {
+	assert.Equal(t, "tm_abcdef", APIKeyHint("tm_abcdefghijkl"))
+	assert.Equal(t, "tm_ab", APIKeyHint("tm_ab"))
+}
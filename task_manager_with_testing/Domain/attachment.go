@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTaskAttachments is how many files a task can have attached
+const MaxTaskAttachments = 20
+
+// maxFilenameLength bounds the name an attachment is stored and downloaded under, in bytes
+const maxFilenameLength = 255
+
+// Attachment describes a file attached to a task. The metadata is stored on the task
+// itself; the contents are kept in a blob store under the task and attachment IDs.
+// ContentType is sniffed from the contents rather than taken from the client.
+type Attachment struct {
+	ID          string    `bson:"id" json:"id"`
+	Filename    string    `bson:"filename" json:"filename"`
+	ContentType string    `bson:"content_type" json:"content_type"`
+	Size        int64     `bson:"size" json:"size"`
+	SHA256      string    `bson:"sha256" json:"sha256"`
+	UploadedBy  string    `bson:"uploaded_by" json:"uploaded_by"`
+	UploadedAt  time.Time `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// FindAttachment returns the task's attachment with the given ID
+func (t Task) FindAttachment(id string) (Attachment, bool) {
+	for _, attachment := range t.Attachments {
+		if attachment.ID == id {
+			return attachment, true
+		}
+	}
+
+	return Attachment{}, false
+}
+
+// CleanFilename turns the name a client gave a file into one that is safe to store and
+// send back in a Content-Disposition header: the directories and control characters
+// are dropped and long names are cut short, keeping their extension.
+func CleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "attachment"
+	}
+
+	if len(name) > maxFilenameLength {
+		ext := path.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = truncateUTF8(strings.TrimSuffix(name, ext), maxFilenameLength-len(ext)) + ext
+	}
+
+	return name
+}
+
+// truncateUTF8 cuts s down to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
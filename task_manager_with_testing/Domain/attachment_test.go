@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanFilename(t *testing.T) {
+	tests := map[string]string{
+		"notes.txt":              "notes.txt",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\me\report.pdf`: "report.pdf",
+		"  spaced out.md ":       "spaced out.md",
+		"say \"hi\"\r\n.txt":     "say hi.txt",
+		"":                       "attachment",
+		"..":                     "attachment",
+		"dir/":                   "dir",
+		"/":                      "attachment",
+		"r\u00e9sum\u00e9.pdf":   "r\u00e9sum\u00e9.pdf",
+		"bad\xffbyte.bin":        "badbyte.bin",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, CleanFilename(name), name)
+	}
+}
+
+func TestCleanFilename_Truncates(t *testing.T) {
+	cleaned := CleanFilename(strings.Repeat("é", 200) + ".tar.gz")
+	assert.LessOrEqual(t, len(cleaned), maxFilenameLength)
+	assert.True(t, strings.HasSuffix(cleaned, "é.gz"), cleaned)
+
+	cleaned = CleanFilename("name." + strings.Repeat("x", 300))
+	assert.Equal(t, maxFilenameLength, len(cleaned))
+}
+
+func TestTask_FindAttachment(t *testing.T) {
+	task := Task{Attachments: []Attachment{{ID: "a1"}, {ID: "a2", Filename: "second.txt"}}}
+
+	attachment, ok := task.FindAttachment("a2")
+	assert.True(t, ok)
+	assert.Equal(t, "second.txt", attachment.Filename)
+
+	_, ok = task.FindAttachment("missing")
+	assert.False(t, ok)
+}
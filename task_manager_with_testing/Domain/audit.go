@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditTaskCreate    = "task.create"
+	AuditTaskUpdate    = "task.update"
+	AuditTaskDelete    = "task.delete"
+	AuditUserRegister  = "user.register"
+	AuditUserPromote   = "user.promote"
+	AuditWebhookCreate = "webhook.create"
+	AuditWebhookDelete = "webhook.delete"
+
+	AuditCalendarTokenCreate = "calendar_token.create"
+	AuditCalendarTokenRevoke = "calendar_token.revoke"
+
+	AuditTagUpdate = "tag.update"
+	AuditTagRename = "tag.rename"
+	AuditTagMerge  = "tag.merge"
+
+	AuditCommentCreate = "comment.create"
+	AuditCommentUpdate = "comment.update"
+	AuditCommentDelete = "comment.delete"
+
+	AuditAttachmentCreate = "attachment.create"
+	AuditAttachmentDelete = "attachment.delete"
+
+	AuditProjectCreate       = "project.create"
+	AuditProjectUpdate       = "project.update"
+	AuditProjectDelete       = "project.delete"
+	AuditProjectMemberUpdate = "project_member.update"
+	AuditProjectMemberRemove = "project_member.remove"
+
+	AuditRoleCreate      = "role.create"
+	AuditRoleUpdate      = "role.update"
+	AuditRoleDelete      = "role.delete"
+	AuditUserRolesUpdate = "user_roles.update"
+
+	AuditAPIKeyCreate = "api_key.create"
+	AuditAPIKeyRevoke = "api_key.revoke"
+)
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+)
+
+// AuditChange is the value of one field before and after a mutation
+type AuditChange struct {
+	Field  string `bson:"field" json:"field"`
+	Before string `bson:"before,omitempty" json:"before,omitempty"`
+	After  string `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// AuditEntry records who changed what and when. Entries form a chain: each one
+// stores the hash of the entry before it, so editing or removing an entry breaks
+// every hash after it.
+type AuditEntry struct {
+	ID         string        `bson:"_id,omitempty" json:"id,omitempty"`
+	Sequence   int64         `bson:"sequence" json:"sequence"`
+	Timestamp  time.Time     `bson:"timestamp" json:"timestamp"`
+	RequestID  string        `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	ActorID    string        `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	Actor      string        `bson:"actor" json:"actor"`
+	Action     string        `bson:"action" json:"action"`
+	TargetType string        `bson:"target_type" json:"target_type"`
+	TargetID   string        `bson:"target_id" json:"target_id"`
+	Changes    []AuditChange `bson:"changes" json:"changes"`
+	PrevHash   string        `bson:"prev_hash" json:"prev_hash"`
+	Hash       string        `bson:"hash" json:"hash"`
+}
+
+// ComputeHash hashes every field of the entry except its ID and its own hash
+func (e AuditEntry) ComputeHash() string {
+	changes := e.Changes
+	if changes == nil {
+		changes = []AuditChange{}
+	}
+
+	// a struct marshals its fields in declaration order, so the encoding is stable
+	content, _ := json.Marshal(struct {
+		Sequence   int64         `json:"sequence"`
+		Timestamp  string        `json:"timestamp"`
+		RequestID  string        `json:"request_id"`
+		ActorID    string        `json:"actor_id"`
+		Actor      string        `json:"actor"`
+		Action     string        `json:"action"`
+		TargetType string        `json:"target_type"`
+		TargetID   string        `json:"target_id"`
+		Changes    []AuditChange `json:"changes"`
+		PrevHash   string        `json:"prev_hash"`
+	}{
+		Sequence:   e.Sequence,
+		Timestamp:  e.Timestamp.UTC().Format(time.RFC3339Nano),
+		RequestID:  e.RequestID,
+		ActorID:    e.ActorID,
+		Actor:      e.Actor,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Changes:    changes,
+		PrevHash:   e.PrevHash,
+	})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain sets the sequence, previous hash and hash of an entry appended after the given one.
+// The timestamp is truncated to milliseconds so it survives a round trip through MongoDB.
+func (e AuditEntry) Chain(previous *AuditEntry) AuditEntry {
+	e.Sequence = 1
+	e.PrevHash = ""
+	if previous != nil {
+		e.Sequence = previous.Sequence + 1
+		e.PrevHash = previous.Hash
+	}
+
+	e.Timestamp = e.Timestamp.UTC().Truncate(time.Millisecond)
+	e.Hash = e.ComputeHash()
+	return e
+}
+
+// AuditVerification is the result of checking the audit chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// VerifyAuditChain checks that entries, ordered by sequence, form an unbroken hash chain
+func VerifyAuditChain(entries []AuditEntry) AuditVerification {
+	var previous *AuditEntry
+	for i := range entries {
+		entry := entries[i]
+		expectedSequence := int64(1)
+		expectedPrevHash := ""
+		if previous != nil {
+			expectedSequence = previous.Sequence + 1
+			expectedPrevHash = previous.Hash
+		}
+
+		var reason string
+		switch {
+		case entry.Sequence != expectedSequence:
+			reason = fmt.Sprintf("expected sequence %d", expectedSequence)
+		case entry.PrevHash != expectedPrevHash:
+			reason = "previous hash does not match"
+		case entry.Hash != entry.ComputeHash():
+			reason = "entry hash does not match its content"
+		}
+
+		if reason != "" {
+			return AuditVerification{Valid: false, Entries: len(entries), BrokenAt: entry.Sequence, Reason: reason}
+		}
+
+		previous = &entry
+	}
+
+	return AuditVerification{Valid: true, Entries: len(entries)}
+}
+
+// AuditQuery filters the audit log; entries are returned newest first
+type AuditQuery struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	Cursor     string
+	Limit      int
+}
+
+// Validate checks the audit query
+func (q *AuditQuery) Validate() error {
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return errors.New("since must be before until")
+	}
+
+	if q.Limit < 0 || q.Limit > MaxAuditPageSize {
+		return fmt.Errorf("limit must be between 1 and %d", MaxAuditPageSize)
+	}
+
+	return nil
+}
+
+// AuditPage is one page of audit entries along with the cursor for the next page
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func auditChain(length int) []AuditEntry {
+	entries := []AuditEntry{}
+	var previous *AuditEntry
+	for i := 0; i < length; i++ {
+		entry := AuditEntry{
+			Timestamp:  time.Now(),
+			Actor:      "admin",
+			Action:     AuditTaskUpdate,
+			TargetType: "task",
+			TargetID:   "1",
+			Changes:    []AuditChange{{Field: "status", Before: "pending", After: "completed"}},
+		}.Chain(previous)
+
+		entries = append(entries, entry)
+		previous = &entries[len(entries)-1]
+	}
+
+	return entries
+}
+
+func TestAuditEntry_Chain(t *testing.T) {
+	entries := auditChain(2)
+
+	assert.Equal(t, int64(1), entries[0].Sequence)
+	assert.Empty(t, entries[0].PrevHash)
+	assert.Equal(t, int64(2), entries[1].Sequence)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.Equal(t, entries[1].Hash, entries[1].ComputeHash())
+	assert.Equal(t, time.UTC, entries[0].Timestamp.Location())
+	assert.Zero(t, entries[0].Timestamp.Nanosecond()%int(time.Millisecond))
+}
+
+func TestAuditEntry_ComputeHash(t *testing.T) {
+	entry := auditChain(1)[0]
+
+	withoutID := entry
+	withoutID.ID = "another-id"
+	assert.Equal(t, entry.ComputeHash(), withoutID.ComputeHash())
+
+	emptyChanges := entry
+	emptyChanges.Changes = nil
+	assert.Equal(t, emptyChanges.ComputeHash(), AuditEntry{
+		Sequence:   entry.Sequence,
+		Timestamp:  entry.Timestamp,
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Changes:    []AuditChange{},
+	}.ComputeHash())
+
+	changed := entry
+	changed.Actor = "someone else"
+	assert.NotEqual(t, entry.ComputeHash(), changed.ComputeHash())
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(entries []AuditEntry) []AuditEntry
+		brokenAt int64
+		reason   string
+	}{
+		{
+			name:   "untouched chain",
+			tamper: func(entries []AuditEntry) []AuditEntry { return entries },
+		},
+		{
+			name: "edited entry",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				entries[1].Changes[0].After = "pending"
+				return entries
+			},
+			brokenAt: 2,
+			reason:   "entry hash does not match its content",
+		},
+		{
+			name: "edited entry with recomputed hash",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				entries[1].Actor = "someone else"
+				entries[1].Hash = entries[1].ComputeHash()
+				return entries
+			},
+			brokenAt: 3,
+			reason:   "previous hash does not match",
+		},
+		{
+			name: "removed entry",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				return append(entries[:1], entries[2:]...)
+			},
+			brokenAt: 3,
+			reason:   "expected sequence 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := VerifyAuditChain(tt.tamper(auditChain(3)))
+
+			assert.Equal(t, tt.reason == "", result.Valid)
+			assert.Equal(t, tt.brokenAt, result.BrokenAt)
+			assert.Equal(t, tt.reason, result.Reason)
+		})
+	}
+}
+
+func TestVerifyAuditChain_Empty(t *testing.T) {
+	result := VerifyAuditChain(nil)
+
+	assert.True(t, result.Valid)
+	assert.Equal(t, 0, result.Entries)
+}
+
+func TestAuditQuery_Validate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		query    AuditQuery
+		expected string
+	}{
+		{name: "empty query", query: AuditQuery{}},
+		{name: "time range", query: AuditQuery{Since: now.Add(-time.Hour), Until: now, Limit: MaxAuditPageSize}},
+		{name: "since after until", query: AuditQuery{Since: now, Until: now.Add(-time.Hour)}, expected: "since must be before until"},
+		{name: "since equals until", query: AuditQuery{Since: now, Until: now}, expected: "since must be before until"},
+		{name: "negative limit", query: AuditQuery{Limit: -1}, expected: "limit must be between 1 and 200"},
+		{name: "limit too large", query: AuditQuery{Limit: MaxAuditPageSize + 1}, expected: "limit must be between 1 and 200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Operations that can be made in a batch
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchOperations caps the number of operations in a single batch
+const MaxBatchOperations = 500
+
+// BatchRequest is a list of task operations applied in order. Operations are applied
+// independently unless Atomic is set, in which case either all of them are applied or
+// none are.
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// Validate checks the size of the batch; each operation is validated on its own
+func (r *BatchRequest) Validate() error {
+	if len(r.Operations) == 0 || len(r.Operations) > MaxBatchOperations {
+		return fmt.Errorf("a batch must have between 1 and %d operations", MaxBatchOperations)
+	}
+
+	return nil
+}
+
+// BatchOperation creates, updates or deletes one task. Updates name the version they
+// were based on, like the If-Match header of a single update.
+type BatchOperation struct {
+	Op      string `json:"op"`
+	ID      string `json:"id,omitempty"`
+	Version *int64 `json:"version,omitempty"`
+	Task    *Task  `json:"task,omitempty"`
+}
+
+// Validate checks that the operation names what it needs, and validates its task
+func (o *BatchOperation) Validate() error {
+	switch o.Op {
+	case BatchCreate:
+		if o.ID != "" {
+			return errors.New("id must not be set when creating a task")
+		}
+	case BatchUpdate:
+		if o.ID == "" {
+			return errors.New("id is required")
+		}
+
+		if o.Version == nil {
+			return errors.New("version is required when updating a task")
+		}
+	case BatchDelete:
+		if o.ID == "" {
+			return errors.New("id is required")
+		}
+
+		return nil
+	default:
+		return errors.New("op must be create, update or delete")
+	}
+
+	if o.Task == nil {
+		return errors.New("task is required")
+	}
+
+	return o.Task.Validate()
+}
+
+// Permission returns the permission needed to make the operation
+func (o BatchOperation) Permission() string {
+	switch o.Op {
+	case BatchCreate:
+		return PermissionTaskCreate
+	case BatchDelete:
+		return PermissionTaskDelete
+	default:
+		return PermissionTaskUpdate
+	}
+}
+
+// BatchResult is the outcome of one operation in a batch. Task is the task as it was
+// created or updated; Err is nil when the operation was applied.
+type BatchResult struct {
+	Op   string
+	ID   string
+	Task *Task
+	Err  error
+}
+
+// TaskWrite is one write a repository applies as part of a batch
+type TaskWrite struct {
+	Op      string
+	ID      string
+	Version int64
+	Task    Task
+}
+
+// TaskWriteResult is the outcome of one write in a batch
+type TaskWriteResult struct {
+	Task Task
+	Err  error
+}
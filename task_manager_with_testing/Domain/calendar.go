@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Components a calendar feed can show tasks as
+const (
+	CalendarEvents = "vevent"
+	CalendarTodos  = "vtodo"
+)
+
+// CalendarToken lets a calendar client read its user's feed without the Authorization
+// header. A user has at most one; only its hash is stored, so the token itself is shown
+// once, when it is created.
+type CalendarToken struct {
+	UserID    string    `bson:"_id" json:"-"`
+	TokenHash string    `bson:"token_hash" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// CalendarFeed is a user's pending tasks as a calendar shows them. Tag changes whenever
+// the rendered feed would, so clients can poll it with conditional requests.
+type CalendarFeed struct {
+	Username  string
+	Component string
+	Tasks     []Task
+	Tag       string
+}
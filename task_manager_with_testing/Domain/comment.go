@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCommentLength is the longest a comment body may be, in characters
+const MaxCommentLength = 5000
+
+// Comment is a message in the discussion of a task. A comment that replies to another
+// names it as its parent. Deleted comments stay in the thread so their replies keep
+// their place, but their body is no longer shown.
+type Comment struct {
+	ID        string     `bson:"_id,omitempty" json:"id,omitempty"`
+	TaskID    string     `bson:"task_id" json:"task_id"`
+	ParentID  string     `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	AuthorID  string     `bson:"author_id" json:"author_id"`
+	Author    string     `bson:"author" json:"author"`
+	Body      string     `bson:"body" json:"body"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	Edited    bool       `bson:"edited,omitempty" json:"edited"`
+	EditedAt  *time.Time `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	Deleted   bool       `bson:"deleted,omitempty" json:"deleted"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Replies   []Comment  `bson:"-" json:"replies,omitempty"`
+}
+
+// Validate checks the comment's body and trims the space around it
+func (c *Comment) Validate() error {
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Body == "" {
+		return errors.New("body must not be empty")
+	}
+
+	if utf8.RuneCountInString(c.Body) > MaxCommentLength {
+		return fmt.Errorf("body must be at most %d characters", MaxCommentLength)
+	}
+
+	return nil
+}
+
+// CanModifyComment reports whether the identity may edit or delete the comment: only
+// its author and those who manage every task can
+func (i Identity) CanModifyComment(comment Comment) bool {
+	return i.CanManageAll() || (comment.AuthorID != "" && comment.AuthorID == i.UserID)
+}
+
+// CommentThreads nests the comments under the ones they reply to. Comments are expected
+// oldest first and each level of the threads keeps that order. A reply whose parent is
+// missing is shown at the top level rather than lost.
+func CommentThreads(comments []Comment) []Comment {
+	children := make(map[string][]Comment)
+	known := make(map[string]bool, len(comments))
+	for _, comment := range comments {
+		known[comment.ID] = true
+	}
+
+	roots := []Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != "" && known[comment.ParentID] {
+			children[comment.ParentID] = append(children[comment.ParentID], comment)
+		} else {
+			roots = append(roots, comment)
+		}
+	}
+
+	var nest func(comments []Comment) []Comment
+	nest = func(comments []Comment) []Comment {
+		for i := range comments {
+			if replies := children[comments[i].ID]; len(replies) > 0 {
+				comments[i].Replies = nest(replies)
+			}
+		}
+		return comments
+	}
+
+	return nest(roots)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComment_Validate(t *testing.T) {
+	comment := Comment{Body: "  Looks good \n"}
+	assert.NoError(t, comment.Validate())
+	assert.Equal(t, "Looks good", comment.Body)
+
+	comment = Comment{Body: " \t\n"}
+	assert.EqualError(t, comment.Validate(), "body must not be empty")
+
+	comment = Comment{Body: strings.Repeat("é", MaxCommentLength)}
+	assert.NoError(t, comment.Validate())
+
+	comment = Comment{Body: strings.Repeat("é", MaxCommentLength+1)}
+	assert.EqualError(t, comment.Validate(), fmt.Sprintf("body must be at most %d characters", MaxCommentLength))
+}
+
+func TestIdentity_CanModifyComment(t *testing.T) {
+	comment := Comment{AuthorID: "author"}
+
+	assert.True(t, Identity{UserID: "author"}.CanModifyComment(comment))
+	assert.True(t, Identity{UserID: "admin", Roles: []string{AdminRole}, Permissions: Permissions}.CanModifyComment(comment))
+	assert.False(t, Identity{UserID: "other"}.CanModifyComment(comment))
+	assert.False(t, Identity{}.CanModifyComment(Comment{}))
+}
+
+func TestCommentThreads(t *testing.T) {
+	comments := []Comment{
+		{ID: "1", Body: "First"},
+		{ID: "2", Body: "Second"},
+		{ID: "3", ParentID: "1", Body: "Reply to first"},
+		{ID: "4", ParentID: "3", Body: "Reply to reply"},
+		{ID: "5", ParentID: "1", Body: "Another reply"},
+		{ID: "6", ParentID: "gone", Body: "Orphan"},
+	}
+
+	assert.Equal(t, []Comment{
+		{ID: "1", Body: "First", Replies: []Comment{
+			{ID: "3", ParentID: "1", Body: "Reply to first", Replies: []Comment{
+				{ID: "4", ParentID: "3", Body: "Reply to reply"},
+			}},
+			{ID: "5", ParentID: "1", Body: "Another reply"},
+		}},
+		{ID: "2", Body: "Second"},
+		{ID: "6", ParentID: "gone", Body: "Orphan"},
+	}, CommentThreads(comments))
+
+	assert.Equal(t, []Comment{}, CommentThreads(nil))
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+)
+
+// MaxTaskDependencies is the most tasks a single task can depend on
+const MaxTaskDependencies = 50
+
+// ErrDependencyCycle is returned when tasks depend on each other in a loop
+var ErrDependencyCycle = errors.New("dependencies would create a cycle")
+
+// TaskNode is a task along with its subtasks
+type TaskNode struct {
+	Task     Task       `json:"task"`
+	Subtasks []TaskNode `json:"subtasks"`
+}
+
+// TaskDependency is an edge of the dependency graph: TaskID depends on DependsOn
+type TaskDependency struct {
+	TaskID    string `json:"task_id"`
+	DependsOn string `json:"depends_on"`
+}
+
+// TaskGraph is a task with everything it transitively depends on. Tasks are in
+// topological order: every task comes after the tasks it depends on.
+type TaskGraph struct {
+	Tasks        []Task           `json:"tasks"`
+	Dependencies []TaskDependency `json:"dependencies"`
+}
+
+// NewTaskGraph orders the tasks topologically and lists the dependencies between them;
+// dependencies on tasks that are not in the list are left out. Tasks that could go in
+// either order are ordered by ID.
+func NewTaskGraph(tasks []Task) (TaskGraph, error) {
+	byID := make(map[string]Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	graph := TaskGraph{Tasks: []Task{}, Dependencies: []TaskDependency{}}
+	remaining := make(map[string]int, len(tasks))
+	dependents := make(map[string][]string)
+	for _, task := range tasks {
+		remaining[task.ID] = 0
+		for _, dependency := range task.DependsOn {
+			if _, ok := byID[dependency]; !ok {
+				continue
+			}
+
+			remaining[task.ID]++
+			dependents[dependency] = append(dependents[dependency], task.ID)
+			graph.Dependencies = append(graph.Dependencies, TaskDependency{TaskID: task.ID, DependsOn: dependency})
+		}
+	}
+
+	ready := []string{}
+	for id, count := range remaining {
+		if count == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		id := ready[0]
+		ready = ready[1:]
+
+		graph.Tasks = append(graph.Tasks, byID[id])
+		for _, dependent := range dependents[id] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(graph.Tasks) < len(byID) {
+		return TaskGraph{}, ErrDependencyCycle
+	}
+
+	sort.Slice(graph.Dependencies, func(i, j int) bool {
+		if graph.Dependencies[i].TaskID != graph.Dependencies[j].TaskID {
+			return graph.Dependencies[i].TaskID < graph.Dependencies[j].TaskID
+		}
+		return graph.Dependencies[i].DependsOn < graph.Dependencies[j].DependsOn
+	})
+
+	return graph, nil
+}
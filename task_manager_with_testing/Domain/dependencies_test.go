@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskIDs(tasks []Task) []string {
+	ids := []string{}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	return ids
+}
+
+func TestNewTaskGraph(t *testing.T) {
+	graph, err := NewTaskGraph([]Task{
+		{ID: "release", DependsOn: []string{"build", "docs"}},
+		{ID: "docs", DependsOn: []string{"design"}},
+		{ID: "build", DependsOn: []string{"design", "deleted"}},
+		{ID: "design"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"design", "build", "docs", "release"}, taskIDs(graph.Tasks))
+	assert.Equal(t, []TaskDependency{
+		{TaskID: "build", DependsOn: "design"},
+		{TaskID: "docs", DependsOn: "design"},
+		{TaskID: "release", DependsOn: "build"},
+		{TaskID: "release", DependsOn: "docs"},
+	}, graph.Dependencies)
+}
+
+func TestNewTaskGraph_Empty(t *testing.T) {
+	graph, err := NewTaskGraph(nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, graph.Tasks)
+	assert.Empty(t, graph.Dependencies)
+}
+
+func TestNewTaskGraph_Cycle(t *testing.T) {
+	_, err := NewTaskGraph([]Task{
+		{ID: "a", DependsOn: []string{"c"}},
+		{ID: "b", DependsOn: []string{"a"}},
+		{ID: "c", DependsOn: []string{"b"}},
+		{ID: "d"},
+	})
+
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 )
@@ -10,7 +12,23 @@ type User struct {
 	ID       string `bson:"_id,omitempty" json:"id,omitempty"`
 	Username string             `bson:"username" json:"username" binding:"required"`
 	Password string             `bson:"password" json:"password" binding:"required"`
-	Role     string             `bson:"role" json:"role"`
+	// Role is the single role users held before they could hold several; it is only
+	// read from older records, whose Roles are empty
+	Role  string   `bson:"role,omitempty" json:"-"`
+	Roles []string `bson:"roles,omitempty" json:"roles,omitempty"`
+	// Issuer and Subject link a user created by single sign-on to their account at the
+	// identity provider; such users have no password
+	Issuer  string `bson:"issuer,omitempty" json:"-"`
+	Subject string `bson:"subject,omitempty" json:"-"`
+}
+
+// RoleNames returns the names of the roles the user holds
+func (u User) RoleNames() []string {
+	if len(u.Roles) == 0 && u.Role != "" {
+		return []string{u.Role}
+	}
+
+	return u.Roles
 }
 
 type Task struct {
@@ -18,6 +36,42 @@ type Task struct {
 	Title   string             `bson:"title" json:"title" binding:"required"`
 	DueDate time.Time          `bson:"due_date" json:"due_date" binding:"required"`
 	Status  string             `bson:"status" json:"status" binding:"required"`
+	OwnerID string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	// ProjectID is the project the task belongs to; tasks from before projects have none
+	ProjectID string `bson:"project_id,omitempty" json:"project_id,omitempty"`
+	Version int64              `bson:"version" json:"version"`
+	// ParentID is the task this one is a subtask of
+	ParentID string `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	// DependsOn lists the tasks that must be completed before this one
+	DependsOn []string `bson:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// Tags are the normalized labels the task is grouped by, in sorted order
+	Tags []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	// Recurrence is an RFC 5545 RRULE; completing the task creates the next instance
+	Recurrence string `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+	// Occurrence is the task's position in its recurring series, starting at 1
+	Occurrence int `bson:"occurrence,omitempty" json:"occurrence,omitempty"`
+	// NextID is the instance created when this recurring task was completed
+	NextID string `bson:"next_id,omitempty" json:"next_id,omitempty"`
+	// Attachments describes the files attached to the task; they are added and removed
+	// through their own endpoints, never by updating the task
+	Attachments []Attachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
+}
+
+// TaskVersion is a snapshot of a task as it was after one of its changes
+type TaskVersion struct {
+	TaskID     string    `bson:"task_id" json:"task_id"`
+	Version    int64     `bson:"version" json:"version"`
+	Title      string    `bson:"title" json:"title"`
+	DueDate    time.Time `bson:"due_date" json:"due_date"`
+	Status     string    `bson:"status" json:"status"`
+	OwnerID    string    `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	ProjectID  string    `bson:"project_id,omitempty" json:"project_id,omitempty"`
+	ParentID   string    `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	DependsOn  []string  `bson:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Tags       []string  `bson:"tags,omitempty" json:"tags,omitempty"`
+	Recurrence string    `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+	ModifiedBy string    `bson:"modified_by" json:"modified_by"`
+	ModifiedAt time.Time `bson:"modified_at" json:"modified_at"`
 }
 
 func (t *Task) Validate() error {
@@ -29,20 +83,151 @@ func (t *Task) Validate() error {
 		return errors.New("due date is required")
 	}
 
+	// which statuses exist and how a task moves between them is up to the workflow
 	if t.Status == "" {
 		return errors.New("status is required")
 	}
 
-	if t.Status != "pending" && t.Status != "completed" {
-		return errors.New("status must be either pending or completed")
+	if len(t.DependsOn) > MaxTaskDependencies {
+		return fmt.Errorf("a task can depend on at most %d tasks", MaxTaskDependencies)
+	}
+
+	for _, id := range t.DependsOn {
+		if id == "" {
+			return errors.New("dependency IDs must not be empty")
+		}
+	}
+
+	if t.Recurrence != "" {
+		rule, err := ParseRecurrenceRule(t.Recurrence)
+		if err != nil {
+			return err
+		}
+
+		// the due date is the first occurrence of the rest of the series
+		if err := rule.validateStart(t.DueDate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AdminRole is the built-in role that grants every permission
+const AdminRole = "admin"
+
+// Identity is the authenticated user a request is made on behalf of, along with the
+// roles they hold and the permissions those roles give them. APIKeyID is set when the
+// request was made with an API key, whose scopes narrow the permissions.
+type Identity struct {
+	UserID      string
+	Username    string
+	Roles       []string
+	Permissions []string
+	APIKeyID    string
+}
+
+// Can reports whether the user has the given permission
+func (i Identity) Can(permission string) bool {
+	return slices.Contains(i.Permissions, permission)
+}
+
+// HasRole reports whether the user holds the given role
+func (i Identity) HasRole(role string) bool {
+	return slices.Contains(i.Roles, role)
+}
+
+// CanManageAll reports whether the user may see and change every task and project
+func (i Identity) CanManageAll() bool {
+	return i.Can(PermissionTaskManageAll)
+}
+
+// CanModify reports whether the user may change the given task on their own account,
+// without a project role: they manage every task or own it
+func (i Identity) CanModify(task Task) bool {
+	return i.CanManageAll() || (task.OwnerID != "" && task.OwnerID == i.UserID)
+}
+
+// AccessToken is a signed JWT along with the claims needed to revoke it
+type AccessToken struct {
+	Token     string
+	ID        string
+	Username  string
+	ExpiresAt time.Time
+}
+
+// RefreshToken is the stored, hashed form of a refresh token. Tokens rotated from
+// the same login share a family so a reused token can revoke all of them.
+type RefreshToken struct {
+	ID              string    `bson:"_id,omitempty" json:"id,omitempty"`
+	TokenHash       string    `bson:"token_hash" json:"-"`
+	FamilyID        string    `bson:"family_id" json:"family_id"`
+	Username        string    `bson:"username" json:"username"`
+	AccessTokenID   string    `bson:"access_token_id" json:"access_token_id"`
+	AccessExpiresAt time.Time `bson:"access_expires_at" json:"access_expires_at"`
+	ExpiresAt       time.Time `bson:"expires_at" json:"expires_at"`
+	Used            bool      `bson:"used" json:"used"`
+	Revoked         bool      `bson:"revoked" json:"revoked"`
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Default and maximum number of tasks returned in a single page
+const (
+	DefaultTaskPageSize = 20
+	MaxTaskPageSize     = 100
+)
+
+// TaskQuery holds the filters, sort order and paging options for listing tasks
+type TaskQuery struct {
+	OwnerID     string
+	// ProjectID limits the tasks to those of one project
+	ProjectID   string
+	// Visibility limits the tasks to those a user can see; nil means every task
+	Visibility  *TaskVisibility
+	Status      string
+	// Statuses limits the tasks to those in any of the statuses, on top of Status
+	Statuses    []string
+	DueAfter    time.Time
+	DueBefore   time.Time
+	TitlePrefix string
+	// Tags holds groups of tags; a task matches when it has at least one tag of every group
+	Tags        [][]string
+	SortBy      string
+	Descending  bool
+	Cursor      string
+	Limit       int
+}
+
+// TaskPage is one page of tasks along with the cursor for the next page
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Validate checks the query and normalizes its tag filter
+func (q *TaskQuery) Validate() error {
+	tags, err := normalizeTagFilter(q.Tags)
+	if err != nil {
+		return err
+	}
+	q.Tags = tags
+
+	if q.SortBy != "" && q.SortBy != "due_date" && q.SortBy != "title" {
+		return errors.New("sort must be either due_date or title")
 	}
 
-	if t.Status == "completed" && time.Now().Before(t.DueDate) {
-		return errors.New("due date must be in the past")
+	if !q.DueAfter.IsZero() && !q.DueBefore.IsZero() && !q.DueAfter.Before(q.DueBefore) {
+		return errors.New("due_after must be before due_before")
 	}
 
-	if t.Status == "pending" && time.Now().After(t.DueDate) {
-		return errors.New("due date must be in the future")
+	if q.Limit < 0 || q.Limit > MaxTaskPageSize {
+		return fmt.Errorf("limit must be between 1 and %d", MaxTaskPageSize)
 	}
 
 	return nil
@@ -94,4 +279,50 @@ type BadRequestError struct {
 
 func (e *BadRequestError) Error() string {
 	return e.Message
-}
\ No newline at end of file
+}
+
+// ConflictError reports that a resource changed since the client last read it
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// AlreadyExistsError reports that a resource cannot be created because one with the
+// same name or identity already exists
+type AlreadyExistsError struct {
+	Message string
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return e.Message
+}
+
+type TimeoutError struct {
+	Message string
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Message
+}
+
+// TooLargeError reports a request body or upload over its size limit
+type TooLargeError struct {
+	Message string
+}
+
+func (e *TooLargeError) Error() string {
+	return e.Message
+}
+
+// NotAppliedError reports an operation of an all-or-nothing batch that was not applied
+// because another operation in the batch failed
+type NotAppliedError struct {
+	Message string
+}
+
+func (e *NotAppliedError) Error() string {
+	return e.Message
+}
@@ -80,13 +80,13 @@ func TestTask_Validate(t *testing.T) {
 			expected: "status is required",
 		},
 		{
-			name: "invalid status",
+			name: "status outside the default workflow",
 			task: Task{
 				Title:   "Task 5",
 				DueDate: time.Now().Add(24 * time.Hour),
-				Status:  "invalid",
+				Status:  "triage",
 			},
-			expected: "status must be either pending or completed",
+			expected: "",
 		},
 		{
 			name: "completed task with future due date",
@@ -95,7 +95,7 @@ func TestTask_Validate(t *testing.T) {
 				DueDate: time.Now().Add(24 * time.Hour),
 				Status:  "completed",
 			},
-			expected: "due date must be in the past",
+			expected: "",
 		},
 		{
 			name: "pending task with past due date",
@@ -104,7 +104,7 @@ func TestTask_Validate(t *testing.T) {
 				DueDate: time.Now().Add(-24 * time.Hour),
 				Status:  "pending",
 			},
-			expected: "due date must be in the future",
+			expected: "",
 		},
 	}
 
@@ -148,4 +148,85 @@ func TestInternalServerError(t *testing.T) {
 func TestBadRequestError(t *testing.T) {
 	err := &BadRequestError{Message: "Bad request"}
 	assert.EqualError(t, err, "Bad request")
-}
\ No newline at end of file
+}
+
+func TestAlreadyExistsError(t *testing.T) {
+	err := &AlreadyExistsError{Message: "Role already exists"}
+	assert.EqualError(t, err, "Role already exists")
+}
+
+func TestTimeoutError(t *testing.T) {
+	err := &TimeoutError{Message: "Request timed out"}
+	assert.EqualError(t, err, "Request timed out")
+}
+
+func TestTaskQuery_Validate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		query    TaskQuery
+		expected string
+	}{
+		{
+			name:     "empty query",
+			query:    TaskQuery{},
+			expected: "",
+		},
+		{
+			name:     "full query",
+			query:    TaskQuery{Status: "pending", DueAfter: now, DueBefore: now.Add(time.Hour), TitlePrefix: "Task", SortBy: "title", Limit: 10},
+			expected: "",
+		},
+		{
+			name:     "invalid sort",
+			query:    TaskQuery{SortBy: "status"},
+			expected: "sort must be either due_date or title",
+		},
+		{
+			name:     "inverted due date range",
+			query:    TaskQuery{DueAfter: now.Add(time.Hour), DueBefore: now},
+			expected: "due_after must be before due_before",
+		},
+		{
+			name:     "limit too large",
+			query:    TaskQuery{Limit: MaxTaskPageSize + 1},
+			expected: "limit must be between 1 and 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIdentity_CanModify(t *testing.T) {
+	owned := Task{ID: "1", OwnerID: "owner-id"}
+	unowned := Task{ID: "2"}
+
+	tests := []struct {
+		name     string
+		identity Identity
+		task     Task
+		expected bool
+	}{
+		{"owner", Identity{UserID: "owner-id", Roles: []string{UserRole}}, owned, true},
+		{"other user", Identity{UserID: "other-id", Roles: []string{UserRole}}, owned, false},
+		{"admin", Identity{UserID: "admin-id", Roles: []string{AdminRole}, Permissions: Permissions}, owned, true},
+		{"user on unowned task", Identity{UserID: "owner-id", Roles: []string{UserRole}}, unowned, false},
+		{"anonymous on unowned task", Identity{}, unowned, false},
+		{"admin on unowned task", Identity{UserID: "admin-id", Roles: []string{AdminRole}, Permissions: Permissions}, unowned, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.identity.CanModify(tt.task))
+		})
+	}
+}
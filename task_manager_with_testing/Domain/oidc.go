@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExternalAccount is a user as an identity provider describes them after they log in
+// with single sign-on. Issuer and Subject identify the account for good; Username is
+// only what a new user is named after. Roles are nil when the provider does not
+// manage roles, in which case a user keeps the roles given to them here.
+type ExternalAccount struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Roles    []string
+}
+
+// CandidateUsername returns the name to try for a new user on the given attempt,
+// starting at 1, when earlier candidates were taken by other users
+func (a ExternalAccount) CandidateUsername(attempt int) string {
+	if attempt <= 1 {
+		return a.Username
+	}
+
+	return fmt.Sprintf("%s-%d", a.Username, attempt)
+}
+
+// OIDCLoginExpiry is how long a user has to log in at the identity provider
+const OIDCLoginExpiry = 10 * time.Minute
+
+// OIDCLogin is a single sign-on login in progress, between sending the browser to the
+// identity provider and its return. It is found by the hash of the state sent along
+// and can be completed once.
+type OIDCLogin struct {
+	StateHash    string    `bson:"_id"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalAccount_CandidateUsername(t *testing.T) {
+	account := ExternalAccount{Username: "alice"}
+
+	assert.Equal(t, "alice", account.CandidateUsername(1))
+	assert.Equal(t, "alice-2", account.CandidateUsername(2))
+	assert.Equal(t, "alice-10", account.CandidateUsername(10))
+}
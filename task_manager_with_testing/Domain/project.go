@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Roles a member can have in a project
+const (
+	// ProjectOwner manages the project and its members, and edits its tasks
+	ProjectOwner = "owner"
+	// ProjectEditor creates, changes and deletes the project's tasks
+	ProjectEditor = "editor"
+	// ProjectViewer reads the project's tasks
+	ProjectViewer = "viewer"
+)
+
+// Limits on a project's name, description and members
+const (
+	MaxProjectNameLength        = 100
+	MaxProjectDescriptionLength = 1000
+	MaxProjectMembers           = 100
+)
+
+// Project groups tasks and the users who work on them. Every user has a personal
+// project, created the first time they add a task without naming a project.
+type Project struct {
+	ID          string `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string `bson:"name" json:"name"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+	// PersonalOf is the user whose personal project this is
+	PersonalOf string          `bson:"personal_of,omitempty" json:"personal_of,omitempty"`
+	Members    []ProjectMember `bson:"members" json:"members"`
+	Version    int64           `bson:"version" json:"version"`
+	CreatedAt  time.Time       `bson:"created_at" json:"created_at"`
+}
+
+// ProjectMember is a user's role in a project
+type ProjectMember struct {
+	UserID   string `bson:"user_id" json:"user_id"`
+	Username string `bson:"username" json:"username"`
+	Role     string `bson:"role" json:"role"`
+}
+
+// Validate checks the project's name and description and trims the space around them
+func (p *Project) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if utf8.RuneCountInString(p.Name) > MaxProjectNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxProjectNameLength)
+	}
+
+	p.Description = strings.TrimSpace(p.Description)
+	if utf8.RuneCountInString(p.Description) > MaxProjectDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", MaxProjectDescriptionLength)
+	}
+
+	return nil
+}
+
+// ValidateProjectRole checks that the role is one a project member can have
+func ValidateProjectRole(role string) error {
+	if role != ProjectOwner && role != ProjectEditor && role != ProjectViewer {
+		return fmt.Errorf("role must be %s, %s or %s", ProjectOwner, ProjectEditor, ProjectViewer)
+	}
+
+	return nil
+}
+
+// RoleOf returns the user's role in the project, or "" if they are not a member
+func (p Project) RoleOf(userID string) string {
+	for _, member := range p.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+
+	return ""
+}
+
+// SetMember adds the member to the project or changes their role
+func (p *Project) SetMember(member ProjectMember) {
+	for i := range p.Members {
+		if p.Members[i].UserID == member.UserID {
+			p.Members[i] = member
+			return
+		}
+	}
+
+	p.Members = append(p.Members, member)
+}
+
+// RemoveMember removes the user from the project and reports whether they were a member
+func (p *Project) RemoveMember(userID string) bool {
+	for i := range p.Members {
+		if p.Members[i].UserID == userID {
+			p.Members = slices.Delete(p.Members, i, i+1)
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasOwner reports whether any member of the project is an owner
+func (p Project) HasOwner() bool {
+	return slices.ContainsFunc(p.Members, func(member ProjectMember) bool {
+		return member.Role == ProjectOwner
+	})
+}
+
+// CanEditTasks reports whether the project role may create, change and delete tasks
+func CanEditTasks(role string) bool {
+	return role == ProjectOwner || role == ProjectEditor
+}
+
+// TaskAccess is what a user may do with tasks. Admins manage every task, and other users
+// the tasks of their projects as their role there allows. Tasks from before projects,
+// which have none, can only be managed by their owner.
+type TaskAccess struct {
+	Identity Identity
+	// Roles maps the IDs of the user's projects to their role in each
+	Roles map[string]string
+	// PersonalProjectID is the ID of the user's personal project, once it is known
+	PersonalProjectID string
+}
+
+// ProjectRole returns the user's role in a project, or "" if they are not a member.
+// Admins act as owners of every project.
+func (a TaskAccess) ProjectRole(projectID string) string {
+	if a.Identity.CanManageAll() {
+		return ProjectOwner
+	}
+
+	return a.Roles[projectID]
+}
+
+// TaskRole returns the user's role for a task, or "" if they cannot see it
+func (a TaskAccess) TaskRole(task Task) string {
+	if task.ProjectID != "" {
+		return a.ProjectRole(task.ProjectID)
+	}
+
+	if a.Identity.CanModify(task) {
+		return ProjectOwner
+	}
+
+	return ""
+}
+
+// CanView reports whether the user can see the task
+func (a TaskAccess) CanView(task Task) bool {
+	return a.TaskRole(task) != ""
+}
+
+// CanEdit reports whether the user can change or delete the task
+func (a TaskAccess) CanEdit(task Task) bool {
+	return CanEditTasks(a.TaskRole(task))
+}
+
+// Visibility limits task queries to the tasks the user can see; admins see every task
+// and get nil
+func (a TaskAccess) Visibility() *TaskVisibility {
+	if a.Identity.CanManageAll() {
+		return nil
+	}
+
+	visibility := &TaskVisibility{UserID: a.Identity.UserID, ProjectIDs: []string{}}
+	for id := range a.Roles {
+		visibility.ProjectIDs = append(visibility.ProjectIDs, id)
+	}
+	slices.Sort(visibility.ProjectIDs)
+
+	return visibility
+}
+
+// TaskVisibility describes the tasks a user can see: those in any of their projects,
+// and those without a project that they own
+type TaskVisibility struct {
+	UserID     string
+	ProjectIDs []string
+}
+
+// Includes reports whether the task is one the user can see
+func (v TaskVisibility) Includes(task Task) bool {
+	if task.ProjectID == "" {
+		return task.OwnerID != "" && task.OwnerID == v.UserID
+	}
+
+	return slices.Contains(v.ProjectIDs, task.ProjectID)
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject_Validate(t *testing.T) {
+	project := Project{Name: "  Launch  ", Description: " Ship it "}
+	assert.NoError(t, project.Validate())
+	assert.Equal(t, "Launch", project.Name)
+	assert.Equal(t, "Ship it", project.Description)
+
+	assert.EqualError(t, (&Project{Name: " "}).Validate(), "name is required")
+	assert.EqualError(t, (&Project{Name: strings.Repeat("a", MaxProjectNameLength+1)}).Validate(), "name must be at most 100 characters")
+	assert.Error(t, (&Project{Name: "Launch", Description: strings.Repeat("a", MaxProjectDescriptionLength+1)}).Validate())
+}
+
+func TestProject_Members(t *testing.T) {
+	project := Project{Members: []ProjectMember{{UserID: "1", Role: ProjectOwner}}}
+
+	project.SetMember(ProjectMember{UserID: "2", Role: ProjectViewer})
+	project.SetMember(ProjectMember{UserID: "2", Role: ProjectEditor})
+	assert.Len(t, project.Members, 2)
+	assert.Equal(t, ProjectEditor, project.RoleOf("2"))
+	assert.Equal(t, "", project.RoleOf("3"))
+
+	assert.True(t, project.RemoveMember("1"))
+	assert.False(t, project.RemoveMember("1"))
+	assert.False(t, project.HasOwner())
+
+	assert.EqualError(t, ValidateProjectRole("admin"), "role must be owner, editor or viewer")
+	assert.NoError(t, ValidateProjectRole(ProjectViewer))
+}
+
+func TestTaskAccess(t *testing.T) {
+	user := Identity{UserID: "1", Roles: []string{UserRole}}
+	access := TaskAccess{Identity: user, Roles: map[string]string{"p1": ProjectEditor, "p2": ProjectViewer}}
+
+	tests := []struct {
+		name    string
+		task    Task
+		canView bool
+		canEdit bool
+	}{
+		{name: "editor", task: Task{OwnerID: "2", ProjectID: "p1"}, canView: true, canEdit: true},
+		{name: "viewer", task: Task{OwnerID: "1", ProjectID: "p2"}, canView: true},
+		{name: "not a member", task: Task{OwnerID: "1", ProjectID: "p3"}},
+		{name: "own task without a project", task: Task{OwnerID: "1"}, canView: true, canEdit: true},
+		{name: "other task without a project", task: Task{OwnerID: "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.canView, access.CanView(tt.task))
+			assert.Equal(t, tt.canEdit, access.CanEdit(tt.task))
+			assert.Equal(t, tt.canView, access.Visibility().Includes(tt.task), "the visibility filter agrees with CanView")
+		})
+	}
+
+	assert.Equal(t, &TaskVisibility{UserID: "1", ProjectIDs: []string{"p1", "p2"}}, access.Visibility())
+}
+
+func TestTaskAccess_Admin(t *testing.T) {
+	access := TaskAccess{Identity: Identity{UserID: "1", Roles: []string{AdminRole}, Permissions: Permissions}}
+
+	assert.Nil(t, access.Visibility())
+	assert.Equal(t, ProjectOwner, access.ProjectRole("any"))
+	assert.True(t, access.CanEdit(Task{OwnerID: "2", ProjectID: "p1"}))
+	assert.True(t, access.CanEdit(Task{OwnerID: "2"}))
+}
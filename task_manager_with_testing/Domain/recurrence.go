@@ -0,0 +1,400 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies a recurrence rule can repeat at
+const (
+	FrequencyDaily   = "DAILY"
+	FrequencyWeekly  = "WEEKLY"
+	FrequencyMonthly = "MONTHLY"
+	FrequencyYearly  = "YEARLY"
+)
+
+// Default and maximum number of occurrences returned by a preview
+const (
+	DefaultOccurrencePreview = 10
+	MaxOccurrencePreview     = 100
+)
+
+const (
+	maxRecurrenceLength   = 256
+	maxRecurrenceInterval = 1000
+	// maxRecurrencePeriods bounds how many days, weeks, months or years are scanned
+	// for occurrences, so a series that is far behind cannot loop for long
+	maxRecurrencePeriods = 10000
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RecurrenceRule is the subset of an RFC 5545 RRULE that tasks support: FREQ,
+// INTERVAL, BYDAY, COUNT and UNTIL. Weeks start on Monday.
+type RecurrenceRule struct {
+	Frequency string
+	Interval  int
+	ByDay     []WeekdayNum
+	Count     int
+	Until     time.Time
+}
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. A non-zero ordinal picks the
+// nth such weekday of the month (or of the year for YEARLY rules), counting from the
+// end when negative.
+type WeekdayNum struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// ParseRecurrenceRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// A leading "RRULE:" is allowed.
+func ParseRecurrenceRule(text string) (RecurrenceRule, error) {
+	if len(text) > maxRecurrenceLength {
+		return RecurrenceRule{}, recurrenceError("must be at most %d characters", maxRecurrenceLength)
+	}
+
+	text = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(text)), "RRULE:")
+	rule := RecurrenceRule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(text, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" || value == "" {
+			return RecurrenceRule{}, recurrenceError("%q is not a NAME=VALUE pair", part)
+		}
+
+		if seen[name] {
+			return RecurrenceRule{}, recurrenceError("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				rule.Frequency = value
+			default:
+				err = recurrenceError("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(value); err != nil || rule.Interval < 1 || rule.Interval > maxRecurrenceInterval {
+				err = recurrenceError("INTERVAL must be between 1 and %d", maxRecurrenceInterval)
+			}
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(value); err != nil || rule.Count < 1 {
+				err = recurrenceError("COUNT must be a positive integer")
+			}
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		default:
+			err = recurrenceError("%s is not supported", name)
+		}
+
+		if err != nil {
+			return RecurrenceRule{}, err
+		}
+	}
+
+	if rule.Frequency == "" {
+		return RecurrenceRule{}, recurrenceError("FREQ is required")
+	}
+
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return RecurrenceRule{}, recurrenceError("COUNT and UNTIL cannot both be given")
+	}
+
+	for _, day := range rule.ByDay {
+		if day.Ordinal == 0 {
+			continue
+		}
+
+		switch {
+		case rule.Frequency != FrequencyMonthly && rule.Frequency != FrequencyYearly:
+			return RecurrenceRule{}, recurrenceError("BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY")
+		case rule.Frequency == FrequencyMonthly && (day.Ordinal < -5 || day.Ordinal > 5):
+			return RecurrenceRule{}, recurrenceError("BYDAY ordinals must be between -5 and 5 for MONTHLY rules")
+		}
+	}
+
+	return rule, nil
+}
+
+func recurrenceError(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid recurrence rule: "+format, args...)
+}
+
+// parseUntil accepts a UTC date-time (20240131T090000Z) or a date, which includes the whole day
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+
+	if until, err := time.Parse("20060102", value); err == nil {
+		return until.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+
+	return time.Time{}, recurrenceError("UNTIL must be a date (YYYYMMDD) or a UTC date-time (YYYYMMDDTHHMMSSZ)")
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	days := []WeekdayNum{}
+	for _, code := range strings.Split(value, ",") {
+		if len(code) < 2 {
+			return nil, recurrenceError("%q is not a BYDAY weekday", code)
+		}
+
+		prefix, name := code[:len(code)-2], code[len(code)-2:]
+		weekday, ok := weekdayCodes[name]
+		if !ok {
+			return nil, recurrenceError("%q is not a BYDAY weekday", code)
+		}
+
+		day := WeekdayNum{Weekday: weekday}
+		if prefix != "" {
+			ordinal, err := strconv.Atoi(prefix)
+			if err != nil || ordinal == 0 || ordinal < -53 || ordinal > 53 {
+				return nil, recurrenceError("%q has an invalid BYDAY ordinal", code)
+			}
+			day.Ordinal = ordinal
+		}
+
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+// Occurrences returns up to limit occurrences of a series that starts at start, in
+// order. The start counts as the first occurrence when the rule allows it.
+func (r RecurrenceRule) Occurrences(start time.Time, limit int) []time.Time {
+	occurrences := []time.Time{}
+	if limit <= 0 {
+		return occurrences
+	}
+
+	r.each(start, func(occurrence time.Time) bool {
+		occurrences = append(occurrences, occurrence)
+		return len(occurrences) < limit
+	})
+
+	return occurrences
+}
+
+// each calls yield with every occurrence from start on until it returns false or the series ends
+func (r RecurrenceRule) each(start time.Time, yield func(time.Time) bool) {
+	count := 0
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		for _, occurrence := range r.period(start, period) {
+			if occurrence.Before(start) {
+				continue
+			}
+
+			if !r.Until.IsZero() && occurrence.After(r.Until) {
+				return
+			}
+
+			count++
+			if r.Count > 0 && count > r.Count {
+				return
+			}
+
+			if !yield(occurrence) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the candidate occurrences, in order, of the nth day, week, month or
+// year of the series. They keep the start's time of day and location.
+func (r RecurrenceRule) period(start time.Time, n int) []time.Time {
+	step := n * r.Interval
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	}
+
+	switch r.Frequency {
+	case FrequencyDaily:
+		day := at(start.Year(), start.Month(), start.Day()+step)
+		if !r.onWeekday(day) {
+			return nil
+		}
+		return []time.Time{day}
+	case FrequencyWeekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(start.Year(), start.Month(), start.Day()+7*step)}
+		}
+
+		monday := start.Day() - (int(start.Weekday())+6)%7 + 7*step
+		days := []time.Time{}
+		for i := 0; i < 7; i++ {
+			if day := at(start.Year(), start.Month(), monday+i); r.onWeekday(day) {
+				days = append(days, day)
+			}
+		}
+		return days
+	case FrequencyMonthly:
+		first := at(start.Year(), start.Month()+time.Month(step), 1)
+		if len(r.ByDay) == 0 {
+			// months without the start's day of the month are skipped
+			day := at(first.Year(), first.Month(), start.Day())
+			if day.Month() != first.Month() {
+				return nil
+			}
+			return []time.Time{day}
+		}
+		return r.byDayBetween(first, at(first.Year(), first.Month()+1, 1))
+	case FrequencyYearly:
+		first := at(start.Year()+step, time.January, 1)
+		if len(r.ByDay) == 0 {
+			// February 29 only occurs in leap years
+			day := at(first.Year(), start.Month(), start.Day())
+			if day.Month() != start.Month() {
+				return nil
+			}
+			return []time.Time{day}
+		}
+		return r.byDayBetween(first, at(first.Year()+1, time.January, 1))
+	}
+
+	return nil
+}
+
+// onWeekday reports whether BYDAY allows the day; rules without BYDAY allow every day
+func (r RecurrenceRule) onWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	for _, byDay := range r.ByDay {
+		if byDay.Weekday == day.Weekday() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// byDayBetween returns the days from first up to end that BYDAY picks, ordinals
+// counting within that range
+func (r RecurrenceRule) byDayBetween(first, end time.Time) []time.Time {
+	byWeekday := make(map[time.Weekday][]time.Time)
+	for i := 0; ; i++ {
+		day := time.Date(first.Year(), first.Month(), first.Day()+i, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), first.Location())
+		if !day.Before(end) {
+			break
+		}
+		byWeekday[day.Weekday()] = append(byWeekday[day.Weekday()], day)
+	}
+
+	picked := make(map[time.Time]bool)
+	days := []time.Time{}
+	pick := func(day time.Time) {
+		if !picked[day] {
+			picked[day] = true
+			days = append(days, day)
+		}
+	}
+
+	for _, byDay := range r.ByDay {
+		candidates := byWeekday[byDay.Weekday]
+		switch {
+		case byDay.Ordinal == 0:
+			for _, day := range candidates {
+				pick(day)
+			}
+		case byDay.Ordinal > 0 && byDay.Ordinal <= len(candidates):
+			pick(candidates[byDay.Ordinal-1])
+		case byDay.Ordinal < 0 && -byDay.Ordinal <= len(candidates):
+			pick(candidates[len(candidates)+byDay.Ordinal])
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// validateStart checks that a series starting at start has that date as its first occurrence
+func (r RecurrenceRule) validateStart(start time.Time) error {
+	if !r.Until.IsZero() && r.Until.Before(start) {
+		return recurrenceError("UNTIL is before the due date")
+	}
+
+	first := r.Occurrences(start, 1)
+	if len(first) == 0 || !first[0].Equal(start) {
+		return recurrenceError("the due date must fall on a day the rule allows")
+	}
+
+	return nil
+}
+
+// remaining returns the rule for the rest of a series from its nth occurrence on;
+// ok is false when a COUNT has already run out
+func (r RecurrenceRule) remaining(occurrence int) (rule RecurrenceRule, ok bool) {
+	if r.Count > 0 && occurrence > 1 {
+		r.Count -= occurrence - 1
+		if r.Count < 1 {
+			return RecurrenceRule{}, false
+		}
+	}
+
+	return r, true
+}
+
+// Occurrences returns up to limit due dates of a recurring task's series, starting
+// with its own due date
+func (t *Task) Occurrences(limit int) ([]time.Time, error) {
+	rule, err := ParseRecurrenceRule(t.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, ok := rule.remaining(t.Occurrence)
+	if !ok {
+		return []time.Time{}, nil
+	}
+
+	return rule.Occurrences(t.DueDate, limit), nil
+}
+
+// NextOccurrence returns the due date of the instance that follows a recurring task
+// once it is completed, and its position in the series. Occurrences that are already
+// past are skipped. ok is false when the series has ended.
+func (t *Task) NextOccurrence(now time.Time) (next time.Time, position int, ok bool) {
+	rule, err := ParseRecurrenceRule(t.Recurrence)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	rule, ok = rule.remaining(t.Occurrence)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+
+	ok = false
+	position = max(t.Occurrence, 1) - 1
+	rule.each(t.DueDate, func(occurrence time.Time) bool {
+		position++
+		if occurrence.After(t.DueDate) && occurrence.After(now) {
+			next, ok = occurrence, true
+			return false
+		}
+		return true
+	})
+
+	return next, position, ok
+}
@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dates(times []time.Time) []string {
+	formatted := []string{}
+	for _, t := range times {
+		formatted = append(formatted, t.Format("2006-01-02 15:04 Mon"))
+	}
+
+	return formatted
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	rule, err := ParseRecurrenceRule("RRULE:freq=monthly;interval=2;byday=MO,-1fr;count=6")
+
+	assert.NoError(t, err)
+	assert.Equal(t, RecurrenceRule{
+		Frequency: FrequencyMonthly,
+		Interval:  2,
+		ByDay:     []WeekdayNum{{Weekday: time.Monday}, {Ordinal: -1, Weekday: time.Friday}},
+		Count:     6,
+	}, rule)
+
+	rule, err = ParseRecurrenceRule("FREQ=DAILY;UNTIL=20300131")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rule.Interval)
+	assert.Equal(t, time.Date(2030, time.January, 31, 23, 59, 59, 999999999, time.UTC), rule.Until)
+}
+
+func TestParseRecurrenceRule_Invalid(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		{rule: "", err: `invalid recurrence rule: "" is not a NAME=VALUE pair`},
+		{rule: "INTERVAL=2", err: "invalid recurrence rule: FREQ is required"},
+		{rule: "FREQ=HOURLY", err: "invalid recurrence rule: FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY"},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", err: "invalid recurrence rule: FREQ is given more than once"},
+		{rule: "FREQ=DAILY;INTERVAL=0", err: "invalid recurrence rule: INTERVAL must be between 1 and 1000"},
+		{rule: "FREQ=DAILY;COUNT=-1", err: "invalid recurrence rule: COUNT must be a positive integer"},
+		{rule: "FREQ=DAILY;UNTIL=tomorrow", err: "invalid recurrence rule: UNTIL must be a date (YYYYMMDD) or a UTC date-time (YYYYMMDDTHHMMSSZ)"},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20300101", err: "invalid recurrence rule: COUNT and UNTIL cannot both be given"},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", err: `invalid recurrence rule: "XX" is not a BYDAY weekday`},
+		{rule: "FREQ=MONTHLY;BYDAY=0MO", err: `invalid recurrence rule: "0MO" has an invalid BYDAY ordinal`},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", err: "invalid recurrence rule: BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY"},
+		{rule: "FREQ=MONTHLY;BYDAY=6MO", err: "invalid recurrence rule: BYDAY ordinals must be between -5 and 5 for MONTHLY rules"},
+		{rule: "FREQ=DAILY;BYMONTH=1", err: "invalid recurrence rule: BYMONTH is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := ParseRecurrenceRule(tt.rule)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestRecurrenceRule_Occurrences(t *testing.T) {
+	// Wednesday, January 31 2024
+	start := time.Date(2024, time.January, 31, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		expected []string
+	}{
+		{
+			name:     "daily every other day",
+			rule:     "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			start:    start,
+			expected: []string{"2024-01-31 09:30 Wed", "2024-02-02 09:30 Fri", "2024-02-04 09:30 Sun"},
+		},
+		{
+			name:     "daily on weekdays",
+			rule:     "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			start:    time.Date(2024, time.February, 2, 9, 30, 0, 0, time.UTC),
+			expected: []string{"2024-02-02 09:30 Fri", "2024-02-05 09:30 Mon", "2024-02-06 09:30 Tue"},
+		},
+		{
+			name:     "weekly on the start's weekday",
+			rule:     "FREQ=WEEKLY",
+			start:    start,
+			expected: []string{"2024-01-31 09:30 Wed", "2024-02-07 09:30 Wed", "2024-02-14 09:30 Wed"},
+		},
+		{
+			name:     "every other week on two days",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			start:    start,
+			expected: []string{"2024-01-31 09:30 Wed", "2024-02-12 09:30 Mon", "2024-02-14 09:30 Wed"},
+		},
+		{
+			name:     "monthly skips months without the day",
+			rule:     "FREQ=MONTHLY",
+			start:    start,
+			expected: []string{"2024-01-31 09:30 Wed", "2024-03-31 09:30 Sun", "2024-05-31 09:30 Fri"},
+		},
+		{
+			name:     "monthly on the last Wednesday",
+			rule:     "FREQ=MONTHLY;BYDAY=-1WE",
+			start:    start,
+			expected: []string{"2024-01-31 09:30 Wed", "2024-02-28 09:30 Wed", "2024-03-27 09:30 Wed"},
+		},
+		{
+			name:     "yearly on a leap day",
+			rule:     "FREQ=YEARLY",
+			start:    time.Date(2024, time.February, 29, 9, 30, 0, 0, time.UTC),
+			expected: []string{"2024-02-29 09:30 Thu", "2028-02-29 09:30 Tue", "2032-02-29 09:30 Sun"},
+		},
+		{
+			name:     "yearly on the first Monday of the year",
+			rule:     "FREQ=YEARLY;BYDAY=1MO",
+			start:    time.Date(2024, time.January, 1, 9, 30, 0, 0, time.UTC),
+			expected: []string{"2024-01-01 09:30 Mon", "2025-01-06 09:30 Mon", "2026-01-05 09:30 Mon"},
+		},
+		{
+			name:     "until includes its whole day",
+			rule:     "FREQ=DAILY;UNTIL=20240201",
+			start:    start,
+			expected: []string{"2024-01-31 09:30 Wed", "2024-02-01 09:30 Thu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.rule)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, dates(rule.Occurrences(tt.start, 3)))
+		})
+	}
+}
+
+func TestTaskValidate_Recurrence(t *testing.T) {
+	// a Monday a year from now
+	monday := time.Now().AddDate(1, 0, 0)
+	monday = monday.AddDate(0, 0, (8-int(monday.Weekday()))%7)
+
+	tests := []struct {
+		name   string
+		rule   string
+		status string
+		err    string
+	}{
+		{name: "valid", rule: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{name: "completed ahead of the due date", rule: "FREQ=WEEKLY", status: "completed"},
+		{name: "invalid rule", rule: "FREQ=SOMETIMES", err: "invalid recurrence rule: FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY"},
+		{name: "due date not allowed by the rule", rule: "FREQ=WEEKLY;BYDAY=TU", err: "invalid recurrence rule: the due date must fall on a day the rule allows"},
+		{name: "ends before the due date", rule: "FREQ=DAILY;UNTIL=20200101", err: "invalid recurrence rule: UNTIL is before the due date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{Title: "Chore", DueDate: monday, Status: "pending", Recurrence: tt.rule}
+			if tt.status != "" {
+				task.Status = tt.status
+			}
+			err := task.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTaskNextOccurrence(t *testing.T) {
+	due := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	task := Task{DueDate: due, Recurrence: "FREQ=WEEKLY;COUNT=4", Occurrence: 1}
+
+	next, position, ok := task.NextOccurrence(due)
+	assert.True(t, ok)
+	assert.Equal(t, due.AddDate(0, 0, 7), next)
+	assert.Equal(t, 2, position)
+
+	// occurrences that have already passed are skipped
+	next, position, ok = task.NextOccurrence(due.AddDate(0, 0, 15))
+	assert.True(t, ok)
+	assert.Equal(t, due.AddDate(0, 0, 21), next)
+	assert.Equal(t, 4, position)
+
+	_, _, ok = task.NextOccurrence(due.AddDate(0, 0, 22))
+	assert.False(t, ok)
+
+	task.Occurrence = 4
+	_, _, ok = task.NextOccurrence(due)
+	assert.False(t, ok)
+
+	occurrences, err := task.Occurrences(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{due}, occurrences)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reminder tells a task's owner that the task is coming due or overdue. LeadTime is
+// how long before the due date it is sent; zero means once the task is overdue.
+type Reminder struct {
+	TaskID   string        `bson:"task_id" json:"task_id"`
+	Title    string        `bson:"title" json:"title"`
+	DueDate  time.Time     `bson:"due_date" json:"due_date"`
+	LeadTime time.Duration `bson:"lead_time" json:"lead_time"`
+	Username string        `bson:"username" json:"username"`
+	SentAt   time.Time     `bson:"sent_at" json:"sent_at"`
+}
+
+// Key identifies a reminder; a task gets one reminder per lead time and due date,
+// so moving the due date schedules new ones
+func (r Reminder) Key() string {
+	return fmt.Sprintf("%s:%d:%d", r.TaskID, r.DueDate.Unix(), int64(r.LeadTime/time.Second))
+}
+
+// Subject is the one-line summary of the reminder
+func (r Reminder) Subject() string {
+	if r.LeadTime == 0 {
+		return fmt.Sprintf("Task overdue: %s", r.Title)
+	}
+
+	return fmt.Sprintf("Task due in %s: %s", r.LeadTime, r.Title)
+}
+
+// Body is the full text of the reminder
+func (r Reminder) Body() string {
+	return fmt.Sprintf("Hi %s,\n\nYour task %q is due at %s.\n", r.Username, r.Title, r.DueDate.UTC().Format(time.RFC1123))
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Permissions a role can grant
+const (
+	PermissionTaskRead   = "task:read"
+	PermissionTaskCreate = "task:create"
+	PermissionTaskUpdate = "task:update"
+	PermissionTaskDelete = "task:delete"
+	// PermissionTaskManageAll lets a user see and change every task and project,
+	// whatever their membership
+	PermissionTaskManageAll = "task:manage_all"
+	PermissionCommentWrite  = "comment:write"
+	PermissionProjectCreate = "project:create"
+	PermissionUserPromote   = "user:promote"
+	PermissionRoleManage    = "role:manage"
+	PermissionAuditRead     = "audit:read"
+	PermissionWebhookManage = "webhook:manage"
+)
+
+// Permissions lists every permission a role can grant
+var Permissions = []string{
+	PermissionTaskRead,
+	PermissionTaskCreate,
+	PermissionTaskUpdate,
+	PermissionTaskDelete,
+	PermissionTaskManageAll,
+	PermissionCommentWrite,
+	PermissionProjectCreate,
+	PermissionUserPromote,
+	PermissionRoleManage,
+	PermissionAuditRead,
+	PermissionWebhookManage,
+}
+
+// UserRole is the role every user is given when they register
+const UserRole = "user"
+
+// Limits on a role's description and on how many roles a user can hold
+const (
+	MaxRoleDescriptionLength = 200
+	MaxUserRoles             = 10
+)
+
+// roleNamePattern is the shape of a role name: lowercase letters, digits, dashes and
+// underscores, starting with a letter
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// Role is a named set of permissions. The admin and user roles are built in and cannot
+// be changed; admins create any others they need.
+type Role struct {
+	Name        string    `bson:"_id" json:"name"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	Permissions []string  `bson:"permissions" json:"permissions"`
+	BuiltIn     bool      `bson:"-" json:"built_in"`
+	Version     int64     `bson:"version" json:"version"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at,omitempty"`
+}
+
+// BuiltInRoles returns the roles that exist without being created
+func BuiltInRoles() []Role {
+	return []Role{
+		{
+			Name:        AdminRole,
+			Description: "Manages every task, user, role and integration",
+			Permissions: slices.Clone(Permissions),
+			BuiltIn:     true,
+		},
+		{
+			Name:        UserRole,
+			Description: "Works on their own tasks and the projects they are a member of",
+			Permissions: []string{PermissionTaskRead, PermissionTaskCreate, PermissionTaskUpdate, PermissionTaskDelete, PermissionCommentWrite, PermissionProjectCreate},
+			BuiltIn:     true,
+		},
+	}
+}
+
+// BuiltInRole returns the built-in role with the given name
+func BuiltInRole(name string) (Role, bool) {
+	for _, role := range BuiltInRoles() {
+		if role.Name == name {
+			return role, true
+		}
+	}
+
+	return Role{}, false
+}
+
+// Validate checks the role's name, description and permissions, trimming the
+// description and putting the permissions in the order of Permissions
+func (r *Role) Validate() error {
+	if !roleNamePattern.MatchString(r.Name) {
+		return errors.New("name must be 2 to 32 lowercase letters, digits, dashes or underscores, starting with a letter")
+	}
+
+	if _, ok := BuiltInRole(r.Name); ok {
+		return fmt.Errorf("%s is a built-in role", r.Name)
+	}
+
+	r.Description = strings.TrimSpace(r.Description)
+	if utf8.RuneCountInString(r.Description) > MaxRoleDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", MaxRoleDescriptionLength)
+	}
+
+	if len(r.Permissions) == 0 {
+		return errors.New("permissions must list at least one permission")
+	}
+
+	for _, permission := range r.Permissions {
+		if !slices.Contains(Permissions, permission) {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+
+	r.Permissions = SortPermissions(r.Permissions)
+	return nil
+}
+
+// SortPermissions returns the permissions without duplicates, in the order of Permissions
+func SortPermissions(permissions []string) []string {
+	sorted := []string{}
+	for _, permission := range Permissions {
+		if slices.Contains(permissions, permission) {
+			sorted = append(sorted, permission)
+		}
+	}
+
+	return sorted
+}
+
+// ValidateUserRoles checks the roles assigned to a user and returns them without
+// duplicates, in sorted order. Whether the roles exist is up to the caller.
+func ValidateUserRoles(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, errors.New("roles must list at least one role")
+	}
+
+	normalized := slices.Clone(roles)
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) > MaxUserRoles {
+		return nil, fmt.Errorf("a user can hold at most %d roles", MaxUserRoles)
+	}
+
+	for _, role := range normalized {
+		if !roleNamePattern.MatchString(role) {
+			return nil, fmt.Errorf("invalid role name %q", role)
+		}
+	}
+
+	return normalized, nil
+}
+
+// Grants are the roles a user holds and the permissions those roles give them.
+// RolePermissions lists the permissions of each role, so the roles can be narrowed
+// along with the permissions.
+type Grants struct {
+	Roles           []string            `json:"roles"`
+	Permissions     []string            `json:"permissions"`
+	RolePermissions map[string][]string `json:"-"`
+}
+
+// GrantsOf works out the grants of a user holding the given roles
+func GrantsOf(roles []Role) Grants {
+	grants := Grants{Roles: []string{}, RolePermissions: make(map[string][]string, len(roles))}
+	var permissions []string
+	for _, role := range roles {
+		grants.Roles = append(grants.Roles, role.Name)
+		grants.RolePermissions[role.Name] = append(grants.RolePermissions[role.Name], role.Permissions...)
+		permissions = append(permissions, role.Permissions...)
+	}
+
+	slices.Sort(grants.Roles)
+	grants.Roles = slices.Compact(grants.Roles)
+	grants.Permissions = SortPermissions(permissions)
+	return grants
+}
+
+// NewIdentity creates the identity of a user with the given grants
+func NewIdentity(userID, username string, grants Grants) Identity {
+	return Identity{UserID: userID, Username: username, Roles: grants.Roles, Permissions: grants.Permissions}
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole_Validate(t *testing.T) {
+	role := Role{Name: "auditor", Description: " Reads the log ", Permissions: []string{PermissionAuditRead, PermissionTaskRead, PermissionAuditRead}}
+	assert.NoError(t, role.Validate())
+	assert.Equal(t, "Reads the log", role.Description)
+	assert.Equal(t, []string{PermissionTaskRead, PermissionAuditRead}, role.Permissions)
+
+	tests := []struct {
+		name string
+		role Role
+		err  string
+	}{
+		{name: "invalid name", role: Role{Name: "Auditor", Permissions: []string{PermissionTaskRead}}, err: "name must be 2 to 32 lowercase letters, digits, dashes or underscores, starting with a letter"},
+		{name: "built-in name", role: Role{Name: AdminRole, Permissions: []string{PermissionTaskRead}}, err: "admin is a built-in role"},
+		{name: "no permissions", role: Role{Name: "auditor"}, err: "permissions must list at least one permission"},
+		{name: "unknown permission", role: Role{Name: "auditor", Permissions: []string{"task:fly"}}, err: `unknown permission "task:fly"`},
+		{name: "long description", role: Role{Name: "auditor", Description: strings.Repeat("a", MaxRoleDescriptionLength+1), Permissions: []string{PermissionTaskRead}}, err: "description must be at most 200 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.role.Validate(), tt.err)
+		})
+	}
+}
+
+func TestValidateUserRoles(t *testing.T) {
+	roles, err := ValidateUserRoles([]string{"user", "auditor", "user"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"auditor", "user"}, roles)
+
+	_, err = ValidateUserRoles(nil)
+	assert.EqualError(t, err, "roles must list at least one role")
+
+	_, err = ValidateUserRoles([]string{"Not A Role"})
+	assert.Error(t, err)
+}
+
+func TestGrantsOf(t *testing.T) {
+	user, _ := BuiltInRole(UserRole)
+	auditor := Role{Name: "auditor", Permissions: []string{PermissionAuditRead, PermissionTaskRead}}
+
+	grants := GrantsOf([]Role{user, auditor})
+
+	assert.Equal(t, []string{"auditor", UserRole}, grants.Roles)
+	assert.Equal(t, []string{PermissionTaskRead, PermissionTaskCreate, PermissionTaskUpdate, PermissionTaskDelete, PermissionCommentWrite, PermissionProjectCreate, PermissionAuditRead}, grants.Permissions)
+
+	identity := NewIdentity("1", "alice", grants)
+	assert.True(t, identity.Can(PermissionAuditRead))
+	assert.False(t, identity.Can(PermissionRoleManage))
+	assert.True(t, identity.HasRole("auditor"))
+	assert.False(t, identity.CanManageAll())
+}
+
+func TestUser_RoleNames(t *testing.T) {
+	assert.Equal(t, []string{AdminRole}, User{Role: AdminRole}.RoleNames())
+	assert.Equal(t, []string{"auditor", UserRole}, User{Role: AdminRole, Roles: []string{"auditor", UserRole}}.RoleNames())
+	assert.Empty(t, User{}.RoleNames())
+}
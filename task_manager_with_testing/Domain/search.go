@@ -0,0 +1,291 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default and maximum number of results returned by a search
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+const (
+	maxSearchTextLength = 256
+	minSearchPrefix     = 2
+	snippetLength       = 120
+)
+
+// TaskSearchQuery is a full-text search over task titles. The text is a list of
+// words that must all appear; `"quoted words"` must appear together in that order,
+// and a word ending in `*` matches any word starting with it.
+type TaskSearchQuery struct {
+	Text    string
+	OwnerID string
+	// Visibility limits the results to the tasks a user can see; nil means every task
+	Visibility *TaskVisibility
+	Limit      int
+}
+
+// Validate checks the search query
+func (q *TaskSearchQuery) Validate() error {
+	if strings.TrimSpace(q.Text) == "" {
+		return errors.New("q is required")
+	}
+
+	if len(q.Text) > maxSearchTextLength {
+		return fmt.Errorf("q must be at most %d characters", maxSearchTextLength)
+	}
+
+	if q.Limit < 0 || q.Limit > MaxSearchLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxSearchLimit)
+	}
+
+	_, err := ParseSearchText(q.Text)
+	return err
+}
+
+// TaskSearchResult is a task matching a search, with its relevance score and
+// its title with the matching words wrapped in <mark> tags
+type TaskSearchResult struct {
+	Task    Task    `json:"task"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchTerms is a parsed search text; words and prefixes are lower case
+type SearchTerms struct {
+	Words    []string
+	Prefixes []string
+	Phrases  [][]string
+}
+
+// ParseSearchText splits search text into words, prefixes and phrases
+func ParseSearchText(text string) (SearchTerms, error) {
+	var terms SearchTerms
+
+	for i, part := range strings.Split(text, `"`) {
+		if i%2 == 1 {
+			// text between quotes is a phrase
+			phrase := tokenStrings(part)
+			switch len(phrase) {
+			case 0:
+			case 1:
+				terms.Words = append(terms.Words, phrase[0])
+			default:
+				terms.Phrases = append(terms.Phrases, phrase)
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			words := tokenStrings(field)
+			if strings.HasSuffix(field, "*") && len(words) > 0 {
+				// only the last word of something like "re-plan*" is a prefix
+				prefix := words[len(words)-1]
+				if utf8.RuneCountInString(prefix) < minSearchPrefix {
+					return SearchTerms{}, fmt.Errorf("prefixes must be at least %d characters", minSearchPrefix)
+				}
+
+				terms.Words = append(terms.Words, words[:len(words)-1]...)
+				terms.Prefixes = append(terms.Prefixes, prefix)
+				continue
+			}
+
+			terms.Words = append(terms.Words, words...)
+		}
+	}
+
+	if strings.Count(text, `"`)%2 == 1 {
+		return SearchTerms{}, errors.New("q has an unterminated phrase")
+	}
+
+	if terms.IsEmpty() {
+		return SearchTerms{}, errors.New("q must contain at least one word")
+	}
+
+	return terms, nil
+}
+
+// IsEmpty reports whether there is nothing to search for
+func (s SearchTerms) IsEmpty() bool {
+	return len(s.Words) == 0 && len(s.Prefixes) == 0 && len(s.Phrases) == 0
+}
+
+// Match reports whether the text contains every term and scores how well it matches.
+// Exact words count more than prefixes and phrases more than either; the total is
+// scaled down for long texts so that a short title made of the search words ranks first.
+func (s SearchTerms) Match(text string) (float64, bool) {
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return 0, false
+	}
+
+	score := 0.0
+	for _, word := range s.Words {
+		count := 0
+		for _, token := range tokens {
+			if token.Text == word {
+				count++
+			}
+		}
+		if count == 0 {
+			return 0, false
+		}
+		score += 1 + math.Log(float64(count))
+	}
+
+	for _, prefix := range s.Prefixes {
+		count := 0
+		for _, token := range tokens {
+			if strings.HasPrefix(token.Text, prefix) {
+				count++
+			}
+		}
+		if count == 0 {
+			return 0, false
+		}
+		score += 0.5 * (1 + math.Log(float64(count)))
+	}
+
+	for _, phrase := range s.Phrases {
+		count := len(phraseStarts(tokens, phrase))
+		if count == 0 {
+			return 0, false
+		}
+		score += 2 * float64(len(phrase)) * (1 + math.Log(float64(count)))
+	}
+
+	return score / math.Sqrt(float64(len(tokens))), true
+}
+
+// Snippet returns the part of the text around the first match, HTML escaped, with the
+// matching words wrapped in <mark> tags
+func (s SearchTerms) Snippet(text string) string {
+	tokens := Tokenize(text)
+	marked := make([]bool, len(tokens))
+	for i, token := range tokens {
+		for _, word := range s.Words {
+			marked[i] = marked[i] || token.Text == word
+		}
+		for _, prefix := range s.Prefixes {
+			marked[i] = marked[i] || strings.HasPrefix(token.Text, prefix)
+		}
+	}
+	for _, phrase := range s.Phrases {
+		for _, start := range phraseStarts(tokens, phrase) {
+			for i := start; i < start+len(phrase); i++ {
+				marked[i] = true
+			}
+		}
+	}
+
+	// keep snippetLength bytes, starting a little before the first match
+	start, end := 0, len(text)
+	for i, token := range tokens {
+		if marked[i] {
+			start = max(0, token.Start-snippetLength/4)
+			break
+		}
+	}
+	if end-start > snippetLength {
+		end = start + snippetLength
+	}
+	start, end = runeBoundary(text, start), runeBoundary(text, end)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	position := start
+	for i, token := range tokens {
+		if !marked[i] || token.Start < start || token.End > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[position:token.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[token.Start:token.End]))
+		b.WriteString("</mark>")
+		position = token.End
+	}
+	b.WriteString(html.EscapeString(text[position:end]))
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// Token is a lower-cased word and its byte offsets in the original text
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into words made of letters and digits
+func Tokenize(text string) []Token {
+	tokens := []Token{}
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWordRune && start < 0:
+			start = i
+		case !isWordRune && start >= 0:
+			tokens = append(tokens, Token{Text: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, Token{Text: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+
+	return tokens
+}
+
+func tokenStrings(text string) []string {
+	words := []string{}
+	for _, token := range Tokenize(text) {
+		words = append(words, token.Text)
+	}
+
+	return words
+}
+
+// phraseStarts returns the index of every token where the phrase begins
+func phraseStarts(tokens []Token, phrase []string) []int {
+	starts := []int{}
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		matches := true
+		for j, word := range phrase {
+			if tokens[i+j].Text != word {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			starts = append(starts, i)
+		}
+	}
+
+	return starts
+}
+
+// runeBoundary moves a byte offset back to the start of the rune it falls in
+func runeBoundary(text string, offset int) int {
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+
+	return offset
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearchText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected SearchTerms
+		err      string
+	}{
+		{
+			name:     "words are lower cased",
+			text:     "Sprint  Review",
+			expected: SearchTerms{Words: []string{"sprint", "review"}},
+		},
+		{
+			name:     "prefix",
+			text:     "plan* review",
+			expected: SearchTerms{Words: []string{"review"}, Prefixes: []string{"plan"}},
+		},
+		{
+			name:     "phrase",
+			text:     `"sprint review" notes`,
+			expected: SearchTerms{Words: []string{"notes"}, Phrases: [][]string{{"sprint", "review"}}},
+		},
+		{
+			name:     "single word phrase is a word",
+			text:     `"sprint"`,
+			expected: SearchTerms{Words: []string{"sprint"}},
+		},
+		{
+			name:     "punctuation splits words",
+			text:     "re-plan*",
+			expected: SearchTerms{Words: []string{"re"}, Prefixes: []string{"plan"}},
+		},
+		{name: "short prefix", text: "p*", err: "prefixes must be at least 2 characters"},
+		{name: "unterminated phrase", text: `"sprint review`, err: "q has an unterminated phrase"},
+		{name: "no words", text: `"" *`, err: "q must contain at least one word"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := ParseSearchText(tt.text)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected.Words, terms.Words)
+			assert.Equal(t, tt.expected.Prefixes, terms.Prefixes)
+			assert.Equal(t, tt.expected.Phrases, terms.Phrases)
+		})
+	}
+}
+
+func TestSearchTerms_Match(t *testing.T) {
+	tests := []struct {
+		text    string
+		title   string
+		matches bool
+	}{
+		{text: "sprint review", title: "Review the sprint", matches: true},
+		{text: "sprint review", title: "Sprint planning", matches: false},
+		{text: "sprint", title: "Sprints", matches: false},
+		{text: "sprint*", title: "Sprints", matches: true},
+		{text: `"sprint review"`, title: "Sprint review notes", matches: true},
+		{text: `"sprint review"`, title: "Review the sprint", matches: false},
+		{text: "café", title: "Book the CAFÉ", matches: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text+" in "+tt.title, func(t *testing.T) {
+			terms, err := ParseSearchText(tt.text)
+			assert.NoError(t, err)
+
+			_, ok := terms.Match(tt.title)
+			assert.Equal(t, tt.matches, ok)
+		})
+	}
+}
+
+func TestSearchTerms_MatchRanking(t *testing.T) {
+	terms, err := ParseSearchText("sprint review")
+	assert.NoError(t, err)
+
+	short, _ := terms.Match("Sprint review")
+	long, _ := terms.Match("Write the notes for the sprint review meeting")
+	assert.Greater(t, short, long)
+
+	phrase, err := ParseSearchText(`"sprint review"`)
+	assert.NoError(t, err)
+
+	together, _ := phrase.Match("Sprint review notes")
+	apart, _ := terms.Match("Sprint notes review")
+	assert.Greater(t, together, apart)
+}
+
+func TestSearchTerms_Snippet(t *testing.T) {
+	terms, err := ParseSearchText(`plan* "sprint review"`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "<mark>Planning</mark> &amp; <mark>sprint</mark> <mark>review</mark>", terms.Snippet("Planning & sprint review"))
+
+	long := strings.Repeat("filler ", 40) + "sprint review " + strings.Repeat("filler ", 40) + "planning"
+	snippet := terms.Snippet(long)
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, "<mark>sprint</mark> <mark>review</mark>")
+	assert.NotContains(t, snippet, "<mark>planning</mark>")
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("Über-café, 2024!")
+
+	assert.Equal(t, []Token{
+		{Text: "über", Start: 0, End: 5},
+		{Text: "café", Start: 6, End: 11},
+		{Text: "2024", Start: 13, End: 17},
+	}, tokens)
+}
+
+func TestTaskSearchQuery_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    TaskSearchQuery
+		expected string
+	}{
+		{name: "valid", query: TaskSearchQuery{Text: "sprint", Limit: MaxSearchLimit}},
+		{name: "empty", query: TaskSearchQuery{Text: "  "}, expected: "q is required"},
+		{name: "too long", query: TaskSearchQuery{Text: strings.Repeat("a", 257)}, expected: "q must be at most 256 characters"},
+		{name: "limit too large", query: TaskSearchQuery{Text: "sprint", Limit: MaxSearchLimit + 1}, expected: "limit must be between 1 and 100"},
+		{name: "invalid text", query: TaskSearchQuery{Text: `"sprint`}, expected: "q has an unterminated phrase"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on the tags of a task and on tag filters
+const (
+	MaxTaskTags     = 20
+	MaxTagLength    = 32
+	MaxTagFilters   = 10
+	MaxTagsInFilter = 20
+)
+
+// Tag is an entry of a user's tag catalog. Tags come into being when a task first uses
+// them; the catalog only stores the ones given a color. Count is the number of the
+// user's tasks that carry the tag and is worked out when the catalog is read.
+type Tag struct {
+	OwnerID string `bson:"owner_id" json:"-"`
+	Name    string `bson:"name" json:"name"`
+	Color   string `bson:"color,omitempty" json:"color,omitempty"`
+	Count   int    `bson:"-" json:"count"`
+}
+
+// TagMerge merges tags into another one
+type TagMerge struct {
+	From []string `json:"from"`
+	Into string   `json:"into"`
+}
+
+// Validate checks the merge and normalizes its tags
+func (m *TagMerge) Validate() error {
+	if len(m.From) == 0 {
+		return errors.New("from must list the tags to merge")
+	}
+
+	if len(m.From) > MaxTagsInFilter {
+		return fmt.Errorf("at most %d tags can be merged at once", MaxTagsInFilter)
+	}
+
+	into, err := NormalizeTag(m.Into)
+	if err != nil {
+		return err
+	}
+
+	from, err := NormalizeTags(m.From)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(from, into) {
+		return errors.New("a tag cannot be merged into itself")
+	}
+
+	m.From, m.Into = from, into
+	return nil
+}
+
+// NormalizeTag returns the canonical form of a tag: trimmed, lower case, with runs of
+// spaces turned into a single dash. Tags may only hold letters, digits, '-', '_' and ':'.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if tag == "" {
+		return "", errors.New("tags must not be empty")
+	}
+
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != ':' {
+			return "", fmt.Errorf("tag %q may only contain letters, digits, '-', '_' and ':'", tag)
+		}
+	}
+
+	return tag, nil
+}
+
+// NormalizeTags normalizes each tag of a task and returns them sorted, without repeats
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > MaxTaskTags {
+		return nil, fmt.Errorf("a task can have at most %d tags", MaxTaskTags)
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// NormalizeTagColor checks that a color is a CSS hex color, #rgb or #rrggbb, and
+// returns it in lower case. An empty color is left empty.
+func NormalizeTagColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		return "", nil
+	}
+
+	if (len(color) != 4 && len(color) != 7) || color[0] != '#' {
+		return "", errors.New("color must be a hex color such as #1e90ff")
+	}
+
+	for _, c := range color[1:] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return "", errors.New("color must be a hex color such as #1e90ff")
+		}
+	}
+
+	return color, nil
+}
+
+// HasTags reports whether the task matches a tag filter: it must have at least one of
+// the tags of every group
+func (t Task) HasTags(filter [][]string) bool {
+	for _, group := range filter {
+		if !slices.ContainsFunc(group, func(tag string) bool { return slices.Contains(t.Tags, tag) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeTagFilter normalizes the tags of a filter, dropping repeats within a group
+func normalizeTagFilter(filter [][]string) ([][]string, error) {
+	if len(filter) > MaxTagFilters {
+		return nil, fmt.Errorf("at most %d tag filters can be given", MaxTagFilters)
+	}
+
+	normalized := make([][]string, 0, len(filter))
+	for _, group := range filter {
+		if len(group) > MaxTagsInFilter {
+			return nil, fmt.Errorf("a tag filter can list at most %d tags", MaxTagsInFilter)
+		}
+
+		tags, err := NormalizeTags(group)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(tags) > 0 {
+			normalized = append(normalized, tags)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+
+	return normalized, nil
+}
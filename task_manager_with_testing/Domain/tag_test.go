@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected string
+		err      string
+	}{
+		{name: "lower cased and trimmed", tag: "  Urgent ", expected: "urgent"},
+		{name: "spaces become a dash", tag: "Money \t Matters", expected: "money-matters"},
+		{name: "scoped", tag: "area:Backend_API", expected: "area:backend_api"},
+		{name: "letters of any script", tag: "Größe", expected: "größe"},
+		{name: "empty", tag: "   ", err: "tags must not be empty"},
+		{name: "slash", tag: "a/b", err: `tag "a/b" may only contain letters, digits, '-', '_' and ':'`},
+		{name: "comma", tag: "a,b", err: `tag "a,b" may only contain letters, digits, '-', '_' and ':'`},
+		{name: "too long", tag: strings.Repeat("é", MaxTagLength+1), err: fmt.Sprintf("is longer than %d characters", MaxTagLength)},
+		{name: "longest", tag: strings.Repeat("é", MaxTagLength), expected: strings.Repeat("é", MaxTagLength)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tag, err := NormalizeTag(test.tag)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, tag)
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"Work", "home", "work ", "Home"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home", "work"}, tags)
+
+	tags, err = NormalizeTags(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, tags)
+
+	many := make([]string, MaxTaskTags+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag%d", i)
+	}
+	_, err = NormalizeTags(many)
+	assert.EqualError(t, err, fmt.Sprintf("a task can have at most %d tags", MaxTaskTags))
+}
+
+func TestNormalizeTagColor(t *testing.T) {
+	for color, expected := range map[string]string{"#1E90FF": "#1e90ff", " #abc ": "#abc", "": ""} {
+		normalized, err := NormalizeTagColor(color)
+		assert.NoError(t, err, color)
+		assert.Equal(t, expected, normalized)
+	}
+
+	for _, color := range []string{"1e90ff", "#1e90f", "#ggg", "blue", "#1e90ff00"} {
+		_, err := NormalizeTagColor(color)
+		assert.Error(t, err, color)
+	}
+}
+
+func TestTask_HasTags(t *testing.T) {
+	task := Task{Tags: []string{"home", "urgent"}}
+
+	assert.True(t, task.HasTags(nil))
+	assert.True(t, task.HasTags([][]string{{"home"}, {"urgent"}}))
+	assert.True(t, task.HasTags([][]string{{"work", "home"}}))
+	assert.False(t, task.HasTags([][]string{{"home"}, {"work"}}))
+	assert.False(t, Task{}.HasTags([][]string{{"home"}}))
+}
+
+func TestTaskQuery_ValidateNormalizesTags(t *testing.T) {
+	query := TaskQuery{Tags: [][]string{{"Home", "home", "Work"}, {}, {"URGENT"}}}
+	assert.NoError(t, query.Validate())
+	assert.Equal(t, [][]string{{"home", "work"}, {"urgent"}}, query.Tags)
+
+	query = TaskQuery{Tags: make([][]string, MaxTagFilters+1)}
+	assert.Error(t, query.Validate())
+
+	query = TaskQuery{Tags: [][]string{{"a/b"}}}
+	assert.Error(t, query.Validate())
+}
+
+func TestTagMerge_Validate(t *testing.T) {
+	merge := TagMerge{From: []string{"Bug", "defect", "bug"}, Into: " Issue "}
+	assert.NoError(t, merge.Validate())
+	assert.Equal(t, TagMerge{From: []string{"bug", "defect"}, Into: "issue"}, merge)
+
+	merge = TagMerge{From: []string{"bug"}, Into: "BUG"}
+	assert.EqualError(t, merge.Validate(), "a tag cannot be merged into itself")
+
+	merge = TagMerge{Into: "issue"}
+	assert.EqualError(t, merge.Validate(), "from must list the tags to merge")
+}
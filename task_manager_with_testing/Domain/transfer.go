@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Formats tasks can be exported and imported in
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// MaxImportRecords caps the number of tasks in a single import
+const MaxImportRecords = 10000
+
+// What an import does with a task whose title the caller already uses
+const (
+	DuplicateFail   = "fail"
+	DuplicateSkip   = "skip"
+	DuplicateUpdate = "update"
+)
+
+// What an import did, or in a dry run would do, with each record
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+	ImportSkip   = "skip"
+	ImportFail   = "fail"
+)
+
+// TaskColumns are the columns of an exported task, in order. They are named after the
+// task's JSON fields.
+var TaskColumns = []string{"id", "title", "due_date", "status", "owner_id", "project_id", "version", "parent_id", "depends_on", "tags", "recurrence", "occurrence", "next_id"}
+
+// ImportColumns are the columns an import sets. The other task columns are kept by the
+// server, so an export can be imported again but they are ignored.
+var ImportColumns = []string{"title", "due_date", "status", "project_id", "parent_id", "depends_on", "tags", "recurrence"}
+
+// IgnoreColumn is the mapping target that drops a column from an import
+const IgnoreColumn = "-"
+
+// ImportOptions control how records are imported
+type ImportOptions struct {
+	// Format is FormatCSV, FormatJSON or FormatNDJSON
+	Format string
+	// Mapping renames columns of the file, or fields of its objects, to task columns or
+	// to IgnoreColumn
+	Mapping map[string]string
+	// DryRun checks every record and reports what would happen without saving anything
+	DryRun bool
+	// OnDuplicate is DuplicateFail, DuplicateSkip or DuplicateUpdate
+	OnDuplicate string
+}
+
+// Validate checks the options
+func (o *ImportOptions) Validate() error {
+	if err := ValidateFormat(o.Format); err != nil {
+		return err
+	}
+
+	if o.OnDuplicate != "" && o.OnDuplicate != DuplicateFail && o.OnDuplicate != DuplicateSkip && o.OnDuplicate != DuplicateUpdate {
+		return errors.New("on_duplicate must be fail, skip or update")
+	}
+
+	for source, target := range o.Mapping {
+		if target != IgnoreColumn && !IsTaskColumn(target) {
+			return fmt.Errorf("column %s is mapped to %s, which is not a task column", source, target)
+		}
+	}
+
+	return nil
+}
+
+// ValidateFormat checks that tasks can be exported and imported in the format
+func ValidateFormat(format string) error {
+	if format != FormatCSV && format != FormatJSON && format != FormatNDJSON {
+		return errors.New("format must be csv, json or ndjson")
+	}
+
+	return nil
+}
+
+// ImportRecord is one task read from an import. Fields lists the columns the record
+// had, which are the only ones changed when it updates a duplicate; Err is set when the
+// record could not be read.
+type ImportRecord struct {
+	Row    int
+	Task   Task
+	Fields map[string]bool
+	Err    error
+}
+
+// ImportRow is the outcome of one record. Row is the line of a CSV or NDJSON record, or
+// the position of a record in a JSON array starting at 1.
+type ImportRow struct {
+	Row    int    `json:"row"`
+	Title  string `json:"title,omitempty"`
+	Action string `json:"action"`
+	TaskID string `json:"task_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport sums up an import. In a dry run the counts are what would have happened.
+type ImportReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// IsTaskColumn reports whether the column is one of TaskColumns
+func IsTaskColumn(column string) bool {
+	for _, known := range TaskColumns {
+		if known == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsImportColumn reports whether an import sets the column
+func IsImportColumn(column string) bool {
+	for _, known := range ImportColumns {
+		if known == column {
+			return true
+		}
+	}
+
+	return false
+}
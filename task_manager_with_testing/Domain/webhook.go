@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Task lifecycle events sent to webhooks
+const (
+	EventTaskCreated   = "task.created"
+	EventTaskUpdated   = "task.updated"
+	EventTaskCompleted = "task.completed"
+	EventTaskDeleted   = "task.deleted"
+)
+
+// EventTypes lists every event a webhook can subscribe to
+var EventTypes = []string{EventTaskCreated, EventTaskUpdated, EventTaskCompleted, EventTaskDeleted}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	DefaultDeliveryPageSize = 50
+	MaxDeliveryPageSize     = 200
+)
+
+const maxWebhookURLLength = 2048
+
+// Webhook is an endpoint that receives task events. An empty event list subscribes to
+// every event. The secret signs each payload; it is only shown when the webhook is created.
+type Webhook struct {
+	ID        string    `bson:"_id,omitempty" json:"id,omitempty"`
+	URL       string    `bson:"url" json:"url" binding:"required"`
+	Events    []string  `bson:"events" json:"events"`
+	Secret    string    `bson:"secret" json:"secret,omitempty"`
+	CreatedBy string    `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Validate checks the webhook's URL and event filter
+func (w *Webhook) Validate() error {
+	if len(w.URL) > maxWebhookURLLength {
+		return fmt.Errorf("url must be at most %d characters", maxWebhookURLLength)
+	}
+
+	parsed, err := url.Parse(w.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	for _, event := range w.Events {
+		if !isEventType(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	return nil
+}
+
+// Subscribes reports whether the webhook wants events of the given type
+func (w *Webhook) Subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TaskEvent is the JSON payload posted to webhooks
+type TaskEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor"`
+	Task       Task      `json:"task"`
+}
+
+// WebhookAttempt records one try at delivering an event
+type WebhookAttempt struct {
+	Number      int       `bson:"number" json:"number"`
+	AttemptedAt time.Time `bson:"attempted_at" json:"attempted_at"`
+	StatusCode  int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMS  int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// WebhookDelivery is one event on its way to one webhook, along with every attempt
+// made so far. A replayed delivery starts over as a new delivery that names the
+// one it replays.
+type WebhookDelivery struct {
+	ID            string           `bson:"_id,omitempty" json:"id,omitempty"`
+	WebhookID     string           `bson:"webhook_id" json:"webhook_id"`
+	EventID       string           `bson:"event_id" json:"event_id"`
+	EventType     string           `bson:"event_type" json:"event_type"`
+	Payload       string           `bson:"payload" json:"payload"`
+	Status        string           `bson:"status" json:"status"`
+	Attempts      []WebhookAttempt `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time        `bson:"next_attempt_at" json:"next_attempt_at"`
+	ReplayOf      string           `bson:"replay_of,omitempty" json:"replay_of,omitempty"`
+	CreatedAt     time.Time        `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time        `bson:"updated_at" json:"updated_at"`
+}
+
+// DeliveryQuery filters the list of recent deliveries, newest first
+type DeliveryQuery struct {
+	WebhookID string
+	Status    string
+	Limit     int
+}
+
+// Validate checks the delivery filters
+func (q *DeliveryQuery) Validate() error {
+	if q.Status != "" && q.Status != DeliveryPending && q.Status != DeliverySucceeded && q.Status != DeliveryFailed {
+		return errors.New("status must be pending, succeeded or failed")
+	}
+
+	if q.Limit < 0 || q.Limit > MaxDeliveryPageSize {
+		return fmt.Errorf("limit must be between 1 and %d", MaxDeliveryPageSize)
+	}
+
+	return nil
+}
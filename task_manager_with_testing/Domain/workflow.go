@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Categories of workflow states. They tell the rest of the service what a state means
+// without it knowing the state's name.
+const (
+	// CategoryOpen states are still being worked on; their tasks get reminders and
+	// appear in calendar feeds
+	CategoryOpen = "open"
+	// CategoryCompleted states finish a task: they satisfy the tasks depending on it and
+	// move a recurring task on to its next instance
+	CategoryCompleted = "completed"
+	// CategoryCancelled states close a task without finishing it
+	CategoryCancelled = "cancelled"
+)
+
+// AnyState matches every state as the source of a transition
+const AnyState = "*"
+
+// Workflow lists the states a task can be in and the transitions allowed between them.
+// Tasks can be created in any state; after that their status only changes along a
+// transition.
+type Workflow struct {
+	// Initial is the state new instances of recurring tasks start in
+	Initial     string               `json:"initial"`
+	States      []WorkflowState      `json:"states"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+// WorkflowState is a status a task can have
+type WorkflowState struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// WorkflowTransition allows a task to move from one state to another. From may be
+// AnyState. Only users with one of the roles may take the transition; when no roles are
+// listed, anyone who can modify the task may.
+type WorkflowTransition struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// DefaultWorkflow is used unless the configuration defines another. Open tasks move
+// freely between pending, in_progress and blocked, can be completed or cancelled, and
+// completed tasks can be reopened; only admins can reopen a cancelled task.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		Initial: "pending",
+		States: []WorkflowState{
+			{Name: "pending", Category: CategoryOpen},
+			{Name: "in_progress", Category: CategoryOpen},
+			{Name: "blocked", Category: CategoryOpen},
+			{Name: "completed", Category: CategoryCompleted},
+			{Name: "cancelled", Category: CategoryCancelled},
+		},
+		Transitions: []WorkflowTransition{
+			{From: "pending", To: "in_progress"},
+			{From: "pending", To: "blocked"},
+			{From: "in_progress", To: "pending"},
+			{From: "in_progress", To: "blocked"},
+			{From: "blocked", To: "pending"},
+			{From: "blocked", To: "in_progress"},
+			{From: "pending", To: "completed"},
+			{From: "in_progress", To: "completed"},
+			{From: "completed", To: "pending"},
+			{From: "pending", To: "cancelled"},
+			{From: "in_progress", To: "cancelled"},
+			{From: "blocked", To: "cancelled"},
+			{From: "cancelled", To: "pending", Roles: []string{AdminRole}},
+		},
+	}
+}
+
+// Validate checks that the workflow is complete and consistent. A state without a
+// category is open.
+func (w *Workflow) Validate() error {
+	if len(w.States) == 0 {
+		return errors.New("workflow needs at least one state")
+	}
+
+	seen := make(map[string]bool, len(w.States))
+	for i, state := range w.States {
+		if state.Name == "" || state.Name == AnyState || strings.ContainsAny(state.Name, ", ") {
+			return fmt.Errorf("workflow state name %q is invalid", state.Name)
+		}
+
+		if seen[state.Name] {
+			return fmt.Errorf("workflow state %s is listed twice", state.Name)
+		}
+		seen[state.Name] = true
+
+		switch state.Category {
+		case "":
+			w.States[i].Category = CategoryOpen
+		case CategoryOpen, CategoryCompleted, CategoryCancelled:
+		default:
+			return fmt.Errorf("workflow state %s must be in category %s, %s or %s", state.Name, CategoryOpen, CategoryCompleted, CategoryCancelled)
+		}
+	}
+
+	if !seen[w.Initial] {
+		return errors.New("workflow initial state must be one of its states")
+	}
+
+	if w.Category(w.Initial) != CategoryOpen {
+		return errors.New("workflow initial state must be open")
+	}
+
+	pairs := make(map[[2]string]bool, len(w.Transitions))
+	for _, transition := range w.Transitions {
+		if (transition.From != AnyState && !seen[transition.From]) || !seen[transition.To] {
+			return fmt.Errorf("workflow transition from %s to %s names an unknown state", transition.From, transition.To)
+		}
+
+		if transition.From == transition.To {
+			return fmt.Errorf("workflow transition from %s to itself is not needed", transition.From)
+		}
+
+		pair := [2]string{transition.From, transition.To}
+		if pairs[pair] {
+			return fmt.Errorf("workflow transition from %s to %s is listed twice", transition.From, transition.To)
+		}
+		pairs[pair] = true
+
+		if slices.Contains(transition.Roles, "") {
+			return fmt.Errorf("workflow transition from %s to %s has an empty role", transition.From, transition.To)
+		}
+	}
+
+	return nil
+}
+
+// HasState reports whether the workflow has a state with the name
+func (w Workflow) HasState(name string) bool {
+	return w.Category(name) != ""
+}
+
+// Category returns the category of the named state, or "" if there is no such state
+func (w Workflow) Category(name string) string {
+	for _, state := range w.States {
+		if state.Name == name {
+			return state.Category
+		}
+	}
+
+	return ""
+}
+
+// IsCompleted reports whether the named state finishes a task
+func (w Workflow) IsCompleted(name string) bool {
+	return w.Category(name) == CategoryCompleted
+}
+
+// StateNames lists the names of the workflow's states in order
+func (w Workflow) StateNames() []string {
+	names := make([]string, len(w.States))
+	for i, state := range w.States {
+		names[i] = state.Name
+	}
+
+	return names
+}
+
+// OpenStates lists the names of the states whose tasks are still being worked on
+func (w Workflow) OpenStates() []string {
+	names := []string{}
+	for _, state := range w.States {
+		if state.Category == CategoryOpen {
+			names = append(names, state.Name)
+		}
+	}
+
+	return names
+}
+
+// Transition returns the transition between two states, preferring one that names its
+// source over one from AnyState
+func (w Workflow) Transition(from, to string) (WorkflowTransition, bool) {
+	wildcard, found := WorkflowTransition{}, false
+	for _, transition := range w.Transitions {
+		if transition.To != to {
+			continue
+		}
+
+		if transition.From == from {
+			return transition, true
+		}
+
+		if transition.From == AnyState && from != to {
+			wildcard, found = transition, true
+		}
+	}
+
+	return wildcard, found
+}
+
+// CheckTransition checks that the identity may move a task from one state to another.
+// Keeping the same state is always allowed. A move the workflow does not allow is a
+// BadRequestError and one the identity's role may not make is a ForbiddenError.
+func (w Workflow) CheckTransition(identity Identity, from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	transition, ok := w.Transition(from, to)
+	if !ok {
+		return &BadRequestError{Message: fmt.Sprintf("A task cannot move from %s to %s", from, to)}
+	}
+
+	if !transition.Allows(identity) {
+		return &ForbiddenError{Message: fmt.Sprintf("Your role cannot move a task from %s to %s", from, to)}
+	}
+
+	return nil
+}
+
+// NextStates lists the states, in workflow order, the identity may move a task in the
+// given state to
+func (w Workflow) NextStates(identity Identity, from string) []string {
+	next := []string{}
+	for _, state := range w.States {
+		if state.Name == from {
+			continue
+		}
+
+		if transition, ok := w.Transition(from, state.Name); ok && transition.Allows(identity) {
+			next = append(next, state.Name)
+		}
+	}
+
+	return next
+}
+
+// Allows reports whether one of the identity's roles may take the transition
+func (t WorkflowTransition) Allows(identity Identity) bool {
+	return len(t.Roles) == 0 || slices.ContainsFunc(t.Roles, identity.HasRole)
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultWorkflow_IsValid(t *testing.T) {
+	workflow := DefaultWorkflow()
+	assert.NoError(t, workflow.Validate())
+	assert.Equal(t, []string{"pending", "in_progress", "blocked"}, workflow.OpenStates())
+	assert.True(t, workflow.IsCompleted("completed"))
+	assert.False(t, workflow.IsCompleted("cancelled"))
+}
+
+func TestWorkflow_Validate(t *testing.T) {
+	states := func() []WorkflowState {
+		return []WorkflowState{{Name: "todo"}, {Name: "done", Category: CategoryCompleted}}
+	}
+
+	tests := []struct {
+		name     string
+		workflow Workflow
+		expected string
+	}{
+		{
+			name:     "no states",
+			workflow: Workflow{Initial: "todo"},
+			expected: "workflow needs at least one state",
+		},
+		{
+			name:     "unnamed state",
+			workflow: Workflow{Initial: "todo", States: []WorkflowState{{Name: ""}}},
+			expected: `workflow state name "" is invalid`,
+		},
+		{
+			name:     "wildcard state",
+			workflow: Workflow{Initial: "todo", States: []WorkflowState{{Name: AnyState}}},
+			expected: `workflow state name "*" is invalid`,
+		},
+		{
+			name:     "duplicate state",
+			workflow: Workflow{Initial: "todo", States: []WorkflowState{{Name: "todo"}, {Name: "todo"}}},
+			expected: "workflow state todo is listed twice",
+		},
+		{
+			name:     "unknown category",
+			workflow: Workflow{Initial: "todo", States: []WorkflowState{{Name: "todo", Category: "archived"}}},
+			expected: "workflow state todo must be in category open, completed or cancelled",
+		},
+		{
+			name:     "unknown initial state",
+			workflow: Workflow{Initial: "backlog", States: states()},
+			expected: "workflow initial state must be one of its states",
+		},
+		{
+			name:     "closed initial state",
+			workflow: Workflow{Initial: "done", States: states()},
+			expected: "workflow initial state must be open",
+		},
+		{
+			name:     "transition to unknown state",
+			workflow: Workflow{Initial: "todo", States: states(), Transitions: []WorkflowTransition{{From: "todo", To: "review"}}},
+			expected: "workflow transition from todo to review names an unknown state",
+		},
+		{
+			name:     "transition to itself",
+			workflow: Workflow{Initial: "todo", States: states(), Transitions: []WorkflowTransition{{From: "todo", To: "todo"}}},
+			expected: "workflow transition from todo to itself is not needed",
+		},
+		{
+			name:     "duplicate transition",
+			workflow: Workflow{Initial: "todo", States: states(), Transitions: []WorkflowTransition{{From: "todo", To: "done"}, {From: "todo", To: "done"}}},
+			expected: "workflow transition from todo to done is listed twice",
+		},
+		{
+			name:     "empty role",
+			workflow: Workflow{Initial: "todo", States: states(), Transitions: []WorkflowTransition{{From: "todo", To: "done", Roles: []string{""}}}},
+			expected: "workflow transition from todo to done has an empty role",
+		},
+		{
+			name:     "valid",
+			workflow: Workflow{Initial: "todo", States: states(), Transitions: []WorkflowTransition{{From: AnyState, To: "todo"}, {From: "todo", To: "done"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workflow.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWorkflow_Validate_DefaultsCategory(t *testing.T) {
+	workflow := Workflow{Initial: "todo", States: []WorkflowState{{Name: "todo"}}}
+
+	assert.NoError(t, workflow.Validate())
+	assert.Equal(t, CategoryOpen, workflow.Category("todo"))
+}
+
+func TestWorkflow_CheckTransition(t *testing.T) {
+	workflow := Workflow{
+		Initial: "todo",
+		States:  []WorkflowState{{Name: "todo"}, {Name: "review"}, {Name: "done", Category: CategoryCompleted}},
+		Transitions: []WorkflowTransition{
+			{From: AnyState, To: "todo"},
+			{From: "todo", To: "review"},
+			{From: "review", To: "done", Roles: []string{"reviewer", AdminRole}},
+		},
+	}
+	user := Identity{Roles: []string{UserRole}}
+	reviewer := Identity{Roles: []string{UserRole, "reviewer"}}
+
+	assert.NoError(t, workflow.CheckTransition(user, "todo", "todo"))
+	assert.NoError(t, workflow.CheckTransition(user, "todo", "review"))
+	assert.NoError(t, workflow.CheckTransition(user, "done", "todo"))
+	assert.NoError(t, workflow.CheckTransition(reviewer, "review", "done"))
+	assert.IsType(t, &ForbiddenError{}, workflow.CheckTransition(user, "review", "done"))
+	assert.IsType(t, &BadRequestError{}, workflow.CheckTransition(reviewer, "todo", "done"))
+
+	assert.Equal(t, []string{"todo"}, workflow.NextStates(user, "review"))
+	assert.Equal(t, []string{"todo", "done"}, workflow.NextStates(reviewer, "review"))
+	assert.Equal(t, []string{"review"}, workflow.NextStates(user, "todo"))
+}
@@ -1,8 +1,12 @@
 package infrastructure
 
 import (
+	"context"
 	"net/http"
 	"strings"
+	"time"
+
+	domain "task-manager/Domain"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -11,19 +15,39 @@ import (
 // AuthMiddleware interface
 type AuthMiddleware interface {
 	Authenticate() gin.HandlerFunc
-	Authorize(roles ...string) gin.HandlerFunc
+	Authorize(permissions ...string) gin.HandlerFunc
+}
+
+// RevocationList reports whether an access token has been revoked
+type RevocationList interface {
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// GrantResolver looks up the roles a user holds and the permissions they give. It
+// returns a NotFoundError for a user that no longer exists.
+type GrantResolver interface {
+	ResolveGrants(ctx context.Context, userID string) (domain.Grants, error)
+}
+
+// APIKeyAuthenticator works out who an API key acts for and with which permissions. It
+// returns an UnauthorizedError for a key that is unknown or has expired.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (domain.Identity, error)
 }
 
 type authMiddleware struct {
-	jwtService JWTService
+	jwtService     JWTService
+	revocationList RevocationList
+	grantResolver  GrantResolver
+	apiKeys        APIKeyAuthenticator
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(jwtService JWTService) AuthMiddleware {
-	return &authMiddleware{jwtService}
+func NewAuthMiddleware(jwtService JWTService, revocationList RevocationList, grantResolver GrantResolver, apiKeys APIKeyAuthenticator) AuthMiddleware {
+	return &authMiddleware{jwtService, revocationList, grantResolver, apiKeys}
 }
 
-// Authenticate middleware
+// Authenticate middleware accepts either a bearer JWT or an API key
 func (m *authMiddleware) Authenticate() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
@@ -34,6 +58,11 @@ func (m *authMiddleware) Authenticate() gin.HandlerFunc {
 		}
 
 		authParts := strings.Split(authHeader, " ")
+		if len(authParts) == 2 && strings.ToLower(authParts[0]) == "apikey" {
+			m.authenticateAPIKey(ctx, authParts[1])
+			return
+		}
+
 		if len(authParts) != 2 || strings.ToLower(authParts[0]) != "bearer" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
 			ctx.Abort()
@@ -60,39 +89,93 @@ func (m *authMiddleware) Authenticate() gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
-		
-		ctx.Set("username", claims["username"])
-		ctx.Set("role", claims["role"])
 
-		ctx.Next()
-	}
-}
+		tokenID, _ := claims["jti"].(string)
+		userID, _ := claims["sub"].(string)
+		if tokenID == "" || userID == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			ctx.Abort()
+			return
+		}
 
-// Authorize middleware
-func (m *authMiddleware) Authorize(roles ...string) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		tokenString := ctx.GetHeader("Authorization")
-		tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-		token, _ := m.jwtService.ValidateToken(tokenString)
-		claims, _ := token.Claims.(jwt.MapClaims)
-		role := claims["role"].(string)
-
-		if !contains(roles, role) {
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized for this action"})
+		revoked, err := m.revocationList.IsRevoked(ctx.Request.Context(), tokenID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking token"})
+			ctx.Abort()
+			return
+		}
+
+		if revoked {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
 			ctx.Abort()
 			return
 		}
 
+		grants, err := m.grantResolver.ResolveGrants(ctx.Request.Context(), userID)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User no longer exists"})
+			ctx.Abort()
+			return
+		}
+
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error resolving permissions"})
+			ctx.Abort()
+			return
+		}
+
+		username, _ := claims["user"].(string)
+		expiresAt, _ := claims["exp"].(float64)
+
+		ctx.Set("username", username)
+		ctx.Set("identity", domain.NewIdentity(userID, username, grants))
+		ctx.Set("access_token", domain.AccessToken{
+			Token:     tokenString,
+			ID:        tokenID,
+			Username:  username,
+			ExpiresAt: time.Unix(int64(expiresAt), 0),
+		})
+
 		ctx.Next()
 	}
 }
 
-// contains checks if a string slice contains a specific string
-func contains(slice []string, str string) bool {
-	for _, s := range slice {
-		if s == str {
-			return true
+// authenticateAPIKey lets the request through as the identity the key acts as; no
+// access token is set, since there is no session to log out of
+func (m *authMiddleware) authenticateAPIKey(ctx *gin.Context, key string) {
+	identity, err := m.apiKeys.AuthenticateAPIKey(ctx.Request.Context(), key)
+	if unauthorized, ok := err.(*domain.UnauthorizedError); ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": unauthorized.Error()})
+		ctx.Abort()
+		return
+	}
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking API key"})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("username", identity.Username)
+	ctx.Set("identity", identity)
+	ctx.Next()
+}
+
+// Authorize middleware lets the request through only if the authenticated user has
+// every one of the permissions
+func (m *authMiddleware) Authorize(permissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		identity, _ := ctx.Get("identity")
+		user, _ := identity.(domain.Identity)
+
+		for _, permission := range permissions {
+			if !user.Can(permission) {
+				ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized for this action"})
+				ctx.Abort()
+				return
+			}
 		}
+
+		ctx.Next()
 	}
-	return false
-}
\ No newline at end of file
+}
@@ -1,11 +1,14 @@
 package infrastructure
 
 import (
-	// "errors"
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	domain "task-manager/Domain"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,9 +20,9 @@ type MockJWTService struct {
 	mock.Mock
 }
 
-func (m *MockJWTService) GenerateToken(username string, role string) (string, error) {
-	args := m.Called(username, role)
-	return args.String(0), args.Error(1)
+func (m *MockJWTService) GenerateToken(userID string, username string) (domain.AccessToken, error) {
+	args := m.Called(userID, username)
+	return args.Get(0).(domain.AccessToken), args.Error(1)
 }
 
 func (m *MockJWTService) ValidateToken(token string) (*jwt.Token, error) {
@@ -27,16 +30,51 @@ func (m *MockJWTService) ValidateToken(token string) (*jwt.Token, error) {
 	return args.Get(0).(*jwt.Token), args.Error(1)
 }
 
+type MockRevocationList struct {
+	mock.Mock
+}
+
+func (m *MockRevocationList) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockGrantResolver struct {
+	mock.Mock
+}
+
+func (m *MockGrantResolver) ResolveGrants(ctx context.Context, userID string) (domain.Grants, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(domain.Grants), args.Error(1)
+}
+
+type MockAPIKeyAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, key string) (domain.Identity, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(domain.Identity), args.Error(1)
+}
+
+var userGrants = domain.Grants{Roles: []string{domain.UserRole}, Permissions: []string{domain.PermissionTaskRead}}
+
 type AuthMiddlewareTestSuite struct {
 	suite.Suite
-	jwtService    *MockJWTService
+	jwtService     *MockJWTService
+	revocationList *MockRevocationList
+	grantResolver  *MockGrantResolver
+	apiKeys        *MockAPIKeyAuthenticator
 	authMiddleware AuthMiddleware
-	router        *gin.Engine
+	router         *gin.Engine
 }
 
 func (suite *AuthMiddlewareTestSuite) SetupTest() {
 	suite.jwtService = new(MockJWTService)
-	suite.authMiddleware = NewAuthMiddleware(suite.jwtService)
+	suite.revocationList = new(MockRevocationList)
+	suite.grantResolver = new(MockGrantResolver)
+	suite.apiKeys = new(MockAPIKeyAuthenticator)
+	suite.authMiddleware = NewAuthMiddleware(suite.jwtService, suite.revocationList, suite.grantResolver, suite.apiKeys)
 	suite.router = gin.Default()
 }
 
@@ -48,16 +86,21 @@ func (suite *AuthMiddlewareTestSuite) TestAuthenticate_Success() {
 	token := &jwt.Token{
 		Valid: true,
 		Claims: jwt.MapClaims{
-			"username": "testuser",
-			"role":     "user",
+			"jti":  "token-id",
+			"sub":  "user-id",
+			"user": "testuser",
+			"role": "user",
 		},
 	}
 	suite.jwtService.On("ValidateToken", "valid_token").Return(token, nil)
+	suite.revocationList.On("IsRevoked", mock.Anything, "token-id").Return(false, nil)
+	suite.grantResolver.On("ResolveGrants", mock.Anything, "user-id").Return(userGrants, nil)
 
 	suite.router.Use(suite.authMiddleware.Authenticate())
 
 	suite.router.GET("/test", func(ctx *gin.Context) {
-		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+		identity := ctx.MustGet("identity").(domain.Identity)
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated", "username": identity.Username, "user_id": identity.UserID, "roles": identity.Roles, "permissions": identity.Permissions})
 	})
 
 	w := httptest.NewRecorder()
@@ -67,7 +110,180 @@ func (suite *AuthMiddlewareTestSuite) TestAuthenticate_Success() {
 
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 	assert.Contains(suite.T(), w.Body.String(), "Authenticated")
+	assert.Contains(suite.T(), w.Body.String(), "testuser")
+	assert.Contains(suite.T(), w.Body.String(), "user-id")
+	assert.Contains(suite.T(), w.Body.String(), `"roles":["user"]`)
+	assert.Contains(suite.T(), w.Body.String(), `"permissions":["task:read"]`)
 	suite.jwtService.AssertExpectations(suite.T())
+	suite.revocationList.AssertExpectations(suite.T())
+	suite.grantResolver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_DeletedUser() {
+	token := &jwt.Token{
+		Valid:  true,
+		Claims: jwt.MapClaims{"jti": "token-id", "sub": "user-id", "user": "testuser"},
+	}
+	suite.jwtService.On("ValidateToken", "valid_token").Return(token, nil)
+	suite.revocationList.On("IsRevoked", mock.Anything, "token-id").Return(false, nil)
+	suite.grantResolver.On("ResolveGrants", mock.Anything, "user-id").Return(domain.Grants{}, &domain.NotFoundError{Message: "User not found"})
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "User no longer exists")
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_APIKey() {
+	identity := domain.Identity{UserID: "user-id", Username: "ci-bot", Roles: []string{domain.UserRole}, Permissions: []string{domain.PermissionTaskRead}, APIKeyID: "key-id"}
+	suite.apiKeys.On("AuthenticateAPIKey", mock.Anything, "tm_secret").Return(identity, nil)
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", suite.authMiddleware.Authorize(domain.PermissionTaskRead), func(ctx *gin.Context) {
+		_, hasAccessToken := ctx.Get("access_token")
+		ctx.JSON(http.StatusOK, gin.H{"username": ctx.GetString("username"), "key": ctx.MustGet("identity").(domain.Identity).APIKeyID, "session": hasAccessToken})
+	})
+	suite.router.POST("/test", suite.authMiddleware.Authorize(domain.PermissionTaskCreate), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Created"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "ApiKey tm_secret")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"username":"ci-bot","key":"key-id","session":false}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/test", nil)
+	req.Header.Set("Authorization", "ApiKey tm_secret")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	suite.jwtService.AssertNotCalled(suite.T(), "ValidateToken", mock.Anything)
+	suite.apiKeys.AssertExpectations(suite.T())
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_InvalidAPIKey() {
+	suite.apiKeys.On("AuthenticateAPIKey", mock.Anything, "tm_expired").Return(domain.Identity{}, &domain.UnauthorizedError{Message: "API key has expired"})
+	suite.apiKeys.On("AuthenticateAPIKey", mock.Anything, "tm_broken").Return(domain.Identity{}, &domain.InternalServerError{Message: "Error retrieving API key"})
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "ApiKey tm_expired")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "API key has expired")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "ApiKey tm_broken")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Error checking API key")
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_RevokedToken() {
+	token := &jwt.Token{
+		Valid:  true,
+		Claims: jwt.MapClaims{"jti": "revoked-id", "sub": "user-id", "user": "testuser", "role": "user"},
+	}
+	suite.jwtService.On("ValidateToken", "revoked_token").Return(token, nil)
+	suite.revocationList.On("IsRevoked", mock.Anything, "revoked-id").Return(true, nil)
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer revoked_token")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Token has been revoked")
+	suite.revocationList.AssertExpectations(suite.T())
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_MissingTokenID() {
+	token := &jwt.Token{
+		Valid:  true,
+		Claims: jwt.MapClaims{"user": "testuser", "role": "user"},
+	}
+	suite.jwtService.On("ValidateToken", "legacy_token").Return(token, nil)
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer legacy_token")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Invalid token claims")
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_MissingUserID() {
+	token := &jwt.Token{
+		Valid:  true,
+		Claims: jwt.MapClaims{"jti": "token-id", "user": "testuser", "role": "user"},
+	}
+	suite.jwtService.On("ValidateToken", "valid_token").Return(token, nil)
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Invalid token claims")
+}
+
+func (suite *AuthMiddlewareTestSuite) TestAuthenticate_RevocationCheckError() {
+	token := &jwt.Token{
+		Valid:  true,
+		Claims: jwt.MapClaims{"jti": "token-id", "sub": "user-id", "user": "testuser", "role": "user"},
+	}
+	suite.jwtService.On("ValidateToken", "valid_token").Return(token, nil)
+	suite.revocationList.On("IsRevoked", mock.Anything, "token-id").Return(false, errors.New("database unavailable"))
+
+	suite.router.Use(suite.authMiddleware.Authenticate())
+	suite.router.GET("/test", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Authenticated"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Error checking token")
 }
 
 // func (suite *AuthMiddlewareTestSuite) TestAuthenticate_InvalidToken() {
@@ -93,14 +309,17 @@ func (suite *AuthMiddlewareTestSuite) TestAuthorize_Success() {
 	token := &jwt.Token{
 		Valid: true,
 		Claims: jwt.MapClaims{
-			"username": "testuser",
-			"role":     "admin",
+			"jti":  "token-id",
+			"sub":  "user-id",
+			"user": "testuser",
 		},
 	}
 	suite.jwtService.On("ValidateToken", "valid_token").Return(token, nil)
+	suite.revocationList.On("IsRevoked", mock.Anything, "token-id").Return(false, nil)
+	suite.grantResolver.On("ResolveGrants", mock.Anything, "user-id").Return(domain.Grants{Roles: []string{"auditor"}, Permissions: []string{domain.PermissionTaskRead, domain.PermissionAuditRead}}, nil)
 
 	suite.router.Use(suite.authMiddleware.Authenticate())
-	suite.router.Use(suite.authMiddleware.Authorize("admin"))
+	suite.router.Use(suite.authMiddleware.Authorize(domain.PermissionTaskRead, domain.PermissionAuditRead))
 
 	suite.router.GET("/admin", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "Authorized"})
@@ -120,14 +339,17 @@ func (suite *AuthMiddlewareTestSuite) TestAuthorize_Forbidden() {
 	token := &jwt.Token{
 		Valid: true,
 		Claims: jwt.MapClaims{
-			"username": "testuser",
-			"role":     "user",
+			"jti":  "token-id",
+			"sub":  "user-id",
+			"user": "testuser",
 		},
 	}
 	suite.jwtService.On("ValidateToken", "valid_token").Return(token, nil)
+	suite.revocationList.On("IsRevoked", mock.Anything, "token-id").Return(false, nil)
+	suite.grantResolver.On("ResolveGrants", mock.Anything, "user-id").Return(userGrants, nil)
 
 	suite.router.Use(suite.authMiddleware.Authenticate())
-	suite.router.Use(suite.authMiddleware.Authorize("admin"))
+	suite.router.Use(suite.authMiddleware.Authorize(domain.PermissionTaskRead, domain.PermissionAuditRead))
 
 	suite.router.GET("/admin", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "Authorized"})
@@ -0,0 +1,149 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	domain "task-manager/Domain"
+)
+
+// BlobStore keeps the contents of attachments under slash separated keys such as
+// "<task id>/<attachment id>". Implementations stream blobs rather than holding them
+// in memory.
+type BlobStore interface {
+	// Put stores everything read from r under key, replacing any blob stored there. If
+	// reading r fails, the error is returned and nothing is stored.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open streams the blob stored under key; a missing blob is a NotFoundError
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key, if there is one
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every blob whose key starts with prefix followed by a slash
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// localBlobStore keeps each blob in a file below its root directory
+type localBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore creates a blob store that keeps blobs as files below root, creating
+// the directory if it does not exist
+func NewLocalBlobStore(root string) (BlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("creating blob directory: %w", err)
+	}
+
+	return &localBlobStore{root: root}, nil
+}
+
+// Put writes the blob to a temporary file next to its final path and renames it into
+// place once it is complete, so a failed or cancelled upload never leaves part of a blob
+func (s *localBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return &domain.InternalServerError{Message: "Error storing blob"}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return &domain.InternalServerError{Message: "Error storing blob"}
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, contextReader{ctx: ctx, r: r})
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = &domain.InternalServerError{Message: "Error storing blob"}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return &domain.InternalServerError{Message: "Error storing blob"}
+	}
+
+	return nil
+}
+
+// Open opens the blob's file for reading
+func (s *localBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &domain.NotFoundError{Message: "Blob not found"}
+	}
+	if err != nil {
+		return nil, &domain.InternalServerError{Message: "Error reading blob"}
+	}
+
+	return file, nil
+}
+
+// Delete removes the blob's file
+func (s *localBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return &domain.InternalServerError{Message: "Error deleting blob"}
+	}
+
+	return nil
+}
+
+// DeletePrefix removes the directory holding the blobs under the prefix
+func (s *localBlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	path, err := s.path(prefix)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return &domain.InternalServerError{Message: "Error deleting blobs"}
+	}
+
+	return nil
+}
+
+// path maps a key onto a file below the root. Keys are made of IDs the server chose,
+// but they are still checked so that no key can reach outside the root.
+func (s *localBlobStore) path(key string) (string, error) {
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `\:`) {
+			return "", &domain.BadRequestError{Message: "Invalid blob key"}
+		}
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// contextReader stops reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
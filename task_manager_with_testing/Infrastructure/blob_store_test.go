@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBlobStore(t *testing.T) (BlobStore, string) {
+	root := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewLocalBlobStore(root)
+	if err != nil {
+		t.Fatalf("creating blob store: %v", err)
+	}
+	return store, root
+}
+
+func readBlob(t *testing.T, store BlobStore, key string) string {
+	blob, err := store.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("opening blob %s: %v", key, err)
+	}
+	defer blob.Close()
+
+	contents, err := io.ReadAll(blob)
+	assert.NoError(t, err)
+	return string(contents)
+}
+
+func TestLocalBlobStore_PutAndOpen(t *testing.T) {
+	store, root := newTestBlobStore(t)
+
+	assert.NoError(t, store.Put(context.Background(), "task/first", strings.NewReader("hello")))
+	assert.Equal(t, "hello", readBlob(t, store, "task/first"))
+	assert.FileExists(t, filepath.Join(root, "task", "first"))
+
+	assert.NoError(t, store.Put(context.Background(), "task/first", strings.NewReader("replaced")))
+	assert.Equal(t, "replaced", readBlob(t, store, "task/first"))
+
+	_, err := store.Open(context.Background(), "task/missing")
+	assert.IsType(t, &domain.NotFoundError{}, err)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestLocalBlobStore_FailedPutStoresNothing(t *testing.T) {
+	store, root := newTestBlobStore(t)
+
+	err := store.Put(context.Background(), "task/broken", io.MultiReader(strings.NewReader("partial"), failingReader{}))
+	assert.EqualError(t, err, "connection reset")
+
+	_, err = store.Open(context.Background(), "task/broken")
+	assert.IsType(t, &domain.NotFoundError{}, err)
+
+	entries, err := os.ReadDir(filepath.Join(root, "task"))
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "the temporary file is removed")
+}
+
+func TestLocalBlobStore_CancelledPut(t *testing.T) {
+	store, _ := newTestBlobStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := store.Put(ctx, "task/cancelled", strings.NewReader("hello"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLocalBlobStore_Delete(t *testing.T) {
+	store, _ := newTestBlobStore(t)
+	assert.NoError(t, store.Put(context.Background(), "task/first", strings.NewReader("hello")))
+
+	assert.NoError(t, store.Delete(context.Background(), "task/first"))
+	assert.NoError(t, store.Delete(context.Background(), "task/first"))
+
+	_, err := store.Open(context.Background(), "task/first")
+	assert.IsType(t, &domain.NotFoundError{}, err)
+}
+
+func TestLocalBlobStore_DeletePrefix(t *testing.T) {
+	store, _ := newTestBlobStore(t)
+	assert.NoError(t, store.Put(context.Background(), "task/first", strings.NewReader("1")))
+	assert.NoError(t, store.Put(context.Background(), "task/second", strings.NewReader("2")))
+	assert.NoError(t, store.Put(context.Background(), "other/kept", strings.NewReader("3")))
+
+	assert.NoError(t, store.DeletePrefix(context.Background(), "task"))
+	assert.NoError(t, store.DeletePrefix(context.Background(), "missing"))
+
+	_, err := store.Open(context.Background(), "task/first")
+	assert.IsType(t, &domain.NotFoundError{}, err)
+	assert.Equal(t, "3", readBlob(t, store, "other/kept"))
+}
+
+func TestLocalBlobStore_RejectsKeysOutsideTheRoot(t *testing.T) {
+	store, _ := newTestBlobStore(t)
+
+	for _, key := range []string{"../escape", "task/../../escape", "/absolute", "task/", "", `task\..\escape`, "c:/windows"} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"))
+		assert.IsType(t, &domain.BadRequestError{}, err, key)
+	}
+}
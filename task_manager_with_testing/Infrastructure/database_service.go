@@ -4,36 +4,42 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
+	"time"
 )
 
 // DatabaseService interface
 type DatabaseService interface {
-	Connect() *mongo.Database
+	Connect() (*mongo.Database, error)
 }
 
 // databaseService struct
 type databaseService struct {
+	uri  string
+	name string
 }
 
 // NewDatabase creates a new database service
-func NewDatabase() DatabaseService {
-	return &databaseService{}
+func NewDatabase(uri string, name string) DatabaseService {
+	return &databaseService{uri: uri, name: name}
 }
 
-// Connect connects to the database
-func (d *databaseService) Connect() *mongo.Database {
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.Background(), clientOptions)
+// Connect connects to the database and checks that it is reachable
+func (d *databaseService) Connect() (*mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientOptions := options.Client().ApplyURI(d.uri)
+	client, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	err = client.Ping(context.Background(), nil)
+	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal(err)
+		client.Disconnect(context.Background())
+		return nil, err
 	}
 
-	return client.Database("task_manager")
+	return client.Database(d.name), nil
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package infrastructure
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,8 +13,13 @@ type DatabaseServiceTestSuite struct {
 	dbService DatabaseService
 }
 
+// SetupTest connects to MONGODB_TEST_URI, or to localhost when it is not set
 func (suite *DatabaseServiceTestSuite) SetupTest() {
-	suite.dbService = NewDatabase()
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	suite.dbService = NewDatabase(uri, "task_manager")
 }
 
 func TestDatabaseServiceTestSuite(t *testing.T) {
@@ -21,8 +27,22 @@ func TestDatabaseServiceTestSuite(t *testing.T) {
 }
 
 func (suite *DatabaseServiceTestSuite) TestConnect_Success() {
-	db := suite.dbService.Connect()
+	db, err := suite.dbService.Connect()
+	if err != nil {
+		// in CI the server is expected to be there, so it must not pass silently
+		if os.Getenv("MONGODB_TEST_URI") != "" {
+			suite.T().Fatalf("no MongoDB server: %v", err)
+		}
+		suite.T().Skipf("skipping MongoDB tests: %v", err)
+	}
 
 	assert.NotNil(suite.T(), db)
 	assert.Equal(suite.T(), "task_manager", db.Name())
 }
+
+func (suite *DatabaseServiceTestSuite) TestConnect_InvalidURI() {
+	db, err := NewDatabase("not-a-uri", "task_manager").Connect()
+
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), db)
+}
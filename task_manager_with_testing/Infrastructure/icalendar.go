@@ -0,0 +1,139 @@
+package infrastructure
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	domain "task-manager/Domain"
+)
+
+// calendarProductID identifies the application that produced a calendar
+const calendarProductID = "-//task-manager//Tasks//EN"
+
+// calendarUIDDomain qualifies task IDs so their UIDs stay unique among other calendars
+const calendarUIDDomain = "task-manager"
+
+// calendarRefresh is how often clients are asked to poll the feed
+const calendarRefresh = "PT1H"
+
+// maxCalendarLine is the longest a content line may be, in octets, before it is folded
+const maxCalendarLine = 75
+
+// calendarTime is the RFC 5545 form of a UTC date-time
+const calendarTime = "20060102T150405Z"
+
+// WriteCalendar writes the feed as an RFC 5545 calendar with one VEVENT or VTODO per
+// task. Events take place at the task's due date; to-dos are due then. now is the
+// time the calendar is generated at.
+func WriteCalendar(w io.Writer, feed domain.CalendarFeed, now time.Time) error {
+	out := &calendarWriter{w: bufio.NewWriter(w)}
+	out.line("BEGIN", "VCALENDAR")
+	out.line("VERSION", "2.0")
+	out.line("PRODID", calendarProductID)
+	out.line("CALSCALE", "GREGORIAN")
+	out.line("METHOD", "PUBLISH")
+	out.line("X-WR-CALNAME", escapeCalendarText(feed.Username+"'s tasks"))
+	out.line("REFRESH-INTERVAL;VALUE=DURATION", calendarRefresh)
+	out.line("X-PUBLISHED-TTL", calendarRefresh)
+
+	component := "VEVENT"
+	if feed.Component == domain.CalendarTodos {
+		component = "VTODO"
+	}
+
+	for _, task := range feed.Tasks {
+		out.line("BEGIN", component)
+		out.line("UID", task.ID+"@"+calendarUIDDomain)
+		out.line("DTSTAMP", now.UTC().Format(calendarTime))
+		out.line("SUMMARY", escapeCalendarText(task.Title))
+		if component == "VTODO" {
+			out.line("DUE", task.DueDate.UTC().Format(calendarTime))
+			out.line("STATUS", todoStatus(task.Status))
+		} else {
+			out.line("DTSTART", task.DueDate.UTC().Format(calendarTime))
+			out.line("STATUS", "CONFIRMED")
+			out.line("TRANSP", "TRANSPARENT")
+		}
+		out.line("SEQUENCE", fmt.Sprint(task.Version))
+		if len(task.Tags) > 0 {
+			out.line("CATEGORIES", calendarCategories(task.Tags))
+		}
+		if task.ParentID != "" {
+			out.line("RELATED-TO;RELTYPE=PARENT", task.ParentID+"@"+calendarUIDDomain)
+		}
+		out.line("END", component)
+	}
+
+	out.line("END", "VCALENDAR")
+	if out.err != nil {
+		return out.err
+	}
+
+	return out.w.Flush()
+}
+
+// todoStatus maps a task status onto the status of a VTODO; statuses the default
+// workflow does not have need action
+func todoStatus(status string) string {
+	switch status {
+	case "completed":
+		return "COMPLETED"
+	case "in_progress":
+		return "IN-PROCESS"
+	case "cancelled":
+		return "CANCELLED"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+// calendarCategories lists tags as the comma separated values of a CATEGORIES property
+func calendarCategories(tags []string) string {
+	escaped := make([]string, len(tags))
+	for i, tag := range tags {
+		escaped[i] = escapeCalendarText(tag)
+	}
+
+	return strings.Join(escaped, ",")
+}
+
+// escapeCalendarText escapes a TEXT value as RFC 5545 requires
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(text)
+}
+
+// calendarWriter writes content lines, folding the long ones, and keeps the first error
+type calendarWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line ended by CRLF. Lines longer than 75 octets are folded
+// onto continuation lines that start with a space, without splitting a UTF-8 character.
+func (c *calendarWriter) line(name, value string) {
+	if c.err != nil {
+		return
+	}
+
+	content := name + ":" + value
+	limit := maxCalendarLine
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+
+		if _, c.err = c.w.WriteString(content[:cut] + "\r\n "); c.err != nil {
+			return
+		}
+		content = content[cut:]
+		// the leading space of a continuation line counts towards its length
+		limit = maxCalendarLine - 1
+	}
+
+	_, c.err = c.w.WriteString(content + "\r\n")
+}
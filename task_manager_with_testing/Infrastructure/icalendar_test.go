@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func calendarFeed(component string) domain.CalendarFeed {
+	return domain.CalendarFeed{
+		Username:  "alice",
+		Component: component,
+		Tasks: []domain.Task{
+			{ID: "1", Title: "Pay rent, on time; really", DueDate: time.Date(2030, 1, 2, 9, 30, 0, 0, time.FixedZone("EST", -5*3600)), Status: "pending", Version: 3},
+			{ID: "2", Title: "Call back", DueDate: time.Date(2030, 1, 3, 0, 0, 0, 0, time.UTC), Status: "pending", Version: 1, ParentID: "1"},
+		},
+	}
+}
+
+func writeCalendar(t *testing.T, feed domain.CalendarFeed) string {
+	var out bytes.Buffer
+	assert.NoError(t, WriteCalendar(&out, feed, time.Date(2029, 12, 1, 8, 0, 0, 0, time.UTC)))
+	return out.String()
+}
+
+func TestWriteCalendar_Events(t *testing.T) {
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//task-manager//Tasks//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:alice's tasks",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+		"BEGIN:VEVENT",
+		"UID:1@task-manager",
+		"DTSTAMP:20291201T080000Z",
+		`SUMMARY:Pay rent\, on time\; really`,
+		"DTSTART:20300102T143000Z",
+		"STATUS:CONFIRMED",
+		"TRANSP:TRANSPARENT",
+		"SEQUENCE:3",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:2@task-manager",
+		"DTSTAMP:20291201T080000Z",
+		"SUMMARY:Call back",
+		"DTSTART:20300103T000000Z",
+		"STATUS:CONFIRMED",
+		"TRANSP:TRANSPARENT",
+		"SEQUENCE:1",
+		"RELATED-TO;RELTYPE=PARENT:1@task-manager",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), writeCalendar(t, calendarFeed(domain.CalendarEvents)))
+}
+
+func TestWriteCalendar_Todos(t *testing.T) {
+	calendar := writeCalendar(t, calendarFeed(domain.CalendarTodos))
+
+	assert.Contains(t, calendar, "BEGIN:VTODO\r\nUID:1@task-manager\r\nDTSTAMP:20291201T080000Z\r\n")
+	assert.Contains(t, calendar, "DUE:20300102T143000Z\r\nSTATUS:NEEDS-ACTION\r\nSEQUENCE:3\r\nEND:VTODO\r\n")
+	assert.NotContains(t, calendar, "DTSTART")
+	assert.NotContains(t, calendar, "VEVENT")
+}
+
+func TestWriteCalendar_Categories(t *testing.T) {
+	feed := calendarFeed(domain.CalendarEvents)
+	feed.Tasks[0].Tags = []string{"area:home", "urgent"}
+
+	calendar := writeCalendar(t, feed)
+
+	assert.Contains(t, calendar, "SEQUENCE:3\r\nCATEGORIES:area:home,urgent\r\nEND:VEVENT\r\n")
+	assert.Equal(t, 1, strings.Count(calendar, "CATEGORIES"))
+}
+
+func TestWriteCalendar_Empty(t *testing.T) {
+	calendar := writeCalendar(t, domain.CalendarFeed{Username: "alice", Component: domain.CalendarEvents})
+
+	assert.True(t, strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(calendar, "X-PUBLISHED-TTL:PT1H\r\nEND:VCALENDAR\r\n"))
+}
+
+func TestWriteCalendar_FoldsLongLines(t *testing.T) {
+	feed := calendarFeed(domain.CalendarEvents)
+	feed.Tasks[0].Title = strings.Repeat("é", 60) + "\nsecond line"
+
+	calendar := writeCalendar(t, feed)
+
+	for _, line := range strings.Split(calendar, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+
+	unfolded := strings.ReplaceAll(calendar, "\r\n ", "")
+	assert.Contains(t, unfolded, "SUMMARY:"+strings.Repeat("é", 60)+`\nsecond line`+"\r\n")
+}
+
+func TestEscapeCalendarText(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne`, escapeCalendarText("a\\b;c,d\r\ne"))
+}
+
+func TestTodoStatus(t *testing.T) {
+	assert.Equal(t, "NEEDS-ACTION", todoStatus("pending"))
+	assert.Equal(t, "IN-PROCESS", todoStatus("in_progress"))
+	assert.Equal(t, "COMPLETED", todoStatus("completed"))
+	assert.Equal(t, "CANCELLED", todoStatus("cancelled"))
+	assert.Equal(t, "NEEDS-ACTION", todoStatus("triage"))
+}
@@ -1,37 +1,63 @@
 package infrastructure
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
+	domain "task-manager/Domain"
+
 	"github.com/dgrijalva/jwt-go"
 )
 
 // JWTService interface
 type JWTService interface {
-	GenerateToken(username string, role string) (string, error)
+	GenerateToken(userID string, username string) (domain.AccessToken, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 }
 
 type jwtService struct {
 	secretKey string
 	issuer    string
+	expiry    time.Duration
 }
 
 // NewJWTService creates a new JWT service
-func NewJWTService() JWTService {
-	return &jwtService{secretKey: "secured_secret_key", issuer: "task-manager"}
+func NewJWTService(secretKey string, issuer string, expiry time.Duration) JWTService {
+	return &jwtService{secretKey: secretKey, issuer: issuer, expiry: expiry}
 }
 
-// GenerateToken generates a new JWT token
-func (s *jwtService) GenerateToken(username string, role string) (string, error) {
+// GenerateToken generates a new JWT token with a unique ID so it can be revoked. The
+// token names the user only; their roles are looked up on each request, so a change
+// to them applies to tokens already issued.
+func (s *jwtService) GenerateToken(userID string, username string) (domain.AccessToken, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return domain.AccessToken{}, err
+	}
+
+	accessToken := domain.AccessToken{
+		ID:        hex.EncodeToString(id),
+		Username:  username,
+		ExpiresAt: time.Now().Add(s.expiry),
+	}
+
 	claims := jwt.MapClaims{}
+	claims["jti"] = accessToken.ID
+	claims["sub"] = userID
 	claims["user"] = username
-	claims["role"] = role
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
+	claims["iss"] = s.issuer
+	claims["exp"] = accessToken.ExpiresAt.Unix()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+	signed, err := token.SignedString([]byte(s.secretKey))
+	if err != nil {
+		return domain.AccessToken{}, err
+	}
+
+	accessToken.Token = signed
+	return accessToken, nil
 }
 
 // ValidateToken validates a JWT token
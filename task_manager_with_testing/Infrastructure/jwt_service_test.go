@@ -15,7 +15,7 @@ type JWTServiceTestSuite struct {
 }
 
 func (suite *JWTServiceTestSuite) SetupTest() {
-	suite.jwtService = NewJWTService()
+	suite.jwtService = NewJWTService("test_secret_key", "task-manager", time.Hour)
 }
 
 func TestJWTServiceTestSuite(t *testing.T) {
@@ -23,26 +23,40 @@ func TestJWTServiceTestSuite(t *testing.T) {
 }
 
 func (suite *JWTServiceTestSuite) TestGenerateToken_Success() {
-	tokenString, err := suite.jwtService.GenerateToken("testuser", "admin")
+	accessToken, err := suite.jwtService.GenerateToken("user-id", "testuser")
 
 	assert.NoError(suite.T(), err)
-	assert.NotEmpty(suite.T(), tokenString)
+	assert.NotEmpty(suite.T(), accessToken.Token)
+	assert.NotEmpty(suite.T(), accessToken.ID)
+	assert.Equal(suite.T(), "testuser", accessToken.Username)
 
-	token, err := suite.jwtService.ValidateToken(tokenString)
+	token, err := suite.jwtService.ValidateToken(accessToken.Token)
 	assert.NoError(suite.T(), err)
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), "testuser", claims["user"])
-	assert.Equal(suite.T(), "admin", claims["role"])
+	assert.NotContains(suite.T(), claims, "role", "roles are looked up on each request")
+	assert.Equal(suite.T(), accessToken.ID, claims["jti"])
+	assert.Equal(suite.T(), "user-id", claims["sub"])
+}
+
+func (suite *JWTServiceTestSuite) TestGenerateToken_UniqueID() {
+	first, err := suite.jwtService.GenerateToken("user-id", "testuser")
+	assert.NoError(suite.T(), err)
+
+	second, err := suite.jwtService.GenerateToken("user-id", "testuser")
+	assert.NoError(suite.T(), err)
+
+	assert.NotEqual(suite.T(), first.ID, second.ID)
 }
 
 func (suite *JWTServiceTestSuite) TestGenerateToken_Expiration() {
-	tokenString, err := suite.jwtService.GenerateToken("testuser", "admin")
+	accessToken, err := suite.jwtService.GenerateToken("user-id", "testuser")
 	assert.NoError(suite.T(), err)
-	assert.NotEmpty(suite.T(), tokenString)
+	assert.NotEmpty(suite.T(), accessToken.Token)
 
-	token, err := suite.jwtService.ValidateToken(tokenString)
+	token, err := suite.jwtService.ValidateToken(accessToken.Token)
 	assert.NoError(suite.T(), err)
 
 	claims, ok := token.Claims.(jwt.MapClaims)
@@ -50,12 +64,15 @@ func (suite *JWTServiceTestSuite) TestGenerateToken_Expiration() {
 
 	expiration := claims["exp"].(float64)
 	assert.True(suite.T(), expiration > float64(time.Now().Unix()))
+	assert.True(suite.T(), expiration <= float64(time.Now().Add(time.Hour).Unix()))
+	assert.Equal(suite.T(), "task-manager", claims["iss"])
+	assert.Equal(suite.T(), int64(expiration), accessToken.ExpiresAt.Unix())
 }
 
 func (suite *JWTServiceTestSuite) TestValidateToken_Success() {
-	tokenString, _ := suite.jwtService.GenerateToken("testuser", "admin")
+	accessToken, _ := suite.jwtService.GenerateToken("user-id", "testuser")
 
-	token, err := suite.jwtService.ValidateToken(tokenString)
+	token, err := suite.jwtService.ValidateToken(accessToken.Token)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), token)
@@ -64,7 +81,6 @@ func (suite *JWTServiceTestSuite) TestValidateToken_Success() {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), "testuser", claims["user"])
-	assert.Equal(suite.T(), "admin", claims["role"])
 }
 
 func (suite *JWTServiceTestSuite) TestValidateToken_InvalidTokenFormat() {
@@ -72,4 +88,12 @@ func (suite *JWTServiceTestSuite) TestValidateToken_InvalidTokenFormat() {
 
 	assert.Error(suite.T(), err)
 	assert.Empty(suite.T(), token.Valid)
-}
\ No newline at end of file
+}
+
+func (suite *JWTServiceTestSuite) TestValidateToken_WrongSecret() {
+	other := NewJWTService("another_secret_key", "task-manager", time.Hour)
+	accessToken, _ := other.GenerateToken("user-id", "testuser")
+
+	_, err := suite.jwtService.ValidateToken(accessToken.Token)
+	assert.Error(suite.T(), err)
+}
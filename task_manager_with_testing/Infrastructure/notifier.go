@@ -0,0 +1,37 @@
+package infrastructure
+
+import (
+	"context"
+	"log"
+
+	domain "task-manager/Domain"
+)
+
+// Notifier delivers reminders to task owners
+type Notifier interface {
+	Notify(ctx context.Context, reminder domain.Reminder) error
+}
+
+// logNotifier writes reminders to a log, which is enough for development
+type logNotifier struct {
+	logger *log.Logger
+}
+
+// NewLogNotifier creates a notifier that logs reminders, to the standard logger if logger is nil
+func NewLogNotifier(logger *log.Logger) Notifier {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &logNotifier{logger: logger}
+}
+
+// Notify logs the reminder
+func (n *logNotifier) Notify(ctx context.Context, reminder domain.Reminder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n.logger.Printf("reminder for %s: %s (due %s)", reminder.Username, reminder.Subject(), reminder.DueDate.UTC().Format("2006-01-02 15:04 MST"))
+	return nil
+}
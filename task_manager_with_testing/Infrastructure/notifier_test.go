@@ -0,0 +1,44 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReminder() domain.Reminder {
+	return domain.Reminder{
+		TaskID:   "task-id",
+		Title:    "Pay rent",
+		DueDate:  time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC),
+		LeadTime: time.Hour,
+		Username: "alice",
+	}
+}
+
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	notifier := NewLogNotifier(log.New(&buf, "", 0))
+
+	err := notifier.Notify(context.Background(), testReminder())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "reminder for alice: Task due in 1h0m0s: Pay rent (due 2030-01-01 09:00 UTC)\n", buf.String())
+}
+
+func TestLogNotifier_CancelledContext(t *testing.T) {
+	var buf bytes.Buffer
+	notifier := NewLogNotifier(log.New(&buf, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, notifier.Notify(ctx, testReminder()), context.Canceled)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,479 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ErrOIDCRejected wraps every error caused by what the provider or the browser sent,
+// as opposed to the provider being unreachable
+var ErrOIDCRejected = errors.New("single sign-on was rejected")
+
+const (
+	// maxOIDCResponse bounds how much of a provider response is read
+	maxOIDCResponse = 1 << 20
+	// oidcDiscoveryTTL is how long the discovery document is trusted before it is
+	// fetched again
+	oidcDiscoveryTTL = time.Hour
+	// oidcKeyRefreshInterval limits how often the signing keys are fetched again when
+	// a token names a key that is not known yet, as happens after the provider rotates them
+	oidcKeyRefreshInterval = time.Minute
+	// oidcClockSkew is how far the provider's clock may be ahead of or behind ours
+	oidcClockSkew = time.Minute
+)
+
+// oidcSigningMethods are the ID token algorithms accepted; symmetric ones are not,
+// since the client secret is not meant to sign tokens
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// OIDCConfig names the provider and how this service is registered with it
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	Timeout      time.Duration
+}
+
+// OIDCClient runs this service's side of the OpenID Connect authorization code flow
+// with PKCE
+type OIDCClient interface {
+	// AuthCodeURL returns the provider page the browser is sent to in order to log in.
+	// The code challenge is derived from the verifier.
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	// Exchange redeems the authorization code and returns the claims of the ID token
+	// after checking its signature, issuer, audience, lifetime and nonce
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error)
+}
+
+// oidcDiscovery is the part of the provider's discovery document the client uses
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a public key from the provider's key set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type oidcClient struct {
+	config OIDCConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu           sync.Mutex
+	discovery    *oidcDiscovery
+	discoveredAt time.Time
+	keys         map[string]interface{}
+	keysFetched  time.Time
+}
+
+// NewOIDCClient creates a client for the configured provider. The discovery document
+// and signing keys are fetched when first needed, so the service starts even while
+// the provider is down.
+func NewOIDCClient(config OIDCConfig) OIDCClient {
+	return &oidcClient{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		now:    time.Now,
+	}
+}
+
+// PKCEChallenge derives the S256 code challenge sent for a code verifier
+func PKCEChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (c *oidcClient) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.config.ClientID)
+	query.Set("redirect_uri", c.config.RedirectURL)
+	query.Set("scope", strings.Join(c.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", PKCEChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+func (c *oidcClient) Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error) {
+	discovery, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", c.config.ClientID)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	defer response.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxOIDCResponse)).Decode(&tokens); err != nil && response.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("reading token response: %w", err)
+	}
+
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("%w: token endpoint returned %s: %s", ErrOIDCRejected, tokens.Error, tokens.ErrorDescription)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", response.StatusCode)
+	}
+
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrOIDCRejected)
+	}
+
+	return c.verify(ctx, tokens.IDToken, nonce)
+}
+
+// verify checks the ID token's signature against the provider's keys and its claims
+// against this client, returning the claims
+func (c *oidcClient) verify(ctx context.Context, idToken, nonce string) (map[string]interface{}, error) {
+	token, err := c.parse(ctx, idToken)
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 && c.expireKeys() {
+		// the provider may have replaced a key without giving the new one another ID
+		token, err = c.parse(ctx, idToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid ID token claims", ErrOIDCRejected)
+	}
+
+	if err := c.checkClaims(claims, nonce); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCRejected, err)
+	}
+
+	return claims, nil
+}
+
+// parse checks the ID token's signature with the provider's key. A provider that
+// cannot be reached is told apart from a token that is not valid.
+func (c *oidcClient) parse(ctx context.Context, idToken string) (*jwt.Token, error) {
+	var keyErr error
+	parser := jwt.Parser{ValidMethods: oidcSigningMethods, SkipClaimsValidation: true}
+	token, err := parser.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := c.signingKey(ctx, kid)
+		if _, unknown := err.(unknownKeyError); err != nil && !unknown {
+			keyErr = err
+		}
+		return key, err
+	})
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ID token: %w", ErrOIDCRejected, err)
+	}
+
+	return token, nil
+}
+
+// expireKeys drops the cached signing keys so they are fetched again, unless they
+// were fetched within oidcKeyRefreshInterval
+func (c *oidcClient) expireKeys() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil || c.now().Sub(c.keysFetched) < oidcKeyRefreshInterval {
+		return false
+	}
+
+	c.keys = nil
+	return true
+}
+
+// checkClaims applies the ID token validation rules of OpenID Connect Core 3.1.3.7
+func (c *oidcClient) checkClaims(claims jwt.MapClaims, nonce string) error {
+	if issuer, _ := claims["iss"].(string); issuer != c.config.Issuer {
+		return fmt.Errorf("ID token was issued by %q", issuer)
+	}
+
+	audiences := []string{}
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = append(audiences, aud)
+	case []interface{}:
+		for _, value := range aud {
+			if audience, ok := value.(string); ok {
+				audiences = append(audiences, audience)
+			}
+		}
+	}
+
+	found := false
+	for _, audience := range audiences {
+		found = found || audience == c.config.ClientID
+	}
+	if !found {
+		return errors.New("ID token is not meant for this client")
+	}
+
+	if azp, ok := claims["azp"].(string); ok && azp != c.config.ClientID {
+		return errors.New("ID token was issued to another client")
+	}
+
+	now := c.now()
+	expiresAt, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("ID token has no expiry")
+	}
+	if now.Add(-oidcClockSkew).After(time.Unix(int64(expiresAt), 0)) {
+		return errors.New("ID token has expired")
+	}
+
+	if issuedAt, ok := claims["iat"].(float64); ok && time.Unix(int64(issuedAt), 0).After(now.Add(oidcClockSkew)) {
+		return errors.New("ID token was issued in the future")
+	}
+
+	if notBefore, ok := claims["nbf"].(float64); ok && time.Unix(int64(notBefore), 0).After(now.Add(oidcClockSkew)) {
+		return errors.New("ID token is not valid yet")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return errors.New("ID token nonce does not match the login")
+	}
+
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return errors.New("ID token has no subject")
+	}
+
+	return nil
+}
+
+// discover returns the provider's discovery document, fetching it when it is missing
+// or stale
+func (c *oidcClient) discover(ctx context.Context) (*oidcDiscovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil && c.now().Sub(c.discoveredAt) < oidcDiscoveryTTL {
+		return c.discovery, nil
+	}
+
+	discovery := &oidcDiscovery{}
+	if err := c.getJSON(ctx, strings.TrimSuffix(c.config.Issuer, "/")+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, fmt.Errorf("fetching discovery document: %w", err)
+	}
+
+	if discovery.Issuer != c.config.Issuer {
+		return nil, fmt.Errorf("discovery document names issuer %q instead of %q", discovery.Issuer, c.config.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing an endpoint")
+	}
+
+	c.discovery, c.discoveredAt = discovery, c.now()
+	return discovery, nil
+}
+
+// unknownKeyError reports a token signed with a key the provider does not publish
+type unknownKeyError string
+
+func (e unknownKeyError) Error() string {
+	return fmt.Sprintf("ID token is signed with unknown key %q", string(e))
+}
+
+// signingKey returns the provider's public key with the given ID, fetching the key
+// set again if the key is not known yet. A token without a key ID may only be used
+// when the provider has a single key.
+func (c *oidcClient) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	discovery, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.lookupKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	if c.keys != nil && c.now().Sub(c.keysFetched) < oidcKeyRefreshInterval {
+		return nil, unknownKeyError(kid)
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(ctx, discovery.JWKSURI, &keySet); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		// keys of types this client does not support are skipped
+		if publicKey, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = publicKey
+		}
+	}
+
+	c.keys, c.keysFetched = keys, c.now()
+
+	if key, ok := c.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, unknownKeyError(kid)
+}
+
+// lookupKey finds a cached key; the caller holds the lock
+func (c *oidcClient) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+
+	key, ok := c.keys[kid]
+	return key, ok && kid != ""
+}
+
+// getJSON fetches a provider document
+func (c *oidcClient) getJSON(ctx context.Context, target string, value interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", target, response.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, maxOIDCResponse)).Decode(value)
+}
+
+// publicKey decodes an RSA or elliptic curve public key
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC key is not on its curve")
+		}
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,425 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockOIDCProvider is an OpenID Connect provider serving discovery, a key set and a
+// token endpoint that checks PKCE, so the client can be tested without a real one
+type mockOIDCProvider struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	keys          map[string]interface{}
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	grants        map[string]mockOIDCGrant
+	jwksRequests  int
+	// claims is changed by tests to issue tokens a client must reject
+	claims func(claims jwt.MapClaims)
+}
+
+// mockOIDCGrant is an authorization code handed out once the user "logged in"
+type mockOIDCGrant struct {
+	challenge string
+	nonce     string
+	subject   string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &mockOIDCProvider{
+		keys:          map[string]interface{}{"key-1": key},
+		signingKID:    "key-1",
+		signingMethod: jwt.SigningMethodRS256,
+		grants:        make(map[string]mockOIDCGrant),
+		claims:        func(jwt.MapClaims) {},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.server.URL,
+			"authorization_endpoint": provider.server.URL + "/authorize",
+			"token_endpoint":         provider.server.URL + "/token",
+			"jwks_uri":               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", provider.serveKeys)
+	mux.HandleFunc("/token", provider.serveToken)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+// authorize stands in for the user logging in at the provider: it hands out a code for
+// the login the client's authorization URL describes
+func (p *mockOIDCProvider) authorize(t *testing.T, authURL, subject string) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	code := "code-" + query.Get("state")
+	p.grants[code] = mockOIDCGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), subject: subject}
+	return code
+}
+
+func (p *mockOIDCProvider) serveKeys(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.jwksRequests++
+	keys := []map[string]string{}
+	for kid, key := range p.keys {
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		case *ecdsa.PrivateKey:
+			keys = append(keys, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+				"y": base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+func (p *mockOIDCProvider) serveToken(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	clientID, secret, _ := r.BasicAuth()
+	if clientID != "task-manager" || secret != "client-secret" {
+		fail("invalid_client")
+		return
+	}
+
+	grant, ok := p.grants[r.PostFormValue("code")]
+	delete(p.grants, r.PostFormValue("code"))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != "https://tasks.example.com/auth/oidc/callback" {
+		fail("invalid_grant")
+		return
+	}
+
+	if PKCEChallenge(r.PostFormValue("code_verifier")) != grant.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.server.URL,
+		"sub":                grant.subject,
+		"aud":                "task-manager",
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              grant.nonce,
+		"preferred_username": "alice",
+		"groups":             []string{"tm-admins"},
+	}
+	p.claims(claims)
+
+	token := jwt.NewWithClaims(p.signingMethod, claims)
+	token.Header["kid"] = p.signingKID
+	idToken, err := token.SignedString(p.keys[p.signingKID])
+	if err != nil {
+		idToken, _ = token.SignedString([]byte("client-secret"))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "opaque", "token_type": "Bearer", "id_token": idToken})
+}
+
+// OIDCClientTestSuite runs the client against the mock provider
+type OIDCClientTestSuite struct {
+	suite.Suite
+	provider *mockOIDCProvider
+	client   *oidcClient
+}
+
+func (suite *OIDCClientTestSuite) SetupTest() {
+	suite.provider = newMockOIDCProvider(suite.T())
+	suite.client = NewOIDCClient(OIDCConfig{
+		Issuer:       suite.provider.server.URL,
+		ClientID:     "task-manager",
+		ClientSecret: "client-secret",
+		RedirectURL:  "https://tasks.example.com/auth/oidc/callback",
+		Scopes:       []string{"openid", "profile"},
+		Timeout:      time.Second,
+	}).(*oidcClient)
+}
+
+func TestOIDCClientTestSuite(t *testing.T) {
+	suite.Run(t, new(OIDCClientTestSuite))
+}
+
+// login runs the flow up to the code exchange
+func (suite *OIDCClientTestSuite) login(verifier string) (map[string]interface{}, error) {
+	authURL, err := suite.client.AuthCodeURL(context.Background(), "state", "nonce", "verifier-0123456789-0123456789-0123456789")
+	suite.Require().NoError(err)
+
+	code := suite.provider.authorize(suite.T(), authURL, "248289761001")
+	return suite.client.Exchange(context.Background(), code, verifier, "nonce")
+}
+
+func (suite *OIDCClientTestSuite) TestAuthCodeURL() {
+	authURL, err := suite.client.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	suite.Require().NoError(err)
+
+	parsed, err := url.Parse(authURL)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.provider.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(suite.T(), url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"task-manager"},
+		"redirect_uri":          {"https://tasks.example.com/auth/oidc/callback"},
+		"scope":                 {"openid profile"},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {PKCEChallenge("verifier")},
+		"code_challenge_method": {"S256"},
+	}, parsed.Query())
+}
+
+func (suite *OIDCClientTestSuite) TestPKCEChallenge() {
+	// the example of RFC 7636 appendix B
+	assert.Equal(suite.T(), "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
+
+func (suite *OIDCClientTestSuite) TestExchange() {
+	claims, err := suite.login("verifier-0123456789-0123456789-0123456789")
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "248289761001", claims["sub"])
+	assert.Equal(suite.T(), "alice", claims["preferred_username"])
+	assert.Equal(suite.T(), []interface{}{"tm-admins"}, claims["groups"])
+}
+
+func (suite *OIDCClientTestSuite) TestExchange_WrongCodeVerifier() {
+	_, err := suite.login("another-verifier")
+
+	assert.ErrorIs(suite.T(), err, ErrOIDCRejected)
+	assert.ErrorContains(suite.T(), err, "invalid_grant")
+}
+
+func (suite *OIDCClientTestSuite) TestExchange_CodeUsedTwice() {
+	authURL, err := suite.client.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	suite.Require().NoError(err)
+	code := suite.provider.authorize(suite.T(), authURL, "248289761001")
+
+	_, err = suite.client.Exchange(context.Background(), code, "verifier", "nonce")
+	suite.Require().NoError(err)
+
+	_, err = suite.client.Exchange(context.Background(), code, "verifier", "nonce")
+	assert.ErrorIs(suite.T(), err, ErrOIDCRejected)
+}
+
+func (suite *OIDCClientTestSuite) TestExchange_RejectedIDTokens() {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	tests := []struct {
+		name     string
+		claims   func(claims jwt.MapClaims)
+		setup    func()
+		expected string
+	}{
+		{
+			name:     "another issuer",
+			claims:   func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+			expected: `ID token was issued by "https://evil.example.com"`,
+		},
+		{
+			name:     "another audience",
+			claims:   func(claims jwt.MapClaims) { claims["aud"] = []string{"another-client"} },
+			expected: "ID token is not meant for this client",
+		},
+		{
+			name: "another authorized party",
+			claims: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"task-manager", "another-client"}
+				claims["azp"] = "another-client"
+			},
+			expected: "ID token was issued to another client",
+		},
+		{
+			name:     "expired",
+			claims:   func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+			expected: "ID token has expired",
+		},
+		{
+			name:     "not valid yet",
+			claims:   func(claims jwt.MapClaims) { claims["nbf"] = time.Now().Add(5 * time.Minute).Unix() },
+			expected: "ID token is not valid yet",
+		},
+		{
+			name:     "another nonce",
+			claims:   func(claims jwt.MapClaims) { claims["nonce"] = "replayed" },
+			expected: "ID token nonce does not match the login",
+		},
+		{
+			name:     "no subject",
+			claims:   func(claims jwt.MapClaims) { delete(claims, "sub") },
+			expected: "ID token has no subject",
+		},
+		{
+			name:     "signed with a key the provider does not publish",
+			setup:    func() { suite.provider.keys["key-1"] = otherKey },
+			expected: "crypto/rsa: verification error",
+		},
+		{
+			name:     "signed with the client secret",
+			setup:    func() { suite.provider.signingMethod = jwt.SigningMethodHS256 },
+			expected: "signing method HS256 is invalid",
+		},
+		{
+			name: "unknown key ID",
+			setup: func() {
+				suite.provider.keys["key-2"] = otherKey
+				suite.provider.signingKID = "key-2"
+			},
+			expected: `ID token is signed with unknown key "key-2"`,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			suite.SetupTest()
+			if tt.claims != nil {
+				suite.provider.claims = tt.claims
+			}
+			if tt.setup != nil {
+				// keys are fetched before the provider changes, as they would be by an
+				// earlier login
+				_, err := suite.login("verifier-0123456789-0123456789-0123456789")
+				suite.Require().NoError(err)
+				suite.provider.mu.Lock()
+				tt.setup()
+				suite.provider.mu.Unlock()
+			}
+
+			_, err := suite.login("verifier-0123456789-0123456789-0123456789")
+
+			assert.ErrorIs(suite.T(), err, ErrOIDCRejected)
+			assert.ErrorContains(suite.T(), err, tt.expected)
+		})
+	}
+}
+
+func (suite *OIDCClientTestSuite) TestExchange_UnsignedIDToken() {
+	suite.provider.signingMethod = jwt.SigningMethodNone
+	suite.provider.keys["key-1"] = jwt.UnsafeAllowNoneSignatureType
+
+	_, err := suite.login("verifier-0123456789-0123456789-0123456789")
+
+	assert.ErrorIs(suite.T(), err, ErrOIDCRejected)
+}
+
+func (suite *OIDCClientTestSuite) TestExchange_FollowsKeyRotation() {
+	now := time.Now()
+	suite.client.now = func() time.Time { return now }
+	_, err := suite.login("verifier-0123456789-0123456789-0123456789")
+	suite.Require().NoError(err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	suite.provider.mu.Lock()
+	suite.provider.keys = map[string]interface{}{"key-2": ecKey}
+	suite.provider.signingKID, suite.provider.signingMethod = "key-2", jwt.SigningMethodES256
+	suite.provider.mu.Unlock()
+
+	// the key set was fetched a moment ago, so it is not fetched again yet
+	_, err = suite.login("verifier-0123456789-0123456789-0123456789")
+	assert.ErrorContains(suite.T(), err, "unknown key")
+	assert.Equal(suite.T(), 1, suite.provider.jwksRequests)
+
+	now = now.Add(oidcKeyRefreshInterval)
+	claims, err := suite.login("verifier-0123456789-0123456789-0123456789")
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "248289761001", claims["sub"])
+	assert.Equal(suite.T(), 2, suite.provider.jwksRequests)
+}
+
+func (suite *OIDCClientTestSuite) TestExchange_FollowsAKeyReplacedUnderTheSameID() {
+	now := time.Now()
+	suite.client.now = func() time.Time { return now }
+	_, err := suite.login("verifier-0123456789-0123456789-0123456789")
+	suite.Require().NoError(err)
+
+	replacement, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	suite.provider.mu.Lock()
+	suite.provider.keys["key-1"] = replacement
+	suite.provider.mu.Unlock()
+
+	_, err = suite.login("verifier-0123456789-0123456789-0123456789")
+	assert.ErrorIs(suite.T(), err, ErrOIDCRejected)
+
+	now = now.Add(oidcKeyRefreshInterval)
+	_, err = suite.login("verifier-0123456789-0123456789-0123456789")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, suite.provider.jwksRequests)
+}
+
+func (suite *OIDCClientTestSuite) TestDiscovery_IssuerMismatch() {
+	suite.client.config.Issuer = suite.provider.server.URL + "/"
+
+	_, err := suite.client.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+
+	assert.ErrorContains(suite.T(), err, "discovery document names issuer")
+	assert.False(suite.T(), errors.Is(err, ErrOIDCRejected))
+}
+
+func (suite *OIDCClientTestSuite) TestProviderUnreachable() {
+	suite.provider.server.Close()
+
+	_, err := suite.client.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), errors.Is(err, ErrOIDCRejected), "an unreachable provider is not the user's fault")
+}
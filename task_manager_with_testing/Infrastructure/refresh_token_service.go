@@ -0,0 +1,36 @@
+package infrastructure
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// RefreshTokenService interface
+type RefreshTokenService interface {
+	GenerateToken() (string, error)
+	HashToken(token string) string
+}
+
+type refreshTokenService struct{}
+
+// NewRefreshTokenService creates a new refresh token service
+func NewRefreshTokenService() RefreshTokenService {
+	return &refreshTokenService{}
+}
+
+// GenerateToken generates a random opaque refresh token
+func (s *refreshTokenService) GenerateToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashToken hashes a refresh token for storage; the tokens are random so a fast hash is enough
+func (s *refreshTokenService) HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,40 @@
+package infrastructure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RefreshTokenServiceTestSuite struct {
+	suite.Suite
+	refreshTokenService RefreshTokenService
+}
+
+func (suite *RefreshTokenServiceTestSuite) SetupTest() {
+	suite.refreshTokenService = NewRefreshTokenService()
+}
+
+func TestRefreshTokenServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RefreshTokenServiceTestSuite))
+}
+
+func (suite *RefreshTokenServiceTestSuite) TestGenerateToken_Unique() {
+	first, err := suite.refreshTokenService.GenerateToken()
+	assert.NoError(suite.T(), err)
+
+	second, err := suite.refreshTokenService.GenerateToken()
+	assert.NoError(suite.T(), err)
+
+	assert.Len(suite.T(), first, 43)
+	assert.NotEqual(suite.T(), first, second)
+}
+
+func (suite *RefreshTokenServiceTestSuite) TestHashToken() {
+	hash := suite.refreshTokenService.HashToken("token")
+
+	assert.Equal(suite.T(), hash, suite.refreshTokenService.HashToken("token"))
+	assert.NotEqual(suite.T(), hash, suite.refreshTokenService.HashToken("other"))
+	assert.NotContains(suite.T(), hash, "token")
+}
@@ -0,0 +1,61 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithRequestID(header string) (*httptest.ResponseRecorder, string) {
+	var seen string
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/test", func(ctx *gin.Context) {
+		seen = RequestIDFromContext(ctx.Request.Context())
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	if header != "" {
+		req.Header.Set(RequestIDHeader, header)
+	}
+	router.ServeHTTP(w, req)
+
+	return w, seen
+}
+
+func TestRequestIDMiddleware_Generates(t *testing.T) {
+	w, seen := serveWithRequestID("")
+
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+
+	_, other := serveWithRequestID("")
+	assert.NotEqual(t, seen, other)
+}
+
+func TestRequestIDMiddleware_ReusesClientID(t *testing.T) {
+	w, seen := serveWithRequestID("client-request-1")
+
+	assert.Equal(t, "client-request-1", seen)
+	assert.Equal(t, "client-request-1", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDMiddleware_RejectsInvalidClientID(t *testing.T) {
+	for _, header := range []string{"has spaces", strings.Repeat("a", maxRequestIDLength+1)} {
+		_, seen := serveWithRequestID(header)
+		assert.NotEqual(t, header, seen)
+		assert.Len(t, seen, 32)
+	}
+}
+
+func TestRequestIDFromContext_Missing(t *testing.T) {
+	assert.Empty(t, RequestIDFromContext(context.Background()))
+}
@@ -0,0 +1,140 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+)
+
+// smtpTimeout bounds a delivery when the context has no deadline of its own
+const smtpTimeout = 30 * time.Second
+
+// SMTPConfig configures the SMTP notifier. Users are mailed at their username when it
+// is an email address, and at username@RecipientDomain otherwise.
+type SMTPConfig struct {
+	Host            string
+	Port            int
+	Username        string
+	Password        string
+	From            string
+	RecipientDomain string
+}
+
+// smtpNotifier emails reminders through an SMTP server
+type smtpNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier creates a notifier that emails reminders
+func NewSMTPNotifier(config SMTPConfig) Notifier {
+	return &smtpNotifier{config: config}
+}
+
+// Notify emails the reminder to the task's owner. STARTTLS is used when the server
+// offers it, and credentials are only sent when a username is configured.
+func (n *smtpNotifier) Notify(ctx context.Context, reminder domain.Reminder) error {
+	to, err := n.recipient(reminder.Username)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port)))
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP server: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	// abandon the conversation as soon as the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return fmt.Errorf("authenticating to SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("sending SMTP sender: %w", err)
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("sending SMTP recipient: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("starting SMTP message: %w", err)
+	}
+
+	if _, err := writer.Write(n.message(to, reminder)); err != nil {
+		return fmt.Errorf("writing SMTP message: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("finishing SMTP message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// recipient returns the email address of a user
+func (n *smtpNotifier) recipient(username string) (string, error) {
+	address := username
+	if !strings.Contains(address, "@") {
+		if n.config.RecipientDomain == "" {
+			return "", fmt.Errorf("no email address for user %s", username)
+		}
+		address = username + "@" + n.config.RecipientDomain
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid email address for user %s: %w", username, err)
+	}
+
+	return parsed.Address, nil
+}
+
+// message formats the reminder as a plain text email
+func (n *smtpNotifier) message(to string, reminder domain.Reminder) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", reminder.Subject()))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(reminder.Body())
+
+	return []byte(b.String())
+}
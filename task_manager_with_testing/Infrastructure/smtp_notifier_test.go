@@ -0,0 +1,198 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMail is one message received by the fake SMTP server
+type fakeMail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer speaks just enough SMTP to accept messages and records them.
+// Recipients listed in reject are refused.
+type fakeSMTPServer struct {
+	listener net.Listener
+	mails    chan fakeMail
+	reject   map[string]bool
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting fake SMTP server: %v", err)
+	}
+
+	server := &fakeSMTPServer{listener: listener, mails: make(chan fakeMail, 10), reject: make(map[string]bool)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeSMTPServer) config() SMTPConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "tasks@example.com", RecipientDomain: "example.com"}
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var mail fakeMail
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			mail.auth = string(credentials)
+			reply("235 authenticated")
+		case "MAIL":
+			mail.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>")
+			if s.reject[to] {
+				reply("550 no such user")
+				continue
+			}
+			mail.to = append(mail.to, to)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			mail.data = data.String()
+			s.mails <- mail
+			mail = fakeMail{}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) receive(t *testing.T) fakeMail {
+	select {
+	case mail := <-s.mails:
+		return mail
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+		return fakeMail{}
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier := NewSMTPNotifier(server.config())
+
+	reminder := testReminder()
+	reminder.Title = "Pay rent – März"
+	err := notifier.Notify(context.Background(), reminder)
+	assert.NoError(t, err)
+
+	mail := server.receive(t)
+	assert.Empty(t, mail.auth)
+	assert.Equal(t, "tasks@example.com", mail.from)
+	assert.Equal(t, []string{"alice@example.com"}, mail.to)
+	assert.Contains(t, mail.data, "To: alice@example.com\r\n")
+	assert.Contains(t, mail.data, "Subject: =?utf-8?q?Task_due_in_1h0m0s:_Pay_rent_=E2=80=93_M=C3=A4rz?=\r\n")
+	assert.Contains(t, mail.data, "Content-Type: text/plain; charset=utf-8\r\n")
+	assert.Contains(t, mail.data, "Hi alice,\r\n")
+}
+
+func TestSMTPNotifier_Auth(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	config := server.config()
+	config.Username = "mailer"
+	config.Password = "secret"
+	notifier := NewSMTPNotifier(config)
+
+	reminder := testReminder()
+	reminder.Username = "bob@example.org"
+	assert.NoError(t, notifier.Notify(context.Background(), reminder))
+
+	mail := server.receive(t)
+	assert.Equal(t, "\x00mailer\x00secret", mail.auth)
+	assert.Equal(t, []string{"bob@example.org"}, mail.to)
+}
+
+func TestSMTPNotifier_Errors(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	server.reject["alice@example.com"] = true
+
+	err := NewSMTPNotifier(server.config()).Notify(context.Background(), testReminder())
+	assert.ErrorContains(t, err, "sending SMTP recipient")
+
+	config := server.config()
+	config.RecipientDomain = ""
+	err = NewSMTPNotifier(config).Notify(context.Background(), testReminder())
+	assert.EqualError(t, err, "no email address for user alice")
+
+	// nothing listens on a closed port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserving a port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	config = server.config()
+	config.Port = port
+	err = NewSMTPNotifier(config).Notify(context.Background(), testReminder())
+	assert.ErrorContains(t, err, "connecting to SMTP server")
+}
+
+func TestSMTPNotifier_CancelledContext(t *testing.T) {
+	server := startFakeSMTPServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewSMTPNotifier(server.config()).Notify(ctx, testReminder())
+	assert.Error(t, err)
+}
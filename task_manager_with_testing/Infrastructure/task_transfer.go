@@ -0,0 +1,470 @@
+package infrastructure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+)
+
+// listSeparator separates the values of the depends_on and tags columns of a CSV file
+const listSeparator = ";"
+
+// byteOrderMark is how spreadsheets often start the CSV files they save
+const byteOrderMark = "\ufeff"
+
+// maxImportLine bounds the length of a single NDJSON record
+const maxImportLine = 1 << 20
+
+// TaskEncoder writes tasks in one of the export formats
+type TaskEncoder interface {
+	// Encode writes one task
+	Encode(task domain.Task) error
+	// Close ends the output; it must be called even when no task was written
+	Close() error
+}
+
+// NewTaskEncoder creates an encoder that writes tasks to w in the given format
+func NewTaskEncoder(format string, w io.Writer) (TaskEncoder, error) {
+	switch format {
+	case domain.FormatCSV:
+		return &csvTaskEncoder{writer: csv.NewWriter(w)}, nil
+	case domain.FormatJSON:
+		return &jsonTaskEncoder{w: w}, nil
+	case domain.FormatNDJSON:
+		return &ndjsonTaskEncoder{encoder: json.NewEncoder(w)}, nil
+	}
+
+	return nil, &domain.BadRequestError{Message: "format must be csv, json or ndjson"}
+}
+
+// csvTaskEncoder writes a header row followed by one row per task
+type csvTaskEncoder struct {
+	writer  *csv.Writer
+	started bool
+}
+
+// Encode writes the task's row, after the header if it is the first
+func (e *csvTaskEncoder) Encode(task domain.Task) error {
+	e.start()
+	e.writer.Write(taskRow(task))
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// Close writes the header if no task was written
+func (e *csvTaskEncoder) Close() error {
+	e.start()
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvTaskEncoder) start() {
+	if !e.started {
+		e.started = true
+		e.writer.Write(domain.TaskColumns)
+	}
+}
+
+// taskRow returns the task's values in the order of domain.TaskColumns
+func taskRow(task domain.Task) []string {
+	occurrence := ""
+	if task.Occurrence > 0 {
+		occurrence = strconv.Itoa(task.Occurrence)
+	}
+
+	return []string{
+		task.ID,
+		task.Title,
+		task.DueDate.UTC().Format(time.RFC3339),
+		task.Status,
+		task.OwnerID,
+		task.ProjectID,
+		strconv.FormatInt(task.Version, 10),
+		task.ParentID,
+		strings.Join(task.DependsOn, listSeparator),
+		strings.Join(task.Tags, listSeparator),
+		task.Recurrence,
+		occurrence,
+		task.NextID,
+	}
+}
+
+// jsonTaskEncoder writes a single JSON array of tasks
+type jsonTaskEncoder struct {
+	w       io.Writer
+	started bool
+}
+
+// Encode writes the task as the next element of the array
+func (e *jsonTaskEncoder) Encode(task domain.Task) error {
+	separator := ","
+	if !e.started {
+		e.started, separator = true, "["
+	}
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(append([]byte(separator), payload...))
+	return err
+}
+
+// Close ends the array
+func (e *jsonTaskEncoder) Close() error {
+	end := "]\n"
+	if !e.started {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// ndjsonTaskEncoder writes one JSON object per line
+type ndjsonTaskEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonTaskEncoder) Encode(task domain.Task) error {
+	return e.encoder.Encode(task)
+}
+
+func (e *ndjsonTaskEncoder) Close() error {
+	return nil
+}
+
+// DecodeTasks reads the records of an import in the given format. The mapping renames
+// columns, or object fields, to task columns or to domain.IgnoreColumn. A record that
+// cannot be read is returned with its error so the rest of the import can go ahead; a
+// file that cannot be read at all fails with a BadRequestError, while errors reading r
+// are returned as they are.
+func DecodeTasks(format string, r io.Reader, mapping map[string]string) ([]domain.ImportRecord, error) {
+	switch format {
+	case domain.FormatCSV:
+		return decodeCSVTasks(r, mapping)
+	case domain.FormatJSON:
+		return decodeJSONTasks(r, mapping)
+	case domain.FormatNDJSON:
+		return decodeNDJSONTasks(r, mapping)
+	}
+
+	return nil, &domain.BadRequestError{Message: "format must be csv, json or ndjson"}
+}
+
+// decodeCSVTasks reads a CSV file whose first row names its columns
+func decodeCSVTasks(r io.Reader, mapping map[string]string) ([]domain.ImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &domain.BadRequestError{Message: "The CSV file has no header row"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	columns := make([]string, len(header))
+	mapped := make(map[string]bool)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, byteOrderMark))
+		column, err := mapColumn(name, mapping, mapped)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = column
+	}
+
+	for source := range mapping {
+		if !containsColumn(header, source) {
+			return nil, &domain.BadRequestError{Message: fmt.Sprintf("Column %s in the mapping is not in the file", source)}
+		}
+	}
+
+	var records []domain.ImportRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, domain.ImportRecord{Row: parseErr.StartLine, Err: &domain.BadRequestError{Message: parseErr.Err.Error()}})
+		} else if err != nil {
+			return nil, err
+		} else {
+			line, _ := reader.FieldPos(0)
+			record := domain.ImportRecord{Row: line, Fields: make(map[string]bool)}
+			if len(fields) != len(columns) {
+				record.Err = &domain.BadRequestError{Message: fmt.Sprintf("The row has %d columns but the header has %d", len(fields), len(columns))}
+			} else {
+				for i, value := range fields {
+					if record.Err = setColumn(&record, columns[i], value); record.Err != nil {
+						break
+					}
+				}
+			}
+			records = append(records, record)
+		}
+
+		if len(records) > domain.MaxImportRecords {
+			return nil, tooManyRecords()
+		}
+	}
+}
+
+// decodeJSONTasks reads a JSON array of task objects
+func decodeJSONTasks(r io.Reader, mapping map[string]string) ([]domain.ImportRecord, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		if err != nil && !isJSONSyntaxError(err) {
+			return nil, err
+		}
+		return nil, &domain.BadRequestError{Message: "A JSON import must be an array of tasks"}
+	}
+
+	var records []domain.ImportRecord
+	for row := 1; decoder.More(); row++ {
+		var object map[string]json.RawMessage
+		if err := decoder.Decode(&object); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, jsonError(err)
+			}
+			records = append(records, domain.ImportRecord{Row: row, Err: &domain.BadRequestError{Message: "The record must be an object"}})
+		} else {
+			records = append(records, decodeObject(row, object, mapping))
+		}
+
+		if len(records) > domain.MaxImportRecords {
+			return nil, tooManyRecords()
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, jsonError(err)
+	}
+
+	return records, nil
+}
+
+// decodeNDJSONTasks reads one task object per line; blank lines are skipped
+func decodeNDJSONTasks(r io.Reader, mapping map[string]string) ([]domain.ImportRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxImportLine)
+
+	var records []domain.ImportRecord
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(text, &object); err != nil || object == nil {
+			records = append(records, domain.ImportRecord{Row: line, Err: &domain.BadRequestError{Message: "The line must be a JSON object"}})
+		} else {
+			records = append(records, decodeObject(line, object, mapping))
+		}
+
+		if len(records) > domain.MaxImportRecords {
+			return nil, tooManyRecords()
+		}
+	}
+
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, &domain.BadRequestError{Message: fmt.Sprintf("Lines must not be longer than %d bytes", maxImportLine)}
+	}
+
+	return records, scanner.Err()
+}
+
+// decodeObject reads a record from a JSON object. depends_on and tags may be arrays of
+// strings; every other value must be a string.
+func decodeObject(row int, object map[string]json.RawMessage, mapping map[string]string) domain.ImportRecord {
+	record := domain.ImportRecord{Row: row, Fields: make(map[string]bool)}
+
+	// fields are read in a fixed order so a record with several problems always reports the same one
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mapped := make(map[string]bool)
+	for _, key := range keys {
+		column, err := mapColumn(key, mapping, mapped)
+		if err != nil {
+			record.Err = err
+			return record
+		}
+
+		if !domain.IsImportColumn(column) {
+			continue
+		}
+
+		raw := object[key]
+		var list []string
+		if (column == "depends_on" || column == "tags") && json.Unmarshal(raw, &list) == nil {
+			if column == "depends_on" {
+				record.Task.DependsOn = list
+			} else {
+				record.Task.Tags = list
+			}
+			record.Fields[column] = true
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			record.Err = &domain.BadRequestError{Message: fmt.Sprintf("%s must be a string", key)}
+			return record
+		}
+
+		if record.Err = setColumn(&record, column, value); record.Err != nil {
+			return record
+		}
+	}
+
+	return record
+}
+
+// mapColumn returns the task column a column of the file is read into. Columns must be
+// task columns unless they are mapped, and no two may be read into the same one.
+func mapColumn(name string, mapping map[string]string, mapped map[string]bool) (string, error) {
+	column := name
+	if target, ok := mapping[name]; ok {
+		column = target
+	}
+
+	if column == domain.IgnoreColumn {
+		return column, nil
+	}
+
+	if !domain.IsTaskColumn(column) {
+		return "", &domain.BadRequestError{Message: fmt.Sprintf("Unknown column %s; map it to a task column, or to %s to ignore it", name, domain.IgnoreColumn)}
+	}
+
+	if mapped[column] {
+		return "", &domain.BadRequestError{Message: fmt.Sprintf("More than one column is read into %s", column)}
+	}
+	mapped[column] = true
+
+	return column, nil
+}
+
+// setColumn sets one of the record's task fields from its text. Columns kept by the
+// server are ignored.
+func setColumn(record *domain.ImportRecord, column, value string) error {
+	value = strings.TrimSpace(value)
+	task := &record.Task
+
+	switch column {
+	case "title":
+		task.Title = value
+	case "status":
+		task.Status = value
+	case "project_id":
+		task.ProjectID = value
+	case "parent_id":
+		task.ParentID = value
+	case "recurrence":
+		task.Recurrence = value
+	case "due_date":
+		due, err := parseDueDate(value)
+		if err != nil {
+			return err
+		}
+		task.DueDate = due
+	case "depends_on":
+		task.DependsOn = splitList(value)
+	case "tags":
+		task.Tags = splitList(value)
+	default:
+		return nil
+	}
+
+	record.Fields[column] = true
+	return nil
+}
+
+// splitList reads the values of a list column, skipping empty ones
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+// parseDueDate reads an RFC3339 timestamp or, as spreadsheets tend to write them, a
+// plain date taken as midnight UTC. An empty value leaves the due date unset.
+func parseDueDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if due, err := time.Parse(time.RFC3339, value); err == nil {
+		return due, nil
+	}
+
+	if due, err := time.Parse(time.DateOnly, value); err == nil {
+		return due, nil
+	}
+
+	return time.Time{}, &domain.BadRequestError{Message: "due_date must be an RFC3339 timestamp or a YYYY-MM-DD date"}
+}
+
+func containsColumn(header []string, name string) bool {
+	for _, column := range header {
+		if strings.TrimSpace(strings.TrimPrefix(column, byteOrderMark)) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// csvError reports a malformed CSV header; other errors come from reading the file
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &domain.BadRequestError{Message: "Invalid CSV: " + parseErr.Error()}
+	}
+
+	return err
+}
+
+// jsonError reports malformed JSON; other errors come from reading the file
+func jsonError(err error) error {
+	if isJSONSyntaxError(err) {
+		return &domain.BadRequestError{Message: "Invalid JSON: " + err.Error()}
+	}
+
+	return err
+}
+
+func isJSONSyntaxError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func tooManyRecords() error {
+	return &domain.BadRequestError{Message: fmt.Sprintf("An import can have at most %d tasks", domain.MaxImportRecords)}
+}
@@ -0,0 +1,201 @@
+package infrastructure
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func transferTasks() []domain.Task {
+	due := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+	return []domain.Task{
+		{ID: "1", Title: "Plain", DueDate: due, Status: "pending", OwnerID: "owner", Version: 1},
+		{ID: "2", Title: "Quoted, \"really\"", DueDate: due, Status: "pending", OwnerID: "owner", Version: 3, ParentID: "1", DependsOn: []string{"3", "4"}, Tags: []string{"home", "urgent"}, Recurrence: "FREQ=DAILY", Occurrence: 2},
+	}
+}
+
+func encodeTasks(t *testing.T, format string, tasks []domain.Task) string {
+	var out bytes.Buffer
+	encoder, err := NewTaskEncoder(format, &out)
+	assert.NoError(t, err)
+
+	for _, task := range tasks {
+		assert.NoError(t, encoder.Encode(task))
+	}
+	assert.NoError(t, encoder.Close())
+
+	return out.String()
+}
+
+func TestTaskEncoder_CSV(t *testing.T) {
+	assert.Equal(t, "id,title,due_date,status,owner_id,project_id,version,parent_id,depends_on,tags,recurrence,occurrence,next_id\n"+
+		"1,Plain,2030-01-02T15:04:05Z,pending,owner,,1,,,,,,\n"+
+		"2,\"Quoted, \"\"really\"\"\",2030-01-02T15:04:05Z,pending,owner,,3,1,3;4,home;urgent,FREQ=DAILY,2,\n",
+		encodeTasks(t, domain.FormatCSV, transferTasks()))
+
+	assert.Equal(t, "id,title,due_date,status,owner_id,project_id,version,parent_id,depends_on,tags,recurrence,occurrence,next_id\n", encodeTasks(t, domain.FormatCSV, nil))
+}
+
+func TestTaskEncoder_JSON(t *testing.T) {
+	assert.JSONEq(t, `[
+		{"id": "1", "title": "Plain", "due_date": "2030-01-02T15:04:05Z", "status": "pending", "owner_id": "owner", "version": 1},
+		{"id": "2", "title": "Quoted, \"really\"", "due_date": "2030-01-02T15:04:05Z", "status": "pending", "owner_id": "owner", "version": 3,
+		 "parent_id": "1", "depends_on": ["3", "4"], "tags": ["home", "urgent"], "recurrence": "FREQ=DAILY", "occurrence": 2}
+	]`, encodeTasks(t, domain.FormatJSON, transferTasks()))
+
+	assert.Equal(t, "[]\n", encodeTasks(t, domain.FormatJSON, nil))
+}
+
+func TestTaskEncoder_NDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(encodeTasks(t, domain.FormatNDJSON, transferTasks()), "\n"), "\n")
+
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id": "1", "title": "Plain", "due_date": "2030-01-02T15:04:05Z", "status": "pending", "owner_id": "owner", "version": 1}`, lines[0])
+}
+
+func TestTaskEncoder_UnknownFormat(t *testing.T) {
+	_, err := NewTaskEncoder("xml", &bytes.Buffer{})
+	assert.IsType(t, &domain.BadRequestError{}, err)
+}
+
+// TestDecodeTasks_RoundTrip checks that every export format imports the columns an import sets
+func TestDecodeTasks_RoundTrip(t *testing.T) {
+	for _, format := range []string{domain.FormatCSV, domain.FormatJSON, domain.FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			records, err := DecodeTasks(format, strings.NewReader(encodeTasks(t, format, transferTasks())), nil)
+
+			assert.NoError(t, err)
+			if assert.Len(t, records, 2) {
+				assert.NoError(t, records[1].Err)
+				assert.Equal(t, domain.Task{
+					Title:      "Quoted, \"really\"",
+					DueDate:    time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC),
+					Status:     "pending",
+					ParentID:   "1",
+					DependsOn:  []string{"3", "4"},
+					Tags:       []string{"home", "urgent"},
+					Recurrence: "FREQ=DAILY",
+				}, records[1].Task)
+			}
+		})
+	}
+}
+
+func TestDecodeTasks_CSV(t *testing.T) {
+	input := byteOrderMark + "Name,Due,Notes,status\n" +
+		"Task 1,2030-01-02,ignored,pending\n" +
+		"\n" +
+		"Task 2,tomorrow,,pending\n" +
+		"Task 3,2030-01-02\n"
+
+	records, err := DecodeTasks(domain.FormatCSV, strings.NewReader(input), map[string]string{"Name": "title", "Due": "due_date", "Notes": "-"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, 2, records[0].Row)
+		assert.NoError(t, records[0].Err)
+		assert.Equal(t, domain.Task{Title: "Task 1", DueDate: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), Status: "pending"}, records[0].Task)
+		assert.Equal(t, map[string]bool{"title": true, "due_date": true, "status": true}, records[0].Fields)
+
+		assert.Equal(t, 4, records[1].Row)
+		assert.EqualError(t, records[1].Err, "due_date must be an RFC3339 timestamp or a YYYY-MM-DD date")
+
+		assert.Equal(t, 5, records[2].Row)
+		assert.EqualError(t, records[2].Err, "The row has 2 columns but the header has 4")
+	}
+}
+
+func TestDecodeTasks_CSVLists(t *testing.T) {
+	input := "title,depends_on,tags\n" +
+		"Task 1, 1 ;;2 ,home; Urgent\n" +
+		"Task 2,,\n"
+
+	records, err := DecodeTasks(domain.FormatCSV, strings.NewReader(input), nil)
+
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, []string{"1", "2"}, records[0].Task.DependsOn)
+		assert.Equal(t, []string{"home", "Urgent"}, records[0].Task.Tags)
+		assert.Nil(t, records[1].Task.Tags)
+		assert.True(t, records[1].Fields["tags"])
+	}
+}
+
+func TestDecodeTasks_CSVHeader(t *testing.T) {
+	cases := map[string]struct {
+		input   string
+		mapping map[string]string
+		err     string
+	}{
+		"empty":          {"", nil, "The CSV file has no header row"},
+		"unknown column": {"title,notes\n", nil, "Unknown column notes; map it to a task column, or to - to ignore it"},
+		"repeated":       {"title,Name\n", map[string]string{"Name": "title"}, "More than one column is read into title"},
+		"missing source": {"title\n", map[string]string{"Name": "title"}, "Column Name in the mapping is not in the file"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeTasks(domain.FormatCSV, strings.NewReader(c.input), c.mapping)
+
+			assert.IsType(t, &domain.BadRequestError{}, err)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func TestDecodeTasks_JSON(t *testing.T) {
+	input := `[
+		{"name": "Task 1", "due_date": "2030-01-02T15:04:05Z", "status": "pending", "depends_on": "1; 2", "tags": ["home", "Urgent"], "id": "kept by the server"},
+		42,
+		{"title": "Task 3", "status": 1},
+		{"title": "Task 4", "extra": true}
+	]`
+
+	records, err := DecodeTasks(domain.FormatJSON, strings.NewReader(input), map[string]string{"name": "title"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, records, 4) {
+		assert.NoError(t, records[0].Err)
+		assert.Equal(t, domain.Task{Title: "Task 1", DueDate: time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC), Status: "pending", DependsOn: []string{"1", "2"}, Tags: []string{"home", "Urgent"}}, records[0].Task)
+		assert.Equal(t, map[string]bool{"title": true, "due_date": true, "status": true, "depends_on": true, "tags": true}, records[0].Fields)
+
+		assert.Equal(t, 2, records[1].Row)
+		assert.EqualError(t, records[1].Err, "The record must be an object")
+		assert.EqualError(t, records[2].Err, "status must be a string")
+		assert.EqualError(t, records[3].Err, "Unknown column extra; map it to a task column, or to - to ignore it")
+	}
+}
+
+func TestDecodeTasks_InvalidJSON(t *testing.T) {
+	_, err := DecodeTasks(domain.FormatJSON, strings.NewReader(`{"title": "Not an array"}`), nil)
+	assert.EqualError(t, err, "A JSON import must be an array of tasks")
+
+	_, err = DecodeTasks(domain.FormatJSON, strings.NewReader(`[{"title": "Task 1"}, {"title": `), nil)
+	assert.IsType(t, &domain.BadRequestError{}, err)
+}
+
+func TestDecodeTasks_NDJSON(t *testing.T) {
+	input := `{"title": "Task 1", "status": "pending"}` + "\n\n" + `{"title": ` + "\n" + `{"title": "Task 4"}`
+
+	records, err := DecodeTasks(domain.FormatNDJSON, strings.NewReader(input), nil)
+
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, []int{1, 3, 4}, []int{records[0].Row, records[1].Row, records[2].Row})
+		assert.NoError(t, records[0].Err)
+		assert.EqualError(t, records[1].Err, "The line must be a JSON object")
+		assert.Equal(t, "Task 4", records[2].Task.Title)
+	}
+}
+
+func TestDecodeTasks_TooManyRecords(t *testing.T) {
+	input := strings.Repeat(`{"title": "Task"}`+"\n", domain.MaxImportRecords+1)
+
+	_, err := DecodeTasks(domain.FormatNDJSON, strings.NewReader(input), nil)
+	assert.EqualError(t, err, "An import can have at most 10000 tasks")
+}
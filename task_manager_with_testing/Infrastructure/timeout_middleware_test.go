@@ -0,0 +1,46 @@
+package infrastructure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutMiddleware_SetsDeadline(t *testing.T) {
+	router := gin.New()
+	router.Use(TimeoutMiddleware(50 * time.Millisecond))
+	router.GET("/test", func(ctx *gin.Context) {
+		deadline, ok := ctx.Request.Context().Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), deadline, 50*time.Millisecond)
+
+		<-ctx.Request.Context().Done()
+		ctx.Status(http.StatusGatewayTimeout)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestTimeoutMiddleware_Disabled(t *testing.T) {
+	router := gin.New()
+	router.Use(TimeoutMiddleware(0))
+	router.GET("/test", func(ctx *gin.Context) {
+		_, ok := ctx.Request.Context().Deadline()
+		assert.False(t, ok)
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,95 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	domain "task-manager/Domain"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// maxWebhookResponse bounds how much of a response body is read before it is discarded
+const maxWebhookResponse = 64 << 10
+
+// WebhookSender posts webhook deliveries to their endpoints
+type WebhookSender interface {
+	// Send posts the delivery's payload and returns the response status code. It fails
+	// if the request cannot be made or the endpoint does not answer with a 2xx status.
+	Send(ctx context.Context, webhook domain.Webhook, delivery domain.WebhookDelivery) (int, error)
+}
+
+// webhookSender sends signed webhook requests over HTTP
+type webhookSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookSender creates a sender whose requests give up after timeout
+func NewWebhookSender(timeout time.Duration) WebhookSender {
+	return &webhookSender{
+		client: &http.Client{
+			Timeout: timeout,
+			// a redirect could send the signed payload somewhere the admin never registered
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		now: time.Now,
+	}
+}
+
+// SignWebhookPayload signs a payload sent at the given Unix time. Receivers recompute the
+// HMAC-SHA256 of "<timestamp>.<payload>" with the webhook's secret and compare it to the
+// signature header; the timestamp lets them reject old requests that are replayed.
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send posts the delivery's payload, signed with the webhook's secret
+func (s *webhookSender) Send(ctx context.Context, webhook domain.Webhook, delivery domain.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	timestamp := s.now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "task-manager-webhooks")
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, payload))
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxWebhookResponse))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
@@ -0,0 +1,100 @@
+package infrastructure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDelivery() domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:        "delivery-id",
+		EventType: domain.EventTaskCreated,
+		Payload:   `{"type":"task.created"}`,
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t,
+		"sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163",
+		SignWebhookPayload("secret", 1700000000, []byte("{}")),
+	)
+}
+
+func TestWebhookSender_Send(t *testing.T) {
+	var request *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := NewWebhookSender(time.Second).(*webhookSender)
+	sender.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	status, err := sender.Send(context.Background(), domain.Webhook{URL: server.URL, Secret: "secret"}, testDelivery())
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, `{"type":"task.created"}`, string(body))
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	assert.Equal(t, "1700000000", request.Header.Get(WebhookTimestampHeader))
+	assert.Equal(t, domain.EventTaskCreated, request.Header.Get(WebhookEventHeader))
+	assert.Equal(t, "delivery-id", request.Header.Get(WebhookDeliveryHeader))
+	assert.Equal(t, SignWebhookPayload("secret", 1700000000, body), request.Header.Get(WebhookSignatureHeader))
+}
+
+func TestWebhookSender_Send_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := NewWebhookSender(time.Second).Send(context.Background(), domain.Webhook{URL: server.URL}, testDelivery())
+
+	assert.EqualError(t, err, "endpoint responded with status 503")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestWebhookSender_Send_DoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	status, err := NewWebhookSender(time.Second).Send(context.Background(), domain.Webhook{URL: server.URL}, testDelivery())
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.False(t, followed)
+}
+
+func TestWebhookSender_Send_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	status, err := NewWebhookSender(50*time.Millisecond).Send(context.Background(), domain.Webhook{URL: server.URL}, testDelivery())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, status)
+}
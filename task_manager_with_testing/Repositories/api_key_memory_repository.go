@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// apiKeyMemoryRepository keeps API keys in memory, oldest first
+type apiKeyMemoryRepository struct {
+	mu   sync.RWMutex
+	keys []domain.APIKey
+}
+
+// NewAPIKeyMemoryRepository creates a new in-memory API key repository
+func NewAPIKeyMemoryRepository() APIKeyRepository {
+	return &apiKeyMemoryRepository{keys: []domain.APIKey{}}
+}
+
+// CreateKey stores a new key and returns it with its assigned ID
+func (r *apiKeyMemoryRepository) CreateKey(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.KeyHash == key.KeyHash {
+			return domain.APIKey{}, &domain.InternalServerError{Message: "Error creating API key"}
+		}
+	}
+
+	key.ID = primitive.NewObjectID().Hex()
+	key.Scopes = slices.Clone(key.Scopes)
+	r.keys = append(r.keys, key)
+
+	return key, nil
+}
+
+// GetKeys lists a user's keys, oldest first
+func (r *apiKeyMemoryRepository) GetKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []domain.APIKey{}
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// FindByHash retrieves the key with the given hash
+func (r *apiKeyMemoryRepository) FindByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+
+	return domain.APIKey{}, &domain.NotFoundError{Message: "API key not found"}
+}
+
+// DeleteKey deletes one of the user's keys
+func (r *apiKeyMemoryRepository) DeleteKey(ctx context.Context, userID, id string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, key := range r.keys {
+		if key.ID == id && key.UserID == userID {
+			r.keys = append(r.keys[:i:i], r.keys[i+1:]...)
+			return nil
+		}
+	}
+
+	return &domain.NotFoundError{Message: "API key not found"}
+}
+
+// TouchKey records that the key was used, unless a later use is already recorded
+func (r *apiKeyMemoryRepository) TouchKey(ctx context.Context, id string, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, key := range r.keys {
+		if key.ID == id && (key.LastUsedAt == nil || key.LastUsedAt.Before(usedAt)) {
+			r.keys[i].LastUsedAt = &usedAt
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepository stores the hashed API keys users create
+type APIKeyRepository interface {
+	// CreateKey stores a new key and returns it with its assigned ID
+	CreateKey(ctx context.Context, key domain.APIKey) (domain.APIKey, error)
+	// GetKeys lists a user's keys, oldest first
+	GetKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (domain.APIKey, error)
+	// DeleteKey deletes one of the user's keys
+	DeleteKey(ctx context.Context, userID, id string) error
+	// TouchKey records that the key was used, unless a later use is already recorded
+	TouchKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// apiKeyRepository struct
+type apiKeyRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(database *mongo.Database, collection string) APIKeyRepository {
+	return &apiKeyRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the unique index keys are looked up by and the index that
+// lists each user's keys
+func (r *apiKeyRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return databaseError(err, "Error creating API key indexes")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// CreateKey stores a new key and returns it with its assigned ID
+func (r *apiKeyRepository) CreateKey(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	key.ID = ""
+	result, err := r.db.Collection(r.collection).InsertOne(ctx, key)
+	if err != nil {
+		return domain.APIKey{}, databaseError(err, "Error creating API key")
+	}
+
+	if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+		key.ID = objId.Hex()
+	}
+
+	return key, nil
+}
+
+// GetKeys lists a user's keys, oldest first
+func (r *apiKeyRepository) GetKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving API keys")
+	}
+
+	defer cursor.Close(ctx)
+
+	keys := []domain.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, databaseError(err, "Error retrieving API keys")
+	}
+
+	return keys, nil
+}
+
+// FindByHash retrieves the key with the given hash
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.APIKey{}, &domain.NotFoundError{Message: "API key not found"}
+	}
+
+	if err != nil {
+		return domain.APIKey{}, databaseError(err, "Error retrieving API key")
+	}
+
+	return key, nil
+}
+
+// DeleteKey deletes one of the user's keys
+func (r *apiKeyRepository) DeleteKey(ctx context.Context, userID, id string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	result, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": objId, "user_id": userID})
+	if err != nil {
+		return databaseError(err, "Error deleting API key")
+	}
+
+	if result.DeletedCount == 0 {
+		return &domain.NotFoundError{Message: "API key not found"}
+	}
+
+	return nil
+}
+
+// TouchKey records that the key was used, unless a later use is already recorded
+func (r *apiKeyRepository) TouchKey(ctx context.Context, id string, usedAt time.Time) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	filter := bson.M{"_id": objId, "$or": bson.A{
+		bson.M{"last_used_at": bson.M{"$exists": false}},
+		bson.M{"last_used_at": bson.M{"$lt": usedAt}},
+	}}
+	_, err = r.db.Collection(r.collection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	if err != nil {
+		return databaseError(err, "Error updating API key")
+	}
+
+	return nil
+}
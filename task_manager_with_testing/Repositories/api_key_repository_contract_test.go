@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyRepositoryContractSuite checks the behaviour every APIKeyRepository backend must share
+type APIKeyRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() APIKeyRepository
+	repo          APIKeyRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *APIKeyRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestAPIKeyRepositoryContract_Memory runs the contract against the in-memory backend
+func TestAPIKeyRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &APIKeyRepositoryContractSuite{newRepository: NewAPIKeyMemoryRepository})
+}
+
+// TestAPIKeyRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestAPIKeyRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &APIKeyRepositoryContractSuite{newRepository: func() APIKeyRepository {
+		db.Collection("api_keys").Drop(context.Background())
+		return NewAPIKeyRepository(db, "api_keys")
+	}})
+}
+
+func (suite *APIKeyRepositoryContractSuite) createKey(userID, name, hash string) domain.APIKey {
+	key, err := suite.repo.CreateKey(context.Background(), domain.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Hint:      "tm_abcdef",
+		KeyHash:   hash,
+		Scopes:    []string{domain.PermissionTaskRead},
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(key.ID)
+	return key
+}
+
+func (suite *APIKeyRepositoryContractSuite) TestCreateAndFind() {
+	key := suite.createKey("user-1", "CI", "hash-1")
+
+	found, err := suite.repo.FindByHash(context.Background(), "hash-1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), key, found)
+
+	_, err = suite.repo.FindByHash(context.Background(), "hash-2")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *APIKeyRepositoryContractSuite) TestGetKeys_OnlyTheUsersKeysOldestFirst() {
+	first := suite.createKey("user-1", "CI", "hash-1")
+	suite.createKey("user-2", "Other", "hash-2")
+	second := suite.createKey("user-1", "Backup", "hash-3")
+
+	keys, err := suite.repo.GetKeys(context.Background(), "user-1")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []domain.APIKey{first, second}, keys)
+
+	keys, err = suite.repo.GetKeys(context.Background(), "user-3")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), keys)
+}
+
+func (suite *APIKeyRepositoryContractSuite) TestDeleteKey() {
+	key := suite.createKey("user-1", "CI", "hash-1")
+
+	err := suite.repo.DeleteKey(context.Background(), "user-2", key.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	assert.NoError(suite.T(), suite.repo.DeleteKey(context.Background(), "user-1", key.ID))
+
+	_, err = suite.repo.FindByHash(context.Background(), "hash-1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteKey(context.Background(), "user-1", key.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteKey(context.Background(), "user-1", "not-an-id")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *APIKeyRepositoryContractSuite) TestTouchKey_KeepsTheLatestUse() {
+	key := suite.createKey("user-1", "CI", "hash-1")
+	later := time.Now().UTC().Truncate(time.Millisecond)
+	earlier := later.Add(-time.Minute)
+
+	suite.Require().NoError(suite.repo.TouchKey(context.Background(), key.ID, later))
+	suite.Require().NoError(suite.repo.TouchKey(context.Background(), key.ID, earlier))
+
+	found, err := suite.repo.FindByHash(context.Background(), "hash-1")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(found.LastUsedAt)
+	assert.True(suite.T(), later.Equal(*found.LastUsedAt))
+
+	assert.NoError(suite.T(), suite.repo.TouchKey(context.Background(), primitive.NewObjectID().Hex(), later))
+}
+
+func (suite *APIKeyRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.FindByHash(ctx, "hash-1")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auditMemoryRepository keeps the audit log in memory, oldest entry first
+type auditMemoryRepository struct {
+	mu      sync.RWMutex
+	entries []domain.AuditEntry
+}
+
+// NewAuditMemoryRepository creates a new in-memory audit repository
+func NewAuditMemoryRepository() AuditRepository {
+	return &auditMemoryRepository{entries: []domain.AuditEntry{}}
+}
+
+// Append chains the entry onto the newest one and stores it
+func (r *auditMemoryRepository) Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.AuditEntry{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var previous *domain.AuditEntry
+	if len(r.entries) > 0 {
+		previous = &r.entries[len(r.entries)-1]
+	}
+
+	chained := entry.Chain(previous)
+	chained.ID = primitive.NewObjectID().Hex()
+	r.entries = append(r.entries, chained)
+
+	return chained, nil
+}
+
+// GetEntries retrieves one page of audit entries matching the query, newest first
+func (r *auditMemoryRepository) GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.AuditPage{}, contextError(err)
+	}
+
+	var before int64
+	if query.Cursor != "" {
+		var err error
+		if before, err = decodeAuditCursor(query.Cursor); err != nil {
+			return domain.AuditPage{}, err
+		}
+	}
+
+	limit := auditPageSize(query)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []domain.AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0 && len(entries) <= limit; i-- {
+		entry := r.entries[i]
+		if before != 0 && entry.Sequence >= before {
+			continue
+		}
+
+		if matchesAuditQuery(entry, query) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return newAuditPage(entries, limit), nil
+}
+
+// GetChain retrieves every audit entry, oldest first
+func (r *auditMemoryRepository) GetChain(ctx context.Context) ([]domain.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]domain.AuditEntry{}, r.entries...), nil
+}
+
+func matchesAuditQuery(entry domain.AuditEntry, query domain.AuditQuery) bool {
+	if query.Actor != "" && entry.Actor != query.Actor {
+		return false
+	}
+
+	if query.Action != "" && entry.Action != query.Action {
+		return false
+	}
+
+	if query.TargetType != "" && entry.TargetType != query.TargetType {
+		return false
+	}
+
+	if query.TargetID != "" && entry.TargetID != query.TargetID {
+		return false
+	}
+
+	if !query.Since.IsZero() && entry.Timestamp.Before(query.Since) {
+		return false
+	}
+
+	return query.Until.IsZero() || entry.Timestamp.Before(query.Until)
+}
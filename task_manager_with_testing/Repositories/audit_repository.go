@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxAuditAppendAttempts bounds the retries when concurrent appends race for the same sequence
+const maxAuditAppendAttempts = 5
+
+// AuditRepository is an append-only store of audit entries; there is no way to
+// update or delete an entry
+type AuditRepository interface {
+	Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error)
+	GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error)
+	GetChain(ctx context.Context) ([]domain.AuditEntry, error)
+}
+
+// auditRepository struct
+type auditRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(database *mongo.Database, collection string) AuditRepository {
+	return &auditRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the unique sequence index that keeps the chain linear
+func (r *auditRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "sequence", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return databaseError(err, "Error creating audit index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// Append chains the entry onto the newest one and stores it
+func (r *auditRepository) Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.AuditEntry{}, err
+	}
+
+	collection := r.db.Collection(r.collection)
+	for attempt := 0; attempt < maxAuditAppendAttempts; attempt++ {
+		var previous *domain.AuditEntry
+
+		var last domain.AuditEntry
+		err := collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"sequence": -1})).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return domain.AuditEntry{}, databaseError(err, "Error appending audit entry")
+		}
+		if err == nil {
+			previous = &last
+		}
+
+		chained := entry.Chain(previous)
+		chained.ID = ""
+
+		result, err := collection.InsertOne(ctx, chained)
+		if mongo.IsDuplicateKeyError(err) {
+			// another entry took this sequence number first
+			continue
+		}
+		if err != nil {
+			return domain.AuditEntry{}, databaseError(err, "Error appending audit entry")
+		}
+
+		if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+			chained.ID = objId.Hex()
+		}
+
+		return chained, nil
+	}
+
+	return domain.AuditEntry{}, &domain.InternalServerError{Message: "Error appending audit entry"}
+}
+
+// GetEntries retrieves one page of audit entries matching the query, newest first
+func (r *auditRepository) GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	filter := bson.M{}
+	if query.Actor != "" {
+		filter["actor"] = query.Actor
+	}
+
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+
+	if query.TargetType != "" {
+		filter["target_type"] = query.TargetType
+	}
+
+	if query.TargetID != "" {
+		filter["target_id"] = query.TargetID
+	}
+
+	timestamp := bson.M{}
+	if !query.Since.IsZero() {
+		timestamp["$gte"] = query.Since
+	}
+	if !query.Until.IsZero() {
+		timestamp["$lt"] = query.Until
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	if query.Cursor != "" {
+		before, err := decodeAuditCursor(query.Cursor)
+		if err != nil {
+			return domain.AuditPage{}, err
+		}
+
+		filter["sequence"] = bson.M{"$lt": before}
+	}
+
+	limit := auditPageSize(query)
+
+	// fetch one extra entry to find out whether there is a next page
+	opts := options.Find().SetSort(bson.M{"sequence": -1}).SetLimit(int64(limit + 1))
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, opts)
+	if err != nil {
+		return domain.AuditPage{}, databaseError(err, "Error retrieving audit entries")
+	}
+
+	defer cursor.Close(ctx)
+
+	entries := []domain.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return domain.AuditPage{}, databaseError(err, "Error retrieving audit entries")
+	}
+
+	return newAuditPage(entries, limit), nil
+}
+
+// GetChain retrieves every audit entry, oldest first
+func (r *auditRepository) GetChain(ctx context.Context) ([]domain.AuditEntry, error) {
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving audit entries")
+	}
+
+	defer cursor.Close(ctx)
+
+	entries := []domain.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, databaseError(err, "Error retrieving audit entries")
+	}
+
+	return entries, nil
+}
+
+// decodeAuditCursor reads the sequence number the next page starts before
+func decodeAuditCursor(cursor string) (int64, error) {
+	sequence, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || sequence < 1 {
+		return 0, &domain.BadRequestError{Message: "Invalid cursor"}
+	}
+
+	return sequence, nil
+}
+
+func auditPageSize(query domain.AuditQuery) int {
+	if query.Limit <= 0 {
+		return domain.DefaultAuditPageSize
+	}
+
+	return query.Limit
+}
+
+// newAuditPage trims the extra entry fetched past the limit and turns it into a cursor
+func newAuditPage(entries []domain.AuditEntry, limit int) domain.AuditPage {
+	page := domain.AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = strconv.FormatInt(page.Entries[limit-1].Sequence, 10)
+	}
+
+	return page
+}
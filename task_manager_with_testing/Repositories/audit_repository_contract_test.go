@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// AuditRepositoryContractSuite checks the behaviour every AuditRepository backend must share
+type AuditRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() AuditRepository
+	repo          AuditRepository
+}
+
+// SetupTest starts every test with an empty audit log
+func (suite *AuditRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestAuditRepositoryContract_Memory runs the contract against the in-memory backend
+func TestAuditRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &AuditRepositoryContractSuite{newRepository: NewAuditMemoryRepository})
+}
+
+// TestAuditRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestAuditRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &AuditRepositoryContractSuite{newRepository: func() AuditRepository {
+		db.Collection("audit_log").Drop(context.Background())
+		return NewAuditRepository(db, "audit_log")
+	}})
+}
+
+func (suite *AuditRepositoryContractSuite) appendEntry(entry domain.AuditEntry) domain.AuditEntry {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	appended, err := suite.repo.Append(context.Background(), entry)
+	suite.Require().NoError(err)
+
+	return appended
+}
+
+func (suite *AuditRepositoryContractSuite) TestAppend_ChainsEntries() {
+	first := suite.appendEntry(domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskCreate, TargetType: "task", TargetID: "1"})
+	second := suite.appendEntry(domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskDelete, TargetType: "task", TargetID: "1"})
+
+	assert.NotEmpty(suite.T(), first.ID)
+	assert.Equal(suite.T(), int64(1), first.Sequence)
+	assert.Empty(suite.T(), first.PrevHash)
+	assert.Equal(suite.T(), int64(2), second.Sequence)
+	assert.Equal(suite.T(), first.Hash, second.PrevHash)
+
+	chain, err := suite.repo.GetChain(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), chain, 2)
+	assert.True(suite.T(), domain.VerifyAuditChain(chain).Valid)
+}
+
+func (suite *AuditRepositoryContractSuite) TestAppend_KeepsChanges() {
+	suite.appendEntry(domain.AuditEntry{
+		Actor:      "admin",
+		Action:     domain.AuditUserPromote,
+		TargetType: "user",
+		TargetID:   "testuser",
+		RequestID:  "request-id",
+		Changes:    []domain.AuditChange{{Field: "role", Before: "user", After: "admin"}},
+	})
+
+	page, err := suite.repo.GetEntries(context.Background(), domain.AuditQuery{})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Equal(suite.T(), "request-id", page.Entries[0].RequestID)
+	assert.Equal(suite.T(), []domain.AuditChange{{Field: "role", Before: "user", After: "admin"}}, page.Entries[0].Changes)
+	assert.Equal(suite.T(), page.Entries[0].Hash, page.Entries[0].ComputeHash())
+}
+
+func (suite *AuditRepositoryContractSuite) TestGetEntries_Empty() {
+	page, err := suite.repo.GetEntries(context.Background(), domain.AuditQuery{})
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), page.Entries)
+	assert.Empty(suite.T(), page.Entries)
+	assert.Empty(suite.T(), page.NextCursor)
+}
+
+func (suite *AuditRepositoryContractSuite) TestGetEntries_Filters() {
+	now := time.Now().Truncate(time.Millisecond)
+	suite.appendEntry(domain.AuditEntry{Actor: "alice", Action: domain.AuditTaskCreate, TargetType: "task", TargetID: "1", Timestamp: now.Add(-2 * time.Hour)})
+	suite.appendEntry(domain.AuditEntry{Actor: "alice", Action: domain.AuditTaskUpdate, TargetType: "task", TargetID: "1", Timestamp: now.Add(-time.Hour)})
+	suite.appendEntry(domain.AuditEntry{Actor: "bob", Action: domain.AuditTaskUpdate, TargetType: "task", TargetID: "2", Timestamp: now})
+	suite.appendEntry(domain.AuditEntry{Actor: "admin", Action: domain.AuditUserPromote, TargetType: "user", TargetID: "bob", Timestamp: now})
+
+	page, err := suite.repo.GetEntries(context.Background(), domain.AuditQuery{Actor: "alice"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Entries, 2)
+
+	page, err = suite.repo.GetEntries(context.Background(), domain.AuditQuery{Action: domain.AuditTaskUpdate, TargetType: "task", TargetID: "2"})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Equal(suite.T(), "bob", page.Entries[0].Actor)
+
+	page, err = suite.repo.GetEntries(context.Background(), domain.AuditQuery{Since: now.Add(-90 * time.Minute), Until: now})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Equal(suite.T(), domain.AuditTaskUpdate, page.Entries[0].Action)
+	assert.Equal(suite.T(), "alice", page.Entries[0].Actor)
+}
+
+func (suite *AuditRepositoryContractSuite) TestGetEntries_Pagination() {
+	for i := 0; i < 5; i++ {
+		suite.appendEntry(domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskCreate, TargetType: "task", TargetID: fmt.Sprint(i)})
+	}
+
+	query := domain.AuditQuery{Limit: 2}
+	var sequences []int64
+	for pages := 0; ; pages++ {
+		suite.Require().Less(pages, 5)
+
+		page, err := suite.repo.GetEntries(context.Background(), query)
+		suite.Require().NoError(err)
+		for _, entry := range page.Entries {
+			sequences = append(sequences, entry.Sequence)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	assert.Equal(suite.T(), []int64{5, 4, 3, 2, 1}, sequences)
+}
+
+func (suite *AuditRepositoryContractSuite) TestGetEntries_InvalidCursor() {
+	_, err := suite.repo.GetEntries(context.Background(), domain.AuditQuery{Cursor: "abc"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *AuditRepositoryContractSuite) TestConcurrentAppends() {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := suite.repo.Append(context.Background(), domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskCreate, TargetType: "task", TargetID: fmt.Sprint(i), Timestamp: time.Now()})
+			assert.NoError(suite.T(), err)
+		}(i)
+	}
+	wg.Wait()
+
+	chain, err := suite.repo.GetChain(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), domain.VerifyAuditChain(chain).Valid)
+}
+
+func (suite *AuditRepositoryContractSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := suite.repo.Append(ctx, domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskCreate, Timestamp: time.Now()})
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+
+	_, err = suite.repo.GetEntries(ctx, domain.AuditQuery{})
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
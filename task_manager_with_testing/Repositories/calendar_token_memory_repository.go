@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+)
+
+// calendarTokenMemoryRepository keeps calendar tokens in memory, keyed by user ID
+type calendarTokenMemoryRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]domain.CalendarToken
+}
+
+// NewCalendarTokenMemoryRepository creates a new in-memory calendar token repository
+func NewCalendarTokenMemoryRepository() CalendarTokenRepository {
+	return &calendarTokenMemoryRepository{tokens: make(map[string]domain.CalendarToken)}
+}
+
+// SaveToken stores the user's token, replacing the one they had
+func (r *calendarTokenMemoryRepository) SaveToken(ctx context.Context, token domain.CalendarToken) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for userID, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash && userID != token.UserID {
+			return &domain.InternalServerError{Message: "Error saving calendar token"}
+		}
+	}
+
+	r.tokens[token.UserID] = token
+	return nil
+}
+
+// FindByHash retrieves the token with the given hash
+func (r *calendarTokenMemoryRepository) FindByHash(ctx context.Context, tokenHash string) (domain.CalendarToken, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.CalendarToken{}, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return domain.CalendarToken{}, &domain.NotFoundError{Message: "Calendar token not found"}
+}
+
+// DeleteToken removes the user's token
+func (r *calendarTokenMemoryRepository) DeleteToken(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[userID]; !ok {
+		return &domain.NotFoundError{Message: "Calendar token not found"}
+	}
+
+	delete(r.tokens, userID)
+	return nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CalendarTokenRepository stores the token each user's calendar feed is read with
+type CalendarTokenRepository interface {
+	// SaveToken stores the user's token, replacing the one they had
+	SaveToken(ctx context.Context, token domain.CalendarToken) error
+	FindByHash(ctx context.Context, tokenHash string) (domain.CalendarToken, error)
+	DeleteToken(ctx context.Context, userID string) error
+}
+
+// calendarTokenRepository struct
+type calendarTokenRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewCalendarTokenRepository creates a new calendar token repository
+func NewCalendarTokenRepository(database *mongo.Database, collection string) CalendarTokenRepository {
+	return &calendarTokenRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the unique index tokens are looked up by
+func (r *calendarTokenRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return databaseError(err, "Error creating calendar token index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// SaveToken stores the user's token, replacing the one they had
+func (r *calendarTokenRepository) SaveToken(ctx context.Context, token domain.CalendarToken) error {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": token.UserID}
+	_, err := r.db.Collection(r.collection).ReplaceOne(ctx, filter, token, options.Replace().SetUpsert(true))
+	if err != nil {
+		return databaseError(err, "Error saving calendar token")
+	}
+
+	return nil
+}
+
+// FindByHash retrieves the token with the given hash
+func (r *calendarTokenRepository) FindByHash(ctx context.Context, tokenHash string) (domain.CalendarToken, error) {
+	var token domain.CalendarToken
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.CalendarToken{}, &domain.NotFoundError{Message: "Calendar token not found"}
+	}
+
+	if err != nil {
+		return domain.CalendarToken{}, databaseError(err, "Error retrieving calendar token")
+	}
+
+	return token, nil
+}
+
+// DeleteToken removes the user's token
+func (r *calendarTokenRepository) DeleteToken(ctx context.Context, userID string) error {
+	result, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return databaseError(err, "Error deleting calendar token")
+	}
+
+	if result.DeletedCount == 0 {
+		return &domain.NotFoundError{Message: "Calendar token not found"}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// CalendarTokenRepositoryContractSuite checks the behaviour every CalendarTokenRepository backend must share
+type CalendarTokenRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() CalendarTokenRepository
+	repo          CalendarTokenRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *CalendarTokenRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestCalendarTokenRepositoryContract_Memory runs the contract against the in-memory backend
+func TestCalendarTokenRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &CalendarTokenRepositoryContractSuite{newRepository: NewCalendarTokenMemoryRepository})
+}
+
+// TestCalendarTokenRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestCalendarTokenRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &CalendarTokenRepositoryContractSuite{newRepository: func() CalendarTokenRepository {
+		db.Collection("calendar_tokens").Drop(context.Background())
+		return NewCalendarTokenRepository(db, "calendar_tokens")
+	}})
+}
+
+func calendarToken(userID, hash string) domain.CalendarToken {
+	return domain.CalendarToken{UserID: userID, TokenHash: hash, CreatedAt: time.Now().UTC().Truncate(time.Millisecond)}
+}
+
+func (suite *CalendarTokenRepositoryContractSuite) TestSaveAndFind() {
+	token := calendarToken("user-1", "hash-1")
+	suite.Require().NoError(suite.repo.SaveToken(context.Background(), token))
+
+	found, err := suite.repo.FindByHash(context.Background(), "hash-1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), token, found)
+
+	_, err = suite.repo.FindByHash(context.Background(), "hash-2")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CalendarTokenRepositoryContractSuite) TestSaveToken_ReplacesTheUsersToken() {
+	suite.Require().NoError(suite.repo.SaveToken(context.Background(), calendarToken("user-1", "old")))
+	suite.Require().NoError(suite.repo.SaveToken(context.Background(), calendarToken("user-1", "new")))
+
+	_, err := suite.repo.FindByHash(context.Background(), "old")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	found, err := suite.repo.FindByHash(context.Background(), "new")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "user-1", found.UserID)
+}
+
+func (suite *CalendarTokenRepositoryContractSuite) TestDeleteToken() {
+	suite.Require().NoError(suite.repo.SaveToken(context.Background(), calendarToken("user-1", "hash-1")))
+	suite.Require().NoError(suite.repo.SaveToken(context.Background(), calendarToken("user-2", "hash-2")))
+
+	assert.NoError(suite.T(), suite.repo.DeleteToken(context.Background(), "user-1"))
+
+	_, err := suite.repo.FindByHash(context.Background(), "hash-1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.repo.FindByHash(context.Background(), "hash-2")
+	assert.NoError(suite.T(), err)
+
+	err = suite.repo.DeleteToken(context.Background(), "user-1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CalendarTokenRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.FindByHash(ctx, "hash-1")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
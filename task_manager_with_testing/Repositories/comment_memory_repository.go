@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// commentMemoryRepository keeps comments in memory, oldest first
+type commentMemoryRepository struct {
+	mu       sync.RWMutex
+	comments []domain.Comment
+}
+
+// NewCommentMemoryRepository creates a new in-memory comment repository
+func NewCommentMemoryRepository() CommentRepository {
+	return &commentMemoryRepository{comments: []domain.Comment{}}
+}
+
+// CreateComment stores a new comment and returns it with its assigned ID
+func (r *commentMemoryRepository) CreateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Comment{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment.ID = primitive.NewObjectID().Hex()
+	comment.Replies = nil
+	r.comments = append(r.comments, comment)
+
+	return comment, nil
+}
+
+// GetComment retrieves a comment by ID
+func (r *commentMemoryRepository) GetComment(ctx context.Context, id string) (domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Comment{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.Comment{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, comment := range r.comments {
+		if comment.ID == id {
+			return comment, nil
+		}
+	}
+
+	return domain.Comment{}, &domain.NotFoundError{Message: "Comment not found"}
+}
+
+// GetComments retrieves every comment on a task, oldest first
+func (r *commentMemoryRepository) GetComments(ctx context.Context, taskID string) ([]domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comments := []domain.Comment{}
+	for _, comment := range r.comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+
+	return comments, nil
+}
+
+// UpdateComment stores the body and the edited and deleted markers of a comment
+func (r *commentMemoryRepository) UpdateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Comment{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(comment.ID) {
+		return domain.Comment{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.comments {
+		if existing.ID == comment.ID {
+			existing.Body = comment.Body
+			existing.Edited, existing.EditedAt = comment.Edited, comment.EditedAt
+			existing.Deleted, existing.DeletedAt = comment.Deleted, comment.DeletedAt
+			r.comments[i] = existing
+			return existing, nil
+		}
+	}
+
+	return domain.Comment{}, &domain.NotFoundError{Message: "Comment not found"}
+}
+
+// DeleteComments removes every comment on a task
+func (r *commentMemoryRepository) DeleteComments(ctx context.Context, taskID string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.comments[:0]
+	for _, comment := range r.comments {
+		if comment.TaskID != taskID {
+			kept = append(kept, comment)
+		}
+	}
+	r.comments = kept
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CommentRepository stores the comments on tasks
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error)
+	GetComment(ctx context.Context, id string) (domain.Comment, error)
+	// GetComments retrieves every comment on a task, deleted ones included, oldest first
+	GetComments(ctx context.Context, taskID string) ([]domain.Comment, error)
+	// UpdateComment stores the body and the edited and deleted markers of a comment
+	UpdateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error)
+	// DeleteComments removes every comment on a task for good
+	DeleteComments(ctx context.Context, taskID string) error
+}
+
+// commentRepository struct
+type commentRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewCommentRepository creates a new comment repository
+func NewCommentRepository(database *mongo.Database, collection string) CommentRepository {
+	return &commentRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the index that lists a task's comments in order
+func (r *commentRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		return databaseError(err, "Error creating comment index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// CreateComment stores a new comment and returns it with its assigned ID
+func (r *commentRepository) CreateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.Comment{}, err
+	}
+
+	comment.ID = ""
+	comment.Replies = nil
+	result, err := r.db.Collection(r.collection).InsertOne(ctx, comment)
+	if err != nil {
+		return domain.Comment{}, databaseError(err, "Error creating comment")
+	}
+
+	if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+		comment.ID = objId.Hex()
+	}
+
+	return comment, nil
+}
+
+// GetComment retrieves a comment by ID
+func (r *commentRepository) GetComment(ctx context.Context, id string) (domain.Comment, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.Comment{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	var comment domain.Comment
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": objId}).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return domain.Comment{}, &domain.NotFoundError{Message: "Comment not found"}
+	}
+
+	if err != nil {
+		return domain.Comment{}, databaseError(err, "Error retrieving comment")
+	}
+
+	return comment, nil
+}
+
+// GetComments retrieves every comment on a task, oldest first
+func (r *commentRepository) GetComments(ctx context.Context, taskID string) ([]domain.Comment, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{"task_id": taskID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving comments")
+	}
+
+	defer cursor.Close(ctx)
+
+	comments := []domain.Comment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, databaseError(err, "Error retrieving comments")
+	}
+
+	return comments, nil
+}
+
+// UpdateComment stores the body and the edited and deleted markers of a comment
+func (r *commentRepository) UpdateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	objId, err := primitive.ObjectIDFromHex(comment.ID)
+	if err != nil {
+		return domain.Comment{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"body":       comment.Body,
+		"edited":     comment.Edited,
+		"edited_at":  comment.EditedAt,
+		"deleted":    comment.Deleted,
+		"deleted_at": comment.DeletedAt,
+	}}
+
+	var updated domain.Comment
+	err = r.db.Collection(r.collection).FindOneAndUpdate(ctx, bson.M{"_id": objId}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return domain.Comment{}, &domain.NotFoundError{Message: "Comment not found"}
+	}
+
+	if err != nil {
+		return domain.Comment{}, databaseError(err, "Error updating comment")
+	}
+
+	return updated, nil
+}
+
+// DeleteComments removes every comment on a task
+func (r *commentRepository) DeleteComments(ctx context.Context, taskID string) error {
+	_, err := r.db.Collection(r.collection).DeleteMany(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		return databaseError(err, "Error deleting comments")
+	}
+
+	return nil
+}
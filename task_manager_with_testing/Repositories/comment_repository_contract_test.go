@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentRepositoryContractSuite checks the behaviour every CommentRepository backend must share
+type CommentRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() CommentRepository
+	repo          CommentRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *CommentRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestCommentRepositoryContract_Memory runs the contract against the in-memory backend
+func TestCommentRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &CommentRepositoryContractSuite{newRepository: NewCommentMemoryRepository})
+}
+
+// TestCommentRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestCommentRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &CommentRepositoryContractSuite{newRepository: func() CommentRepository {
+		db.Collection("comments").Drop(context.Background())
+		return NewCommentRepository(db, "comments")
+	}})
+}
+
+func (suite *CommentRepositoryContractSuite) createComment(taskID, parentID, body string) domain.Comment {
+	created, err := suite.repo.CreateComment(context.Background(), domain.Comment{
+		TaskID:    taskID,
+		ParentID:  parentID,
+		AuthorID:  "author-1",
+		Author:    "alice",
+		Body:      body,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	suite.Require().NoError(err)
+	return created
+}
+
+func (suite *CommentRepositoryContractSuite) TestCreateAndGetComment() {
+	created := suite.createComment("task-1", "", "First")
+	assert.NotEmpty(suite.T(), created.ID)
+
+	found, err := suite.repo.GetComment(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created, found)
+
+	_, err = suite.repo.GetComment(context.Background(), primitive.NewObjectID().Hex())
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.repo.GetComment(context.Background(), "not-an-id")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *CommentRepositoryContractSuite) TestGetComments_OldestFirstPerTask() {
+	first := suite.createComment("task-1", "", "First")
+	suite.createComment("task-2", "", "Elsewhere")
+	reply := suite.createComment("task-1", first.ID, "Reply")
+
+	comments, err := suite.repo.GetComments(context.Background(), "task-1")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []domain.Comment{first, reply}, comments)
+
+	comments, err = suite.repo.GetComments(context.Background(), "task-3")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), comments)
+}
+
+func (suite *CommentRepositoryContractSuite) TestUpdateComment() {
+	created := suite.createComment("task-1", "", "First")
+	at := time.Now().UTC().Truncate(time.Millisecond)
+
+	edited := created
+	edited.Body = "Edited"
+	edited.Edited, edited.EditedAt = true, &at
+	edited.TaskID = "task-2"
+
+	updated, err := suite.repo.UpdateComment(context.Background(), edited)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "Edited", updated.Body)
+	assert.True(suite.T(), updated.Edited)
+	assert.True(suite.T(), at.Equal(*updated.EditedAt))
+	assert.Equal(suite.T(), "task-1", updated.TaskID, "only the body and markers change")
+
+	deleted := updated
+	deleted.Deleted, deleted.DeletedAt = true, &at
+	_, err = suite.repo.UpdateComment(context.Background(), deleted)
+	suite.Require().NoError(err)
+
+	found, err := suite.repo.GetComment(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), found.Deleted)
+	assert.Equal(suite.T(), "Edited", found.Body)
+
+	_, err = suite.repo.UpdateComment(context.Background(), domain.Comment{ID: primitive.NewObjectID().Hex()})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CommentRepositoryContractSuite) TestDeleteComments() {
+	suite.createComment("task-1", "", "First")
+	suite.createComment("task-1", "", "Second")
+	kept := suite.createComment("task-2", "", "Elsewhere")
+
+	suite.Require().NoError(suite.repo.DeleteComments(context.Background(), "task-1"))
+	suite.Require().NoError(suite.repo.DeleteComments(context.Background(), "task-3"))
+
+	comments, err := suite.repo.GetComments(context.Background(), "task-1")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), comments)
+
+	comments, err = suite.repo.GetComments(context.Background(), "task-2")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []domain.Comment{kept}, comments)
+}
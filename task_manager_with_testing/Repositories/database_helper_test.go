@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// connectTestDatabase connects to the MongoDB server used by the integration tests.
+// The server defaults to localhost and can be changed with MONGODB_TEST_URI. Tests are
+// skipped when it cannot be reached, unless MONGODB_TEST_URI is set.
+func connectTestDatabase(t *testing.T) *mongo.Client {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+	if err != nil {
+		skipWithoutMongo(t, "cannot connect to MongoDB: %v", err)
+	}
+
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		skipWithoutMongo(t, "no MongoDB server at %s: %v", uri, err)
+	}
+
+	return client
+}
+
+// skipWithoutMongo skips a test that needs something the MongoDB server does not offer.
+// When MONGODB_TEST_URI is set the server is expected to be there, as it is in CI, so
+// the test fails instead of passing silently.
+func skipWithoutMongo(t testing.TB, format string, args ...any) {
+	t.Helper()
+	if os.Getenv("MONGODB_TEST_URI") != "" {
+		t.Fatalf(format, args...)
+	}
+	t.Skipf(format, args...)
+}
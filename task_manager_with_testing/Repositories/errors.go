@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// contextError reports a cancelled or expired request context as a timeout
+func contextError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return &domain.TimeoutError{Message: "Request was cancelled"}
+	}
+
+	return &domain.TimeoutError{Message: "Request timed out"}
+}
+
+// databaseError converts a database driver error into a domain error
+func databaseError(err error, message string) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || mongo.IsTimeout(err) {
+		return contextError(err)
+	}
+
+	return &domain.InternalServerError{Message: message}
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+)
+
+// oidcLoginMemoryRepository keeps single sign-on logins in memory, keyed by state hash
+type oidcLoginMemoryRepository struct {
+	mu     sync.Mutex
+	logins map[string]domain.OIDCLogin
+}
+
+// NewOIDCLoginMemoryRepository creates a new in-memory single sign-on login repository
+func NewOIDCLoginMemoryRepository() OIDCLoginRepository {
+	return &oidcLoginMemoryRepository{logins: make(map[string]domain.OIDCLogin)}
+}
+
+// SaveLogin stores a login in progress, dropping logins that have expired
+func (r *oidcLoginMemoryRepository) SaveLogin(ctx context.Context, login domain.OIDCLogin) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for stateHash, existing := range r.logins {
+		if !existing.ExpiresAt.After(now) {
+			delete(r.logins, stateHash)
+		}
+	}
+
+	if _, ok := r.logins[login.StateHash]; ok {
+		return &domain.InternalServerError{Message: "Error saving login"}
+	}
+
+	r.logins[login.StateHash] = login
+
+	return nil
+}
+
+// TakeLogin removes and returns the unexpired login with the given state hash
+func (r *oidcLoginMemoryRepository) TakeLogin(ctx context.Context, stateHash string) (domain.OIDCLogin, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.OIDCLogin{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	login, ok := r.logins[stateHash]
+	delete(r.logins, stateHash)
+
+	if !ok || !login.ExpiresAt.After(time.Now()) {
+		return domain.OIDCLogin{}, &domain.NotFoundError{Message: "Login not found"}
+	}
+
+	return login, nil
+}
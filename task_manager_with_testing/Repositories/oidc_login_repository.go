@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OIDCLoginRepository keeps single sign-on logins between sending the browser to the
+// identity provider and its return
+type OIDCLoginRepository interface {
+	SaveLogin(ctx context.Context, login domain.OIDCLogin) error
+	// TakeLogin removes and returns the unexpired login with the given state hash, so
+	// each login can be completed once
+	TakeLogin(ctx context.Context, stateHash string) (domain.OIDCLogin, error)
+}
+
+// oidcLoginRepository struct
+type oidcLoginRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewOIDCLoginRepository creates a new single sign-on login repository
+func NewOIDCLoginRepository(database *mongo.Database, collection string) OIDCLoginRepository {
+	return &oidcLoginRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the TTL index that clears out logins never completed
+func (r *oidcLoginRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return databaseError(err, "Error creating login index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// SaveLogin stores a login in progress
+func (r *oidcLoginRepository) SaveLogin(ctx context.Context, login domain.OIDCLogin) error {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, login)
+	if err != nil {
+		return databaseError(err, "Error saving login")
+	}
+
+	return nil
+}
+
+// TakeLogin removes and returns the login; the TTL index only clears expired logins
+// once a minute, so expiry is checked here as well
+func (r *oidcLoginRepository) TakeLogin(ctx context.Context, stateHash string) (domain.OIDCLogin, error) {
+	var login domain.OIDCLogin
+	filter := bson.M{"_id": stateHash}
+	err := r.db.Collection(r.collection).FindOneAndDelete(ctx, filter).Decode(&login)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.OIDCLogin{}, &domain.NotFoundError{Message: "Login not found"}
+	}
+
+	if err != nil {
+		return domain.OIDCLogin{}, databaseError(err, "Error retrieving login")
+	}
+
+	if !login.ExpiresAt.After(time.Now()) {
+		return domain.OIDCLogin{}, &domain.NotFoundError{Message: "Login not found"}
+	}
+
+	return login, nil
+}
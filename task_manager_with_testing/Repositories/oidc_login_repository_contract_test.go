@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// OIDCLoginRepositoryContractSuite checks the behaviour every OIDCLoginRepository backend must share
+type OIDCLoginRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() OIDCLoginRepository
+	repo          OIDCLoginRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *OIDCLoginRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestOIDCLoginRepositoryContract_Memory runs the contract against the in-memory backend
+func TestOIDCLoginRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &OIDCLoginRepositoryContractSuite{newRepository: NewOIDCLoginMemoryRepository})
+}
+
+// TestOIDCLoginRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestOIDCLoginRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &OIDCLoginRepositoryContractSuite{newRepository: func() OIDCLoginRepository {
+		db.Collection("oidc_logins").Drop(context.Background())
+		return NewOIDCLoginRepository(db, "oidc_logins")
+	}})
+}
+
+func (suite *OIDCLoginRepositoryContractSuite) TestTakeLogin_OnlyOnce() {
+	login := domain.OIDCLogin{
+		StateHash:    "state-hash",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+		ExpiresAt:    time.Now().Add(10 * time.Minute).UTC().Truncate(time.Millisecond),
+	}
+	suite.Require().NoError(suite.repo.SaveLogin(context.Background(), login))
+
+	_, err := suite.repo.TakeLogin(context.Background(), "other-hash")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	taken, err := suite.repo.TakeLogin(context.Background(), "state-hash")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), login, taken)
+
+	_, err = suite.repo.TakeLogin(context.Background(), "state-hash")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *OIDCLoginRepositoryContractSuite) TestTakeLogin_Expired() {
+	suite.Require().NoError(suite.repo.SaveLogin(context.Background(), domain.OIDCLogin{
+		StateHash: "state-hash",
+		ExpiresAt: time.Now().Add(-time.Second),
+	}))
+
+	_, err := suite.repo.TakeLogin(context.Background(), "state-hash")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *OIDCLoginRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.TakeLogin(ctx, "state-hash")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
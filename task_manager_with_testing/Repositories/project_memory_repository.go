@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// projectMemoryRepository keeps projects in memory, keyed by ID
+type projectMemoryRepository struct {
+	mu       sync.RWMutex
+	projects map[string]domain.Project
+}
+
+// NewProjectMemoryRepository creates a new in-memory project repository
+func NewProjectMemoryRepository() ProjectRepository {
+	return &projectMemoryRepository{projects: make(map[string]domain.Project)}
+}
+
+// CreateProject stores a new project and returns it with its assigned ID
+func (r *projectMemoryRepository) CreateProject(ctx context.Context, project domain.Project) (domain.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Project{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if project.PersonalOf != "" {
+		if _, ok := r.personalProject(project.PersonalOf); ok {
+			return domain.Project{}, &domain.BadRequestError{Message: "User already has a personal project"}
+		}
+	}
+
+	return r.createProject(project), nil
+}
+
+// EnsurePersonalProject returns the user's personal project, creating it if they have none
+func (r *projectMemoryRepository) EnsurePersonalProject(ctx context.Context, project domain.Project) (domain.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Project{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if personal, ok := r.personalProject(project.PersonalOf); ok {
+		return personal, nil
+	}
+
+	return r.createProject(project), nil
+}
+
+// GetProject retrieves a project by ID
+func (r *projectMemoryRepository) GetProject(ctx context.Context, id string) (domain.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Project{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.Project{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	project, ok := r.projects[id]
+	if !ok {
+		return domain.Project{}, &domain.NotFoundError{Message: "Project not found"}
+	}
+
+	return cloneProject(project), nil
+}
+
+// GetProjects retrieves the user's projects, or every project, ordered by name
+func (r *projectMemoryRepository) GetProjects(ctx context.Context, userID string) ([]domain.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	projects := []domain.Project{}
+	for _, project := range r.projects {
+		if userID == "" || project.RoleOf(userID) != "" {
+			projects = append(projects, cloneProject(project))
+		}
+	}
+
+	slices.SortFunc(projects, func(a, b domain.Project) int {
+		if order := strings.Compare(a.Name, b.Name); order != 0 {
+			return order
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return projects, nil
+}
+
+// UpdateProject updates a project if it is still at the given version
+func (r *projectMemoryRepository) UpdateProject(ctx context.Context, id string, version int64, project domain.Project) (domain.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Project{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.Project{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.projects[id]
+	if !ok {
+		return domain.Project{}, &domain.NotFoundError{Message: "Project not found"}
+	}
+
+	if existing.Version != version {
+		return domain.Project{}, &domain.ConflictError{Message: "Project has been modified since it was read"}
+	}
+
+	existing.Name = project.Name
+	existing.Description = project.Description
+	existing.Members = slices.Clone(project.Members)
+	existing.Version++
+	r.projects[id] = existing
+
+	return cloneProject(existing), nil
+}
+
+// DeleteProject deletes a project
+func (r *projectMemoryRepository) DeleteProject(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[id]; !ok {
+		return &domain.NotFoundError{Message: "Project not found"}
+	}
+
+	delete(r.projects, id)
+	return nil
+}
+
+// createProject stores a new project; the caller holds the write lock
+func (r *projectMemoryRepository) createProject(project domain.Project) domain.Project {
+	project.ID = primitive.NewObjectID().Hex()
+	project.Version = 1
+	project.Members = slices.Clone(project.Members)
+	r.projects[project.ID] = project
+
+	return cloneProject(project)
+}
+
+// personalProject finds the user's personal project; the caller holds the lock
+func (r *projectMemoryRepository) personalProject(userID string) (domain.Project, bool) {
+	for _, project := range r.projects {
+		if project.PersonalOf == userID {
+			return cloneProject(project), true
+		}
+	}
+
+	return domain.Project{}, false
+}
+
+// cloneProject copies a project so callers cannot change the stored members
+func cloneProject(project domain.Project) domain.Project {
+	project.Members = slices.Clone(project.Members)
+	return project
+}
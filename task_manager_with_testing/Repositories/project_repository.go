@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ProjectRepository stores projects along with their members. Every project carries a
+// version that starts at 1 and is incremented by each update; updating with an outdated
+// version fails with a ConflictError.
+type ProjectRepository interface {
+	CreateProject(ctx context.Context, project domain.Project) (domain.Project, error)
+	// EnsurePersonalProject returns the personal project of the user the project is
+	// personal to, creating it from the project if they have none yet
+	EnsurePersonalProject(ctx context.Context, project domain.Project) (domain.Project, error)
+	GetProject(ctx context.Context, id string) (domain.Project, error)
+	// GetProjects retrieves the projects the user is a member of, or every project for an
+	// empty user ID, ordered by name
+	GetProjects(ctx context.Context, userID string) ([]domain.Project, error)
+	// UpdateProject stores the name, description and members of a project if it is still
+	// at the given version, and returns it with its new version
+	UpdateProject(ctx context.Context, id string, version int64, project domain.Project) (domain.Project, error)
+	DeleteProject(ctx context.Context, id string) error
+}
+
+// projectRepository struct
+type projectRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewProjectRepository creates a new project repository
+func NewProjectRepository(database *mongo.Database, collection string) ProjectRepository {
+	return &projectRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the index that finds a user's projects and the unique index that
+// gives each user at most one personal project
+func (r *projectRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "members.user_id", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "personal_of", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"personal_of": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return databaseError(err, "Error creating project indexes")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// CreateProject stores a new project and returns it with its assigned ID
+func (r *projectRepository) CreateProject(ctx context.Context, project domain.Project) (domain.Project, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.Project{}, err
+	}
+
+	project.ID = ""
+	project.Version = 1
+	result, err := r.db.Collection(r.collection).InsertOne(ctx, project)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Project{}, &domain.BadRequestError{Message: "User already has a personal project"}
+	}
+	if err != nil {
+		return domain.Project{}, databaseError(err, "Error creating project")
+	}
+
+	if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+		project.ID = objId.Hex()
+	}
+
+	return project, nil
+}
+
+// EnsurePersonalProject inserts the project only if the user has no personal project,
+// in a single upsert so concurrent calls end up with the same one
+func (r *projectRepository) EnsurePersonalProject(ctx context.Context, project domain.Project) (domain.Project, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.Project{}, err
+	}
+
+	project.ID = ""
+	project.Version = 1
+	filter := bson.M{"personal_of": project.PersonalOf}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var personal domain.Project
+	err := r.db.Collection(r.collection).FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": project}, opts).Decode(&personal)
+	if mongo.IsDuplicateKeyError(err) {
+		// another request created it between the lookup and the insert of the upsert
+		err = r.db.Collection(r.collection).FindOne(ctx, filter).Decode(&personal)
+	}
+	if err != nil {
+		return domain.Project{}, databaseError(err, "Error creating personal project")
+	}
+
+	return personal, nil
+}
+
+// GetProject retrieves a project by ID
+func (r *projectRepository) GetProject(ctx context.Context, id string) (domain.Project, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.Project{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	var project domain.Project
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": objId}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return domain.Project{}, &domain.NotFoundError{Message: "Project not found"}
+	}
+
+	if err != nil {
+		return domain.Project{}, databaseError(err, "Error retrieving project")
+	}
+
+	return project, nil
+}
+
+// GetProjects retrieves the user's projects, or every project, ordered by name
+func (r *projectRepository) GetProjects(ctx context.Context, userID string) ([]domain.Project, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	if userID != "" {
+		filter["members.user_id"] = userID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving projects")
+	}
+
+	defer cursor.Close(ctx)
+
+	projects := []domain.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, databaseError(err, "Error retrieving projects")
+	}
+
+	return projects, nil
+}
+
+// UpdateProject updates a project if it is still at the given version
+func (r *projectRepository) UpdateProject(ctx context.Context, id string, version int64, project domain.Project) (domain.Project, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.Project{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":        project.Name,
+			"description": project.Description,
+			"members":     project.Members,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	collection := r.db.Collection(r.collection)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated domain.Project
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": objId, "version": version}, update, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		// tell a missing project apart from one that has moved on to another version
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": objId})
+		if err != nil {
+			return domain.Project{}, databaseError(err, "Error updating project")
+		}
+
+		if count == 0 {
+			return domain.Project{}, &domain.NotFoundError{Message: "Project not found"}
+		}
+
+		return domain.Project{}, &domain.ConflictError{Message: "Project has been modified since it was read"}
+	}
+
+	if err != nil {
+		return domain.Project{}, databaseError(err, "Error updating project")
+	}
+
+	return updated, nil
+}
+
+// DeleteProject deletes a project
+func (r *projectRepository) DeleteProject(ctx context.Context, id string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	result, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": objId})
+	if err != nil {
+		return databaseError(err, "Error deleting project")
+	}
+
+	if result.DeletedCount == 0 {
+		return &domain.NotFoundError{Message: "Project not found"}
+	}
+
+	return nil
+}
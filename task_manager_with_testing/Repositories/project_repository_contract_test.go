@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProjectRepositoryContractSuite checks the behaviour every ProjectRepository backend must share
+type ProjectRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() ProjectRepository
+	repo          ProjectRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *ProjectRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestProjectRepositoryContract_Memory runs the contract against the in-memory backend
+func TestProjectRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &ProjectRepositoryContractSuite{newRepository: NewProjectMemoryRepository})
+}
+
+// TestProjectRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestProjectRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &ProjectRepositoryContractSuite{newRepository: func() ProjectRepository {
+		db.Collection("projects").Drop(context.Background())
+		return NewProjectRepository(db, "projects")
+	}})
+}
+
+func (suite *ProjectRepositoryContractSuite) createProject(name string, members ...domain.ProjectMember) domain.Project {
+	created, err := suite.repo.CreateProject(context.Background(), domain.Project{
+		Name:      name,
+		Members:   members,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	suite.Require().NoError(err)
+	return created
+}
+
+func (suite *ProjectRepositoryContractSuite) TestCreateAndGetProject() {
+	created := suite.createProject("Launch", domain.ProjectMember{UserID: "user-1", Username: "alice", Role: domain.ProjectOwner})
+	assert.NotEmpty(suite.T(), created.ID)
+	assert.Equal(suite.T(), int64(1), created.Version)
+
+	found, err := suite.repo.GetProject(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created, found)
+
+	_, err = suite.repo.GetProject(context.Background(), primitive.NewObjectID().Hex())
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.repo.GetProject(context.Background(), "not-an-id")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *ProjectRepositoryContractSuite) TestGetProjects_ByMemberOrderedByName() {
+	zoo := suite.createProject("Zoo", domain.ProjectMember{UserID: "user-1", Role: domain.ProjectViewer})
+	suite.createProject("Garden", domain.ProjectMember{UserID: "user-2", Role: domain.ProjectOwner})
+	apps := suite.createProject("Apps",
+		domain.ProjectMember{UserID: "user-2", Role: domain.ProjectOwner},
+		domain.ProjectMember{UserID: "user-1", Role: domain.ProjectEditor})
+
+	projects, err := suite.repo.GetProjects(context.Background(), "user-1")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []domain.Project{apps, zoo}, projects)
+
+	projects, err = suite.repo.GetProjects(context.Background(), "")
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), projects, 3)
+
+	projects, err = suite.repo.GetProjects(context.Background(), "user-3")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), projects)
+}
+
+func (suite *ProjectRepositoryContractSuite) TestEnsurePersonalProject() {
+	personal := domain.Project{Name: "Personal", PersonalOf: "user-1", Members: []domain.ProjectMember{{UserID: "user-1", Role: domain.ProjectOwner}}}
+
+	first, err := suite.repo.EnsurePersonalProject(context.Background(), personal)
+	suite.Require().NoError(err)
+	assert.NotEmpty(suite.T(), first.ID)
+	assert.Equal(suite.T(), "user-1", first.PersonalOf)
+
+	personal.Name = "Another"
+	second, err := suite.repo.EnsurePersonalProject(context.Background(), personal)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), first, second)
+
+	_, err = suite.repo.CreateProject(context.Background(), personal)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *ProjectRepositoryContractSuite) TestEnsurePersonalProject_Concurrent() {
+	personal := domain.Project{Name: "Personal", PersonalOf: "user-1", Members: []domain.ProjectMember{{UserID: "user-1", Role: domain.ProjectOwner}}}
+
+	var wg sync.WaitGroup
+	ids := make([]string, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			project, err := suite.repo.EnsurePersonalProject(context.Background(), personal)
+			if assert.NoError(suite.T(), err) {
+				ids[i] = project.ID
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		assert.Equal(suite.T(), ids[0], id)
+	}
+}
+
+func (suite *ProjectRepositoryContractSuite) TestUpdateProject() {
+	created := suite.createProject("Launch", domain.ProjectMember{UserID: "user-1", Role: domain.ProjectOwner})
+
+	changed := created
+	changed.Name = "Relaunch"
+	changed.Description = "Second attempt"
+	changed.Members = append(changed.Members, domain.ProjectMember{UserID: "user-2", Username: "bob", Role: domain.ProjectEditor})
+	changed.PersonalOf = "user-1"
+
+	updated, err := suite.repo.UpdateProject(context.Background(), created.ID, created.Version, changed)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(2), updated.Version)
+	assert.Equal(suite.T(), "Relaunch", updated.Name)
+	assert.Equal(suite.T(), "Second attempt", updated.Description)
+	assert.Equal(suite.T(), changed.Members, updated.Members)
+	assert.Empty(suite.T(), updated.PersonalOf, "only the name, description and members change")
+
+	found, err := suite.repo.GetProject(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), updated, found)
+
+	_, err = suite.repo.UpdateProject(context.Background(), created.ID, created.Version, changed)
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+
+	_, err = suite.repo.UpdateProject(context.Background(), primitive.NewObjectID().Hex(), 1, changed)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *ProjectRepositoryContractSuite) TestDeleteProject() {
+	created := suite.createProject("Launch", domain.ProjectMember{UserID: "user-1", Role: domain.ProjectOwner})
+
+	suite.Require().NoError(suite.repo.DeleteProject(context.Background(), created.ID))
+
+	_, err := suite.repo.GetProject(context.Background(), created.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteProject(context.Background(), created.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// refreshTokenMemoryRepository keeps refresh tokens in memory, keyed by ID
+type refreshTokenMemoryRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]domain.RefreshToken
+}
+
+// NewRefreshTokenMemoryRepository creates a new in-memory refresh token repository
+func NewRefreshTokenMemoryRepository() RefreshTokenRepository {
+	return &refreshTokenMemoryRepository{tokens: make(map[string]domain.RefreshToken)}
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *refreshTokenMemoryRepository) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = primitive.NewObjectID().Hex()
+	r.tokens[token.ID] = token
+
+	return nil
+}
+
+// FindByHash retrieves a refresh token by the hash of its value
+func (r *refreshTokenMemoryRepository) FindByHash(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.RefreshToken{}, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return domain.RefreshToken{}, &domain.NotFoundError{Message: "Refresh token not found"}
+}
+
+// MarkUsed flags an unused refresh token as used
+func (r *refreshTokenMemoryRepository) MarkUsed(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.Used {
+		return &domain.NotFoundError{Message: "Refresh token not found"}
+	}
+
+	token.Used = true
+	r.tokens[id] = token
+
+	return nil
+}
+
+// RevokeFamily revokes every refresh token in a family and returns them
+func (r *refreshTokenMemoryRepository) RevokeFamily(ctx context.Context, familyID string) ([]domain.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := []domain.RefreshToken{}
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			r.tokens[id] = token
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
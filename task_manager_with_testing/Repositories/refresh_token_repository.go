@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RefreshTokenRepository interface
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (domain.RefreshToken, error)
+	MarkUsed(ctx context.Context, id string) error
+	RevokeFamily(ctx context.Context, familyID string) ([]domain.RefreshToken, error)
+}
+
+// refreshTokenRepository struct
+type refreshTokenRepository struct {
+	db         *mongo.Database
+	collection string
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(database *mongo.Database, collection string) RefreshTokenRepository {
+	return &refreshTokenRepository{db: database, collection: collection}
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	token.ID = ""
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, token)
+
+	if err != nil {
+		return databaseError(err, "Error creating refresh token")
+	}
+
+	return nil
+}
+
+// FindByHash retrieves a refresh token by the hash of its value
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.RefreshToken{}, &domain.NotFoundError{Message: "Refresh token not found"}
+	}
+
+	if err != nil {
+		return domain.RefreshToken{}, databaseError(err, "Error retrieving refresh token")
+	}
+
+	return token, nil
+}
+
+// MarkUsed flags an unused refresh token as used; it fails with NotFoundError if
+// the token does not exist or has already been used
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	filter := bson.M{"_id": objId, "used": false}
+	updateResult, err := r.db.Collection(r.collection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"used": true}})
+
+	if err != nil {
+		return databaseError(err, "Error updating refresh token")
+	}
+
+	if updateResult.MatchedCount == 0 {
+		return &domain.NotFoundError{Message: "Refresh token not found"}
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes every refresh token in a family and returns them
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) ([]domain.RefreshToken, error) {
+	filter := bson.M{"family_id": familyID}
+
+	_, err := r.db.Collection(r.collection).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return nil, databaseError(err, "Error revoking refresh tokens")
+	}
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter)
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving refresh tokens")
+	}
+
+	defer cursor.Close(ctx)
+
+	tokens := []domain.RefreshToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, databaseError(err, "Error retrieving refresh tokens")
+	}
+
+	return tokens, nil
+}
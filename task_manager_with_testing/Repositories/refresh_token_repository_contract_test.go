@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshTokenRepositoryContractSuite checks the behaviour every RefreshTokenRepository backend must share
+type RefreshTokenRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() RefreshTokenRepository
+	repo          RefreshTokenRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *RefreshTokenRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestRefreshTokenRepositoryContract_Memory runs the contract against the in-memory backend
+func TestRefreshTokenRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &RefreshTokenRepositoryContractSuite{newRepository: NewRefreshTokenMemoryRepository})
+}
+
+// TestRefreshTokenRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestRefreshTokenRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &RefreshTokenRepositoryContractSuite{newRepository: func() RefreshTokenRepository {
+		db.Collection("refresh_tokens").Drop(context.Background())
+		return NewRefreshTokenRepository(db, "refresh_tokens")
+	}})
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) createToken(hash, familyID string) domain.RefreshToken {
+	err := suite.repo.CreateRefreshToken(context.Background(), domain.RefreshToken{
+		TokenHash:     hash,
+		FamilyID:      familyID,
+		Username:      "testuser",
+		AccessTokenID: "access-" + hash,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	suite.Require().NoError(err)
+
+	token, err := suite.repo.FindByHash(context.Background(), hash)
+	suite.Require().NoError(err)
+	return token
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestCreateAndFindByHash() {
+	token := suite.createToken("hash", "family")
+
+	assert.True(suite.T(), primitive.IsValidObjectID(token.ID))
+	assert.Equal(suite.T(), "family", token.FamilyID)
+	assert.Equal(suite.T(), "testuser", token.Username)
+	assert.Equal(suite.T(), "access-hash", token.AccessTokenID)
+	assert.False(suite.T(), token.Used)
+	assert.False(suite.T(), token.Revoked)
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestFindByHash_NotFound() {
+	_, err := suite.repo.FindByHash(context.Background(), "missing")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestMarkUsed() {
+	token := suite.createToken("hash", "family")
+
+	assert.NoError(suite.T(), suite.repo.MarkUsed(context.Background(), token.ID))
+
+	used, err := suite.repo.FindByHash(context.Background(), "hash")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), used.Used)
+
+	err = suite.repo.MarkUsed(context.Background(), token.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err, "a token can only be used once")
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestMarkUsed_Errors() {
+	err := suite.repo.MarkUsed(context.Background(), "invalid")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	err = suite.repo.MarkUsed(context.Background(), primitive.NewObjectID().Hex())
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestMarkUsed_Concurrent() {
+	token := suite.createToken("hash", "family")
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- suite.repo.MarkUsed(context.Background(), token.ID)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		}
+	}
+	assert.Equal(suite.T(), 1, succeeded)
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestRevokeFamily() {
+	suite.createToken("first", "family")
+	suite.createToken("second", "family")
+	suite.createToken("other", "other-family")
+
+	revoked, err := suite.repo.RevokeFamily(context.Background(), "family")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), revoked, 2)
+	for _, token := range revoked {
+		assert.True(suite.T(), token.Revoked)
+		assert.Equal(suite.T(), "family", token.FamilyID)
+	}
+
+	first, err := suite.repo.FindByHash(context.Background(), "first")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), first.Revoked)
+
+	other, err := suite.repo.FindByHash(context.Background(), "other")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), other.Revoked)
+}
+
+func (suite *RefreshTokenRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.FindByHash(ctx, "hash")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
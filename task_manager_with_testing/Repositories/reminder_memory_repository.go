@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+)
+
+// reminderMemoryRepository keeps sent reminders in memory, keyed by reminder key
+type reminderMemoryRepository struct {
+	mu        sync.Mutex
+	reminders map[string]domain.Reminder
+}
+
+// NewReminderMemoryRepository creates a new in-memory reminder repository
+func NewReminderMemoryRepository() ReminderRepository {
+	return &reminderMemoryRepository{reminders: make(map[string]domain.Reminder)}
+}
+
+// Claim records a reminder as sent; it returns false if it already was
+func (r *reminderMemoryRepository) Claim(ctx context.Context, reminder domain.Reminder) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.reminders[reminder.Key()]; ok {
+		return false, nil
+	}
+
+	r.reminders[reminder.Key()] = reminder
+	return true, nil
+}
+
+// Release forgets a claimed reminder so it is tried again
+func (r *reminderMemoryRepository) Release(ctx context.Context, reminder domain.Reminder) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.reminders, reminder.Key())
+	return nil
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReminderRepository records which reminders have been sent so each is sent once,
+// even by several scheduler instances or after a restart
+type ReminderRepository interface {
+	// Claim records a reminder as sent; it returns false if it already was
+	Claim(ctx context.Context, reminder domain.Reminder) (bool, error)
+	// Release forgets a claimed reminder that could not be sent so it is tried again
+	Release(ctx context.Context, reminder domain.Reminder) error
+}
+
+// reminderRepository struct
+type reminderRepository struct {
+	db         *mongo.Database
+	collection string
+}
+
+// NewReminderRepository creates a new reminder repository
+func NewReminderRepository(database *mongo.Database, collection string) ReminderRepository {
+	return &reminderRepository{db: database, collection: collection}
+}
+
+// reminderDocument is a reminder stored under its key, which the _id index keeps unique
+type reminderDocument struct {
+	Key             string `bson:"_id"`
+	domain.Reminder `bson:",inline"`
+}
+
+// Claim records a reminder as sent; it returns false if it already was
+func (r *reminderRepository) Claim(ctx context.Context, reminder domain.Reminder) (bool, error) {
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, reminderDocument{Key: reminder.Key(), Reminder: reminder})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, databaseError(err, "Error recording reminder")
+	}
+
+	return true, nil
+}
+
+// Release forgets a claimed reminder so it is tried again
+func (r *reminderRepository) Release(ctx context.Context, reminder domain.Reminder) error {
+	_, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": reminder.Key()})
+	if err != nil {
+		return databaseError(err, "Error releasing reminder")
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// ReminderRepositoryContractSuite checks the behaviour every ReminderRepository backend must share
+type ReminderRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() ReminderRepository
+	repo          ReminderRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *ReminderRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestReminderRepositoryContract_Memory runs the contract against the in-memory backend
+func TestReminderRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &ReminderRepositoryContractSuite{newRepository: NewReminderMemoryRepository})
+}
+
+// TestReminderRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestReminderRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &ReminderRepositoryContractSuite{newRepository: func() ReminderRepository {
+		db.Collection("reminders").Drop(context.Background())
+		return NewReminderRepository(db, "reminders")
+	}})
+}
+
+func testReminder() domain.Reminder {
+	return domain.Reminder{
+		TaskID:   "task-id",
+		Title:    "Pay rent",
+		DueDate:  time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC),
+		LeadTime: time.Hour,
+		Username: "alice",
+		SentAt:   time.Now(),
+	}
+}
+
+func (suite *ReminderRepositoryContractSuite) TestClaimOnce() {
+	reminder := testReminder()
+
+	claimed, err := suite.repo.Claim(context.Background(), reminder)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), claimed)
+
+	claimed, err = suite.repo.Claim(context.Background(), reminder)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), claimed)
+
+	// another lead time or due date is a different reminder
+	reminder.LeadTime = 0
+	claimed, err = suite.repo.Claim(context.Background(), reminder)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), claimed)
+
+	reminder.DueDate = reminder.DueDate.Add(time.Hour)
+	claimed, err = suite.repo.Claim(context.Background(), reminder)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), claimed)
+}
+
+func (suite *ReminderRepositoryContractSuite) TestRelease() {
+	reminder := testReminder()
+
+	claimed, err := suite.repo.Claim(context.Background(), reminder)
+	suite.Require().NoError(err)
+	suite.Require().True(claimed)
+
+	suite.Require().NoError(suite.repo.Release(context.Background(), reminder))
+
+	claimed, err = suite.repo.Claim(context.Background(), reminder)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), claimed)
+
+	// releasing a reminder that was never claimed is not an error
+	reminder.TaskID = "other-task"
+	assert.NoError(suite.T(), suite.repo.Release(context.Background(), reminder))
+}
+
+func (suite *ReminderRepositoryContractSuite) TestConcurrentClaims() {
+	reminder := testReminder()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claims := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := suite.repo.Claim(context.Background(), reminder)
+			assert.NoError(suite.T(), err)
+			if claimed {
+				mu.Lock()
+				claims++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(suite.T(), 1, claims)
+}
+
+func (suite *ReminderRepositoryContractSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := suite.repo.Claim(ctx, testReminder())
+	assert.Error(suite.T(), err)
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// revokedTokenMemoryRepository keeps the revocation list in memory
+type revokedTokenMemoryRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+}
+
+// NewRevokedTokenMemoryRepository creates a new in-memory revoked token repository
+func NewRevokedTokenMemoryRepository() RevokedTokenRepository {
+	return &revokedTokenMemoryRepository{tokens: make(map[string]time.Time)}
+}
+
+// Revoke adds an access token ID to the revocation list, dropping entries that have expired
+func (r *revokedTokenMemoryRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, expiry := range r.tokens {
+		if !expiry.After(now) {
+			delete(r.tokens, id)
+		}
+	}
+
+	r.tokens[tokenID] = expiresAt
+
+	return nil
+}
+
+// IsRevoked reports whether an unexpired access token ID is on the revocation list
+func (r *revokedTokenMemoryRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expiresAt, ok := r.tokens[tokenID]
+	return ok && expiresAt.After(time.Now()), nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevokedTokenRepository interface
+type RevokedTokenRepository interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// revokedTokenRepository struct
+type revokedTokenRepository struct {
+	db         *mongo.Database
+	collection string
+}
+
+// NewRevokedTokenRepository creates a new revoked token repository
+func NewRevokedTokenRepository(database *mongo.Database, collection string) RevokedTokenRepository {
+	return &revokedTokenRepository{db: database, collection: collection}
+}
+
+// Revoke adds an access token ID to the revocation list. Entries only matter
+// until the token would have expired anyway.
+func (r *revokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	filter := bson.M{"_id": tokenID}
+	update := bson.M{"$set": bson.M{"expires_at": expiresAt}}
+
+	_, err := r.db.Collection(r.collection).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return databaseError(err, "Error revoking token")
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether an unexpired access token ID is on the revocation list
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	filter := bson.M{"_id": tokenID, "expires_at": bson.M{"$gt": time.Now()}}
+
+	count, err := r.db.Collection(r.collection).CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, databaseError(err, "Error checking token revocation")
+	}
+
+	return count > 0, nil
+}
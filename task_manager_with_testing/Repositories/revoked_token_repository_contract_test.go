@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// RevokedTokenRepositoryContractSuite checks the behaviour every RevokedTokenRepository backend must share
+type RevokedTokenRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() RevokedTokenRepository
+	repo          RevokedTokenRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *RevokedTokenRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestRevokedTokenRepositoryContract_Memory runs the contract against the in-memory backend
+func TestRevokedTokenRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &RevokedTokenRepositoryContractSuite{newRepository: NewRevokedTokenMemoryRepository})
+}
+
+// TestRevokedTokenRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestRevokedTokenRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &RevokedTokenRepositoryContractSuite{newRepository: func() RevokedTokenRepository {
+		db.Collection("revoked_tokens").Drop(context.Background())
+		return NewRevokedTokenRepository(db, "revoked_tokens")
+	}})
+}
+
+func (suite *RevokedTokenRepositoryContractSuite) TestRevokeAndCheck() {
+	revoked, err := suite.repo.IsRevoked(context.Background(), "token-id")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), revoked)
+
+	assert.NoError(suite.T(), suite.repo.Revoke(context.Background(), "token-id", time.Now().Add(time.Hour)))
+
+	revoked, err = suite.repo.IsRevoked(context.Background(), "token-id")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), revoked)
+
+	revoked, err = suite.repo.IsRevoked(context.Background(), "other-id")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), revoked)
+}
+
+func (suite *RevokedTokenRepositoryContractSuite) TestRevoke_Twice() {
+	assert.NoError(suite.T(), suite.repo.Revoke(context.Background(), "token-id", time.Now().Add(time.Hour)))
+	assert.NoError(suite.T(), suite.repo.Revoke(context.Background(), "token-id", time.Now().Add(time.Hour)))
+
+	revoked, err := suite.repo.IsRevoked(context.Background(), "token-id")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), revoked)
+}
+
+func (suite *RevokedTokenRepositoryContractSuite) TestExpiredEntriesAreIgnored() {
+	assert.NoError(suite.T(), suite.repo.Revoke(context.Background(), "token-id", time.Now().Add(-time.Minute)))
+
+	revoked, err := suite.repo.IsRevoked(context.Background(), "token-id")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), revoked)
+}
+
+func (suite *RevokedTokenRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.IsRevoked(ctx, "token-id")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
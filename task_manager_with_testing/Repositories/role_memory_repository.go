@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	domain "task-manager/Domain"
+)
+
+// roleMemoryRepository keeps custom roles in memory, keyed by name
+type roleMemoryRepository struct {
+	mu    sync.RWMutex
+	roles map[string]domain.Role
+}
+
+// NewRoleMemoryRepository creates a new in-memory role repository
+func NewRoleMemoryRepository() RoleRepository {
+	return &roleMemoryRepository{roles: make(map[string]domain.Role)}
+}
+
+// CreateRole stores a new role
+func (r *roleMemoryRepository) CreateRole(ctx context.Context, role domain.Role) (domain.Role, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Role{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.roles[role.Name]; ok {
+		return domain.Role{}, &domain.AlreadyExistsError{Message: "Role already exists"}
+	}
+
+	role.Version = 1
+	role.Permissions = slices.Clone(role.Permissions)
+	r.roles[role.Name] = role
+	return role, nil
+}
+
+// GetRole retrieves a role by name
+func (r *roleMemoryRepository) GetRole(ctx context.Context, name string) (domain.Role, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Role{}, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, ok := r.roles[name]
+	if !ok {
+		return domain.Role{}, &domain.NotFoundError{Message: "Role not found"}
+	}
+
+	role.Permissions = slices.Clone(role.Permissions)
+	return role, nil
+}
+
+// GetRoles retrieves every custom role, ordered by name
+func (r *roleMemoryRepository) GetRoles(ctx context.Context) ([]domain.Role, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := []domain.Role{}
+	for _, role := range r.roles {
+		role.Permissions = slices.Clone(role.Permissions)
+		roles = append(roles, role)
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return roles, nil
+}
+
+// UpdateRole updates a role if it is still at the given version
+func (r *roleMemoryRepository) UpdateRole(ctx context.Context, name string, version int64, role domain.Role) (domain.Role, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Role{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.roles[name]
+	if !ok {
+		return domain.Role{}, &domain.NotFoundError{Message: "Role not found"}
+	}
+
+	if stored.Version != version {
+		return domain.Role{}, &domain.ConflictError{Message: "Role has been modified since it was read"}
+	}
+
+	stored.Description = role.Description
+	stored.Permissions = slices.Clone(role.Permissions)
+	stored.Version++
+	r.roles[name] = stored
+
+	stored.Permissions = slices.Clone(stored.Permissions)
+	return stored, nil
+}
+
+// DeleteRole removes a role
+func (r *roleMemoryRepository) DeleteRole(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.roles[name]; !ok {
+		return &domain.NotFoundError{Message: "Role not found"}
+	}
+
+	delete(r.roles, name)
+	return nil
+}
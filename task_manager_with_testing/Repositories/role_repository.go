@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoleRepository stores the custom roles admins create, keyed by name; the built-in roles
+// are not stored. Every role carries a version that starts at 1 and is incremented by
+// each update; updating with an outdated version fails with a ConflictError. Creating a
+// role whose name is taken fails with an AlreadyExistsError.
+type RoleRepository interface {
+	CreateRole(ctx context.Context, role domain.Role) (domain.Role, error)
+	GetRole(ctx context.Context, name string) (domain.Role, error)
+	// GetRoles retrieves every custom role, ordered by name
+	GetRoles(ctx context.Context) ([]domain.Role, error)
+	// UpdateRole stores the description and permissions of a role if it is still at the
+	// given version, and returns it with its new version
+	UpdateRole(ctx context.Context, name string, version int64, role domain.Role) (domain.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+}
+
+// roleRepository struct
+type roleRepository struct {
+	db         *mongo.Database
+	collection string
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(database *mongo.Database, collection string) RoleRepository {
+	return &roleRepository{db: database, collection: collection}
+}
+
+// CreateRole stores a new role; the name is the document ID, so it is unique
+func (r *roleRepository) CreateRole(ctx context.Context, role domain.Role) (domain.Role, error) {
+	role.Version = 1
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, role)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Role{}, &domain.AlreadyExistsError{Message: "Role already exists"}
+	}
+	if err != nil {
+		return domain.Role{}, databaseError(err, "Error creating role")
+	}
+
+	return role, nil
+}
+
+// GetRole retrieves a role by name
+func (r *roleRepository) GetRole(ctx context.Context, name string) (domain.Role, error) {
+	var role domain.Role
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": name}).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		return domain.Role{}, &domain.NotFoundError{Message: "Role not found"}
+	}
+
+	if err != nil {
+		return domain.Role{}, databaseError(err, "Error retrieving role")
+	}
+
+	return role, nil
+}
+
+// GetRoles retrieves every custom role, ordered by name
+func (r *roleRepository) GetRoles(ctx context.Context) ([]domain.Role, error) {
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving roles")
+	}
+
+	defer cursor.Close(ctx)
+
+	roles := []domain.Role{}
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, databaseError(err, "Error retrieving roles")
+	}
+
+	return roles, nil
+}
+
+// UpdateRole updates a role if it is still at the given version
+func (r *roleRepository) UpdateRole(ctx context.Context, name string, version int64, role domain.Role) (domain.Role, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"description": role.Description,
+			"permissions": role.Permissions,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	collection := r.db.Collection(r.collection)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated domain.Role
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": name, "version": version}, update, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		// tell a missing role apart from one that has moved on to another version
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": name})
+		if err != nil {
+			return domain.Role{}, databaseError(err, "Error updating role")
+		}
+
+		if count == 0 {
+			return domain.Role{}, &domain.NotFoundError{Message: "Role not found"}
+		}
+
+		return domain.Role{}, &domain.ConflictError{Message: "Role has been modified since it was read"}
+	}
+
+	if err != nil {
+		return domain.Role{}, databaseError(err, "Error updating role")
+	}
+
+	return updated, nil
+}
+
+// DeleteRole removes a role
+func (r *roleRepository) DeleteRole(ctx context.Context, name string) error {
+	result, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return databaseError(err, "Error deleting role")
+	}
+
+	if result.DeletedCount == 0 {
+		return &domain.NotFoundError{Message: "Role not found"}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// RoleRepositoryContractSuite checks the behaviour every RoleRepository backend must share
+type RoleRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() RoleRepository
+	repo          RoleRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *RoleRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestRoleRepositoryContract_Memory runs the contract against the in-memory backend
+func TestRoleRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &RoleRepositoryContractSuite{newRepository: NewRoleMemoryRepository})
+}
+
+// TestRoleRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestRoleRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &RoleRepositoryContractSuite{newRepository: func() RoleRepository {
+		db.Collection("roles").Drop(context.Background())
+		return NewRoleRepository(db, "roles")
+	}})
+}
+
+func (suite *RoleRepositoryContractSuite) createRole(name string, permissions ...string) domain.Role {
+	created, err := suite.repo.CreateRole(context.Background(), domain.Role{
+		Name:        name,
+		Permissions: permissions,
+		CreatedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	})
+	suite.Require().NoError(err)
+	return created
+}
+
+func (suite *RoleRepositoryContractSuite) TestCreateAndGetRole() {
+	created := suite.createRole("auditor", domain.PermissionTaskRead, domain.PermissionAuditRead)
+	assert.Equal(suite.T(), int64(1), created.Version)
+
+	found, err := suite.repo.GetRole(context.Background(), "auditor")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created, found)
+
+	_, err = suite.repo.GetRole(context.Background(), "missing")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *RoleRepositoryContractSuite) TestCreateRole_Duplicate() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+
+	_, err := suite.repo.CreateRole(context.Background(), domain.Role{Name: "auditor", Permissions: []string{domain.PermissionTaskRead}})
+	assert.IsType(suite.T(), &domain.AlreadyExistsError{}, err)
+}
+
+func (suite *RoleRepositoryContractSuite) TestGetRoles_OrderedByName() {
+	suite.createRole("triage", domain.PermissionTaskUpdate)
+	suite.createRole("auditor", domain.PermissionAuditRead)
+
+	roles, err := suite.repo.GetRoles(context.Background())
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(roles, 2)
+	assert.Equal(suite.T(), "auditor", roles[0].Name)
+	assert.Equal(suite.T(), "triage", roles[1].Name)
+}
+
+func (suite *RoleRepositoryContractSuite) TestUpdateRole() {
+	created := suite.createRole("auditor", domain.PermissionAuditRead)
+
+	update := created
+	update.Description = "Reads the audit log"
+	update.Permissions = []string{domain.PermissionTaskRead, domain.PermissionAuditRead}
+	updated, err := suite.repo.UpdateRole(context.Background(), "auditor", created.Version, update)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), updated.Version)
+	assert.Equal(suite.T(), "Reads the audit log", updated.Description)
+	assert.Equal(suite.T(), update.Permissions, updated.Permissions)
+
+	_, err = suite.repo.UpdateRole(context.Background(), "auditor", created.Version, update)
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+
+	_, err = suite.repo.UpdateRole(context.Background(), "missing", 1, update)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *RoleRepositoryContractSuite) TestDeleteRole() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+
+	assert.NoError(suite.T(), suite.repo.DeleteRole(context.Background(), "auditor"))
+
+	_, err := suite.repo.GetRole(context.Background(), "auditor")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteRole(context.Background(), "auditor")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	domain "task-manager/Domain"
+)
+
+// tagMemoryRepository keeps each user's tag catalog in memory, keyed by owner and name
+type tagMemoryRepository struct {
+	mu   sync.RWMutex
+	tags map[string]map[string]domain.Tag
+}
+
+// NewTagMemoryRepository creates a new in-memory tag repository
+func NewTagMemoryRepository() TagRepository {
+	return &tagMemoryRepository{tags: make(map[string]map[string]domain.Tag)}
+}
+
+// SaveTag stores the owner's settings for a tag, replacing the ones it had
+func (r *tagMemoryRepository) SaveTag(ctx context.Context, tag domain.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owned, ok := r.tags[tag.OwnerID]
+	if !ok {
+		owned = make(map[string]domain.Tag)
+		r.tags[tag.OwnerID] = owned
+	}
+
+	tag.Count = 0
+	owned[tag.Name] = tag
+	return nil
+}
+
+// GetTags retrieves the owner's catalog, ordered by name
+func (r *tagMemoryRepository) GetTags(ctx context.Context, ownerID string) ([]domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := []domain.Tag{}
+	for _, tag := range r.tags[ownerID] {
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// DeleteTags removes the owner's settings for the tags
+func (r *tagMemoryRepository) DeleteTags(ctx context.Context, ownerID string, names []string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		delete(r.tags[ownerID], name)
+	}
+
+	return nil
+}
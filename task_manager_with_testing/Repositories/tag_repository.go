@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TagRepository stores each user's tag catalog: the settings of the tags they use.
+// How many tasks carry a tag is counted from the tasks themselves.
+type TagRepository interface {
+	// SaveTag stores the owner's settings for a tag, replacing the ones it had
+	SaveTag(ctx context.Context, tag domain.Tag) error
+	// GetTags retrieves the owner's catalog, ordered by name
+	GetTags(ctx context.Context, ownerID string) ([]domain.Tag, error)
+	// DeleteTags removes the owner's settings for the tags; tags without any are skipped
+	DeleteTags(ctx context.Context, ownerID string, names []string) error
+}
+
+// tagRepository struct
+type tagRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository(database *mongo.Database, collection string) TagRepository {
+	return &tagRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the unique index on each owner's tag names
+func (r *tagRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return databaseError(err, "Error creating tag index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// SaveTag stores the owner's settings for a tag, replacing the ones it had
+func (r *tagRepository) SaveTag(ctx context.Context, tag domain.Tag) error {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return err
+	}
+
+	filter := bson.M{"owner_id": tag.OwnerID, "name": tag.Name}
+	_, err := r.db.Collection(r.collection).ReplaceOne(ctx, filter, tag, options.Replace().SetUpsert(true))
+	if err != nil {
+		return databaseError(err, "Error saving tag")
+	}
+
+	return nil
+}
+
+// GetTags retrieves the owner's catalog, ordered by name
+func (r *tagRepository) GetTags(ctx context.Context, ownerID string) ([]domain.Tag, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{"owner_id": ownerID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving tags")
+	}
+
+	defer cursor.Close(ctx)
+
+	tags := []domain.Tag{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, databaseError(err, "Error retrieving tags")
+	}
+
+	return tags, nil
+}
+
+// DeleteTags removes the owner's settings for the tags
+func (r *tagRepository) DeleteTags(ctx context.Context, ownerID string, names []string) error {
+	_, err := r.db.Collection(r.collection).DeleteMany(ctx, bson.M{"owner_id": ownerID, "name": bson.M{"$in": names}})
+	if err != nil {
+		return databaseError(err, "Error deleting tags")
+	}
+
+	return nil
+}
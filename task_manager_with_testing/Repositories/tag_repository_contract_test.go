@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TagRepositoryContractSuite checks the behaviour every TagRepository backend must share
+type TagRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() TagRepository
+	repo          TagRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *TagRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestTagRepositoryContract_Memory runs the contract against the in-memory backend
+func TestTagRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &TagRepositoryContractSuite{newRepository: NewTagMemoryRepository})
+}
+
+// TestTagRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestTagRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &TagRepositoryContractSuite{newRepository: func() TagRepository {
+		db.Collection("tags").Drop(context.Background())
+		return NewTagRepository(db, "tags")
+	}})
+}
+
+func (suite *TagRepositoryContractSuite) TestSaveAndGetTags() {
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "owner", Name: "work", Color: "#0000ff"}))
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "owner", Name: "home", Color: "#00ff00"}))
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "other", Name: "work", Color: "#ff0000"}))
+
+	tags, err := suite.repo.GetTags(context.Background(), "owner")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []domain.Tag{
+		{OwnerID: "owner", Name: "home", Color: "#00ff00"},
+		{OwnerID: "owner", Name: "work", Color: "#0000ff"},
+	}, tags)
+
+	tags, err = suite.repo.GetTags(context.Background(), "nobody")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), tags)
+}
+
+func (suite *TagRepositoryContractSuite) TestSaveTag_ReplacesTheTag() {
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "owner", Name: "work", Color: "#0000ff"}))
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "owner", Name: "work", Color: "#ffffff"}))
+
+	tags, err := suite.repo.GetTags(context.Background(), "owner")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []domain.Tag{{OwnerID: "owner", Name: "work", Color: "#ffffff"}}, tags)
+}
+
+func (suite *TagRepositoryContractSuite) TestDeleteTags() {
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "owner", Name: "home", Color: "#00ff00"}))
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "owner", Name: "work", Color: "#0000ff"}))
+	suite.Require().NoError(suite.repo.SaveTag(context.Background(), domain.Tag{OwnerID: "other", Name: "home", Color: "#ff0000"}))
+
+	assert.NoError(suite.T(), suite.repo.DeleteTags(context.Background(), "owner", []string{"home", "missing"}))
+
+	tags, err := suite.repo.GetTags(context.Background(), "owner")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []domain.Tag{{OwnerID: "owner", Name: "work", Color: "#0000ff"}}, tags)
+
+	tags, err = suite.repo.GetTags(context.Background(), "other")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), tags, 1)
+}
+
+func (suite *TagRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.GetTags(ctx, "owner")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
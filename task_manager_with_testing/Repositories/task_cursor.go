@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	domain "task-manager/Domain"
+)
+
+// taskCursor marks the last task of a page so the next page can resume after it
+type taskCursor struct {
+	Sort    string    `json:"s"`
+	ID      string    `json:"id"`
+	Title   string    `json:"t,omitempty"`
+	DueDate time.Time `json:"d,omitempty"`
+}
+
+// sortField returns the field tasks are ordered by, defaulting to the due date
+func sortField(query domain.TaskQuery) string {
+	if query.SortBy == "" {
+		return "due_date"
+	}
+	return query.SortBy
+}
+
+// sortKey identifies the sort order of a query, e.g. "due_date" or "-title"
+func sortKey(query domain.TaskQuery) string {
+	if query.Descending {
+		return "-" + sortField(query)
+	}
+	return sortField(query)
+}
+
+// encodeTaskCursor builds an opaque cursor pointing after the given task
+func encodeTaskCursor(query domain.TaskQuery, task domain.Task) string {
+	cursor := taskCursor{Sort: sortKey(query), ID: task.ID}
+	switch sortField(query) {
+	case "title":
+		cursor.Title = task.Title
+	default:
+		cursor.DueDate = task.DueDate
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor parses a cursor and checks that it belongs to the same sort order
+func decodeTaskCursor(query domain.TaskQuery) (taskCursor, error) {
+	var cursor taskCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return cursor, &domain.BadRequestError{Message: "Invalid cursor"}
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return cursor, &domain.BadRequestError{Message: "Invalid cursor"}
+	}
+
+	if cursor.Sort != sortKey(query) {
+		return cursor, &domain.BadRequestError{Message: "Cursor does not match the requested sort order"}
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	domain "task-manager/Domain"
+)
+
+// taskHistoryMemoryRepository keeps task versions in memory, keyed by task ID
+type taskHistoryMemoryRepository struct {
+	mu       sync.RWMutex
+	versions map[string][]domain.TaskVersion
+}
+
+// NewTaskHistoryMemoryRepository creates a new in-memory task history repository
+func NewTaskHistoryMemoryRepository() TaskHistoryRepository {
+	return &taskHistoryMemoryRepository{versions: make(map[string][]domain.TaskVersion)}
+}
+
+// AddVersion stores a snapshot of a task version
+func (r *taskHistoryMemoryRepository) AddVersion(ctx context.Context, version domain.TaskVersion) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.versions[version.TaskID]
+	for _, existing := range versions {
+		if existing.Version == version.Version {
+			return &domain.ConflictError{Message: "Task version already recorded"}
+		}
+	}
+
+	versions = append(versions, version)
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	r.versions[version.TaskID] = versions
+
+	return nil
+}
+
+// GetHistory retrieves every recorded version of a task, oldest first
+func (r *taskHistoryMemoryRepository) GetHistory(ctx context.Context, taskID string) ([]domain.TaskVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]domain.TaskVersion{}, r.versions[taskID]...), nil
+}
+
+// DeleteHistory removes every recorded version of a task
+func (r *taskHistoryMemoryRepository) DeleteHistory(ctx context.Context, taskID string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.versions, taskID)
+
+	return nil
+}
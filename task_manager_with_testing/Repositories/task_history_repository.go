@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskHistoryRepository keeps a snapshot of every version of each task
+type TaskHistoryRepository interface {
+	AddVersion(ctx context.Context, version domain.TaskVersion) error
+	GetHistory(ctx context.Context, taskID string) ([]domain.TaskVersion, error)
+	DeleteHistory(ctx context.Context, taskID string) error
+}
+
+// taskHistoryRepository struct
+type taskHistoryRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewTaskHistoryRepository creates a new task history repository
+func NewTaskHistoryRepository(database *mongo.Database, collection string) TaskHistoryRepository {
+	return &taskHistoryRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the unique index that allows one snapshot per task version
+func (r *taskHistoryRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "task_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return databaseError(err, "Error creating task history index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// AddVersion stores a snapshot of a task version
+func (r *taskHistoryRepository) AddVersion(ctx context.Context, version domain.TaskVersion) error {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, version)
+	if mongo.IsDuplicateKeyError(err) {
+		return &domain.ConflictError{Message: "Task version already recorded"}
+	}
+
+	if err != nil {
+		return databaseError(err, "Error recording task version")
+	}
+
+	return nil
+}
+
+// GetHistory retrieves every recorded version of a task, oldest first
+func (r *taskHistoryRepository) GetHistory(ctx context.Context, taskID string) ([]domain.TaskVersion, error) {
+	opts := options.Find().SetSort(bson.M{"version": 1})
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving task history")
+	}
+
+	defer cursor.Close(ctx)
+
+	versions := []domain.TaskVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, databaseError(err, "Error retrieving task history")
+	}
+
+	return versions, nil
+}
+
+// DeleteHistory removes every recorded version of a task
+func (r *taskHistoryRepository) DeleteHistory(ctx context.Context, taskID string) error {
+	_, err := r.db.Collection(r.collection).DeleteMany(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		return databaseError(err, "Error deleting task history")
+	}
+
+	return nil
+}
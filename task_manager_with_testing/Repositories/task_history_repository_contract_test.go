@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TaskHistoryRepositoryContractSuite checks the behaviour every TaskHistoryRepository backend must share
+type TaskHistoryRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() TaskHistoryRepository
+	repo          TaskHistoryRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *TaskHistoryRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestTaskHistoryRepositoryContract_Memory runs the contract against the in-memory backend
+func TestTaskHistoryRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &TaskHistoryRepositoryContractSuite{newRepository: NewTaskHistoryMemoryRepository})
+}
+
+// TestTaskHistoryRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestTaskHistoryRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &TaskHistoryRepositoryContractSuite{newRepository: func() TaskHistoryRepository {
+		db.Collection("task_history").Drop(context.Background())
+		return NewTaskHistoryRepository(db, "task_history")
+	}})
+}
+
+func taskVersion(taskID string, version int64, title string) domain.TaskVersion {
+	return domain.TaskVersion{
+		TaskID:     taskID,
+		Version:    version,
+		Title:      title,
+		DueDate:    time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+		Status:     "pending",
+		ModifiedBy: "testuser",
+		ModifiedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func (suite *TaskHistoryRepositoryContractSuite) TestAddVersionAndGetHistory() {
+	second := taskVersion("task-1", 2, "Second")
+	suite.Require().NoError(suite.repo.AddVersion(context.Background(), second))
+	suite.Require().NoError(suite.repo.AddVersion(context.Background(), taskVersion("task-1", 1, "First")))
+	suite.Require().NoError(suite.repo.AddVersion(context.Background(), taskVersion("task-2", 1, "Other")))
+
+	history, err := suite.repo.GetHistory(context.Background(), "task-1")
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(history, 2)
+	assert.Equal(suite.T(), "First", history[0].Title)
+	assert.Equal(suite.T(), second, history[1])
+}
+
+func (suite *TaskHistoryRepositoryContractSuite) TestAddVersion_Duplicate() {
+	suite.Require().NoError(suite.repo.AddVersion(context.Background(), taskVersion("task-1", 1, "First")))
+
+	err := suite.repo.AddVersion(context.Background(), taskVersion("task-1", 1, "Again"))
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+}
+
+func (suite *TaskHistoryRepositoryContractSuite) TestGetHistory_Empty() {
+	history, err := suite.repo.GetHistory(context.Background(), "task-1")
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), history)
+	assert.Empty(suite.T(), history)
+}
+
+func (suite *TaskHistoryRepositoryContractSuite) TestDeleteHistory() {
+	suite.Require().NoError(suite.repo.AddVersion(context.Background(), taskVersion("task-1", 1, "First")))
+	suite.Require().NoError(suite.repo.AddVersion(context.Background(), taskVersion("task-2", 1, "Other")))
+
+	assert.NoError(suite.T(), suite.repo.DeleteHistory(context.Background(), "task-1"))
+
+	history, err := suite.repo.GetHistory(context.Background(), "task-1")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), history)
+
+	history, err = suite.repo.GetHistory(context.Background(), "task-2")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 1)
+}
+
+func (suite *TaskHistoryRepositoryContractSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := suite.repo.AddVersion(ctx, taskVersion("task-1", 1, "First"))
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+
+	_, err = suite.repo.GetHistory(ctx, "task-1")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
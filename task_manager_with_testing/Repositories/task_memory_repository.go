@@ -0,0 +1,697 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// taskMemoryRepository keeps tasks in memory, keyed by ID, along with an inverted
+// index from each word of a title to the tasks containing it
+type taskMemoryRepository struct {
+	mu    sync.RWMutex
+	tasks map[string]domain.Task
+	index map[string]map[string]bool
+	words []string
+}
+
+// NewTaskMemoryRepository creates a new in-memory task repository
+func NewTaskMemoryRepository() TaskRepository {
+	return &taskMemoryRepository{tasks: make(map[string]domain.Task), index: make(map[string]map[string]bool)}
+}
+
+// CreateTask creates a new task and returns it with its assigned ID
+func (r *taskMemoryRepository) CreateTask(ctx context.Context, task domain.Task) (domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Task{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.createTask(primitive.NewObjectID().Hex(), task), nil
+}
+
+// GetTask retrieves a task by ID
+func (r *taskMemoryRepository) GetTask(ctx context.Context, id string) (domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Task{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	return task, nil
+}
+
+// GetTasks retrieves one page of tasks matching the query
+func (r *taskMemoryRepository) GetTasks(ctx context.Context, query domain.TaskQuery) (domain.TaskPage, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TaskPage{}, contextError(err)
+	}
+
+	var after *taskCursor
+	if query.Cursor != "" {
+		cursor, err := decodeTaskCursor(query)
+		if err != nil {
+			return domain.TaskPage{}, err
+		}
+		after = &cursor
+	}
+
+	r.mu.RLock()
+	tasks := []domain.Task{}
+	for _, task := range r.tasks {
+		if matchesTaskQuery(task, query) {
+			tasks = append(tasks, task)
+		}
+	}
+	r.mu.RUnlock()
+
+	field := sortField(query)
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareTasks(tasks[i], tasks[j], field, query.Descending) < 0
+	})
+
+	if after != nil {
+		last := domain.Task{ID: after.ID, Title: after.Title, DueDate: after.DueDate}
+		start := sort.Search(len(tasks), func(i int) bool {
+			return compareTasks(tasks[i], last, field, query.Descending) > 0
+		})
+		tasks = tasks[start:]
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = domain.DefaultTaskPageSize
+	}
+
+	page := domain.TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor = encodeTaskCursor(query, page.Tasks[limit-1])
+	}
+
+	return page, nil
+}
+
+// UpdateTask updates a task if it is still at the given version and returns it with its new version
+func (r *taskMemoryRepository) UpdateTask(ctx context.Context, id string, version int64, task domain.Task) (domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Task{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateTask(id, version, task)
+}
+
+// updateTask updates a stored task; the caller holds the write lock
+func (r *taskMemoryRepository) updateTask(id string, version int64, task domain.Task) (domain.Task, error) {
+	existing, ok := r.tasks[id]
+	if !ok {
+		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	if existing.Version != version {
+		return domain.Task{}, &domain.ConflictError{Message: "Task has been modified since it was read"}
+	}
+
+	r.unindexTask(existing)
+	existing.Title = task.Title
+	existing.DueDate = task.DueDate
+	existing.Status = task.Status
+	existing.ProjectID = task.ProjectID
+	existing.ParentID = task.ParentID
+	existing.DependsOn = cloneIDs(task.DependsOn)
+	existing.Tags = cloneIDs(task.Tags)
+	existing.Recurrence = task.Recurrence
+	existing.Occurrence = task.Occurrence
+	existing.NextID = task.NextID
+	existing.Version++
+	r.tasks[id] = existing
+	r.indexTask(existing)
+
+	return existing, nil
+}
+
+// DeleteTask deletes a task
+func (r *taskMemoryRepository) DeleteTask(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := r.deleteTask(id)
+	return err
+}
+
+// createTask stores a new task under the given ID; the caller holds the write lock
+func (r *taskMemoryRepository) createTask(id string, task domain.Task) domain.Task {
+	task.ID = id
+	task.Version = 1
+	task.DependsOn = cloneIDs(task.DependsOn)
+	task.Tags = cloneIDs(task.Tags)
+	task.Attachments = slices.Clone(task.Attachments)
+	r.tasks[task.ID] = task
+	r.indexTask(task)
+
+	return task
+}
+
+// deleteTask removes a stored task and returns it; the caller holds the write lock
+func (r *taskMemoryRepository) deleteTask(id string) (domain.Task, error) {
+	existing, ok := r.tasks[id]
+	if !ok {
+		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	r.unindexTask(existing)
+	delete(r.tasks, id)
+
+	return existing, nil
+}
+
+// restoreTask puts back a task as it was before a write that is being undone
+func (r *taskMemoryRepository) restoreTask(id string, previous domain.Task, existed bool) {
+	if current, ok := r.tasks[id]; ok {
+		r.unindexTask(current)
+		delete(r.tasks, id)
+	}
+
+	if existed {
+		r.tasks[id] = previous
+		r.indexTask(previous)
+	}
+}
+
+// GetTasksByTitles retrieves the owner's tasks with any of the given titles
+func (r *taskMemoryRepository) GetTasksByTitles(ctx context.Context, ownerID string, titles []string) ([]domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	wanted := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		wanted[title] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := []domain.Task{}
+	for _, task := range r.tasks {
+		if task.OwnerID == ownerID && wanted[task.Title] {
+			tasks = append(tasks, task)
+		}
+	}
+
+	sortTasksByID(tasks)
+	return tasks, nil
+}
+
+// GetTagCounts counts the owner's tasks carrying each of their tags
+func (r *taskMemoryRepository) GetTagCounts(ctx context.Context, ownerID string) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, task := range r.tasks {
+		if task.OwnerID != ownerID {
+			continue
+		}
+
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+
+	return counts, nil
+}
+
+// ReplaceTags swaps the given tags for another one on every task of the owner that has
+// any of them, keeping each task's tags sorted, and returns the changed tasks
+func (r *taskMemoryRepository) ReplaceTags(ctx context.Context, ownerID string, from []string, to string) ([]domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := []domain.Task{}
+	for id, task := range r.tasks {
+		if task.OwnerID != ownerID || !task.HasTags([][]string{from}) {
+			continue
+		}
+
+		task.Tags = replacedTags(task.Tags, from, to)
+		task.Version++
+		r.tasks[id] = task
+		changed = append(changed, task)
+	}
+
+	sortTasksByID(changed)
+	return changed, nil
+}
+
+// GetUnassignedTaskOwners lists the owners of tasks that have no project, in order
+func (r *taskMemoryRepository) GetUnassignedTaskOwners(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	owners := []string{}
+	for _, task := range r.tasks {
+		if task.ProjectID == "" && task.OwnerID != "" && !slices.Contains(owners, task.OwnerID) {
+			owners = append(owners, task.OwnerID)
+		}
+	}
+	sort.Strings(owners)
+
+	return owners, nil
+}
+
+// AssignProject moves the owner's tasks that have no project into the project
+func (r *taskMemoryRepository) AssignProject(ctx context.Context, ownerID, projectID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var moved int64
+	for id, task := range r.tasks {
+		if task.ProjectID != "" || task.OwnerID != ownerID {
+			continue
+		}
+
+		task.ProjectID = projectID
+		task.Version++
+		r.tasks[id] = task
+		moved++
+	}
+
+	return moved, nil
+}
+
+// AddAttachment adds the attachment to the task unless it already has the most allowed
+func (r *taskMemoryRepository) AddAttachment(ctx context.Context, taskID string, attachment domain.Attachment) (domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Task{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(taskID) {
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	if len(task.Attachments) >= domain.MaxTaskAttachments {
+		return domain.Task{}, &domain.BadRequestError{Message: fmt.Sprintf("A task can have at most %d attachments", domain.MaxTaskAttachments)}
+	}
+
+	task.Attachments = append(slices.Clone(task.Attachments), attachment)
+	task.Version++
+	r.tasks[taskID] = task
+
+	return task, nil
+}
+
+// RemoveAttachment removes the attachment from the task
+func (r *taskMemoryRepository) RemoveAttachment(ctx context.Context, taskID, attachmentID string) (domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Task{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(taskID) {
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	if _, ok := task.FindAttachment(attachmentID); !ok {
+		return domain.Task{}, &domain.NotFoundError{Message: "Attachment not found"}
+	}
+
+	task.Attachments = slices.DeleteFunc(slices.Clone(task.Attachments), func(attachment domain.Attachment) bool {
+		return attachment.ID == attachmentID
+	})
+	if len(task.Attachments) == 0 {
+		task.Attachments = nil
+	}
+	task.Version++
+	r.tasks[taskID] = task
+
+	return task, nil
+}
+
+// WriteTasks applies the writes in order under a single lock. An atomic batch that
+// fails puts back every task it had changed.
+func (r *taskMemoryRepository) WriteTasks(ctx context.Context, writes []domain.TaskWrite, atomic bool) ([]domain.TaskWriteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type change struct {
+		id       string
+		previous domain.Task
+		existed  bool
+	}
+
+	results := make([]domain.TaskWriteResult, len(writes))
+	var changes []change
+	for i, write := range writes {
+		id := write.ID
+		if write.Op == domain.BatchCreate {
+			id = write.Task.ID
+			if id == "" {
+				id = primitive.NewObjectID().Hex()
+			}
+		}
+
+		previous, existed := r.tasks[id]
+		result := &results[i]
+		switch {
+		case !primitive.IsValidObjectID(id):
+			result.Err = &domain.BadRequestError{Message: "Invalid ID"}
+		case write.Op == domain.BatchCreate && existed:
+			result.Err = &domain.InternalServerError{Message: "Error creating task"}
+		case write.Op == domain.BatchCreate:
+			result.Task = r.createTask(id, write.Task)
+		case write.Op == domain.BatchUpdate:
+			result.Task, result.Err = r.updateTask(id, write.Version, write.Task)
+		case write.Op == domain.BatchDelete:
+			_, result.Err = r.deleteTask(id)
+		default:
+			result.Err = &domain.BadRequestError{Message: "Unknown write " + write.Op}
+		}
+
+		if result.Err == nil {
+			changes = append(changes, change{id, previous, existed})
+			continue
+		}
+
+		if atomic {
+			for j := len(changes) - 1; j >= 0; j-- {
+				r.restoreTask(changes[j].id, changes[j].previous, changes[j].existed)
+			}
+			return results, result.Err
+		}
+	}
+
+	return results, nil
+}
+
+// GetTasksByIDs retrieves the tasks with the given IDs; IDs without a task are skipped
+func (r *taskMemoryRepository) GetTasksByIDs(ctx context.Context, ids []string) ([]domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	for _, id := range ids {
+		if !primitive.IsValidObjectID(id) {
+			return nil, &domain.BadRequestError{Message: "Invalid ID"}
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := []domain.Task{}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if task, ok := r.tasks[id]; ok && !seen[id] {
+			seen[id] = true
+			tasks = append(tasks, task)
+		}
+	}
+
+	sortTasksByID(tasks)
+	return tasks, nil
+}
+
+// GetSubtasks retrieves the direct subtasks of the given tasks
+func (r *taskMemoryRepository) GetSubtasks(ctx context.Context, parentIDs []string) ([]domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	parents := make(map[string]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		parents[id] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := []domain.Task{}
+	for _, task := range r.tasks {
+		if task.ParentID != "" && parents[task.ParentID] {
+			tasks = append(tasks, task)
+		}
+	}
+
+	sortTasksByID(tasks)
+	return tasks, nil
+}
+
+// SearchTasks finds the tasks whose titles match the search, most relevant first
+func (r *taskMemoryRepository) SearchTasks(ctx context.Context, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	terms, limit, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// every word, including those of phrases, must appear, so intersect their postings
+	var candidates map[string]bool
+	postings := func(ids map[string]bool) {
+		if candidates == nil {
+			candidates = make(map[string]bool, len(ids))
+			for id := range ids {
+				candidates[id] = true
+			}
+			return
+		}
+
+		matching := make(map[string]bool)
+		for id := range candidates {
+			if ids[id] {
+				matching[id] = true
+			}
+		}
+		candidates = matching
+	}
+
+	for _, word := range terms.Words {
+		postings(r.index[word])
+	}
+
+	for _, phrase := range terms.Phrases {
+		for _, word := range phrase {
+			postings(r.index[word])
+		}
+	}
+
+	for _, prefix := range terms.Prefixes {
+		ids := make(map[string]bool)
+		for i := sort.SearchStrings(r.words, prefix); i < len(r.words) && strings.HasPrefix(r.words[i], prefix); i++ {
+			for id := range r.index[r.words[i]] {
+				ids[id] = true
+			}
+		}
+		postings(ids)
+	}
+
+	tasks := []domain.Task{}
+	for id := range candidates {
+		task := r.tasks[id]
+		if query.OwnerID != "" && task.OwnerID != query.OwnerID {
+			continue
+		}
+
+		if query.Visibility == nil || query.Visibility.Includes(task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	// phrases are checked, and results scored, against the full title
+	return rankSearchResults(terms, tasks, limit), nil
+}
+
+// indexTask adds the words of a task's title to the inverted index
+func (r *taskMemoryRepository) indexTask(task domain.Task) {
+	for _, token := range domain.Tokenize(task.Title) {
+		ids, ok := r.index[token.Text]
+		if !ok {
+			ids = make(map[string]bool)
+			r.index[token.Text] = ids
+
+			i := sort.SearchStrings(r.words, token.Text)
+			r.words = append(r.words, "")
+			copy(r.words[i+1:], r.words[i:])
+			r.words[i] = token.Text
+		}
+		ids[task.ID] = true
+	}
+}
+
+// unindexTask removes the words of a task's title from the inverted index
+func (r *taskMemoryRepository) unindexTask(task domain.Task) {
+	for _, token := range domain.Tokenize(task.Title) {
+		ids, ok := r.index[token.Text]
+		if !ok {
+			continue
+		}
+
+		delete(ids, task.ID)
+		if len(ids) == 0 {
+			delete(r.index, token.Text)
+
+			i := sort.SearchStrings(r.words, token.Text)
+			r.words = append(r.words[:i], r.words[i+1:]...)
+		}
+	}
+}
+
+// replacedTags swaps any of the old tags for the new one, keeping the tags sorted
+func replacedTags(tags []string, from []string, to string) []string {
+	replaced := []string{to}
+	for _, tag := range tags {
+		if tag != to && !slices.Contains(from, tag) {
+			replaced = append(replaced, tag)
+		}
+	}
+	sort.Strings(replaced)
+
+	return replaced
+}
+
+func sortTasksByID(tasks []domain.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+}
+
+// cloneIDs copies a list of IDs so a stored task does not share it with the caller
+func cloneIDs(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return append([]string{}, ids...)
+}
+
+// matchesTaskQuery reports whether a task passes the query filters
+func matchesTaskQuery(task domain.Task, query domain.TaskQuery) bool {
+	if query.OwnerID != "" && task.OwnerID != query.OwnerID {
+		return false
+	}
+
+	if query.ProjectID != "" && task.ProjectID != query.ProjectID {
+		return false
+	}
+
+	if query.Visibility != nil && !query.Visibility.Includes(task) {
+		return false
+	}
+
+	if query.Status != "" && task.Status != query.Status {
+		return false
+	}
+
+	if query.Statuses != nil && !slices.Contains(query.Statuses, task.Status) {
+		return false
+	}
+
+	if !query.DueAfter.IsZero() && task.DueDate.Before(query.DueAfter) {
+		return false
+	}
+
+	if !query.DueBefore.IsZero() && !task.DueDate.Before(query.DueBefore) {
+		return false
+	}
+
+	if !task.HasTags(query.Tags) {
+		return false
+	}
+
+	return strings.HasPrefix(task.Title, query.TitlePrefix)
+}
+
+// compareTasks orders two tasks by the sort field, breaking ties by ID
+func compareTasks(a, b domain.Task, field string, descending bool) int {
+	var result int
+	switch field {
+	case "title":
+		result = strings.Compare(a.Title, b.Title)
+	default:
+		result = a.DueDate.Compare(b.DueDate)
+	}
+
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+
+	if descending {
+		return -result
+	}
+	return result
+}
@@ -2,26 +2,62 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	domain "task-manager/Domain"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// TaskRepository interface
+// TaskRepository interface. Every task carries a version that starts at 1 and is
+// incremented by each update; updating with an outdated version fails with a ConflictError.
 type TaskRepository interface {
-	CreateTask(task domain.Task) error
-	GetTask(id string) (domain.Task, error)
-	GetTasks() ([]domain.Task, error)
-	UpdateTask(id string, task domain.Task) error
-	DeleteTask(id string) error
+	CreateTask(ctx context.Context, task domain.Task) (domain.Task, error)
+	GetTask(ctx context.Context, id string) (domain.Task, error)
+	GetTasks(ctx context.Context, query domain.TaskQuery) (domain.TaskPage, error)
+	UpdateTask(ctx context.Context, id string, version int64, task domain.Task) (domain.Task, error)
+	DeleteTask(ctx context.Context, id string) error
+	SearchTasks(ctx context.Context, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error)
+	GetTasksByIDs(ctx context.Context, ids []string) ([]domain.Task, error)
+	GetSubtasks(ctx context.Context, parentIDs []string) ([]domain.Task, error)
+	GetTasksByTitles(ctx context.Context, ownerID string, titles []string) ([]domain.Task, error)
+	// GetTagCounts counts the owner's tasks carrying each of their tags
+	GetTagCounts(ctx context.Context, ownerID string) (map[string]int, error)
+	// ReplaceTags swaps the given tags for another one on every task of the owner that has
+	// any of them, giving each a new version, and returns the changed tasks
+	ReplaceTags(ctx context.Context, ownerID string, from []string, to string) ([]domain.Task, error)
+	// GetUnassignedTaskOwners lists the owners of tasks that have no project, leaving out
+	// tasks that have no owner either
+	GetUnassignedTaskOwners(ctx context.Context) ([]string, error)
+	// AssignProject moves the owner's tasks that have no project into the project, giving
+	// each a new version, and returns how many were moved
+	AssignProject(ctx context.Context, ownerID, projectID string) (int64, error)
+	// AddAttachment adds an attachment to a task that has fewer than the most allowed,
+	// giving it a new version
+	AddAttachment(ctx context.Context, taskID string, attachment domain.Attachment) (domain.Task, error)
+	// RemoveAttachment removes an attachment from a task, giving it a new version
+	RemoveAttachment(ctx context.Context, taskID, attachmentID string) (domain.Task, error)
+	// WriteTasks applies a batch of writes in order and returns the outcome of each.
+	// Creates keep an ID they were given so later writes can refer to them. An atomic
+	// batch stops at the first failed write, undoes the writes before it and returns
+	// that write's error; the writes after it have no outcome.
+	WriteTasks(ctx context.Context, writes []domain.TaskWrite, atomic bool) ([]domain.TaskWriteResult, error)
 }
 
 // taskRepository struct
 type taskRepository struct {
 	db         *mongo.Database
 	collection string
+
+	mu      sync.Mutex
+	indexed bool
 }
 
 // NewTaskRepository creates a new task repository
@@ -29,20 +65,53 @@ func NewTaskRepository(database *mongo.Database, collection string) TaskReposito
 	return &taskRepository{db: database, collection: collection}
 }
 
-// CreateTask creates a new task
-func (r *taskRepository) CreateTask(task domain.Task) error {
-	task.ID = ""
-	_, err := r.db.Collection(r.collection).InsertOne(context.TODO(), task)
+// ensureIndexes creates the indexes used to find subtasks and a project's tasks, the text
+// index used to search task titles and the multikey index used to filter tasks by tag. The text index has no
+// language so words are matched as they are written, without stemming or stop words.
+func (r *taskRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
 
+	_, err := r.db.Collection(r.collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+		{Keys: bson.D{{Key: "project_id", Value: 1}}},
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "tags", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "title", Value: "text"}},
+			Options: options.Index().SetDefaultLanguage("none"),
+		},
+	})
 	if err != nil {
-		return &domain.InternalServerError{Message: "Error creating task"}
+		return databaseError(err, "Error creating task indexes")
 	}
 
+	r.indexed = true
 	return nil
 }
 
+// CreateTask creates a new task and returns it with its assigned ID
+func (r *taskRepository) CreateTask(ctx context.Context, task domain.Task) (domain.Task, error) {
+	task.ID = ""
+	task.Version = 1
+	result, err := r.db.Collection(r.collection).InsertOne(ctx, task)
+
+	if err != nil {
+		return domain.Task{}, databaseError(err, "Error creating task")
+	}
+
+	if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+		task.ID = objId.Hex()
+	}
+
+	return task, nil
+}
+
 // GetTask retrieves a task by ID
-func (r *taskRepository) GetTask(id string) (domain.Task, error) {
+func (r *taskRepository) GetTask(ctx context.Context, id string) (domain.Task, error) {
 	objId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
@@ -50,75 +119,216 @@ func (r *taskRepository) GetTask(id string) (domain.Task, error) {
 
 	filter := bson.M{"_id": objId}
 	var task domain.Task
-	err = r.db.Collection(r.collection).FindOne(context.TODO(), filter).Decode(&task)
+	err = r.db.Collection(r.collection).FindOne(ctx, filter).Decode(&task)
 
 	if err == mongo.ErrNoDocuments {
 		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
 	}
 
 	if err != nil {
-		return domain.Task{}, &domain.InternalServerError{Message: "Error retriving task"}
+		return domain.Task{}, databaseError(err, "Error retriving task")
 	}
 
 	return task, nil
 }
 
-// GetTasks retrieves all tasks
-func (r *taskRepository) GetTasks() ([]domain.Task, error) {
-	cursor, err := r.db.Collection(r.collection).Find(context.TODO(), bson.M{})
+// GetTasks retrieves one page of tasks matching the query
+func (r *taskRepository) GetTasks(ctx context.Context, query domain.TaskQuery) (domain.TaskPage, error) {
+	if len(query.Tags) > 0 || query.ProjectID != "" || query.Visibility != nil {
+		if err := r.ensureIndexes(ctx); err != nil {
+			return domain.TaskPage{}, err
+		}
+	}
+
+	filter := bson.M{}
+	if query.OwnerID != "" {
+		filter["owner_id"] = query.OwnerID
+	}
+	if query.ProjectID != "" {
+		filter["project_id"] = query.ProjectID
+	}
 
-	if cursor.RemainingBatchLength() == 0 {
-		return nil, &domain.NotFoundError{Message: "Tasks not found"}
+	status := bson.M{}
+	if query.Status != "" {
+		status["$eq"] = query.Status
+	}
+	if query.Statuses != nil {
+		status["$in"] = query.Statuses
+	}
+	if len(status) > 0 {
+		filter["status"] = status
 	}
 
+	dueDate := bson.M{}
+	if !query.DueAfter.IsZero() {
+		dueDate["$gte"] = query.DueAfter
+	}
+	if !query.DueBefore.IsZero() {
+		dueDate["$lt"] = query.DueBefore
+	}
+	if len(dueDate) > 0 {
+		filter["due_date"] = dueDate
+	}
+
+	if query.TitlePrefix != "" {
+		filter["title"] = bson.M{"$regex": "^" + regexp.QuoteMeta(query.TitlePrefix)}
+	}
+
+	conditions := bson.A{}
+	for _, group := range query.Tags {
+		conditions = append(conditions, bson.M{"tags": bson.M{"$in": group}})
+	}
+	if query.Visibility != nil {
+		conditions = append(conditions, visibilityFilter(*query.Visibility))
+	}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	field := sortField(query)
+	direction, operator := 1, "$gt"
+	if query.Descending {
+		direction, operator = -1, "$lt"
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeTaskCursor(query)
+		if err != nil {
+			return domain.TaskPage{}, err
+		}
+
+		objId, err := primitive.ObjectIDFromHex(cursor.ID)
+		if err != nil {
+			return domain.TaskPage{}, &domain.BadRequestError{Message: "Invalid cursor"}
+		}
+
+		var value interface{} = cursor.DueDate
+		if field == "title" {
+			value = cursor.Title
+		}
+
+		filter["$or"] = bson.A{
+			bson.M{field: bson.M{operator: value}},
+			bson.M{field: value, "_id": bson.M{operator: objId}},
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = domain.DefaultTaskPageSize
+	}
+
+	// fetch one extra task to find out whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, opts)
 	if err != nil {
-		return nil, &domain.InternalServerError{Message: "Error retrieving tasks"}
+		return domain.TaskPage{}, databaseError(err, "Error retrieving tasks")
 	}
 
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
-	var tasks []domain.Task
-	for cursor.Next(context.TODO()) {
-		var task domain.Task
-		cursor.Decode(&task)
-		tasks = append(tasks, task)
+	tasks := []domain.Task{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return domain.TaskPage{}, databaseError(err, "Error retrieving tasks")
 	}
 
-	return tasks, nil
+	page := domain.TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor = encodeTaskCursor(query, page.Tasks[limit-1])
+	}
+
+	return page, nil
 }
 
-// UpdateTask updates a task
-func (r *taskRepository) UpdateTask(id string, task domain.Task) error {
+// UpdateTask updates a task if it is still at the given version and returns it with its new version
+func (r *taskRepository) UpdateTask(ctx context.Context, id string, version int64, task domain.Task) (domain.Task, error) {
 	objId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return &domain.BadRequestError{Message: "Invalid ID"}
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
 	}
 
-	filter := bson.M{"_id": objId}
+	filter := bson.M{"_id": objId, "version": version}
+	if version == 0 {
+		// tasks stored before versioning have no version field
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"title":    task.Title,
-			"due_date": task.DueDate,
-			"status":   task.Status,
-		},
+	set := bson.M{
+		"title":    task.Title,
+		"due_date": task.DueDate,
+		"status":   task.Status,
+	}
+	unset := bson.M{}
+	if task.ProjectID != "" {
+		set["project_id"] = task.ProjectID
+	} else {
+		unset["project_id"] = ""
+	}
+	if task.ParentID != "" {
+		set["parent_id"] = task.ParentID
+	} else {
+		unset["parent_id"] = ""
+	}
+	if len(task.DependsOn) > 0 {
+		set["depends_on"] = task.DependsOn
+	} else {
+		unset["depends_on"] = ""
+	}
+	if len(task.Tags) > 0 {
+		set["tags"] = task.Tags
+	} else {
+		unset["tags"] = ""
+	}
+	if task.Recurrence != "" {
+		set["recurrence"] = task.Recurrence
+		set["occurrence"] = task.Occurrence
+	} else {
+		unset["recurrence"] = ""
+		unset["occurrence"] = ""
+	}
+	if task.NextID != "" {
+		set["next_id"] = task.NextID
+	} else {
+		unset["next_id"] = ""
 	}
 
-	updateResult, err := r.db.Collection(r.collection).UpdateOne(context.TODO(), filter, update)
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
 
-	if err != nil {
-		return &domain.InternalServerError{Message: "Error updating task"}
+	collection := r.db.Collection(r.collection)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated domain.Task
+	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		// tell a missing task apart from one that has moved on to another version
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": objId})
+		if err != nil {
+			return domain.Task{}, databaseError(err, "Error updating task")
+		}
+
+		if count == 0 {
+			return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+		}
+
+		return domain.Task{}, &domain.ConflictError{Message: "Task has been modified since it was read"}
 	}
 
-	if updateResult.MatchedCount == 0 {
-		return &domain.NotFoundError{Message: "Task not found"}
+	if err != nil {
+		return domain.Task{}, databaseError(err, "Error updating task")
 	}
 
-	return nil
+	return updated, nil
 }
 
 // DeleteTask deletes a task
-func (r *taskRepository) DeleteTask(id string) error {
+func (r *taskRepository) DeleteTask(ctx context.Context, id string) error {
 	objId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return &domain.BadRequestError{Message: "Invalid ID"}
@@ -126,10 +336,10 @@ func (r *taskRepository) DeleteTask(id string) error {
 
 	filter := bson.M{"_id": objId}
 
-	deleteResult, err := r.db.Collection(r.collection).DeleteOne(context.TODO(), filter)
+	deleteResult, err := r.db.Collection(r.collection).DeleteOne(ctx, filter)
 
 	if err != nil {
-		return &domain.InternalServerError{Message: "Error deleting task"}
+		return databaseError(err, "Error deleting task")
 	}
 
 	if deleteResult.DeletedCount == 0 {
@@ -138,3 +348,532 @@ func (r *taskRepository) DeleteTask(id string) error {
 
 	return nil
 }
+
+// GetTasksByIDs retrieves the tasks with the given IDs; IDs without a task are skipped
+func (r *taskRepository) GetTasksByIDs(ctx context.Context, ids []string) ([]domain.Task, error) {
+	objIds, err := objectIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.findTasks(ctx, bson.M{"_id": bson.M{"$in": objIds}})
+}
+
+// GetSubtasks retrieves the direct subtasks of the given tasks
+func (r *taskRepository) GetSubtasks(ctx context.Context, parentIDs []string) ([]domain.Task, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.findTasks(ctx, bson.M{"parent_id": bson.M{"$in": parentIDs}})
+}
+
+// GetTasksByTitles retrieves the owner's tasks with any of the given titles
+func (r *taskRepository) GetTasksByTitles(ctx context.Context, ownerID string, titles []string) ([]domain.Task, error) {
+	return r.findTasks(ctx, bson.M{"owner_id": ownerID, "title": bson.M{"$in": titles}})
+}
+
+// errBatchFailed aborts the transaction of an atomic batch in which a write failed
+var errBatchFailed = errors.New("batch failed")
+
+// GetTagCounts counts the owner's tasks carrying each of their tags
+func (r *taskRepository) GetTagCounts(ctx context.Context, ownerID string) (map[string]int, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"owner_id": ownerID, "tags.0": bson.M{"$exists": true}}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := r.db.Collection(r.collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, databaseError(err, "Error counting tags")
+	}
+
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Tag   string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, databaseError(err, "Error counting tags")
+	}
+
+	counts := make(map[string]int, len(groups))
+	for _, group := range groups {
+		counts[group.Tag] = group.Count
+	}
+	return counts, nil
+}
+
+// GetUnassignedTaskOwners lists the owners of tasks stored without a project
+func (r *taskRepository) GetUnassignedTaskOwners(ctx context.Context) ([]string, error) {
+	filter := bson.M{"project_id": bson.M{"$exists": false}, "owner_id": bson.M{"$exists": true, "$ne": ""}}
+	values, err := r.db.Collection(r.collection).Distinct(ctx, "owner_id", filter)
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving task owners")
+	}
+
+	owners := make([]string, 0, len(values))
+	for _, value := range values {
+		if owner, ok := value.(string); ok {
+			owners = append(owners, owner)
+		}
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+// AssignProject sets the project of the owner's tasks stored without one
+func (r *taskRepository) AssignProject(ctx context.Context, ownerID, projectID string) (int64, error) {
+	filter := bson.M{"owner_id": ownerID, "project_id": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"project_id": projectID}, "$inc": bson.M{"version": 1}}
+
+	result, err := r.db.Collection(r.collection).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, databaseError(err, "Error assigning tasks to a project")
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// maxTagAttempts bounds how often a tag change to a task is retried when another change to
+// it lands first
+const maxTagAttempts = 3
+
+// ReplaceTags looks up the owner's tasks that have any of the old tags and swaps them for
+// the new one, keeping each task's tags sorted. Each task is updated on its own against
+// the version that was read, as UpdateTask does, so a change that lands in between is
+// never overwritten.
+func (r *taskRepository) ReplaceTags(ctx context.Context, ownerID string, from []string, to string) ([]domain.Task, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	tagged, err := r.findTasks(ctx, bson.M{"owner_id": ownerID, "tags": bson.M{"$in": from}})
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []domain.Task{}
+	for _, task := range tagged {
+		updated, ok, err := r.replaceTaskTags(ctx, task, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			changed = append(changed, updated)
+		}
+	}
+
+	return changed, nil
+}
+
+// replaceTaskTags swaps the tags of a single task. When the task has moved on to another
+// version it is read again and the swap retried, and it reports false once the task is
+// gone or no longer has any of the old tags.
+func (r *taskRepository) replaceTaskTags(ctx context.Context, task domain.Task, from []string, to string) (domain.Task, bool, error) {
+	objId, err := primitive.ObjectIDFromHex(task.ID)
+	if err != nil {
+		return domain.Task{}, false, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	collection := r.db.Collection(r.collection)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	for attempt := 1; ; attempt++ {
+		filter := bson.M{"_id": objId, "version": task.Version}
+		if task.Version == 0 {
+			// tasks stored before versioning have no version field
+			filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		}
+		update := bson.M{"$set": bson.M{"tags": replacedTags(task.Tags, from, to)}, "$inc": bson.M{"version": 1}}
+
+		var updated domain.Task
+		err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+		if err == nil {
+			return updated, true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return domain.Task{}, false, databaseError(err, "Error replacing tags")
+		}
+		if attempt == maxTagAttempts {
+			return domain.Task{}, false, &domain.ConflictError{Message: "Task has been modified since it was read"}
+		}
+
+		task = domain.Task{}
+		err = collection.FindOne(ctx, bson.M{"_id": objId}).Decode(&task)
+		if err == mongo.ErrNoDocuments {
+			return domain.Task{}, false, nil
+		}
+		if err != nil {
+			return domain.Task{}, false, databaseError(err, "Error replacing tags")
+		}
+		if !task.HasTags([][]string{from}) {
+			return domain.Task{}, false, nil
+		}
+	}
+}
+
+// AddAttachment pushes the attachment onto the task unless it already has the most
+// allowed; the limit is part of the filter so concurrent uploads cannot exceed it
+func (r *taskRepository) AddAttachment(ctx context.Context, taskID string, attachment domain.Attachment) (domain.Task, error) {
+	objId, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	filter := bson.M{"_id": objId, fmt.Sprintf("attachments.%d", domain.MaxTaskAttachments-1): bson.M{"$exists": false}}
+	update := bson.M{"$push": bson.M{"attachments": attachment}, "$inc": bson.M{"version": 1}}
+
+	var task domain.Task
+	err = r.db.Collection(r.collection).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		if _, err := r.GetTask(ctx, taskID); err != nil {
+			return domain.Task{}, err
+		}
+		return domain.Task{}, &domain.BadRequestError{Message: fmt.Sprintf("A task can have at most %d attachments", domain.MaxTaskAttachments)}
+	}
+
+	if err != nil {
+		return domain.Task{}, databaseError(err, "Error adding attachment")
+	}
+
+	return task, nil
+}
+
+// RemoveAttachment pulls the attachment from the task
+func (r *taskRepository) RemoveAttachment(ctx context.Context, taskID, attachmentID string) (domain.Task, error) {
+	objId, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return domain.Task{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	filter := bson.M{"_id": objId, "attachments.id": attachmentID}
+	update := bson.M{"$pull": bson.M{"attachments": bson.M{"id": attachmentID}}, "$inc": bson.M{"version": 1}}
+
+	var task domain.Task
+	err = r.db.Collection(r.collection).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		if _, err := r.GetTask(ctx, taskID); err != nil {
+			return domain.Task{}, err
+		}
+		return domain.Task{}, &domain.NotFoundError{Message: "Attachment not found"}
+	}
+
+	if err != nil {
+		return domain.Task{}, databaseError(err, "Error removing attachment")
+	}
+
+	return task, nil
+}
+
+// WriteTasks applies the writes in order, inserting consecutive creates with a single
+// request. An atomic batch runs in a transaction, which needs a replica set.
+func (r *taskRepository) WriteTasks(ctx context.Context, writes []domain.TaskWrite, atomic bool) ([]domain.TaskWriteResult, error) {
+	if !atomic {
+		results, _ := r.applyWrites(ctx, writes, false)
+		return results, nil
+	}
+
+	supported, err := r.supportsTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !supported {
+		return nil, &domain.InternalServerError{Message: "All-or-nothing batches need MongoDB to run as a replica set"}
+	}
+
+	var results []domain.TaskWriteResult
+	err = r.db.Client().UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			var failed bool
+			if results, failed = r.applyWrites(sc, writes, true); failed {
+				return nil, errBatchFailed
+			}
+			return nil, nil
+		})
+		return err
+	})
+
+	if errors.Is(err, errBatchFailed) {
+		for _, result := range results {
+			if result.Err != nil {
+				return results, result.Err
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, databaseError(err, "Error writing tasks")
+	}
+
+	return results, nil
+}
+
+// supportsTransactions reports whether the server is a replica set member or a mongos;
+// a standalone server cannot run transactions
+func (r *taskRepository) supportsTransactions(ctx context.Context) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	if err := r.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, databaseError(err, "Error writing tasks")
+	}
+
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// applyWrites applies the writes in order and reports whether any of them failed. When
+// stopOnFailure is set the first failure ends the batch.
+func (r *taskRepository) applyWrites(ctx context.Context, writes []domain.TaskWrite, stopOnFailure bool) ([]domain.TaskWriteResult, bool) {
+	results := make([]domain.TaskWriteResult, len(writes))
+	failed := false
+
+	for i := 0; i < len(writes); {
+		if writes[i].Op == domain.BatchCreate {
+			end := i + 1
+			for end < len(writes) && writes[end].Op == domain.BatchCreate {
+				end++
+			}
+
+			if r.insertTasks(ctx, writes[i:end], results[i:end], stopOnFailure) {
+				failed = true
+				if stopOnFailure {
+					return results, true
+				}
+			}
+
+			i = end
+			continue
+		}
+
+		switch writes[i].Op {
+		case domain.BatchUpdate:
+			results[i].Task, results[i].Err = r.UpdateTask(ctx, writes[i].ID, writes[i].Version, writes[i].Task)
+		case domain.BatchDelete:
+			results[i].Err = r.DeleteTask(ctx, writes[i].ID)
+		default:
+			results[i].Err = &domain.BadRequestError{Message: "Unknown write " + writes[i].Op}
+		}
+
+		if results[i].Err != nil {
+			failed = true
+			if stopOnFailure {
+				return results, true
+			}
+		}
+		i++
+	}
+
+	return results, failed
+}
+
+// insertTasks creates the tasks with one request, recording the outcome of each in
+// results, and reports whether any of them failed. Ordered inserts stop at the first failure.
+func (r *taskRepository) insertTasks(ctx context.Context, writes []domain.TaskWrite, results []domain.TaskWriteResult, ordered bool) bool {
+	documents := make([]interface{}, 0, len(writes))
+	positions := make([]int, 0, len(writes))
+	failed := false
+
+	for i, write := range writes {
+		task := write.Task
+		task.Version = 1
+
+		objId := primitive.NewObjectID()
+		if task.ID != "" {
+			var err error
+			if objId, err = primitive.ObjectIDFromHex(task.ID); err != nil {
+				results[i].Err = &domain.BadRequestError{Message: "Invalid ID"}
+				failed = true
+				if ordered {
+					return true
+				}
+				continue
+			}
+		}
+
+		document, err := taskDocument(objId, task)
+		if err != nil {
+			results[i].Err = &domain.InternalServerError{Message: "Error creating task"}
+			failed = true
+			if ordered {
+				return true
+			}
+			continue
+		}
+
+		task.ID = objId.Hex()
+		results[i].Task = task
+		documents = append(documents, document)
+		positions = append(positions, i)
+	}
+
+	if len(documents) == 0 {
+		return failed
+	}
+
+	_, err := r.db.Collection(r.collection).InsertMany(ctx, documents, options.InsertMany().SetOrdered(ordered))
+	if err == nil {
+		return failed
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		for _, position := range positions {
+			results[position] = domain.TaskWriteResult{Err: databaseError(err, "Error creating task")}
+		}
+		return true
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Index < len(positions) {
+			results[positions[writeErr.Index]] = domain.TaskWriteResult{Err: &domain.InternalServerError{Message: "Error creating task"}}
+		}
+	}
+
+	if ordered {
+		// an ordered insert stops at its first failure, leaving the rest unwritten
+		first := bulkErr.WriteErrors[0].Index
+		for _, position := range positions[min(first+1, len(positions)):] {
+			results[position] = domain.TaskWriteResult{}
+		}
+	}
+
+	return true
+}
+
+// taskDocument encodes a task for insertion under the given ObjectID
+func taskDocument(objId primitive.ObjectID, task domain.Task) (bson.D, error) {
+	task.ID = ""
+	data, err := bson.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	return append(bson.D{{Key: "_id", Value: objId}}, document...), nil
+}
+
+// findTasks retrieves every task matching the filter, ordered by ID
+func (r *taskRepository) findTasks(ctx context.Context, filter bson.M) ([]domain.Task, error) {
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving tasks")
+	}
+
+	defer cursor.Close(ctx)
+
+	tasks := []domain.Task{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, databaseError(err, "Error retrieving tasks")
+	}
+
+	return tasks, nil
+}
+
+// objectIDs converts hex task IDs to ObjectIDs
+func objectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objIds := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, &domain.BadRequestError{Message: "Invalid ID"}
+		}
+		objIds = append(objIds, objId)
+	}
+
+	return objIds, nil
+}
+
+// SearchTasks finds the tasks whose titles match the search, most relevant first.
+// The text index narrows the search to titles containing any of the words; regular
+// expressions then keep only titles with every word, prefix and phrase.
+func (r *taskRepository) SearchTasks(ctx context.Context, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error) {
+	terms, limit, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	conditions := bson.A{}
+	if query.OwnerID != "" {
+		conditions = append(conditions, bson.M{"owner_id": query.OwnerID})
+	}
+	if query.Visibility != nil {
+		conditions = append(conditions, visibilityFilter(*query.Visibility))
+	}
+
+	var search []string
+	for _, word := range terms.Words {
+		search = append(search, word)
+		conditions = append(conditions, titleRegex(regexp.QuoteMeta(word)+`(?![\p{L}\p{N}])`))
+	}
+
+	for _, phrase := range terms.Phrases {
+		words := make([]string, len(phrase))
+		for i, word := range phrase {
+			words[i] = regexp.QuoteMeta(word)
+		}
+
+		search = append(search, phrase...)
+		conditions = append(conditions, titleRegex(strings.Join(words, `[^\p{L}\p{N}]+`)+`(?![\p{L}\p{N}])`))
+	}
+
+	for _, prefix := range terms.Prefixes {
+		conditions = append(conditions, titleRegex(regexp.QuoteMeta(prefix)))
+	}
+
+	opts := options.Find().SetLimit(maxSearchCandidates)
+	if len(search) > 0 {
+		conditions = append(conditions, bson.M{"$text": bson.M{"$search": strings.Join(search, " ")}})
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).SetSort(bson.M{"score": bson.M{"$meta": "textScore"}})
+	}
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		return nil, databaseError(err, "Error searching tasks")
+	}
+
+	defer cursor.Close(ctx)
+
+	tasks := []domain.Task{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, databaseError(err, "Error searching tasks")
+	}
+
+	return rankSearchResults(terms, tasks, limit), nil
+}
+
+// titleRegex matches titles containing the pattern at the start of a word, ignoring case
+func titleRegex(pattern string) bson.M {
+	return bson.M{"title": primitive.Regex{Pattern: `(^|[^\p{L}\p{N}])` + pattern, Options: "i"}}
+}
+
+// visibilityFilter matches the tasks in the user's projects and, from before projects,
+// the tasks without a project that they own
+func visibilityFilter(visibility domain.TaskVisibility) bson.M {
+	projectIDs := visibility.ProjectIDs
+	if projectIDs == nil {
+		projectIDs = []string{}
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{"project_id": bson.M{"$in": projectIDs}},
+		bson.M{"project_id": bson.M{"$exists": false}, "owner_id": visibility.UserID},
+	}}
+}
@@ -0,0 +1,857 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskRepositoryContractSuite checks the behaviour every TaskRepository backend must share
+type TaskRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() TaskRepository
+	repo          TaskRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *TaskRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestTaskRepositoryContract_Memory runs the contract against the in-memory backend
+func TestTaskRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &TaskRepositoryContractSuite{newRepository: NewTaskMemoryRepository})
+}
+
+// TestTaskRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestTaskRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &TaskRepositoryContractSuite{newRepository: func() TaskRepository {
+		db.Collection("tasks").Drop(context.Background())
+		return NewTaskRepository(db, "tasks")
+	}})
+}
+
+// createTask stores a task and returns it with the ID assigned by the repository
+func (suite *TaskRepositoryContractSuite) createTask(task domain.Task) domain.Task {
+	created, err := suite.repo.CreateTask(context.Background(), task)
+	suite.Require().NoError(err)
+
+	return created
+}
+
+func (suite *TaskRepositoryContractSuite) TestCreateAndGetTask() {
+	created := suite.createTask(domain.Task{Title: "Contract Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	assert.True(suite.T(), primitive.IsValidObjectID(created.ID))
+	assert.Equal(suite.T(), "Contract Task", created.Title)
+	assert.Equal(suite.T(), int64(1), created.Version)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Contract Task", task.Title)
+	assert.Equal(suite.T(), "pending", task.Status)
+}
+
+func (suite *TaskRepositoryContractSuite) TestCreateTask_IgnoresGivenID() {
+	given := primitive.NewObjectID().Hex()
+	created := suite.createTask(domain.Task{ID: given, Title: "Contract Task", DueDate: time.Now(), Status: "pending"})
+	assert.NotEqual(suite.T(), given, created.ID)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_OwnerFilter() {
+	suite.createTask(domain.Task{Title: "Mine", DueDate: time.Now(), Status: "pending", OwnerID: "owner-id"})
+	suite.createTask(domain.Task{Title: "Theirs", DueDate: time.Now(), Status: "pending", OwnerID: "other-id"})
+
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{OwnerID: "owner-id"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 1)
+	assert.Equal(suite.T(), "Mine", page.Tasks[0].Title)
+	assert.Equal(suite.T(), "owner-id", page.Tasks[0].OwnerID)
+
+	page, err = suite.repo.GetTasks(context.Background(), domain.TaskQuery{})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 2)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_KeepsOwner() {
+	created := suite.createTask(domain.Task{Title: "Mine", DueDate: time.Now(), Status: "pending", OwnerID: "owner-id"})
+
+	_, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "Mine", DueDate: time.Now(), Status: "completed"})
+	assert.NoError(suite.T(), err)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "owner-id", task.OwnerID)
+	assert.Equal(suite.T(), "completed", task.Status)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTask_InvalidID() {
+	_, err := suite.repo.GetTask(context.Background(), "invalid")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTask_NotFound() {
+	_, err := suite.repo.GetTask(context.Background(), primitive.NewObjectID().Hex())
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_Empty() {
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{})
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), page.Tasks)
+	assert.Empty(suite.T(), page.Tasks)
+	assert.Empty(suite.T(), page.NextCursor)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_Filters() {
+	now := time.Now().Truncate(time.Millisecond)
+	suite.createTask(domain.Task{Title: "Sprint planning", DueDate: now.Add(24 * time.Hour), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Sprint review", DueDate: now.Add(72 * time.Hour), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Retrospective", DueDate: now.Add(48 * time.Hour), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Sprint demo", DueDate: now.Add(-24 * time.Hour), Status: "completed"})
+
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{Status: "pending"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 3)
+
+	page, err = suite.repo.GetTasks(context.Background(), domain.TaskQuery{TitlePrefix: "Sprint", DueAfter: now, DueBefore: now.Add(72 * time.Hour)})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 1)
+	assert.Equal(suite.T(), "Sprint planning", page.Tasks[0].Title)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_StatusesFilter() {
+	now := time.Now().Truncate(time.Millisecond)
+	suite.createTask(domain.Task{Title: "Pending", DueDate: now.Add(time.Hour), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Started", DueDate: now.Add(2 * time.Hour), Status: "in_progress"})
+	suite.createTask(domain.Task{Title: "Done", DueDate: now.Add(3 * time.Hour), Status: "completed"})
+
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{Statuses: []string{"pending", "in_progress"}, SortBy: "due_date"})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(page.Tasks, 2)
+	assert.Equal(suite.T(), []string{"Pending", "Started"}, []string{page.Tasks[0].Title, page.Tasks[1].Title})
+
+	page, err = suite.repo.GetTasks(context.Background(), domain.TaskQuery{Status: "in_progress", Statuses: []string{"pending", "in_progress"}})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 1)
+
+	page, err = suite.repo.GetTasks(context.Background(), domain.TaskQuery{Statuses: []string{}})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), page.Tasks)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_ProjectFilters() {
+	now := time.Now().Truncate(time.Millisecond)
+	suite.createTask(domain.Task{Title: "Shared", DueDate: now.Add(time.Hour), Status: "pending", OwnerID: "other-id", ProjectID: "project-1"})
+	suite.createTask(domain.Task{Title: "Elsewhere", DueDate: now.Add(2 * time.Hour), Status: "pending", OwnerID: "owner-id", ProjectID: "project-2"})
+	suite.createTask(domain.Task{Title: "Legacy", DueDate: now.Add(3 * time.Hour), Status: "pending", OwnerID: "owner-id"})
+	suite.createTask(domain.Task{Title: "Legacy of another", DueDate: now.Add(4 * time.Hour), Status: "pending", OwnerID: "other-id"})
+
+	titles := func(query domain.TaskQuery) []string {
+		query.SortBy = "due_date"
+		page, err := suite.repo.GetTasks(context.Background(), query)
+		suite.Require().NoError(err)
+
+		titles := []string{}
+		for _, task := range page.Tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	visibility := &domain.TaskVisibility{UserID: "owner-id", ProjectIDs: []string{"project-1"}}
+	assert.Equal(suite.T(), []string{"Shared", "Legacy"}, titles(domain.TaskQuery{Visibility: visibility}))
+	assert.Equal(suite.T(), []string{"Shared"}, titles(domain.TaskQuery{Visibility: visibility, ProjectID: "project-1"}))
+	assert.Empty(suite.T(), titles(domain.TaskQuery{Visibility: visibility, ProjectID: "project-2"}))
+	assert.Equal(suite.T(), []string{"Legacy"}, titles(domain.TaskQuery{Visibility: &domain.TaskVisibility{UserID: "owner-id"}}))
+
+	// the visibility and the cursor are both alternatives, so they must not replace each other
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{Visibility: visibility, SortBy: "due_date", Limit: 1})
+	suite.Require().NoError(err)
+	page, err = suite.repo.GetTasks(context.Background(), domain.TaskQuery{Visibility: visibility, SortBy: "due_date", Limit: 1, Cursor: page.NextCursor})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Tasks, 1)
+	assert.Equal(suite.T(), "Legacy", page.Tasks[0].Title)
+	assert.Empty(suite.T(), page.NextCursor)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_Project() {
+	created := suite.createTask(domain.Task{Title: "Movable", DueDate: time.Now().Add(time.Hour), Status: "pending", OwnerID: "owner-id", ProjectID: "project-1"})
+
+	moved := created
+	moved.ProjectID = "project-2"
+	updated, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, moved)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "project-2", updated.ProjectID)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "project-2", task.ProjectID)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_TitlePrefixIsLiteral() {
+	suite.createTask(domain.Task{Title: "a.b", DueDate: time.Now(), Status: "pending"})
+	suite.createTask(domain.Task{Title: "axb", DueDate: time.Now(), Status: "pending"})
+
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{TitlePrefix: "a."})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 1)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_Pagination() {
+	due := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		// equal due dates make the ID tie-breaker decide the order
+		suite.createTask(domain.Task{Title: fmt.Sprintf("Task %d", i), DueDate: due, Status: "pending"})
+	}
+
+	for _, descending := range []bool{false, true} {
+		query := domain.TaskQuery{SortBy: "due_date", Descending: descending, Limit: 2}
+		var ids []string
+		for pages := 0; ; pages++ {
+			suite.Require().Less(pages, 5)
+
+			page, err := suite.repo.GetTasks(context.Background(), query)
+			suite.Require().NoError(err)
+			for _, task := range page.Tasks {
+				ids = append(ids, task.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		assert.Len(suite.T(), ids, 5)
+		for i := 1; i < len(ids); i++ {
+			if descending {
+				assert.Greater(suite.T(), ids[i-1], ids[i])
+			} else {
+				assert.Less(suite.T(), ids[i-1], ids[i])
+			}
+		}
+	}
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_InvalidCursor() {
+	_, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{Cursor: "%%%"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask() {
+	created := suite.createTask(domain.Task{Title: "Contract Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	updated, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "Updated Task", DueDate: time.Now().Add(-time.Hour), Status: "completed"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created.ID, updated.ID)
+	assert.Equal(suite.T(), int64(2), updated.Version)
+	assert.Equal(suite.T(), "Updated Task", updated.Title)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created.ID, task.ID)
+	assert.Equal(suite.T(), "Updated Task", task.Title)
+	assert.Equal(suite.T(), "completed", task.Status)
+	assert.Equal(suite.T(), int64(2), task.Version)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_Errors() {
+	_, err := suite.repo.UpdateTask(context.Background(), "invalid", 1, domain.Task{Title: "Task"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.repo.UpdateTask(context.Background(), primitive.NewObjectID().Hex(), 1, domain.Task{Title: "Task"})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_StaleVersion() {
+	created := suite.createTask(domain.Task{Title: "Contract Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	_, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "First", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "Second", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "First", task.Title)
+}
+
+func (suite *TaskRepositoryContractSuite) TestConcurrentUpdates() {
+	created := suite.createTask(domain.Task{Title: "Contract Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, conflicts := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: fmt.Sprintf("Task %d", i), DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := err.(*domain.ConflictError); ok {
+				conflicts++
+			} else if assert.NoError(suite.T(), err) {
+				succeeded++
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(suite.T(), 1, succeeded)
+	assert.Equal(suite.T(), 9, conflicts)
+}
+
+func (suite *TaskRepositoryContractSuite) TestDeleteTask() {
+	created := suite.createTask(domain.Task{Title: "Contract Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	assert.NoError(suite.T(), suite.repo.DeleteTask(context.Background(), created.ID))
+
+	_, err := suite.repo.GetTask(context.Background(), created.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteTask(context.Background(), created.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestDeleteTask_InvalidID() {
+	err := suite.repo.DeleteTask(context.Background(), "invalid")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestConcurrentCreates() {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := suite.repo.CreateTask(context.Background(), domain.Task{Title: fmt.Sprintf("Task %02d", i), DueDate: time.Now(), Status: "pending"})
+			assert.NoError(suite.T(), err)
+		}(i)
+	}
+	wg.Wait()
+
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{Limit: domain.MaxTaskPageSize})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Tasks, 20)
+}
+
+func (suite *TaskRepositoryContractSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := suite.repo.CreateTask(ctx, domain.Task{Title: "Contract Task", DueDate: time.Now(), Status: "pending"})
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+
+	_, err = suite.repo.GetTasks(ctx, domain.TaskQuery{})
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+
+	_, err = suite.repo.SearchTasks(ctx, domain.TaskSearchQuery{Text: "sprint"})
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) searchTitles(query domain.TaskSearchQuery) []string {
+	results, err := suite.repo.SearchTasks(context.Background(), query)
+	suite.Require().NoError(err)
+
+	titles := []string{}
+	for _, result := range results {
+		titles = append(titles, result.Task.Title)
+	}
+
+	return titles
+}
+
+func (suite *TaskRepositoryContractSuite) TestSearchTasks() {
+	suite.createTask(domain.Task{Title: "Sprint review", DueDate: time.Now(), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Prepare notes for the sprint review meeting", DueDate: time.Now(), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Review the sprint backlog", DueDate: time.Now(), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Sprint planning", DueDate: time.Now(), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Sprints are fun", DueDate: time.Now(), Status: "pending"})
+
+	// every word must appear, in any case and order, and shorter titles rank first
+	assert.Equal(suite.T(), []string{"Sprint review", "Review the sprint backlog", "Prepare notes for the sprint review meeting"}, suite.searchTitles(domain.TaskSearchQuery{Text: "REVIEW sprint"}))
+
+	assert.Equal(suite.T(), []string{"Sprint review", "Prepare notes for the sprint review meeting"}, suite.searchTitles(domain.TaskSearchQuery{Text: `"sprint review"`}))
+
+	assert.ElementsMatch(suite.T(), []string{"Sprint planning", "Sprints are fun", "Sprint review", "Review the sprint backlog", "Prepare notes for the sprint review meeting"}, suite.searchTitles(domain.TaskSearchQuery{Text: "sprint*"}))
+
+	assert.Equal(suite.T(), []string{"Sprint planning"}, suite.searchTitles(domain.TaskSearchQuery{Text: "sprint plan*"}))
+
+	assert.Empty(suite.T(), suite.searchTitles(domain.TaskSearchQuery{Text: "retrospective"}))
+}
+
+func (suite *TaskRepositoryContractSuite) TestSearchTasks_SnippetAndLimit() {
+	suite.createTask(domain.Task{Title: "Sprint review", DueDate: time.Now(), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Sprint review <draft>", DueDate: time.Now(), Status: "pending"})
+
+	results, err := suite.repo.SearchTasks(context.Background(), domain.TaskSearchQuery{Text: "review", Limit: 1})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(results, 1)
+	assert.Equal(suite.T(), "Sprint review", results[0].Task.Title)
+	assert.Equal(suite.T(), "Sprint <mark>review</mark>", results[0].Snippet)
+	assert.Greater(suite.T(), results[0].Score, 0.0)
+
+	results, err = suite.repo.SearchTasks(context.Background(), domain.TaskSearchQuery{Text: "draft"})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(results, 1)
+	assert.Equal(suite.T(), "Sprint review &lt;<mark>draft</mark>&gt;", results[0].Snippet)
+}
+
+func (suite *TaskRepositoryContractSuite) TestSearchTasks_OwnerFilter() {
+	suite.createTask(domain.Task{Title: "Sprint review", DueDate: time.Now(), Status: "pending", OwnerID: "owner-id"})
+	suite.createTask(domain.Task{Title: "Sprint review", DueDate: time.Now(), Status: "pending", OwnerID: "other-id"})
+
+	results, err := suite.repo.SearchTasks(context.Background(), domain.TaskSearchQuery{Text: "sprint", OwnerID: "owner-id"})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(results, 1)
+	assert.Equal(suite.T(), "owner-id", results[0].Task.OwnerID)
+}
+
+func (suite *TaskRepositoryContractSuite) TestSearchTasks_Visibility() {
+	suite.createTask(domain.Task{Title: "Sprint review", DueDate: time.Now(), Status: "pending", OwnerID: "other-id", ProjectID: "project-1"})
+	suite.createTask(domain.Task{Title: "Sprint planning", DueDate: time.Now(), Status: "pending", OwnerID: "other-id", ProjectID: "project-2"})
+	suite.createTask(domain.Task{Title: "Sprint retro", DueDate: time.Now(), Status: "pending", OwnerID: "owner-id"})
+
+	visibility := &domain.TaskVisibility{UserID: "owner-id", ProjectIDs: []string{"project-1"}}
+	assert.ElementsMatch(suite.T(), []string{"Sprint review", "Sprint retro"}, suite.searchTitles(domain.TaskSearchQuery{Text: "sprint", Visibility: visibility}))
+}
+
+func (suite *TaskRepositoryContractSuite) TestSearchTasks_FollowsUpdatesAndDeletes() {
+	created := suite.createTask(domain.Task{Title: "Sprint review", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	_, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "Quarterly planning", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	suite.Require().NoError(err)
+
+	assert.Empty(suite.T(), suite.searchTitles(domain.TaskSearchQuery{Text: "sprint"}))
+	assert.Equal(suite.T(), []string{"Quarterly planning"}, suite.searchTitles(domain.TaskSearchQuery{Text: "quarter*"}))
+
+	suite.Require().NoError(suite.repo.DeleteTask(context.Background(), created.ID))
+	assert.Empty(suite.T(), suite.searchTitles(domain.TaskSearchQuery{Text: "quarter*"}))
+}
+
+func (suite *TaskRepositoryContractSuite) TestSearchTasks_InvalidQuery() {
+	_, err := suite.repo.SearchTasks(context.Background(), domain.TaskSearchQuery{Text: `"sprint review`})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_Relations() {
+	parent := suite.createTask(domain.Task{Title: "Parent", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	dependency := suite.createTask(domain.Task{Title: "Dependency", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	created := suite.createTask(domain.Task{Title: "Child", DueDate: time.Now().Add(time.Hour), Status: "pending", ParentID: parent.ID, DependsOn: []string{dependency.ID}})
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), parent.ID, task.ParentID)
+	assert.Equal(suite.T(), []string{dependency.ID}, task.DependsOn)
+
+	_, err = suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "Child", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	suite.Require().NoError(err)
+
+	task, err = suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), task.ParentID)
+	assert.Empty(suite.T(), task.DependsOn)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasksByIDs() {
+	first := suite.createTask(domain.Task{Title: "First", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	second := suite.createTask(domain.Task{Title: "Second", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	suite.createTask(domain.Task{Title: "Third", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	tasks, err := suite.repo.GetTasksByIDs(context.Background(), []string{second.ID, primitive.NewObjectID().Hex(), first.ID, second.ID})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(tasks, 2)
+	assert.Equal(suite.T(), first.ID, tasks[0].ID)
+	assert.Equal(suite.T(), second.ID, tasks[1].ID)
+
+	tasks, err = suite.repo.GetTasksByIDs(context.Background(), []string{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), tasks)
+
+	_, err = suite.repo.GetTasksByIDs(context.Background(), []string{"invalid"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetSubtasks() {
+	first := suite.createTask(domain.Task{Title: "First", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	second := suite.createTask(domain.Task{Title: "Second", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	a := suite.createTask(domain.Task{Title: "A", DueDate: time.Now().Add(time.Hour), Status: "pending", ParentID: first.ID})
+	b := suite.createTask(domain.Task{Title: "B", DueDate: time.Now().Add(time.Hour), Status: "pending", ParentID: second.ID})
+	suite.createTask(domain.Task{Title: "C", DueDate: time.Now().Add(time.Hour), Status: "pending", ParentID: a.ID})
+
+	tasks, err := suite.repo.GetSubtasks(context.Background(), []string{first.ID, second.ID})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(tasks, 2)
+	assert.Equal(suite.T(), a.ID, tasks[0].ID)
+	assert.Equal(suite.T(), b.ID, tasks[1].ID)
+
+	tasks, err = suite.repo.GetSubtasks(context.Background(), []string{b.ID})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), tasks)
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_Recurrence() {
+	created := suite.createTask(domain.Task{Title: "Chore", DueDate: time.Now().Add(time.Hour), Status: "pending", Recurrence: "FREQ=DAILY", Occurrence: 1})
+	next := primitive.NewObjectID().Hex()
+
+	_, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, domain.Task{Title: "Chore", DueDate: created.DueDate, Status: "completed", Recurrence: "FREQ=WEEKLY", Occurrence: 3, NextID: next})
+	suite.Require().NoError(err)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "FREQ=WEEKLY", task.Recurrence)
+	assert.Equal(suite.T(), 3, task.Occurrence)
+	assert.Equal(suite.T(), next, task.NextID)
+
+	_, err = suite.repo.UpdateTask(context.Background(), created.ID, task.Version, domain.Task{Title: "Chore", DueDate: created.DueDate, Status: "pending"})
+	suite.Require().NoError(err)
+
+	task, err = suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), task.Recurrence)
+	assert.Zero(suite.T(), task.Occurrence)
+	assert.Empty(suite.T(), task.NextID)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasksByTitles() {
+	first := suite.createTask(domain.Task{Title: "First", DueDate: time.Now().Add(time.Hour), Status: "pending", OwnerID: "owner"})
+	suite.createTask(domain.Task{Title: "Second", DueDate: time.Now().Add(time.Hour), Status: "pending", OwnerID: "owner"})
+	suite.createTask(domain.Task{Title: "First", DueDate: time.Now().Add(time.Hour), Status: "pending", OwnerID: "other"})
+
+	tasks, err := suite.repo.GetTasksByTitles(context.Background(), "owner", []string{"First", "Third", "first"})
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(tasks, 1)
+	assert.Equal(suite.T(), first.ID, tasks[0].ID)
+}
+
+// taggedTask returns a pending task of the owner with the given tags
+func taggedTask(title, ownerID string, tags ...string) domain.Task {
+	return domain.Task{Title: title, DueDate: time.Now().Add(time.Hour), Status: "pending", OwnerID: ownerID, Tags: tags}
+}
+
+func (suite *TaskRepositoryContractSuite) tagFilterTitles(query domain.TaskQuery) []string {
+	query.SortBy = "title"
+	page, err := suite.repo.GetTasks(context.Background(), query)
+	suite.Require().NoError(err)
+
+	titles := []string{}
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTasks_TagFilter() {
+	suite.createTask(taggedTask("Both", "owner", "home", "urgent"))
+	suite.createTask(taggedTask("Home", "owner", "home"))
+	suite.createTask(taggedTask("Work", "owner", "urgent", "work"))
+	suite.createTask(taggedTask("Untagged", "owner"))
+	suite.createTask(taggedTask("Theirs", "other", "home"))
+
+	assert.Equal(suite.T(), []string{"Both", "Home"}, suite.tagFilterTitles(domain.TaskQuery{OwnerID: "owner", Tags: [][]string{{"home"}}}))
+	assert.Equal(suite.T(), []string{"Both"}, suite.tagFilterTitles(domain.TaskQuery{OwnerID: "owner", Tags: [][]string{{"home"}, {"urgent"}}}))
+	assert.Equal(suite.T(), []string{"Both", "Home", "Work"}, suite.tagFilterTitles(domain.TaskQuery{OwnerID: "owner", Tags: [][]string{{"home", "work"}}}))
+	assert.Equal(suite.T(), []string{"Both", "Work"}, suite.tagFilterTitles(domain.TaskQuery{OwnerID: "owner", Tags: [][]string{{"urgent"}, {"home", "work"}}}))
+	assert.Equal(suite.T(), []string{"Both", "Home", "Theirs"}, suite.tagFilterTitles(domain.TaskQuery{Tags: [][]string{{"home"}}}))
+	assert.Empty(suite.T(), suite.tagFilterTitles(domain.TaskQuery{OwnerID: "owner", Tags: [][]string{{"missing"}}}))
+}
+
+func (suite *TaskRepositoryContractSuite) TestUpdateTask_Tags() {
+	created := suite.createTask(taggedTask("Tagged", "owner", "home"))
+
+	updated, err := suite.repo.UpdateTask(context.Background(), created.ID, created.Version, taggedTask("Tagged", "owner", "home", "work"))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"home", "work"}, updated.Tags)
+
+	updated, err = suite.repo.UpdateTask(context.Background(), created.ID, updated.Version, taggedTask("Tagged", "owner"))
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), updated.Tags)
+}
+
+func (suite *TaskRepositoryContractSuite) TestGetTagCounts() {
+	suite.createTask(taggedTask("Both", "owner", "home", "urgent"))
+	suite.createTask(taggedTask("Home", "owner", "home"))
+	suite.createTask(taggedTask("Untagged", "owner"))
+	suite.createTask(taggedTask("Theirs", "other", "work"))
+
+	counts, err := suite.repo.GetTagCounts(context.Background(), "owner")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"home": 2, "urgent": 1}, counts)
+
+	counts, err = suite.repo.GetTagCounts(context.Background(), "nobody")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), counts)
+}
+
+func (suite *TaskRepositoryContractSuite) TestReplaceTags() {
+	both := suite.createTask(taggedTask("Both", "owner", "bug", "defect", "ui"))
+	one := suite.createTask(taggedTask("One", "owner", "defect"))
+	already := suite.createTask(taggedTask("Already", "owner", "bug", "zz"))
+	untouched := suite.createTask(taggedTask("Untouched", "owner", "ui"))
+	theirs := suite.createTask(taggedTask("Theirs", "other", "defect"))
+
+	changed, err := suite.repo.ReplaceTags(context.Background(), "owner", []string{"defect", "zz"}, "bug")
+	suite.Require().NoError(err)
+	suite.Require().Len(changed, 3)
+
+	returned := make(map[string]domain.Task)
+	for _, task := range changed {
+		returned[task.ID] = task
+	}
+
+	for _, expected := range []struct {
+		task    domain.Task
+		tags    []string
+		version int64
+	}{
+		{both, []string{"bug", "ui"}, 2},
+		{one, []string{"bug"}, 2},
+		{already, []string{"bug"}, 2},
+		{untouched, []string{"ui"}, 1},
+		{theirs, []string{"defect"}, 1},
+	} {
+		task, err := suite.repo.GetTask(context.Background(), expected.task.ID)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), expected.tags, task.Tags, expected.task.Title)
+		assert.Equal(suite.T(), expected.version, task.Version, expected.task.Title)
+
+		if expected.version > 1 {
+			assert.Equal(suite.T(), task, returned[task.ID], expected.task.Title)
+		} else {
+			assert.NotContains(suite.T(), returned, task.ID, expected.task.Title)
+		}
+	}
+}
+
+func (suite *TaskRepositoryContractSuite) TestReplaceTags_KeepsTagsSorted() {
+	created := suite.createTask(taggedTask("Task", "owner", "alpha", "old", "zulu"))
+
+	_, err := suite.repo.ReplaceTags(context.Background(), "owner", []string{"old"}, "mike")
+	suite.Require().NoError(err)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"alpha", "mike", "zulu"}, task.Tags)
+}
+
+func (suite *TaskRepositoryContractSuite) TestAssignProject() {
+	legacy := suite.createTask(taggedTask("Legacy", "owner"))
+	assigned := taggedTask("Assigned", "owner")
+	assigned.ProjectID = "project"
+	assigned = suite.createTask(assigned)
+	theirs := suite.createTask(taggedTask("Theirs", "other"))
+	suite.createTask(taggedTask("Unowned", ""))
+
+	owners, err := suite.repo.GetUnassignedTaskOwners(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"other", "owner"}, owners)
+
+	moved, err := suite.repo.AssignProject(context.Background(), "owner", "personal")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), moved)
+
+	for _, expected := range []struct {
+		task    domain.Task
+		project string
+		version int64
+	}{
+		{legacy, "personal", 2},
+		{assigned, "project", 1},
+		{theirs, "", 1},
+	} {
+		task, err := suite.repo.GetTask(context.Background(), expected.task.ID)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), expected.project, task.ProjectID, expected.task.Title)
+		assert.Equal(suite.T(), expected.version, task.Version, expected.task.Title)
+	}
+
+	owners, err = suite.repo.GetUnassignedTaskOwners(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"other"}, owners)
+}
+
+func testAttachment(id string) domain.Attachment {
+	return domain.Attachment{
+		ID:          id,
+		Filename:    id + ".png",
+		ContentType: "image/png",
+		Size:        42,
+		SHA256:      "abc123",
+		UploadedBy:  "alice",
+		UploadedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func (suite *TaskRepositoryContractSuite) TestAddAttachment() {
+	created := suite.createTask(domain.Task{Title: "Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	first, second := testAttachment("first"), testAttachment("second")
+
+	_, err := suite.repo.AddAttachment(context.Background(), created.ID, first)
+	suite.Require().NoError(err)
+	updated, err := suite.repo.AddAttachment(context.Background(), created.ID, second)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), created.Version+2, updated.Version)
+
+	task, err := suite.repo.GetTask(context.Background(), created.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(task.Attachments, 2)
+	assert.Equal(suite.T(), first.ID, task.Attachments[0].ID)
+	assert.True(suite.T(), first.UploadedAt.Equal(task.Attachments[0].UploadedAt))
+	assert.Equal(suite.T(), second.SHA256, task.Attachments[1].SHA256)
+
+	_, err = suite.repo.AddAttachment(context.Background(), primitive.NewObjectID().Hex(), first)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestAddAttachment_Limit() {
+	created := suite.createTask(domain.Task{Title: "Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	for i := 0; i < domain.MaxTaskAttachments; i++ {
+		_, err := suite.repo.AddAttachment(context.Background(), created.ID, testAttachment(fmt.Sprint(i)))
+		suite.Require().NoError(err)
+	}
+
+	_, err := suite.repo.AddAttachment(context.Background(), created.ID, testAttachment("extra"))
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestAttachmentsSurviveUpdates() {
+	created := suite.createTask(domain.Task{Title: "Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	added, err := suite.repo.AddAttachment(context.Background(), created.ID, testAttachment("kept"))
+	suite.Require().NoError(err)
+
+	updated, err := suite.repo.UpdateTask(context.Background(), created.ID, added.Version, domain.Task{Title: "Renamed", DueDate: created.DueDate, Status: "pending"})
+	suite.Require().NoError(err)
+	suite.Require().Len(updated.Attachments, 1)
+	assert.Equal(suite.T(), "kept", updated.Attachments[0].ID)
+}
+
+func (suite *TaskRepositoryContractSuite) TestRemoveAttachment() {
+	created := suite.createTask(domain.Task{Title: "Task", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	_, err := suite.repo.AddAttachment(context.Background(), created.ID, testAttachment("first"))
+	suite.Require().NoError(err)
+	_, err = suite.repo.AddAttachment(context.Background(), created.ID, testAttachment("second"))
+	suite.Require().NoError(err)
+
+	updated, err := suite.repo.RemoveAttachment(context.Background(), created.ID, "first")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), created.Version+3, updated.Version)
+	suite.Require().Len(updated.Attachments, 1)
+	assert.Equal(suite.T(), "second", updated.Attachments[0].ID)
+
+	_, err = suite.repo.RemoveAttachment(context.Background(), created.ID, "first")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.repo.RemoveAttachment(context.Background(), primitive.NewObjectID().Hex(), "second")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestWriteTasks() {
+	kept := suite.createTask(domain.Task{Title: "Kept", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	removed := suite.createTask(domain.Task{Title: "Removed", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	assigned := primitive.NewObjectID().Hex()
+
+	results, err := suite.repo.WriteTasks(context.Background(), []domain.TaskWrite{
+		{Op: domain.BatchCreate, Task: domain.Task{Title: "Created", DueDate: time.Now().Add(time.Hour), Status: "pending"}},
+		{Op: domain.BatchCreate, Task: domain.Task{ID: assigned, Title: "Assigned", DueDate: time.Now().Add(time.Hour), Status: "pending"}},
+		{Op: domain.BatchUpdate, ID: kept.ID, Version: kept.Version, Task: domain.Task{Title: "Kept", DueDate: kept.DueDate, Status: "pending", NextID: assigned}},
+		{Op: domain.BatchUpdate, ID: removed.ID, Version: removed.Version + 1, Task: domain.Task{Title: "Stale", DueDate: removed.DueDate, Status: "pending"}},
+		{Op: domain.BatchDelete, ID: removed.ID},
+		{Op: domain.BatchDelete, ID: removed.ID},
+	}, false)
+
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 6)
+	assert.NoError(suite.T(), results[0].Err)
+	assert.NotEmpty(suite.T(), results[0].Task.ID)
+	assert.Equal(suite.T(), int64(1), results[0].Task.Version)
+	assert.NoError(suite.T(), results[1].Err)
+	assert.Equal(suite.T(), assigned, results[1].Task.ID)
+	assert.NoError(suite.T(), results[2].Err)
+	assert.Equal(suite.T(), kept.Version+1, results[2].Task.Version)
+	assert.IsType(suite.T(), &domain.ConflictError{}, results[3].Err)
+	assert.NoError(suite.T(), results[4].Err)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, results[5].Err)
+
+	for _, id := range []string{results[0].Task.ID, assigned} {
+		_, err := suite.repo.GetTask(context.Background(), id)
+		assert.NoError(suite.T(), err)
+	}
+
+	task, err := suite.repo.GetTask(context.Background(), kept.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), assigned, task.NextID)
+
+	_, err = suite.repo.GetTask(context.Background(), removed.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRepositoryContractSuite) TestWriteTasks_AtomicRollsBack() {
+	kept := suite.createTask(domain.Task{Title: "Kept", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	removed := suite.createTask(domain.Task{Title: "Removed", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+
+	results, err := suite.repo.WriteTasks(context.Background(), []domain.TaskWrite{
+		{Op: domain.BatchCreate, Task: domain.Task{Title: "Created", DueDate: time.Now().Add(time.Hour), Status: "pending"}},
+		{Op: domain.BatchUpdate, ID: kept.ID, Version: kept.Version, Task: domain.Task{Title: "Renamed", DueDate: kept.DueDate, Status: "pending"}},
+		{Op: domain.BatchDelete, ID: removed.ID},
+		{Op: domain.BatchUpdate, ID: kept.ID, Version: kept.Version, Task: domain.Task{Title: "Stale", DueDate: kept.DueDate, Status: "pending"}},
+		{Op: domain.BatchDelete, ID: primitive.NewObjectID().Hex()},
+	}, true)
+
+	var internal *domain.InternalServerError
+	if errors.As(err, &internal) {
+		skipWithoutMongo(suite.T(), "transactions are not available: %v", err)
+	}
+
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+	suite.Require().Len(results, 5)
+	assert.Equal(suite.T(), err, results[3].Err)
+	assert.NoError(suite.T(), results[4].Err)
+
+	page, err := suite.repo.GetTasks(context.Background(), domain.TaskQuery{SortBy: "title"})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Tasks, 2)
+	assert.Equal(suite.T(), kept, page.Tasks[0])
+	assert.Equal(suite.T(), removed.ID, page.Tasks[1].ID)
+
+	// the restored tasks can still be found by their titles
+	found, err := suite.repo.SearchTasks(context.Background(), domain.TaskSearchQuery{Text: "kept"})
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), found, 1)
+}
+
+func (suite *TaskRepositoryContractSuite) TestWriteTasks_Atomic() {
+	results, err := suite.repo.WriteTasks(context.Background(), []domain.TaskWrite{
+		{Op: domain.BatchCreate, Task: domain.Task{Title: "First", DueDate: time.Now().Add(time.Hour), Status: "pending"}},
+		{Op: domain.BatchCreate, Task: domain.Task{Title: "Second", DueDate: time.Now().Add(time.Hour), Status: "pending"}},
+	}, true)
+
+	var internal *domain.InternalServerError
+	if errors.As(err, &internal) {
+		skipWithoutMongo(suite.T(), "transactions are not available: %v", err)
+	}
+
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 2)
+
+	tasks, err := suite.repo.GetTasksByIDs(context.Background(), []string{results[0].Task.ID, results[1].Task.ID})
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), tasks, 2)
+}
@@ -13,8 +13,6 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // TaskRepositoryTestSuite defines the test suite for UserRepository
@@ -28,23 +26,18 @@ type TaskRepositoryTestSuite struct {
 
 // SetupSuite runs once before the test suite
 func (suite *TaskRepositoryTestSuite) SetupSuite() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
-	suite.NoError(err)
-
-	err = client.Ping(ctx, readpref.Primary())
-	suite.NoError(err)
-
-	suite.client = client
+	suite.client = connectTestDatabase(suite.T())
 	suite.collection = "tasks_test"
-	suite.db = client.Database("test_db")
+	suite.db = suite.client.Database("test_db")
 	suite.repo = NewTaskRepository(suite.db, suite.collection)
 }
 
 // TearDownSuite runs once after the test suite
 func (suite *TaskRepositoryTestSuite) TearDownSuite() {
+	if suite.client == nil {
+		return
+	}
+
 	// drop the database at the end
 	err := suite.client.Database("test_db").Drop(context.Background())
 	suite.NoError(err)
@@ -72,8 +65,9 @@ func (suite *TaskRepositoryTestSuite) TestCreateTask() {
 		Status: "pending",
 	}
 
-	err := suite.repo.CreateTask(task)
+	created, err := suite.repo.CreateTask(context.TODO(), task)
 	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), created.ID)
 
 	var result domain.Task
 	err = suite.db.Collection(suite.collection).FindOne(context.TODO(), bson.M{"title": "Test Task"}).Decode(&result)
@@ -94,19 +88,19 @@ func (suite *TaskRepositoryTestSuite) TestGetTask_Success() {
 
 	id := insertResult.InsertedID.(primitive.ObjectID).Hex()
 	
-	result, err := suite.repo.GetTask(id)
+	result, err := suite.repo.GetTask(context.TODO(), id)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), "Test Task", result.Title)
 }
 
 // TestGetTask_InvalidId tests the GetTask method with invalid input
 func (suite *TaskRepositoryTestSuite) TestGetTask_InvalidId() {
-	_, err := suite.repo.GetTask("invalid")
+	_, err := suite.repo.GetTask(context.TODO(), "invalid")
 	assert.Error(suite.T(), err)
 }
 
 func (suite *TaskRepositoryTestSuite) TestGetTask_NotFound() {
-	_, err := suite.repo.GetTask(primitive.NewObjectID().Hex())
+	_, err := suite.repo.GetTask(context.TODO(), primitive.NewObjectID().Hex())
 	assert.Error(suite.T(), err)
 }
 
@@ -121,16 +115,77 @@ func (suite *TaskRepositoryTestSuite) TestGetTasks_Success() {
 	_, err := suite.db.Collection(suite.collection).InsertOne(context.TODO(), task)
 	assert.NoError(suite.T(), err)
 
-	tasks, err := suite.repo.GetTasks()
+	page, err := suite.repo.GetTasks(context.TODO(), domain.TaskQuery{})
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), 1, len(tasks))
+	assert.Equal(suite.T(), 1, len(page.Tasks))
+	assert.Empty(suite.T(), page.NextCursor)
 }
 
-// TestGetTasks_NotFound tests the GetTasks method with no tasks
-func (suite *TaskRepositoryTestSuite) TestGetTasks_NotFound() {
-	tasks, err := suite.repo.GetTasks()
-	assert.Error(suite.T(), err)
-	assert.Nil(suite.T(), tasks)
+// TestGetTasks_Empty tests the GetTasks method with no tasks
+func (suite *TaskRepositoryTestSuite) TestGetTasks_Empty() {
+	page, err := suite.repo.GetTasks(context.TODO(), domain.TaskQuery{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), page.Tasks)
+	assert.Empty(suite.T(), page.NextCursor)
+}
+
+// TestGetTasks_Filters tests the GetTasks method with status, due date and title filters
+func (suite *TaskRepositoryTestSuite) TestGetTasks_Filters() {
+	now := time.Now()
+	tasks := []interface{}{
+		domain.Task{Title: "Sprint planning", DueDate: now.Add(24 * time.Hour), Status: "pending"},
+		domain.Task{Title: "Sprint review", DueDate: now.Add(72 * time.Hour), Status: "pending"},
+		domain.Task{Title: "Retrospective", DueDate: now.Add(48 * time.Hour), Status: "pending"},
+		domain.Task{Title: "Sprint demo", DueDate: now.Add(-24 * time.Hour), Status: "completed"},
+	}
+	_, err := suite.db.Collection(suite.collection).InsertMany(context.TODO(), tasks)
+	assert.NoError(suite.T(), err)
+
+	page, err := suite.repo.GetTasks(context.TODO(), domain.TaskQuery{
+		Status:      "pending",
+		TitlePrefix: "Sprint",
+		DueBefore:   now.Add(96 * time.Hour),
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(page.Tasks))
+	assert.Equal(suite.T(), "Sprint planning", page.Tasks[0].Title)
+	assert.Equal(suite.T(), "Sprint review", page.Tasks[1].Title)
+}
+
+// TestGetTasks_Pagination tests paging through tasks with the returned cursor
+func (suite *TaskRepositoryTestSuite) TestGetTasks_Pagination() {
+	titles := []string{"A", "B", "C", "D", "E"}
+	for i, title := range titles {
+		task := domain.Task{Title: title, DueDate: time.Now().Add(time.Duration(i+1) * time.Hour), Status: "pending"}
+		_, err := suite.db.Collection(suite.collection).InsertOne(context.TODO(), task)
+		assert.NoError(suite.T(), err)
+	}
+
+	query := domain.TaskQuery{SortBy: "title", Descending: true, Limit: 2}
+	var seen []string
+	for {
+		page, err := suite.repo.GetTasks(context.TODO(), query)
+		assert.NoError(suite.T(), err)
+		for _, task := range page.Tasks {
+			seen = append(seen, task.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	assert.Equal(suite.T(), []string{"E", "D", "C", "B", "A"}, seen)
+}
+
+// TestGetTasks_InvalidCursor tests the GetTasks method with a malformed or mismatched cursor
+func (suite *TaskRepositoryTestSuite) TestGetTasks_InvalidCursor() {
+	_, err := suite.repo.GetTasks(context.TODO(), domain.TaskQuery{Cursor: "not-a-cursor"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	cursor := encodeTaskCursor(domain.TaskQuery{SortBy: "title"}, domain.Task{ID: primitive.NewObjectID().Hex(), Title: "A"})
+	_, err = suite.repo.GetTasks(context.TODO(), domain.TaskQuery{SortBy: "due_date", Cursor: cursor})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
 }
 
 // TestUpdateTask_Success tests the UpdateTask method with valid input
@@ -150,8 +205,9 @@ func (suite *TaskRepositoryTestSuite) TestUpdateTask_Success() {
 		Title: "Updated Task",
 	}
 
-	err = suite.repo.UpdateTask(id, newTask)
+	updated, err := suite.repo.UpdateTask(context.TODO(), id, 0, newTask)
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), updated.Version)
 
 	var result domain.Task
 	err = suite.db.Collection(suite.collection).FindOne(context.TODO(), bson.M{"_id": insertResult.InsertedID}).Decode(&result)
@@ -159,14 +215,34 @@ func (suite *TaskRepositoryTestSuite) TestUpdateTask_Success() {
 	assert.Equal(suite.T(), "Updated Task", result.Title)
 }
 
+// TestUpdateTask_UnversionedTask tests updating a task stored before tasks had versions
+func (suite *TaskRepositoryTestSuite) TestUpdateTask_UnversionedTask() {
+	insertResult, err := suite.db.Collection(suite.collection).InsertOne(context.TODO(), bson.M{
+		"title":    "Test Task",
+		"due_date": time.Now().Add(24 * time.Hour),
+		"status":   "pending",
+	})
+	assert.NoError(suite.T(), err)
+
+	id := insertResult.InsertedID.(primitive.ObjectID).Hex()
+
+	task, err := suite.repo.GetTask(context.TODO(), id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), task.Version)
+
+	updated, err := suite.repo.UpdateTask(context.TODO(), id, task.Version, domain.Task{Title: "Updated Task"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), updated.Version)
+}
+
 // TestUpdateTask_InvalidId tests the UpdateTask method with invalid input
 func (suite *TaskRepositoryTestSuite) TestUpdateTask_InvalidId() {
-	err := suite.repo.UpdateTask("invalid", domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"})
+	_, err := suite.repo.UpdateTask(context.TODO(), "invalid", 1, domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"})
 	assert.Error(suite.T(), err)
 }
 
 func (suite *TaskRepositoryTestSuite) TestUpdateTask_NotFound() {
-	err := suite.repo.UpdateTask(primitive.NewObjectID().Hex(), domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"})
+	_, err := suite.repo.UpdateTask(context.TODO(), primitive.NewObjectID().Hex(), 1, domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"})
 	assert.Error(suite.T(), err)
 }
 
@@ -183,7 +259,7 @@ func (suite *TaskRepositoryTestSuite) TestDeleteTask_Success() {
 
 	id := insertResult.InsertedID.(primitive.ObjectID).Hex()
 
-	err = suite.repo.DeleteTask(id)
+	err = suite.repo.DeleteTask(context.TODO(), id)
 	assert.NoError(suite.T(), err)
 
 	var result domain.Task
@@ -193,13 +269,52 @@ func (suite *TaskRepositoryTestSuite) TestDeleteTask_Success() {
 
 // TestDeleteTask_InvalidId tests the DeleteTask method with invalid input
 func (suite *TaskRepositoryTestSuite) TestDeleteTask_InvalidId() {
-	err := suite.repo.DeleteTask("invalid")
+	err := suite.repo.DeleteTask(context.TODO(), "invalid")
 	assert.Error(suite.T(), err)
 }
 
 func (suite *TaskRepositoryTestSuite) TestDeleteTask_NotFound() {
-	err := suite.repo.DeleteTask(primitive.NewObjectID().Hex())
+	err := suite.repo.DeleteTask(context.TODO(), primitive.NewObjectID().Hex())
 	assert.Error(suite.T(), err)
 }
 
 
+
+// TestReplaceTaskTags_RetriesChangedTask checks that a tag change keeps an update that
+// landed after the task was read
+func (suite *TaskRepositoryTestSuite) TestReplaceTaskTags_RetriesChangedTask() {
+	stale, err := suite.repo.CreateTask(context.TODO(), domain.Task{Title: "Chore", DueDate: time.Now().Add(time.Hour), Status: "pending", Tags: []string{"home", "old"}})
+	suite.Require().NoError(err)
+
+	renamed := stale
+	renamed.Title = "Renamed"
+	renamed, err = suite.repo.UpdateTask(context.TODO(), stale.ID, stale.Version, renamed)
+	suite.Require().NoError(err)
+
+	updated, ok, err := suite.repo.(*taskRepository).replaceTaskTags(context.TODO(), stale, []string{"old"}, "new")
+	suite.Require().NoError(err)
+	suite.Require().True(ok)
+	assert.Equal(suite.T(), "Renamed", updated.Title)
+	assert.Equal(suite.T(), []string{"home", "new"}, updated.Tags)
+	assert.Equal(suite.T(), renamed.Version+1, updated.Version)
+}
+
+// TestReplaceTaskTags_SkipsRetaggedTask checks that a task which lost the old tags after
+// it was read is left alone
+func (suite *TaskRepositoryTestSuite) TestReplaceTaskTags_SkipsRetaggedTask() {
+	stale, err := suite.repo.CreateTask(context.TODO(), domain.Task{Title: "Chore", DueDate: time.Now().Add(time.Hour), Status: "pending", Tags: []string{"old"}})
+	suite.Require().NoError(err)
+
+	retagged := stale
+	retagged.Tags = []string{"other"}
+	_, err = suite.repo.UpdateTask(context.TODO(), stale.ID, stale.Version, retagged)
+	suite.Require().NoError(err)
+
+	_, ok, err := suite.repo.(*taskRepository).replaceTaskTags(context.TODO(), stale, []string{"old"}, "new")
+	suite.Require().NoError(err)
+	assert.False(suite.T(), ok)
+
+	task, err := suite.repo.GetTask(context.TODO(), stale.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"other"}, task.Tags)
+}
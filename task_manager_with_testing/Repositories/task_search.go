@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"sort"
+
+	domain "task-manager/Domain"
+)
+
+// maxSearchCandidates bounds how many tasks a search scores before ranking them
+const maxSearchCandidates = 1000
+
+// parseSearchQuery parses the search text and returns the number of results to keep
+func parseSearchQuery(query domain.TaskSearchQuery) (domain.SearchTerms, int, error) {
+	terms, err := domain.ParseSearchText(query.Text)
+	if err != nil {
+		return domain.SearchTerms{}, 0, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = domain.DefaultSearchLimit
+	}
+
+	return terms, limit, nil
+}
+
+// rankSearchResults scores the candidate tasks, drops those that do not match every
+// term and returns the best ones, highest score first and ties broken by ID
+func rankSearchResults(terms domain.SearchTerms, candidates []domain.Task, limit int) []domain.TaskSearchResult {
+	results := []domain.TaskSearchResult{}
+	for _, task := range candidates {
+		score, ok := terms.Match(task.Title)
+		if !ok {
+			continue
+		}
+
+		results = append(results, domain.TaskSearchResult{Task: task, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	// only the results that are returned need a snippet
+	for i := range results {
+		results[i].Snippet = terms.Snippet(results[i].Task.Title)
+	}
+
+	return results
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userMemoryRepository keeps users in memory, keyed by ID
+type userMemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]domain.User
+}
+
+// NewUserMemoryRepository creates a new in-memory user repository
+func NewUserMemoryRepository() UserRepository {
+	return &userMemoryRepository{users: make(map[string]domain.User)}
+}
+
+// CreateUser creates a new user
+func (r *userMemoryRepository) CreateUser(ctx context.Context, user domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.Subject != "" {
+		for _, existing := range r.users {
+			if existing.Issuer == user.Issuer && existing.Subject == user.Subject {
+				return &domain.AlreadyExistsError{Message: "Account is already linked to a user"}
+			}
+		}
+	}
+
+	user.ID = primitive.NewObjectID().Hex()
+	r.users[user.ID] = user
+
+	return nil
+}
+
+func (r *userMemoryRepository) UpdateUser(ctx context.Context, id string, user domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return &domain.NotFoundError{Message: "User not found"}
+	}
+
+	user.ID = id
+	r.users[id] = user
+
+	return nil
+}
+
+func (r *userMemoryRepository) FindByUsername(ctx context.Context, username string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return domain.User{}, &domain.NotFoundError{Message: "User not found"}
+}
+
+func (r *userMemoryRepository) FindByID(ctx context.Context, id string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.User{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return domain.User{}, &domain.NotFoundError{Message: "User not found"}
+	}
+
+	return user, nil
+}
+
+// FindByExternalID finds the user linked to the account with the given issuer and subject
+func (r *userMemoryRepository) FindByExternalID(ctx context.Context, issuer, subject string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if subject != "" && user.Issuer == issuer && user.Subject == subject {
+			return user, nil
+		}
+	}
+
+	return domain.User{}, &domain.NotFoundError{Message: "User not found"}
+}
+
+func (r *userMemoryRepository) CountUsers(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.users)), nil
+}
+
+// CountUsersWithRole counts the users holding the role
+func (r *userMemoryRepository) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if slices.Contains(user.RoleNames(), role) {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -2,25 +2,35 @@ package repositories
 
 import (
 	"context"
+	"sync"
 	domain "task-manager/Domain"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserRepository interface
 type UserRepository interface {
-	CreateUser(user domain.User) error
-	UpdateUser(id string, user domain.User) error
-	FindByUsername(username string) (domain.User, error)
-	CountUsers() (int64, error)
+	CreateUser(ctx context.Context, user domain.User) error
+	UpdateUser(ctx context.Context, id string, user domain.User) error
+	FindByUsername(ctx context.Context, username string) (domain.User, error)
+	FindByID(ctx context.Context, id string) (domain.User, error)
+	// FindByExternalID finds the user linked to an identity provider's account
+	FindByExternalID(ctx context.Context, issuer, subject string) (domain.User, error)
+	CountUsers(ctx context.Context) (int64, error)
+	// CountUsersWithRole counts the users holding the named role
+	CountUsersWithRole(ctx context.Context, role string) (int64, error)
 }
 
 // userRepository struct
 type userRepository struct {
 	db         *mongo.Database
 	collection string
+
+	mu      sync.Mutex
+	indexed bool
 }
 
 // NewUserRepository creates a new user repository
@@ -28,18 +38,48 @@ func NewUserRepository(database *mongo.Database, collection string) UserReposito
 	return &userRepository{db: database, collection: collection}
 }
 
+// ensureIndexes creates the unique index that links at most one user to each account
+// at an identity provider
+func (r *userRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "issuer", Value: 1}, {Key: "subject", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"subject": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return databaseError(err, "Error creating user index")
+	}
+
+	r.indexed = true
+	return nil
+}
+
 // CreateUser creates a new user
-func (r *userRepository) CreateUser(user domain.User) error {	
-	_, err := r.db.Collection(r.collection).InsertOne(context.TODO(), user)
+func (r *userRepository) CreateUser(ctx context.Context, user domain.User) error {	
+	if err := r.ensureIndexes(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, user)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return &domain.AlreadyExistsError{Message: "Account is already linked to a user"}
+	}
 
 	if err != nil {
-		return &domain.InternalServerError{Message: "Error creating user"}
+		return databaseError(err, "Error creating user")
 	}
 
 	return nil
 }
 
-func (r *userRepository) UpdateUser(id string, user domain.User) error {
+func (r *userRepository) UpdateUser(ctx context.Context, id string, user domain.User) error {
 	objId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return &domain.BadRequestError{Message: "Invalid ID"}
@@ -47,40 +87,94 @@ func (r *userRepository) UpdateUser(id string, user domain.User) error {
 	user.ID = ""
 	filter := bson.M{"_id": objId}
 	update := bson.M{"$set": user}
-	_, err = r.db.Collection(r.collection).UpdateOne(context.TODO(), filter, update)
+	updateResult, err := r.db.Collection(r.collection).UpdateOne(ctx, filter, update)
 
-	if err == mongo.ErrNoDocuments {
-		return &domain.NotFoundError{Message: "User not found"}
+	if err != nil {
+		return databaseError(err, "Error updating user")
 	}
 
-	if err != nil {
-		return &domain.InternalServerError{Message: "Error updating user"}
+	if updateResult.MatchedCount == 0 {
+		return &domain.NotFoundError{Message: "User not found"}
 	}
 
 	return nil
 }
 
-func (r *userRepository) FindByUsername(username string) (domain.User, error) {
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (domain.User, error) {
 	var user domain.User
 	filter := bson.M{"username": username}
-	err := r.db.Collection(r.collection).FindOne(context.TODO(), filter).Decode(&user)
+	err := r.db.Collection(r.collection).FindOne(ctx, filter).Decode(&user)
 
 	if err == mongo.ErrNoDocuments {
 		return domain.User{}, &domain.NotFoundError{Message: "User not found"}
 	}
 
 	if err != nil {
-		return domain.User{}, &domain.InternalServerError{Message: "Error retrieving user"}
+		return domain.User{}, databaseError(err, "Error retrieving user")
 	}
 
 	return user, nil
 }
 
-func (r *userRepository) CountUsers() (int64, error) {
-	count, err := r.db.Collection(r.collection).CountDocuments(context.TODO(), bson.M{})
+func (r *userRepository) FindByID(ctx context.Context, id string) (domain.User, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.User{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	var user domain.User
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": objId}).Decode(&user)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.User{}, &domain.NotFoundError{Message: "User not found"}
+	}
+
+	if err != nil {
+		return domain.User{}, databaseError(err, "Error retrieving user")
+	}
+
+	return user, nil
+}
+
+// FindByExternalID finds the user linked to the account with the given issuer and subject
+func (r *userRepository) FindByExternalID(ctx context.Context, issuer, subject string) (domain.User, error) {
+	var user domain.User
+	filter := bson.M{"issuer": issuer, "subject": subject}
+	err := r.db.Collection(r.collection).FindOne(ctx, filter).Decode(&user)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.User{}, &domain.NotFoundError{Message: "User not found"}
+	}
+
+	if err != nil {
+		return domain.User{}, databaseError(err, "Error retrieving user")
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) CountUsers(ctx context.Context) (int64, error) {
+	count, err := r.db.Collection(r.collection).CountDocuments(ctx, bson.M{})
+
+	if err != nil {
+		return 0, databaseError(err, "Error counting users")
+	}
+
+	return count, nil
+}
+
+
+// CountUsersWithRole counts the users holding the role, including older users whose
+// single role is the one asked for
+func (r *userRepository) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"roles": role},
+		bson.M{"roles": bson.M{"$exists": false}, "role": role},
+	}}
+	count, err := r.db.Collection(r.collection).CountDocuments(ctx, filter)
 
 	if err != nil {
-		return 0, &domain.InternalServerError{Message: "Error counting users"}
+		return 0, databaseError(err, "Error counting users")
 	}
 
 	return count, nil
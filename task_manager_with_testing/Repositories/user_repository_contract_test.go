@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepositoryContractSuite checks the behaviour every UserRepository backend must share
+type UserRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() UserRepository
+	repo          UserRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *UserRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestUserRepositoryContract_Memory runs the contract against the in-memory backend
+func TestUserRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &UserRepositoryContractSuite{newRepository: NewUserMemoryRepository})
+}
+
+// TestUserRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestUserRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &UserRepositoryContractSuite{newRepository: func() UserRepository {
+		db.Collection("users").Drop(context.Background())
+		return NewUserRepository(db, "users")
+	}})
+}
+
+func (suite *UserRepositoryContractSuite) TestCreateAndFindUser() {
+	err := suite.repo.CreateUser(context.Background(), domain.User{Username: "testuser", Password: "hashed", Roles: []string{"user"}})
+	assert.NoError(suite.T(), err)
+
+	user, err := suite.repo.FindByUsername(context.Background(), "testuser")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), primitive.IsValidObjectID(user.ID))
+	assert.Equal(suite.T(), "hashed", user.Password)
+	assert.Equal(suite.T(), []string{"user"}, user.Roles)
+}
+
+func (suite *UserRepositoryContractSuite) TestFindByUsername_NotFound() {
+	_, err := suite.repo.FindByUsername(context.Background(), "missing")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *UserRepositoryContractSuite) TestFindByID() {
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "testuser", Password: "hashed", Role: "user"}))
+	user, err := suite.repo.FindByUsername(context.Background(), "testuser")
+	suite.Require().NoError(err)
+
+	found, err := suite.repo.FindByID(context.Background(), user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "testuser", found.Username)
+
+	_, err = suite.repo.FindByID(context.Background(), "invalid")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.repo.FindByID(context.Background(), primitive.NewObjectID().Hex())
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *UserRepositoryContractSuite) TestFindByExternalID() {
+	linked := domain.User{Username: "alice", Roles: []string{"user"}, Issuer: "https://login.example.com", Subject: "248289761001"}
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), linked))
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "bob", Password: "hashed"}))
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "carol", Password: "hashed"}))
+
+	found, err := suite.repo.FindByExternalID(context.Background(), "https://login.example.com", "248289761001")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "alice", found.Username)
+	assert.Equal(suite.T(), "https://login.example.com", found.Issuer)
+	assert.Equal(suite.T(), "248289761001", found.Subject)
+
+	_, err = suite.repo.FindByExternalID(context.Background(), "https://other.example.com", "248289761001")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.repo.FindByExternalID(context.Background(), "", "")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *UserRepositoryContractSuite) TestCreateUser_AccountLinkedOnce() {
+	linked := domain.User{Username: "alice", Issuer: "https://login.example.com", Subject: "248289761001"}
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), linked))
+
+	linked.Username = "alice-2"
+	err := suite.repo.CreateUser(context.Background(), linked)
+	assert.IsType(suite.T(), &domain.AlreadyExistsError{}, err)
+
+	linked.Issuer = "https://other.example.com"
+	assert.NoError(suite.T(), suite.repo.CreateUser(context.Background(), linked))
+}
+
+func (suite *UserRepositoryContractSuite) TestUpdateUser() {
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "testuser", Password: "hashed", Role: "user"}))
+	user, err := suite.repo.FindByUsername(context.Background(), "testuser")
+	suite.Require().NoError(err)
+
+	user.Roles = []string{"admin", "user"}
+	assert.NoError(suite.T(), suite.repo.UpdateUser(context.Background(), user.ID, user))
+
+	updated, err := suite.repo.FindByUsername(context.Background(), "testuser")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), user.ID, updated.ID)
+	assert.Equal(suite.T(), []string{"admin", "user"}, updated.Roles)
+}
+
+func (suite *UserRepositoryContractSuite) TestUpdateUser_Errors() {
+	err := suite.repo.UpdateUser(context.Background(), "invalid", domain.User{Username: "testuser"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	err = suite.repo.UpdateUser(context.Background(), primitive.NewObjectID().Hex(), domain.User{Username: "testuser"})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *UserRepositoryContractSuite) TestCountUsers() {
+	count, err := suite.repo.CountUsers(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), count)
+
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "first", Password: "hashed"}))
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "second", Password: "hashed"}))
+
+	count, err = suite.repo.CountUsers(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+}
+
+func (suite *UserRepositoryContractSuite) TestCountUsersWithRole() {
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "legacy", Password: "hashed", Role: "admin"}))
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "first", Password: "hashed", Roles: []string{"admin", "user"}}))
+	suite.Require().NoError(suite.repo.CreateUser(context.Background(), domain.User{Username: "second", Password: "hashed", Roles: []string{"user"}}))
+
+	count, err := suite.repo.CountUsersWithRole(context.Background(), "admin")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+
+	count, err = suite.repo.CountUsersWithRole(context.Background(), "auditor")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), count)
+}
+
+func (suite *UserRepositoryContractSuite) TestExpiredContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := suite.repo.FindByUsername(ctx, "testuser")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
+	assert.Equal(suite.T(), "Request timed out", err.Error())
+}
@@ -3,7 +3,6 @@ package repositories
 import (
 	"context"
 	"testing"
-	"time"
 
 	domain "task-manager/Domain"
 
@@ -12,8 +11,6 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // UserRepositoryTestSuite defines the test suite for UserRepository
@@ -27,23 +24,18 @@ type UserRepositoryTestSuite struct {
 
 // SetupSuite runs once before the test suite
 func (suite *UserRepositoryTestSuite) SetupSuite() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
-	suite.NoError(err)
-
-	err = client.Ping(ctx, readpref.Primary())
-	suite.NoError(err)
-
-	suite.client = client
+	suite.client = connectTestDatabase(suite.T())
 	suite.collection = "users_test"
-	suite.db = client.Database("test_db")
+	suite.db = suite.client.Database("test_db")
 	suite.repo = NewUserRepository(suite.db, suite.collection)
 }
 
 // TearDownSuite runs once after the test suite
 func (suite *UserRepositoryTestSuite) TearDownSuite() {
+	if suite.client == nil {
+		return
+	}
+
 	// drop the database at the end
 	err := suite.client.Database("test_db").Drop(context.Background())
 	suite.NoError(err)
@@ -71,7 +63,7 @@ func (suite *UserRepositoryTestSuite) TestCreateUser() {
 		Password: "password123",
 	}
 
-	err := suite.repo.CreateUser(user)
+	err := suite.repo.CreateUser(context.TODO(), user)
 	assert.NoError(suite.T(), err)
 
 	// Verify user exists in the database
@@ -100,7 +92,7 @@ func (suite *UserRepositoryTestSuite) TestUpdateUser() {
 		Username: "updateduser",
 	}
 
-	err = suite.repo.UpdateUser(id, updatedUser)
+	err = suite.repo.UpdateUser(context.TODO(), id, updatedUser)
 	assert.NoError(suite.T(), err)
 
 	// Verify user was updated
@@ -123,14 +115,14 @@ func (suite *UserRepositoryTestSuite) TestFindByUsername_Success() {
 	assert.NoError(suite.T(), err)
 
 	// Find the user
-	storedUser, err := suite.repo.FindByUsername(user.Username)
+	storedUser, err := suite.repo.FindByUsername(context.TODO(), user.Username)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), user.Username, storedUser.Username)
 }
 
 // TestFindByUsername_NotFound tests the FindByUsername method when the user is not found
 func (suite *UserRepositoryTestSuite) TestFindByUsername_NotFound() {
-	_, err := suite.repo.FindByUsername("nonexistentuser")
+	_, err := suite.repo.FindByUsername(context.TODO(), "nonexistentuser")
 	assert.Error(suite.T(), err)
 }
 
@@ -150,14 +142,14 @@ func (suite *UserRepositoryTestSuite) TestCountUsers_Success() {
 	_, err = suite.db.Collection(suite.collection).InsertOne(context.TODO(), user2)
 	assert.NoError(suite.T(), err)
 
-	count, err := suite.repo.CountUsers()
+	count, err := suite.repo.CountUsers(context.TODO())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(2), count)
 }
 
 // TestCountUsers_Empty tests the CountUsers method when there are no users
 func (suite *UserRepositoryTestSuite) TestCountUsers_Empty() {
-	count, err := suite.repo.CountUsers()
+	count, err := suite.repo.CountUsers(context.TODO())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(0), count)
 }
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookDeliveryMemoryRepository keeps webhook deliveries in memory, oldest first
+type webhookDeliveryMemoryRepository struct {
+	mu         sync.RWMutex
+	deliveries []domain.WebhookDelivery
+}
+
+// NewWebhookDeliveryMemoryRepository creates a new in-memory webhook delivery repository
+func NewWebhookDeliveryMemoryRepository() WebhookDeliveryRepository {
+	return &webhookDeliveryMemoryRepository{deliveries: []domain.WebhookDelivery{}}
+}
+
+// CreateDelivery stores a new delivery and returns it with its assigned ID
+func (r *webhookDeliveryMemoryRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) (domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookDelivery{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery.ID = primitive.NewObjectID().Hex()
+	delivery.Attempts = append([]domain.WebhookAttempt{}, delivery.Attempts...)
+	r.deliveries = append(r.deliveries, delivery)
+
+	return delivery, nil
+}
+
+// GetDelivery retrieves a delivery by ID
+func (r *webhookDeliveryMemoryRepository) GetDelivery(ctx context.Context, id string) (domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookDelivery{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.WebhookDelivery{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return r.deliveries[i], nil
+	}
+
+	return domain.WebhookDelivery{}, &domain.NotFoundError{Message: "Delivery not found"}
+}
+
+// GetDeliveries retrieves the most recent deliveries matching the query, newest first
+func (r *webhookDeliveryMemoryRepository) GetDeliveries(ctx context.Context, query domain.DeliveryQuery) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	limit := deliveryPageSize(query)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []domain.WebhookDelivery{}
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := r.deliveries[i]
+		if query.WebhookID != "" && delivery.WebhookID != query.WebhookID {
+			continue
+		}
+
+		if query.Status != "" && delivery.Status != query.Status {
+			continue
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDue picks the pending delivery that has been due the longest and postpones it by lease
+func (r *webhookDeliveryMemoryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (domain.WebhookDelivery, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookDelivery{}, false, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := -1
+	for i, delivery := range r.deliveries {
+		if delivery.Status != domain.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		if due < 0 || delivery.NextAttemptAt.Before(r.deliveries[due].NextAttemptAt) {
+			due = i
+		}
+	}
+
+	if due < 0 {
+		return domain.WebhookDelivery{}, false, nil
+	}
+
+	r.deliveries[due].NextAttemptAt = now.Add(lease)
+	return r.deliveries[due], true, nil
+}
+
+// UpdateDelivery replaces a stored delivery
+func (r *webhookDeliveryMemoryRepository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(delivery.ID) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.indexOf(delivery.ID)
+	if i < 0 {
+		return &domain.NotFoundError{Message: "Delivery not found"}
+	}
+
+	delivery.Attempts = append([]domain.WebhookAttempt{}, delivery.Attempts...)
+	r.deliveries[i] = delivery
+
+	return nil
+}
+
+// indexOf finds a delivery by ID; the caller must hold the lock
+func (r *webhookDeliveryMemoryRepository) indexOf(id string) int {
+	for i, delivery := range r.deliveries {
+		if delivery.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
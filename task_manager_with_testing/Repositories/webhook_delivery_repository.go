@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookDeliveryRepository stores webhook deliveries and hands due ones to the workers
+// that send them
+type WebhookDeliveryRepository interface {
+	CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) (domain.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id string) (domain.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, query domain.DeliveryQuery) ([]domain.WebhookDelivery, error)
+	// ClaimDue picks the pending delivery that has been due the longest and postpones it
+	// by lease so no other worker picks it up meanwhile; it returns false if none is due
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (domain.WebhookDelivery, bool, error)
+	UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+}
+
+// webhookDeliveryRepository struct
+type webhookDeliveryRepository struct {
+	db         *mongo.Database
+	collection string
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// NewWebhookDeliveryRepository creates a new webhook delivery repository
+func NewWebhookDeliveryRepository(database *mongo.Database, collection string) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: database, collection: collection}
+}
+
+// ensureIndexes creates the index the workers use to find due deliveries and the one
+// used to list recent deliveries
+func (r *webhookDeliveryRepository) ensureIndexes(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexed {
+		return nil
+	}
+
+	_, err := r.db.Collection(r.collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return databaseError(err, "Error creating webhook delivery indexes")
+	}
+
+	r.indexed = true
+	return nil
+}
+
+// CreateDelivery stores a new delivery and returns it with its assigned ID
+func (r *webhookDeliveryRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) (domain.WebhookDelivery, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	delivery.ID = ""
+	result, err := r.db.Collection(r.collection).InsertOne(ctx, delivery)
+
+	if err != nil {
+		return domain.WebhookDelivery{}, databaseError(err, "Error creating webhook delivery")
+	}
+
+	if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+		delivery.ID = objId.Hex()
+	}
+
+	return delivery, nil
+}
+
+// GetDelivery retrieves a delivery by ID
+func (r *webhookDeliveryRepository) GetDelivery(ctx context.Context, id string) (domain.WebhookDelivery, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.WebhookDelivery{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	var delivery domain.WebhookDelivery
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": objId}).Decode(&delivery)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.WebhookDelivery{}, &domain.NotFoundError{Message: "Delivery not found"}
+	}
+
+	if err != nil {
+		return domain.WebhookDelivery{}, databaseError(err, "Error retrieving webhook delivery")
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries retrieves the most recent deliveries matching the query, newest first
+func (r *webhookDeliveryRepository) GetDeliveries(ctx context.Context, query domain.DeliveryQuery) ([]domain.WebhookDelivery, error) {
+	filter := bson.M{}
+	if query.WebhookID != "" {
+		filter["webhook_id"] = query.WebhookID
+	}
+
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(deliveryPageSize(query)))
+
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving webhook deliveries")
+	}
+
+	defer cursor.Close(ctx)
+
+	deliveries := []domain.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, databaseError(err, "Error retrieving webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDue picks the pending delivery that has been due the longest and postpones it by lease
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (domain.WebhookDelivery, bool, error) {
+	if err := r.ensureIndexes(ctx); err != nil {
+		return domain.WebhookDelivery{}, false, err
+	}
+
+	filter := bson.M{"status": domain.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery domain.WebhookDelivery
+	err := r.db.Collection(r.collection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.WebhookDelivery{}, false, nil
+	}
+
+	if err != nil {
+		return domain.WebhookDelivery{}, false, databaseError(err, "Error claiming webhook delivery")
+	}
+
+	return delivery, true, nil
+}
+
+// UpdateDelivery replaces a stored delivery
+func (r *webhookDeliveryRepository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	objId, err := primitive.ObjectIDFromHex(delivery.ID)
+	if err != nil {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	delivery.ID = ""
+	result, err := r.db.Collection(r.collection).ReplaceOne(ctx, bson.M{"_id": objId}, delivery)
+
+	if err != nil {
+		return databaseError(err, "Error updating webhook delivery")
+	}
+
+	if result.MatchedCount == 0 {
+		return &domain.NotFoundError{Message: "Delivery not found"}
+	}
+
+	return nil
+}
+
+func deliveryPageSize(query domain.DeliveryQuery) int {
+	if query.Limit <= 0 {
+		return domain.DefaultDeliveryPageSize
+	}
+
+	return query.Limit
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// WebhookDeliveryRepositoryContractSuite checks the behaviour every WebhookDeliveryRepository backend must share
+type WebhookDeliveryRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() WebhookDeliveryRepository
+	repo          WebhookDeliveryRepository
+	now           time.Time
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *WebhookDeliveryRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+	suite.now = time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+}
+
+// TestWebhookDeliveryRepositoryContract_Memory runs the contract against the in-memory backend
+func TestWebhookDeliveryRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &WebhookDeliveryRepositoryContractSuite{newRepository: NewWebhookDeliveryMemoryRepository})
+}
+
+// TestWebhookDeliveryRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestWebhookDeliveryRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &WebhookDeliveryRepositoryContractSuite{newRepository: func() WebhookDeliveryRepository {
+		db.Collection("webhook_deliveries").Drop(context.Background())
+		return NewWebhookDeliveryRepository(db, "webhook_deliveries")
+	}})
+}
+
+// createDelivery stores a pending delivery that is due at the given offset from now
+func (suite *WebhookDeliveryRepositoryContractSuite) createDelivery(webhookID string, due time.Duration) domain.WebhookDelivery {
+	delivery, err := suite.repo.CreateDelivery(context.Background(), domain.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventID:       "event",
+		EventType:     domain.EventTaskCreated,
+		Payload:       `{"type":"task.created"}`,
+		Status:        domain.DeliveryPending,
+		Attempts:      []domain.WebhookAttempt{},
+		NextAttemptAt: suite.now.Add(due),
+		CreatedAt:     suite.now,
+		UpdatedAt:     suite.now,
+	})
+	suite.Require().NoError(err)
+
+	// keep creation times apart so the newest-first order is well defined
+	suite.now = suite.now.Add(time.Second)
+	return delivery
+}
+
+func (suite *WebhookDeliveryRepositoryContractSuite) TestCreateAndGetDelivery() {
+	created := suite.createDelivery("webhook", 0)
+	assert.NotEmpty(suite.T(), created.ID)
+
+	delivery, err := suite.repo.GetDelivery(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "webhook", delivery.WebhookID)
+	assert.Equal(suite.T(), domain.DeliveryPending, delivery.Status)
+	assert.Equal(suite.T(), `{"type":"task.created"}`, delivery.Payload)
+
+	_, err = suite.repo.GetDelivery(context.Background(), "not-an-id")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.repo.GetDelivery(context.Background(), "0123456789abcdef01234567")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *WebhookDeliveryRepositoryContractSuite) TestGetDeliveries() {
+	first := suite.createDelivery("first", 0)
+	second := suite.createDelivery("second", 0)
+	third := suite.createDelivery("first", 0)
+
+	third.Status = domain.DeliveryFailed
+	suite.Require().NoError(suite.repo.UpdateDelivery(context.Background(), third))
+
+	ids := func(query domain.DeliveryQuery) []string {
+		deliveries, err := suite.repo.GetDeliveries(context.Background(), query)
+		suite.Require().NoError(err)
+
+		ids := []string{}
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(suite.T(), []string{third.ID, second.ID, first.ID}, ids(domain.DeliveryQuery{}))
+	assert.Equal(suite.T(), []string{third.ID, second.ID}, ids(domain.DeliveryQuery{Limit: 2}))
+	assert.Equal(suite.T(), []string{third.ID, first.ID}, ids(domain.DeliveryQuery{WebhookID: "first"}))
+	assert.Equal(suite.T(), []string{third.ID}, ids(domain.DeliveryQuery{Status: domain.DeliveryFailed}))
+	assert.Equal(suite.T(), []string{first.ID}, ids(domain.DeliveryQuery{WebhookID: "first", Status: domain.DeliveryPending}))
+}
+
+func (suite *WebhookDeliveryRepositoryContractSuite) TestClaimDue() {
+	later := suite.createDelivery("webhook", time.Minute)
+	overdue := suite.createDelivery("webhook", -time.Minute)
+	due := suite.createDelivery("webhook", 0)
+	now := suite.now
+
+	claimed, ok, err := suite.repo.ClaimDue(context.Background(), now, time.Minute)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), overdue.ID, claimed.ID)
+	assert.True(suite.T(), now.Add(time.Minute).Equal(claimed.NextAttemptAt))
+
+	claimed, ok, err = suite.repo.ClaimDue(context.Background(), now, time.Minute)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), due.ID, claimed.ID)
+
+	// claimed deliveries are leased and the last one is not due yet
+	_, ok, err = suite.repo.ClaimDue(context.Background(), now, time.Minute)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	// a delivery that is no longer pending is never claimed
+	later.Status = domain.DeliverySucceeded
+	suite.Require().NoError(suite.repo.UpdateDelivery(context.Background(), later))
+
+	claimed, ok, err = suite.repo.ClaimDue(context.Background(), now.Add(time.Minute), time.Minute)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.NotEqual(suite.T(), later.ID, claimed.ID)
+}
+
+func (suite *WebhookDeliveryRepositoryContractSuite) TestConcurrentClaims() {
+	suite.createDelivery("webhook", 0)
+	now := suite.now
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claims := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := suite.repo.ClaimDue(context.Background(), now, time.Minute)
+			assert.NoError(suite.T(), err)
+			if ok {
+				mu.Lock()
+				claims++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(suite.T(), 1, claims)
+}
+
+func (suite *WebhookDeliveryRepositoryContractSuite) TestUpdateDelivery() {
+	delivery := suite.createDelivery("webhook", 0)
+
+	delivery.Status = domain.DeliverySucceeded
+	delivery.Attempts = append(delivery.Attempts, domain.WebhookAttempt{Number: 1, AttemptedAt: suite.now, StatusCode: 204, DurationMS: 12})
+	assert.NoError(suite.T(), suite.repo.UpdateDelivery(context.Background(), delivery))
+
+	updated, err := suite.repo.GetDelivery(context.Background(), delivery.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.DeliverySucceeded, updated.Status)
+	if assert.Len(suite.T(), updated.Attempts, 1) {
+		assert.Equal(suite.T(), 204, updated.Attempts[0].StatusCode)
+		assert.Equal(suite.T(), int64(12), updated.Attempts[0].DurationMS)
+	}
+
+	delivery.ID = "0123456789abcdef01234567"
+	err = suite.repo.UpdateDelivery(context.Background(), delivery)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *WebhookDeliveryRepositoryContractSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := suite.repo.ClaimDue(ctx, suite.now, time.Minute)
+	assert.Error(suite.T(), err)
+}
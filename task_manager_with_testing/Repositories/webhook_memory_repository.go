@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookMemoryRepository keeps webhooks in memory, oldest first
+type webhookMemoryRepository struct {
+	mu       sync.RWMutex
+	webhooks []domain.Webhook
+}
+
+// NewWebhookMemoryRepository creates a new in-memory webhook repository
+func NewWebhookMemoryRepository() WebhookRepository {
+	return &webhookMemoryRepository{webhooks: []domain.Webhook{}}
+}
+
+// CreateWebhook stores a new webhook and returns it with its assigned ID
+func (r *webhookMemoryRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Webhook{}, contextError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = primitive.NewObjectID().Hex()
+	webhook.Events = append([]string{}, webhook.Events...)
+	r.webhooks = append(r.webhooks, webhook)
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (r *webhookMemoryRepository) GetWebhook(ctx context.Context, id string) (domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Webhook{}, contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.Webhook{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, webhook := range r.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+
+	return domain.Webhook{}, &domain.NotFoundError{Message: "Webhook not found"}
+}
+
+// GetWebhooks retrieves every webhook, oldest first
+func (r *webhookMemoryRepository) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]domain.Webhook{}, r.webhooks...), nil
+}
+
+// DeleteWebhook deletes a webhook by ID
+func (r *webhookMemoryRepository) DeleteWebhook(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if !primitive.IsValidObjectID(id) {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, webhook := range r.webhooks {
+		if webhook.ID == id {
+			r.webhooks = append(r.webhooks[:i:i], r.webhooks[i+1:]...)
+			return nil
+		}
+	}
+
+	return &domain.NotFoundError{Message: "Webhook not found"}
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookRepository interface
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (domain.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+}
+
+// webhookRepository struct
+type webhookRepository struct {
+	db         *mongo.Database
+	collection string
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(database *mongo.Database, collection string) WebhookRepository {
+	return &webhookRepository{db: database, collection: collection}
+}
+
+// CreateWebhook stores a new webhook and returns it with its assigned ID
+func (r *webhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	webhook.ID = ""
+	result, err := r.db.Collection(r.collection).InsertOne(ctx, webhook)
+
+	if err != nil {
+		return domain.Webhook{}, databaseError(err, "Error creating webhook")
+	}
+
+	if objId, ok := result.InsertedID.(primitive.ObjectID); ok {
+		webhook.ID = objId.Hex()
+	}
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (r *webhookRepository) GetWebhook(ctx context.Context, id string) (domain.Webhook, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.Webhook{}, &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	var webhook domain.Webhook
+	err = r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": objId}).Decode(&webhook)
+
+	if err == mongo.ErrNoDocuments {
+		return domain.Webhook{}, &domain.NotFoundError{Message: "Webhook not found"}
+	}
+
+	if err != nil {
+		return domain.Webhook{}, databaseError(err, "Error retrieving webhook")
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks retrieves every webhook, oldest first
+func (r *webhookRepository) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	cursor, err := r.db.Collection(r.collection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, databaseError(err, "Error retrieving webhooks")
+	}
+
+	defer cursor.Close(ctx)
+
+	webhooks := []domain.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, databaseError(err, "Error retrieving webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook by ID
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &domain.BadRequestError{Message: "Invalid ID"}
+	}
+
+	result, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": objId})
+	if err != nil {
+		return databaseError(err, "Error deleting webhook")
+	}
+
+	if result.DeletedCount == 0 {
+		return &domain.NotFoundError{Message: "Webhook not found"}
+	}
+
+	return nil
+}
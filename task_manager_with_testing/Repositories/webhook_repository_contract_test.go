@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// WebhookRepositoryContractSuite checks the behaviour every WebhookRepository backend must share
+type WebhookRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func() WebhookRepository
+	repo          WebhookRepository
+}
+
+// SetupTest starts every test with an empty repository
+func (suite *WebhookRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository()
+}
+
+// TestWebhookRepositoryContract_Memory runs the contract against the in-memory backend
+func TestWebhookRepositoryContract_Memory(t *testing.T) {
+	suite.Run(t, &WebhookRepositoryContractSuite{newRepository: NewWebhookMemoryRepository})
+}
+
+// TestWebhookRepositoryContract_Mongo runs the contract against the MongoDB backend
+func TestWebhookRepositoryContract_Mongo(t *testing.T) {
+	client := connectTestDatabase(t)
+	db := client.Database("test_contract_db")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	suite.Run(t, &WebhookRepositoryContractSuite{newRepository: func() WebhookRepository {
+		db.Collection("webhooks").Drop(context.Background())
+		return NewWebhookRepository(db, "webhooks")
+	}})
+}
+
+func (suite *WebhookRepositoryContractSuite) createWebhook(url string, events ...string) domain.Webhook {
+	webhook, err := suite.repo.CreateWebhook(context.Background(), domain.Webhook{
+		URL:       url,
+		Events:    append([]string{}, events...),
+		Secret:    "secret",
+		CreatedBy: "admin",
+		CreatedAt: time.Now(),
+	})
+	suite.Require().NoError(err)
+
+	return webhook
+}
+
+func (suite *WebhookRepositoryContractSuite) TestCreateAndGetWebhook() {
+	created := suite.createWebhook("https://example.com/hook", domain.EventTaskCreated)
+	assert.NotEmpty(suite.T(), created.ID)
+
+	webhook, err := suite.repo.GetWebhook(context.Background(), created.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "https://example.com/hook", webhook.URL)
+	assert.Equal(suite.T(), []string{domain.EventTaskCreated}, webhook.Events)
+	assert.Equal(suite.T(), "secret", webhook.Secret)
+	assert.Equal(suite.T(), "admin", webhook.CreatedBy)
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGetWebhook_Errors() {
+	_, err := suite.repo.GetWebhook(context.Background(), "not-an-id")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.repo.GetWebhook(context.Background(), "0123456789abcdef01234567")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGetWebhooks() {
+	webhooks, err := suite.repo.GetWebhooks(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), webhooks)
+
+	first := suite.createWebhook("https://example.com/first")
+	second := suite.createWebhook("https://example.com/second", domain.EventTaskDeleted)
+
+	webhooks, err = suite.repo.GetWebhooks(context.Background())
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), webhooks, 2) {
+		assert.Equal(suite.T(), first.ID, webhooks[0].ID)
+		assert.Equal(suite.T(), second.ID, webhooks[1].ID)
+	}
+}
+
+func (suite *WebhookRepositoryContractSuite) TestDeleteWebhook() {
+	webhook := suite.createWebhook("https://example.com/hook")
+
+	assert.NoError(suite.T(), suite.repo.DeleteWebhook(context.Background(), webhook.ID))
+
+	_, err := suite.repo.GetWebhook(context.Background(), webhook.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteWebhook(context.Background(), webhook.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.repo.DeleteWebhook(context.Background(), "not-an-id")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
@@ -0,0 +1,173 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+)
+
+// apiKeyTouchInterval is how stale a key's last use may get before it is recorded
+// again, so a busy key does not cost a write on every request
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyUsecase lets users create keys for scripts and services to call the API with.
+// A key acts as the user who created it, limited to its scopes.
+type APIKeyUsecase interface {
+	// CreateKey creates a key for the caller. The key itself is returned this once.
+	CreateKey(ctx context.Context, identity domain.Identity, key domain.APIKey) (domain.APIKey, string, error)
+	// GetKeys lists the caller's keys, oldest first
+	GetKeys(ctx context.Context, identity domain.Identity) ([]domain.APIKey, error)
+	// RevokeKey deletes one of the caller's keys
+	RevokeKey(ctx context.Context, identity domain.Identity, id string) error
+	infrastructure.APIKeyAuthenticator
+}
+
+// apiKeyUsecase struct
+type apiKeyUsecase struct {
+	keyRepo      repositories.APIKeyRepository
+	userRepo     repositories.UserRepository
+	grants       infrastructure.GrantResolver
+	audit        auditRecorder
+	tokenService infrastructure.RefreshTokenService
+	now          func() time.Time
+}
+
+// NewAPIKeyUsecase creates a new API key usecase; keys are generated and hashed like
+// refresh tokens
+func NewAPIKeyUsecase(keyRepo repositories.APIKeyRepository, userRepo repositories.UserRepository, grants infrastructure.GrantResolver, auditRepo repositories.AuditRepository, tokenService infrastructure.RefreshTokenService) APIKeyUsecase {
+	return &apiKeyUsecase{
+		keyRepo:      keyRepo,
+		userRepo:     userRepo,
+		grants:       grants,
+		audit:        auditRecorder{auditRepo},
+		tokenService: tokenService,
+		now:          time.Now,
+	}
+}
+
+// CreateKey creates a key with scopes the caller has. Only its hash is stored.
+func (u *apiKeyUsecase) CreateKey(ctx context.Context, identity domain.Identity, key domain.APIKey) (domain.APIKey, string, error) {
+	if identity.APIKeyID != "" {
+		return domain.APIKey{}, "", &domain.ForbiddenError{Message: "API keys cannot be used to manage API keys"}
+	}
+
+	now := u.now().UTC().Truncate(time.Millisecond)
+	if err := key.Validate(now); err != nil {
+		return domain.APIKey{}, "", &domain.BadRequestError{Message: err.Error()}
+	}
+
+	for _, scope := range key.Scopes {
+		if !identity.Can(scope) {
+			return domain.APIKey{}, "", &domain.ForbiddenError{Message: "You cannot give an API key permissions you do not have"}
+		}
+	}
+
+	existing, err := u.keyRepo.GetKeys(ctx, identity.UserID)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	if len(existing) >= domain.MaxAPIKeysPerUser {
+		return domain.APIKey{}, "", &domain.BadRequestError{Message: fmt.Sprintf("You can have at most %d API keys", domain.MaxAPIKeysPerUser)}
+	}
+
+	token, err := u.tokenService.GenerateToken()
+	if err != nil {
+		return domain.APIKey{}, "", &domain.InternalServerError{Message: "Error generating API key"}
+	}
+
+	secret := domain.APIKeyPrefix + token
+	if key.ExpiresAt != nil {
+		expiresAt := key.ExpiresAt.UTC().Truncate(time.Millisecond)
+		key.ExpiresAt = &expiresAt
+	}
+
+	created, err := u.keyRepo.CreateKey(ctx, domain.APIKey{
+		UserID:    identity.UserID,
+		Name:      key.Name,
+		Hint:      domain.APIKeyHint(secret),
+		KeyHash:   u.tokenService.HashToken(secret),
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditAPIKeyCreate, "api_key", created.ID, nil, apiKeyAuditFields(created))
+	return created, secret, nil
+}
+
+func (u *apiKeyUsecase) GetKeys(ctx context.Context, identity domain.Identity) ([]domain.APIKey, error) {
+	if identity.APIKeyID != "" {
+		return nil, &domain.ForbiddenError{Message: "API keys cannot be used to manage API keys"}
+	}
+
+	return u.keyRepo.GetKeys(ctx, identity.UserID)
+}
+
+// RevokeKey deletes one of the caller's keys; it stops working straight away
+func (u *apiKeyUsecase) RevokeKey(ctx context.Context, identity domain.Identity, id string) error {
+	if identity.APIKeyID != "" {
+		return &domain.ForbiddenError{Message: "API keys cannot be used to manage API keys"}
+	}
+
+	if err := u.keyRepo.DeleteKey(ctx, identity.UserID, id); err != nil {
+		return err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditAPIKeyRevoke, "api_key", id, nil, nil)
+	return nil
+}
+
+// AuthenticateAPIKey returns the identity a key acts as: its user, with the permissions
+// their roles give them today narrowed to the key's scopes. Its use is recorded at most
+// once every apiKeyTouchInterval.
+func (u *apiKeyUsecase) AuthenticateAPIKey(ctx context.Context, secret string) (domain.Identity, error) {
+	if !strings.HasPrefix(secret, domain.APIKeyPrefix) {
+		return domain.Identity{}, &domain.UnauthorizedError{Message: "Invalid API key"}
+	}
+
+	key, err := u.keyRepo.FindByHash(ctx, u.tokenService.HashToken(secret))
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.Identity{}, &domain.UnauthorizedError{Message: "Invalid API key"}
+	}
+	if err != nil {
+		return domain.Identity{}, err
+	}
+
+	now := u.now().UTC().Truncate(time.Millisecond)
+	if key.Expired(now) {
+		return domain.Identity{}, &domain.UnauthorizedError{Message: "API key has expired"}
+	}
+
+	user, err := u.userRepo.FindByID(ctx, key.UserID)
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.Identity{}, &domain.UnauthorizedError{Message: "Invalid API key"}
+	}
+	if err != nil {
+		return domain.Identity{}, err
+	}
+
+	grants, err := u.grants.ResolveGrants(ctx, user.ID)
+	if err != nil {
+		return domain.Identity{}, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := u.keyRepo.TouchKey(ctx, key.ID, now); err != nil {
+			log.Printf("api keys: failed to record the use of key %s: %v", key.ID, err)
+		}
+	}
+
+	identity := domain.NewIdentity(user.ID, user.Username, key.Limit(grants))
+	identity.APIKeyID = key.ID
+	return identity, nil
+}
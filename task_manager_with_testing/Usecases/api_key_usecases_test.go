@@ -0,0 +1,223 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// APIKeyUsecaseTestSuite creates and checks API keys against the in-memory repositories
+type APIKeyUsecaseTestSuite struct {
+	suite.Suite
+	keyRepo   repositories.APIKeyRepository
+	userRepo  repositories.UserRepository
+	roleRepo  repositories.RoleRepository
+	auditRepo repositories.AuditRepository
+	usecase   *apiKeyUsecase
+	now       time.Time
+	alice     domain.Identity
+}
+
+func (suite *APIKeyUsecaseTestSuite) SetupTest() {
+	suite.keyRepo = repositories.NewAPIKeyMemoryRepository()
+	suite.userRepo = repositories.NewUserMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.roleRepo = repositories.NewRoleMemoryRepository()
+	resolver := NewPermissionResolver(suite.userRepo, suite.roleRepo, 0)
+	suite.usecase = NewAPIKeyUsecase(suite.keyRepo, suite.userRepo, resolver, suite.auditRepo, infrastructure.NewRefreshTokenService()).(*apiKeyUsecase)
+	suite.now = time.Now().UTC().Truncate(time.Millisecond)
+	suite.usecase.now = func() time.Time { return suite.now }
+
+	suite.Require().NoError(suite.userRepo.CreateUser(context.Background(), domain.User{Username: "alice", Password: "hashed", Roles: []string{domain.UserRole}}))
+	user, err := suite.userRepo.FindByUsername(context.Background(), "alice")
+	suite.Require().NoError(err)
+	suite.alice = identityWithRoles(user.ID, user.Username, domain.UserRole)
+}
+
+func TestAPIKeyUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(APIKeyUsecaseTestSuite))
+}
+
+func (suite *APIKeyUsecaseTestSuite) createKey(scopes ...string) (domain.APIKey, string) {
+	key, secret, err := suite.usecase.CreateKey(context.Background(), suite.alice, domain.APIKey{Name: "CI", Scopes: scopes})
+	suite.Require().NoError(err)
+	return key, secret
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestCreateKey() {
+	key, secret := suite.createKey(domain.PermissionTaskRead, domain.PermissionTaskCreate)
+
+	assert.True(suite.T(), strings.HasPrefix(secret, domain.APIKeyPrefix))
+	assert.Equal(suite.T(), domain.APIKeyHint(secret), key.Hint)
+	assert.Equal(suite.T(), suite.alice.UserID, key.UserID)
+	assert.NotContains(suite.T(), key.KeyHash, secret)
+
+	keys, err := suite.usecase.GetKeys(context.Background(), suite.alice)
+	suite.Require().NoError(err)
+	suite.Require().Len(keys, 1)
+	assert.Equal(suite.T(), key.ID, keys[0].ID)
+	assert.Nil(suite.T(), keys[0].LastUsedAt)
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestCreateKey_Errors() {
+	_, _, err := suite.usecase.CreateKey(context.Background(), suite.alice, domain.APIKey{Name: "CI"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, _, err = suite.usecase.CreateKey(context.Background(), suite.alice, domain.APIKey{Name: "CI", Scopes: []string{domain.PermissionAuditRead}})
+	assert.EqualError(suite.T(), err, "You cannot give an API key permissions you do not have")
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	withKey := suite.alice
+	withKey.APIKeyID = "key-id"
+	_, _, err = suite.usecase.CreateKey(context.Background(), withKey, domain.APIKey{Name: "CI", Scopes: []string{domain.PermissionTaskRead}})
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	for i := 0; i < domain.MaxAPIKeysPerUser; i++ {
+		suite.createKey(domain.PermissionTaskRead)
+	}
+	_, _, err = suite.usecase.CreateKey(context.Background(), suite.alice, domain.APIKey{Name: "CI", Scopes: []string{domain.PermissionTaskRead}})
+	assert.EqualError(suite.T(), err, "You can have at most 20 API keys")
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestAuthenticateAPIKey_LimitsPermissionsToTheScopes() {
+	key, secret := suite.createKey(domain.PermissionTaskRead)
+
+	identity, err := suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.alice.UserID, identity.UserID)
+	assert.Equal(suite.T(), "alice", identity.Username)
+	assert.Equal(suite.T(), key.ID, identity.APIKeyID)
+	assert.Empty(suite.T(), identity.Roles, "the user role grants more than the key's scopes")
+	assert.Equal(suite.T(), []string{domain.PermissionTaskRead}, identity.Permissions)
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestAuthenticateAPIKey_NarrowKeyCannotTakeRoleTransitions() {
+	user, err := suite.userRepo.FindByID(context.Background(), suite.alice.UserID)
+	suite.Require().NoError(err)
+	user.Roles = []string{domain.AdminRole}
+	suite.Require().NoError(suite.userRepo.UpdateUser(context.Background(), user.ID, user))
+	alice := identityWithRoles(user.ID, user.Username, domain.AdminRole)
+
+	// reopening a cancelled task needs the admin role in the default workflow
+	taskUsecase := NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), suite.auditRepo, &recordingPublisher{}, domain.DefaultWorkflow())
+	task, err := taskUsecase.CreateTask(context.Background(), alice, domain.Task{Title: "Dropped", DueDate: time.Now().Add(time.Hour), Status: "cancelled"})
+	suite.Require().NoError(err)
+	task.Status = "pending"
+
+	_, narrow, err := suite.usecase.CreateKey(context.Background(), alice, domain.APIKey{Name: "Sync", Scopes: []string{domain.PermissionTaskRead, domain.PermissionTaskUpdate}})
+	suite.Require().NoError(err)
+	identity, err := suite.usecase.AuthenticateAPIKey(context.Background(), narrow)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), identity.Roles)
+
+	_, err = taskUsecase.UpdateTask(context.Background(), identity, task.ID, task.Version, task)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	// a key scoped to every permission of the role keeps it
+	_, full, err := suite.usecase.CreateKey(context.Background(), alice, domain.APIKey{Name: "Admin", Scopes: domain.Permissions})
+	suite.Require().NoError(err)
+	identity, err = suite.usecase.AuthenticateAPIKey(context.Background(), full)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{domain.AdminRole}, identity.Roles)
+
+	reopened, err := taskUsecase.UpdateTask(context.Background(), identity, task.ID, task.Version, task)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "pending", reopened.Status)
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestAuthenticateAPIKey_FollowsTheOwnersRoles() {
+	_, secret := suite.createKey(domain.PermissionTaskRead, domain.PermissionTaskDelete)
+
+	_, err := suite.roleRepo.CreateRole(context.Background(), domain.Role{Name: "reader", Permissions: []string{domain.PermissionTaskRead}})
+	suite.Require().NoError(err)
+	user, err := suite.userRepo.FindByID(context.Background(), suite.alice.UserID)
+	suite.Require().NoError(err)
+	user.Roles = []string{"reader"}
+	suite.Require().NoError(suite.userRepo.UpdateUser(context.Background(), user.ID, user))
+
+	identity, err := suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"reader"}, identity.Roles)
+	assert.Equal(suite.T(), []string{domain.PermissionTaskRead}, identity.Permissions)
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestAuthenticateAPIKey_Rejected() {
+	expiresAt := suite.now.Add(time.Hour)
+	_, secret, err := suite.usecase.CreateKey(context.Background(), suite.alice, domain.APIKey{Name: "CI", Scopes: []string{domain.PermissionTaskRead}, ExpiresAt: &expiresAt})
+	suite.Require().NoError(err)
+
+	_, err = suite.usecase.AuthenticateAPIKey(context.Background(), "not-a-key")
+	assert.EqualError(suite.T(), err, "Invalid API key")
+
+	_, err = suite.usecase.AuthenticateAPIKey(context.Background(), secret+"x")
+	assert.EqualError(suite.T(), err, "Invalid API key")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+
+	suite.now = expiresAt
+	_, err = suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+	assert.EqualError(suite.T(), err, "API key has expired")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestAuthenticateAPIKey_RecordsTheLastUse() {
+	_, secret := suite.createKey(domain.PermissionTaskRead)
+	lastUsed := func() time.Time {
+		keys, err := suite.usecase.GetKeys(context.Background(), suite.alice)
+		suite.Require().NoError(err)
+		suite.Require().NotNil(keys[0].LastUsedAt)
+		return *keys[0].LastUsedAt
+	}
+
+	first := suite.now
+	_, err := suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), first.Equal(lastUsed()))
+
+	suite.now = first.Add(apiKeyTouchInterval / 2)
+	_, err = suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), first.Equal(lastUsed()), "a use soon after the last one is not written")
+
+	suite.now = first.Add(apiKeyTouchInterval)
+	_, err = suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), suite.now.Equal(lastUsed()))
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestRevokeKey() {
+	key, secret := suite.createKey(domain.PermissionTaskRead)
+
+	err := suite.usecase.RevokeKey(context.Background(), identityWithRoles("someone-else", "bob", domain.UserRole), key.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	suite.Require().NoError(suite.usecase.RevokeKey(context.Background(), suite.alice, key.ID))
+
+	_, err = suite.usecase.AuthenticateAPIKey(context.Background(), secret)
+	assert.EqualError(suite.T(), err, "Invalid API key")
+}
+
+func (suite *APIKeyUsecaseTestSuite) TestKeysAreAudited() {
+	key, secret := suite.createKey(domain.PermissionTaskRead)
+	suite.Require().NoError(suite.usecase.RevokeKey(context.Background(), suite.alice, key.ID))
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{TargetType: "api_key", Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 2)
+
+	assert.Equal(suite.T(), domain.AuditAPIKeyRevoke, page.Entries[0].Action)
+	assert.Equal(suite.T(), domain.AuditAPIKeyCreate, page.Entries[1].Action)
+	assert.Equal(suite.T(), key.ID, page.Entries[1].TargetID)
+	for _, change := range page.Entries[1].Changes {
+		assert.NotContains(suite.T(), change.After, secret)
+	}
+}
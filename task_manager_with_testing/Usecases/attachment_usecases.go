@@ -0,0 +1,246 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sniffLength is how much of a file is looked at to work out its content type
+const sniffLength = 512
+
+// AttachmentUsecase manages the files attached to tasks. Their metadata is kept on the
+// task and their contents in a blob store. Anyone who can see a task can read its
+// attachments, and anyone who can modify it can add and remove them. It publishes nothing itself but listens to task events
+// so the blobs of a deleted task are removed with it.
+type AttachmentUsecase interface {
+	TaskEventPublisher
+	// UploadAttachment streams a file from r into the blob store and attaches it to the task
+	UploadAttachment(ctx context.Context, identity domain.Identity, taskID, filename string, r io.Reader) (domain.Attachment, error)
+	// GetAttachments lists the task's attachments, oldest first
+	GetAttachments(ctx context.Context, identity domain.Identity, taskID string) ([]domain.Attachment, error)
+	// OpenAttachment returns an attachment with a stream of its contents, which the caller closes
+	OpenAttachment(ctx context.Context, identity domain.Identity, taskID, id string) (domain.Attachment, io.ReadCloser, error)
+	// DeleteAttachment removes an attachment and its contents
+	DeleteAttachment(ctx context.Context, identity domain.Identity, taskID, id string) error
+	// MaxSize is the largest file that can be attached, in bytes
+	MaxSize() int64
+}
+
+// attachmentUsecase struct
+type attachmentUsecase struct {
+	taskRepo    repositories.TaskRepository
+	projectRepo repositories.ProjectRepository
+	blobs       infrastructure.BlobStore
+	audit       auditRecorder
+	maxSize     int64
+}
+
+// NewAttachmentUsecase creates a new attachment usecase that accepts files of up to maxSize bytes
+func NewAttachmentUsecase(taskRepo repositories.TaskRepository, projectRepo repositories.ProjectRepository, blobs infrastructure.BlobStore, auditRepo repositories.AuditRepository, maxSize int64) AttachmentUsecase {
+	return &attachmentUsecase{taskRepo: taskRepo, projectRepo: projectRepo, blobs: blobs, audit: auditRecorder{auditRepo}, maxSize: maxSize}
+}
+
+// UploadAttachment stores the file and then records it on the task. The content type is
+// sniffed from the first bytes and the SHA-256 checksum is worked out as the file streams
+// through, so it is never held in memory. If the task cannot take the attachment after
+// all, the stored blob is removed again.
+func (u *attachmentUsecase) UploadAttachment(ctx context.Context, identity domain.Identity, taskID, filename string, r io.Reader) (domain.Attachment, error) {
+	task, err := u.task(ctx, identity, taskID, true)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+
+	if len(task.Attachments) >= domain.MaxTaskAttachments {
+		return domain.Attachment{}, &domain.BadRequestError{Message: fmt.Sprintf("A task can have at most %d attachments", domain.MaxTaskAttachments)}
+	}
+
+	body := &sizeLimitedReader{r: r, limit: u.maxSize, remaining: u.maxSize}
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return domain.Attachment{}, err
+	}
+	if n == 0 {
+		return domain.Attachment{}, &domain.BadRequestError{Message: "The file is empty"}
+	}
+
+	attachment := domain.Attachment{
+		ID:          primitive.NewObjectID().Hex(),
+		Filename:    domain.CleanFilename(filename),
+		ContentType: http.DetectContentType(head[:n]),
+		UploadedBy:  identity.Username,
+		UploadedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	hash := sha256.New()
+	key := attachmentKey(taskID, attachment.ID)
+	contents := io.TeeReader(io.MultiReader(bytes.NewReader(head[:n]), body), hash)
+	if err := u.blobs.Put(ctx, key, contents); err != nil {
+		return domain.Attachment{}, err
+	}
+
+	attachment.Size = u.maxSize - body.remaining
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if _, err := u.taskRepo.AddAttachment(ctx, taskID, attachment); err != nil {
+		u.deleteBlob(ctx, key)
+		return domain.Attachment{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditAttachmentCreate, "attachment", attachment.ID, nil, attachmentAuditFields(taskID, attachment))
+	return attachment, nil
+}
+
+// GetAttachments lists the attachments of a task the caller can see
+func (u *attachmentUsecase) GetAttachments(ctx context.Context, identity domain.Identity, taskID string) ([]domain.Attachment, error) {
+	task, err := u.task(ctx, identity, taskID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Attachments == nil {
+		return []domain.Attachment{}, nil
+	}
+
+	return task.Attachments, nil
+}
+
+// OpenAttachment opens the contents of an attachment of a task the caller can see
+func (u *attachmentUsecase) OpenAttachment(ctx context.Context, identity domain.Identity, taskID, id string) (domain.Attachment, io.ReadCloser, error) {
+	task, err := u.task(ctx, identity, taskID, false)
+	if err != nil {
+		return domain.Attachment{}, nil, err
+	}
+
+	attachment, ok := task.FindAttachment(id)
+	if !ok {
+		return domain.Attachment{}, nil, &domain.NotFoundError{Message: "Attachment not found"}
+	}
+
+	blob, err := u.blobs.Open(ctx, attachmentKey(taskID, id))
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.Attachment{}, nil, &domain.NotFoundError{Message: "Attachment contents not found"}
+	}
+	if err != nil {
+		return domain.Attachment{}, nil, err
+	}
+
+	return attachment, blob, nil
+}
+
+// DeleteAttachment removes the attachment from the task first, so it is gone even if its
+// blob cannot be deleted; such a blob is left to be removed with the task
+func (u *attachmentUsecase) DeleteAttachment(ctx context.Context, identity domain.Identity, taskID, id string) error {
+	task, err := u.task(ctx, identity, taskID, true)
+	if err != nil {
+		return err
+	}
+
+	attachment, ok := task.FindAttachment(id)
+	if !ok {
+		return &domain.NotFoundError{Message: "Attachment not found"}
+	}
+
+	if _, err := u.taskRepo.RemoveAttachment(ctx, taskID, id); err != nil {
+		return err
+	}
+
+	u.deleteBlob(ctx, attachmentKey(taskID, id))
+	u.audit.record(ctx, identity, domain.AuditAttachmentDelete, "attachment", id, attachmentAuditFields(taskID, attachment), nil)
+	return nil
+}
+
+// MaxSize is the largest file that can be attached, in bytes
+func (u *attachmentUsecase) MaxSize() int64 {
+	return u.maxSize
+}
+
+// Publish removes every blob of a deleted task, including any whose upload finished
+// while the task was being deleted
+func (u *attachmentUsecase) Publish(ctx context.Context, event domain.TaskEvent) {
+	if event.Type != domain.EventTaskDeleted {
+		return
+	}
+
+	deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if err := u.blobs.DeletePrefix(deleteCtx, event.Task.ID); err != nil {
+		log.Printf("attachments: failed to delete the attachments of task %s: %v", event.Task.ID, err)
+	}
+}
+
+// task returns the task if the caller can see it, and can modify it when they are
+// changing its attachments; tasks they cannot see are reported as not found
+func (u *attachmentUsecase) task(ctx context.Context, identity domain.Identity, id string, modify bool) (domain.Task, error) {
+	access, err := loadTaskAccess(ctx, u.projectRepo, identity)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	task, err := u.taskRepo.GetTask(ctx, id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if !access.CanView(task) {
+		return domain.Task{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	if modify && !access.CanEdit(task) {
+		return domain.Task{}, &domain.ForbiddenError{Message: "Viewers cannot modify a project's tasks"}
+	}
+
+	return task, nil
+}
+
+// deleteBlob removes a blob that is no longer attached to anything. Like audit entries,
+// a failure is logged rather than reported to the caller.
+func (u *attachmentUsecase) deleteBlob(ctx context.Context, key string) {
+	deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if err := u.blobs.Delete(deleteCtx, key); err != nil {
+		log.Printf("attachments: failed to delete blob %s: %v", key, err)
+	}
+}
+
+// attachmentKey is where the contents of an attachment are kept in the blob store
+func attachmentKey(taskID, id string) string {
+	return taskID + "/" + id
+}
+
+// sizeLimitedReader fails with a TooLargeError once more than the limit has been read
+type sizeLimitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return 0, &domain.TooLargeError{Message: "Attachments must be at most " + strconv.FormatInt(r.limit, 10) + " bytes"}
+	}
+
+	return n, err
+}
@@ -0,0 +1,195 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// AttachmentUsecaseTestSuite attaches files to tasks on top of the in-memory repositories
+// and a blob store in a temporary directory
+type AttachmentUsecaseTestSuite struct {
+	suite.Suite
+	auditRepo   repositories.AuditRepository
+	blobs       infrastructure.BlobStore
+	taskUsecase TaskUsecase
+	usecase     AttachmentUsecase
+	task        domain.Task
+}
+
+func (suite *AttachmentUsecaseTestSuite) SetupTest() {
+	taskRepo := repositories.NewTaskMemoryRepository()
+	projectRepo := repositories.NewProjectMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+
+	var err error
+	suite.blobs, err = infrastructure.NewLocalBlobStore(suite.T().TempDir())
+	suite.Require().NoError(err)
+
+	suite.usecase = NewAttachmentUsecase(taskRepo, projectRepo, suite.blobs, suite.auditRepo, 1024)
+	suite.taskUsecase = NewTaskUsecase(taskRepo, repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), projectRepo, suite.auditRepo, suite.usecase, domain.DefaultWorkflow())
+
+	suite.task, err = suite.taskUsecase.CreateTask(context.Background(), owner, *batchTask("Attach to me"))
+	suite.Require().NoError(err)
+}
+
+func TestAttachmentUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(AttachmentUsecaseTestSuite))
+}
+
+func (suite *AttachmentUsecaseTestSuite) upload(filename, contents string) domain.Attachment {
+	attachment, err := suite.usecase.UploadAttachment(context.Background(), owner, suite.task.ID, filename, strings.NewReader(contents))
+	suite.Require().NoError(err)
+	return attachment
+}
+
+func (suite *AttachmentUsecaseTestSuite) read(id string) string {
+	_, blob, err := suite.usecase.OpenAttachment(context.Background(), owner, suite.task.ID, id)
+	suite.Require().NoError(err)
+	defer blob.Close()
+
+	contents, err := io.ReadAll(blob)
+	suite.Require().NoError(err)
+	return string(contents)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestUploadAttachment() {
+	sum := sha256.Sum256([]byte("plain notes"))
+
+	attachment := suite.upload("../notes.txt", "plain notes")
+
+	assert.NotEmpty(suite.T(), attachment.ID)
+	assert.Equal(suite.T(), "notes.txt", attachment.Filename)
+	assert.Equal(suite.T(), "text/plain; charset=utf-8", attachment.ContentType)
+	assert.Equal(suite.T(), int64(len("plain notes")), attachment.Size)
+	assert.Equal(suite.T(), hex.EncodeToString(sum[:]), attachment.SHA256)
+	assert.Equal(suite.T(), owner.Username, attachment.UploadedBy)
+	assert.False(suite.T(), attachment.UploadedAt.IsZero())
+	assert.Equal(suite.T(), "plain notes", suite.read(attachment.ID))
+
+	attachments, err := suite.usecase.GetAttachments(context.Background(), owner, suite.task.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []domain.Attachment{attachment}, attachments)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestUploadAttachment_SniffsContentType() {
+	attachment := suite.upload("picture.txt", "\x89PNG\r\n\x1a\n"+strings.Repeat("\x00", 600))
+
+	assert.Equal(suite.T(), "image/png", attachment.ContentType)
+	assert.Equal(suite.T(), int64(608), attachment.Size)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestUploadAttachment_SizeLimit() {
+	attachment := suite.upload("exact.bin", strings.Repeat("a", 1024))
+	assert.Equal(suite.T(), int64(1024), attachment.Size)
+
+	_, err := suite.usecase.UploadAttachment(context.Background(), owner, suite.task.ID, "big.bin", strings.NewReader(strings.Repeat("a", 1025)))
+	assert.IsType(suite.T(), &domain.TooLargeError{}, err)
+
+	attachments, err := suite.usecase.GetAttachments(context.Background(), owner, suite.task.ID)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), attachments, 1)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestUploadAttachment_Errors() {
+	_, err := suite.usecase.UploadAttachment(context.Background(), owner, suite.task.ID, "empty.txt", strings.NewReader(""))
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.UploadAttachment(context.Background(), otherUser, suite.task.ID, "notes.txt", strings.NewReader("hi"))
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.usecase.UploadAttachment(context.Background(), owner, "507f1f77bcf86cd799439011", "notes.txt", strings.NewReader("hi"))
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	for i := 0; i < domain.MaxTaskAttachments; i++ {
+		suite.upload("notes.txt", "hi")
+	}
+	_, err = suite.usecase.UploadAttachment(context.Background(), owner, suite.task.ID, "notes.txt", strings.NewReader("hi"))
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestUploadAttachment_AdminCanAttach() {
+	attachment, err := suite.usecase.UploadAttachment(context.Background(), admin, suite.task.ID, "notes.txt", strings.NewReader("hi"))
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), admin.Username, attachment.UploadedBy)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestAttachmentsSurviveTaskUpdates() {
+	attachment := suite.upload("notes.txt", "hi")
+
+	current, err := suite.taskUsecase.GetTask(context.Background(), owner, suite.task.ID)
+	suite.Require().NoError(err)
+	assert.Greater(suite.T(), current.Version, suite.task.Version)
+
+	update := current
+	update.Title = "Renamed"
+	update.Attachments = nil
+	updated, err := suite.taskUsecase.UpdateTask(context.Background(), owner, suite.task.ID, current.Version, update)
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), []domain.Attachment{attachment}, updated.Attachments)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestOpenAttachment_Errors() {
+	attachment := suite.upload("notes.txt", "hi")
+
+	_, _, err := suite.usecase.OpenAttachment(context.Background(), otherUser, suite.task.ID, attachment.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, _, err = suite.usecase.OpenAttachment(context.Background(), owner, suite.task.ID, "missing")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestDeleteAttachment() {
+	attachment := suite.upload("notes.txt", "hi")
+
+	err := suite.usecase.DeleteAttachment(context.Background(), owner, suite.task.ID, attachment.ID)
+	suite.Require().NoError(err)
+
+	attachments, err := suite.usecase.GetAttachments(context.Background(), owner, suite.task.ID)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), attachments)
+
+	_, err = suite.blobs.Open(context.Background(), suite.task.ID+"/"+attachment.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.usecase.DeleteAttachment(context.Background(), owner, suite.task.ID, attachment.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestDeleteTask_RemovesBlobs() {
+	attachment := suite.upload("notes.txt", "hi")
+
+	err := suite.taskUsecase.DeleteTask(context.Background(), owner, suite.task.ID)
+	suite.Require().NoError(err)
+
+	_, err = suite.blobs.Open(context.Background(), suite.task.ID+"/"+attachment.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *AttachmentUsecaseTestSuite) TestAttachmentsAreAudited() {
+	attachment := suite.upload("notes.txt", "hi")
+	err := suite.usecase.DeleteAttachment(context.Background(), owner, suite.task.ID, attachment.ID)
+	suite.Require().NoError(err)
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{TargetType: "attachment", Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 2)
+
+	assert.Equal(suite.T(), domain.AuditAttachmentDelete, page.Entries[0].Action)
+	assert.Contains(suite.T(), page.Entries[0].Changes, domain.AuditChange{Field: "filename", Before: "notes.txt"})
+	assert.Equal(suite.T(), domain.AuditAttachmentCreate, page.Entries[1].Action)
+	assert.Contains(suite.T(), page.Entries[1].Changes, domain.AuditChange{Field: "sha256", After: attachment.SHA256})
+	assert.Contains(suite.T(), page.Entries[1].Changes, domain.AuditChange{Field: "task_id", After: suite.task.ID})
+}
@@ -0,0 +1,194 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+)
+
+// recordTimeout bounds how long recording an audit entry or task version may take once the change is made
+const recordTimeout = 5 * time.Second
+
+// AuditUsecase interface
+type AuditUsecase interface {
+	GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error)
+	Verify(ctx context.Context) (domain.AuditVerification, error)
+}
+
+// auditUsecase struct
+type auditUsecase struct {
+	auditRepo repositories.AuditRepository
+}
+
+// NewAuditUsecase creates a new audit usecase
+func NewAuditUsecase(auditRepo repositories.AuditRepository) AuditUsecase {
+	return &auditUsecase{auditRepo}
+}
+
+// GetEntries retrieves one page of audit entries matching the query, newest first
+func (u *auditUsecase) GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	if err := query.Validate(); err != nil {
+		return domain.AuditPage{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultAuditPageSize
+	}
+
+	return u.auditRepo.GetEntries(ctx, query)
+}
+
+// Verify walks the whole audit log and reports the first entry that breaks the hash chain
+func (u *auditUsecase) Verify(ctx context.Context) (domain.AuditVerification, error) {
+	entries, err := u.auditRepo.GetChain(ctx)
+	if err != nil {
+		return domain.AuditVerification{}, err
+	}
+
+	return domain.VerifyAuditChain(entries), nil
+}
+
+// auditRecorder appends an audit entry for each mutation made by the usecases
+type auditRecorder struct {
+	auditRepo repositories.AuditRepository
+}
+
+// record stores who changed the target and how. The change has already been made,
+// so a failure to record it is logged rather than reported to the caller, and the
+// entry is still written if the request is cancelled in the meantime.
+func (r auditRecorder) record(ctx context.Context, identity domain.Identity, action, targetType, targetID string, before, after map[string]string) {
+	entry := domain.AuditEntry{
+		Timestamp:  time.Now(),
+		RequestID:  infrastructure.RequestIDFromContext(ctx),
+		ActorID:    identity.UserID,
+		Actor:      identity.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    auditChanges(before, after),
+	}
+
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if _, err := r.auditRepo.Append(writeCtx, entry); err != nil {
+		log.Printf("audit: failed to record %s of %s %s by %s: %v", action, targetType, targetID, identity.Username, err)
+	}
+}
+
+// auditChanges lists the fields whose values differ, ordered by field name
+func auditChanges(before, after map[string]string) []domain.AuditChange {
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	changes := []domain.AuditChange{}
+	for field := range fields {
+		if before[field] != after[field] {
+			changes = append(changes, domain.AuditChange{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+// taskAuditFields lists the audited fields of a task
+func taskAuditFields(task domain.Task) map[string]string {
+	return map[string]string{
+		"title":      task.Title,
+		"due_date":   task.DueDate.UTC().Format(time.RFC3339),
+		"status":     task.Status,
+		"owner_id":   task.OwnerID,
+		"project_id": task.ProjectID,
+		"parent_id":  task.ParentID,
+		"depends_on": strings.Join(task.DependsOn, ","),
+		"tags":       strings.Join(task.Tags, ","),
+		"recurrence": task.Recurrence,
+	}
+}
+
+// commentAuditFields lists the audited fields of a comment
+func commentAuditFields(comment domain.Comment) map[string]string {
+	return map[string]string{
+		"task_id":   comment.TaskID,
+		"parent_id": comment.ParentID,
+		"author":    comment.Author,
+		"body":      comment.Body,
+	}
+}
+
+// attachmentAuditFields lists the audited fields of an attachment
+func attachmentAuditFields(taskID string, attachment domain.Attachment) map[string]string {
+	return map[string]string{
+		"task_id":      taskID,
+		"filename":     attachment.Filename,
+		"content_type": attachment.ContentType,
+		"size":         strconv.FormatInt(attachment.Size, 10),
+		"sha256":       attachment.SHA256,
+	}
+}
+
+// projectAuditFields lists the audited fields of a project
+func projectAuditFields(project domain.Project) map[string]string {
+	return map[string]string{
+		"name":        project.Name,
+		"description": project.Description,
+		"personal_of": project.PersonalOf,
+	}
+}
+
+// projectMemberAuditFields lists the audited fields of a project member
+func projectMemberAuditFields(member domain.ProjectMember) map[string]string {
+	return map[string]string{
+		"user_id":  member.UserID,
+		"username": member.Username,
+		"role":     member.Role,
+	}
+}
+
+// userAuditFields lists the audited fields of a user; the password hash is never recorded
+func userAuditFields(user domain.User) map[string]string {
+	return map[string]string{
+		"username": user.Username,
+		"roles":    strings.Join(user.RoleNames(), ","),
+	}
+}
+
+// roleAuditFields lists the audited fields of a role
+func roleAuditFields(role domain.Role) map[string]string {
+	return map[string]string{
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": strings.Join(role.Permissions, ","),
+	}
+}
+
+// apiKeyAuditFields lists the audited fields of an API key; the key and its hash are
+// never recorded
+func apiKeyAuditFields(key domain.APIKey) map[string]string {
+	fields := map[string]string{
+		"name":   key.Name,
+		"hint":   key.Hint,
+		"scopes": strings.Join(key.Scopes, ","),
+	}
+	if key.ExpiresAt != nil {
+		fields["expires_at"] = key.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	return fields
+}
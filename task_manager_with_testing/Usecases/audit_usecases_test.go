@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	args := m.Called(ctx, entry)
+	return args.Get(0).(domain.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditRepository) GetEntries(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(domain.AuditPage), args.Error(1)
+}
+
+func (m *MockAuditRepository) GetChain(ctx context.Context) ([]domain.AuditEntry, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.AuditEntry), args.Error(1)
+}
+
+type AuditUsecaseTestSuite struct {
+	suite.Suite
+	auditRepo *MockAuditRepository
+	usecase   AuditUsecase
+}
+
+func (suite *AuditUsecaseTestSuite) SetupTest() {
+	suite.auditRepo = new(MockAuditRepository)
+	suite.usecase = NewAuditUsecase(suite.auditRepo)
+}
+
+func (suite *AuditUsecaseTestSuite) TearDownTest() {
+	suite.auditRepo.AssertExpectations(suite.T())
+}
+
+func TestAuditUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditUsecaseTestSuite))
+}
+
+func (suite *AuditUsecaseTestSuite) TestGetEntries() {
+	query := domain.AuditQuery{Action: domain.AuditTaskUpdate, Limit: domain.DefaultAuditPageSize}
+	page := domain.AuditPage{Entries: []domain.AuditEntry{{Sequence: 1}}}
+	suite.auditRepo.On("GetEntries", mock.Anything, query).Return(page, nil)
+
+	result, err := suite.usecase.GetEntries(context.Background(), domain.AuditQuery{Action: domain.AuditTaskUpdate})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), page, result)
+}
+
+func (suite *AuditUsecaseTestSuite) TestGetEntries_InvalidQuery() {
+	now := time.Now()
+	_, err := suite.usecase.GetEntries(context.Background(), domain.AuditQuery{Since: now, Until: now.Add(-time.Hour)})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *AuditUsecaseTestSuite) TestVerify() {
+	first := domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskCreate, TargetID: "1", Timestamp: time.Now()}.Chain(nil)
+	second := domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskDelete, TargetID: "1", Timestamp: time.Now()}.Chain(&first)
+	suite.auditRepo.On("GetChain", mock.Anything).Return([]domain.AuditEntry{first, second}, nil)
+
+	result, err := suite.usecase.Verify(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), result.Valid)
+	assert.Equal(suite.T(), 2, result.Entries)
+}
+
+func (suite *AuditUsecaseTestSuite) TestVerify_Tampered() {
+	first := domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskCreate, TargetID: "1", Timestamp: time.Now()}.Chain(nil)
+	second := domain.AuditEntry{Actor: "admin", Action: domain.AuditTaskDelete, TargetID: "1", Timestamp: time.Now()}.Chain(&first)
+	first.Actor = "someone else"
+	suite.auditRepo.On("GetChain", mock.Anything).Return([]domain.AuditEntry{first, second}, nil)
+
+	result, err := suite.usecase.Verify(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), result.Valid)
+	assert.Equal(suite.T(), int64(1), result.BrokenAt)
+}
+
+func (suite *AuditUsecaseTestSuite) TestAuditChanges() {
+	changes := auditChanges(
+		map[string]string{"title": "Old", "status": "pending", "owner_id": "1"},
+		map[string]string{"title": "New", "status": "pending", "due_date": "2030-01-01T00:00:00Z"},
+	)
+
+	assert.Equal(suite.T(), []domain.AuditChange{
+		{Field: "due_date", After: "2030-01-01T00:00:00Z"},
+		{Field: "owner_id", Before: "1"},
+		{Field: "title", Before: "Old", After: "New"},
+	}, changes)
+}
@@ -0,0 +1,152 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+)
+
+// CalendarUsecase serves each user's open tasks as a calendar feed. Calendar clients
+// cannot send an Authorization header, so the feed is read with a token of its own.
+type CalendarUsecase interface {
+	// CreateToken issues a new feed token for the caller, replacing the one they had
+	CreateToken(ctx context.Context, identity domain.Identity) (string, error)
+	// RevokeToken removes the caller's feed token
+	RevokeToken(ctx context.Context, identity domain.Identity) error
+	// GetFeed returns the open tasks of the user the token belongs to, as the given component
+	GetFeed(ctx context.Context, token, component string) (domain.CalendarFeed, error)
+	// WriteFeed renders the feed as an iCalendar file
+	WriteFeed(w io.Writer, feed domain.CalendarFeed) error
+}
+
+// calendarUsecase struct
+type calendarUsecase struct {
+	tokenRepo    repositories.CalendarTokenRepository
+	userRepo     repositories.UserRepository
+	grants       infrastructure.GrantResolver
+	taskUsecase  TaskUsecase
+	audit        auditRecorder
+	tokenService infrastructure.RefreshTokenService
+	openStates   []string
+}
+
+// NewCalendarUsecase creates a new calendar usecase; feed tokens are generated and hashed
+// like refresh tokens, and feeds list the tasks in the workflow's open states
+func NewCalendarUsecase(tokenRepo repositories.CalendarTokenRepository, userRepo repositories.UserRepository, grants infrastructure.GrantResolver, taskUsecase TaskUsecase, auditRepo repositories.AuditRepository, tokenService infrastructure.RefreshTokenService, workflow domain.Workflow) CalendarUsecase {
+	return &calendarUsecase{tokenRepo: tokenRepo, userRepo: userRepo, grants: grants, taskUsecase: taskUsecase, audit: auditRecorder{auditRepo}, tokenService: tokenService, openStates: workflow.OpenStates()}
+}
+
+// CreateToken issues a new feed token for the caller. Only its hash is stored, and the
+// token it replaces stops working straight away.
+func (u *calendarUsecase) CreateToken(ctx context.Context, identity domain.Identity) (string, error) {
+	token, err := u.tokenService.GenerateToken()
+	if err != nil {
+		return "", &domain.InternalServerError{Message: "Error generating calendar token"}
+	}
+
+	err = u.tokenRepo.SaveToken(ctx, domain.CalendarToken{
+		UserID:    identity.UserID,
+		TokenHash: u.tokenService.HashToken(token),
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditCalendarTokenCreate, "user", identity.Username, nil, nil)
+	return token, nil
+}
+
+// RevokeToken removes the caller's feed token
+func (u *calendarUsecase) RevokeToken(ctx context.Context, identity domain.Identity) error {
+	if err := u.tokenRepo.DeleteToken(ctx, identity.UserID); err != nil {
+		return err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditCalendarTokenRevoke, "user", identity.Username, nil, nil)
+	return nil
+}
+
+// GetFeed returns the open tasks the token's user owns, soonest first, as events
+// unless to-dos are asked for. An admin's feed only has their own tasks too, since a
+// calendar of everyone's would be unusable.
+func (u *calendarUsecase) GetFeed(ctx context.Context, token, component string) (domain.CalendarFeed, error) {
+	if component == "" {
+		component = domain.CalendarEvents
+	}
+	if component != domain.CalendarEvents && component != domain.CalendarTodos {
+		return domain.CalendarFeed{}, &domain.BadRequestError{Message: "component must be either vevent or vtodo"}
+	}
+
+	if token == "" {
+		return domain.CalendarFeed{}, &domain.UnauthorizedError{Message: "Calendar token is required"}
+	}
+
+	stored, err := u.tokenRepo.FindByHash(ctx, u.tokenService.HashToken(token))
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.CalendarFeed{}, &domain.UnauthorizedError{Message: "Invalid calendar token"}
+	}
+	if err != nil {
+		return domain.CalendarFeed{}, err
+	}
+
+	user, err := u.userRepo.FindByID(ctx, stored.UserID)
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.CalendarFeed{}, &domain.UnauthorizedError{Message: "Invalid calendar token"}
+	}
+	if err != nil {
+		return domain.CalendarFeed{}, err
+	}
+
+	grants, err := u.grants.ResolveGrants(ctx, user.ID)
+	if err != nil {
+		return domain.CalendarFeed{}, err
+	}
+
+	identity := domain.NewIdentity(user.ID, user.Username, grants)
+	if !identity.Can(domain.PermissionTaskRead) {
+		return domain.CalendarFeed{}, &domain.ForbiddenError{Message: "You are not authorized for this action"}
+	}
+
+	feed := domain.CalendarFeed{Username: user.Username, Component: component, Tasks: []domain.Task{}}
+	query := domain.TaskQuery{OwnerID: user.ID, Statuses: u.openStates, SortBy: "due_date", Limit: domain.MaxTaskPageSize}
+	for {
+		page, err := u.taskUsecase.GetTasks(ctx, identity, query)
+		if err != nil {
+			return domain.CalendarFeed{}, err
+		}
+
+		feed.Tasks = append(feed.Tasks, page.Tasks...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	feed.Tag = calendarTag(feed)
+	return feed, nil
+}
+
+// WriteFeed renders the feed as an iCalendar file
+func (u *calendarUsecase) WriteFeed(w io.Writer, feed domain.CalendarFeed) error {
+	return infrastructure.WriteCalendar(w, feed, time.Now())
+}
+
+// calendarTag fingerprints everything the rendered feed depends on. Any change to a
+// task bumps its version, so the task IDs and versions stand in for their contents.
+func calendarTag(feed domain.CalendarFeed) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", feed.Username, feed.Component)
+	for _, task := range feed.Tasks {
+		fmt.Fprintf(hash, "%s:%d\n", task.ID, task.Version)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
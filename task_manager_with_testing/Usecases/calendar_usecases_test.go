@@ -0,0 +1,210 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// CalendarUsecaseTestSuite serves calendar feeds from the in-memory repositories
+type CalendarUsecaseTestSuite struct {
+	suite.Suite
+	auditRepo   repositories.AuditRepository
+	userRepo    repositories.UserRepository
+	roleRepo    repositories.RoleRepository
+	taskUsecase TaskUsecase
+	usecase     CalendarUsecase
+	alice       domain.Identity
+	bob         domain.Identity
+}
+
+func (suite *CalendarUsecaseTestSuite) SetupTest() {
+	userRepo := repositories.NewUserMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.taskUsecase = NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), suite.auditRepo, &recordingPublisher{}, domain.DefaultWorkflow())
+	suite.userRepo = userRepo
+	suite.roleRepo = repositories.NewRoleMemoryRepository()
+	suite.usecase = NewCalendarUsecase(repositories.NewCalendarTokenMemoryRepository(), userRepo, NewPermissionResolver(userRepo, suite.roleRepo, 0), suite.taskUsecase, suite.auditRepo, infrastructure.NewRefreshTokenService(), domain.DefaultWorkflow())
+
+	suite.alice = suite.createUser(userRepo, "alice", "user")
+	suite.bob = suite.createUser(userRepo, "bob", domain.AdminRole)
+}
+
+func TestCalendarUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(CalendarUsecaseTestSuite))
+}
+
+func (suite *CalendarUsecaseTestSuite) createUser(userRepo repositories.UserRepository, username, role string) domain.Identity {
+	suite.Require().NoError(userRepo.CreateUser(context.Background(), domain.User{Username: username, Password: "hashed", Roles: []string{role}}))
+	user, err := userRepo.FindByUsername(context.Background(), username)
+	suite.Require().NoError(err)
+
+	return identityWithRoles(user.ID, user.Username, role)
+}
+
+func (suite *CalendarUsecaseTestSuite) createTask(identity domain.Identity, title string) domain.Task {
+	task, err := suite.taskUsecase.CreateTask(context.Background(), identity, *batchTask(title))
+	suite.Require().NoError(err)
+	return task
+}
+
+func (suite *CalendarUsecaseTestSuite) token(identity domain.Identity) string {
+	token, err := suite.usecase.CreateToken(context.Background(), identity)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(token)
+	return token
+}
+
+func titles(tasks []domain.Task) []string {
+	titles := make([]string, len(tasks))
+	for i, task := range tasks {
+		titles[i] = task.Title
+	}
+	return titles
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_HasOnlyTheUsersPendingTasks() {
+	suite.createTask(suite.alice, "Pay rent")
+	_, err := suite.taskUsecase.CreateTask(context.Background(), suite.alice, domain.Task{Title: "Call back", DueDate: time.Now().Add(-time.Hour), Status: "completed"})
+	suite.Require().NoError(err)
+	suite.createTask(suite.bob, "Someone else's")
+
+	feed, err := suite.usecase.GetFeed(context.Background(), suite.token(suite.alice), domain.CalendarTodos)
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "alice", feed.Username)
+	assert.Equal(suite.T(), domain.CalendarTodos, feed.Component)
+	assert.Equal(suite.T(), []string{"Pay rent"}, titles(feed.Tasks))
+	assert.NotEmpty(suite.T(), feed.Tag)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_AdminsOnlySeeTheirOwnTasks() {
+	suite.createTask(suite.alice, "Pay rent")
+	suite.createTask(suite.bob, "Review")
+
+	feed, err := suite.usecase.GetFeed(context.Background(), suite.token(suite.bob), domain.CalendarEvents)
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"Review"}, titles(feed.Tasks))
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_PagesThroughEveryTask() {
+	for i := 0; i < domain.MaxTaskPageSize+5; i++ {
+		suite.createTask(suite.alice, fmt.Sprintf("Task %03d", i))
+	}
+
+	feed, err := suite.usecase.GetFeed(context.Background(), suite.token(suite.alice), domain.CalendarEvents)
+
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), feed.Tasks, domain.MaxTaskPageSize+5)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_TagChangesWithTheTasks() {
+	token := suite.token(suite.alice)
+	task := suite.createTask(suite.alice, "Pay rent")
+
+	first, err := suite.usecase.GetFeed(context.Background(), token, domain.CalendarEvents)
+	suite.Require().NoError(err)
+	again, err := suite.usecase.GetFeed(context.Background(), token, domain.CalendarEvents)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), first.Tag, again.Tag)
+
+	todos, err := suite.usecase.GetFeed(context.Background(), token, domain.CalendarTodos)
+	suite.Require().NoError(err)
+	assert.NotEqual(suite.T(), first.Tag, todos.Tag)
+
+	task.Title = "Pay the rent"
+	_, err = suite.taskUsecase.UpdateTask(context.Background(), suite.alice, task.ID, task.Version, task)
+	suite.Require().NoError(err)
+
+	updated, err := suite.usecase.GetFeed(context.Background(), token, domain.CalendarEvents)
+	suite.Require().NoError(err)
+	assert.NotEqual(suite.T(), first.Tag, updated.Tag)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_InvalidComponent() {
+	_, err := suite.usecase.GetFeed(context.Background(), suite.token(suite.alice), "vjournal")
+
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_InvalidToken() {
+	suite.token(suite.alice)
+
+	_, err := suite.usecase.GetFeed(context.Background(), "not-a-token", domain.CalendarEvents)
+	assert.EqualError(suite.T(), err, "Invalid calendar token")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+
+	_, err = suite.usecase.GetFeed(context.Background(), "", domain.CalendarEvents)
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestGetFeed_RequiresTaskRead() {
+	_, err := suite.roleRepo.CreateRole(context.Background(), domain.Role{Name: "auditor", Permissions: []string{domain.PermissionAuditRead}})
+	suite.Require().NoError(err)
+	auditor := suite.createUser(suite.userRepo, "carol", "auditor")
+
+	_, err = suite.usecase.GetFeed(context.Background(), suite.token(auditor), domain.CalendarEvents)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestCreateToken_RevokesThePreviousToken() {
+	old := suite.token(suite.alice)
+	current := suite.token(suite.alice)
+	assert.NotEqual(suite.T(), old, current)
+
+	_, err := suite.usecase.GetFeed(context.Background(), old, domain.CalendarEvents)
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+
+	_, err = suite.usecase.GetFeed(context.Background(), current, domain.CalendarEvents)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestRevokeToken() {
+	token := suite.token(suite.alice)
+
+	suite.Require().NoError(suite.usecase.RevokeToken(context.Background(), suite.alice))
+
+	_, err := suite.usecase.GetFeed(context.Background(), token, domain.CalendarEvents)
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+
+	err = suite.usecase.RevokeToken(context.Background(), suite.alice)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestTokensAreAudited() {
+	suite.token(suite.alice)
+	suite.Require().NoError(suite.usecase.RevokeToken(context.Background(), suite.alice))
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{TargetType: "user", Limit: 10})
+	suite.Require().NoError(err)
+
+	var actions []string
+	for _, entry := range page.Entries {
+		actions = append(actions, entry.Action)
+	}
+	assert.ElementsMatch(suite.T(), []string{domain.AuditCalendarTokenCreate, domain.AuditCalendarTokenRevoke}, actions)
+}
+
+func (suite *CalendarUsecaseTestSuite) TestWriteFeed() {
+	suite.createTask(suite.alice, "Pay rent")
+	feed, err := suite.usecase.GetFeed(context.Background(), suite.token(suite.alice), domain.CalendarEvents)
+	suite.Require().NoError(err)
+
+	var out bytes.Buffer
+	suite.Require().NoError(suite.usecase.WriteFeed(&out, feed))
+
+	assert.True(suite.T(), strings.HasPrefix(out.String(), "BEGIN:VCALENDAR\r\n"))
+	assert.Contains(suite.T(), out.String(), "UID:"+feed.Tasks[0].ID+"@task-manager\r\n")
+	assert.Contains(suite.T(), out.String(), "SUMMARY:Pay rent\r\n")
+}
@@ -0,0 +1,195 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+)
+
+// CommentUsecase manages the discussion of each task. Anyone who can see a task can read
+// and add to its comments; only a comment's author or an admin can edit or delete it.
+type CommentUsecase interface {
+	// AddComment adds a comment to a task, as a reply if it names a parent
+	AddComment(ctx context.Context, identity domain.Identity, taskID string, comment domain.Comment) (domain.Comment, error)
+	// GetComments retrieves the comments on a task as threads, oldest first
+	GetComments(ctx context.Context, identity domain.Identity, taskID string) ([]domain.Comment, error)
+	// UpdateComment changes the body of a comment and marks it as edited
+	UpdateComment(ctx context.Context, identity domain.Identity, taskID, id string, comment domain.Comment) (domain.Comment, error)
+	// DeleteComment marks a comment as deleted, keeping its replies in place
+	DeleteComment(ctx context.Context, identity domain.Identity, taskID, id string) error
+}
+
+// commentUsecase struct
+type commentUsecase struct {
+	commentRepo repositories.CommentRepository
+	taskUsecase TaskUsecase
+	audit       auditRecorder
+}
+
+// NewCommentUsecase creates a new comment usecase; access to a task's comments follows
+// access to the task through the task usecase
+func NewCommentUsecase(commentRepo repositories.CommentRepository, taskUsecase TaskUsecase, auditRepo repositories.AuditRepository) CommentUsecase {
+	return &commentUsecase{commentRepo: commentRepo, taskUsecase: taskUsecase, audit: auditRecorder{auditRepo}}
+}
+
+// AddComment adds a comment by the caller to a task they can see. A reply must name a
+// comment on the same task that has not been deleted.
+func (u *commentUsecase) AddComment(ctx context.Context, identity domain.Identity, taskID string, comment domain.Comment) (domain.Comment, error) {
+	if err := comment.Validate(); err != nil {
+		return domain.Comment{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	if _, err := u.taskUsecase.GetTask(ctx, identity, taskID); err != nil {
+		return domain.Comment{}, err
+	}
+
+	if comment.ParentID != "" {
+		parent, err := u.comment(ctx, taskID, comment.ParentID)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return domain.Comment{}, &domain.BadRequestError{Message: "parent_id must name a comment on this task"}
+		}
+		if err != nil {
+			return domain.Comment{}, err
+		}
+
+		if parent.Deleted {
+			return domain.Comment{}, &domain.BadRequestError{Message: "Cannot reply to a deleted comment"}
+		}
+	}
+
+	created, err := u.commentRepo.CreateComment(ctx, domain.Comment{
+		TaskID:    taskID,
+		ParentID:  comment.ParentID,
+		AuthorID:  identity.UserID,
+		Author:    identity.Username,
+		Body:      comment.Body,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditCommentCreate, "comment", created.ID, nil, commentAuditFields(created))
+	return created, nil
+}
+
+// GetComments retrieves the comments on a task the caller can see, with every reply
+// nested under the comment it answers. Deleted comments are kept without their body.
+func (u *commentUsecase) GetComments(ctx context.Context, identity domain.Identity, taskID string) ([]domain.Comment, error) {
+	if _, err := u.taskUsecase.GetTask(ctx, identity, taskID); err != nil {
+		return nil, err
+	}
+
+	comments, err := u.commentRepo.GetComments(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range comments {
+		comments[i] = redactComment(comments[i])
+	}
+
+	return domain.CommentThreads(comments), nil
+}
+
+// UpdateComment replaces the body of a comment the caller may modify and marks it as
+// edited. Deleted comments cannot be edited.
+func (u *commentUsecase) UpdateComment(ctx context.Context, identity domain.Identity, taskID, id string, comment domain.Comment) (domain.Comment, error) {
+	if err := comment.Validate(); err != nil {
+		return domain.Comment{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	existing, err := u.authorizeModification(ctx, identity, taskID, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	if existing.Deleted {
+		return domain.Comment{}, &domain.BadRequestError{Message: "Cannot edit a deleted comment"}
+	}
+
+	if existing.Body == comment.Body {
+		return existing, nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	updated := existing
+	updated.Body = comment.Body
+	updated.Edited, updated.EditedAt = true, &now
+
+	updated, err = u.commentRepo.UpdateComment(ctx, updated)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditCommentUpdate, "comment", id, commentAuditFields(existing), commentAuditFields(updated))
+	return updated, nil
+}
+
+// DeleteComment marks a comment the caller may modify as deleted. It stays in the
+// thread, without its body, so the replies to it keep their place.
+func (u *commentUsecase) DeleteComment(ctx context.Context, identity domain.Identity, taskID, id string) error {
+	existing, err := u.authorizeModification(ctx, identity, taskID, id)
+	if err != nil {
+		return err
+	}
+
+	if existing.Deleted {
+		return &domain.NotFoundError{Message: "Comment not found"}
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	deleted := existing
+	deleted.Deleted, deleted.DeletedAt = true, &now
+
+	if _, err := u.commentRepo.UpdateComment(ctx, deleted); err != nil {
+		return err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditCommentDelete, "comment", id, commentAuditFields(existing), nil)
+	return nil
+}
+
+// authorizeModification returns the comment if it is on a task the caller can see and
+// they may modify it
+func (u *commentUsecase) authorizeModification(ctx context.Context, identity domain.Identity, taskID, id string) (domain.Comment, error) {
+	if _, err := u.taskUsecase.GetTask(ctx, identity, taskID); err != nil {
+		return domain.Comment{}, err
+	}
+
+	existing, err := u.comment(ctx, taskID, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	if !identity.CanModifyComment(existing) {
+		return domain.Comment{}, &domain.ForbiddenError{Message: "You can only modify your own comments"}
+	}
+
+	return existing, nil
+}
+
+// comment retrieves a comment by ID, reporting comments on other tasks as not found
+func (u *commentUsecase) comment(ctx context.Context, taskID, id string) (domain.Comment, error) {
+	comment, err := u.commentRepo.GetComment(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	if comment.TaskID != taskID {
+		return domain.Comment{}, &domain.NotFoundError{Message: "Comment not found"}
+	}
+
+	return comment, nil
+}
+
+// redactComment hides the body of a deleted comment
+func redactComment(comment domain.Comment) domain.Comment {
+	if comment.Deleted {
+		comment.Body = ""
+	}
+
+	return comment
+}
@@ -0,0 +1,195 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// CommentUsecaseTestSuite discusses tasks on top of the in-memory repositories
+type CommentUsecaseTestSuite struct {
+	suite.Suite
+	auditRepo   repositories.AuditRepository
+	taskUsecase TaskUsecase
+	usecase     CommentUsecase
+	task        domain.Task
+}
+
+func (suite *CommentUsecaseTestSuite) SetupTest() {
+	commentRepo := repositories.NewCommentMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.taskUsecase = NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), commentRepo, repositories.NewProjectMemoryRepository(), suite.auditRepo, &recordingPublisher{}, domain.DefaultWorkflow())
+	suite.usecase = NewCommentUsecase(commentRepo, suite.taskUsecase, suite.auditRepo)
+
+	var err error
+	suite.task, err = suite.taskUsecase.CreateTask(context.Background(), owner, *batchTask("Discuss me"))
+	suite.Require().NoError(err)
+}
+
+func TestCommentUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(CommentUsecaseTestSuite))
+}
+
+func (suite *CommentUsecaseTestSuite) addComment(identity domain.Identity, parentID, body string) domain.Comment {
+	comment, err := suite.usecase.AddComment(context.Background(), identity, suite.task.ID, domain.Comment{ParentID: parentID, Body: body})
+	suite.Require().NoError(err)
+	return comment
+}
+
+func (suite *CommentUsecaseTestSuite) comments(identity domain.Identity) []domain.Comment {
+	comments, err := suite.usecase.GetComments(context.Background(), identity, suite.task.ID)
+	suite.Require().NoError(err)
+	return comments
+}
+
+func (suite *CommentUsecaseTestSuite) TestAddComment() {
+	comment := suite.addComment(owner, "", "  Should we split this up? ")
+
+	assert.NotEmpty(suite.T(), comment.ID)
+	assert.Equal(suite.T(), suite.task.ID, comment.TaskID)
+	assert.Equal(suite.T(), owner.UserID, comment.AuthorID)
+	assert.Equal(suite.T(), owner.Username, comment.Author)
+	assert.Equal(suite.T(), "Should we split this up?", comment.Body)
+	assert.False(suite.T(), comment.CreatedAt.IsZero())
+	assert.False(suite.T(), comment.Edited)
+}
+
+func (suite *CommentUsecaseTestSuite) TestAddComment_AuthorIsTheCaller() {
+	comment, err := suite.usecase.AddComment(context.Background(), admin, suite.task.ID, domain.Comment{AuthorID: owner.UserID, Author: "someone", Body: "Moderated"})
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), admin.UserID, comment.AuthorID)
+	assert.Equal(suite.T(), admin.Username, comment.Author)
+}
+
+func (suite *CommentUsecaseTestSuite) TestAddComment_Errors() {
+	_, err := suite.usecase.AddComment(context.Background(), owner, suite.task.ID, domain.Comment{Body: "   "})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.AddComment(context.Background(), otherUser, suite.task.ID, domain.Comment{Body: "Hi"})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	other, err := suite.taskUsecase.CreateTask(context.Background(), owner, *batchTask("Other task"))
+	suite.Require().NoError(err)
+	elsewhere, err := suite.usecase.AddComment(context.Background(), owner, other.ID, domain.Comment{Body: "Elsewhere"})
+	suite.Require().NoError(err)
+
+	_, err = suite.usecase.AddComment(context.Background(), owner, suite.task.ID, domain.Comment{ParentID: elsewhere.ID, Body: "Reply"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *CommentUsecaseTestSuite) TestGetComments_Threads() {
+	first := suite.addComment(owner, "", "First")
+	second := suite.addComment(admin, "", "Second")
+	reply := suite.addComment(admin, first.ID, "Reply")
+	nested := suite.addComment(owner, reply.ID, "Nested")
+
+	comments := suite.comments(owner)
+	suite.Require().Len(comments, 2)
+	assert.Equal(suite.T(), first.ID, comments[0].ID)
+	assert.Equal(suite.T(), second.ID, comments[1].ID)
+	suite.Require().Len(comments[0].Replies, 1)
+	assert.Equal(suite.T(), reply.ID, comments[0].Replies[0].ID)
+	suite.Require().Len(comments[0].Replies[0].Replies, 1)
+	assert.Equal(suite.T(), nested.ID, comments[0].Replies[0].Replies[0].ID)
+
+	_, err := suite.usecase.GetComments(context.Background(), otherUser, suite.task.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CommentUsecaseTestSuite) TestUpdateComment_MarksEdited() {
+	comment := suite.addComment(owner, "", "Frist")
+
+	updated, err := suite.usecase.UpdateComment(context.Background(), owner, suite.task.ID, comment.ID, domain.Comment{Body: "First"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "First", updated.Body)
+	assert.True(suite.T(), updated.Edited)
+	suite.Require().NotNil(updated.EditedAt)
+
+	unchanged, err := suite.usecase.UpdateComment(context.Background(), owner, suite.task.ID, comment.ID, domain.Comment{Body: "First"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), updated.EditedAt, unchanged.EditedAt)
+
+	assert.True(suite.T(), suite.comments(owner)[0].Edited)
+}
+
+func (suite *CommentUsecaseTestSuite) TestUpdateComment_OnlyAuthorOrAdmin() {
+	comment := suite.addComment(admin, "", "From the admin")
+
+	_, err := suite.usecase.UpdateComment(context.Background(), owner, suite.task.ID, comment.ID, domain.Comment{Body: "Hijacked"})
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	comment = suite.addComment(owner, "", "From the owner")
+	_, err = suite.usecase.UpdateComment(context.Background(), admin, suite.task.ID, comment.ID, domain.Comment{Body: "Moderated"})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *CommentUsecaseTestSuite) TestUpdateComment_WrongTask() {
+	comment := suite.addComment(owner, "", "First")
+	other, err := suite.taskUsecase.CreateTask(context.Background(), owner, *batchTask("Other task"))
+	suite.Require().NoError(err)
+
+	_, err = suite.usecase.UpdateComment(context.Background(), owner, other.ID, comment.ID, domain.Comment{Body: "Moved"})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CommentUsecaseTestSuite) TestDeleteComment_KeepsReplies() {
+	first := suite.addComment(owner, "", "First")
+	suite.addComment(admin, first.ID, "Reply")
+
+	suite.Require().NoError(suite.usecase.DeleteComment(context.Background(), owner, suite.task.ID, first.ID))
+
+	comments := suite.comments(owner)
+	suite.Require().Len(comments, 1)
+	assert.True(suite.T(), comments[0].Deleted)
+	assert.NotNil(suite.T(), comments[0].DeletedAt)
+	assert.Empty(suite.T(), comments[0].Body)
+	suite.Require().Len(comments[0].Replies, 1)
+	assert.Equal(suite.T(), "Reply", comments[0].Replies[0].Body)
+
+	err := suite.usecase.DeleteComment(context.Background(), owner, suite.task.ID, first.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.usecase.UpdateComment(context.Background(), owner, suite.task.ID, first.ID, domain.Comment{Body: "Back"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.AddComment(context.Background(), owner, suite.task.ID, domain.Comment{ParentID: first.ID, Body: "Reply"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *CommentUsecaseTestSuite) TestDeleteComment_OnlyAuthorOrAdmin() {
+	comment := suite.addComment(admin, "", "From the admin")
+	err := suite.usecase.DeleteComment(context.Background(), owner, suite.task.ID, comment.ID)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	comment = suite.addComment(owner, "", "From the owner")
+	assert.NoError(suite.T(), suite.usecase.DeleteComment(context.Background(), admin, suite.task.ID, comment.ID))
+}
+
+func (suite *CommentUsecaseTestSuite) TestDeleteTask_RemovesComments() {
+	suite.addComment(owner, "", "First")
+
+	suite.Require().NoError(suite.taskUsecase.DeleteTask(context.Background(), owner, suite.task.ID))
+
+	_, err := suite.usecase.GetComments(context.Background(), owner, suite.task.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *CommentUsecaseTestSuite) TestCommentChangesAreAudited() {
+	comment := suite.addComment(owner, "", "First")
+	_, err := suite.usecase.UpdateComment(context.Background(), admin, suite.task.ID, comment.ID, domain.Comment{Body: "Moderated"})
+	suite.Require().NoError(err)
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{TargetType: "comment", Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 2)
+	assert.Equal(suite.T(), domain.AuditCommentUpdate, page.Entries[0].Action)
+	assert.Equal(suite.T(), admin.Username, page.Entries[0].Actor)
+	assert.Equal(suite.T(), []domain.AuditChange{{Field: "body", Before: "First", After: "Moderated"}}, page.Entries[0].Changes)
+	assert.Equal(suite.T(), domain.AuditCommentCreate, page.Entries[1].Action)
+}
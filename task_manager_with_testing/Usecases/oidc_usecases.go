@@ -0,0 +1,172 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+)
+
+// OIDCUsecase logs users in through an OpenID Connect identity provider
+type OIDCUsecase interface {
+	// BeginLogin starts a login and returns the provider page to send the browser to,
+	// along with the state the provider sends back to complete the login
+	BeginLogin(ctx context.Context) (string, string, error)
+	// CompleteLogin finishes the login the provider sent the browser back from with
+	// the given state and authorization code
+	CompleteLogin(ctx context.Context, state, code string) (domain.TokenPair, error)
+}
+
+// OIDCSettings says how ID token claims describe a user. Without a RoleClaim, roles
+// are managed in this service. With one, its values are the user's roles; when a
+// RoleMapping is set, only the values it maps give roles.
+type OIDCSettings struct {
+	UsernameClaim string
+	RoleClaim     string
+	RoleMapping   map[string]string
+}
+
+// oidcUsecase struct
+type oidcUsecase struct {
+	client       infrastructure.OIDCClient
+	loginRepo    repositories.OIDCLoginRepository
+	users        UserUsecase
+	tokenService infrastructure.RefreshTokenService
+	settings     OIDCSettings
+}
+
+// NewOIDCUsecase creates a new single sign-on usecase. The state, nonce and PKCE code
+// verifier of each login are random tokens generated like refresh tokens.
+func NewOIDCUsecase(client infrastructure.OIDCClient, loginRepo repositories.OIDCLoginRepository, users UserUsecase, tokenService infrastructure.RefreshTokenService, settings OIDCSettings) OIDCUsecase {
+	return &oidcUsecase{
+		client:       client,
+		loginRepo:    loginRepo,
+		users:        users,
+		tokenService: tokenService,
+		settings:     settings,
+	}
+}
+
+// BeginLogin stores a new login, keyed by the hash of its state, and returns the URL
+// that asks the provider to authenticate the user for it, along with the state
+func (u *oidcUsecase) BeginLogin(ctx context.Context) (string, string, error) {
+	secrets := make([]string, 3)
+	for i := range secrets {
+		secret, err := u.tokenService.GenerateToken()
+		if err != nil {
+			return "", "", &domain.InternalServerError{Message: "Error starting single sign-on"}
+		}
+		secrets[i] = secret
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	authURL, err := u.client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc: failed to build the authorization URL: %v", err)
+		return "", "", &domain.InternalServerError{Message: "Error contacting the identity provider"}
+	}
+
+	err = u.loginRepo.SaveLogin(ctx, domain.OIDCLogin{
+		StateHash:    u.tokenService.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(domain.OIDCLoginExpiry).UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// CompleteLogin redeems the code for the login the state belongs to and logs in the
+// user the ID token names, creating them on their first login
+func (u *oidcUsecase) CompleteLogin(ctx context.Context, state, code string) (domain.TokenPair, error) {
+	if state == "" || code == "" {
+		return domain.TokenPair{}, &domain.BadRequestError{Message: "state and code are required"}
+	}
+
+	login, err := u.loginRepo.TakeLogin(ctx, u.tokenService.HashToken(state))
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.TokenPair{}, &domain.UnauthorizedError{Message: "Single sign-on login has expired, please try again"}
+	}
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	claims, err := u.client.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if errors.Is(err, infrastructure.ErrOIDCRejected) {
+		log.Printf("oidc: login rejected: %v", err)
+		return domain.TokenPair{}, &domain.UnauthorizedError{Message: "Single sign-on failed"}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return domain.TokenPair{}, &domain.TimeoutError{Message: "Request timed out"}
+		}
+		log.Printf("oidc: failed to redeem the authorization code: %v", err)
+		return domain.TokenPair{}, &domain.InternalServerError{Message: "Error contacting the identity provider"}
+	}
+
+	return u.users.LoginExternal(ctx, u.account(claims))
+}
+
+// account describes the user the verified ID token claims name
+func (u *oidcUsecase) account(claims map[string]interface{}) domain.ExternalAccount {
+	account := domain.ExternalAccount{}
+	account.Issuer, _ = claims["iss"].(string)
+	account.Subject, _ = claims["sub"].(string)
+
+	for _, claim := range []string{u.settings.UsernameClaim, "email", "sub"} {
+		if username, ok := claims[claim].(string); ok && strings.TrimSpace(username) != "" {
+			account.Username = strings.TrimSpace(username)
+			break
+		}
+	}
+
+	if u.settings.RoleClaim != "" {
+		account.Roles = u.roles(claims[u.settings.RoleClaim])
+	}
+
+	return account
+}
+
+// roles maps the values of the role claim, a string or a list of them, to role names.
+// Values that are not mapped, or not valid role names, are skipped; a user left
+// without roles gets the default user role.
+func (u *oidcUsecase) roles(claim interface{}) []string {
+	values := []string{}
+	switch claim := claim.(type) {
+	case string:
+		values = append(values, claim)
+	case []interface{}:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	roles := []string{}
+	for _, value := range values {
+		role := value
+		if len(u.settings.RoleMapping) > 0 {
+			role = u.settings.RoleMapping[value]
+		}
+
+		if _, err := domain.ValidateUserRoles([]string{role}); err == nil && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		return []string{domain.UserRole}
+	}
+
+	return roles
+}
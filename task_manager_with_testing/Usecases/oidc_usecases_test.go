@@ -0,0 +1,322 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testIssuer = "https://login.example.com"
+
+type MockOIDCClient struct {
+	mock.Mock
+}
+
+func (m *MockOIDCClient) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	args := m.Called(ctx, state, nonce, codeVerifier)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOIDCClient) Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error) {
+	args := m.Called(ctx, code, codeVerifier, nonce)
+	claims, _ := args.Get(0).(map[string]interface{})
+	return claims, args.Error(1)
+}
+
+// OIDCUsecaseTestSuite logs users in with a mocked provider against the in-memory repositories
+type OIDCUsecaseTestSuite struct {
+	suite.Suite
+	client    *MockOIDCClient
+	userRepo  repositories.UserRepository
+	roleRepo  repositories.RoleRepository
+	auditRepo repositories.AuditRepository
+	resolver  PermissionResolver
+	settings  OIDCSettings
+	usecase   OIDCUsecase
+}
+
+func (suite *OIDCUsecaseTestSuite) SetupTest() {
+	suite.client = new(MockOIDCClient)
+	suite.userRepo = repositories.NewUserMemoryRepository()
+	suite.roleRepo = repositories.NewRoleMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.resolver = NewPermissionResolver(suite.userRepo, suite.roleRepo, time.Minute)
+	suite.settings = OIDCSettings{UsernameClaim: "preferred_username"}
+	suite.newUsecase()
+}
+
+func (suite *OIDCUsecaseTestSuite) newUsecase() {
+	tokenService := infrastructure.NewRefreshTokenService()
+	users := NewUserUsecase(suite.userRepo, repositories.NewRefreshTokenMemoryRepository(), repositories.NewRevokedTokenMemoryRepository(), suite.auditRepo,
+		infrastructure.NewPasswordService(), infrastructure.NewJWTService("0123456789abcdef0123456789abcdef", "task-manager", time.Minute), tokenService, time.Hour, suite.resolver)
+	suite.usecase = NewOIDCUsecase(suite.client, repositories.NewOIDCLoginMemoryRepository(), users, tokenService, suite.settings)
+}
+
+func (suite *OIDCUsecaseTestSuite) TearDownTest() {
+	suite.client.AssertExpectations(suite.T())
+}
+
+func TestOIDCUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(OIDCUsecaseTestSuite))
+}
+
+// login runs a whole login in which the provider vouches for the given claims
+func (suite *OIDCUsecaseTestSuite) login(claims map[string]interface{}) (domain.TokenPair, error) {
+	var state, nonce, verifier string
+	suite.client.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		state, nonce, verifier = args.String(1), args.String(2), args.String(3)
+	}).Return("https://login.example.com/authorize", nil).Once()
+
+	_, _, err := suite.usecase.BeginLogin(context.Background())
+	suite.Require().NoError(err)
+
+	claims["iss"] = testIssuer
+	suite.client.On("Exchange", mock.Anything, "code", verifier, nonce).Return(claims, nil).Once()
+	return suite.usecase.CompleteLogin(context.Background(), state, "code")
+}
+
+func (suite *OIDCUsecaseTestSuite) user(subject string) domain.User {
+	user, err := suite.userRepo.FindByExternalID(context.Background(), testIssuer, subject)
+	suite.Require().NoError(err)
+	return user
+}
+
+func (suite *OIDCUsecaseTestSuite) TestBeginLogin() {
+	suite.client.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("https://login.example.com/authorize?state=x", nil)
+
+	authURL, returnedState, err := suite.usecase.BeginLogin(context.Background())
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "https://login.example.com/authorize?state=x", authURL)
+	state, nonce, verifier := suite.client.Calls[0].Arguments.String(1), suite.client.Calls[0].Arguments.String(2), suite.client.Calls[0].Arguments.String(3)
+	assert.Equal(suite.T(), state, returnedState)
+	assert.Len(suite.T(), verifier, 43, "a PKCE code verifier is 43 to 128 characters")
+	assert.NotEqual(suite.T(), state, nonce)
+	assert.NotEqual(suite.T(), nonce, verifier)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestBeginLogin_ProviderUnreachable() {
+	suite.client.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("connection refused"))
+
+	_, _, err := suite.usecase.BeginLogin(context.Background())
+
+	assert.EqualError(suite.T(), err, "Error contacting the identity provider")
+	assert.IsType(suite.T(), &domain.InternalServerError{}, err)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_ProvisionsTheUser() {
+	tokens, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice"})
+
+	suite.Require().NoError(err)
+	assert.NotEmpty(suite.T(), tokens.AccessToken)
+	assert.NotEmpty(suite.T(), tokens.RefreshToken)
+
+	user := suite.user("248289761001")
+	assert.Equal(suite.T(), "alice", user.Username)
+	assert.Empty(suite.T(), user.Password)
+	assert.Equal(suite.T(), []string{domain.AdminRole}, user.Roles, "the first user is the admin, as on registration")
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Equal(suite.T(), domain.AuditUserRegister, page.Entries[0].Action)
+	assert.Equal(suite.T(), "alice", page.Entries[0].TargetID)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_LinksByIssuerAndSubject() {
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice"})
+	suite.Require().NoError(err)
+	first := suite.user("248289761001")
+
+	// a renamed account at the provider is still the same user
+	_, err = suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice.smith"})
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), first, suite.user("248289761001"))
+	count, err := suite.userRepo.CountUsers(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_PicksAFreeUsername() {
+	suite.Require().NoError(suite.userRepo.CreateUser(context.Background(), domain.User{Username: "alice", Password: "hashed", Roles: []string{domain.AdminRole}}))
+	suite.Require().NoError(suite.userRepo.CreateUser(context.Background(), domain.User{Username: "alice-2", Password: "hashed", Roles: []string{domain.UserRole}}))
+
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice"})
+	suite.Require().NoError(err)
+	_, err = suite.login(map[string]interface{}{"sub": "248289761002", "email": "bob@example.com"})
+	suite.Require().NoError(err)
+	_, err = suite.login(map[string]interface{}{"sub": "248289761003"})
+	suite.Require().NoError(err)
+
+	user := suite.user("248289761001")
+	assert.Equal(suite.T(), "alice-3", user.Username)
+	assert.Equal(suite.T(), []string{domain.UserRole}, user.Roles)
+	assert.Equal(suite.T(), "bob@example.com", suite.user("248289761002").Username)
+	assert.Equal(suite.T(), "248289761003", suite.user("248289761003").Username)
+
+	// the password user is untouched
+	alice, err := suite.userRepo.FindByUsername(context.Background(), "alice")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "hashed", alice.Password)
+	assert.Empty(suite.T(), alice.Subject)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_NoFreeUsername() {
+	for attempt := 1; attempt <= maxUsernameAttempts; attempt++ {
+		username := domain.ExternalAccount{Username: "bob"}.CandidateUsername(attempt)
+		suite.Require().NoError(suite.userRepo.CreateUser(context.Background(), domain.User{Username: username, Password: "hashed", Roles: []string{domain.UserRole}}))
+	}
+
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "bob"})
+
+	assert.IsType(suite.T(), &domain.AlreadyExistsError{}, err)
+}
+
+// racingUserRepository has another login of the same account create its user just
+// before this one does
+type racingUserRepository struct {
+	repositories.UserRepository
+}
+
+func (r racingUserRepository) CreateUser(ctx context.Context, user domain.User) error {
+	winner := user
+	winner.Username = user.Username + "-elsewhere"
+	if err := r.UserRepository.CreateUser(ctx, winner); err != nil {
+		return err
+	}
+
+	return r.UserRepository.CreateUser(ctx, user)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_AccountLinkedConcurrently() {
+	suite.userRepo = racingUserRepository{suite.userRepo}
+	suite.newUsecase()
+
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice"})
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), "alice-elsewhere", suite.user("248289761001").Username)
+	count, err := suite.userRepo.CountUsers(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_MapsTheRoleClaim() {
+	suite.settings.RoleClaim = "groups"
+	suite.settings.RoleMapping = map[string]string{"tm-admins": domain.AdminRole, "tm-users": domain.UserRole}
+	suite.newUsecase()
+
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice", "groups": []interface{}{"engineering", "tm-users"}})
+	suite.Require().NoError(err)
+	user := suite.user("248289761001")
+	assert.Equal(suite.T(), []string{domain.UserRole}, user.Roles, "the provider's roles replace the first-user rule")
+
+	grants, err := suite.resolver.ResolveGrants(context.Background(), user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{domain.UserRole}, grants.Roles)
+
+	// promotion at the provider takes effect on the next login
+	_, err = suite.login(map[string]interface{}{"sub": "248289761001", "groups": []interface{}{"tm-admins", "tm-users"}})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{domain.AdminRole, domain.UserRole}, suite.user("248289761001").Roles)
+
+	grants, err = suite.resolver.ResolveGrants(context.Background(), user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{domain.AdminRole, domain.UserRole}, grants.Roles, "cached permissions are dropped")
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{Action: domain.AuditUserRolesUpdate, Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Equal(suite.T(), []domain.AuditChange{{Field: "roles", Before: "user", After: "admin,user"}}, page.Entries[0].Changes)
+
+	// without any mapped value the user falls back to the default role
+	_, err = suite.login(map[string]interface{}{"sub": "248289761001", "groups": "engineering"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{domain.UserRole}, suite.user("248289761001").Roles)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_RoleClaimWithoutMapping() {
+	suite.settings.RoleClaim = "roles"
+	suite.newUsecase()
+
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice", "roles": []interface{}{"auditor", "Not A Role!", 42}})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"auditor"}, suite.user("248289761001").Roles)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_RolesManagedHere() {
+	_, err := suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice", "groups": []interface{}{"tm-users"}})
+	suite.Require().NoError(err)
+	user := suite.user("248289761001")
+	user.Roles = []string{"auditor"}
+	suite.Require().NoError(suite.userRepo.UpdateUser(context.Background(), user.ID, user))
+
+	_, err = suite.login(map[string]interface{}{"sub": "248289761001", "preferred_username": "alice"})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"auditor"}, suite.user("248289761001").Roles)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_UnknownOrUsedState() {
+	_, err := suite.usecase.CompleteLogin(context.Background(), "made-up", "code")
+	assert.EqualError(suite.T(), err, "Single sign-on login has expired, please try again")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+
+	var state string
+	suite.client.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		state = args.String(1)
+	}).Return("https://login.example.com/authorize", nil)
+	_, _, err = suite.usecase.BeginLogin(context.Background())
+	suite.Require().NoError(err)
+	suite.client.On("Exchange", mock.Anything, "code", mock.Anything, mock.Anything).Return(map[string]interface{}{"iss": testIssuer, "sub": "248289761001"}, nil).Once()
+
+	_, err = suite.usecase.CompleteLogin(context.Background(), state, "code")
+	suite.Require().NoError(err)
+
+	_, err = suite.usecase.CompleteLogin(context.Background(), state, "code")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+
+	_, err = suite.usecase.CompleteLogin(context.Background(), "", "code")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *OIDCUsecaseTestSuite) TestCompleteLogin_ExchangeFails() {
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{fmt.Errorf("%w: ID token has expired", infrastructure.ErrOIDCRejected), &domain.UnauthorizedError{Message: "Single sign-on failed"}},
+		{&url.Error{Op: "Post", URL: "https://login.example.com/token", Err: errors.New("connection refused")}, &domain.InternalServerError{Message: "Error contacting the identity provider"}},
+	}
+
+	for _, tt := range tests {
+		var state string
+		suite.client.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			state = args.String(1)
+		}).Return("https://login.example.com/authorize", nil).Once()
+		_, _, err := suite.usecase.BeginLogin(context.Background())
+		suite.Require().NoError(err)
+		suite.client.On("Exchange", mock.Anything, "code", mock.Anything, mock.Anything).Return(nil, tt.err).Once()
+
+		_, err = suite.usecase.CompleteLogin(context.Background(), state, "code")
+
+		assert.Equal(suite.T(), tt.expected, err)
+	}
+
+	count, err := suite.userRepo.CountUsers(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), count)
+}
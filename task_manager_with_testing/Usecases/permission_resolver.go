@@ -0,0 +1,130 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+)
+
+// maxCachedGrants bounds how many users' grants are cached at once; the cache is
+// emptied when it fills up
+const maxCachedGrants = 10000
+
+// PermissionResolver works out the roles each user holds and the permissions they give,
+// caching them for a while. Changes made on this instance invalidate the cache straight
+// away; other instances pick them up once their cached grants expire.
+type PermissionResolver interface {
+	infrastructure.GrantResolver
+	// InvalidateUser forgets the cached grants of a user whose roles changed
+	InvalidateUser(userID string)
+	// InvalidateAll forgets every cached grant, after a role's permissions changed
+	InvalidateAll()
+}
+
+// cachedGrants are a user's grants and when they stop being used
+type cachedGrants struct {
+	grants    domain.Grants
+	expiresAt time.Time
+}
+
+// permissionResolver struct
+type permissionResolver struct {
+	userRepo repositories.UserRepository
+	roleRepo repositories.RoleRepository
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedGrants
+	// generation is bumped by every invalidation, so grants worked out from roles read
+	// before it are not cached after it
+	generation int64
+}
+
+// NewPermissionResolver creates a new permission resolver that caches grants for ttl;
+// a zero ttl turns caching off
+func NewPermissionResolver(userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, ttl time.Duration) PermissionResolver {
+	return &permissionResolver{userRepo: userRepo, roleRepo: roleRepo, ttl: ttl, cache: make(map[string]cachedGrants)}
+}
+
+// ResolveGrants returns the user's grants from the cache, or looks up their roles.
+// Roles that no longer exist are skipped.
+func (r *permissionResolver) ResolveGrants(ctx context.Context, userID string) (domain.Grants, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[userID]
+	generation := r.generation
+	r.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.grants, nil
+	}
+
+	user, err := r.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return domain.Grants{}, err
+	}
+
+	roles, err := r.roles(ctx, user.RoleNames())
+	if err != nil {
+		return domain.Grants{}, err
+	}
+
+	grants := domain.GrantsOf(roles)
+	if r.ttl <= 0 {
+		return grants, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.generation == generation {
+		if len(r.cache) >= maxCachedGrants {
+			r.cache = make(map[string]cachedGrants)
+		}
+		r.cache[userID] = cachedGrants{grants: grants, expiresAt: time.Now().Add(r.ttl)}
+	}
+
+	return grants, nil
+}
+
+// roles looks up the named roles, built in or custom
+func (r *permissionResolver) roles(ctx context.Context, names []string) ([]domain.Role, error) {
+	roles := []domain.Role{}
+	for _, name := range names {
+		if role, ok := domain.BuiltInRole(name); ok {
+			roles = append(roles, role)
+			continue
+		}
+
+		role, err := r.roleRepo.GetRole(ctx, name)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+func (r *permissionResolver) InvalidateUser(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	delete(r.cache, userID)
+}
+
+func (r *permissionResolver) InvalidateAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.cache = make(map[string]cachedGrants)
+}
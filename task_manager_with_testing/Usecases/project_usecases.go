@@ -0,0 +1,364 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+)
+
+// personalProjectName is the name a user's personal project starts with
+const personalProjectName = "Personal"
+
+// maxProjectAttempts bounds how often a change to a project is retried when another
+// change to it lands first
+const maxProjectAttempts = 3
+
+// ProjectUsecase manages projects and their members. Members of a project can see it;
+// only its owners and admins can change it, manage its members or delete it. What each
+// member may do with the project's tasks is checked by the task usecase.
+type ProjectUsecase interface {
+	// CreateProject creates a project with the caller as its owner
+	CreateProject(ctx context.Context, identity domain.Identity, project domain.Project) (domain.Project, error)
+	// GetProjects lists the projects the caller is a member of, ordered by name
+	GetProjects(ctx context.Context, identity domain.Identity) ([]domain.Project, error)
+	GetProject(ctx context.Context, identity domain.Identity, id string) (domain.Project, error)
+	// UpdateProject changes the name and description of a project
+	UpdateProject(ctx context.Context, identity domain.Identity, id string, project domain.Project) (domain.Project, error)
+	// DeleteProject deletes a project that has no tasks left
+	DeleteProject(ctx context.Context, identity domain.Identity, id string) error
+	// SetMember adds a user to a project with the role, or changes their role
+	SetMember(ctx context.Context, identity domain.Identity, id, username, role string) (domain.Project, error)
+	// RemoveMember removes a user from a project; members can also leave on their own
+	RemoveMember(ctx context.Context, identity domain.Identity, id, username string) (domain.Project, error)
+	// TaskAccess looks up what the caller may do with tasks through their projects
+	TaskAccess(ctx context.Context, identity domain.Identity) (domain.TaskAccess, error)
+	// AssignLegacyTasks moves the tasks created before projects into their owners'
+	// personal projects and returns how many were moved
+	AssignLegacyTasks(ctx context.Context) (int64, error)
+}
+
+// projectUsecase struct
+type projectUsecase struct {
+	projectRepo repositories.ProjectRepository
+	userRepo    repositories.UserRepository
+	taskRepo    repositories.TaskRepository
+	audit       auditRecorder
+}
+
+// NewProjectUsecase creates a new project usecase
+func NewProjectUsecase(projectRepo repositories.ProjectRepository, userRepo repositories.UserRepository, taskRepo repositories.TaskRepository, auditRepo repositories.AuditRepository) ProjectUsecase {
+	return &projectUsecase{projectRepo: projectRepo, userRepo: userRepo, taskRepo: taskRepo, audit: auditRecorder{auditRepo}}
+}
+
+// CreateProject creates a project owned by the caller; members are added afterwards
+func (u *projectUsecase) CreateProject(ctx context.Context, identity domain.Identity, project domain.Project) (domain.Project, error) {
+	if err := project.Validate(); err != nil {
+		return domain.Project{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	created, err := u.projectRepo.CreateProject(ctx, domain.Project{
+		Name:        project.Name,
+		Description: project.Description,
+		Members:     []domain.ProjectMember{{UserID: identity.UserID, Username: identity.Username, Role: domain.ProjectOwner}},
+		CreatedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditProjectCreate, "project", created.ID, nil, projectAuditFields(created))
+	return created, nil
+}
+
+// GetProjects lists the caller's projects; admins get every project
+func (u *projectUsecase) GetProjects(ctx context.Context, identity domain.Identity) ([]domain.Project, error) {
+	if identity.CanManageAll() {
+		return u.projectRepo.GetProjects(ctx, "")
+	}
+
+	return u.projectRepo.GetProjects(ctx, identity.UserID)
+}
+
+// GetProject retrieves a project the caller is a member of; other projects are reported as not found
+func (u *projectUsecase) GetProject(ctx context.Context, identity domain.Identity, id string) (domain.Project, error) {
+	project, err := u.projectRepo.GetProject(ctx, id)
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	if !identity.CanManageAll() && project.RoleOf(identity.UserID) == "" {
+		return domain.Project{}, &domain.NotFoundError{Message: "Project not found"}
+	}
+
+	return project, nil
+}
+
+// UpdateProject renames a project the caller owns and changes its description
+func (u *projectUsecase) UpdateProject(ctx context.Context, identity domain.Identity, id string, project domain.Project) (domain.Project, error) {
+	if err := project.Validate(); err != nil {
+		return domain.Project{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	var existing domain.Project
+	updated, err := u.modify(ctx, identity, id, func(stored *domain.Project) error {
+		if err := checkProjectOwner(identity, *stored); err != nil {
+			return err
+		}
+
+		existing = *stored
+		stored.Name, stored.Description = project.Name, project.Description
+		return nil
+	})
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditProjectUpdate, "project", id, projectAuditFields(existing), projectAuditFields(updated))
+	return updated, nil
+}
+
+// DeleteProject deletes a project the caller owns. Its tasks must be deleted or moved to
+// another project first, and personal projects cannot be deleted.
+func (u *projectUsecase) DeleteProject(ctx context.Context, identity domain.Identity, id string) error {
+	project, err := u.GetProject(ctx, identity, id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkProjectOwner(identity, project); err != nil {
+		return err
+	}
+
+	if project.PersonalOf != "" {
+		return &domain.BadRequestError{Message: "A personal project cannot be deleted"}
+	}
+
+	page, err := u.taskRepo.GetTasks(ctx, domain.TaskQuery{ProjectID: id, Limit: 1})
+	if err != nil {
+		return err
+	}
+
+	if len(page.Tasks) > 0 {
+		return &domain.BadRequestError{Message: "Project has tasks; delete or move them first"}
+	}
+
+	if err := u.projectRepo.DeleteProject(ctx, id); err != nil {
+		return err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditProjectDelete, "project", id, projectAuditFields(project), nil)
+	return nil
+}
+
+// SetMember gives a user a role in a project the caller owns. A project must keep at
+// least one owner, and the owner of a personal project keeps their role.
+func (u *projectUsecase) SetMember(ctx context.Context, identity domain.Identity, id, username, role string) (domain.Project, error) {
+	if err := domain.ValidateProjectRole(role); err != nil {
+		return domain.Project{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	user, err := u.userRepo.FindByUsername(ctx, username)
+	if _, ok := err.(*domain.NotFoundError); ok {
+		return domain.Project{}, &domain.BadRequestError{Message: "User not found"}
+	}
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	member := domain.ProjectMember{UserID: user.ID, Username: user.Username, Role: role}
+	var previous string
+	updated, err := u.modify(ctx, identity, id, func(project *domain.Project) error {
+		if err := checkProjectOwner(identity, *project); err != nil {
+			return err
+		}
+
+		previous = project.RoleOf(member.UserID)
+		if previous == "" && len(project.Members) >= domain.MaxProjectMembers {
+			return &domain.BadRequestError{Message: fmt.Sprintf("A project can have at most %d members", domain.MaxProjectMembers)}
+		}
+
+		if project.PersonalOf == member.UserID && role != domain.ProjectOwner {
+			return &domain.BadRequestError{Message: "The owner of a personal project cannot be changed"}
+		}
+
+		project.SetMember(member)
+		if !project.HasOwner() {
+			return &domain.BadRequestError{Message: "A project must keep at least one owner"}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	var before map[string]string
+	if previous != "" {
+		before = projectMemberAuditFields(domain.ProjectMember{UserID: member.UserID, Username: member.Username, Role: previous})
+	}
+	u.audit.record(ctx, identity, domain.AuditProjectMemberUpdate, "project", id, before, projectMemberAuditFields(member))
+	return updated, nil
+}
+
+// RemoveMember removes a member from a project the caller owns, or the caller from a
+// project they are a member of
+func (u *projectUsecase) RemoveMember(ctx context.Context, identity domain.Identity, id, username string) (domain.Project, error) {
+	var removed domain.ProjectMember
+	updated, err := u.modify(ctx, identity, id, func(project *domain.Project) error {
+		found := false
+		for _, member := range project.Members {
+			if member.Username == username {
+				removed, found = member, true
+			}
+		}
+
+		if !found || removed.UserID != identity.UserID {
+			if err := checkProjectOwner(identity, *project); err != nil {
+				return err
+			}
+		}
+
+		if !found {
+			return &domain.NotFoundError{Message: "Member not found"}
+		}
+
+		if project.PersonalOf == removed.UserID {
+			return &domain.BadRequestError{Message: "The owner of a personal project cannot be changed"}
+		}
+
+		project.RemoveMember(removed.UserID)
+		if !project.HasOwner() {
+			return &domain.BadRequestError{Message: "A project must keep at least one owner"}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditProjectMemberRemove, "project", id, projectMemberAuditFields(removed), nil)
+	return updated, nil
+}
+
+// TaskAccess looks up the caller's role in each of their projects. Their personal
+// project is created if they have none yet, so the access also covers the tasks they
+// create in it later.
+func (u *projectUsecase) TaskAccess(ctx context.Context, identity domain.Identity) (domain.TaskAccess, error) {
+	access, err := loadTaskAccess(ctx, u.projectRepo, identity)
+	if err != nil || identity.CanManageAll() || access.PersonalProjectID != "" {
+		return access, err
+	}
+
+	personal, err := ensurePersonalProject(ctx, u.projectRepo, identity)
+	if err != nil {
+		return domain.TaskAccess{}, err
+	}
+
+	access.Roles[personal.ID] = personal.RoleOf(identity.UserID)
+	access.PersonalProjectID = personal.ID
+	return access, nil
+}
+
+// AssignLegacyTasks gives the tasks created before projects, which have none, their
+// owner's personal project, creating it if the owner has none yet. It runs at startup and
+// only finds tasks the first time. Tasks without an owner, or whose owner no longer
+// exists, are left as they are and keep the rules from before projects.
+func (u *projectUsecase) AssignLegacyTasks(ctx context.Context) (int64, error) {
+	owners, err := u.taskRepo.GetUnassignedTaskOwners(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, ownerID := range owners {
+		owner, err := u.userRepo.FindByID(ctx, ownerID)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return moved, err
+		}
+
+		personal, err := ensurePersonalProject(ctx, u.projectRepo, domain.Identity{UserID: owner.ID, Username: owner.Username})
+		if err != nil {
+			return moved, err
+		}
+
+		count, err := u.taskRepo.AssignProject(ctx, ownerID, personal.ID)
+		if err != nil {
+			return moved, err
+		}
+		moved += count
+	}
+
+	return moved, nil
+}
+
+// modify applies the change to a project the caller is a member of and stores it. When
+// another change to the project is stored first, the change is applied again to the
+// project as that left it.
+func (u *projectUsecase) modify(ctx context.Context, identity domain.Identity, id string, change func(project *domain.Project) error) (domain.Project, error) {
+	for attempt := 1; ; attempt++ {
+		project, err := u.GetProject(ctx, identity, id)
+		if err != nil {
+			return domain.Project{}, err
+		}
+
+		version := project.Version
+		if err := change(&project); err != nil {
+			return domain.Project{}, err
+		}
+
+		updated, err := u.projectRepo.UpdateProject(ctx, id, version, project)
+		if _, ok := err.(*domain.ConflictError); ok && attempt < maxProjectAttempts {
+			continue
+		}
+
+		return updated, err
+	}
+}
+
+// checkProjectOwner checks that the caller may change the project
+func checkProjectOwner(identity domain.Identity, project domain.Project) error {
+	if !identity.CanManageAll() && project.RoleOf(identity.UserID) != domain.ProjectOwner {
+		return &domain.ForbiddenError{Message: "Only project owners can change the project"}
+	}
+
+	return nil
+}
+
+// loadTaskAccess looks up the identity's role in each of their projects. Admins can
+// manage every task, so their projects are not looked up.
+func loadTaskAccess(ctx context.Context, projectRepo repositories.ProjectRepository, identity domain.Identity) (domain.TaskAccess, error) {
+	access := domain.TaskAccess{Identity: identity, Roles: map[string]string{}}
+	if identity.CanManageAll() {
+		return access, nil
+	}
+
+	projects, err := projectRepo.GetProjects(ctx, identity.UserID)
+	if err != nil {
+		return domain.TaskAccess{}, err
+	}
+
+	for _, project := range projects {
+		access.Roles[project.ID] = project.RoleOf(identity.UserID)
+		if project.PersonalOf == identity.UserID {
+			access.PersonalProjectID = project.ID
+		}
+	}
+
+	return access, nil
+}
+
+// ensurePersonalProject returns the identity's personal project, creating it the first
+// time it is needed with them as its only owner
+func ensurePersonalProject(ctx context.Context, projectRepo repositories.ProjectRepository, identity domain.Identity) (domain.Project, error) {
+	return projectRepo.EnsurePersonalProject(ctx, domain.Project{
+		Name:       personalProjectName,
+		PersonalOf: identity.UserID,
+		Members:    []domain.ProjectMember{{UserID: identity.UserID, Username: identity.Username, Role: domain.ProjectOwner}},
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	})
+}
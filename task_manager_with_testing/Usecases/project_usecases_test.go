@@ -0,0 +1,325 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// ProjectUsecaseTestSuite shares projects between users on top of the in-memory
+// repositories, and checks what each member may do with the project's tasks
+type ProjectUsecaseTestSuite struct {
+	suite.Suite
+	auditRepo   repositories.AuditRepository
+	taskRepo    repositories.TaskRepository
+	usecase     ProjectUsecase
+	taskUsecase TaskUsecase
+	alice       domain.Identity
+	bob         domain.Identity
+	carol       domain.Identity
+	project     domain.Project
+}
+
+func (suite *ProjectUsecaseTestSuite) SetupTest() {
+	userRepo := repositories.NewUserMemoryRepository()
+	taskRepo := repositories.NewTaskMemoryRepository()
+	suite.taskRepo = taskRepo
+	projectRepo := repositories.NewProjectMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.usecase = NewProjectUsecase(projectRepo, userRepo, taskRepo, suite.auditRepo)
+	suite.taskUsecase = NewTaskUsecase(taskRepo, repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), projectRepo, suite.auditRepo, &recordingPublisher{}, domain.DefaultWorkflow())
+
+	identities := make([]domain.Identity, 3)
+	for i, username := range []string{"alice", "bob", "carol"} {
+		suite.Require().NoError(userRepo.CreateUser(context.Background(), domain.User{Username: username, Roles: []string{domain.UserRole}}))
+		user, err := userRepo.FindByUsername(context.Background(), username)
+		suite.Require().NoError(err)
+		identities[i] = identityWithRoles(user.ID, username, domain.UserRole)
+	}
+	suite.alice, suite.bob, suite.carol = identities[0], identities[1], identities[2]
+
+	var err error
+	suite.project, err = suite.usecase.CreateProject(context.Background(), suite.alice, domain.Project{Name: "Launch"})
+	suite.Require().NoError(err)
+}
+
+func TestProjectUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectUsecaseTestSuite))
+}
+
+// share gives bob the role in the project
+func (suite *ProjectUsecaseTestSuite) share(role string) {
+	_, err := suite.usecase.SetMember(context.Background(), suite.alice, suite.project.ID, "bob", role)
+	suite.Require().NoError(err)
+}
+
+// createTask creates a task in the project as alice
+func (suite *ProjectUsecaseTestSuite) createTask(title string) domain.Task {
+	task := batchTask(title)
+	task.ProjectID = suite.project.ID
+	created, err := suite.taskUsecase.CreateTask(context.Background(), suite.alice, *task)
+	suite.Require().NoError(err)
+	return created
+}
+
+func (suite *ProjectUsecaseTestSuite) TestCreateProject() {
+	assert.Equal(suite.T(), "Launch", suite.project.Name)
+	assert.Equal(suite.T(), []domain.ProjectMember{{UserID: suite.alice.UserID, Username: "alice", Role: domain.ProjectOwner}}, suite.project.Members)
+
+	_, err := suite.usecase.CreateProject(context.Background(), suite.alice, domain.Project{Name: " "})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	entries, err := suite.auditRepo.GetChain(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), domain.AuditProjectCreate, entries[0].Action)
+	assert.Equal(suite.T(), suite.project.ID, entries[0].TargetID)
+}
+
+func (suite *ProjectUsecaseTestSuite) TestGetProject_OnlyMembers() {
+	_, err := suite.usecase.GetProject(context.Background(), suite.bob, suite.project.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.usecase.GetProject(context.Background(), admin, suite.project.ID)
+	assert.NoError(suite.T(), err)
+
+	suite.share(domain.ProjectViewer)
+	projects, err := suite.usecase.GetProjects(context.Background(), suite.bob)
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 1)
+	assert.Equal(suite.T(), suite.project.ID, projects[0].ID)
+
+	projects, err = suite.usecase.GetProjects(context.Background(), suite.carol)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), projects)
+}
+
+func (suite *ProjectUsecaseTestSuite) TestUpdateProject_OnlyOwners() {
+	suite.share(domain.ProjectEditor)
+
+	_, err := suite.usecase.UpdateProject(context.Background(), suite.bob, suite.project.ID, domain.Project{Name: "Relaunch"})
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	updated, err := suite.usecase.UpdateProject(context.Background(), suite.alice, suite.project.ID, domain.Project{Name: "Relaunch", Description: "Again"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "Relaunch", updated.Name)
+	assert.Len(suite.T(), updated.Members, 2, "members are kept")
+}
+
+func (suite *ProjectUsecaseTestSuite) TestSetMember() {
+	suite.share(domain.ProjectViewer)
+	suite.share(domain.ProjectEditor)
+
+	project, err := suite.usecase.GetProject(context.Background(), suite.alice, suite.project.ID)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), project.Members, 2)
+	assert.Equal(suite.T(), domain.ProjectEditor, project.RoleOf(suite.bob.UserID))
+
+	// only owners manage members
+	_, err = suite.usecase.SetMember(context.Background(), suite.bob, suite.project.ID, "carol", domain.ProjectViewer)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	_, err = suite.usecase.SetMember(context.Background(), suite.alice, suite.project.ID, "nobody", domain.ProjectViewer)
+	assert.EqualError(suite.T(), err, "User not found")
+
+	_, err = suite.usecase.SetMember(context.Background(), suite.alice, suite.project.ID, "carol", "admin")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.SetMember(context.Background(), suite.alice, suite.project.ID, "alice", domain.ProjectEditor)
+	assert.EqualError(suite.T(), err, "A project must keep at least one owner")
+
+	entries, err := suite.auditRepo.GetChain(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 3)
+	assert.Equal(suite.T(), domain.AuditProjectMemberUpdate, entries[2].Action)
+	assert.Contains(suite.T(), entries[2].Changes, domain.AuditChange{Field: "role", Before: domain.ProjectViewer, After: domain.ProjectEditor})
+}
+
+func (suite *ProjectUsecaseTestSuite) TestRemoveMember() {
+	suite.share(domain.ProjectViewer)
+	_, err := suite.usecase.SetMember(context.Background(), suite.alice, suite.project.ID, "carol", domain.ProjectEditor)
+	suite.Require().NoError(err)
+
+	// members cannot remove each other, but can leave
+	_, err = suite.usecase.RemoveMember(context.Background(), suite.bob, suite.project.ID, "carol")
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	project, err := suite.usecase.RemoveMember(context.Background(), suite.bob, suite.project.ID, "bob")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "", project.RoleOf(suite.bob.UserID))
+
+	_, err = suite.usecase.RemoveMember(context.Background(), suite.alice, suite.project.ID, "bob")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.usecase.RemoveMember(context.Background(), suite.alice, suite.project.ID, "alice")
+	assert.EqualError(suite.T(), err, "A project must keep at least one owner")
+}
+
+func (suite *ProjectUsecaseTestSuite) TestDeleteProject() {
+	task := suite.createTask("Blocker")
+
+	err := suite.usecase.DeleteProject(context.Background(), suite.alice, suite.project.ID)
+	assert.EqualError(suite.T(), err, "Project has tasks; delete or move them first")
+
+	suite.Require().NoError(suite.taskUsecase.DeleteTask(context.Background(), suite.alice, task.ID))
+	suite.Require().NoError(suite.usecase.DeleteProject(context.Background(), suite.alice, suite.project.ID))
+
+	_, err = suite.usecase.GetProject(context.Background(), suite.alice, suite.project.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *ProjectUsecaseTestSuite) TestPersonalProject() {
+	created, err := suite.taskUsecase.CreateTask(context.Background(), suite.alice, *batchTask("Errand"))
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(created.ProjectID)
+
+	personal, err := suite.usecase.GetProject(context.Background(), suite.alice, created.ProjectID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.alice.UserID, personal.PersonalOf)
+
+	// later tasks go to the same project
+	again, err := suite.taskUsecase.CreateTask(context.Background(), suite.alice, *batchTask("Another errand"))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), created.ProjectID, again.ProjectID)
+
+	err = suite.usecase.DeleteProject(context.Background(), suite.alice, personal.ID)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.SetMember(context.Background(), suite.alice, personal.ID, "alice", domain.ProjectViewer)
+	assert.EqualError(suite.T(), err, "The owner of a personal project cannot be changed")
+}
+
+func (suite *ProjectUsecaseTestSuite) TestTaskAccess_Viewer() {
+	task := suite.createTask("Plan")
+	suite.share(domain.ProjectViewer)
+
+	found, err := suite.taskUsecase.GetTask(context.Background(), suite.bob, task.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), task.ID, found.ID)
+
+	page, err := suite.taskUsecase.GetTasks(context.Background(), suite.bob, domain.TaskQuery{ProjectID: suite.project.ID})
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), page.Tasks, 1)
+
+	states, err := suite.taskUsecase.GetNextStates(context.Background(), suite.bob, task.ID)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), states)
+
+	_, err = suite.taskUsecase.UpdateTask(context.Background(), suite.bob, task.ID, task.Version, *batchTask("Renamed"))
+	assert.EqualError(suite.T(), err, "Viewers cannot modify a project's tasks")
+
+	err = suite.taskUsecase.DeleteTask(context.Background(), suite.bob, task.ID)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	created := batchTask("Sneaky")
+	created.ProjectID = suite.project.ID
+	_, err = suite.taskUsecase.CreateTask(context.Background(), suite.bob, *created)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+}
+
+func (suite *ProjectUsecaseTestSuite) TestTaskAccess_Editor() {
+	task := suite.createTask("Plan")
+	suite.share(domain.ProjectEditor)
+
+	changed := *batchTask("Plan carefully")
+	updated, err := suite.taskUsecase.UpdateTask(context.Background(), suite.bob, task.ID, task.Version, changed)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.project.ID, updated.ProjectID, "a task stays in its project")
+	assert.Equal(suite.T(), suite.alice.UserID, updated.OwnerID)
+
+	// the editor cannot move the task to a project they are not a member of
+	other, err := suite.usecase.CreateProject(context.Background(), suite.carol, domain.Project{Name: "Other"})
+	suite.Require().NoError(err)
+	changed.ProjectID = other.ID
+	_, err = suite.taskUsecase.UpdateTask(context.Background(), suite.bob, task.ID, updated.Version, changed)
+	assert.EqualError(suite.T(), err, "Project not found")
+
+	suite.Require().NoError(suite.taskUsecase.DeleteTask(context.Background(), suite.bob, task.ID))
+}
+
+func (suite *ProjectUsecaseTestSuite) TestTaskAccess_NonMember() {
+	task := suite.createTask("Plan")
+
+	_, err := suite.taskUsecase.GetTask(context.Background(), suite.carol, task.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	page, err := suite.taskUsecase.GetTasks(context.Background(), suite.carol, domain.TaskQuery{})
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), page.Tasks)
+
+	// changing a task is no way to find out it exists either
+	_, err = suite.taskUsecase.UpdateTask(context.Background(), suite.carol, task.ID, task.Version, *batchTask("Plan"))
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+	err = suite.taskUsecase.DeleteTask(context.Background(), suite.carol, task.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	// removed members lose access straight away
+	suite.share(domain.ProjectEditor)
+	_, err = suite.usecase.RemoveMember(context.Background(), suite.alice, suite.project.ID, "bob")
+	suite.Require().NoError(err)
+	_, err = suite.taskUsecase.GetTask(context.Background(), suite.bob, task.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *ProjectUsecaseTestSuite) TestTaskAccess_AdminNeedsAnExistingProject() {
+	created := batchTask("Admin task")
+	created.ProjectID = suite.project.ID
+	_, err := suite.taskUsecase.CreateTask(context.Background(), admin, *created)
+	assert.NoError(suite.T(), err)
+
+	created.ProjectID = "6500000000000000000000aa"
+	_, err = suite.taskUsecase.CreateTask(context.Background(), admin, *created)
+	assert.EqualError(suite.T(), err, "Project not found")
+}
+
+func (suite *ProjectUsecaseTestSuite) TestTaskAccess_ForStream() {
+	suite.share(domain.ProjectViewer)
+
+	access, err := suite.usecase.TaskAccess(context.Background(), suite.bob)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), domain.ProjectViewer, access.Roles[suite.project.ID])
+	assert.NotEmpty(suite.T(), access.PersonalProjectID, "the personal project is created so its tasks are streamed too")
+	assert.Equal(suite.T(), domain.ProjectOwner, access.Roles[access.PersonalProjectID])
+}
+
+func (suite *ProjectUsecaseTestSuite) TestAssignLegacyTasks() {
+	legacy := func(title, ownerID string) domain.Task {
+		task, err := suite.taskRepo.CreateTask(context.Background(), domain.Task{Title: title, DueDate: time.Now().Add(time.Hour), Status: "pending", OwnerID: ownerID})
+		suite.Require().NoError(err)
+		return task
+	}
+	old := legacy("Old", suite.bob.UserID)
+	orphaned := legacy("Orphaned", "6500000000000000000000ff")
+	unowned := legacy("Unowned", "")
+
+	moved, err := suite.usecase.AssignLegacyTasks(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), moved)
+
+	projects, err := suite.usecase.GetProjects(context.Background(), suite.bob)
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 1)
+	assert.Equal(suite.T(), suite.bob.UserID, projects[0].PersonalOf)
+
+	task, err := suite.taskUsecase.GetTask(context.Background(), suite.bob, old.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), projects[0].ID, task.ProjectID)
+
+	// tasks of users that are gone, and tasks without an owner, keep the old rules
+	for _, kept := range []domain.Task{orphaned, unowned} {
+		task, err := suite.taskRepo.GetTask(context.Background(), kept.ID)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), task.ProjectID, kept.Title)
+	}
+
+	// the next start finds nothing left to move
+	moved, err = suite.usecase.AssignLegacyTasks(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(0), moved)
+}
@@ -0,0 +1,198 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+)
+
+// ReminderUsecase sends reminders for open tasks that are coming due or overdue
+type ReminderUsecase interface {
+	// SendDueReminders sends every reminder that is due and returns how many were sent
+	SendDueReminders(ctx context.Context) (int, error)
+}
+
+// reminderUsecase struct
+type reminderUsecase struct {
+	taskRepo     repositories.TaskRepository
+	userRepo     repositories.UserRepository
+	reminderRepo repositories.ReminderRepository
+	notifier     infrastructure.Notifier
+	leadTimes    []time.Duration
+	catchUp      time.Duration
+	openStates   []string
+	now          func() time.Time
+}
+
+// NewReminderUsecase creates a new reminder usecase. A task is reminded once for each
+// lead time before its due date; a zero lead time reminds once it is overdue. Tasks
+// that have been overdue for longer than catchUp are no longer reminded, and neither
+// are tasks that are no longer in an open state of the workflow.
+func NewReminderUsecase(taskRepo repositories.TaskRepository, userRepo repositories.UserRepository, reminderRepo repositories.ReminderRepository, notifier infrastructure.Notifier, leadTimes []time.Duration, catchUp time.Duration, workflow domain.Workflow) ReminderUsecase {
+	sorted := append([]time.Duration{}, leadTimes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &reminderUsecase{
+		taskRepo:     taskRepo,
+		userRepo:     userRepo,
+		reminderRepo: reminderRepo,
+		notifier:     notifier,
+		leadTimes:    sorted,
+		catchUp:      catchUp,
+		openStates:   workflow.OpenStates(),
+		now:          time.Now,
+	}
+}
+
+// SendDueReminders sends the reminders that are due for open tasks. Each reminder is
+// claimed before it is sent, so it is sent at most once even if several instances run
+// or the service restarts; a reminder that fails to send is released and retried on
+// the next run.
+func (u *reminderUsecase) SendDueReminders(ctx context.Context) (int, error) {
+	if len(u.leadTimes) == 0 {
+		return 0, nil
+	}
+
+	now := u.now()
+	query := domain.TaskQuery{
+		Statuses:  u.openStates,
+		DueAfter:  now.Add(-u.catchUp),
+		DueBefore: now.Add(u.leadTimes[len(u.leadTimes)-1]),
+		SortBy:    "due_date",
+		Limit:     domain.MaxTaskPageSize,
+	}
+
+	usernames := make(map[string]string)
+	sent := 0
+	for {
+		page, err := u.taskRepo.GetTasks(ctx, query)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, task := range page.Tasks {
+			if err := ctx.Err(); err != nil {
+				return sent, err
+			}
+
+			lead, ok := u.leadTime(task.DueDate, now)
+			if !ok || task.OwnerID == "" {
+				continue
+			}
+
+			username, ok := usernames[task.OwnerID]
+			if !ok {
+				owner, err := u.userRepo.FindByID(ctx, task.OwnerID)
+				var notFound *domain.NotFoundError
+				if err != nil && !errors.As(err, &notFound) {
+					return sent, err
+				}
+
+				username = owner.Username
+				usernames[task.OwnerID] = username
+			}
+
+			if username == "" {
+				continue
+			}
+
+			reminder := domain.Reminder{
+				TaskID:   task.ID,
+				Title:    task.Title,
+				DueDate:  task.DueDate,
+				LeadTime: lead,
+				Username: username,
+				SentAt:   now,
+			}
+
+			if u.send(ctx, reminder) {
+				sent++
+			}
+		}
+
+		if page.NextCursor == "" {
+			return sent, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// leadTime picks the shortest lead time whose reminder is due, so a task created close
+// to its due date gets one reminder rather than one for every lead time it has passed
+func (u *reminderUsecase) leadTime(dueDate, now time.Time) (time.Duration, bool) {
+	for _, lead := range u.leadTimes {
+		if !dueDate.Add(-lead).After(now) {
+			return lead, true
+		}
+	}
+
+	return 0, false
+}
+
+// send claims and sends one reminder and reports whether it was sent by this call
+func (u *reminderUsecase) send(ctx context.Context, reminder domain.Reminder) bool {
+	claimed, err := u.reminderRepo.Claim(ctx, reminder)
+	if err != nil {
+		log.Printf("reminders: failed to record reminder for task %s: %v", reminder.TaskID, err)
+		return false
+	}
+
+	if !claimed {
+		return false
+	}
+
+	if err := u.notifier.Notify(ctx, reminder); err != nil {
+		log.Printf("reminders: failed to send reminder for task %s: %v", reminder.TaskID, err)
+
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+		defer cancel()
+		if err := u.reminderRepo.Release(releaseCtx, reminder); err != nil {
+			log.Printf("reminders: failed to release reminder for task %s: %v", reminder.TaskID, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// ReminderScheduler runs a ReminderUsecase in the background at a fixed interval
+type ReminderScheduler struct {
+	usecase ReminderUsecase
+	runner  *periodic
+}
+
+// NewReminderScheduler creates a scheduler that sends reminders every interval
+func NewReminderScheduler(usecase ReminderUsecase, interval time.Duration) *ReminderScheduler {
+	s := &ReminderScheduler{usecase: usecase}
+	s.runner = &periodic{interval: interval, job: s.run}
+	return s
+}
+
+// Start sends reminders right away and then every interval until Stop is called.
+// Starting a scheduler that is already running does nothing.
+func (s *ReminderScheduler) Start() {
+	s.runner.start()
+}
+
+// Stop cancels the run in progress and waits for it to finish, or for ctx to be done
+func (s *ReminderScheduler) Stop(ctx context.Context) error {
+	return s.runner.stop(ctx)
+}
+
+func (s *ReminderScheduler) run(ctx context.Context) {
+	sent, err := s.usecase.SendDueReminders(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("reminders: run failed after %d reminders: %v", sent, err)
+		return
+	}
+
+	if sent > 0 {
+		log.Printf("reminders: sent %d reminders", sent)
+	}
+}
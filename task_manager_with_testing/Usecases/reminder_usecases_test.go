@@ -0,0 +1,246 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, reminder domain.Reminder) error {
+	args := m.Called(ctx, reminder)
+	return args.Error(0)
+}
+
+// ReminderUsecaseTestSuite runs reminders against the in-memory repositories
+type ReminderUsecaseTestSuite struct {
+	suite.Suite
+	taskRepo     repositories.TaskRepository
+	userRepo     repositories.UserRepository
+	reminderRepo repositories.ReminderRepository
+	notifier     *MockNotifier
+	now          time.Time
+	ownerID      string
+}
+
+func (suite *ReminderUsecaseTestSuite) SetupTest() {
+	suite.taskRepo = repositories.NewTaskMemoryRepository()
+	suite.userRepo = repositories.NewUserMemoryRepository()
+	suite.reminderRepo = repositories.NewReminderMemoryRepository()
+	suite.notifier = new(MockNotifier)
+	suite.now = time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	suite.Require().NoError(suite.userRepo.CreateUser(context.Background(), domain.User{Username: "alice", Password: "hashed", Roles: []string{domain.UserRole}}))
+	user, err := suite.userRepo.FindByUsername(context.Background(), "alice")
+	suite.Require().NoError(err)
+	suite.ownerID = user.ID
+}
+
+func TestReminderUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(ReminderUsecaseTestSuite))
+}
+
+// newUsecase creates a usecase that reminds a day and an hour ahead and when overdue.
+// Usecases created by one test share their repositories, like a restarted service.
+func (suite *ReminderUsecaseTestSuite) newUsecase() ReminderUsecase {
+	usecase := NewReminderUsecase(suite.taskRepo, suite.userRepo, suite.reminderRepo, suite.notifier, []time.Duration{time.Hour, 0, 24 * time.Hour}, 24*time.Hour, domain.DefaultWorkflow())
+	usecase.(*reminderUsecase).now = func() time.Time { return suite.now }
+	return usecase
+}
+
+func (suite *ReminderUsecaseTestSuite) createTask(title string, dueDate time.Time, status, ownerID string) domain.Task {
+	task, err := suite.taskRepo.CreateTask(context.Background(), domain.Task{Title: title, DueDate: dueDate, Status: status, OwnerID: ownerID})
+	suite.Require().NoError(err)
+	return task
+}
+
+// expectReminder expects the notifier to be asked for one reminder of the task
+func (suite *ReminderUsecaseTestSuite) expectReminder(task domain.Task, lead time.Duration, err error) {
+	suite.notifier.On("Notify", mock.Anything, domain.Reminder{
+		TaskID:   task.ID,
+		Title:    task.Title,
+		DueDate:  task.DueDate,
+		LeadTime: lead,
+		Username: "alice",
+		SentAt:   suite.now,
+	}).Return(err).Once()
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_LeadTimes() {
+	task := suite.createTask("Pay rent", suite.now.Add(30*time.Hour), "pending", suite.ownerID)
+	usecase := suite.newUsecase()
+
+	sent, err := usecase.SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, sent)
+
+	for _, step := range []struct {
+		now  time.Time
+		lead time.Duration
+	}{
+		{now: task.DueDate.Add(-23 * time.Hour), lead: 24 * time.Hour},
+		{now: task.DueDate.Add(-30 * time.Minute), lead: time.Hour},
+		{now: task.DueDate.Add(time.Minute), lead: 0},
+	} {
+		suite.now = step.now
+		suite.expectReminder(task, step.lead, nil)
+
+		sent, err := usecase.SendDueReminders(context.Background())
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 1, sent)
+
+		// each reminder is sent once
+		sent, err = usecase.SendDueReminders(context.Background())
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 0, sent)
+	}
+
+	suite.notifier.AssertExpectations(suite.T())
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_OnlyShortestLeadTime() {
+	task := suite.createTask("Pay rent", suite.now.Add(30*time.Minute), "pending", suite.ownerID)
+	suite.expectReminder(task, time.Hour, nil)
+
+	sent, err := suite.newUsecase().SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, sent)
+	suite.notifier.AssertExpectations(suite.T())
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_NotResentAfterRestart() {
+	task := suite.createTask("Pay rent", suite.now.Add(30*time.Minute), "pending", suite.ownerID)
+	suite.expectReminder(task, time.Hour, nil)
+
+	sent, err := suite.newUsecase().SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, sent)
+
+	sent, err = suite.newUsecase().SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, sent)
+	suite.notifier.AssertExpectations(suite.T())
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_RetriesFailedReminders() {
+	task := suite.createTask("Pay rent", suite.now.Add(30*time.Minute), "pending", suite.ownerID)
+	usecase := suite.newUsecase()
+
+	suite.expectReminder(task, time.Hour, errors.New("mail server unavailable"))
+	sent, err := usecase.SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, sent)
+
+	suite.expectReminder(task, time.Hour, nil)
+	sent, err = usecase.SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, sent)
+	suite.notifier.AssertExpectations(suite.T())
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_OpenStates() {
+	started := suite.createTask("Write report", suite.now.Add(30*time.Minute), "in_progress", suite.ownerID)
+	blocked := suite.createTask("Pay invoice", suite.now.Add(40*time.Minute), "blocked", suite.ownerID)
+	suite.expectReminder(started, time.Hour, nil)
+	suite.expectReminder(blocked, time.Hour, nil)
+
+	sent, err := suite.newUsecase().SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, sent)
+	suite.notifier.AssertExpectations(suite.T())
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_SkipsTasks() {
+	suite.createTask("Completed", suite.now.Add(30*time.Minute), "completed", suite.ownerID)
+	suite.createTask("Cancelled", suite.now.Add(30*time.Minute), "cancelled", suite.ownerID)
+	suite.createTask("No owner", suite.now.Add(30*time.Minute), "pending", "")
+	suite.createTask("Unknown owner", suite.now.Add(30*time.Minute), "pending", "0123456789abcdef01234567")
+	suite.createTask("Long overdue", suite.now.Add(-48*time.Hour), "pending", suite.ownerID)
+	suite.createTask("Far away", suite.now.Add(48*time.Hour), "pending", suite.ownerID)
+
+	sent, err := suite.newUsecase().SendDueReminders(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, sent)
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+func (suite *ReminderUsecaseTestSuite) TestSendDueReminders_CancelledContext() {
+	suite.createTask("Pay rent", suite.now.Add(30*time.Minute), "pending", suite.ownerID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := suite.newUsecase().SendDueReminders(ctx)
+	assert.Error(suite.T(), err)
+	suite.notifier.AssertNotCalled(suite.T(), "Notify", mock.Anything, mock.Anything)
+}
+
+// blockingReminderUsecase reports each run and blocks until the run is cancelled
+type blockingReminderUsecase struct {
+	runs chan struct{}
+}
+
+func (u *blockingReminderUsecase) SendDueReminders(ctx context.Context) (int, error) {
+	u.runs <- struct{}{}
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestReminderScheduler_StartAndStop(t *testing.T) {
+	usecase := &blockingReminderUsecase{runs: make(chan struct{}, 1)}
+	scheduler := NewReminderScheduler(usecase, time.Hour)
+
+	scheduler.Start()
+	scheduler.Start()
+
+	select {
+	case <-usecase.runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scheduler did not run on start")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, scheduler.Stop(ctx))
+	assert.NoError(t, scheduler.Stop(ctx))
+}
+
+func TestReminderScheduler_RunsEveryInterval(t *testing.T) {
+	usecase := &countingReminderUsecase{runs: make(chan struct{}, 1)}
+	scheduler := NewReminderScheduler(usecase, 10*time.Millisecond)
+
+	scheduler.Start()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-usecase.runs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the scheduler ran %d times", i)
+		}
+	}
+
+	assert.NoError(t, scheduler.Stop(context.Background()))
+}
+
+// countingReminderUsecase reports each run and returns straight away
+type countingReminderUsecase struct {
+	runs chan struct{}
+}
+
+func (u *countingReminderUsecase) SendDueReminders(ctx context.Context) (int, error) {
+	select {
+	case u.runs <- struct{}{}:
+	default:
+	}
+	return 0, nil
+}
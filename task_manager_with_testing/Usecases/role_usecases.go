@@ -0,0 +1,247 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+)
+
+// maxRoleAttempts bounds how often a change to a role is retried when another change to
+// it lands first
+const maxRoleAttempts = 3
+
+// RoleUsecase manages the roles users can hold and which roles each user holds. The
+// built-in roles cannot be changed. Nobody can hand out a permission they do not have
+// themselves, either through a role they define or a role they assign.
+type RoleUsecase interface {
+	// GetRoles lists the built-in roles followed by the custom ones, ordered by name
+	GetRoles(ctx context.Context) ([]domain.Role, error)
+	GetRole(ctx context.Context, name string) (domain.Role, error)
+	CreateRole(ctx context.Context, identity domain.Identity, role domain.Role) (domain.Role, error)
+	// UpdateRole changes the description and permissions of a custom role
+	UpdateRole(ctx context.Context, identity domain.Identity, name string, role domain.Role) (domain.Role, error)
+	// DeleteRole deletes a custom role no user holds any more
+	DeleteRole(ctx context.Context, identity domain.Identity, name string) error
+	// GetUserRoles returns the roles a user holds and the permissions they give
+	GetUserRoles(ctx context.Context, username string) (domain.Grants, error)
+	// SetUserRoles replaces the roles a user holds
+	SetUserRoles(ctx context.Context, identity domain.Identity, username string, roles []string) (domain.Grants, error)
+}
+
+// roleUsecase struct
+type roleUsecase struct {
+	roleRepo    repositories.RoleRepository
+	userRepo    repositories.UserRepository
+	permissions PermissionResolver
+	audit       auditRecorder
+}
+
+// NewRoleUsecase creates a new role usecase; every change is passed on to the
+// permission resolver so cached permissions are dropped
+func NewRoleUsecase(roleRepo repositories.RoleRepository, userRepo repositories.UserRepository, permissions PermissionResolver, auditRepo repositories.AuditRepository) RoleUsecase {
+	return &roleUsecase{roleRepo: roleRepo, userRepo: userRepo, permissions: permissions, audit: auditRecorder{auditRepo}}
+}
+
+func (u *roleUsecase) GetRoles(ctx context.Context) ([]domain.Role, error) {
+	custom, err := u.roleRepo.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(domain.BuiltInRoles(), custom...), nil
+}
+
+func (u *roleUsecase) GetRole(ctx context.Context, name string) (domain.Role, error) {
+	if role, ok := domain.BuiltInRole(name); ok {
+		return role, nil
+	}
+
+	return u.roleRepo.GetRole(ctx, name)
+}
+
+// CreateRole creates a custom role with permissions the caller has
+func (u *roleUsecase) CreateRole(ctx context.Context, identity domain.Identity, role domain.Role) (domain.Role, error) {
+	if err := role.Validate(); err != nil {
+		return domain.Role{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	if !canGrant(identity, role) {
+		return domain.Role{}, &domain.ForbiddenError{Message: "You cannot grant permissions you do not have"}
+	}
+
+	created, err := u.roleRepo.CreateRole(ctx, domain.Role{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.Permissions,
+		CreatedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		return domain.Role{}, err
+	}
+
+	// a role that was deleted while still named by a user comes back into effect
+	u.permissions.InvalidateAll()
+	u.audit.record(ctx, identity, domain.AuditRoleCreate, "role", created.Name, nil, roleAuditFields(created))
+	return created, nil
+}
+
+// UpdateRole changes a custom role. The caller must have the permissions the role had
+// as well as the ones it is given, so they cannot take away what they could not grant.
+func (u *roleUsecase) UpdateRole(ctx context.Context, identity domain.Identity, name string, role domain.Role) (domain.Role, error) {
+	if _, ok := domain.BuiltInRole(name); ok {
+		return domain.Role{}, &domain.BadRequestError{Message: "Built-in roles cannot be changed"}
+	}
+
+	role.Name = name
+	if err := role.Validate(); err != nil {
+		return domain.Role{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	for attempt := 1; ; attempt++ {
+		existing, err := u.roleRepo.GetRole(ctx, name)
+		if err != nil {
+			return domain.Role{}, err
+		}
+
+		if !canGrant(identity, existing) || !canGrant(identity, role) {
+			return domain.Role{}, &domain.ForbiddenError{Message: "You cannot grant permissions you do not have"}
+		}
+
+		updated, err := u.roleRepo.UpdateRole(ctx, name, existing.Version, role)
+		if _, ok := err.(*domain.ConflictError); ok && attempt < maxRoleAttempts {
+			continue
+		}
+		if err != nil {
+			return domain.Role{}, err
+		}
+
+		u.permissions.InvalidateAll()
+		u.audit.record(ctx, identity, domain.AuditRoleUpdate, "role", name, roleAuditFields(existing), roleAuditFields(updated))
+		return updated, nil
+	}
+}
+
+// DeleteRole deletes a custom role; it must be taken away from its users first
+func (u *roleUsecase) DeleteRole(ctx context.Context, identity domain.Identity, name string) error {
+	if _, ok := domain.BuiltInRole(name); ok {
+		return &domain.BadRequestError{Message: "Built-in roles cannot be changed"}
+	}
+
+	role, err := u.roleRepo.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !canGrant(identity, role) {
+		return &domain.ForbiddenError{Message: "You cannot grant permissions you do not have"}
+	}
+
+	count, err := u.userRepo.CountUsersWithRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return &domain.BadRequestError{Message: fmt.Sprintf("Role is still held by %d users", count)}
+	}
+
+	if err := u.roleRepo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+
+	u.permissions.InvalidateAll()
+	u.audit.record(ctx, identity, domain.AuditRoleDelete, "role", name, roleAuditFields(role), nil)
+	return nil
+}
+
+func (u *roleUsecase) GetUserRoles(ctx context.Context, username string) (domain.Grants, error) {
+	user, err := u.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return domain.Grants{}, err
+	}
+
+	return u.permissions.ResolveGrants(ctx, user.ID)
+}
+
+// SetUserRoles replaces a user's roles. Every role given or taken away may only grant
+// permissions the caller has, and callers cannot take away their own right to assign roles.
+func (u *roleUsecase) SetUserRoles(ctx context.Context, identity domain.Identity, username string, names []string) (domain.Grants, error) {
+	names, err := domain.ValidateUserRoles(names)
+	if err != nil {
+		return domain.Grants{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	user, err := u.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return domain.Grants{}, err
+	}
+
+	roles := make([]domain.Role, 0, len(names))
+	for _, name := range names {
+		role, err := u.GetRole(ctx, name)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return domain.Grants{}, &domain.BadRequestError{Message: fmt.Sprintf("Role %s does not exist", name)}
+		}
+		if err != nil {
+			return domain.Grants{}, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	previous := user.RoleNames()
+	for _, role := range roles {
+		if !slices.Contains(previous, role.Name) && !canGrant(identity, role) {
+			return domain.Grants{}, &domain.ForbiddenError{Message: "You cannot grant permissions you do not have"}
+		}
+	}
+
+	for _, name := range previous {
+		if slices.Contains(names, name) {
+			continue
+		}
+
+		role, err := u.GetRole(ctx, name)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return domain.Grants{}, err
+		}
+
+		if !canGrant(identity, role) {
+			return domain.Grants{}, &domain.ForbiddenError{Message: "You cannot take away permissions you do not have"}
+		}
+	}
+
+	grants := domain.GrantsOf(roles)
+	if user.ID == identity.UserID && !slices.Contains(grants.Permissions, domain.PermissionUserPromote) {
+		return domain.Grants{}, &domain.BadRequestError{Message: "You cannot take away your own permission to assign roles"}
+	}
+
+	before := userAuditFields(user)
+
+	user.Role, user.Roles = "", names
+	if err := u.userRepo.UpdateUser(ctx, user.ID, user); err != nil {
+		return domain.Grants{}, err
+	}
+
+	u.permissions.InvalidateUser(user.ID)
+	u.audit.record(ctx, identity, domain.AuditUserRolesUpdate, "user", username, before, userAuditFields(user))
+	return grants, nil
+}
+
+// canGrant reports whether the identity has every permission the role grants
+func canGrant(identity domain.Identity, role domain.Role) bool {
+	for _, permission := range role.Permissions {
+		if !identity.Can(permission) {
+			return false
+		}
+	}
+
+	return true
+}
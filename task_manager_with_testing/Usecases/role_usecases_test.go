@@ -0,0 +1,243 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// RoleUsecaseTestSuite manages roles on top of the in-memory repositories
+type RoleUsecaseTestSuite struct {
+	suite.Suite
+	userRepo    repositories.UserRepository
+	roleRepo    repositories.RoleRepository
+	auditRepo   repositories.AuditRepository
+	permissions PermissionResolver
+	usecase     RoleUsecase
+	root        domain.Identity
+	alice       domain.User
+}
+
+func (suite *RoleUsecaseTestSuite) SetupTest() {
+	suite.userRepo = repositories.NewUserMemoryRepository()
+	suite.roleRepo = repositories.NewRoleMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.permissions = NewPermissionResolver(suite.userRepo, suite.roleRepo, time.Hour)
+	suite.usecase = NewRoleUsecase(suite.roleRepo, suite.userRepo, suite.permissions, suite.auditRepo)
+
+	suite.root = suite.identity(suite.createUser("root", domain.AdminRole))
+	suite.alice = suite.createUser("alice", domain.UserRole)
+}
+
+func TestRoleUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(RoleUsecaseTestSuite))
+}
+
+func (suite *RoleUsecaseTestSuite) createUser(username string, roles ...string) domain.User {
+	suite.Require().NoError(suite.userRepo.CreateUser(context.Background(), domain.User{Username: username, Password: "hashed", Roles: roles}))
+	user, err := suite.userRepo.FindByUsername(context.Background(), username)
+	suite.Require().NoError(err)
+	return user
+}
+
+// identity resolves the user's identity the way the auth middleware does
+func (suite *RoleUsecaseTestSuite) identity(user domain.User) domain.Identity {
+	grants, err := suite.permissions.ResolveGrants(context.Background(), user.ID)
+	suite.Require().NoError(err)
+	return domain.NewIdentity(user.ID, user.Username, grants)
+}
+
+func (suite *RoleUsecaseTestSuite) createRole(name string, permissions ...string) domain.Role {
+	role, err := suite.usecase.CreateRole(context.Background(), suite.root, domain.Role{Name: name, Permissions: permissions})
+	suite.Require().NoError(err)
+	return role
+}
+
+func (suite *RoleUsecaseTestSuite) TestGetRoles_BuiltInRolesFirst() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+
+	roles, err := suite.usecase.GetRoles(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(roles, 3)
+	assert.Equal(suite.T(), domain.AdminRole, roles[0].Name)
+	assert.True(suite.T(), roles[0].BuiltIn)
+	assert.Equal(suite.T(), domain.UserRole, roles[1].Name)
+	assert.Equal(suite.T(), "auditor", roles[2].Name)
+	assert.False(suite.T(), roles[2].BuiltIn)
+
+	role, err := suite.usecase.GetRole(context.Background(), domain.AdminRole)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), domain.Permissions, role.Permissions)
+}
+
+func (suite *RoleUsecaseTestSuite) TestCreateRole() {
+	role := suite.createRole("auditor", domain.PermissionAuditRead, domain.PermissionTaskRead)
+
+	assert.Equal(suite.T(), []string{domain.PermissionTaskRead, domain.PermissionAuditRead}, role.Permissions)
+	assert.Equal(suite.T(), int64(1), role.Version)
+	assert.False(suite.T(), role.CreatedAt.IsZero())
+
+	_, err := suite.usecase.CreateRole(context.Background(), suite.root, domain.Role{Name: "auditor", Permissions: []string{domain.PermissionTaskRead}})
+	assert.IsType(suite.T(), &domain.AlreadyExistsError{}, err)
+
+	_, err = suite.usecase.CreateRole(context.Background(), suite.root, domain.Role{Name: domain.UserRole, Permissions: []string{domain.PermissionTaskRead}})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.CreateRole(context.Background(), suite.root, domain.Role{Name: "flyer", Permissions: []string{"task:fly"}})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *RoleUsecaseTestSuite) TestCreateRole_CannotGrantMissingPermissions() {
+	suite.createRole("role-admin", domain.PermissionRoleManage, domain.PermissionTaskRead)
+	manager := suite.identity(suite.createUser("manager", "role-admin"))
+
+	_, err := suite.usecase.CreateRole(context.Background(), manager, domain.Role{Name: "reader", Permissions: []string{domain.PermissionTaskRead}})
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.usecase.CreateRole(context.Background(), manager, domain.Role{Name: "auditor", Permissions: []string{domain.PermissionAuditRead}})
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+}
+
+func (suite *RoleUsecaseTestSuite) TestSetUserRoles() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+	assert.False(suite.T(), suite.identity(suite.alice).Can(domain.PermissionAuditRead))
+
+	grants, err := suite.usecase.SetUserRoles(context.Background(), suite.root, "alice", []string{"user", "auditor", "user"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"auditor", "user"}, grants.Roles)
+	assert.Contains(suite.T(), grants.Permissions, domain.PermissionAuditRead)
+
+	// the cached grants of alice were dropped when her roles changed
+	identity := suite.identity(suite.alice)
+	assert.True(suite.T(), identity.Can(domain.PermissionAuditRead))
+	assert.True(suite.T(), identity.HasRole("auditor"))
+
+	fetched, err := suite.usecase.GetUserRoles(context.Background(), "alice")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), grants, fetched)
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{Action: domain.AuditUserRolesUpdate, Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Contains(suite.T(), page.Entries[0].Changes, domain.AuditChange{Field: "roles", Before: "user", After: "auditor,user"})
+}
+
+func (suite *RoleUsecaseTestSuite) TestSetUserRoles_Errors() {
+	_, err := suite.usecase.SetUserRoles(context.Background(), suite.root, "alice", nil)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.SetUserRoles(context.Background(), suite.root, "alice", []string{"missing"})
+	assert.EqualError(suite.T(), err, "Role missing does not exist")
+
+	_, err = suite.usecase.SetUserRoles(context.Background(), suite.root, "nobody", []string{"user"})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.usecase.SetUserRoles(context.Background(), suite.root, "root", []string{"user"})
+	assert.EqualError(suite.T(), err, "You cannot take away your own permission to assign roles")
+}
+
+func (suite *RoleUsecaseTestSuite) TestSetUserRoles_CannotEscalate() {
+	suite.createRole("promoter", domain.PermissionUserPromote, domain.PermissionTaskRead)
+	promoter := suite.identity(suite.createUser("promoter", "promoter"))
+
+	_, err := suite.usecase.SetUserRoles(context.Background(), promoter, "alice", []string{domain.AdminRole})
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	_, err = suite.usecase.SetUserRoles(context.Background(), promoter, "root", []string{domain.UserRole})
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err, "admin permissions cannot be taken away either")
+
+	suite.createRole("reader", domain.PermissionTaskRead)
+	_, err = suite.usecase.SetUserRoles(context.Background(), promoter, "alice", []string{domain.UserRole, "reader"})
+	assert.NoError(suite.T(), err, "roles the user already holds can be kept")
+}
+
+func (suite *RoleUsecaseTestSuite) TestUpdateRole_RefreshesCachedPermissions() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+	_, err := suite.usecase.SetUserRoles(context.Background(), suite.root, "alice", []string{"auditor"})
+	suite.Require().NoError(err)
+	assert.False(suite.T(), suite.identity(suite.alice).Can(domain.PermissionWebhookManage))
+
+	updated, err := suite.usecase.UpdateRole(context.Background(), suite.root, "auditor", domain.Role{Description: "Reviews integrations", Permissions: []string{domain.PermissionAuditRead, domain.PermissionWebhookManage}})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(2), updated.Version)
+	assert.Equal(suite.T(), "Reviews integrations", updated.Description)
+
+	assert.True(suite.T(), suite.identity(suite.alice).Can(domain.PermissionWebhookManage))
+
+	_, err = suite.usecase.UpdateRole(context.Background(), suite.root, domain.AdminRole, domain.Role{Permissions: []string{domain.PermissionTaskRead}})
+	assert.EqualError(suite.T(), err, "Built-in roles cannot be changed")
+
+	_, err = suite.usecase.UpdateRole(context.Background(), suite.root, "missing", domain.Role{Permissions: []string{domain.PermissionTaskRead}})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *RoleUsecaseTestSuite) TestDeleteRole() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+	_, err := suite.usecase.SetUserRoles(context.Background(), suite.root, "alice", []string{"user", "auditor"})
+	suite.Require().NoError(err)
+
+	err = suite.usecase.DeleteRole(context.Background(), suite.root, "auditor")
+	assert.EqualError(suite.T(), err, "Role is still held by 1 users")
+
+	_, err = suite.usecase.SetUserRoles(context.Background(), suite.root, "alice", []string{"user"})
+	suite.Require().NoError(err)
+	assert.NoError(suite.T(), suite.usecase.DeleteRole(context.Background(), suite.root, "auditor"))
+
+	_, err = suite.usecase.GetRole(context.Background(), "auditor")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.usecase.DeleteRole(context.Background(), suite.root, domain.UserRole)
+	assert.EqualError(suite.T(), err, "Built-in roles cannot be changed")
+}
+
+func (suite *RoleUsecaseTestSuite) TestRoleChangesAreAudited() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+	_, err := suite.usecase.UpdateRole(context.Background(), suite.root, "auditor", domain.Role{Permissions: []string{domain.PermissionTaskRead}})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.usecase.DeleteRole(context.Background(), suite.root, "auditor"))
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{TargetType: "role", Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 3)
+
+	assert.Equal(suite.T(), domain.AuditRoleDelete, page.Entries[0].Action)
+	assert.Equal(suite.T(), domain.AuditRoleUpdate, page.Entries[1].Action)
+	assert.Contains(suite.T(), page.Entries[1].Changes, domain.AuditChange{Field: "permissions", Before: domain.PermissionAuditRead, After: domain.PermissionTaskRead})
+	assert.Equal(suite.T(), domain.AuditRoleCreate, page.Entries[2].Action)
+	assert.Equal(suite.T(), "root", page.Entries[2].Actor)
+}
+
+func (suite *RoleUsecaseTestSuite) TestResolveGrants_CachesUntilInvalidated() {
+	suite.createRole("auditor", domain.PermissionAuditRead)
+	suite.identity(suite.alice)
+
+	// a change made behind the usecase's back is not seen until the cache is invalidated
+	alice := suite.alice
+	alice.Roles = []string{"auditor"}
+	suite.Require().NoError(suite.userRepo.UpdateUser(context.Background(), alice.ID, alice))
+	assert.True(suite.T(), suite.identity(suite.alice).HasRole(domain.UserRole))
+
+	suite.permissions.InvalidateUser(alice.ID)
+	assert.Equal(suite.T(), []string{"auditor"}, suite.identity(suite.alice).Roles)
+}
+
+func (suite *RoleUsecaseTestSuite) TestResolveGrants() {
+	legacy := suite.createUser("legacy")
+	legacy.Role = domain.AdminRole
+	suite.Require().NoError(suite.userRepo.UpdateUser(context.Background(), legacy.ID, legacy))
+	assert.True(suite.T(), suite.identity(legacy).CanManageAll(), "the single role of older users is honoured")
+
+	_, err := suite.permissions.ResolveGrants(context.Background(), "507f1f77bcf86cd799439011")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	uncached := NewPermissionResolver(suite.userRepo, suite.roleRepo, 0)
+	grants, err := uncached.ResolveGrants(context.Background(), suite.alice.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{domain.UserRole}, grants.Roles)
+}
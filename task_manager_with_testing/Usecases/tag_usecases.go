@@ -0,0 +1,214 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+)
+
+// TagUsecase manages each user's tag catalog. Tags belong to the user whose tasks carry
+// them, so even an admin only sees and changes their own.
+type TagUsecase interface {
+	// GetTags lists the caller's tags with the number of their tasks carrying each
+	GetTags(ctx context.Context, identity domain.Identity) ([]domain.Tag, error)
+	// UpdateTag sets the color of one of the caller's tags
+	UpdateTag(ctx context.Context, identity domain.Identity, name string, tag domain.Tag) (domain.Tag, error)
+	// RenameTag renames one of the caller's tags on every task carrying it
+	RenameTag(ctx context.Context, identity domain.Identity, name, newName string) (domain.Tag, error)
+	// MergeTags replaces some of the caller's tags with another one on every task carrying them
+	MergeTags(ctx context.Context, identity domain.Identity, merge domain.TagMerge) (domain.Tag, error)
+}
+
+// tagUsecase struct
+type tagUsecase struct {
+	tagRepo     repositories.TagRepository
+	taskRepo    repositories.TaskRepository
+	historyRepo repositories.TaskHistoryRepository
+	audit       auditRecorder
+	events      TaskEventPublisher
+}
+
+// NewTagUsecase creates a new tag usecase. Renaming and merging tags changes tasks, so
+// each changed task gets a new version in its history and is published to events.
+func NewTagUsecase(tagRepo repositories.TagRepository, taskRepo repositories.TaskRepository, historyRepo repositories.TaskHistoryRepository, auditRepo repositories.AuditRepository, events TaskEventPublisher) TagUsecase {
+	return &tagUsecase{tagRepo: tagRepo, taskRepo: taskRepo, historyRepo: historyRepo, audit: auditRecorder{auditRepo}, events: events}
+}
+
+// GetTags lists every tag the caller's tasks carry or that they gave a color, by name
+func (u *tagUsecase) GetTags(ctx context.Context, identity domain.Identity) ([]domain.Tag, error) {
+	catalog, err := u.catalog(ctx, identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]domain.Tag, 0, len(catalog))
+	for _, tag := range catalog {
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// UpdateTag sets the color of a tag. A tag can be given a color before any task uses
+// it; clearing the color of a tag no task uses removes it from the catalog.
+func (u *tagUsecase) UpdateTag(ctx context.Context, identity domain.Identity, name string, tag domain.Tag) (domain.Tag, error) {
+	name, err := domain.NormalizeTag(name)
+	if err != nil {
+		return domain.Tag{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	color, err := domain.NormalizeTagColor(tag.Color)
+	if err != nil {
+		return domain.Tag{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	catalog, err := u.catalog(ctx, identity.UserID)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	existing := catalog[name]
+	updated := domain.Tag{OwnerID: identity.UserID, Name: name, Color: color}
+	if color == "" {
+		err = u.tagRepo.DeleteTags(ctx, identity.UserID, []string{name})
+	} else {
+		err = u.tagRepo.SaveTag(ctx, updated)
+	}
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditTagUpdate, "tag", name, map[string]string{"color": existing.Color}, map[string]string{"color": color})
+	updated.Count = existing.Count
+	return updated, nil
+}
+
+// RenameTag renames a tag on every one of the caller's tasks, keeping its color. A tag
+// cannot be renamed to one that already exists; the two have to be merged instead.
+func (u *tagUsecase) RenameTag(ctx context.Context, identity domain.Identity, name, newName string) (domain.Tag, error) {
+	from, err := domain.NormalizeTag(name)
+	if err != nil {
+		return domain.Tag{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	to, err := domain.NormalizeTag(newName)
+	if err != nil {
+		return domain.Tag{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	if from == to {
+		return domain.Tag{}, &domain.BadRequestError{Message: "The new name is the same as the old one"}
+	}
+
+	catalog, err := u.catalog(ctx, identity.UserID)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	if _, ok := catalog[from]; !ok {
+		return domain.Tag{}, &domain.NotFoundError{Message: "Tag not found"}
+	}
+
+	if _, ok := catalog[to]; ok {
+		return domain.Tag{}, &domain.BadRequestError{Message: fmt.Sprintf("Tag %s already exists; merge the tags instead", to)}
+	}
+
+	return u.replace(ctx, identity, domain.AuditTagRename, catalog, []string{from}, to)
+}
+
+// MergeTags replaces the tags with another one on every one of the caller's tasks. The
+// tag merged into may be a new one. It keeps its color, or else takes the first one
+// among the merged tags.
+func (u *tagUsecase) MergeTags(ctx context.Context, identity domain.Identity, merge domain.TagMerge) (domain.Tag, error) {
+	if err := merge.Validate(); err != nil {
+		return domain.Tag{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	catalog, err := u.catalog(ctx, identity.UserID)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	for _, name := range merge.From {
+		if _, ok := catalog[name]; !ok {
+			return domain.Tag{}, &domain.NotFoundError{Message: fmt.Sprintf("Tag %s not found", name)}
+		}
+	}
+
+	return u.replace(ctx, identity, domain.AuditTagMerge, catalog, merge.From, merge.Into)
+}
+
+// replace swaps the tags for another one on the caller's tasks, records and publishes
+// each changed task, and moves their color over to the tag, unless it has one of its
+// own. catalog is the caller's catalog as it was before.
+func (u *tagUsecase) replace(ctx context.Context, identity domain.Identity, action string, catalog map[string]domain.Tag, from []string, to string) (domain.Tag, error) {
+	changed, err := u.taskRepo.ReplaceTags(ctx, identity.UserID, from, to)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	if catalog[to].Color == "" {
+		for _, name := range from {
+			if color := catalog[name].Color; color != "" {
+				if err := u.tagRepo.SaveTag(ctx, domain.Tag{OwnerID: identity.UserID, Name: to, Color: color}); err != nil {
+					return domain.Tag{}, err
+				}
+				break
+			}
+		}
+	}
+
+	if err := u.tagRepo.DeleteTags(ctx, identity.UserID, from); err != nil {
+		return domain.Tag{}, err
+	}
+
+	for _, task := range changed {
+		recordTaskVersion(ctx, u.historyRepo, identity, task)
+		u.events.Publish(ctx, newTaskEvent(identity, domain.EventTaskUpdated, task))
+	}
+
+	before := map[string]string{"tags": strings.Join(from, ",")}
+	after := map[string]string{"tag": to, "tasks": strconv.Itoa(len(changed))}
+	u.audit.record(ctx, identity, action, "tag", to, before, after)
+
+	catalog, err = u.catalog(ctx, identity.UserID)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	return catalog[to], nil
+}
+
+// catalog returns the owner's tags by name: those their tasks carry, with how many do,
+// and those given a color
+func (u *tagUsecase) catalog(ctx context.Context, ownerID string) (map[string]domain.Tag, error) {
+	counts, err := u.taskRepo.GetTagCounts(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := u.tagRepo.GetTags(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[string]domain.Tag, len(counts)+len(stored))
+	for name, count := range counts {
+		catalog[name] = domain.Tag{OwnerID: ownerID, Name: name, Count: count}
+	}
+
+	for _, tag := range stored {
+		tag.Count = counts[tag.Name]
+		catalog[tag.Name] = tag
+	}
+
+	return catalog, nil
+}
@@ -0,0 +1,241 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TagUsecaseTestSuite manages tag catalogs on top of the in-memory repositories
+type TagUsecaseTestSuite struct {
+	suite.Suite
+	auditRepo   repositories.AuditRepository
+	events      *recordingPublisher
+	taskUsecase TaskUsecase
+	usecase     TagUsecase
+}
+
+func (suite *TagUsecaseTestSuite) SetupTest() {
+	taskRepo := repositories.NewTaskMemoryRepository()
+	historyRepo := repositories.NewTaskHistoryMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.events = &recordingPublisher{}
+	suite.taskUsecase = NewTaskUsecase(taskRepo, historyRepo, repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), suite.auditRepo, suite.events, domain.DefaultWorkflow())
+	suite.usecase = NewTagUsecase(repositories.NewTagMemoryRepository(), taskRepo, historyRepo, suite.auditRepo, suite.events)
+}
+
+func TestTagUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(TagUsecaseTestSuite))
+}
+
+func (suite *TagUsecaseTestSuite) createTask(identity domain.Identity, title string, tags ...string) domain.Task {
+	task := batchTask(title)
+	task.Tags = tags
+	created, err := suite.taskUsecase.CreateTask(context.Background(), identity, *task)
+	suite.Require().NoError(err)
+	return created
+}
+
+func (suite *TagUsecaseTestSuite) tags(identity domain.Identity) []domain.Tag {
+	tags, err := suite.usecase.GetTags(context.Background(), identity)
+	suite.Require().NoError(err)
+	for i := range tags {
+		tags[i].OwnerID = ""
+	}
+	return tags
+}
+
+func (suite *TagUsecaseTestSuite) taskTags(id string) []string {
+	task, err := suite.taskUsecase.GetTask(context.Background(), owner, id)
+	suite.Require().NoError(err)
+	return task.Tags
+}
+
+func (suite *TagUsecaseTestSuite) TestCreateTask_NormalizesTags() {
+	created := suite.createTask(owner, "Pay rent", " Home ", "home", "Money  Matters", "area:Finance")
+
+	assert.Equal(suite.T(), []string{"area:finance", "home", "money-matters"}, created.Tags)
+
+	task := batchTask("Invalid")
+	task.Tags = []string{"not/allowed"}
+	_, err := suite.taskUsecase.CreateTask(context.Background(), owner, *task)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TagUsecaseTestSuite) TestGetTasks_FiltersByTag() {
+	suite.createTask(owner, "Both", "home", "urgent")
+	suite.createTask(owner, "Home", "home")
+	suite.createTask(owner, "Work", "work")
+
+	page, err := suite.taskUsecase.GetTasks(context.Background(), owner, domain.TaskQuery{Tags: [][]string{{"URGENT", "Work"}}, SortBy: "title"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"Both", "Work"}, titles(page.Tasks))
+
+	_, err = suite.taskUsecase.GetTasks(context.Background(), owner, domain.TaskQuery{Tags: [][]string{{"a b/c"}}})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TagUsecaseTestSuite) TestGetTags_CountsTheCallersTasks() {
+	suite.createTask(owner, "Both", "home", "urgent")
+	suite.createTask(owner, "Home", "home")
+	suite.createTask(otherUser, "Theirs", "work")
+	_, err := suite.usecase.UpdateTag(context.Background(), owner, "later", domain.Tag{Color: "#ABC"})
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), []domain.Tag{
+		{Name: "home", Count: 2},
+		{Name: "later", Color: "#abc"},
+		{Name: "urgent", Count: 1},
+	}, suite.tags(owner))
+	assert.Empty(suite.T(), suite.tags(admin))
+}
+
+func (suite *TagUsecaseTestSuite) TestUpdateTag() {
+	suite.createTask(owner, "Home", "home")
+
+	tag, err := suite.usecase.UpdateTag(context.Background(), owner, "Home", domain.Tag{Color: "#00FF00"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), domain.Tag{OwnerID: owner.UserID, Name: "home", Color: "#00ff00", Count: 1}, tag)
+
+	_, err = suite.usecase.UpdateTag(context.Background(), owner, "home", domain.Tag{Color: "green"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.UpdateTag(context.Background(), owner, "home", domain.Tag{})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []domain.Tag{{Name: "home", Count: 1}}, suite.tags(owner))
+}
+
+func (suite *TagUsecaseTestSuite) TestRenameTag() {
+	first := suite.createTask(owner, "First", "bug", "ui")
+	second := suite.createTask(owner, "Second", "bug")
+	theirs := suite.createTask(otherUser, "Theirs", "bug")
+	_, err := suite.usecase.UpdateTag(context.Background(), owner, "bug", domain.Tag{Color: "#ff0000"})
+	suite.Require().NoError(err)
+
+	tag, err := suite.usecase.RenameTag(context.Background(), owner, "bug", "Defect")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), domain.Tag{OwnerID: owner.UserID, Name: "defect", Color: "#ff0000", Count: 2}, tag)
+
+	assert.Equal(suite.T(), []string{"defect", "ui"}, suite.taskTags(first.ID))
+	assert.Equal(suite.T(), []string{"defect"}, suite.taskTags(second.ID))
+	assert.Equal(suite.T(), []domain.Tag{{Name: "defect", Color: "#ff0000", Count: 2}, {Name: "ui", Count: 1}}, suite.tags(owner))
+
+	task, err := suite.taskUsecase.GetTask(context.Background(), otherUser, theirs.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"bug"}, task.Tags)
+}
+
+func (suite *TagUsecaseTestSuite) TestRenameTag_BumpsTaskVersions() {
+	created := suite.createTask(owner, "First", "bug")
+
+	_, err := suite.usecase.RenameTag(context.Background(), owner, "bug", "defect")
+	suite.Require().NoError(err)
+
+	_, err = suite.taskUsecase.UpdateTask(context.Background(), owner, created.ID, created.Version, created)
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+}
+
+func (suite *TagUsecaseTestSuite) TestMergeTags_RecordsAndPublishesChangedTasks() {
+	created := suite.createTask(owner, "First", "bug", "defect")
+	suite.createTask(owner, "Untouched", "ui")
+
+	_, err := suite.usecase.MergeTags(context.Background(), owner, domain.TagMerge{From: []string{"bug", "defect"}, Into: "issue"})
+	suite.Require().NoError(err)
+
+	history, err := suite.taskUsecase.GetTaskHistory(context.Background(), owner, created.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(history, 2)
+	assert.Equal(suite.T(), int64(2), history[1].Version)
+	assert.Equal(suite.T(), []string{"issue"}, history[1].Tags)
+	assert.Equal(suite.T(), owner.Username, history[1].ModifiedBy)
+
+	assert.Equal(suite.T(), []string{domain.EventTaskCreated, domain.EventTaskCreated, domain.EventTaskUpdated}, suite.events.types())
+	updated := suite.events.events[2].Task
+	assert.Equal(suite.T(), created.ID, updated.ID)
+	assert.Equal(suite.T(), int64(2), updated.Version)
+	assert.Equal(suite.T(), []string{"issue"}, updated.Tags)
+}
+
+func (suite *TagUsecaseTestSuite) TestRenameTag_Errors() {
+	suite.createTask(owner, "First", "bug", "defect")
+
+	_, err := suite.usecase.RenameTag(context.Background(), owner, "missing", "other")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	_, err = suite.usecase.RenameTag(context.Background(), owner, "bug", "defect")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.RenameTag(context.Background(), owner, "bug", "BUG")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.RenameTag(context.Background(), owner, "bug", "")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TagUsecaseTestSuite) TestMergeTags() {
+	both := suite.createTask(owner, "Both", "bug", "defect", "ui")
+	one := suite.createTask(owner, "One", "defect")
+	_, err := suite.usecase.UpdateTag(context.Background(), owner, "defect", domain.Tag{Color: "#ff0000"})
+	suite.Require().NoError(err)
+
+	tag, err := suite.usecase.MergeTags(context.Background(), owner, domain.TagMerge{From: []string{"Bug", "defect"}, Into: "issue"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), domain.Tag{OwnerID: owner.UserID, Name: "issue", Color: "#ff0000", Count: 2}, tag)
+
+	assert.Equal(suite.T(), []string{"issue", "ui"}, suite.taskTags(both.ID))
+	assert.Equal(suite.T(), []string{"issue"}, suite.taskTags(one.ID))
+	assert.Equal(suite.T(), []domain.Tag{{Name: "issue", Color: "#ff0000", Count: 2}, {Name: "ui", Count: 1}}, suite.tags(owner))
+}
+
+func (suite *TagUsecaseTestSuite) TestMergeTags_KeepsTheTargetsColor() {
+	suite.createTask(owner, "First", "bug")
+	suite.createTask(owner, "Second", "defect")
+	_, err := suite.usecase.UpdateTag(context.Background(), owner, "bug", domain.Tag{Color: "#ff0000"})
+	suite.Require().NoError(err)
+	_, err = suite.usecase.UpdateTag(context.Background(), owner, "defect", domain.Tag{Color: "#0000ff"})
+	suite.Require().NoError(err)
+
+	tag, err := suite.usecase.MergeTags(context.Background(), owner, domain.TagMerge{From: []string{"bug"}, Into: "defect"})
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "#0000ff", tag.Color)
+	assert.Equal(suite.T(), 2, tag.Count)
+}
+
+func (suite *TagUsecaseTestSuite) TestMergeTags_Errors() {
+	suite.createTask(owner, "First", "bug")
+
+	_, err := suite.usecase.MergeTags(context.Background(), owner, domain.TagMerge{From: []string{"bug", "missing"}, Into: "issue"})
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+	assert.Equal(suite.T(), []string{"bug"}, titlesOfTags(suite.tags(owner)))
+
+	_, err = suite.usecase.MergeTags(context.Background(), owner, domain.TagMerge{From: []string{"bug"}, Into: "Bug"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.MergeTags(context.Background(), owner, domain.TagMerge{Into: "issue"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TagUsecaseTestSuite) TestTagChangesAreAudited() {
+	suite.createTask(owner, "First", "bug")
+	_, err := suite.usecase.RenameTag(context.Background(), owner, "bug", "defect")
+	suite.Require().NoError(err)
+
+	page, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{Action: domain.AuditTagRename, Limit: 10})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Entries, 1)
+	assert.Equal(suite.T(), "defect", page.Entries[0].TargetID)
+	assert.Contains(suite.T(), page.Entries[0].Changes, domain.AuditChange{Field: "tasks", After: "1"})
+}
+
+func titlesOfTags(tags []domain.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
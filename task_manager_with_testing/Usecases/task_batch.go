@@ -0,0 +1,295 @@
+package usecases
+
+import (
+	"context"
+	"log"
+
+	domain "task-manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// batchStep is an operation of a batch that passed its checks, along with the writes
+// that apply it
+type batchStep struct {
+	// index is the position of the operation in the batch
+	index int
+	// write is the position of the write that applies the operation
+	write int
+	// next is the position of the write creating the next instance of a completed
+	// recurring task, or -1
+	next int
+	// existing is the task as it was before an update or delete
+	existing domain.Task
+}
+
+// BatchTasks applies a batch of operations with the same checks as the single-task
+// methods, and returns the outcome of each. The tasks involved, duplicate titles and
+// subtasks are looked up once for the whole batch, and the writes are made together.
+// An atomic batch is applied in full or not at all; when it fails the first failure is
+// also returned as the error.
+func (u *taskUsecase) BatchTasks(ctx context.Context, identity domain.Identity, request domain.BatchRequest) ([]domain.BatchResult, error) {
+	if err := request.Validate(); err != nil {
+		return nil, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := request.Operations
+	results, writes, steps, err := u.planBatch(ctx, access, operations)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Atomic {
+		for _, result := range results {
+			if result.Err != nil {
+				return notApplied(results), result.Err
+			}
+		}
+	}
+
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	var created []*domain.Task
+	for _, step := range steps {
+		if operations[step.index].Op == domain.BatchCreate {
+			created = append(created, &writes[step.write].Task)
+		}
+	}
+	if err := u.inPersonalProject(ctx, &access, created...); err != nil {
+		return nil, err
+	}
+
+	written, err := u.taskRepo.WriteTasks(ctx, writes, request.Atomic)
+	if err != nil && (!request.Atomic || len(written) != len(writes)) {
+		return nil, err
+	}
+
+	if err != nil {
+		for _, step := range steps {
+			if failed := written[step.write].Err; failed != nil {
+				results[step.index].Err = failed
+			} else if step.next >= 0 && written[step.next].Err != nil {
+				results[step.index].Err = written[step.next].Err
+			}
+		}
+		return notApplied(results), err
+	}
+
+	for _, step := range steps {
+		u.finishBatchStep(ctx, identity, operations[step.index].Op, step, written, &results[step.index])
+	}
+
+	return results, nil
+}
+
+// planBatch runs the checks of every operation and returns the outcome of those that
+// failed them, along with the writes that apply the others and the steps they belong to
+func (u *taskUsecase) planBatch(ctx context.Context, access domain.TaskAccess, operations []domain.BatchOperation) ([]domain.BatchResult, []domain.TaskWrite, []batchStep, error) {
+	results := make([]domain.BatchResult, len(operations))
+	for i, operation := range operations {
+		results[i] = domain.BatchResult{Op: operation.Op, ID: operation.ID}
+		if err := operation.Validate(); err != nil {
+			results[i].Err = &domain.BadRequestError{Message: err.Error()}
+		} else if !access.Identity.Can(operation.Permission()) {
+			results[i].Err = &domain.ForbiddenError{Message: "You are not authorized for this action"}
+		} else if operation.ID != "" && !primitive.IsValidObjectID(operation.ID) {
+			results[i].Err = &domain.BadRequestError{Message: "Invalid ID"}
+		}
+	}
+
+	existing, taken, parents, err := u.loadBatch(ctx, access.Identity, operations, results)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var writes []domain.TaskWrite
+	var steps []batchStep
+	for i, operation := range operations {
+		if results[i].Err != nil {
+			continue
+		}
+
+		step := batchStep{index: i, next: -1, existing: existing[operation.ID]}
+		switch operation.Op {
+		case domain.BatchCreate:
+			task := *operation.Task
+			if err := u.prepareCreate(ctx, &access, &task); err != nil {
+				results[i].Err = err
+				continue
+			}
+
+			if taken[task.Title] {
+				results[i].Err = &domain.BadRequestError{Message: "Task already exists"}
+				continue
+			}
+			taken[task.Title] = true
+
+			step.write = len(writes)
+			writes = append(writes, domain.TaskWrite{Op: domain.BatchCreate, Task: task})
+		case domain.BatchUpdate:
+			if results[i].Err = checkBatchTarget(access, operation.ID, existing); results[i].Err != nil {
+				continue
+			}
+
+			task := *operation.Task
+			if err := u.prepareUpdate(ctx, &access, step.existing, &task); err != nil {
+				results[i].Err = err
+				continue
+			}
+
+			// the next instance gets its ID up front so the completed task can name it
+			if instance, ok := u.nextInstance(step.existing, task); ok {
+				instance.ID = primitive.NewObjectID().Hex()
+				task.NextID = instance.ID
+				step.next = len(writes)
+				writes = append(writes, domain.TaskWrite{Op: domain.BatchCreate, Task: instance})
+			}
+
+			step.write = len(writes)
+			writes = append(writes, domain.TaskWrite{Op: domain.BatchUpdate, ID: operation.ID, Version: *operation.Version, Task: task})
+		case domain.BatchDelete:
+			if results[i].Err = checkBatchTarget(access, operation.ID, existing); results[i].Err != nil {
+				continue
+			}
+
+			if parents[operation.ID] {
+				results[i].Err = &domain.BadRequestError{Message: "Task has subtasks; delete or move them first"}
+				continue
+			}
+
+			step.write = len(writes)
+			writes = append(writes, domain.TaskWrite{Op: domain.BatchDelete, ID: operation.ID})
+		}
+
+		steps = append(steps, step)
+	}
+
+	return results, writes, steps, nil
+}
+
+// loadBatch looks up, with one request each, the tasks the batch updates or deletes,
+// the titles of the caller's tasks and which of the tasks being deleted have subtasks
+func (u *taskUsecase) loadBatch(ctx context.Context, identity domain.Identity, operations []domain.BatchOperation, results []domain.BatchResult) (map[string]domain.Task, map[string]bool, map[string]bool, error) {
+	var ids, titles, deleted []string
+	for i, operation := range operations {
+		if results[i].Err != nil {
+			continue
+		}
+
+		switch operation.Op {
+		case domain.BatchCreate:
+			titles = append(titles, operation.Task.Title)
+		case domain.BatchDelete:
+			deleted = append(deleted, operation.ID)
+			ids = append(ids, operation.ID)
+		default:
+			ids = append(ids, operation.ID)
+		}
+	}
+
+	existing := make(map[string]domain.Task)
+	if len(ids) > 0 {
+		tasks, err := u.taskRepo.GetTasksByIDs(ctx, ids)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, task := range tasks {
+			existing[task.ID] = task
+		}
+	}
+
+	taken := make(map[string]bool)
+	if len(titles) > 0 {
+		tasks, err := u.taskRepo.GetTasksByTitles(ctx, identity.UserID, titles)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, task := range tasks {
+			taken[task.Title] = true
+		}
+	}
+
+	parents := make(map[string]bool)
+	if len(deleted) > 0 {
+		subtasks, err := u.taskRepo.GetSubtasks(ctx, deleted)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, subtask := range subtasks {
+			parents[subtask.ParentID] = true
+		}
+	}
+
+	return existing, taken, parents, nil
+}
+
+// checkBatchTarget checks that the task being updated or deleted exists and that the
+// caller may modify it
+func checkBatchTarget(access domain.TaskAccess, id string, existing map[string]domain.Task) error {
+	task, ok := existing[id]
+	if !ok {
+		return &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	return checkModification(access, task)
+}
+
+// finishBatchStep records the outcome of an operation once its writes were made, and
+// records, audits and publishes the change like the single-task methods do
+func (u *taskUsecase) finishBatchStep(ctx context.Context, identity domain.Identity, op string, step batchStep, written []domain.TaskWriteResult, result *domain.BatchResult) {
+	var next domain.Task
+	if step.next >= 0 {
+		if written[step.next].Err != nil {
+			log.Printf("recurrence: failed to create the next instance of task %s: %v", step.existing.ID, written[step.next].Err)
+		} else {
+			next = written[step.next].Task
+		}
+	}
+
+	outcome := written[step.write]
+	if outcome.Err != nil {
+		result.Err = outcome.Err
+		if next.ID != "" {
+			if err := u.taskRepo.DeleteTask(context.WithoutCancel(ctx), next.ID); err != nil {
+				log.Printf("recurrence: failed to remove instance %s of task %s: %v", next.ID, step.existing.ID, err)
+			}
+		}
+		return
+	}
+
+	switch op {
+	case domain.BatchCreate:
+		result.ID = outcome.Task.ID
+		result.Task = &outcome.Task
+		u.recordCreate(ctx, identity, outcome.Task)
+	case domain.BatchUpdate:
+		result.Task = &outcome.Task
+		u.recordUpdate(ctx, identity, step.existing, outcome.Task)
+		if next.ID != "" {
+			u.recordCreate(ctx, identity, next)
+		}
+	case domain.BatchDelete:
+		u.recordDelete(ctx, identity, step.existing)
+	}
+}
+
+// notApplied marks every operation of a failed atomic batch that did not fail itself
+func notApplied(results []domain.BatchResult) []domain.BatchResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = &domain.NotAppliedError{Message: "Not applied because another operation in the batch failed"}
+		}
+	}
+
+	return results
+}
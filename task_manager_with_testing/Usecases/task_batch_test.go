@@ -0,0 +1,230 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TaskBatchTestSuite checks batches against the in-memory repositories
+type TaskBatchTestSuite struct {
+	suite.Suite
+	auditRepo repositories.AuditRepository
+	events    *recordingPublisher
+	usecase   TaskUsecase
+}
+
+func (suite *TaskBatchTestSuite) SetupTest() {
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.events = &recordingPublisher{}
+	suite.usecase = NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), suite.auditRepo, suite.events, domain.DefaultWorkflow())
+}
+
+func TestTaskBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(TaskBatchTestSuite))
+}
+
+func batchTask(title string) *domain.Task {
+	return &domain.Task{Title: title, DueDate: time.Now().Add(time.Hour).Truncate(time.Second), Status: "pending"}
+}
+
+func (suite *TaskBatchTestSuite) create(identity domain.Identity, title string) domain.Task {
+	task, err := suite.usecase.CreateTask(context.Background(), identity, *batchTask(title))
+	suite.Require().NoError(err)
+
+	return task
+}
+
+func (suite *TaskBatchTestSuite) titles(identity domain.Identity) []string {
+	page, err := suite.usecase.GetTasks(context.Background(), identity, domain.TaskQuery{SortBy: "title"})
+	suite.Require().NoError(err)
+
+	titles := []string{}
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+// errorTypes lists the type of each result's error, nil for applied operations
+func errorTypes(results []domain.BatchResult) []interface{} {
+	types := make([]interface{}, len(results))
+	for i, result := range results {
+		switch result.Err.(type) {
+		case nil:
+		case *domain.BadRequestError:
+			types[i] = "bad request"
+		case *domain.NotFoundError:
+			types[i] = "not found"
+		case *domain.ForbiddenError:
+			types[i] = "forbidden"
+		case *domain.ConflictError:
+			types[i] = "conflict"
+		case *domain.NotAppliedError:
+			types[i] = "not applied"
+		default:
+			types[i] = result.Err.Error()
+		}
+	}
+	return types
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_AppliesEachOperationOnItsOwn() {
+	existing := suite.create(owner, "Existing")
+	stale := suite.create(owner, "Stale")
+	doomed := suite.create(owner, "Doomed")
+	foreign := suite.create(otherUser, "Foreign")
+
+	renamed := batchTask("Renamed")
+	staleVersion := stale.Version + 1
+	results, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Operations: []domain.BatchOperation{
+		{Op: domain.BatchCreate, Task: batchTask("New")},
+		{Op: domain.BatchCreate, Task: batchTask("Existing")},
+		{Op: domain.BatchCreate, Task: batchTask("New")},
+		{Op: domain.BatchCreate, Task: &domain.Task{Title: "No due date", Status: "pending"}},
+		{Op: domain.BatchUpdate, ID: existing.ID, Version: &existing.Version, Task: renamed},
+		{Op: domain.BatchUpdate, ID: stale.ID, Version: &staleVersion, Task: batchTask("Stale again")},
+		{Op: domain.BatchDelete, ID: doomed.ID},
+		{Op: domain.BatchDelete, ID: foreign.ID},
+		{Op: domain.BatchDelete, ID: "not-an-id"},
+		{Op: "archive", ID: existing.ID},
+	}})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []interface{}{nil, "bad request", "bad request", "bad request", nil, "conflict", nil, "not found", "bad request", "bad request"}, errorTypes(results))
+	assert.EqualError(suite.T(), results[1].Err, "Task already exists")
+	assert.EqualError(suite.T(), results[3].Err, "due date is required")
+
+	suite.Require().NotNil(results[0].Task)
+	assert.Equal(suite.T(), results[0].ID, results[0].Task.ID)
+	assert.Equal(suite.T(), owner.UserID, results[0].Task.OwnerID)
+	suite.Require().NotNil(results[4].Task)
+	assert.Equal(suite.T(), existing.Version+1, results[4].Task.Version)
+
+	assert.Equal(suite.T(), []string{"New", "Renamed", "Stale"}, suite.titles(owner))
+	assert.Equal(suite.T(), []string{"Foreign"}, suite.titles(otherUser))
+
+	entries, err := suite.auditRepo.GetEntries(context.Background(), domain.AuditQuery{Actor: owner.Username, Limit: 50})
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), entries.Entries, 3+3)
+	assert.Equal(suite.T(), []string{
+		domain.EventTaskCreated, domain.EventTaskCreated, domain.EventTaskCreated, domain.EventTaskCreated,
+		domain.EventTaskCreated, domain.EventTaskUpdated, domain.EventTaskDeleted,
+	}, suite.events.types())
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_ChecksThePermissionOfEachOperation() {
+	existing := suite.create(owner, "Existing")
+
+	// a role that may edit tasks but not create or delete them
+	editor := owner
+	editor.Permissions = []string{domain.PermissionTaskRead, domain.PermissionTaskUpdate}
+	results, err := suite.usecase.BatchTasks(context.Background(), editor, domain.BatchRequest{Operations: []domain.BatchOperation{
+		{Op: domain.BatchCreate, Task: batchTask("New")},
+		{Op: domain.BatchUpdate, ID: existing.ID, Version: &existing.Version, Task: batchTask("Renamed")},
+		{Op: domain.BatchDelete, ID: existing.ID},
+	}})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []interface{}{"forbidden", nil, "forbidden"}, errorTypes(results))
+	assert.Equal(suite.T(), []string{"Renamed"}, suite.titles(owner))
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_AtomicAppliesNothingOnFailure() {
+	existing := suite.create(owner, "Existing")
+	staleVersion := existing.Version + 1
+
+	results, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Atomic: true, Operations: []domain.BatchOperation{
+		{Op: domain.BatchCreate, Task: batchTask("New")},
+		{Op: domain.BatchDelete, ID: existing.ID},
+		{Op: domain.BatchUpdate, ID: existing.ID, Version: &staleVersion, Task: batchTask("Renamed")},
+	}})
+
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+	assert.Equal(suite.T(), []interface{}{"not applied", "not applied", "not found"}, errorTypes(results))
+	assert.Equal(suite.T(), []string{"Existing"}, suite.titles(owner))
+	assert.Equal(suite.T(), []string{domain.EventTaskCreated}, suite.events.types())
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_AtomicRejectsInvalidOperationsUpFront() {
+	results, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Atomic: true, Operations: []domain.BatchOperation{
+		{Op: domain.BatchCreate, Task: batchTask("New")},
+		{Op: domain.BatchCreate, Task: &domain.Task{Title: "Bad status", DueDate: time.Now().Add(time.Hour), Status: "later"}},
+	}})
+
+	assert.EqualError(suite.T(), err, "status must be one of pending, in_progress, blocked, completed, cancelled")
+	assert.Equal(suite.T(), []interface{}{"not applied", "bad request"}, errorTypes(results))
+	assert.Empty(suite.T(), suite.titles(owner))
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_Atomic() {
+	existing := suite.create(owner, "Existing")
+
+	results, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Atomic: true, Operations: []domain.BatchOperation{
+		{Op: domain.BatchCreate, Task: batchTask("First")},
+		{Op: domain.BatchCreate, Task: batchTask("Second")},
+		{Op: domain.BatchDelete, ID: existing.ID},
+	}})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []interface{}{nil, nil, nil}, errorTypes(results))
+	assert.Equal(suite.T(), []string{"First", "Second"}, suite.titles(owner))
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_CompletingRecurringTask() {
+	chore := *batchTask("Water the plants")
+	chore.Recurrence = "FREQ=DAILY"
+	first, err := suite.usecase.CreateTask(context.Background(), owner, chore)
+	suite.Require().NoError(err)
+	second, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{Title: "Feed the cat", DueDate: chore.DueDate, Status: "pending", Recurrence: "FREQ=DAILY"})
+	suite.Require().NoError(err)
+
+	completed, stale := first, second
+	completed.Status, stale.Status = "completed", "completed"
+	staleVersion := second.Version + 1
+	results, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Operations: []domain.BatchOperation{
+		{Op: domain.BatchUpdate, ID: first.ID, Version: &first.Version, Task: &completed},
+		{Op: domain.BatchUpdate, ID: second.ID, Version: &staleVersion, Task: &stale},
+	}})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []interface{}{nil, "conflict"}, errorTypes(results))
+	suite.Require().NotEmpty(results[0].Task.NextID)
+
+	next, err := suite.usecase.GetTask(context.Background(), owner, results[0].Task.NextID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 2, next.Occurrence)
+
+	// the instance created for the conflicting update is removed again
+	assert.Equal(suite.T(), []string{"Feed the cat", "Water the plants", "Water the plants"}, suite.titles(owner))
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_DeleteWithSubtasks() {
+	parent := suite.create(owner, "Parent")
+	subtask := *batchTask("Subtask")
+	subtask.ParentID = parent.ID
+	_, err := suite.usecase.CreateTask(context.Background(), owner, subtask)
+	suite.Require().NoError(err)
+
+	results, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Operations: []domain.BatchOperation{
+		{Op: domain.BatchDelete, ID: parent.ID},
+	}})
+
+	suite.Require().NoError(err)
+	assert.EqualError(suite.T(), results[0].Err, "Task has subtasks; delete or move them first")
+}
+
+func (suite *TaskBatchTestSuite) TestBatchTasks_Size() {
+	_, err := suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	operations := make([]domain.BatchOperation, domain.MaxBatchOperations+1)
+	_, err = suite.usecase.BatchTasks(context.Background(), owner, domain.BatchRequest{Operations: operations})
+	assert.EqualError(suite.T(), err, "a batch must have between 1 and 500 operations")
+}
@@ -0,0 +1,169 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+
+	domain "task-manager/Domain"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before it is
+// dropped; a dropped client reconnects and catches up from the replay buffer
+const subscriberBuffer = 64
+
+// TaskEventPublishers publishes every event to each of its publishers in turn
+type TaskEventPublishers []TaskEventPublisher
+
+// Publish passes the event on to every publisher
+func (p TaskEventPublishers) Publish(ctx context.Context, event domain.TaskEvent) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
+
+// TaskStream is an in-process hub that passes task events on to live subscribers as
+// they are published. It keeps the most recent events so a client that reconnects
+// can pick up where it left off.
+type TaskStream interface {
+	TaskEventPublisher
+	// Subscribe starts receiving the events of the tasks the access lets its user see.
+	// Events published after lastEventID that are still in the replay buffer are
+	// returned as the backlog.
+	Subscribe(access domain.TaskAccess, lastEventID string) *TaskSubscription
+	// Close ends every subscription; later subscriptions are closed straight away
+	Close()
+}
+
+// TaskSubscription is one subscriber's view of the stream
+type TaskSubscription struct {
+	// Backlog holds the events missed since the last event the client saw
+	Backlog []domain.TaskEvent
+	// Resync is set when the last event the client saw is no longer in the replay
+	// buffer, so events may have been missed and the client should reload its tasks
+	Resync bool
+	// Events receives new events. It is closed when the stream closes or when the
+	// subscriber falls too far behind.
+	Events <-chan domain.TaskEvent
+
+	cancel func()
+}
+
+// Close stops the subscription
+func (s *TaskSubscription) Close() {
+	s.cancel()
+}
+
+// subscriber is a live subscription as the hub sees it
+type subscriber struct {
+	access domain.TaskAccess
+	events chan domain.TaskEvent
+}
+
+// taskStream struct
+type taskStream struct {
+	mu          sync.Mutex
+	replaySize  int
+	replay      []domain.TaskEvent
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+// NewTaskStream creates a new task stream that replays up to replaySize events
+func NewTaskStream(replaySize int) TaskStream {
+	return &taskStream{
+		replaySize:  replaySize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish remembers the event and hands it to every subscriber allowed to see it.
+// It never blocks: a subscriber whose buffer is full is dropped.
+func (s *taskStream) Publish(ctx context.Context, event domain.TaskEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if s.replaySize > 0 {
+		if len(s.replay) == s.replaySize {
+			copy(s.replay, s.replay[1:])
+			s.replay = s.replay[:len(s.replay)-1]
+		}
+		s.replay = append(s.replay, event)
+	}
+
+	for sub := range s.subscribers {
+		if !sub.access.CanView(event.Task) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe registers a subscriber and collects its backlog under the same lock, so
+// no event is missed or delivered twice between the two
+func (s *taskStream) Subscribe(access domain.TaskAccess, lastEventID string) *TaskSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := &subscriber{access: access, events: make(chan domain.TaskEvent, subscriberBuffer)}
+	subscription := &TaskSubscription{Events: sub.events, cancel: func() { s.unsubscribe(sub) }}
+
+	if s.closed {
+		close(sub.events)
+		return subscription
+	}
+
+	if lastEventID != "" {
+		start := -1
+		for i, event := range s.replay {
+			if event.ID == lastEventID {
+				start = i + 1
+				break
+			}
+		}
+
+		if start < 0 {
+			subscription.Resync = true
+		} else {
+			for _, event := range s.replay[start:] {
+				if access.CanView(event.Task) {
+					subscription.Backlog = append(subscription.Backlog, event)
+				}
+			}
+		}
+	}
+
+	s.subscribers[sub] = struct{}{}
+	return subscription
+}
+
+// unsubscribe removes a subscriber unless the hub already dropped it
+func (s *taskStream) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (s *taskStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,142 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	domain "task-manager/Domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ownedEvent(id string, identity domain.Identity) domain.TaskEvent {
+	return domain.TaskEvent{ID: id, Type: domain.EventTaskUpdated, Task: domain.Task{ID: "task-" + id, OwnerID: identity.UserID}}
+}
+
+// received drains the events waiting on a subscription without blocking
+func received(subscription *TaskSubscription) []string {
+	var ids []string
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func eventIDs(events []domain.TaskEvent) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestTaskStream_PublishesToSubscribersWhoCanSeeTheTask(t *testing.T) {
+	stream := NewTaskStream(16)
+	ownerSub := stream.Subscribe(domain.TaskAccess{Identity: owner}, "")
+	otherSub := stream.Subscribe(domain.TaskAccess{Identity: otherUser}, "")
+	adminSub := stream.Subscribe(domain.TaskAccess{Identity: admin}, "")
+
+	stream.Publish(context.Background(), ownedEvent("1", owner))
+	stream.Publish(context.Background(), ownedEvent("2", otherUser))
+
+	assert.Equal(t, []string{"1"}, received(ownerSub))
+	assert.Equal(t, []string{"2"}, received(otherSub))
+	assert.Equal(t, []string{"1", "2"}, received(adminSub))
+}
+
+func TestTaskStream_PublishesProjectTasksToMembers(t *testing.T) {
+	stream := NewTaskStream(16)
+	viewerSub := stream.Subscribe(domain.TaskAccess{Identity: otherUser, Roles: map[string]string{"project-1": domain.ProjectViewer}}, "")
+	ownerSub := stream.Subscribe(domain.TaskAccess{Identity: owner}, "")
+
+	event := ownedEvent("1", owner)
+	event.Task.ProjectID = "project-1"
+	stream.Publish(context.Background(), event)
+
+	assert.Equal(t, []string{"1"}, received(viewerSub))
+	assert.Empty(t, received(ownerSub), "owning a project task does not make its events visible outside the project")
+}
+
+func TestTaskStream_ReplaysEventsAfterLastEventID(t *testing.T) {
+	stream := NewTaskStream(16)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		stream.Publish(context.Background(), ownedEvent(id, owner))
+	}
+	stream.Publish(context.Background(), ownedEvent("5", otherUser))
+
+	subscription := stream.Subscribe(domain.TaskAccess{Identity: owner}, "2")
+	assert.False(t, subscription.Resync)
+	assert.Equal(t, []string{"3", "4"}, eventIDs(subscription.Backlog))
+
+	latest := stream.Subscribe(domain.TaskAccess{Identity: owner}, "5")
+	assert.False(t, latest.Resync)
+	assert.Empty(t, latest.Backlog)
+
+	fresh := stream.Subscribe(domain.TaskAccess{Identity: owner}, "")
+	assert.False(t, fresh.Resync)
+	assert.Empty(t, fresh.Backlog)
+}
+
+func TestTaskStream_ResyncsWhenLastEventIDIsNoLongerBuffered(t *testing.T) {
+	stream := NewTaskStream(3)
+	for i := 1; i <= 5; i++ {
+		stream.Publish(context.Background(), ownedEvent(fmt.Sprint(i), owner))
+	}
+
+	assert.Equal(t, []string{"5"}, eventIDs(stream.Subscribe(domain.TaskAccess{Identity: owner}, "4").Backlog))
+
+	subscription := stream.Subscribe(domain.TaskAccess{Identity: owner}, "1")
+	assert.True(t, subscription.Resync)
+	assert.Empty(t, subscription.Backlog)
+
+	assert.True(t, NewTaskStream(0).Subscribe(domain.TaskAccess{Identity: owner}, "1").Resync)
+}
+
+func TestTaskStream_DropsSubscribersThatFallBehind(t *testing.T) {
+	stream := NewTaskStream(0)
+	slow := stream.Subscribe(domain.TaskAccess{Identity: owner}, "")
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		stream.Publish(context.Background(), ownedEvent(fmt.Sprint(i), owner))
+	}
+
+	assert.Len(t, received(slow), subscriberBuffer)
+	_, ok := <-slow.Events
+	assert.False(t, ok)
+
+	// closing a dropped subscription is harmless
+	slow.Close()
+}
+
+func TestTaskStream_Close(t *testing.T) {
+	stream := NewTaskStream(16)
+	subscription := stream.Subscribe(domain.TaskAccess{Identity: owner}, "")
+	unsubscribed := stream.Subscribe(domain.TaskAccess{Identity: owner}, "")
+	unsubscribed.Close()
+
+	stream.Close()
+	stream.Publish(context.Background(), ownedEvent("1", owner))
+
+	_, ok := <-subscription.Events
+	assert.False(t, ok)
+
+	_, ok = <-stream.Subscribe(domain.TaskAccess{Identity: owner}, "").Events
+	assert.False(t, ok)
+}
+
+func TestTaskEventPublishers_PublishesToEach(t *testing.T) {
+	first, second := &recordingPublisher{}, &recordingPublisher{}
+
+	TaskEventPublishers{first, second}.Publish(context.Background(), ownedEvent("1", owner))
+
+	assert.Equal(t, []string{domain.EventTaskUpdated}, first.types())
+	assert.Equal(t, []string{domain.EventTaskUpdated}, second.types())
+}
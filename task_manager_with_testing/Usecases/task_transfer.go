@@ -0,0 +1,223 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+)
+
+// ExportTasks writes every task matching the query's filters that the caller can see to
+// w, in the given format and the query's sort order. Tasks are read a page at a time, so
+// the query's cursor and limit are ignored. Nothing is written if the export fails to
+// start.
+func (u *taskUsecase) ExportTasks(ctx context.Context, identity domain.Identity, query domain.TaskQuery, format string, w io.Writer) error {
+	if err := domain.ValidateFormat(format); err != nil {
+		return &domain.BadRequestError{Message: err.Error()}
+	}
+
+	query.Cursor, query.Limit = "", domain.MaxTaskPageSize
+	page, err := u.GetTasks(ctx, identity, query)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := infrastructure.NewTaskEncoder(format, w)
+	if err != nil {
+		return err
+	}
+
+	for {
+		for _, task := range page.Tasks {
+			if err := encoder.Encode(task); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return encoder.Close()
+		}
+
+		query.Cursor = page.NextCursor
+		if page, err = u.GetTasks(ctx, identity, query); err != nil {
+			return err
+		}
+	}
+}
+
+// ImportTasks reads tasks from r and creates them for the caller with the same checks
+// as BatchTasks, a batch at a time. A task whose title the caller already uses, or that
+// appears earlier in the import, is handled as options.OnDuplicate says; updating a
+// duplicate only changes the columns the import has. Every record is reported on, and
+// a dry run reports what would happen without saving anything.
+func (u *taskUsecase) ImportTasks(ctx context.Context, identity domain.Identity, r io.Reader, options domain.ImportOptions) (domain.ImportReport, error) {
+	if err := options.Validate(); err != nil {
+		return domain.ImportReport{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	if options.OnDuplicate == "" {
+		options.OnDuplicate = domain.DuplicateFail
+	}
+
+	records, err := infrastructure.DecodeTasks(options.Format, r, options.Mapping)
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	if len(records) == 0 {
+		return domain.ImportReport{}, &domain.BadRequestError{Message: "The import has no tasks"}
+	}
+
+	existing, err := u.tasksByTitle(ctx, identity, records)
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	report := domain.ImportReport{DryRun: options.DryRun, Rows: make([]domain.ImportRow, len(records))}
+	var operations []domain.BatchOperation
+	var positions []int
+	firstRows := make(map[string]int)
+	for i, record := range records {
+		row := &report.Rows[i]
+		row.Row, row.Title = record.Row, record.Task.Title
+		if record.Err != nil {
+			row.Action, row.Error = domain.ImportFail, record.Err.Error()
+			continue
+		}
+
+		title := record.Task.Title
+		if first, ok := firstRows[title]; ok && title != "" {
+			if options.OnDuplicate == domain.DuplicateSkip {
+				row.Action = domain.ImportSkip
+			} else {
+				row.Action, row.Error = domain.ImportFail, fmt.Sprintf("Duplicates the task on row %d", first)
+			}
+			continue
+		}
+		firstRows[title] = record.Row
+
+		task := record.Task
+		operation := domain.BatchOperation{Op: domain.BatchCreate, Task: &task}
+		if match, ok := existing[title]; ok {
+			switch options.OnDuplicate {
+			case domain.DuplicateSkip:
+				row.Action, row.TaskID = domain.ImportSkip, match.ID
+				continue
+			case domain.DuplicateUpdate:
+				task = mergeImport(match, record)
+				operation = domain.BatchOperation{Op: domain.BatchUpdate, ID: match.ID, Version: &match.Version, Task: &task}
+			}
+			// otherwise the create fails as a duplicate
+		}
+
+		operations = append(operations, operation)
+		positions = append(positions, i)
+	}
+
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	for start := 0; start < len(operations); start += domain.MaxBatchOperations {
+		batch := operations[start:min(start+domain.MaxBatchOperations, len(operations))]
+
+		var results []domain.BatchResult
+		if options.DryRun {
+			results, _, _, err = u.planBatch(ctx, access, batch)
+		} else {
+			results, err = u.BatchTasks(ctx, identity, domain.BatchRequest{Operations: batch})
+		}
+
+		if err != nil {
+			if start == 0 {
+				return domain.ImportReport{}, err
+			}
+
+			// the batches before were saved, so the rest is reported as failed rather than the whole import
+			for _, position := range positions[start:] {
+				report.Rows[position].Action, report.Rows[position].Error = domain.ImportFail, err.Error()
+			}
+			break
+		}
+
+		for j, result := range results {
+			row := &report.Rows[positions[start+j]]
+			if result.Err != nil {
+				row.Action, row.Error = domain.ImportFail, result.Err.Error()
+			} else {
+				row.Action, row.TaskID = result.Op, result.ID
+			}
+		}
+	}
+
+	for _, row := range report.Rows {
+		switch row.Action {
+		case domain.ImportCreate:
+			report.Created++
+		case domain.ImportUpdate:
+			report.Updated++
+		case domain.ImportSkip:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// tasksByTitle looks up the caller's tasks that share a title with a record
+func (u *taskUsecase) tasksByTitle(ctx context.Context, identity domain.Identity, records []domain.ImportRecord) (map[string]domain.Task, error) {
+	var titles []string
+	for _, record := range records {
+		if record.Err == nil && record.Task.Title != "" {
+			titles = append(titles, record.Task.Title)
+		}
+	}
+
+	existing := make(map[string]domain.Task)
+	if len(titles) == 0 {
+		return existing, nil
+	}
+
+	tasks, err := u.taskRepo.GetTasksByTitles(ctx, identity.UserID, titles)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		existing[task.Title] = task
+	}
+
+	return existing, nil
+}
+
+// mergeImport returns the existing task with the columns the record has replaced
+func mergeImport(existing domain.Task, record domain.ImportRecord) domain.Task {
+	task := existing
+	for column := range record.Fields {
+		switch column {
+		case "title":
+			task.Title = record.Task.Title
+		case "due_date":
+			task.DueDate = record.Task.DueDate
+		case "status":
+			task.Status = record.Task.Status
+		case "project_id":
+			task.ProjectID = record.Task.ProjectID
+		case "parent_id":
+			task.ParentID = record.Task.ParentID
+		case "depends_on":
+			task.DependsOn = record.Task.DependsOn
+		case "tags":
+			task.Tags = record.Task.Tags
+		case "recurrence":
+			task.Recurrence = record.Task.Recurrence
+		}
+	}
+
+	return task
+}
@@ -0,0 +1,224 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TaskTransferTestSuite checks exports and imports against the in-memory repositories
+type TaskTransferTestSuite struct {
+	suite.Suite
+	events      *recordingPublisher
+	projectRepo repositories.ProjectRepository
+	usecase     TaskUsecase
+}
+
+func (suite *TaskTransferTestSuite) SetupTest() {
+	suite.events = &recordingPublisher{}
+	suite.projectRepo = repositories.NewProjectMemoryRepository()
+	suite.usecase = NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), suite.projectRepo, repositories.NewAuditMemoryRepository(), suite.events, domain.DefaultWorkflow())
+}
+
+func TestTaskTransferTestSuite(t *testing.T) {
+	suite.Run(t, new(TaskTransferTestSuite))
+}
+
+// futureDate is a due date far enough ahead to be valid for a pending task
+var futureDate = time.Now().AddDate(1, 0, 0).UTC().Format(time.DateOnly)
+
+func (suite *TaskTransferTestSuite) importCSV(identity domain.Identity, input string, options domain.ImportOptions) domain.ImportReport {
+	options.Format = domain.FormatCSV
+	report, err := suite.usecase.ImportTasks(context.Background(), identity, strings.NewReader(input), options)
+	suite.Require().NoError(err)
+
+	return report
+}
+
+func (suite *TaskTransferTestSuite) tasks(identity domain.Identity) map[string]domain.Task {
+	page, err := suite.usecase.GetTasks(context.Background(), identity, domain.TaskQuery{Limit: domain.MaxTaskPageSize})
+	suite.Require().NoError(err)
+
+	tasks := make(map[string]domain.Task)
+	for _, task := range page.Tasks {
+		tasks[task.Title] = task
+	}
+	return tasks
+}
+
+func (suite *TaskTransferTestSuite) TestExportTasks_PagesThroughEveryVisibleTask() {
+	for i := 0; i < domain.MaxTaskPageSize+5; i++ {
+		_, err := suite.usecase.CreateTask(context.Background(), owner, *batchTask(fmt.Sprintf("Task %03d", i)))
+		suite.Require().NoError(err)
+	}
+	_, err := suite.usecase.CreateTask(context.Background(), otherUser, *batchTask("Foreign"))
+	suite.Require().NoError(err)
+
+	var out bytes.Buffer
+	err = suite.usecase.ExportTasks(context.Background(), owner, domain.TaskQuery{SortBy: "title", Cursor: "ignored", Limit: 1}, domain.FormatJSON, &out)
+	suite.Require().NoError(err)
+
+	var exported []domain.Task
+	suite.Require().NoError(json.Unmarshal(out.Bytes(), &exported))
+	assert.Len(suite.T(), exported, domain.MaxTaskPageSize+5)
+	assert.Equal(suite.T(), "Task 000", exported[0].Title)
+	assert.Equal(suite.T(), "Task 104", exported[len(exported)-1].Title)
+}
+
+func (suite *TaskTransferTestSuite) TestExportTasks_WritesNothingWhenItCannotStart() {
+	var out bytes.Buffer
+
+	err := suite.usecase.ExportTasks(context.Background(), owner, domain.TaskQuery{}, "xml", &out)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	err = suite.usecase.ExportTasks(context.Background(), owner, domain.TaskQuery{Status: "unknown"}, domain.FormatCSV, &out)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+	assert.Zero(suite.T(), out.Len())
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks() {
+	report := suite.importCSV(owner, "title,due_date,status\n"+
+		"First,"+futureDate+",pending\n"+
+		"Second,"+futureDate+",later\n"+
+		"First,"+futureDate+",pending\n"+
+		"Third,someday,pending\n", domain.ImportOptions{})
+
+	assert.Equal(suite.T(), 1, report.Created)
+	assert.Equal(suite.T(), 3, report.Failed)
+	assert.Equal(suite.T(), []domain.ImportRow{
+		{Row: 2, Title: "First", Action: domain.ImportCreate, TaskID: suite.tasks(owner)["First"].ID},
+		{Row: 3, Title: "Second", Action: domain.ImportFail, Error: "status must be one of pending, in_progress, blocked, completed, cancelled"},
+		{Row: 4, Title: "First", Action: domain.ImportFail, Error: "Duplicates the task on row 2"},
+		{Row: 5, Title: "Third", Action: domain.ImportFail, Error: "due_date must be an RFC3339 timestamp or a YYYY-MM-DD date"},
+	}, report.Rows)
+	assert.Equal(suite.T(), owner.UserID, suite.tasks(owner)["First"].OwnerID)
+	assert.Equal(suite.T(), []string{domain.EventTaskCreated}, suite.events.types())
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks_DryRunSavesNothing() {
+	report := suite.importCSV(owner, "title,due_date,status,parent_id\n"+
+		"First,"+futureDate+",pending,\n"+
+		"Orphan,"+futureDate+",pending,"+strings.Repeat("a", 24)+"\n", domain.ImportOptions{DryRun: true})
+
+	assert.True(suite.T(), report.DryRun)
+	assert.Equal(suite.T(), 1, report.Created)
+	assert.Equal(suite.T(), 1, report.Failed)
+	assert.Equal(suite.T(), "Parent task not found", report.Rows[1].Error)
+	assert.Empty(suite.T(), suite.tasks(owner))
+	assert.Empty(suite.T(), suite.events.types())
+
+	// not even the personal project the tasks would go to is created
+	projects, err := suite.projectRepo.GetProjects(context.Background(), owner.UserID)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), projects)
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks_CreatesPersonalProject() {
+	report := suite.importCSV(owner, "title,due_date,status\nFirst,"+futureDate+",pending\nSecond,"+futureDate+",pending\n", domain.ImportOptions{})
+	suite.Require().Equal(2, report.Created)
+
+	projects, err := suite.projectRepo.GetProjects(context.Background(), owner.UserID)
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 1)
+	assert.Equal(suite.T(), owner.UserID, projects[0].PersonalOf)
+
+	tasks := suite.tasks(owner)
+	assert.Equal(suite.T(), projects[0].ID, tasks["First"].ProjectID)
+	assert.Equal(suite.T(), projects[0].ID, tasks["Second"].ProjectID)
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks_Duplicates() {
+	chore := *batchTask("Chore")
+	chore.Recurrence = "FREQ=WEEKLY"
+	existing, err := suite.usecase.CreateTask(context.Background(), owner, chore)
+	suite.Require().NoError(err)
+
+	input := "title,due_date\nChore," + futureDate + "\n"
+
+	report := suite.importCSV(owner, "title,due_date,status\nChore,"+futureDate+",pending\n", domain.ImportOptions{})
+	assert.Equal(suite.T(), "Task already exists", report.Rows[0].Error)
+
+	report = suite.importCSV(owner, input, domain.ImportOptions{OnDuplicate: domain.DuplicateSkip})
+	assert.Equal(suite.T(), domain.ImportRow{Row: 2, Title: "Chore", Action: domain.ImportSkip, TaskID: existing.ID}, report.Rows[0])
+
+	report = suite.importCSV(owner, input, domain.ImportOptions{OnDuplicate: domain.DuplicateUpdate})
+	assert.Equal(suite.T(), domain.ImportRow{Row: 2, Title: "Chore", Action: domain.ImportUpdate, TaskID: existing.ID}, report.Rows[0])
+	assert.Equal(suite.T(), 1, report.Updated)
+
+	// the columns the import does not have are left as they were
+	updated := suite.tasks(owner)["Chore"]
+	assert.Equal(suite.T(), existing.Version+1, updated.Version)
+	assert.Equal(suite.T(), futureDate, updated.DueDate.Format(time.DateOnly))
+	assert.Equal(suite.T(), "FREQ=WEEKLY", updated.Recurrence)
+	assert.Equal(suite.T(), "pending", updated.Status)
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks_DuplicatesAreTheCallersOwn() {
+	_, err := suite.usecase.CreateTask(context.Background(), otherUser, *batchTask("Shared name"))
+	suite.Require().NoError(err)
+
+	report := suite.importCSV(owner, "title,due_date,status\nShared name,"+futureDate+",pending\n", domain.ImportOptions{OnDuplicate: domain.DuplicateUpdate})
+
+	assert.Equal(suite.T(), domain.ImportCreate, report.Rows[0].Action)
+	assert.Len(suite.T(), suite.tasks(owner), 1)
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks_RoundTrip() {
+	parent := suite.create(owner, "Parent")
+	subtask := *batchTask("Subtask")
+	subtask.ParentID = parent.ID
+	_, err := suite.usecase.CreateTask(context.Background(), owner, subtask)
+	suite.Require().NoError(err)
+
+	var out bytes.Buffer
+	suite.Require().NoError(suite.usecase.ExportTasks(context.Background(), owner, domain.TaskQuery{SortBy: "title"}, domain.FormatNDJSON, &out))
+
+	// the other user is not a member of the owner's project, so the tasks go to their own
+	options := domain.ImportOptions{Format: domain.FormatNDJSON, Mapping: map[string]string{"project_id": domain.IgnoreColumn}}
+	report, err := suite.usecase.ImportTasks(context.Background(), otherUser, &out, options)
+	suite.Require().NoError(err)
+
+	// the subtask names a parent the other user cannot see
+	assert.Equal(suite.T(), 1, report.Created)
+	assert.Equal(suite.T(), "Parent task not found", report.Rows[1].Error)
+	assert.Equal(suite.T(), otherUser.UserID, suite.tasks(otherUser)["Parent"].OwnerID)
+}
+
+func (suite *TaskTransferTestSuite) TestImportTasks_InvalidImport() {
+	cases := map[string]struct {
+		input   string
+		options domain.ImportOptions
+		err     string
+	}{
+		"format":       {"", domain.ImportOptions{Format: "xml"}, "format must be csv, json or ndjson"},
+		"on_duplicate": {"", domain.ImportOptions{Format: domain.FormatCSV, OnDuplicate: "merge"}, "on_duplicate must be fail, skip or update"},
+		"mapping":      {"", domain.ImportOptions{Format: domain.FormatCSV, Mapping: map[string]string{"Name": "name"}}, "column Name is mapped to name, which is not a task column"},
+		"empty":        {"title\n", domain.ImportOptions{Format: domain.FormatCSV}, "The import has no tasks"},
+	}
+
+	for name, c := range cases {
+		suite.Run(name, func() {
+			_, err := suite.usecase.ImportTasks(context.Background(), owner, strings.NewReader(c.input), c.options)
+
+			assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+			assert.EqualError(suite.T(), err, c.err)
+		})
+	}
+}
+
+func (suite *TaskTransferTestSuite) create(identity domain.Identity, title string) domain.Task {
+	task, err := suite.usecase.CreateTask(context.Background(), identity, *batchTask(title))
+	suite.Require().NoError(err)
+
+	return task
+}
@@ -1,66 +1,771 @@
 package usecases
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
 	domain "task-manager/Domain"
 	repositories "task-manager/Repositories"
 )
 
-// TaskUsecase interface
+// TaskUsecase interface. Every method acts on behalf of the authenticated user and
+// checks their role in the task's project: admins can manage every task, owners and
+// editors of a project can manage its tasks and viewers can only read them. Tasks from
+// before projects, which have none, can only be managed by their owner.
+// Updates must name the version they were based on so concurrent edits are not lost,
+// and can only change a task's status along a transition of the workflow.
 type TaskUsecase interface {
-	CreateTask(task domain.Task) error
-	GetTask(id string) (domain.Task, error)
-	GetTasks() ([]domain.Task, error)
-	UpdateTask(id string, task domain.Task) error
-	DeleteTask(id string) error
+	CreateTask(ctx context.Context, identity domain.Identity, task domain.Task) (domain.Task, error)
+	GetTask(ctx context.Context, identity domain.Identity, id string) (domain.Task, error)
+	GetTasks(ctx context.Context, identity domain.Identity, query domain.TaskQuery) (domain.TaskPage, error)
+	UpdateTask(ctx context.Context, identity domain.Identity, id string, version int64, task domain.Task) (domain.Task, error)
+	DeleteTask(ctx context.Context, identity domain.Identity, id string) error
+	GetTaskHistory(ctx context.Context, identity domain.Identity, id string) ([]domain.TaskVersion, error)
+	SearchTasks(ctx context.Context, identity domain.Identity, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error)
+	GetSubtree(ctx context.Context, identity domain.Identity, id string) (domain.TaskNode, error)
+	GetDependencyGraph(ctx context.Context, identity domain.Identity, id string) (domain.TaskGraph, error)
+	GetOccurrences(ctx context.Context, identity domain.Identity, id string, limit int) ([]time.Time, error)
+	BatchTasks(ctx context.Context, identity domain.Identity, request domain.BatchRequest) ([]domain.BatchResult, error)
+	ExportTasks(ctx context.Context, identity domain.Identity, query domain.TaskQuery, format string, w io.Writer) error
+	ImportTasks(ctx context.Context, identity domain.Identity, r io.Reader, options domain.ImportOptions) (domain.ImportReport, error)
+	GetNextStates(ctx context.Context, identity domain.Identity, id string) ([]string, error)
 }
 
 // taskUsecase struct
 type taskUsecase struct {
-	taskRepo repositories.TaskRepository
+	taskRepo    repositories.TaskRepository
+	historyRepo repositories.TaskHistoryRepository
+	commentRepo repositories.CommentRepository
+	projectRepo repositories.ProjectRepository
+	audit       auditRecorder
+	events      TaskEventPublisher
+	workflow    domain.Workflow
 }
 
-// NewTaskUsecase creates a new task usecase
-func NewTaskUsecase(taskRepo repositories.TaskRepository) TaskUsecase {
-	return &taskUsecase{taskRepo}
+// NewTaskUsecase creates a new task usecase that publishes every change to events and
+// moves tasks between statuses along the workflow, which must be valid
+func NewTaskUsecase(taskRepo repositories.TaskRepository, historyRepo repositories.TaskHistoryRepository, commentRepo repositories.CommentRepository, projectRepo repositories.ProjectRepository, auditRepo repositories.AuditRepository, events TaskEventPublisher, workflow domain.Workflow) TaskUsecase {
+	return &taskUsecase{taskRepo: taskRepo, historyRepo: historyRepo, commentRepo: commentRepo, projectRepo: projectRepo, audit: auditRecorder{auditRepo}, events: events, workflow: workflow}
 }
 
-// CreateTask creates a new task
-func (u *taskUsecase) CreateTask(task domain.Task) error {
+// CreateTask creates a new task owned by the caller in a project they can edit, their
+// personal project unless the task names another
+func (u *taskUsecase) CreateTask(ctx context.Context, identity domain.Identity, task domain.Task) (domain.Task, error) {
 	if err := task.Validate(); err != nil {
-		return &domain.BadRequestError{Message: err.Error()}
+		return domain.Task{}, &domain.BadRequestError{Message: err.Error()}
 	}
 
-	// check if task already exists
-	tasks, _ := u.taskRepo.GetTasks()
-	for _, t := range tasks {
-		if t.Title == task.Title {
-			return &domain.BadRequestError{Message: "Task already exists"}
-		}
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if err := u.prepareCreate(ctx, &access, &task); err != nil {
+		return domain.Task{}, err
+	}
+
+	// check if the owner already has the task; an exact match sorts first among titles sharing its prefix
+	page, err := u.taskRepo.GetTasks(ctx, domain.TaskQuery{OwnerID: task.OwnerID, TitlePrefix: task.Title, SortBy: "title", Limit: 1})
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if len(page.Tasks) > 0 && page.Tasks[0].Title == task.Title {
+		return domain.Task{}, &domain.BadRequestError{Message: "Task already exists"}
+	}
+
+	if err := u.inPersonalProject(ctx, &access, &task); err != nil {
+		return domain.Task{}, err
+	}
+
+	created, err := u.taskRepo.CreateTask(ctx, task)
+	if err != nil {
+		return domain.Task{}, err
 	}
 
-	return u.taskRepo.CreateTask(task)
+	u.recordCreate(ctx, identity, created)
+	return created, nil
+}
+
+// GetTask retrieves a task by ID; tasks the caller cannot see are reported as not found
+func (u *taskUsecase) GetTask(ctx context.Context, identity domain.Identity, id string) (domain.Task, error) {
+	task, _, err := u.viewTask(ctx, identity, id)
+	return task, err
 }
 
-// GetTask retrieves a task by ID
-func (u *taskUsecase) GetTask(id string) (domain.Task, error) {
-	return u.taskRepo.GetTask(id)
+// GetTasks retrieves one page of tasks matching the query, limited to the tasks the caller can see
+func (u *taskUsecase) GetTasks(ctx context.Context, identity domain.Identity, query domain.TaskQuery) (domain.TaskPage, error) {
+	if err := query.Validate(); err != nil {
+		return domain.TaskPage{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	if query.Status != "" {
+		if err := u.checkStatus(query.Status); err != nil {
+			return domain.TaskPage{}, err
+		}
+	}
+
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return domain.TaskPage{}, err
+	}
+	query.Visibility = access.Visibility()
+
+	if query.SortBy == "" {
+		query.SortBy = "due_date"
+	}
+
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultTaskPageSize
+	}
+
+	return u.taskRepo.GetTasks(ctx, query)
 }
 
-// GetTasks retrieves all tasks
-func (u *taskUsecase) GetTasks() ([]domain.Task, error) {
-	return u.taskRepo.GetTasks()
+// SearchTasks finds tasks by the words in their titles, most relevant first, limited to
+// the tasks the caller can see
+func (u *taskUsecase) SearchTasks(ctx context.Context, identity domain.Identity, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error) {
+	if err := query.Validate(); err != nil {
+		return nil, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	query.OwnerID, query.Visibility = "", access.Visibility()
+
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultSearchLimit
+	}
+
+	return u.taskRepo.SearchTasks(ctx, query)
 }
 
-// UpdateTask updates a task
-func (u *taskUsecase) UpdateTask(id string, task domain.Task) error {
+// UpdateTask updates a task the caller is allowed to modify, provided it is still at
+// the given version, and returns it with its new version. A task without a project_id
+// stays in its project.
+func (u *taskUsecase) UpdateTask(ctx context.Context, identity domain.Identity, id string, version int64, task domain.Task) (domain.Task, error) {
 	if err := task.Validate(); err != nil {
+		return domain.Task{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	existing, err := u.authorizeModification(ctx, access, id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if err := u.prepareUpdate(ctx, &access, existing, &task); err != nil {
+		return domain.Task{}, err
+	}
+
+	// completing a recurring task creates its next instance, once
+	var next domain.Task
+	if instance, ok := u.nextInstance(existing, task); ok {
+		if next, err = u.taskRepo.CreateTask(ctx, instance); err != nil {
+			return domain.Task{}, err
+		}
+		task.NextID = next.ID
+	}
+
+	updated, err := u.taskRepo.UpdateTask(ctx, id, version, task)
+	if err != nil {
+		if next.ID != "" {
+			if err := u.taskRepo.DeleteTask(context.WithoutCancel(ctx), next.ID); err != nil {
+				log.Printf("recurrence: failed to remove instance %s of task %s: %v", next.ID, id, err)
+			}
+		}
+		return domain.Task{}, err
+	}
+
+	u.recordUpdate(ctx, identity, existing, updated)
+	if next.ID != "" {
+		u.recordCreate(ctx, identity, next)
+	}
+	return updated, nil
+}
+
+// GetNextStates lists the statuses the caller may move a task they can see to, in
+// workflow order; a task they cannot modify has none
+func (u *taskUsecase) GetNextStates(ctx context.Context, identity domain.Identity, id string) ([]string, error) {
+	task, access, err := u.viewTask(ctx, identity, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !access.CanEdit(task) {
+		return []string{}, nil
+	}
+
+	return u.workflow.NextStates(identity, task.Status), nil
+}
+
+// DeleteTask deletes a task the caller is allowed to modify
+func (u *taskUsecase) DeleteTask(ctx context.Context, identity domain.Identity, id string) error {
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return err
+	}
+
+	existing, err := u.authorizeModification(ctx, access, id)
+	if err != nil {
+		return err
+	}
+
+	subtasks, err := u.taskRepo.GetSubtasks(ctx, []string{id})
+	if err != nil {
+		return err
+	}
+
+	if len(subtasks) > 0 {
+		return &domain.BadRequestError{Message: "Task has subtasks; delete or move them first"}
+	}
+
+	if err := u.taskRepo.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+
+	u.recordDelete(ctx, identity, existing)
+	return nil
+}
+
+// GetTaskHistory retrieves every recorded version of a task the caller can see, oldest first
+func (u *taskUsecase) GetTaskHistory(ctx context.Context, identity domain.Identity, id string) ([]domain.TaskVersion, error) {
+	if _, err := u.GetTask(ctx, identity, id); err != nil {
+		return nil, err
+	}
+
+	return u.historyRepo.GetHistory(ctx, id)
+}
+
+// GetOccurrences previews up to limit due dates of a recurring task's series, starting
+// with the task's own
+func (u *taskUsecase) GetOccurrences(ctx context.Context, identity domain.Identity, id string, limit int) ([]time.Time, error) {
+	if limit < 0 || limit > domain.MaxOccurrencePreview {
+		return nil, &domain.BadRequestError{Message: fmt.Sprintf("count must be between 1 and %d", domain.MaxOccurrencePreview)}
+	}
+
+	if limit == 0 {
+		limit = domain.DefaultOccurrencePreview
+	}
+
+	task, _, err := u.viewTask(ctx, identity, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Recurrence == "" {
+		return nil, &domain.BadRequestError{Message: "Task does not recur"}
+	}
+
+	occurrences, err := task.Occurrences(limit)
+	if err != nil {
+		return nil, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	return occurrences, nil
+}
+
+// GetSubtree retrieves a task the caller can see along with its subtasks, recursively.
+// Subtasks the caller cannot see are left out together with their own subtasks.
+func (u *taskUsecase) GetSubtree(ctx context.Context, identity domain.Identity, id string) (domain.TaskNode, error) {
+	root, access, err := u.viewTask(ctx, identity, id)
+	if err != nil {
+		return domain.TaskNode{}, err
+	}
+
+	children := make(map[string][]domain.Task)
+	visited := map[string]bool{root.ID: true}
+	for level := []string{root.ID}; len(level) > 0; {
+		subtasks, err := u.taskRepo.GetSubtasks(ctx, level)
+		if err != nil {
+			return domain.TaskNode{}, err
+		}
+
+		level = nil
+		for _, subtask := range subtasks {
+			if visited[subtask.ID] || !access.CanView(subtask) {
+				continue
+			}
+
+			visited[subtask.ID] = true
+			children[subtask.ParentID] = append(children[subtask.ParentID], subtask)
+			level = append(level, subtask.ID)
+		}
+	}
+
+	var build func(task domain.Task) domain.TaskNode
+	build = func(task domain.Task) domain.TaskNode {
+		node := domain.TaskNode{Task: task, Subtasks: []domain.TaskNode{}}
+		for _, child := range children[task.ID] {
+			node.Subtasks = append(node.Subtasks, build(child))
+		}
+		return node
+	}
+
+	return build(root), nil
+}
+
+// GetDependencyGraph retrieves a task the caller can see along with every task it
+// transitively depends on, in topological order
+func (u *taskUsecase) GetDependencyGraph(ctx context.Context, identity domain.Identity, id string) (domain.TaskGraph, error) {
+	root, access, err := u.viewTask(ctx, identity, id)
+	if err != nil {
+		return domain.TaskGraph{}, err
+	}
+
+	tasks := []domain.Task{root}
+	visited := map[string]bool{root.ID: true}
+	err = u.walkDependencies(ctx, root.DependsOn, visited, func(task domain.Task) bool {
+		if !access.CanView(task) {
+			return false
+		}
+
+		tasks = append(tasks, task)
+		return true
+	})
+	if err != nil {
+		return domain.TaskGraph{}, err
+	}
+
+	graph, err := domain.NewTaskGraph(tasks)
+	if err != nil {
+		return domain.TaskGraph{}, &domain.InternalServerError{Message: "Task dependencies form a cycle"}
+	}
+
+	return graph, nil
+}
+
+// validateRelations checks the parent and dependencies of a task being created (with an
+// empty id) or updated: they must exist and be visible to the caller, must not lead back
+// to the task itself, and the task cannot become completed while a dependency is pending
+func (u *taskUsecase) validateRelations(ctx context.Context, access domain.TaskAccess, id string, task *domain.Task, previousStatus string) error {
+	if task.ParentID != "" {
+		if task.ParentID == id {
+			return &domain.BadRequestError{Message: "A task cannot be its own subtask"}
+		}
+
+		if err := u.checkAncestors(ctx, access, id, task.ParentID); err != nil {
+			return err
+		}
+	}
+
+	// drop repeated dependencies, keeping the order they were given in
+	seen := make(map[string]bool)
+	var dependsOn []string
+	for _, dependency := range task.DependsOn {
+		if dependency == id {
+			return &domain.BadRequestError{Message: "A task cannot depend on itself"}
+		}
+
+		if !seen[dependency] {
+			seen[dependency] = true
+			dependsOn = append(dependsOn, dependency)
+		}
+	}
+	task.DependsOn = dependsOn
+
+	if len(dependsOn) == 0 {
+		return nil
+	}
+
+	dependencies, err := u.taskRepo.GetTasksByIDs(ctx, dependsOn)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool)
+	pending := []string{}
+	for _, dependency := range dependencies {
+		if !access.CanView(dependency) {
+			continue
+		}
+
+		found[dependency.ID] = true
+		if !u.workflow.IsCompleted(dependency.Status) {
+			pending = append(pending, dependency.ID)
+		}
+	}
+
+	for _, dependency := range dependsOn {
+		if !found[dependency] {
+			return &domain.BadRequestError{Message: fmt.Sprintf("Dependency %s not found", dependency)}
+		}
+	}
+
+	if id != "" {
+		// the task's own dependents are not known yet when it is being created, so only an update can close a loop
+		cycle := false
+		err := u.walkDependencies(ctx, dependsOn, map[string]bool{}, func(dependency domain.Task) bool {
+			cycle = cycle || dependency.ID == id
+			return !cycle
+		})
+		if err != nil {
+			return err
+		}
+
+		if cycle {
+			return &domain.BadRequestError{Message: "Dependencies would create a cycle"}
+		}
+	}
+
+	if u.workflow.IsCompleted(task.Status) && !u.workflow.IsCompleted(previousStatus) && len(pending) > 0 {
+		return &domain.BadRequestError{Message: "Task is blocked by pending dependencies: " + strings.Join(pending, ", ")}
+	}
+
+	return nil
+}
+
+// checkAncestors checks that the parent is visible to the caller and that the task is
+// not among the parent's ancestors
+func (u *taskUsecase) checkAncestors(ctx context.Context, access domain.TaskAccess, id, parentID string) error {
+	parent, err := u.taskRepo.GetTask(ctx, parentID)
+	if _, ok := err.(*domain.NotFoundError); ok || (err == nil && !access.CanView(parent)) {
+		return &domain.BadRequestError{Message: "Parent task not found"}
+	}
+	if err != nil {
+		return err
+	}
+
+	visited := map[string]bool{parent.ID: true}
+	for ancestor := parent.ParentID; ancestor != "" && !visited[ancestor]; {
+		if ancestor == id {
+			return &domain.BadRequestError{Message: "A task cannot be a subtask of its own subtask"}
+		}
+		visited[ancestor] = true
+
+		task, err := u.taskRepo.GetTask(ctx, ancestor)
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ancestor = task.ParentID
+	}
+
+	return nil
+}
+
+// walkDependencies visits every task reachable through the dependencies, one level at a
+// time. Tasks already visited are skipped, and visit returns false to stop following a task's dependencies.
+func (u *taskUsecase) walkDependencies(ctx context.Context, dependsOn []string, visited map[string]bool, visit func(task domain.Task) bool) error {
+	for level := dependsOn; len(level) > 0; {
+		ids := []string{}
+		for _, id := range level {
+			if !visited[id] {
+				visited[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		tasks, err := u.taskRepo.GetTasksByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		level = nil
+		for _, task := range tasks {
+			if visit(task) {
+				level = append(level, task.DependsOn...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// prepareCreate makes a new task the caller's own, with the fields kept by the server
+// reset and its tags normalized, and checks its status, project and relations. A task
+// can be created in any state of the workflow. A task without a project goes to the
+// caller's personal project; when they have none yet the project is left empty, and
+// inPersonalProject creates it just before the task is stored, so checking a task
+// without storing it has no side effects.
+func (u *taskUsecase) prepareCreate(ctx context.Context, access *domain.TaskAccess, task *domain.Task) error {
+	if err := u.checkStatus(task.Status); err != nil {
+		return err
+	}
+
+	if err := normalizeTaskTags(task); err != nil {
+		return err
+	}
+
+	if task.ProjectID == "" {
+		task.ProjectID = access.PersonalProjectID
+	} else if err := u.checkProject(ctx, *access, task.ProjectID); err != nil {
+		return err
+	}
+
+	task.OwnerID = access.Identity.UserID
+	task.NextID = ""
+	task.Attachments = nil
+	task.Occurrence = 0
+	if task.Recurrence != "" {
+		task.Occurrence = 1
+	}
+
+	return u.validateRelations(ctx, *access, "", task, "")
+}
+
+// prepareUpdate normalizes the tags of an update to the existing task, checks that the
+// caller may move it to its new status and project and checks its relations, and
+// carries over the fields kept by the server
+func (u *taskUsecase) prepareUpdate(ctx context.Context, access *domain.TaskAccess, existing domain.Task, task *domain.Task) error {
+	if task.Status != existing.Status {
+		if err := u.checkStatus(task.Status); err != nil {
+			return err
+		}
+
+		if err := u.workflow.CheckTransition(access.Identity, existing.Status, task.Status); err != nil {
+			return err
+		}
+	}
+
+	if task.ProjectID == "" {
+		task.ProjectID = existing.ProjectID
+	} else if task.ProjectID != existing.ProjectID {
+		if err := u.checkProject(ctx, *access, task.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	if err := normalizeTaskTags(task); err != nil {
+		return err
+	}
+
+	if err := u.validateRelations(ctx, *access, existing.ID, task, existing.Status); err != nil {
+		return err
+	}
+
+	// the position in a series, the next instance and the attachments are kept by the server
+	task.Occurrence, task.NextID = existing.Occurrence, existing.NextID
+	task.Attachments = existing.Attachments
+	if task.Recurrence == "" {
+		task.Occurrence = 0
+	} else if task.Occurrence == 0 {
+		task.Occurrence = 1
+	}
+
+	return nil
+}
+
+// normalizeTaskTags puts the task's tags in their canonical form
+func normalizeTaskTags(task *domain.Task) error {
+	tags, err := domain.NormalizeTags(task.Tags)
+	if err != nil {
 		return &domain.BadRequestError{Message: err.Error()}
 	}
 
-	return u.taskRepo.UpdateTask(id, task)
+	task.Tags = tags
+	return nil
 }
 
-// DeleteTask deletes a task
-func (u *taskUsecase) DeleteTask(id string) error {
-	return u.taskRepo.DeleteTask(id)
+// checkProject checks that the project exists and that the caller may add tasks to it
+func (u *taskUsecase) checkProject(ctx context.Context, access domain.TaskAccess, projectID string) error {
+	role := access.ProjectRole(projectID)
+	if access.Identity.CanManageAll() {
+		// admins have a role in every project, including ones that do not exist
+		_, err := u.projectRepo.GetProject(ctx, projectID)
+		switch err.(type) {
+		case nil:
+		case *domain.NotFoundError, *domain.BadRequestError:
+			role = ""
+		default:
+			return err
+		}
+	}
+
+	if role == "" {
+		return &domain.BadRequestError{Message: "Project not found"}
+	}
+
+	if !domain.CanEditTasks(role) {
+		return &domain.ForbiddenError{Message: "Viewers cannot add tasks to a project"}
+	}
+
+	return nil
+}
+
+// inPersonalProject puts the tasks prepareCreate left without a project into the
+// caller's personal project, creating the project the first time it is needed
+func (u *taskUsecase) inPersonalProject(ctx context.Context, access *domain.TaskAccess, tasks ...*domain.Task) error {
+	for _, task := range tasks {
+		if task.ProjectID != "" {
+			continue
+		}
+
+		if access.PersonalProjectID == "" {
+			personal, err := ensurePersonalProject(ctx, u.projectRepo, access.Identity)
+			if err != nil {
+				return err
+			}
+
+			access.Roles[personal.ID] = personal.RoleOf(access.Identity.UserID)
+			access.PersonalProjectID = personal.ID
+		}
+		task.ProjectID = access.PersonalProjectID
+	}
+
+	return nil
+}
+
+// checkStatus checks that the status is a state of the workflow
+func (u *taskUsecase) checkStatus(status string) error {
+	if !u.workflow.HasState(status) {
+		return &domain.BadRequestError{Message: "status must be one of " + strings.Join(u.workflow.StateNames(), ", ")}
+	}
+
+	return nil
+}
+
+// nextInstance returns the instance that follows a recurring task in its series when
+// the update completes it for the first time. The instance starts in the workflow's
+// initial state and is owned by the series' owner; there is none once the series has ended.
+func (u *taskUsecase) nextInstance(existing, task domain.Task) (domain.Task, bool) {
+	if task.Recurrence == "" || !u.workflow.IsCompleted(task.Status) || u.workflow.IsCompleted(existing.Status) || task.NextID != "" {
+		return domain.Task{}, false
+	}
+
+	due, position, ok := task.NextOccurrence(time.Now())
+	if !ok {
+		return domain.Task{}, false
+	}
+
+	return domain.Task{
+		Title:      task.Title,
+		DueDate:    due,
+		Status:     u.workflow.Initial,
+		OwnerID:    existing.OwnerID,
+		ProjectID:  task.ProjectID,
+		ParentID:   task.ParentID,
+		Tags:       task.Tags,
+		Recurrence: task.Recurrence,
+		Occurrence: position,
+	}, true
+}
+
+// recordCreate records the first version of a created task, audits it and publishes it
+func (u *taskUsecase) recordCreate(ctx context.Context, identity domain.Identity, created domain.Task) {
+	u.recordVersion(ctx, identity, created)
+	u.audit.record(ctx, identity, domain.AuditTaskCreate, "task", created.ID, nil, taskAuditFields(created))
+	u.events.Publish(ctx, newTaskEvent(identity, domain.EventTaskCreated, created))
+}
+
+// recordUpdate records the new version of an updated task, audits it and publishes it
+func (u *taskUsecase) recordUpdate(ctx context.Context, identity domain.Identity, existing, updated domain.Task) {
+	u.recordVersion(ctx, identity, updated)
+	u.audit.record(ctx, identity, domain.AuditTaskUpdate, "task", existing.ID, taskAuditFields(existing), taskAuditFields(updated))
+	if u.workflow.IsCompleted(updated.Status) && !u.workflow.IsCompleted(existing.Status) {
+		u.events.Publish(ctx, newTaskEvent(identity, domain.EventTaskCompleted, updated))
+	} else {
+		u.events.Publish(ctx, newTaskEvent(identity, domain.EventTaskUpdated, updated))
+	}
+}
+
+// recordDelete drops the history and comments of a deleted task, audits it and publishes it
+func (u *taskUsecase) recordDelete(ctx context.Context, identity domain.Identity, existing domain.Task) {
+	if err := u.historyRepo.DeleteHistory(ctx, existing.ID); err != nil {
+		log.Printf("task history: failed to delete history of task %s: %v", existing.ID, err)
+	}
+
+	if err := u.commentRepo.DeleteComments(ctx, existing.ID); err != nil {
+		log.Printf("comments: failed to delete comments of task %s: %v", existing.ID, err)
+	}
+
+	u.audit.record(ctx, identity, domain.AuditTaskDelete, "task", existing.ID, taskAuditFields(existing), nil)
+	u.events.Publish(ctx, newTaskEvent(identity, domain.EventTaskDeleted, existing))
+}
+
+// recordVersion stores a snapshot of the task as it is after a change
+func (u *taskUsecase) recordVersion(ctx context.Context, identity domain.Identity, task domain.Task) {
+	recordTaskVersion(ctx, u.historyRepo, identity, task)
+}
+
+// recordTaskVersion stores a snapshot of the task as it is after a change. Like audit
+// entries, a failure to record it is logged rather than reported to the caller.
+func recordTaskVersion(ctx context.Context, historyRepo repositories.TaskHistoryRepository, identity domain.Identity, task domain.Task) {
+	version := domain.TaskVersion{
+		TaskID:     task.ID,
+		Version:    task.Version,
+		Title:      task.Title,
+		DueDate:    task.DueDate,
+		Status:     task.Status,
+		OwnerID:    task.OwnerID,
+		ProjectID:  task.ProjectID,
+		ParentID:   task.ParentID,
+		DependsOn:  task.DependsOn,
+		Tags:       task.Tags,
+		Recurrence: task.Recurrence,
+		ModifiedBy: identity.Username,
+		ModifiedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if err := historyRepo.AddVersion(writeCtx, version); err != nil {
+		log.Printf("task history: failed to record version %d of task %s: %v", task.Version, task.ID, err)
+	}
+}
+
+// access looks up what the caller may do with tasks through their projects
+func (u *taskUsecase) access(ctx context.Context, identity domain.Identity) (domain.TaskAccess, error) {
+	return loadTaskAccess(ctx, u.projectRepo, identity)
+}
+
+// viewTask returns the task if it exists and the caller can see it, along with their access
+func (u *taskUsecase) viewTask(ctx context.Context, identity domain.Identity, id string) (domain.Task, domain.TaskAccess, error) {
+	access, err := u.access(ctx, identity)
+	if err != nil {
+		return domain.Task{}, domain.TaskAccess{}, err
+	}
+
+	task, err := u.taskRepo.GetTask(ctx, id)
+	if err != nil {
+		return domain.Task{}, domain.TaskAccess{}, err
+	}
+
+	if !access.CanView(task) {
+		return domain.Task{}, domain.TaskAccess{}, &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	return task, access, nil
+}
+
+// authorizeModification returns the task if it exists and the caller may modify it
+func (u *taskUsecase) authorizeModification(ctx context.Context, access domain.TaskAccess, id string) (domain.Task, error) {
+	existing, err := u.taskRepo.GetTask(ctx, id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	return existing, checkModification(access, existing)
+}
+
+// checkModification checks that the caller may modify the task; tasks they cannot see
+// are reported as not found, as viewTask does, so their IDs are not given away
+func checkModification(access domain.TaskAccess, task domain.Task) error {
+	if !access.CanView(task) {
+		return &domain.NotFoundError{Message: "Task not found"}
+	}
+
+	if !access.CanEdit(task) {
+		return &domain.ForbiddenError{Message: "Viewers cannot modify a project's tasks"}
+	}
+
+	return nil
 }
@@ -1,13 +1,18 @@
 package usecases
 
 import (
+	"context"
 	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 
@@ -15,40 +20,141 @@ type MockTaskRepository struct {
 	mock.Mock
 }
 
-func (m *MockTaskRepository) CreateTask(task domain.Task) error {
-	args := m.Called(task)
-	return args.Error(0)
+func (m *MockTaskRepository) CreateTask(ctx context.Context, task domain.Task) (domain.Task, error) {
+	args := m.Called(ctx, task)
+	return args.Get(0).(domain.Task), args.Error(1)
 }
 
-func (m *MockTaskRepository) GetTask(id string) (domain.Task, error) {
-	args := m.Called(id)
+func (m *MockTaskRepository) GetTask(ctx context.Context, id string) (domain.Task, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(domain.Task), args.Error(1)
 }
 
-func (m *MockTaskRepository) GetTasks() ([]domain.Task, error) {
-	args := m.Called()
-	return args.Get(0).([]domain.Task), args.Error(1)
+func (m *MockTaskRepository) GetTasks(ctx context.Context, query domain.TaskQuery) (domain.TaskPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(domain.TaskPage), args.Error(1)
 }
 
-func (m *MockTaskRepository) UpdateTask(id string, task domain.Task) error {
-	args := m.Called(id, task)
-	return args.Error(0)
+func (m *MockTaskRepository) UpdateTask(ctx context.Context, id string, version int64, task domain.Task) (domain.Task, error) {
+	args := m.Called(ctx, id, version, task)
+	return args.Get(0).(domain.Task), args.Error(1)
 }
 
-func (m *MockTaskRepository) DeleteTask(id string) error {
-	args := m.Called(id)
+func (m *MockTaskRepository) DeleteTask(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockTaskRepository) GetTasksByIDs(ctx context.Context, ids []string) ([]domain.Task, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) GetSubtasks(ctx context.Context, parentIDs []string) ([]domain.Task, error) {
+	args := m.Called(ctx, parentIDs)
+	return args.Get(0).([]domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) SearchTasks(ctx context.Context, query domain.TaskSearchQuery) ([]domain.TaskSearchResult, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]domain.TaskSearchResult), args.Error(1)
+}
+
+func (m *MockTaskRepository) GetTasksByTitles(ctx context.Context, ownerID string, titles []string) ([]domain.Task, error) {
+	args := m.Called(ctx, ownerID, titles)
+	return args.Get(0).([]domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) GetTagCounts(ctx context.Context, ownerID string) (map[string]int, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockTaskRepository) ReplaceTags(ctx context.Context, ownerID string, from []string, to string) ([]domain.Task, error) {
+	args := m.Called(ctx, ownerID, from, to)
+	return args.Get(0).([]domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) GetUnassignedTaskOwners(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTaskRepository) AssignProject(ctx context.Context, ownerID, projectID string) (int64, error) {
+	args := m.Called(ctx, ownerID, projectID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaskRepository) AddAttachment(ctx context.Context, taskID string, attachment domain.Attachment) (domain.Task, error) {
+	args := m.Called(ctx, taskID, attachment)
+	return args.Get(0).(domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) RemoveAttachment(ctx context.Context, taskID, attachmentID string) (domain.Task, error) {
+	args := m.Called(ctx, taskID, attachmentID)
+	return args.Get(0).(domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) WriteTasks(ctx context.Context, writes []domain.TaskWrite, atomic bool) ([]domain.TaskWriteResult, error) {
+	args := m.Called(ctx, writes, atomic)
+	results, _ := args.Get(0).([]domain.TaskWriteResult)
+	return results, args.Error(1)
+}
+
+// recordingPublisher keeps the task events it is given
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []domain.TaskEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event domain.TaskEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+// types lists the published event types, oldest first
+func (p *recordingPublisher) types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	types := []string{}
+	for _, event := range p.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+var (
+	owner     = identityWithRoles("owner-id", "owner", domain.UserRole)
+	otherUser = identityWithRoles("other-id", "other", domain.UserRole)
+	admin     = identityWithRoles("admin-id", "admin", domain.AdminRole)
+)
+
+// identityWithRoles builds the identity of a user holding built-in roles
+func identityWithRoles(userID, username string, names ...string) domain.Identity {
+	roles := []domain.Role{}
+	for _, name := range names {
+		role, _ := domain.BuiltInRole(name)
+		roles = append(roles, role)
+	}
+
+	return domain.NewIdentity(userID, username, domain.GrantsOf(roles))
+}
+
 type TaskUsecaseTestSuite struct {
 	suite.Suite
-	taskRepo *MockTaskRepository
-	usecase  TaskUsecase
+	taskRepo    *MockTaskRepository
+	historyRepo repositories.TaskHistoryRepository
+	commentRepo repositories.CommentRepository
+	projectRepo repositories.ProjectRepository
+	auditRepo   repositories.AuditRepository
+	events      *recordingPublisher
+	usecase     TaskUsecase
 }
 
 func (suite *TaskUsecaseTestSuite) SetupSuite() {
 	suite.taskRepo = new(MockTaskRepository)
-	suite.usecase = NewTaskUsecase(suite.taskRepo)
 }
 
 func (suite *TaskUsecaseTestSuite) TearDownSuite() {
@@ -58,6 +164,31 @@ func (suite *TaskUsecaseTestSuite) TearDownSuite() {
 
 func (suite *TaskUsecaseTestSuite) SetupTest() {
 	suite.taskRepo.ExpectedCalls = nil
+	suite.historyRepo = repositories.NewTaskHistoryMemoryRepository()
+	suite.commentRepo = repositories.NewCommentMemoryRepository()
+	suite.projectRepo = repositories.NewProjectMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.events = &recordingPublisher{}
+	suite.usecase = NewTaskUsecase(suite.taskRepo, suite.historyRepo, suite.commentRepo, suite.projectRepo, suite.auditRepo, suite.events, domain.DefaultWorkflow())
+}
+
+// personalProject returns the ID of the identity's personal project, creating it if needed
+func (suite *TaskUsecaseTestSuite) personalProject(identity domain.Identity) string {
+	project, err := ensurePersonalProject(context.Background(), suite.projectRepo, identity)
+	suite.Require().NoError(err)
+	return project.ID
+}
+
+// ownerVisibility is what owner can see while they are not a member of any project
+func ownerVisibility() *domain.TaskVisibility {
+	return &domain.TaskVisibility{UserID: owner.UserID, ProjectIDs: []string{}}
+}
+
+// auditEntries returns the audit log recorded by the current test, oldest first
+func (suite *TaskUsecaseTestSuite) auditEntries() []domain.AuditEntry {
+	entries, err := suite.auditRepo.GetChain(context.Background())
+	suite.Require().NoError(err)
+	return entries
 }
 
 func (suite *TaskUsecaseTestSuite) TearDownTest() {
@@ -75,11 +206,58 @@ func (suite *TaskUsecaseTestSuite) TestCreateTask() {
 		Status:  "pending",
 	}
 
-	suite.taskRepo.On("GetTasks").Return([]domain.Task{}, nil)
+	owned := task
+	owned.OwnerID = owner.UserID
+	owned.ProjectID = suite.personalProject(owner)
+
+	suite.taskRepo.On("GetTasks", mock.Anything, domain.TaskQuery{OwnerID: owner.UserID, TitlePrefix: "Test Task", SortBy: "title", Limit: 1}).Return(domain.TaskPage{Tasks: []domain.Task{}}, nil)
 
-	suite.taskRepo.On("CreateTask", task).Return(nil)
+	created := owned
+	created.ID = "1"
+	created.Version = 1
+	suite.taskRepo.On("CreateTask", mock.Anything, owned).Return(created, nil)
+
+	result, err := suite.usecase.CreateTask(context.Background(), owner, task)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created, result)
 
-	err := suite.usecase.CreateTask(task)
+	history, err := suite.historyRepo.GetHistory(context.Background(), "1")
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(history, 1)
+	assert.Equal(suite.T(), int64(1), history[0].Version)
+	assert.Equal(suite.T(), "Test Task", history[0].Title)
+
+	entries := suite.auditEntries()
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), domain.AuditTaskCreate, entries[0].Action)
+	assert.Equal(suite.T(), "owner", entries[0].Actor)
+	assert.Equal(suite.T(), "owner-id", entries[0].ActorID)
+	assert.Equal(suite.T(), "task", entries[0].TargetType)
+	assert.Equal(suite.T(), "1", entries[0].TargetID)
+	assert.Contains(suite.T(), entries[0].Changes, domain.AuditChange{Field: "title", After: "Test Task"})
+
+	suite.Require().Len(suite.events.events, 1)
+	event := suite.events.events[0]
+	assert.Equal(suite.T(), domain.EventTaskCreated, event.Type)
+	assert.NotEmpty(suite.T(), event.ID)
+	assert.Equal(suite.T(), "owner", event.Actor)
+	assert.Equal(suite.T(), created, event.Task)
+}
+
+func (suite *TaskUsecaseTestSuite) TestCreateTask_IgnoresClientOwner() {
+	task := domain.Task{
+		Title:   "Test Task",
+		DueDate: time.Now().Add(24 * time.Hour),
+		Status:  "pending",
+		OwnerID: otherUser.UserID,
+	}
+
+	suite.taskRepo.On("GetTasks", mock.Anything, mock.Anything).Return(domain.TaskPage{Tasks: []domain.Task{}}, nil)
+	suite.taskRepo.On("CreateTask", mock.Anything, mock.MatchedBy(func(task domain.Task) bool {
+		return task.OwnerID == owner.UserID
+	})).Return(domain.Task{ID: "1", OwnerID: owner.UserID}, nil)
+
+	_, err := suite.usecase.CreateTask(context.Background(), owner, task)
 	assert.NoError(suite.T(), err)
 }
 
@@ -98,9 +276,9 @@ func (suite *TaskUsecaseTestSuite) TestCreateTaskWithExistingTitle() {
 		},
 	}
 
-	suite.taskRepo.On("GetTasks").Return(tasks, nil)
+	suite.taskRepo.On("GetTasks", mock.Anything, mock.Anything).Return(domain.TaskPage{Tasks: tasks}, nil)
 
-	err := suite.usecase.CreateTask(task)
+	_, err := suite.usecase.CreateTask(context.Background(), owner, task)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "Task already exists", err.Error())
 }
@@ -112,7 +290,7 @@ func (suite *TaskUsecaseTestSuite) TestCreateTaskWithInvalidTask() {
 		Status:  "pending",
 	}
 
-	err := suite.usecase.CreateTask(task)
+	_, err := suite.usecase.CreateTask(context.Background(), owner, task)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "title is required", err.Error())
 }
@@ -123,13 +301,26 @@ func (suite *TaskUsecaseTestSuite) TestGetTask() {
 		Title:   "Test Task",
 		DueDate: time.Now().Add(24 * time.Hour),
 		Status:  "pending",
+		OwnerID: owner.UserID,
 	}
 
-	suite.taskRepo.On("GetTask", "1").Return(task, nil)
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(task, nil)
 
-	result, err := suite.usecase.GetTask("1")
+	result, err := suite.usecase.GetTask(context.Background(), owner, "1")
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), task, result)
+
+	result, err = suite.usecase.GetTask(context.Background(), admin, "1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), task, result)
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetTask_OtherUsersTask() {
+	task := domain.Task{ID: "1", Title: "Test Task", Status: "pending", OwnerID: owner.UserID}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(task, nil)
+
+	_, err := suite.usecase.GetTask(context.Background(), otherUser, "1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
 }
 
 func (suite *TaskUsecaseTestSuite) TestGetTasks() {
@@ -148,11 +339,35 @@ func (suite *TaskUsecaseTestSuite) TestGetTasks() {
 		},
 	}
 
-	suite.taskRepo.On("GetTasks").Return(tasks, nil)
+	query := domain.TaskQuery{Status: "pending", Visibility: ownerVisibility(), SortBy: "due_date", Limit: domain.DefaultTaskPageSize}
+	suite.taskRepo.On("GetTasks", mock.Anything, query).Return(domain.TaskPage{Tasks: tasks, NextCursor: "next"}, nil)
 
-	result, err := suite.usecase.GetTasks()
+	result, err := suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{Status: "pending"})
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), tasks, result)
+	assert.Equal(suite.T(), tasks, result.Tasks)
+	assert.Equal(suite.T(), "next", result.NextCursor)
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetTasks_OwnerFilter() {
+	// a regular user can filter by owner, but only among the tasks they can see
+	userQuery := domain.TaskQuery{OwnerID: otherUser.UserID, Visibility: ownerVisibility(), SortBy: "due_date", Limit: domain.DefaultTaskPageSize}
+	suite.taskRepo.On("GetTasks", mock.Anything, userQuery).Return(domain.TaskPage{Tasks: []domain.Task{}}, nil)
+
+	_, err := suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{OwnerID: otherUser.UserID})
+	assert.NoError(suite.T(), err)
+
+	// admins see every task
+	adminQuery := domain.TaskQuery{SortBy: "due_date", Limit: domain.DefaultTaskPageSize}
+	suite.taskRepo.On("GetTasks", mock.Anything, adminQuery).Return(domain.TaskPage{Tasks: []domain.Task{}}, nil)
+
+	_, err = suite.usecase.GetTasks(context.Background(), admin, domain.TaskQuery{})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetTasks_InvalidQuery() {
+	_, err := suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{SortBy: "priority"})
+	assert.Error(suite.T(), err)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
 }
 
 func (suite *TaskUsecaseTestSuite) TestUpdateTask() {
@@ -163,10 +378,99 @@ func (suite *TaskUsecaseTestSuite) TestUpdateTask() {
 		Status:  "pending",
 	}
 
-	suite.taskRepo.On("UpdateTask", "1", task).Return(nil)
+	existing := domain.Task{ID: "1", Title: "Test Task", DueDate: task.DueDate, Status: "completed", OwnerID: owner.UserID, Version: 1}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(existing, nil)
+	suite.taskRepo.On("UpdateTask", mock.Anything, "1", int64(1), task).Return(domain.Task{ID: "1", Title: "Test Task", DueDate: task.DueDate, Status: "pending", OwnerID: owner.UserID, Version: 2}, nil)
+	suite.taskRepo.On("UpdateTask", mock.Anything, "1", int64(2), task).Return(domain.Task{ID: "1", Title: "Test Task", DueDate: task.DueDate, Status: "pending", OwnerID: owner.UserID, Version: 3}, nil)
 
-	err := suite.usecase.UpdateTask("1", task)
+	updated, err := suite.usecase.UpdateTask(context.Background(), owner, "1", 1, task)
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), updated.Version)
+
+	_, err = suite.usecase.UpdateTask(context.Background(), admin, "1", 2, task)
+	assert.NoError(suite.T(), err)
+
+	history, err := suite.historyRepo.GetHistory(context.Background(), "1")
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(history, 2)
+	assert.Equal(suite.T(), int64(2), history[0].Version)
+	assert.Equal(suite.T(), "owner", history[0].ModifiedBy)
+	assert.Equal(suite.T(), "pending", history[0].Status)
+	assert.Equal(suite.T(), "admin", history[1].ModifiedBy)
+
+	entries := suite.auditEntries()
+	suite.Require().Len(entries, 2)
+	assert.Equal(suite.T(), domain.AuditTaskUpdate, entries[0].Action)
+	assert.Equal(suite.T(), []domain.AuditChange{{Field: "status", Before: "completed", After: "pending"}}, entries[0].Changes)
+	assert.Equal(suite.T(), "admin", entries[1].Actor)
+	assert.Equal(suite.T(), entries[0].Hash, entries[1].PrevHash)
+
+	assert.Equal(suite.T(), []string{domain.EventTaskUpdated, domain.EventTaskUpdated}, suite.events.types())
+}
+
+func (suite *TaskUsecaseTestSuite) TestUpdateTask_CompletingPublishesCompleted() {
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(-time.Hour), Status: "completed"}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", Status: "pending", OwnerID: owner.UserID, Version: 1}, nil)
+	suite.taskRepo.On("UpdateTask", mock.Anything, "1", int64(1), task).Return(domain.Task{ID: "1", Status: "completed", OwnerID: owner.UserID, Version: 2}, nil)
+
+	_, err := suite.usecase.UpdateTask(context.Background(), owner, "1", 1, task)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{domain.EventTaskCompleted}, suite.events.types())
+}
+
+func (suite *TaskUsecaseTestSuite) TestUpdateTask_RecordsRequestID() {
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", Status: "pending", OwnerID: owner.UserID, Version: 1}, nil)
+	suite.taskRepo.On("UpdateTask", mock.Anything, "1", int64(1), task).Return(domain.Task{ID: "1", Status: "pending", OwnerID: owner.UserID, Version: 2}, nil)
+
+	ctx := infrastructure.WithRequestID(context.Background(), "request-1")
+	_, err := suite.usecase.UpdateTask(ctx, owner, "1", 1, task)
+	assert.NoError(suite.T(), err)
+
+	entries := suite.auditEntries()
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), "request-1", entries[0].RequestID)
+}
+
+func (suite *TaskUsecaseTestSuite) TestUpdateTask_FailureIsNotAudited() {
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", Status: "pending", OwnerID: owner.UserID}, nil)
+	suite.taskRepo.On("UpdateTask", mock.Anything, "1", int64(1), task).Return(domain.Task{}, &domain.InternalServerError{Message: "Error updating task"})
+
+	_, err := suite.usecase.UpdateTask(context.Background(), owner, "1", 1, task)
+	assert.Error(suite.T(), err)
+	assert.Empty(suite.T(), suite.auditEntries())
+	assert.Empty(suite.T(), suite.events.events)
+}
+
+func (suite *TaskUsecaseTestSuite) TestUpdateTask_StaleVersion() {
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", Status: "pending", OwnerID: owner.UserID, Version: 3}, nil)
+	suite.taskRepo.On("UpdateTask", mock.Anything, "1", int64(2), task).Return(domain.Task{}, &domain.ConflictError{Message: "Task has been modified since it was read"})
+
+	_, err := suite.usecase.UpdateTask(context.Background(), owner, "1", 2, task)
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+	assert.Empty(suite.T(), suite.auditEntries())
+
+	history, err := suite.historyRepo.GetHistory(context.Background(), "1")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), history)
+}
+
+func (suite *TaskUsecaseTestSuite) TestUpdateTask_OtherUsersTask() {
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", OwnerID: owner.UserID}, nil)
+
+	_, err := suite.usecase.UpdateTask(context.Background(), otherUser, "1", 1, task)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskUsecaseTestSuite) TestUpdateTask_NotFound() {
+	task := domain.Task{Title: "Test Task", DueDate: time.Now().Add(24 * time.Hour), Status: "pending"}
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{}, &domain.NotFoundError{Message: "Task not found"})
+
+	_, err := suite.usecase.UpdateTask(context.Background(), owner, "1", 1, task)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
 }
 
 func (suite *TaskUsecaseTestSuite) TestUpdateTask_InvalidTask(){
@@ -177,14 +481,560 @@ func (suite *TaskUsecaseTestSuite) TestUpdateTask_InvalidTask(){
 		Status:  "pending",
 	}
 
-	err := suite.usecase.UpdateTask("1", task)
+	_, err := suite.usecase.UpdateTask(context.Background(), owner, "1", 1, task)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "title is required", err.Error())
 }
 
 func (suite *TaskUsecaseTestSuite) TestDeleteTask() {
-	suite.taskRepo.On("DeleteTask", "1").Return(nil)
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", OwnerID: owner.UserID}, nil)
+	suite.taskRepo.On("GetSubtasks", mock.Anything, []string{"1"}).Return([]domain.Task{}, nil)
+	suite.taskRepo.On("DeleteTask", mock.Anything, "1").Return(nil)
+	suite.Require().NoError(suite.historyRepo.AddVersion(context.Background(), domain.TaskVersion{TaskID: "1", Version: 1}))
+	_, err := suite.commentRepo.CreateComment(context.Background(), domain.Comment{TaskID: "1", Body: "Done?"})
+	suite.Require().NoError(err)
+
+	err = suite.usecase.DeleteTask(context.Background(), owner, "1")
+	assert.NoError(suite.T(), err)
+
+	history, err := suite.historyRepo.GetHistory(context.Background(), "1")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), history)
+
+	comments, err := suite.commentRepo.GetComments(context.Background(), "1")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), comments)
+
+	entries := suite.auditEntries()
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), domain.AuditTaskDelete, entries[0].Action)
+	assert.Contains(suite.T(), entries[0].Changes, domain.AuditChange{Field: "owner_id", Before: owner.UserID})
+
+	suite.Require().Len(suite.events.events, 1)
+	assert.Equal(suite.T(), domain.EventTaskDeleted, suite.events.events[0].Type)
+	assert.Equal(suite.T(), "1", suite.events.events[0].Task.ID)
+}
+
+func (suite *TaskUsecaseTestSuite) TestDeleteTask_WithSubtasks() {
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", OwnerID: owner.UserID}, nil)
+	suite.taskRepo.On("GetSubtasks", mock.Anything, []string{"1"}).Return([]domain.Task{{ID: "2", ParentID: "1", OwnerID: owner.UserID}}, nil)
+
+	err := suite.usecase.DeleteTask(context.Background(), owner, "1")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+	assert.Empty(suite.T(), suite.auditEntries())
+}
+
+func (suite *TaskUsecaseTestSuite) TestDeleteTask_OtherUsersTask() {
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", OwnerID: owner.UserID}, nil)
+
+	err := suite.usecase.DeleteTask(context.Background(), otherUser, "1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskUsecaseTestSuite) TestDeleteTask_UnownedTaskRequiresAdmin() {
+	// tasks created before ownership was recorded can only be managed by admins
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1"}, nil)
+	suite.taskRepo.On("GetSubtasks", mock.Anything, []string{"1"}).Return([]domain.Task{}, nil)
+	suite.taskRepo.On("DeleteTask", mock.Anything, "1").Return(nil)
+
+	err := suite.usecase.DeleteTask(context.Background(), owner, "1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	err = suite.usecase.DeleteTask(context.Background(), admin, "1")
+	assert.NoError(suite.T(), err)
+}
+func (suite *TaskUsecaseTestSuite) TestGetTaskHistory() {
+	suite.taskRepo.On("GetTask", mock.Anything, "1").Return(domain.Task{ID: "1", OwnerID: owner.UserID, Version: 1}, nil)
+	suite.Require().NoError(suite.historyRepo.AddVersion(context.Background(), domain.TaskVersion{TaskID: "1", Version: 1, Title: "Test Task"}))
+
+	history, err := suite.usecase.GetTaskHistory(context.Background(), owner, "1")
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(history, 1)
+	assert.Equal(suite.T(), "Test Task", history[0].Title)
+
+	_, err = suite.usecase.GetTaskHistory(context.Background(), otherUser, "1")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskUsecaseTestSuite) TestSearchTasks() {
+	results := []domain.TaskSearchResult{{Task: domain.Task{ID: "1", Title: "Sprint review"}, Score: 1, Snippet: "<mark>Sprint</mark> review"}}
+	suite.taskRepo.On("SearchTasks", mock.Anything, domain.TaskSearchQuery{Text: "sprint", Visibility: ownerVisibility(), Limit: domain.DefaultSearchLimit}).Return(results, nil)
+	suite.taskRepo.On("SearchTasks", mock.Anything, domain.TaskSearchQuery{Text: "sprint", Limit: 5}).Return(results, nil)
+
+	// the visibility filter comes from the caller, not the query
+	found, err := suite.usecase.SearchTasks(context.Background(), owner, domain.TaskSearchQuery{Text: "sprint", OwnerID: otherUser.UserID})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), results, found)
+
+	found, err = suite.usecase.SearchTasks(context.Background(), admin, domain.TaskSearchQuery{Text: "sprint", Limit: 5})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), results, found)
+}
+
+func (suite *TaskUsecaseTestSuite) TestSearchTasks_InvalidQuery() {
+	_, err := suite.usecase.SearchTasks(context.Background(), owner, domain.TaskSearchQuery{Text: ""})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.SearchTasks(context.Background(), owner, domain.TaskSearchQuery{Text: `"sprint`})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+// TaskRelationsTestSuite checks subtasks and dependencies against the in-memory repositories
+type TaskRelationsTestSuite struct {
+	suite.Suite
+	usecase TaskUsecase
+}
+
+func (suite *TaskRelationsTestSuite) SetupTest() {
+	suite.usecase = NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), repositories.NewAuditMemoryRepository(), &recordingPublisher{}, domain.DefaultWorkflow())
+}
+
+func TestTaskRelationsTestSuite(t *testing.T) {
+	suite.Run(t, new(TaskRelationsTestSuite))
+}
+
+// create stores a pending task owned by the identity
+func (suite *TaskRelationsTestSuite) create(identity domain.Identity, title, parentID string, dependsOn ...string) domain.Task {
+	task, err := suite.usecase.CreateTask(context.Background(), identity, domain.Task{
+		Title:     title,
+		DueDate:   time.Now().Add(time.Hour),
+		Status:    "pending",
+		ParentID:  parentID,
+		DependsOn: dependsOn,
+	})
+	suite.Require().NoError(err)
+
+	return task
+}
+
+// update replaces the task's parent and dependencies
+func (suite *TaskRelationsTestSuite) update(task domain.Task, parentID string, dependsOn ...string) (domain.Task, error) {
+	task.ParentID = parentID
+	task.DependsOn = dependsOn
+	return suite.usecase.UpdateTask(context.Background(), owner, task.ID, task.Version, task)
+}
+
+// complete marks the task completed
+func (suite *TaskRelationsTestSuite) complete(task domain.Task) (domain.Task, error) {
+	task.Status = "completed"
+	task.DueDate = time.Now().Add(-time.Hour)
+	return suite.usecase.UpdateTask(context.Background(), owner, task.ID, task.Version, task)
+}
+
+func (suite *TaskRelationsTestSuite) TestCreateTask_Relations() {
+	parent := suite.create(owner, "Parent", "")
+	dependency := suite.create(owner, "Dependency", "")
+
+	child := suite.create(owner, "Child", parent.ID, dependency.ID, dependency.ID)
+	assert.Equal(suite.T(), parent.ID, child.ParentID)
+	assert.Equal(suite.T(), []string{dependency.ID}, child.DependsOn)
+}
+
+func (suite *TaskRelationsTestSuite) TestCreateTask_MissingOrHiddenRelations() {
+	theirs := suite.create(otherUser, "Theirs", "")
+
+	tests := []struct {
+		name     string
+		task     domain.Task
+		expected string
+	}{
+		{name: "missing parent", task: domain.Task{ParentID: primitive.NewObjectID().Hex()}, expected: "Parent task not found"},
+		{name: "hidden parent", task: domain.Task{ParentID: theirs.ID}, expected: "Parent task not found"},
+		{name: "hidden dependency", task: domain.Task{DependsOn: []string{theirs.ID}}, expected: "Dependency " + theirs.ID + " not found"},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			tt.task.Title = "Mine"
+			tt.task.DueDate = time.Now().Add(time.Hour)
+			tt.task.Status = "pending"
+
+			_, err := suite.usecase.CreateTask(context.Background(), owner, tt.task)
+			assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+			assert.EqualError(suite.T(), err, tt.expected)
+		})
+	}
+}
+
+func (suite *TaskRelationsTestSuite) TestUpdateTask_RejectsParentCycle() {
+	root := suite.create(owner, "Root", "")
+	child := suite.create(owner, "Child", root.ID)
+	grandchild := suite.create(owner, "Grandchild", child.ID)
+
+	_, err := suite.update(root, root.ID)
+	assert.EqualError(suite.T(), err, "A task cannot be its own subtask")
+
+	_, err = suite.update(root, grandchild.ID)
+	assert.EqualError(suite.T(), err, "A task cannot be a subtask of its own subtask")
+
+	// moving a subtask elsewhere in the tree is fine
+	_, err = suite.update(grandchild, root.ID)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *TaskRelationsTestSuite) TestUpdateTask_RejectsDependencyCycle() {
+	first := suite.create(owner, "First", "")
+	second := suite.create(owner, "Second", "", first.ID)
+	third := suite.create(owner, "Third", "", second.ID)
+
+	_, err := suite.update(first, "", first.ID)
+	assert.EqualError(suite.T(), err, "A task cannot depend on itself")
+
+	_, err = suite.update(first, "", third.ID)
+	assert.EqualError(suite.T(), err, "Dependencies would create a cycle")
+
+	_, err = suite.update(third, "", first.ID, second.ID)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *TaskRelationsTestSuite) TestUpdateTask_BlockedByPendingDependencies() {
+	dependency := suite.create(owner, "Dependency", "")
+	task := suite.create(owner, "Task", "", dependency.ID)
+
+	_, err := suite.complete(task)
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+	assert.EqualError(suite.T(), err, "Task is blocked by pending dependencies: "+dependency.ID)
+
+	_, err = suite.complete(dependency)
+	suite.Require().NoError(err)
 
-	err := suite.usecase.DeleteTask("1")
+	_, err = suite.complete(task)
 	assert.NoError(suite.T(), err)
-}
\ No newline at end of file
+}
+
+func (suite *TaskRelationsTestSuite) TestDeleteTask_WithSubtasks() {
+	parent := suite.create(owner, "Parent", "")
+	child := suite.create(owner, "Child", parent.ID)
+
+	err := suite.usecase.DeleteTask(context.Background(), owner, parent.ID)
+	assert.EqualError(suite.T(), err, "Task has subtasks; delete or move them first")
+
+	suite.Require().NoError(suite.usecase.DeleteTask(context.Background(), owner, child.ID))
+	assert.NoError(suite.T(), suite.usecase.DeleteTask(context.Background(), owner, parent.ID))
+}
+
+func (suite *TaskRelationsTestSuite) TestGetSubtree() {
+	root := suite.create(owner, "Root", "")
+	first := suite.create(owner, "First", root.ID)
+	second := suite.create(owner, "Second", root.ID)
+	nested := suite.create(owner, "Nested", first.ID)
+	hidden := suite.create(admin, "Added by an admin", second.ID)
+	suite.create(admin, "Under the admin's subtask", hidden.ID)
+
+	tree, err := suite.usecase.GetSubtree(context.Background(), owner, root.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), root.ID, tree.Task.ID)
+	suite.Require().Len(tree.Subtasks, 2)
+	assert.Equal(suite.T(), first.ID, tree.Subtasks[0].Task.ID)
+	suite.Require().Len(tree.Subtasks[0].Subtasks, 1)
+	assert.Equal(suite.T(), nested.ID, tree.Subtasks[0].Subtasks[0].Task.ID)
+	assert.Empty(suite.T(), tree.Subtasks[1].Subtasks)
+
+	tree, err = suite.usecase.GetSubtree(context.Background(), admin, second.ID)
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(tree.Subtasks, 1)
+	assert.Len(suite.T(), tree.Subtasks[0].Subtasks, 1)
+
+	_, err = suite.usecase.GetSubtree(context.Background(), otherUser, root.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRelationsTestSuite) TestGetDependencyGraph() {
+	design := suite.create(owner, "Design", "")
+	build := suite.create(owner, "Build", "", design.ID)
+	docs := suite.create(owner, "Docs", "", design.ID)
+	release := suite.create(owner, "Release", "", build.ID, docs.ID)
+	suite.create(owner, "Unrelated", "")
+
+	graph, err := suite.usecase.GetDependencyGraph(context.Background(), owner, release.ID)
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(graph.Tasks, 4)
+
+	position := make(map[string]int)
+	for i, task := range graph.Tasks {
+		position[task.ID] = i
+	}
+	assert.Equal(suite.T(), 0, position[design.ID])
+	assert.Equal(suite.T(), 3, position[release.ID])
+	assert.Len(suite.T(), graph.Dependencies, 4)
+	for _, dependency := range graph.Dependencies {
+		assert.Less(suite.T(), position[dependency.DependsOn], position[dependency.TaskID])
+	}
+}
+
+// TaskRecurrenceTestSuite checks recurring tasks against the in-memory repositories
+type TaskRecurrenceTestSuite struct {
+	suite.Suite
+	taskRepo repositories.TaskRepository
+	events   *recordingPublisher
+	usecase  TaskUsecase
+}
+
+func (suite *TaskRecurrenceTestSuite) SetupTest() {
+	suite.taskRepo = repositories.NewTaskMemoryRepository()
+	suite.events = &recordingPublisher{}
+	suite.usecase = NewTaskUsecase(suite.taskRepo, repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), repositories.NewAuditMemoryRepository(), suite.events, domain.DefaultWorkflow())
+}
+
+func TestTaskRecurrenceTestSuite(t *testing.T) {
+	suite.Run(t, new(TaskRecurrenceTestSuite))
+}
+
+// createChore stores a recurring task due in an hour
+func (suite *TaskRecurrenceTestSuite) createChore(rule string) domain.Task {
+	task, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{
+		Title:      "Water the plants",
+		DueDate:    time.Now().Add(time.Hour).Truncate(time.Second),
+		Status:     "pending",
+		Recurrence: rule,
+		NextID:     primitive.NewObjectID().Hex(),
+	})
+	suite.Require().NoError(err)
+
+	return task
+}
+
+// complete marks the task completed ahead of its due date
+func (suite *TaskRecurrenceTestSuite) complete(task domain.Task) domain.Task {
+	task.Status = "completed"
+	updated, err := suite.usecase.UpdateTask(context.Background(), owner, task.ID, task.Version, task)
+	suite.Require().NoError(err)
+
+	return updated
+}
+
+func (suite *TaskRecurrenceTestSuite) TestCreateTask_StartsSeries() {
+	task := suite.createChore("FREQ=DAILY")
+
+	assert.Equal(suite.T(), 1, task.Occurrence)
+	assert.Empty(suite.T(), task.NextID)
+}
+
+func (suite *TaskRecurrenceTestSuite) TestUpdateTask_CompletingCreatesNextInstance() {
+	task := suite.createChore("FREQ=DAILY;COUNT=2")
+
+	completed := suite.complete(task)
+	suite.Require().NotEmpty(completed.NextID)
+
+	next, err := suite.usecase.GetTask(context.Background(), owner, completed.NextID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), task.Title, next.Title)
+	assert.Equal(suite.T(), "pending", next.Status)
+	assert.Equal(suite.T(), task.OwnerID, next.OwnerID)
+	assert.Equal(suite.T(), "FREQ=DAILY;COUNT=2", next.Recurrence)
+	assert.Equal(suite.T(), 2, next.Occurrence)
+	assert.Equal(suite.T(), task.DueDate.AddDate(0, 0, 1), next.DueDate)
+
+	// the last instance of the series has no successor
+	last := suite.complete(next)
+	assert.Empty(suite.T(), last.NextID)
+
+	assert.Equal(suite.T(), []string{
+		domain.EventTaskCreated,
+		domain.EventTaskCompleted, domain.EventTaskCreated,
+		domain.EventTaskCompleted,
+	}, suite.events.types())
+}
+
+func (suite *TaskRecurrenceTestSuite) TestUpdateTask_CompletingAgainDoesNotRepeat() {
+	completed := suite.complete(suite.createChore("FREQ=WEEKLY"))
+
+	reopened := completed
+	reopened.Status = "pending"
+	reopened, err := suite.usecase.UpdateTask(context.Background(), owner, reopened.ID, reopened.Version, reopened)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), completed.NextID, reopened.NextID)
+
+	again := suite.complete(reopened)
+	assert.Equal(suite.T(), completed.NextID, again.NextID)
+
+	page, err := suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{})
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), page.Tasks, 2)
+}
+
+func (suite *TaskRecurrenceTestSuite) TestUpdateTask_ConflictRemovesNextInstance() {
+	task := suite.createChore("FREQ=DAILY")
+	task.Status = "completed"
+
+	_, err := suite.usecase.UpdateTask(context.Background(), owner, task.ID, task.Version+1, task)
+	assert.IsType(suite.T(), &domain.ConflictError{}, err)
+
+	page, err := suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{})
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), page.Tasks, 1)
+}
+
+func (suite *TaskRecurrenceTestSuite) TestGetOccurrences() {
+	task := suite.createChore("FREQ=DAILY;INTERVAL=2;COUNT=3")
+
+	occurrences, err := suite.usecase.GetOccurrences(context.Background(), owner, task.ID, 0)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []time.Time{task.DueDate, task.DueDate.AddDate(0, 0, 2), task.DueDate.AddDate(0, 0, 4)}, occurrences)
+
+	occurrences, err = suite.usecase.GetOccurrences(context.Background(), owner, task.ID, 1)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), occurrences, 1)
+
+	_, err = suite.usecase.GetOccurrences(context.Background(), owner, task.ID, domain.MaxOccurrencePreview+1)
+	assert.EqualError(suite.T(), err, "count must be between 1 and 100")
+
+	_, err = suite.usecase.GetOccurrences(context.Background(), otherUser, task.ID, 0)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskRecurrenceTestSuite) TestGetOccurrences_NotRecurring() {
+	task, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{Title: "Once", DueDate: time.Now().Add(time.Hour), Status: "pending"})
+	suite.Require().NoError(err)
+
+	_, err = suite.usecase.GetOccurrences(context.Background(), owner, task.ID, 0)
+	assert.EqualError(suite.T(), err, "Task does not recur")
+}
+
+// TaskWorkflowTestSuite moves tasks through the workflow on top of the in-memory repositories
+type TaskWorkflowTestSuite struct {
+	suite.Suite
+	events  *recordingPublisher
+	usecase TaskUsecase
+}
+
+func (suite *TaskWorkflowTestSuite) SetupTest() {
+	suite.events = &recordingPublisher{}
+	suite.usecase = suite.newUsecase(domain.DefaultWorkflow())
+}
+
+func TestTaskWorkflowTestSuite(t *testing.T) {
+	suite.Run(t, new(TaskWorkflowTestSuite))
+}
+
+func (suite *TaskWorkflowTestSuite) newUsecase(workflow domain.Workflow) TaskUsecase {
+	return NewTaskUsecase(repositories.NewTaskMemoryRepository(), repositories.NewTaskHistoryMemoryRepository(), repositories.NewCommentMemoryRepository(), repositories.NewProjectMemoryRepository(), repositories.NewAuditMemoryRepository(), suite.events, workflow)
+}
+
+// create stores a task due tomorrow in the given status
+func (suite *TaskWorkflowTestSuite) create(status string) domain.Task {
+	task, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{Title: "Ship it", DueDate: time.Now().Add(24 * time.Hour), Status: status})
+	suite.Require().NoError(err)
+	return task
+}
+
+// move changes the task's status on behalf of the identity
+func (suite *TaskWorkflowTestSuite) move(identity domain.Identity, task domain.Task, status string) (domain.Task, error) {
+	task.Status = status
+	return suite.usecase.UpdateTask(context.Background(), identity, task.ID, task.Version, task)
+}
+
+func (suite *TaskWorkflowTestSuite) TestCompleteBeforeDueDate() {
+	task := suite.create("pending")
+
+	started, err := suite.move(owner, task, "in_progress")
+	suite.Require().NoError(err)
+	completed, err := suite.move(owner, started, "completed")
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), "completed", completed.Status)
+	assert.Equal(suite.T(), []string{domain.EventTaskCreated, domain.EventTaskUpdated, domain.EventTaskCompleted}, suite.events.types())
+}
+
+func (suite *TaskWorkflowTestSuite) TestCreateTask_UnknownStatus() {
+	_, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{Title: "Ship it", DueDate: time.Now().Add(time.Hour), Status: "archived"})
+	assert.EqualError(suite.T(), err, "status must be one of pending, in_progress, blocked, completed, cancelled")
+}
+
+func (suite *TaskWorkflowTestSuite) TestUpdateTask_TransitionNotAllowed() {
+	task := suite.create("blocked")
+
+	_, err := suite.move(owner, task, "completed")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+	assert.EqualError(suite.T(), err, "A task cannot move from blocked to completed")
+
+	_, err = suite.move(owner, task, "archived")
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func (suite *TaskWorkflowTestSuite) TestUpdateTask_TransitionNeedsRole() {
+	task := suite.create("cancelled")
+
+	_, err := suite.move(owner, task, "pending")
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	reopened, err := suite.move(admin, task, "pending")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "pending", reopened.Status)
+}
+
+func (suite *TaskWorkflowTestSuite) TestUpdateTask_SameStatusNeedsNoTransition() {
+	task := suite.create("cancelled")
+	task.Title = "Shipped elsewhere"
+
+	updated, err := suite.usecase.UpdateTask(context.Background(), owner, task.ID, task.Version, task)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "Shipped elsewhere", updated.Title)
+}
+
+func (suite *TaskWorkflowTestSuite) TestGetNextStates() {
+	pending := suite.create("pending")
+	cancelled, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{Title: "Dropped", DueDate: time.Now().Add(time.Hour), Status: "cancelled"})
+	suite.Require().NoError(err)
+
+	states, err := suite.usecase.GetNextStates(context.Background(), owner, pending.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"in_progress", "blocked", "completed", "cancelled"}, states)
+
+	states, err = suite.usecase.GetNextStates(context.Background(), owner, cancelled.ID)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), states)
+
+	states, err = suite.usecase.GetNextStates(context.Background(), admin, cancelled.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"pending"}, states)
+
+	_, err = suite.usecase.GetNextStates(context.Background(), otherUser, pending.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *TaskWorkflowTestSuite) TestGetTasks_UnknownStatus() {
+	_, err := suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{Status: "archived"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.GetTasks(context.Background(), owner, domain.TaskQuery{Status: "in_progress"})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *TaskWorkflowTestSuite) TestCustomWorkflow() {
+	suite.usecase = suite.newUsecase(domain.Workflow{
+		Initial: "todo",
+		States: []domain.WorkflowState{
+			{Name: "todo", Category: domain.CategoryOpen},
+			{Name: "review", Category: domain.CategoryOpen},
+			{Name: "done", Category: domain.CategoryCompleted},
+		},
+		Transitions: []domain.WorkflowTransition{
+			{From: "todo", To: "review"},
+			{From: "review", To: "done", Roles: []string{domain.AdminRole}},
+			{From: domain.AnyState, To: "todo"},
+		},
+	})
+
+	dependency := suite.create("todo")
+	chore, err := suite.usecase.CreateTask(context.Background(), owner, domain.Task{Title: "Review the logs", DueDate: time.Now().Add(time.Hour), Status: "review", Recurrence: "FREQ=DAILY", DependsOn: []string{dependency.ID}})
+	suite.Require().NoError(err)
+
+	_, err = suite.move(admin, chore, "done")
+	assert.EqualError(suite.T(), err, "Task is blocked by pending dependencies: "+dependency.ID)
+
+	inReview, err := suite.move(owner, dependency, "review")
+	suite.Require().NoError(err)
+	_, err = suite.move(owner, inReview, "done")
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+	_, err = suite.move(admin, inReview, "done")
+	suite.Require().NoError(err)
+
+	done, err := suite.move(admin, chore, "done")
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(done.NextID)
+
+	next, err := suite.usecase.GetTask(context.Background(), owner, done.NextID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "todo", next.Status)
+}
@@ -1,39 +1,62 @@
 package usecases
 
 import (
+	"context"
+	"slices"
+	"time"
+
 	domain "task-manager/Domain"
 	infrastructure "task-manager/Infrastructure"
 	repositories "task-manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserUsecase interface {
-	Register(username, password string) error
-	Login(username, password string) (string, error)
-	PromoteUser(userID string) error
+	Register(ctx context.Context, username, password string) error
+	Login(ctx context.Context, username, password string) (domain.TokenPair, error)
+	// LoginExternal logs in a user an identity provider vouched for, creating them on
+	// their first login
+	LoginExternal(ctx context.Context, account domain.ExternalAccount) (domain.TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string) (domain.TokenPair, error)
+	Logout(ctx context.Context, accessToken domain.AccessToken, refreshToken string) error
+	PromoteUser(ctx context.Context, identity domain.Identity, username string) error
 }
 
 type userUsecase struct {
-	userRepo        repositories.UserRepository
-	passwordService infrastructure.PasswordService
-	jwtService      infrastructure.JWTService
+	userRepo            repositories.UserRepository
+	refreshTokenRepo    repositories.RefreshTokenRepository
+	revokedTokenRepo    repositories.RevokedTokenRepository
+	audit               auditRecorder
+	passwordService     infrastructure.PasswordService
+	jwtService          infrastructure.JWTService
+	refreshTokenService infrastructure.RefreshTokenService
+	refreshTokenExpiry  time.Duration
+	permissions         PermissionResolver
 }
 
-func NewUserUsecase(userRepo repositories.UserRepository, passwordService infrastructure.PasswordService, jwtService infrastructure.JWTService) UserUsecase {
+func NewUserUsecase(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepository, revokedTokenRepo repositories.RevokedTokenRepository, auditRepo repositories.AuditRepository, passwordService infrastructure.PasswordService, jwtService infrastructure.JWTService, refreshTokenService infrastructure.RefreshTokenService, refreshTokenExpiry time.Duration, permissions PermissionResolver) UserUsecase {
 	return &userUsecase{
-		userRepo:        userRepo,
-		passwordService: passwordService,
-		jwtService:      jwtService,
+		userRepo:            userRepo,
+		refreshTokenRepo:    refreshTokenRepo,
+		revokedTokenRepo:    revokedTokenRepo,
+		audit:               auditRecorder{auditRepo},
+		passwordService:     passwordService,
+		jwtService:          jwtService,
+		refreshTokenService: refreshTokenService,
+		refreshTokenExpiry:  refreshTokenExpiry,
+		permissions:         permissions,
 	}
 }
 
-func (u *userUsecase) Register(username, password string) error {
+func (u *userUsecase) Register(ctx context.Context, username, password string) error {
 	if username == "" || password == "" {
 		return &domain.BadRequestError{Message: "username and password are required"}
 	}
 
-	_, err := u.userRepo.FindByUsername(username)
+	_, err := u.userRepo.FindByUsername(ctx, username)
 	if err == nil {
-		return &domain.BadRequestError{Message: "username already exists"}
+		return &domain.AlreadyExistsError{Message: "username already exists"}
 	} else if _, ok := err.(*domain.NotFoundError); !ok {
 		return err
 	}
@@ -46,53 +69,294 @@ func (u *userUsecase) Register(username, password string) error {
 	user := domain.User{
 		Username: username,
 		Password: hashedPassword,
-		Role:     "user",
+		Roles:    []string{domain.UserRole},
 	}
 	// If first user, promote to admin
-	count, err := u.userRepo.CountUsers()
+	count, err := u.userRepo.CountUsers(ctx)
 	if err != nil {
 		return err
 	}
 
 	if count == 0 {
-		user.Role = "admin"
+		user.Roles = []string{domain.AdminRole}
 	}
 
-	return u.userRepo.CreateUser(user)
+	if err := u.userRepo.CreateUser(ctx, user); err != nil {
+		return err
+	}
+
+	// users register themselves, so the new user is also the actor
+	u.audit.record(ctx, domain.Identity{Username: username, Roles: user.Roles}, domain.AuditUserRegister, "user", username, nil, userAuditFields(user))
+	return nil
 }
 
-func (u *userUsecase) Login(username, password string) (string, error) {
-	user, err := u.userRepo.FindByUsername(username)
+func (u *userUsecase) Login(ctx context.Context, username, password string) (domain.TokenPair, error) {
+	user, err := u.userRepo.FindByUsername(ctx, username)
 	if err != nil {
-		if _, ok := err.(*domain.NotFoundError); ok {
-			return "", &domain.BadRequestError{Message: "invalid username or password"}
+		switch err.(type) {
+		case *domain.NotFoundError:
+			return domain.TokenPair{}, &domain.BadRequestError{Message: "invalid username or password"}
+		case *domain.TimeoutError:
+			return domain.TokenPair{}, err
 		}
-		return "", &domain.InternalServerError{Message: "error authenticating user"}
+		return domain.TokenPair{}, &domain.InternalServerError{Message: "error authenticating user"}
 	}
 
 	if err := u.passwordService.ComparePasswords(user.Password, password); err != nil {
-		return "", &domain.BadRequestError{Message: "invalid username or password"}
+		return domain.TokenPair{}, &domain.BadRequestError{Message: "invalid username or password"}
+	}
+
+	// every login starts a new refresh token family
+	return u.issueTokens(ctx, user, primitive.NewObjectID().Hex())
+}
+
+// maxUsernameAttempts bounds how many numbered usernames are tried for a new single
+// sign-on user whose name is taken
+const maxUsernameAttempts = 100
+
+// LoginExternal logs in the user linked to the account, creating them with the first
+// free username if this is their first login. Single sign-on users have no password.
+// When the provider manages roles, the user's roles follow it on every login.
+func (u *userUsecase) LoginExternal(ctx context.Context, account domain.ExternalAccount) (domain.TokenPair, error) {
+	if account.Issuer == "" || account.Subject == "" {
+		return domain.TokenPair{}, &domain.UnauthorizedError{Message: "invalid single sign-on account"}
+	}
+
+	var roles []string
+	if account.Roles != nil {
+		names, err := domain.ValidateUserRoles(account.Roles)
+		if err != nil {
+			return domain.TokenPair{}, &domain.UnauthorizedError{Message: err.Error()}
+		}
+		roles = names
+	}
+
+	user, err := u.userRepo.FindByExternalID(ctx, account.Issuer, account.Subject)
+	if _, ok := err.(*domain.NotFoundError); ok {
+		user, err = u.provisionUser(ctx, account, roles)
+	}
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if roles != nil && !slices.Equal(roles, user.RoleNames()) {
+		before := userAuditFields(user)
+
+		user.Role, user.Roles = "", roles
+		if err := u.userRepo.UpdateUser(ctx, user.ID, user); err != nil {
+			return domain.TokenPair{}, err
+		}
+
+		u.permissions.InvalidateUser(user.ID)
+		// the identity provider changed the roles, on the user's own login
+		u.audit.record(ctx, domain.Identity{UserID: user.ID, Username: user.Username, Roles: roles}, domain.AuditUserRolesUpdate, "user", user.Username, before, userAuditFields(user))
+	}
+
+	return u.issueTokens(ctx, user, primitive.NewObjectID().Hex())
+}
+
+// provisionUser creates the user for an account logging in for the first time. Roles
+// not given by the provider are assigned as on registration.
+func (u *userUsecase) provisionUser(ctx context.Context, account domain.ExternalAccount, roles []string) (domain.User, error) {
+	if roles == nil {
+		count, err := u.userRepo.CountUsers(ctx)
+		if err != nil {
+			return domain.User{}, err
+		}
+
+		roles = []string{domain.UserRole}
+		if count == 0 {
+			roles = []string{domain.AdminRole}
+		}
+	}
+
+	if account.Username == "" {
+		account.Username = "user"
+	}
+
+	for attempt := 1; attempt <= maxUsernameAttempts; attempt++ {
+		username := account.CandidateUsername(attempt)
+		_, err := u.userRepo.FindByUsername(ctx, username)
+		if err == nil {
+			continue
+		} else if _, ok := err.(*domain.NotFoundError); !ok {
+			return domain.User{}, err
+		}
+
+		user := domain.User{
+			Username: username,
+			Roles:    roles,
+			Issuer:   account.Issuer,
+			Subject:  account.Subject,
+		}
+		if err := u.userRepo.CreateUser(ctx, user); err != nil {
+			// another login of the same account created the user first
+			if _, ok := err.(*domain.AlreadyExistsError); ok {
+				return u.userRepo.FindByExternalID(ctx, account.Issuer, account.Subject)
+			}
+			return domain.User{}, err
+		}
+
+		u.audit.record(ctx, domain.Identity{Username: username, Roles: roles}, domain.AuditUserRegister, "user", username, nil, userAuditFields(user))
+		return u.userRepo.FindByExternalID(ctx, account.Issuer, account.Subject)
+	}
+
+	return domain.User{}, &domain.AlreadyExistsError{Message: "no free username for single sign-on user"}
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Each refresh token
+// can be used once; presenting it again revokes every token in its family.
+func (u *userUsecase) RefreshToken(ctx context.Context, refreshToken string) (domain.TokenPair, error) {
+	stored, err := u.refreshTokenRepo.FindByHash(ctx, u.refreshTokenService.HashToken(refreshToken))
+	if err != nil {
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return domain.TokenPair{}, &domain.UnauthorizedError{Message: "invalid refresh token"}
+		}
+		return domain.TokenPair{}, err
+	}
+
+	if stored.Revoked {
+		return domain.TokenPair{}, &domain.UnauthorizedError{Message: "refresh token has been revoked"}
+	}
+
+	if stored.Used {
+		return domain.TokenPair{}, u.revokeFamily(ctx, stored.FamilyID)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return domain.TokenPair{}, &domain.UnauthorizedError{Message: "refresh token has expired"}
 	}
 
-	token, err := u.jwtService.GenerateToken(user.Username, user.Role)
+	if err := u.refreshTokenRepo.MarkUsed(ctx, stored.ID); err != nil {
+		// another request used the token first
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return domain.TokenPair{}, u.revokeFamily(ctx, stored.FamilyID)
+		}
+		return domain.TokenPair{}, err
+	}
+
+	// reload the user so role changes take effect on refresh
+	user, err := u.userRepo.FindByUsername(ctx, stored.Username)
 	if err != nil {
-		return "", &domain.InternalServerError{Message: "error generating token"}
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return domain.TokenPair{}, &domain.UnauthorizedError{Message: "invalid refresh token"}
+		}
+		return domain.TokenPair{}, err
 	}
 
-	return token, nil
+	return u.issueTokens(ctx, user, stored.FamilyID)
 }
 
+// Logout revokes the caller's access token and, if given, the refresh token family it belongs to
+func (u *userUsecase) Logout(ctx context.Context, accessToken domain.AccessToken, refreshToken string) error {
+	if err := u.revokedTokenRepo.Revoke(ctx, accessToken.ID, accessToken.ExpiresAt); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
 
-func (u *userUsecase) PromoteUser(username string) error {
-	user, err := u.userRepo.FindByUsername(username)
+	stored, err := u.refreshTokenRepo.FindByHash(ctx, u.refreshTokenService.HashToken(refreshToken))
 	if err != nil {
+		if _, ok := err.(*domain.NotFoundError); ok {
+			return nil
+		}
 		return err
 	}
 
-	if user.Role == "admin" {
+	if stored.Username != accessToken.Username {
+		return &domain.ForbiddenError{Message: "refresh token belongs to another user"}
+	}
+
+	_, err = u.revokeTokens(ctx, stored.FamilyID)
+	return err
+}
+
+// issueTokens creates an access token and a refresh token in the given family
+func (u *userUsecase) issueTokens(ctx context.Context, user domain.User, familyID string) (domain.TokenPair, error) {
+	accessToken, err := u.jwtService.GenerateToken(user.ID, user.Username)
+	if err != nil {
+		return domain.TokenPair{}, &domain.InternalServerError{Message: "error generating token"}
+	}
+
+	refreshToken, err := u.refreshTokenService.GenerateToken()
+	if err != nil {
+		return domain.TokenPair{}, &domain.InternalServerError{Message: "error generating token"}
+	}
+
+	err = u.refreshTokenRepo.CreateRefreshToken(ctx, domain.RefreshToken{
+		TokenHash:       u.refreshTokenService.HashToken(refreshToken),
+		FamilyID:        familyID,
+		Username:        user.Username,
+		AccessTokenID:   accessToken.ID,
+		AccessExpiresAt: accessToken.ExpiresAt,
+		ExpiresAt:       time.Now().Add(u.refreshTokenExpiry),
+	})
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return domain.TokenPair{
+		AccessToken:  accessToken.Token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(time.Until(accessToken.ExpiresAt).Seconds()),
+	}, nil
+}
+
+// revokeFamily handles a reused refresh token by revoking its whole family
+func (u *userUsecase) revokeFamily(ctx context.Context, familyID string) error {
+	if _, err := u.revokeTokens(ctx, familyID); err != nil {
+		return err
+	}
+
+	return &domain.UnauthorizedError{Message: "refresh token reuse detected, please log in again"}
+}
+
+// revokeTokens revokes a refresh token family along with the access tokens issued with it
+func (u *userUsecase) revokeTokens(ctx context.Context, familyID string) ([]domain.RefreshToken, error) {
+	tokens, err := u.refreshTokenRepo.RevokeFamily(ctx, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		if token.AccessExpiresAt.After(time.Now()) {
+			if err := u.revokedTokenRepo.Revoke(ctx, token.AccessTokenID, token.AccessExpiresAt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// PromoteUser adds the admin role to a user's roles on behalf of a caller who has every
+// permission it grants
+func (u *userUsecase) PromoteUser(ctx context.Context, identity domain.Identity, username string) error {
+	user, err := u.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	roles := user.RoleNames()
+	if slices.Contains(roles, domain.AdminRole) {
 		return &domain.BadRequestError{Message: "user is already an admin"}
 	}
 
-	user.Role = "admin"
-	return u.userRepo.UpdateUser(user.ID, user)
+	if admin, _ := domain.BuiltInRole(domain.AdminRole); !canGrant(identity, admin) {
+		return &domain.ForbiddenError{Message: "You cannot grant permissions you do not have"}
+	}
+
+	before := userAuditFields(user)
+
+	user.Role, user.Roles = "", append(slices.Clone(roles), domain.AdminRole)
+	slices.Sort(user.Roles)
+	if err := u.userRepo.UpdateUser(ctx, user.ID, user); err != nil {
+		return err
+	}
+
+	u.permissions.InvalidateUser(user.ID)
+	u.audit.record(ctx, identity, domain.AuditUserPromote, "user", username, before, userAuditFields(user))
+	return nil
 }
@@ -1,11 +1,13 @@
 package usecases
 
 import (
+	"context"
 	// "errors"
 	"testing"
-	// "time"
+	"time"
 
 	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/assert"
@@ -18,23 +20,38 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) CreateUser(user domain.User) error {
-	args := m.Called(user)
+func (m *MockUserRepository) CreateUser(ctx context.Context, user domain.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) UpdateUser(id string, user domain.User) error {
-	args := m.Called(id, user)
+func (m *MockUserRepository) UpdateUser(ctx context.Context, id string, user domain.User) error {
+	args := m.Called(ctx, id, user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindByUsername(username string) (domain.User, error) {
-	args := m.Called(username)
+func (m *MockUserRepository) FindByUsername(ctx context.Context, username string) (domain.User, error) {
+	args := m.Called(ctx, username)
 	return args.Get(0).(domain.User), args.Error(1)
 }
 
-func (m *MockUserRepository) CountUsers() (int64, error) {
-	args := m.Called()
+func (m *MockUserRepository) FindByID(ctx context.Context, id string) (domain.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByExternalID(ctx context.Context, issuer, subject string) (domain.User, error) {
+	args := m.Called(ctx, issuer, subject)
+	return args.Get(0).(domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) CountUsers(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	args := m.Called(ctx, role)
 	return args.Get(0).(int64), args.Error(1)
 }
 
@@ -56,9 +73,9 @@ type MockJWTService struct {
 	mock.Mock
 }
 
-func (m *MockJWTService) GenerateToken(username, role string) (string, error) {
-	args := m.Called(username, role)
-	return args.String(0), args.Error(1)
+func (m *MockJWTService) GenerateToken(userID, username string) (domain.AccessToken, error) {
+	args := m.Called(userID, username)
+	return args.Get(0).(domain.AccessToken), args.Error(1)
 }
 
 func (m *MockJWTService) ValidateToken(token string) (*jwt.Token, error) {
@@ -66,13 +83,69 @@ func (m *MockJWTService) ValidateToken(token string) (*jwt.Token, error) {
 	return args.Get(0).(*jwt.Token), args.Error(1)
 }
 
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(domain.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) MarkUsed(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) ([]domain.RefreshToken, error) {
+	args := m.Called(ctx, familyID)
+	return args.Get(0).([]domain.RefreshToken), args.Error(1)
+}
+
+type MockRevokedTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRevokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	args := m.Called(ctx, tokenID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockRefreshTokenService struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenService) GenerateToken() (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenService) HashToken(token string) string {
+	args := m.Called(token)
+	return args.String(0)
+}
+
 // UserUsecaseTestSuite defines the test suite for UserUsecase
 type UserUsecaseTestSuite struct {
 	suite.Suite
-	userRepo        *MockUserRepository
-	passwordService *MockPasswordService
-	jwtService      *MockJWTService
-	usecase         UserUsecase
+	userRepo            *MockUserRepository
+	passwordService     *MockPasswordService
+	jwtService          *MockJWTService
+	refreshTokenRepo    *MockRefreshTokenRepository
+	revokedTokenRepo    *MockRevokedTokenRepository
+	refreshTokenService *MockRefreshTokenService
+	auditRepo           repositories.AuditRepository
+	usecase             UserUsecase
 }
 
 // SetupTest runs before the test runs
@@ -80,25 +153,45 @@ func (suite *UserUsecaseTestSuite) SetupSuite() {
 	suite.userRepo = new(MockUserRepository)
 	suite.passwordService = new(MockPasswordService)
 	suite.jwtService = new(MockJWTService)
-	suite.usecase = NewUserUsecase(suite.userRepo, suite.passwordService, suite.jwtService)
+	suite.refreshTokenRepo = new(MockRefreshTokenRepository)
+	suite.revokedTokenRepo = new(MockRevokedTokenRepository)
+	suite.refreshTokenService = new(MockRefreshTokenService)
 }
 
 func (suite *UserUsecaseTestSuite) TearDownSuite() {
 	suite.userRepo.AssertExpectations(suite.T())
 	suite.passwordService.AssertExpectations(suite.T())
 	suite.jwtService.AssertExpectations(suite.T())
+	suite.refreshTokenRepo.AssertExpectations(suite.T())
+	suite.revokedTokenRepo.AssertExpectations(suite.T())
+	suite.refreshTokenService.AssertExpectations(suite.T())
 }
 
 func (suite *UserUsecaseTestSuite) SetupTest() {
 	suite.userRepo.ExpectedCalls = nil
 	suite.passwordService.ExpectedCalls = nil
 	suite.jwtService.ExpectedCalls = nil
+	suite.refreshTokenRepo.ExpectedCalls = nil
+	suite.revokedTokenRepo.ExpectedCalls = nil
+	suite.refreshTokenService.ExpectedCalls = nil
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.usecase = NewUserUsecase(suite.userRepo, suite.refreshTokenRepo, suite.revokedTokenRepo, suite.auditRepo, suite.passwordService, suite.jwtService, suite.refreshTokenService, 24*time.Hour, NewPermissionResolver(suite.userRepo, repositories.NewRoleMemoryRepository(), time.Minute))
+}
+
+// auditEntries returns the audit log recorded by the current test, oldest first
+func (suite *UserUsecaseTestSuite) auditEntries() []domain.AuditEntry {
+	entries, err := suite.auditRepo.GetChain(context.Background())
+	suite.Require().NoError(err)
+	return entries
 }
 
 func (suite *UserUsecaseTestSuite) TearDownTest() {
 	suite.userRepo.AssertExpectations(suite.T())
 	suite.passwordService.AssertExpectations(suite.T())
 	suite.jwtService.AssertExpectations(suite.T())
+	suite.refreshTokenRepo.AssertExpectations(suite.T())
+	suite.revokedTokenRepo.AssertExpectations(suite.T())
+	suite.refreshTokenService.AssertExpectations(suite.T())
 }
 
 func TestUserUsecaseTestSuite(t *testing.T) {
@@ -111,18 +204,26 @@ func (suite *UserUsecaseTestSuite) TestRegister_Success() {
 	password := "password123"
 	hashedPassword := "hashedpassword"
 
-	suite.userRepo.On("FindByUsername", username).Return(domain.User{}, &domain.NotFoundError{})
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, &domain.NotFoundError{})
 	suite.passwordService.On("HashPassword", password).Return(hashedPassword, nil)
-	suite.userRepo.On("CountUsers").Return(int64(0), nil)
-	suite.userRepo.On("CreateUser", mock.AnythingOfType("domain.User")).Return(nil)
+	suite.userRepo.On("CountUsers", mock.Anything).Return(int64(0), nil)
+	suite.userRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("domain.User")).Return(nil)
 
-	err := suite.usecase.Register(username, password)
+	err := suite.usecase.Register(context.Background(), username, password)
 	assert.NoError(suite.T(), err)
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 	suite.passwordService.AssertCalled(suite.T(), "HashPassword", password)
-	suite.userRepo.AssertCalled(suite.T(), "CountUsers")
-	suite.userRepo.AssertCalled(suite.T(), "CreateUser", mock.AnythingOfType("domain.User"))
+	suite.userRepo.AssertCalled(suite.T(), "CountUsers", mock.Anything)
+	suite.userRepo.AssertCalled(suite.T(), "CreateUser", mock.Anything, mock.AnythingOfType("domain.User"))
+
+	entries := suite.auditEntries()
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), domain.AuditUserRegister, entries[0].Action)
+	assert.Equal(suite.T(), username, entries[0].Actor)
+	for _, change := range entries[0].Changes {
+		assert.NotEqual(suite.T(), "password", change.Field)
+	}
 }
 
 // TestRegister_ExistingUser tests the Register method when the username already exists
@@ -130,13 +231,13 @@ func (suite *UserUsecaseTestSuite) TestRegister_ExistingUser() {
 	username := "testuser"
 	password := "password123"
 
-	suite.userRepo.On("FindByUsername", username).Return(domain.User{}, nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, nil)
 
-	err := suite.usecase.Register(username, password)
-	assert.Error(suite.T(), err)
+	err := suite.usecase.Register(context.Background(), username, password)
+	assert.IsType(suite.T(), &domain.AlreadyExistsError{}, err)
 	assert.Equal(suite.T(), "username already exists", err.Error())
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 }
 
 // TestRegister_EmptyUsername tests the Register method with an empty username
@@ -144,7 +245,7 @@ func (suite *UserUsecaseTestSuite) TestRegister_EmptyUsernameAndPassword() {
 	username := ""
 	password := ""
 
-	err := suite.usecase.Register(username, password)
+	err := suite.usecase.Register(context.Background(), username, password)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "username and password are required", err.Error())
 }
@@ -155,29 +256,29 @@ func (suite *UserUsecaseTestSuite) TestRegister_CountError() {
 	password := "password123"
 	hashedPassword := "hashedpassword"
 
-	suite.userRepo.On("FindByUsername", username).Return(domain.User{}, &domain.NotFoundError{})
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, &domain.NotFoundError{})
 	suite.passwordService.On("HashPassword", password).Return(hashedPassword, nil)
-	suite.userRepo.On("CountUsers").Return(int64(0), &domain.InternalServerError{})
+	suite.userRepo.On("CountUsers", mock.Anything).Return(int64(0), &domain.InternalServerError{})
 
-	err := suite.usecase.Register(username, password)
+	err := suite.usecase.Register(context.Background(), username, password)
 	assert.Error(suite.T(), err)
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 	suite.passwordService.AssertCalled(suite.T(), "HashPassword", password)
-	suite.userRepo.AssertCalled(suite.T(), "CountUsers")
+	suite.userRepo.AssertCalled(suite.T(), "CountUsers", mock.Anything)
 }
 
 func (suite *UserUsecaseTestSuite) TestRegister_HashError() {
 	username := "testuser"
 	password := "password123"
 
-	suite.userRepo.On("FindByUsername", username).Return(domain.User{}, &domain.NotFoundError{})
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, &domain.NotFoundError{})
 	suite.passwordService.On("HashPassword", password).Return("", &domain.InternalServerError{})
 
-	err := suite.usecase.Register(username, password)
+	err := suite.usecase.Register(context.Background(), username, password)
 	assert.Error(suite.T(), err)
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 	suite.passwordService.AssertCalled(suite.T(), "HashPassword", password)
 }
 
@@ -186,25 +287,34 @@ func (suite *UserUsecaseTestSuite) TestLogin_Success() {
 	username := "testuser"
 	password := "password123"
 	hashedPassword := "hashedpassword"
-	token := "token"
+	accessToken := domain.AccessToken{Token: "token", ID: "access-id", Username: username, ExpiresAt: time.Now().Add(15 * time.Minute)}
 
 	user := domain.User{
+		ID:       "user-id",
 		Username: username,
 		Password: hashedPassword,
 		Role:     "user",
 	}
 
-	suite.userRepo.On("FindByUsername", username).Return(user, nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(user, nil)
 	suite.passwordService.On("ComparePasswords", hashedPassword, password).Return(nil)
-	suite.jwtService.On("GenerateToken", username, user.Role).Return(token, nil)
-
-	t, err := suite.usecase.Login(username, password)
+	suite.jwtService.On("GenerateToken", user.ID, username).Return(accessToken, nil)
+	suite.refreshTokenService.On("GenerateToken").Return("refresh", nil)
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token domain.RefreshToken) bool {
+		return token.TokenHash == "refresh-hash" && token.Username == username && token.FamilyID != "" &&
+			token.AccessTokenID == "access-id" && token.ExpiresAt.After(time.Now().Add(23*time.Hour))
+	})).Return(nil)
+
+	tokens, err := suite.usecase.Login(context.Background(), username, password)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), token, t)
+	assert.Equal(suite.T(), "token", tokens.AccessToken)
+	assert.Equal(suite.T(), "refresh", tokens.RefreshToken)
+	assert.InDelta(suite.T(), 900, tokens.ExpiresIn, 1)
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 	suite.passwordService.AssertCalled(suite.T(), "ComparePasswords", hashedPassword, password)
-	suite.jwtService.AssertCalled(suite.T(), "GenerateToken", username, user.Role)
+	suite.jwtService.AssertCalled(suite.T(), "GenerateToken", user.ID, username)
 }
 
 // TestLogin_UserNotFound tests the Login method when the user is not found
@@ -212,13 +322,23 @@ func (suite *UserUsecaseTestSuite) TestLogin_UserNotFound() {
 	username := "testuser"
 	password := "password123"
 
-	suite.userRepo.On("FindByUsername", username).Return(domain.User{}, &domain.NotFoundError{})
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, &domain.NotFoundError{})
 
-	_, err := suite.usecase.Login(username, password)
+	_, err := suite.usecase.Login(context.Background(), username, password)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "invalid username or password", err.Error())
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
+}
+
+// TestLogin_Timeout tests the Login method when the repository times out
+func (suite *UserUsecaseTestSuite) TestLogin_Timeout() {
+	username := "testuser"
+
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, &domain.TimeoutError{Message: "Request timed out"})
+
+	_, err := suite.usecase.Login(context.Background(), username, "password123")
+	assert.IsType(suite.T(), &domain.TimeoutError{}, err)
 }
 
 // TestLogin_PasswordMismatch tests the Login method when the password does not match
@@ -228,19 +348,20 @@ func (suite *UserUsecaseTestSuite) TestLogin_PasswordMismatch() {
 	hashedPassword := "hashedpassword"
 
 	user := domain.User{
+		ID:       "user-id",
 		Username: username,
 		Password: hashedPassword,
 		Role:     "user",
 	}
 
-	suite.userRepo.On("FindByUsername", username).Return(user, nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(user, nil)
 	suite.passwordService.On("ComparePasswords", hashedPassword, password).Return(&domain.BadRequestError{})
 
-	_, err := suite.usecase.Login(username, password)
+	_, err := suite.usecase.Login(context.Background(), username, password)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "invalid username or password", err.Error())
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 	suite.passwordService.AssertCalled(suite.T(), "ComparePasswords", hashedPassword, password)
 }
 
@@ -251,21 +372,147 @@ func (suite *UserUsecaseTestSuite) TestLogin_GenerateTokenError() {
 	hashedPassword := "hashedpassword"
 
 	user := domain.User{
+		ID:       "user-id",
 		Username: username,
 		Password: hashedPassword,
 		Role:     "user",
 	}
 
-	suite.userRepo.On("FindByUsername", username).Return(user, nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(user, nil)
 	suite.passwordService.On("ComparePasswords", hashedPassword, password).Return(nil)
-	suite.jwtService.On("GenerateToken", username, user.Role).Return("", &domain.InternalServerError{})
+	suite.jwtService.On("GenerateToken", user.ID, username).Return(domain.AccessToken{}, &domain.InternalServerError{})
 
-	_, err := suite.usecase.Login(username, password)
+	_, err := suite.usecase.Login(context.Background(), username, password)
 	assert.Error(suite.T(), err)
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 	suite.passwordService.AssertCalled(suite.T(), "ComparePasswords", hashedPassword, password)
-	suite.jwtService.AssertCalled(suite.T(), "GenerateToken", username, user.Role)
+	suite.jwtService.AssertCalled(suite.T(), "GenerateToken", user.ID, username)
+}
+
+// TestRefreshToken_Success tests that a valid refresh token is rotated within its family
+func (suite *UserUsecaseTestSuite) TestRefreshToken_Success() {
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", Username: "testuser", ExpiresAt: time.Now().Add(time.Hour)}
+	user := domain.User{ID: "user-id", Username: "testuser", Role: "admin"}
+	accessToken := domain.AccessToken{Token: "new-token", ID: "new-access-id", Username: "testuser", ExpiresAt: time.Now().Add(15 * time.Minute)}
+
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+	suite.refreshTokenRepo.On("MarkUsed", mock.Anything, "refresh-id").Return(nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, "testuser").Return(user, nil)
+	suite.jwtService.On("GenerateToken", "user-id", "testuser").Return(accessToken, nil)
+	suite.refreshTokenService.On("GenerateToken").Return("new-refresh", nil)
+	suite.refreshTokenService.On("HashToken", "new-refresh").Return("new-refresh-hash")
+	suite.refreshTokenRepo.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token domain.RefreshToken) bool {
+		return token.TokenHash == "new-refresh-hash" && token.FamilyID == "family"
+	})).Return(nil)
+
+	tokens, err := suite.usecase.RefreshToken(context.Background(), "refresh")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "new-token", tokens.AccessToken)
+	assert.Equal(suite.T(), "new-refresh", tokens.RefreshToken)
+}
+
+// TestRefreshToken_Unknown tests the RefreshToken method with a token that was never issued
+func (suite *UserUsecaseTestSuite) TestRefreshToken_Unknown() {
+	suite.refreshTokenService.On("HashToken", "unknown").Return("unknown-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "unknown-hash").Return(domain.RefreshToken{}, &domain.NotFoundError{})
+
+	_, err := suite.usecase.RefreshToken(context.Background(), "unknown")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "invalid refresh token", err.Error())
+}
+
+// TestRefreshToken_Expired tests the RefreshToken method with an expired token
+func (suite *UserUsecaseTestSuite) TestRefreshToken_Expired() {
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", Username: "testuser", ExpiresAt: time.Now().Add(-time.Minute)}
+
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+
+	_, err := suite.usecase.RefreshToken(context.Background(), "refresh")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "refresh token has expired", err.Error())
+}
+
+// TestRefreshToken_Revoked tests the RefreshToken method with a token from a revoked family
+func (suite *UserUsecaseTestSuite) TestRefreshToken_Revoked() {
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", Revoked: true, ExpiresAt: time.Now().Add(time.Hour)}
+
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+
+	_, err := suite.usecase.RefreshToken(context.Background(), "refresh")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+}
+
+// TestRefreshToken_Reuse tests that presenting a used token revokes the family and its access tokens
+func (suite *UserUsecaseTestSuite) TestRefreshToken_Reuse() {
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", Used: true, ExpiresAt: time.Now().Add(time.Hour)}
+	accessExpiry := time.Now().Add(10 * time.Minute)
+	family := []domain.RefreshToken{
+		{ID: "refresh-id", AccessTokenID: "old-access", AccessExpiresAt: time.Now().Add(-time.Minute)},
+		{ID: "next-id", AccessTokenID: "current-access", AccessExpiresAt: accessExpiry},
+	}
+
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+	suite.refreshTokenRepo.On("RevokeFamily", mock.Anything, "family").Return(family, nil)
+	suite.revokedTokenRepo.On("Revoke", mock.Anything, "current-access", accessExpiry).Return(nil)
+
+	_, err := suite.usecase.RefreshToken(context.Background(), "refresh")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+	assert.Contains(suite.T(), err.Error(), "reuse detected")
+}
+
+// TestRefreshToken_ConcurrentUse tests that losing the race to use a token is treated as reuse
+func (suite *UserUsecaseTestSuite) TestRefreshToken_ConcurrentUse() {
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+	suite.refreshTokenRepo.On("MarkUsed", mock.Anything, "refresh-id").Return(&domain.NotFoundError{})
+	suite.refreshTokenRepo.On("RevokeFamily", mock.Anything, "family").Return([]domain.RefreshToken{}, nil)
+
+	_, err := suite.usecase.RefreshToken(context.Background(), "refresh")
+	assert.IsType(suite.T(), &domain.UnauthorizedError{}, err)
+}
+
+// TestLogout_Success tests that logout revokes the access token and the refresh token family
+func (suite *UserUsecaseTestSuite) TestLogout_Success() {
+	accessToken := domain.AccessToken{ID: "access-id", Username: "testuser", ExpiresAt: time.Now().Add(time.Minute)}
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", Username: "testuser"}
+
+	suite.revokedTokenRepo.On("Revoke", mock.Anything, "access-id", accessToken.ExpiresAt).Return(nil)
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+	suite.refreshTokenRepo.On("RevokeFamily", mock.Anything, "family").Return([]domain.RefreshToken{}, nil)
+
+	err := suite.usecase.Logout(context.Background(), accessToken, "refresh")
+	assert.NoError(suite.T(), err)
+}
+
+// TestLogout_AccessTokenOnly tests logout without a refresh token
+func (suite *UserUsecaseTestSuite) TestLogout_AccessTokenOnly() {
+	accessToken := domain.AccessToken{ID: "access-id", Username: "testuser", ExpiresAt: time.Now().Add(time.Minute)}
+
+	suite.revokedTokenRepo.On("Revoke", mock.Anything, "access-id", accessToken.ExpiresAt).Return(nil)
+
+	err := suite.usecase.Logout(context.Background(), accessToken, "")
+	assert.NoError(suite.T(), err)
+}
+
+// TestLogout_OtherUsersRefreshToken tests that a user cannot revoke someone else's refresh token
+func (suite *UserUsecaseTestSuite) TestLogout_OtherUsersRefreshToken() {
+	accessToken := domain.AccessToken{ID: "access-id", Username: "testuser", ExpiresAt: time.Now().Add(time.Minute)}
+	stored := domain.RefreshToken{ID: "refresh-id", FamilyID: "family", Username: "someoneelse"}
+
+	suite.revokedTokenRepo.On("Revoke", mock.Anything, "access-id", accessToken.ExpiresAt).Return(nil)
+	suite.refreshTokenService.On("HashToken", "refresh").Return("refresh-hash")
+	suite.refreshTokenRepo.On("FindByHash", mock.Anything, "refresh-hash").Return(stored, nil)
+
+	err := suite.usecase.Logout(context.Background(), accessToken, "refresh")
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
 }
 
 // TestPromoteUser_Success tests the PromoteUser method with valid input
@@ -277,44 +524,68 @@ func (suite *UserUsecaseTestSuite) TestPromoteUser_Success() {
 		Role:     "user",
 	}
 
-	suite.userRepo.On("FindByUsername", username).Return(user, nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(user, nil)
 
-	user.Role = "admin"
-	suite.userRepo.On("UpdateUser", user.ID, user).Return(nil)
+	user.Role, user.Roles = "", []string{"admin", "user"}
+	suite.userRepo.On("UpdateUser", mock.Anything, user.ID, user).Return(nil)
 
-	err := suite.usecase.PromoteUser(username)
+	adminIdentity := identityWithRoles("admin-id", "root", domain.AdminRole)
+	err := suite.usecase.PromoteUser(context.Background(), adminIdentity, username)
 	assert.NoError(suite.T(), err)
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
-	suite.userRepo.AssertCalled(suite.T(), "UpdateUser", user.ID, user)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
+	suite.userRepo.AssertCalled(suite.T(), "UpdateUser", mock.Anything, user.ID, user)
+
+	entries := suite.auditEntries()
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), domain.AuditUserPromote, entries[0].Action)
+	assert.Equal(suite.T(), "root", entries[0].Actor)
+	assert.Equal(suite.T(), username, entries[0].TargetID)
+	assert.Equal(suite.T(), []domain.AuditChange{{Field: "roles", Before: "user", After: "admin,user"}}, entries[0].Changes)
 }
 
 // TestPromoteUser_UserNotFound tests the PromoteUser method when the user is not found
 func (suite *UserUsecaseTestSuite) TestPromoteUser_UserNotFound() {
 	username := "testuser"
 
-	suite.userRepo.On("FindByUsername", username).Return(domain.User{}, &domain.NotFoundError{Message: "user not found"})
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(domain.User{}, &domain.NotFoundError{Message: "user not found"})
 
-	err := suite.usecase.PromoteUser(username)
+	err := suite.usecase.PromoteUser(context.Background(), admin, username)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "user not found", err.Error())
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
 }
 
 // TestPromoteUser_AlreadyAdmin tests the PromoteUser method when the user is already an admin
 func (suite *UserUsecaseTestSuite) TestPromoteUser_AlreadyAdmin() {
 	username := "testuser"
 	user := domain.User{
+		ID:       "user-id",
 		Username: username,
 		Role:     "admin",
 	}
 
-	suite.userRepo.On("FindByUsername", username).Return(user, nil)
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(user, nil)
 
-	err := suite.usecase.PromoteUser(username)
+	err := suite.usecase.PromoteUser(context.Background(), admin, username)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "user is already an admin", err.Error())
 
-	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", username)
+	suite.userRepo.AssertCalled(suite.T(), "FindByUsername", mock.Anything, username)
+}
+
+// TestPromoteUser_RequiresEveryAdminPermission tests that a caller who may promote users
+// cannot hand out the admin permissions they lack
+func (suite *UserUsecaseTestSuite) TestPromoteUser_RequiresEveryAdminPermission() {
+	username := "testuser"
+	user := domain.User{ID: "user-id", Username: username, Roles: []string{"user"}}
+
+	suite.userRepo.On("FindByUsername", mock.Anything, username).Return(user, nil)
+
+	promoter := domain.Identity{UserID: "promoter-id", Username: "promoter", Roles: []string{"promoter"}, Permissions: []string{domain.PermissionUserPromote}}
+	err := suite.usecase.PromoteUser(context.Background(), promoter, username)
+	assert.IsType(suite.T(), &domain.ForbiddenError{}, err)
+
+	suite.userRepo.AssertNotCalled(suite.T(), "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
 }
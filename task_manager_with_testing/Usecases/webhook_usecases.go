@@ -0,0 +1,365 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	domain "task-manager/Domain"
+	infrastructure "task-manager/Infrastructure"
+	repositories "task-manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// deliveryLease is how long a claimed delivery is hidden from other workers. It must
+// outlast a send, after which the delivery is rescheduled or finished; a delivery
+// whose worker died is picked up again once the lease runs out.
+const deliveryLease = 5 * time.Minute
+
+// maxDeliveriesPerRun keeps one run from holding on to the worker indefinitely
+const maxDeliveriesPerRun = 100
+
+// TaskEventPublisher is told about every change made to a task. Publishing is best
+// effort: the change has already been made, so failures are logged, not returned.
+type TaskEventPublisher interface {
+	Publish(ctx context.Context, event domain.TaskEvent)
+}
+
+// WebhookUsecase manages the webhooks admins register and the deliveries made to them.
+// It publishes task events by queueing a delivery for every subscribed webhook.
+type WebhookUsecase interface {
+	TaskEventPublisher
+	CreateWebhook(ctx context.Context, identity domain.Identity, webhook domain.Webhook) (domain.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, identity domain.Identity, id string) error
+	GetDeliveries(ctx context.Context, query domain.DeliveryQuery) ([]domain.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id string) (domain.WebhookDelivery, error)
+	// DeliverDue makes an attempt at every delivery that is due and returns how many were made
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+// WebhookRetryPolicy says how often a failed delivery is retried and how long to wait
+// between attempts: the delay doubles after each failure, up to MaxDelay
+type WebhookRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// delay returns how long to wait after the given failed attempt
+func (p WebhookRetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxDelay)
+}
+
+// webhookUsecase struct
+type webhookUsecase struct {
+	webhookRepo  repositories.WebhookRepository
+	deliveryRepo repositories.WebhookDeliveryRepository
+	sender       infrastructure.WebhookSender
+	retry        WebhookRetryPolicy
+	audit        auditRecorder
+	now          func() time.Time
+}
+
+// NewWebhookUsecase creates a new webhook usecase
+func NewWebhookUsecase(webhookRepo repositories.WebhookRepository, deliveryRepo repositories.WebhookDeliveryRepository, auditRepo repositories.AuditRepository, sender infrastructure.WebhookSender, retry WebhookRetryPolicy) WebhookUsecase {
+	return &webhookUsecase{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		retry:        retry,
+		audit:        auditRecorder{auditRepo},
+		now:          time.Now,
+	}
+}
+
+// CreateWebhook registers a webhook with a newly generated secret, which is only
+// returned this once
+func (u *webhookUsecase) CreateWebhook(ctx context.Context, identity domain.Identity, webhook domain.Webhook) (domain.Webhook, error) {
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	if err := webhook.Validate(); err != nil {
+		return domain.Webhook{}, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return domain.Webhook{}, &domain.InternalServerError{Message: "Error generating webhook secret"}
+	}
+
+	webhook.Secret = secret
+	webhook.CreatedBy = identity.Username
+	webhook.CreatedAt = u.now().UTC().Truncate(time.Millisecond)
+
+	created, err := u.webhookRepo.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditWebhookCreate, "webhook", created.ID, nil, webhookAuditFields(created))
+	return created, nil
+}
+
+// GetWebhooks retrieves every webhook without its secret
+func (u *webhookUsecase) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	webhooks, err := u.webhookRepo.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook. Its deliveries are kept; those still pending fail
+// when their next attempt finds the webhook gone.
+func (u *webhookUsecase) DeleteWebhook(ctx context.Context, identity domain.Identity, id string) error {
+	existing, err := u.webhookRepo.GetWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.webhookRepo.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	u.audit.record(ctx, identity, domain.AuditWebhookDelete, "webhook", id, webhookAuditFields(existing), nil)
+	return nil
+}
+
+// GetDeliveries retrieves the most recent deliveries matching the query, newest first
+func (u *webhookUsecase) GetDeliveries(ctx context.Context, query domain.DeliveryQuery) ([]domain.WebhookDelivery, error) {
+	if err := query.Validate(); err != nil {
+		return nil, &domain.BadRequestError{Message: err.Error()}
+	}
+
+	return u.deliveryRepo.GetDeliveries(ctx, query)
+}
+
+// ReplayDelivery queues a failed delivery's event to be sent again, as a new delivery
+// with its own attempts
+func (u *webhookUsecase) ReplayDelivery(ctx context.Context, id string) (domain.WebhookDelivery, error) {
+	original, err := u.deliveryRepo.GetDelivery(ctx, id)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	if original.Status != domain.DeliveryFailed {
+		return domain.WebhookDelivery{}, &domain.BadRequestError{Message: "Only failed deliveries can be replayed"}
+	}
+
+	if _, err := u.webhookRepo.GetWebhook(ctx, original.WebhookID); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	now := u.now().UTC().Truncate(time.Millisecond)
+	return u.deliveryRepo.CreateDelivery(ctx, domain.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        domain.DeliveryPending,
+		Attempts:      []domain.WebhookAttempt{},
+		NextAttemptAt: now,
+		ReplayOf:      original.ID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+}
+
+// Publish queues a delivery of the event for every webhook subscribed to it
+func (u *webhookUsecase) Publish(ctx context.Context, event domain.TaskEvent) {
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	webhooks, err := u.webhookRepo.GetWebhooks(writeCtx)
+	if err != nil {
+		log.Printf("webhooks: failed to publish %s of task %s: %v", event.Type, event.Task.ID, err)
+		return
+	}
+
+	var payload []byte
+	now := u.now().UTC().Truncate(time.Millisecond)
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("webhooks: failed to encode %s of task %s: %v", event.Type, event.Task.ID, err)
+				return
+			}
+		}
+
+		_, err := u.deliveryRepo.CreateDelivery(writeCtx, domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        domain.DeliveryPending,
+			Attempts:      []domain.WebhookAttempt{},
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		if err != nil {
+			log.Printf("webhooks: failed to queue %s of task %s for webhook %s: %v", event.Type, event.Task.ID, webhook.ID, err)
+		}
+	}
+}
+
+// DeliverDue claims due deliveries one at a time and makes an attempt at each. A
+// delivery that fails is retried with exponential backoff until it runs out of attempts.
+func (u *webhookUsecase) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for attempted < maxDeliveriesPerRun {
+		if err := ctx.Err(); err != nil {
+			return attempted, err
+		}
+
+		delivery, ok, err := u.deliveryRepo.ClaimDue(ctx, u.now(), deliveryLease)
+		if err != nil {
+			return attempted, err
+		}
+
+		if !ok {
+			return attempted, nil
+		}
+
+		if err := u.attempt(ctx, delivery); err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+// attempt sends a claimed delivery once and records the outcome
+func (u *webhookUsecase) attempt(ctx context.Context, delivery domain.WebhookDelivery) error {
+	webhook, err := u.webhookRepo.GetWebhook(ctx, delivery.WebhookID)
+	var notFound *domain.NotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+
+	started := u.now()
+	attempt := domain.WebhookAttempt{Number: len(delivery.Attempts) + 1, AttemptedAt: started.UTC().Truncate(time.Millisecond)}
+	if notFound != nil {
+		attempt.Error = "Webhook not found"
+	} else {
+		attempt.StatusCode, err = u.sender.Send(ctx, webhook, delivery)
+		attempt.DurationMS = u.now().Sub(started).Milliseconds()
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+	}
+
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.UpdatedAt = attempt.AttemptedAt
+	switch {
+	case attempt.Error == "":
+		delivery.Status = domain.DeliverySucceeded
+	case notFound != nil || attempt.Number >= u.retry.MaxAttempts:
+		delivery.Status = domain.DeliveryFailed
+	default:
+		delivery.NextAttemptAt = started.Add(u.retry.delay(attempt.Number))
+	}
+
+	log.Printf("webhooks: delivery %s of %s to webhook %s, attempt %d: %s", delivery.ID, delivery.EventType, delivery.WebhookID, attempt.Number, attemptOutcome(attempt, delivery.Status))
+
+	// record the attempt even if the run is being cancelled, or it would be made again
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	return u.deliveryRepo.UpdateDelivery(writeCtx, delivery)
+}
+
+func attemptOutcome(attempt domain.WebhookAttempt, status string) string {
+	if attempt.Error == "" {
+		return "delivered"
+	}
+
+	if status == domain.DeliveryFailed {
+		return attempt.Error + ", giving up"
+	}
+
+	return attempt.Error + ", will retry"
+}
+
+// WebhookWorker runs a WebhookUsecase's deliveries in the background, polling for
+// due deliveries at a fixed interval
+type WebhookWorker struct {
+	usecase WebhookUsecase
+	runner  *periodic
+}
+
+// NewWebhookWorker creates a worker that looks for due deliveries every interval
+func NewWebhookWorker(usecase WebhookUsecase, interval time.Duration) *WebhookWorker {
+	w := &WebhookWorker{usecase: usecase}
+	w.runner = &periodic{interval: interval, job: w.run}
+	return w
+}
+
+// Start delivers webhooks until Stop is called; starting a running worker does nothing
+func (w *WebhookWorker) Start() {
+	w.runner.start()
+}
+
+// Stop cancels the run in progress and waits for it to finish, or for ctx to be done
+func (w *WebhookWorker) Stop(ctx context.Context) error {
+	return w.runner.stop(ctx)
+}
+
+func (w *WebhookWorker) run(ctx context.Context) {
+	attempted, err := w.usecase.DeliverDue(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("webhooks: run failed after %d attempts: %v", attempted, err)
+	}
+}
+
+// newTaskEvent describes a change made to a task by the caller
+func newTaskEvent(identity domain.Identity, eventType string, task domain.Task) domain.TaskEvent {
+	return domain.TaskEvent{
+		ID:         primitive.NewObjectID().Hex(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC().Truncate(time.Millisecond),
+		Actor:      identity.Username,
+		Task:       task,
+	}
+}
+
+// generateSecret returns 32 random bytes, hex encoded
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// webhookAuditFields lists the audited fields of a webhook; the secret is never recorded
+func webhookAuditFields(webhook domain.Webhook) map[string]string {
+	return map[string]string{
+		"url":    webhook.URL,
+		"events": strings.Join(webhook.Events, ","),
+	}
+}
@@ -0,0 +1,322 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	domain "task-manager/Domain"
+	repositories "task-manager/Repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockWebhookSender struct {
+	mock.Mock
+}
+
+func (m *MockWebhookSender) Send(ctx context.Context, webhook domain.Webhook, delivery domain.WebhookDelivery) (int, error) {
+	args := m.Called(ctx, webhook, delivery)
+	return args.Int(0), args.Error(1)
+}
+
+// WebhookUsecaseTestSuite runs webhooks against the in-memory repositories
+type WebhookUsecaseTestSuite struct {
+	suite.Suite
+	webhookRepo  repositories.WebhookRepository
+	deliveryRepo repositories.WebhookDeliveryRepository
+	auditRepo    repositories.AuditRepository
+	sender       *MockWebhookSender
+	usecase      WebhookUsecase
+	now          time.Time
+}
+
+func (suite *WebhookUsecaseTestSuite) SetupTest() {
+	suite.webhookRepo = repositories.NewWebhookMemoryRepository()
+	suite.deliveryRepo = repositories.NewWebhookDeliveryMemoryRepository()
+	suite.auditRepo = repositories.NewAuditMemoryRepository()
+	suite.sender = new(MockWebhookSender)
+	suite.now = time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	retry := WebhookRetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	suite.usecase = NewWebhookUsecase(suite.webhookRepo, suite.deliveryRepo, suite.auditRepo, suite.sender, retry)
+	suite.usecase.(*webhookUsecase).now = func() time.Time { return suite.now }
+}
+
+func (suite *WebhookUsecaseTestSuite) TearDownTest() {
+	suite.sender.AssertExpectations(suite.T())
+}
+
+func TestWebhookUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookUsecaseTestSuite))
+}
+
+func (suite *WebhookUsecaseTestSuite) createWebhook(events ...string) domain.Webhook {
+	webhook, err := suite.usecase.CreateWebhook(context.Background(), admin, domain.Webhook{URL: "https://example.com/hook", Events: events})
+	suite.Require().NoError(err)
+	return webhook
+}
+
+// publish publishes an event about a task and returns the deliveries it queued
+func (suite *WebhookUsecaseTestSuite) publish(eventType string) []domain.WebhookDelivery {
+	suite.usecase.Publish(context.Background(), newTaskEvent(owner, eventType, domain.Task{ID: "task-id", Title: "Pay rent"}))
+
+	deliveries, err := suite.deliveryRepo.GetDeliveries(context.Background(), domain.DeliveryQuery{})
+	suite.Require().NoError(err)
+	return deliveries
+}
+
+func (suite *WebhookUsecaseTestSuite) delivery(id string) domain.WebhookDelivery {
+	delivery, err := suite.deliveryRepo.GetDelivery(context.Background(), id)
+	suite.Require().NoError(err)
+	return delivery
+}
+
+func (suite *WebhookUsecaseTestSuite) TestCreateWebhook() {
+	webhook := suite.createWebhook(domain.EventTaskCompleted)
+
+	assert.NotEmpty(suite.T(), webhook.ID)
+	assert.Len(suite.T(), webhook.Secret, 64)
+	assert.Equal(suite.T(), "admin", webhook.CreatedBy)
+	assert.Equal(suite.T(), suite.now, webhook.CreatedAt)
+
+	// the secret is only shown when the webhook is created
+	webhooks, err := suite.usecase.GetWebhooks(context.Background())
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(webhooks, 1)
+	assert.Empty(suite.T(), webhooks[0].Secret)
+
+	entries, err := suite.auditRepo.GetChain(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 1)
+	assert.Equal(suite.T(), domain.AuditWebhookCreate, entries[0].Action)
+	assert.Equal(suite.T(), webhook.ID, entries[0].TargetID)
+	for _, change := range entries[0].Changes {
+		assert.NotEqual(suite.T(), webhook.Secret, change.After)
+	}
+}
+
+func (suite *WebhookUsecaseTestSuite) TestCreateWebhook_Invalid() {
+	tests := []struct {
+		webhook domain.Webhook
+		err     string
+	}{
+		{webhook: domain.Webhook{URL: "example.com/hook"}, err: "url must be an absolute http or https URL"},
+		{webhook: domain.Webhook{URL: "ftp://example.com/hook"}, err: "url must be an absolute http or https URL"},
+		{webhook: domain.Webhook{URL: "https://example.com/hook", Events: []string{"task.archived"}}, err: `unknown event "task.archived"`},
+	}
+
+	for _, tt := range tests {
+		_, err := suite.usecase.CreateWebhook(context.Background(), admin, tt.webhook)
+		assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+		assert.EqualError(suite.T(), err, tt.err)
+	}
+}
+
+func (suite *WebhookUsecaseTestSuite) TestDeleteWebhook() {
+	webhook := suite.createWebhook()
+
+	assert.NoError(suite.T(), suite.usecase.DeleteWebhook(context.Background(), admin, webhook.ID))
+
+	webhooks, err := suite.usecase.GetWebhooks(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), webhooks)
+
+	err = suite.usecase.DeleteWebhook(context.Background(), admin, webhook.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+
+	entries, err := suite.auditRepo.GetChain(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 2)
+	assert.Equal(suite.T(), domain.AuditWebhookDelete, entries[1].Action)
+}
+
+func (suite *WebhookUsecaseTestSuite) TestPublish_QueuesSubscribedWebhooks() {
+	all := suite.createWebhook()
+	completed := suite.createWebhook(domain.EventTaskCompleted)
+
+	deliveries := suite.publish(domain.EventTaskCreated)
+	suite.Require().Len(deliveries, 1)
+	assert.Equal(suite.T(), all.ID, deliveries[0].WebhookID)
+
+	deliveries = suite.publish(domain.EventTaskCompleted)
+	suite.Require().Len(deliveries, 3)
+	assert.ElementsMatch(suite.T(), []string{all.ID, completed.ID}, []string{deliveries[0].WebhookID, deliveries[1].WebhookID})
+	assert.Equal(suite.T(), deliveries[0].EventID, deliveries[1].EventID)
+
+	delivery := deliveries[0]
+	assert.Equal(suite.T(), domain.DeliveryPending, delivery.Status)
+	assert.Equal(suite.T(), domain.EventTaskCompleted, delivery.EventType)
+	assert.Equal(suite.T(), suite.now, delivery.NextAttemptAt)
+	assert.Empty(suite.T(), delivery.Attempts)
+
+	var event domain.TaskEvent
+	suite.Require().NoError(json.Unmarshal([]byte(delivery.Payload), &event))
+	assert.Equal(suite.T(), delivery.EventID, event.ID)
+	assert.Equal(suite.T(), domain.EventTaskCompleted, event.Type)
+	assert.Equal(suite.T(), "owner", event.Actor)
+	assert.Equal(suite.T(), "Pay rent", event.Task.Title)
+}
+
+func (suite *WebhookUsecaseTestSuite) TestDeliverDue_Succeeds() {
+	webhook := suite.createWebhook()
+	delivery := suite.publish(domain.EventTaskCreated)[0]
+
+	suite.sender.On("Send", mock.Anything, mock.MatchedBy(func(w domain.Webhook) bool {
+		return w.ID == webhook.ID && w.Secret == webhook.Secret
+	}), mock.MatchedBy(func(d domain.WebhookDelivery) bool {
+		return d.ID == delivery.ID
+	})).Return(204, nil).Once()
+
+	attempted, err := suite.usecase.DeliverDue(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, attempted)
+
+	delivered := suite.delivery(delivery.ID)
+	assert.Equal(suite.T(), domain.DeliverySucceeded, delivered.Status)
+	suite.Require().Len(delivered.Attempts, 1)
+	assert.Equal(suite.T(), domain.WebhookAttempt{Number: 1, AttemptedAt: suite.now, StatusCode: 204}, delivered.Attempts[0])
+
+	// nothing is left to deliver
+	attempted, err = suite.usecase.DeliverDue(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, attempted)
+}
+
+func (suite *WebhookUsecaseTestSuite) TestDeliverDue_RetriesWithBackoff() {
+	suite.createWebhook()
+	delivery := suite.publish(domain.EventTaskCreated)[0]
+	suite.sender.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(503, errors.New("endpoint responded with status 503"))
+
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		attempted, err := suite.usecase.DeliverDue(context.Background())
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 1, attempted)
+
+		retried := suite.delivery(delivery.ID)
+		assert.Equal(suite.T(), domain.DeliveryPending, retried.Status)
+		assert.Len(suite.T(), retried.Attempts, attempt+1)
+		assert.Equal(suite.T(), suite.now.Add(delay), retried.NextAttemptAt)
+
+		// the retry waits for its delay
+		suite.now = suite.now.Add(delay - time.Second)
+		attempted, err = suite.usecase.DeliverDue(context.Background())
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 0, attempted)
+		suite.now = suite.now.Add(time.Second)
+	}
+
+	attempted, err := suite.usecase.DeliverDue(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, attempted)
+
+	failed := suite.delivery(delivery.ID)
+	assert.Equal(suite.T(), domain.DeliveryFailed, failed.Status)
+	suite.Require().Len(failed.Attempts, 3)
+	assert.Equal(suite.T(), 503, failed.Attempts[2].StatusCode)
+	assert.Equal(suite.T(), "endpoint responded with status 503", failed.Attempts[2].Error)
+	suite.sender.AssertNumberOfCalls(suite.T(), "Send", 3)
+}
+
+func (suite *WebhookUsecaseTestSuite) TestDeliverDue_DeletedWebhook() {
+	webhook := suite.createWebhook()
+	delivery := suite.publish(domain.EventTaskCreated)[0]
+	suite.Require().NoError(suite.usecase.DeleteWebhook(context.Background(), admin, webhook.ID))
+
+	attempted, err := suite.usecase.DeliverDue(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, attempted)
+
+	failed := suite.delivery(delivery.ID)
+	assert.Equal(suite.T(), domain.DeliveryFailed, failed.Status)
+	suite.Require().Len(failed.Attempts, 1)
+	assert.Equal(suite.T(), "Webhook not found", failed.Attempts[0].Error)
+	suite.sender.AssertNotCalled(suite.T(), "Send", mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = suite.usecase.ReplayDelivery(context.Background(), delivery.ID)
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *WebhookUsecaseTestSuite) TestReplayDelivery() {
+	suite.createWebhook()
+	delivery := suite.publish(domain.EventTaskCreated)[0]
+
+	_, err := suite.usecase.ReplayDelivery(context.Background(), delivery.ID)
+	assert.EqualError(suite.T(), err, "Only failed deliveries can be replayed")
+
+	delivery.Status = domain.DeliveryFailed
+	suite.Require().NoError(suite.deliveryRepo.UpdateDelivery(context.Background(), delivery))
+
+	replay, err := suite.usecase.ReplayDelivery(context.Background(), delivery.ID)
+	assert.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), delivery.ID, replay.ID)
+	assert.Equal(suite.T(), delivery.ID, replay.ReplayOf)
+	assert.Equal(suite.T(), delivery.EventID, replay.EventID)
+	assert.Equal(suite.T(), delivery.Payload, replay.Payload)
+	assert.Equal(suite.T(), domain.DeliveryPending, replay.Status)
+	assert.Empty(suite.T(), replay.Attempts)
+
+	suite.sender.On("Send", mock.Anything, mock.Anything, mock.MatchedBy(func(d domain.WebhookDelivery) bool {
+		return d.ID == replay.ID
+	})).Return(200, nil).Once()
+
+	attempted, err := suite.usecase.DeliverDue(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, attempted)
+	assert.Equal(suite.T(), domain.DeliverySucceeded, suite.delivery(replay.ID).Status)
+
+	_, err = suite.usecase.ReplayDelivery(context.Background(), "0123456789abcdef01234567")
+	assert.IsType(suite.T(), &domain.NotFoundError{}, err)
+}
+
+func (suite *WebhookUsecaseTestSuite) TestGetDeliveries_InvalidQuery() {
+	_, err := suite.usecase.GetDeliveries(context.Background(), domain.DeliveryQuery{Status: "lost"})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+
+	_, err = suite.usecase.GetDeliveries(context.Background(), domain.DeliveryQuery{Limit: domain.MaxDeliveryPageSize + 1})
+	assert.IsType(suite.T(), &domain.BadRequestError{}, err)
+}
+
+func TestWebhookRetryPolicy_Delay(t *testing.T) {
+	policy := WebhookRetryPolicy{MaxAttempts: 10, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+
+	expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, delay := range expected {
+		assert.Equal(t, delay, policy.delay(i+1), "after attempt %d", i+1)
+	}
+}
+
+// countingWebhookUsecase reports each delivery run and returns straight away
+type countingWebhookUsecase struct {
+	WebhookUsecase
+	runs chan struct{}
+}
+
+func (u *countingWebhookUsecase) DeliverDue(ctx context.Context) (int, error) {
+	select {
+	case u.runs <- struct{}{}:
+	default:
+	}
+	return 0, nil
+}
+
+func TestWebhookWorker_RunsEveryInterval(t *testing.T) {
+	usecase := &countingWebhookUsecase{runs: make(chan struct{}, 1)}
+	worker := NewWebhookWorker(usecase, 10*time.Millisecond)
+
+	worker.Start()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-usecase.runs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the worker ran %d times", i)
+		}
+	}
+
+	assert.NoError(t, worker.Stop(context.Background()))
+	assert.NoError(t, worker.Stop(context.Background()))
+}